@@ -98,11 +98,23 @@ const (
 	RemoteParam      = "remote"
 	BranchParam      = "branch"
 	TrackFlag        = "track"
+	ShallowFlag      = "shallow"
 	AmendFlag        = "amend"
 	NewFormatFlag    = "new-format"
 	CommitFlag       = "commit"
 	NoCommitFlag     = "no-commit"
 	NoEditFlag       = "no-edit"
+	InteractiveFlag  = "interactive"
+	ContinueFlag     = "continue"
+	NumberFlag       = "number"
+	MinParentsFlag   = "min-parents"
+	MergesFlag       = "merges"
+	NotFlag          = "not"
+	TablesFlag       = "tables"
+	DepthParam       = "depth"
+	SingleBranchFlag = "single-branch"
+	UnshallowFlag    = "unshallow"
+	LazyFlag         = "lazy"
 )
 
 const (
@@ -114,6 +126,12 @@ const (
 	RemoveBackupShortId = "rm"
 )
 
+const (
+	CreateBundleId   = "create"
+	UnbundleBundleId = "unbundle"
+	CloneBundleId    = "clone"
+)
+
 var mergeAbortDetails = `Abort the current conflict resolution process, and try to reconstruct the pre-merge state.
 
 If there were uncommitted working set changes present when the merge started, {{.EmphasisLeft}}dolt merge --abort{{.EmphasisRight}} will be unable to reconstruct these changes. It is therefore recommended to always commit or stash your changes before running dolt merge.
@@ -155,6 +173,7 @@ func CreateAddArgParser() *argparser.ArgParser {
 	ap := argparser.NewArgParser()
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"table", "Working table(s) to add to the list tables staged to be committed. The abbreviation '.' can be used to add all tables."})
 	ap.SupportsFlag("all", "A", "Stages any and all changes (adds, deletes, and modifications).")
+	ap.SupportsFlag(ForceFlag, "f", "Allow adding tables that are ignored by the dolt_ignore table.")
 	return ap
 }
 
@@ -166,6 +185,7 @@ func CreateCloneArgParser() *argparser.ArgParser {
 	ap.SupportsValidatedString(dbfactory.AWSCredsTypeParam, "", "creds-type", "", argparser.ValidatorFromStrList(dbfactory.AWSCredsTypeParam, dbfactory.AWSCredTypes))
 	ap.SupportsString(dbfactory.AWSCredsFileParam, "", "file", "AWS credentials file.")
 	ap.SupportsString(dbfactory.AWSCredsProfile, "", "profile", "AWS profile to use.")
+	ap.SupportsString(dbfactory.AWSEndpointParam, "", "endpoint", "Endpoint of the S3 compatible service of an s3 remote.")
 	return ap
 }
 
@@ -182,6 +202,7 @@ func CreateRemoteArgParser() *argparser.ArgParser {
 	ap.SupportsValidatedString(dbfactory.AWSCredsTypeParam, "", "creds-type", "", argparser.ValidatorFromStrList(dbfactory.AWSCredsTypeParam, dbfactory.AWSCredTypes))
 	ap.SupportsString(dbfactory.AWSCredsFileParam, "", "file", "AWS credentials file")
 	ap.SupportsString(dbfactory.AWSCredsProfile, "", "profile", "AWS profile to use")
+	ap.SupportsString(dbfactory.AWSEndpointParam, "", "endpoint", "Endpoint of the S3 compatible service of an s3 remote.")
 	return ap
 }
 
@@ -191,6 +212,12 @@ func CreateCleanArgParser() *argparser.ArgParser {
 	return ap
 }
 
+func CreateGCArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsFlag(ShallowFlag, "s", "perform a fast, but incomplete garbage collection pass")
+	return ap
+}
+
 func CreateCheckoutArgParser() *argparser.ArgParser {
 	ap := argparser.NewArgParser()
 	ap.SupportsString(CheckoutCoBranch, "", "branch", "Create a new branch named {{.LessThan}}new_branch{{.GreaterThan}} and start it at {{.LessThan}}start_point{{.GreaterThan}}.")
@@ -204,6 +231,43 @@ func CreateCherryPickArgParser() *argparser.ArgParser {
 	return ap
 }
 
+func CreateRebaseArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsFlag(InteractiveFlag, "i", "Edit the list of commits to be rebased before applying them. Each commit can be picked, reworded, squashed, fixed up or dropped, and the commits can be reordered.")
+	ap.SupportsFlag(ContinueFlag, "", "Continue the rebase in progress after resolving conflicts or editing the rebase plan.")
+	ap.SupportsFlag(AbortParam, "", "Abort the rebase in progress and return the branch to the commit it pointed to before the rebase started.")
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"upstream", "The commit to rebase the current branch onto."})
+	return ap
+}
+
+func CreateStashArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsString(MessageArg, "m", "msg", "Use the given {{.LessThan}}msg{{.GreaterThan}} as the description of the stash entry.")
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"subcommand", "One of push, pop, apply, list, drop or clear. Defaults to push."})
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"stash", "The stash entry to pop, apply or drop, e.g. stash@{1}. Defaults to the most recent entry."})
+	return ap
+}
+
+// CreateLogArgParser creates the argparser for the DOLT_LOG table function. The options that limit which commits are
+// shown are the same as for dolt log.
+func CreateLogArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsInt(NumberFlag, "n", "num_commits", "Limit the number of commits to output.")
+	ap.SupportsInt(MinParentsFlag, "", "parent_count", "The minimum number of parents a commit must have to be included in the log.")
+	ap.SupportsFlag(MergesFlag, "", "Equivalent to min-parents == 2, this will limit the log to commits with 2 or more parents.")
+	ap.SupportsStringList(NotFlag, "", "revision", "Excludes commits reachable from any of the {{.LessThan}}revision{{.GreaterThan}}s that follow it.")
+	ap.SupportsStringList(TablesFlag, "", "table", "Limits the log to commits that changed any of the {{.LessThan}}table{{.GreaterThan}}s that follow it.")
+	return ap
+}
+
+// CreateReflogArgParser creates the argparser shared by dolt reflog and the DOLT_REFLOG table function.
+func CreateReflogArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"ref", "The branch, tag or remote tracking branch to show the updates of. Defaults to all refs."})
+	ap.SupportsFlag(AllFlag, "", "Also show updates of working sets and internal refs.")
+	return ap
+}
+
 func CreateFetchArgParser() *argparser.ArgParser {
 	ap := argparser.NewArgParser()
 	ap.SupportsFlag(ForceFlag, "f", "Update refs to remote branches with the current state of the remote, overwriting any conflicting history.")
@@ -257,11 +321,13 @@ func CreateBackupArgParser() *argparser.ArgParser {
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"region", "cloud provider region associated with this backup."})
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"creds-type", "credential type.  Valid options are role, env, and file.  See the help section for additional details."})
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"profile", "AWS profile to use."})
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"endpoint", "endpoint of the S3 compatible service of an s3 backup."})
 	ap.SupportsFlag(VerboseFlag, "v", "When printing the list of backups adds additional details.")
 	ap.SupportsString(dbfactory.AWSRegionParam, "", "region", "")
 	ap.SupportsValidatedString(dbfactory.AWSCredsTypeParam, "", "creds-type", "", argparser.ValidatorFromStrList(dbfactory.AWSCredsTypeParam, dbfactory.AWSCredTypes))
 	ap.SupportsString(dbfactory.AWSCredsFileParam, "", "file", "AWS credentials file")
 	ap.SupportsString(dbfactory.AWSCredsProfile, "", "profile", "AWS profile to use")
+	ap.SupportsString(dbfactory.AWSEndpointParam, "", "endpoint", "Endpoint of the S3 compatible service of an s3 remote.")
 	return ap
 }
 
@@ -273,13 +339,13 @@ func CreateVerifyConstraintsArgParser() *argparser.ArgParser {
 	return ap
 }
 
-var awsParams = []string{dbfactory.AWSRegionParam, dbfactory.AWSCredsTypeParam, dbfactory.AWSCredsFileParam, dbfactory.AWSCredsProfile}
+var awsParams = []string{dbfactory.AWSRegionParam, dbfactory.AWSCredsTypeParam, dbfactory.AWSCredsFileParam, dbfactory.AWSCredsProfile, dbfactory.AWSEndpointParam}
 
 func ProcessBackupArgs(apr *argparser.ArgParseResults, scheme, backupUrl string) (map[string]string, error) {
 	params := map[string]string{}
 
 	var err error
-	if scheme == dbfactory.AWSScheme {
+	if scheme == dbfactory.AWSScheme || scheme == dbfactory.S3Scheme {
 		err = AddAWSParams(backupUrl, apr, params)
 	} else {
 		err = VerifyNoAwsParams(apr)
@@ -289,12 +355,12 @@ func ProcessBackupArgs(apr *argparser.ArgParseResults, scheme, backupUrl string)
 }
 
 func AddAWSParams(remoteUrl string, apr *argparser.ArgParseResults, params map[string]string) error {
-	isAWS := strings.HasPrefix(remoteUrl, "aws")
+	isAWS := strings.HasPrefix(remoteUrl, "aws") || strings.HasPrefix(remoteUrl, "s3")
 
 	if !isAWS {
 		for _, p := range awsParams {
 			if _, ok := apr.GetValue(p); ok {
-				return fmt.Errorf("%s param is only valid for aws cloud remotes in the format aws://dynamo-table:s3-bucket/database", p)
+				return fmt.Errorf("%s param is only valid for aws cloud remotes in the format aws://dynamo-table:s3-bucket/database or s3://s3-bucket/database", p)
 			}
 		}
 	}
@@ -316,7 +382,7 @@ func VerifyNoAwsParams(apr *argparser.ArgParseResults) error {
 		}
 
 		keysStr := strings.Join(awsParamKeys, ",")
-		return fmt.Errorf("The parameters %s, are only valid for aws remotes", keysStr)
+		return fmt.Errorf("The parameters %s, are only valid for aws and s3 remotes", keysStr)
 	}
 
 	return nil
@@ -16,6 +16,7 @@ package commands
 
 import (
 	"context"
+	"errors"
 
 	"github.com/dolthub/dolt/go/cmd/dolt/cli"
 	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
@@ -32,7 +33,9 @@ This command updates the list of tables using the current content found in the w
 
 This command can be performed multiple times before a commit. It only adds the content of the specified table(s) at the time the add command is run; if you want subsequent changes included in the next commit, then you must run dolt add again to add the new content to the index.
 
-The dolt status command can be used to obtain a summary of which tables have changes that are staged for the next commit.`,
+The dolt status command can be used to obtain a summary of which tables have changes that are staged for the next commit.
+
+New tables whose names match a pattern in the dolt_ignore table are not added, unless {{.EmphasisLeft}}-f{{.EmphasisRight}} is given.`,
 	Synopsis: []string{
 		`[{{.LessThan}}table{{.GreaterThan}}...]`,
 	},
@@ -66,6 +69,7 @@ func (cmd AddCmd) Exec(ctx context.Context, commandStr string, args []string, dE
 	apr := cli.ParseArgsOrDie(ap, args, helpPr)
 
 	allFlag := apr.Contains(cli.AllFlag)
+	filterIgnoredTables := !apr.Contains(cli.ForceFlag)
 
 	if dEnv.IsLocked() {
 		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(env.ErrActiveServerLock.New(dEnv.LockFile())), helpPr)
@@ -79,12 +83,12 @@ func (cmd AddCmd) Exec(ctx context.Context, commandStr string, args []string, dE
 	if apr.NArg() == 0 && !allFlag {
 		cli.Println("Nothing specified, nothing added.\n Maybe you wanted to say 'dolt add .'?")
 	} else if allFlag || apr.NArg() == 1 && apr.Arg(0) == "." {
-		roots, err = actions.StageAllTables(ctx, roots)
+		roots, err = actions.StageAllTables(ctx, roots, filterIgnoredTables)
 		if err != nil {
 			return handleStageError(err)
 		}
 	} else {
-		roots, err = actions.StageTables(ctx, roots, apr.Args)
+		roots, err = actions.StageTables(ctx, roots, apr.Args, filterIgnoredTables)
 		if err != nil {
 			return handleStageError(err)
 		}
@@ -118,6 +122,16 @@ func toAddVErr(err error) errhand.VerboseError {
 
 		return bdr.Build()
 
+	case actions.IsTblIgnored(err):
+		tbls := actions.GetTablesForError(err)
+		bdr := errhand.BuildDError("The following tables are ignored by %s:", doltdb.IgnoreTableName)
+		for _, tbl := range tbls {
+			bdr.AddDetails("  %s", tbl)
+		}
+		bdr.AddDetails("Use -f if you really want to add them.")
+
+		return bdr.Build()
+
 	case actions.IsTblInConflict(err) || actions.IsTblViolatesConstraints(err):
 		tbls := actions.GetTablesForError(err)
 		bdr := errhand.BuildDError("error: not all tables merged")
@@ -128,6 +142,9 @@ func toAddVErr(err error) errhand.VerboseError {
 
 		return bdr.Build()
 
+	case errors.As(err, &doltdb.ErrConflictingIgnorePatterns{}):
+		return errhand.VerboseErrorFromError(err)
+
 	default:
 		return errhand.BuildDError("Unknown error").AddCause(err).Build()
 	}
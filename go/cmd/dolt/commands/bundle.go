@@ -0,0 +1,306 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	eventsapi "github.com/dolthub/dolt/go/gen/proto/dolt/services/eventsapi/v1alpha1"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	"github.com/dolthub/dolt/go/store/blobstore"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+var bundleDocs = cli.CommandDocumentationContent{
+	ShortDesc: "Move a repository or a range of commits with a single file",
+	LongDesc: `A bundle is a single file holding branches and tags of a repository along with the history they reference, which allows moving data between machines which can't reach the same remote.
+
+{{.EmphasisLeft}}create{{.EmphasisRight}}
+Writes the refs given to a new bundle {{.LessThan}}file{{.GreaterThan}}. A ref is the name of a branch or a tag, or {{.EmphasisLeft}}HEAD{{.EmphasisRight}} for the current branch. A range {{.EmphasisLeft}}{{.LessThan}}commit{{.GreaterThan}}..{{.LessThan}}ref{{.GreaterThan}}{{.EmphasisRight}} bundles {{.LessThan}}ref{{.GreaterThan}} without the history of {{.LessThan}}commit{{.GreaterThan}}, which makes a smaller bundle that can only be unbundled into a repository which has {{.LessThan}}commit{{.GreaterThan}}. With {{.EmphasisLeft}}--all{{.EmphasisRight}}, every branch and tag is written to the bundle.
+
+{{.EmphasisLeft}}unbundle{{.EmphasisRight}}
+Copies the history of the bundle {{.LessThan}}file{{.GreaterThan}} into the current repository and prints the refs of the bundle along with the commits they point at. No branches or tags are changed, use {{.EmphasisLeft}}dolt branch{{.EmphasisRight}} or {{.EmphasisLeft}}dolt merge{{.EmphasisRight}} with the commits printed to use them.
+
+{{.EmphasisLeft}}clone{{.EmphasisRight}}
+Clones the bundle {{.LessThan}}file{{.GreaterThan}} into a new repository in {{.LessThan}}new-dir{{.GreaterThan}}, as {{.EmphasisLeft}}dolt clone{{.EmphasisRight}} does. The bundle must have been created without ranges.
+
+A bundle created without ranges can also be used as a read-only remote with a url of the form {{.EmphasisLeft}}bundle://path/to/file{{.EmphasisRight}}, to fetch from it or clone it.`,
+
+	Synopsis: []string{
+		"create {{.LessThan}}file{{.GreaterThan}} [--all] [{{.LessThan}}ref{{.GreaterThan}} | {{.LessThan}}commit{{.GreaterThan}}..{{.LessThan}}ref{{.GreaterThan}}]...",
+		"unbundle {{.LessThan}}file{{.GreaterThan}}",
+		"clone {{.LessThan}}file{{.GreaterThan}} [{{.LessThan}}new-dir{{.GreaterThan}}]",
+	},
+}
+
+type BundleCmd struct{}
+
+// Name is returns the name of the Dolt cli command. This is what is used on the command line to invoke the command
+func (cmd BundleCmd) Name() string {
+	return "bundle"
+}
+
+// Description returns a description of the command
+func (cmd BundleCmd) Description() string {
+	return "Move a repository or a range of commits with a single file."
+}
+
+func (cmd BundleCmd) RequiresRepo() bool {
+	return false
+}
+
+func (cmd BundleCmd) Docs() *cli.CommandDocumentation {
+	ap := cmd.ArgParser()
+	return cli.NewCommandDocumentation(bundleDocs, ap)
+}
+
+func (cmd BundleCmd) ArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsFlag(cli.AllFlag, "a", "When creating a bundle, writes every branch and tag to it.")
+	return ap
+}
+
+// EventType returns the type of the event to log
+func (cmd BundleCmd) EventType() eventsapi.ClientEventType {
+	return eventsapi.ClientEventType_REMOTE
+}
+
+// Exec executes the command
+func (cmd BundleCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cmd.ArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, bundleDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+
+	var verr errhand.VerboseError
+
+	switch {
+	case apr.NArg() == 0:
+		verr = errhand.BuildDError("").SetPrintUsage().Build()
+	case apr.Arg(0) == cli.CreateBundleId:
+		if !cli.CheckEnvIsValid(dEnv) {
+			return 1
+		}
+		verr = createBundle(ctx, dEnv, apr)
+	case apr.Arg(0) == cli.UnbundleBundleId:
+		if !cli.CheckEnvIsValid(dEnv) {
+			return 1
+		}
+		verr = unbundle(ctx, dEnv, apr)
+	case apr.Arg(0) == cli.CloneBundleId:
+		verr = cloneBundle(ctx, dEnv, apr)
+	default:
+		verr = errhand.BuildDError("").SetPrintUsage().Build()
+	}
+
+	return HandleVErrAndExitCode(verr, usage)
+}
+
+func createBundle(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgParseResults) errhand.VerboseError {
+	all := apr.Contains(cli.AllFlag)
+	if apr.NArg() < 2 || (apr.NArg() == 2 && !all) {
+		return errhand.BuildDError("").SetPrintUsage().Build()
+	}
+
+	path, err := dEnv.FS.Abs(apr.Arg(1))
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	refs, prerequisites, verr := resolveBundleRefs(ctx, dEnv, apr.Args[2:], all)
+	if verr != nil {
+		return verr
+	}
+
+	err = actions.CreateBundle(ctx, dEnv.TempTableFilesDir(), dEnv.DoltDB, path, refs, prerequisites, buildProgStarter(defaultLanguage), stopProgFuncs)
+	if err != nil {
+		return errhand.BuildDError("error: failed to create bundle").AddCause(err).Build()
+	}
+
+	return nil
+}
+
+// resolveBundleRefs returns the refs and the prerequisite commits of the bundle described by |args|.
+func resolveBundleRefs(ctx context.Context, dEnv *env.DoltEnv, args []string, all bool) ([]doltdb.BundleRef, hash.HashSet, errhand.VerboseError) {
+	allRefs, err := dEnv.DoltDB.GetBundleRefs(ctx)
+	if err != nil {
+		return nil, nil, errhand.BuildDError("error: failed to read refs").AddCause(err).Build()
+	}
+
+	byName := make(map[string]doltdb.BundleRef, len(allRefs))
+	for _, r := range allRefs {
+		byName[r.Ref.String()] = r
+	}
+
+	var refs []doltdb.BundleRef
+	seen := make(map[string]struct{})
+	addRef := func(r doltdb.BundleRef) {
+		if _, ok := seen[r.Ref.String()]; !ok {
+			seen[r.Ref.String()] = struct{}{}
+			refs = append(refs, r)
+		}
+	}
+
+	if all {
+		for _, r := range allRefs {
+			addRef(r)
+		}
+	}
+
+	prerequisites := hash.NewHashSet()
+	for _, arg := range args {
+		name := arg
+		if i := strings.Index(arg, ".."); i >= 0 {
+			name = arg[i+2:]
+
+			cs, err := doltdb.NewCommitSpec(arg[:i])
+			if err != nil {
+				return nil, nil, errhand.BuildDError("error: invalid commit '%s'", arg[:i]).AddCause(err).Build()
+			}
+			cm, err := dEnv.DoltDB.Resolve(ctx, cs, dEnv.RepoStateReader().CWBHeadRef())
+			if err != nil {
+				return nil, nil, errhand.BuildDError("error: unable to resolve commit '%s'", arg[:i]).AddCause(err).Build()
+			}
+			h, err := cm.HashOf()
+			if err != nil {
+				return nil, nil, errhand.VerboseErrorFromError(err)
+			}
+			prerequisites.Insert(h)
+		}
+
+		var candidates []ref.DoltRef
+		if strings.EqualFold(name, "HEAD") {
+			candidates = []ref.DoltRef{dEnv.RepoStateReader().CWBHeadRef()}
+		} else if ref.IsRef(name) {
+			r, err := ref.Parse(name)
+			if err != nil {
+				return nil, nil, errhand.BuildDError("error: invalid ref '%s'", name).AddCause(err).Build()
+			}
+			candidates = []ref.DoltRef{r}
+		} else {
+			candidates = []ref.DoltRef{ref.NewBranchRef(name), ref.NewTagRef(name)}
+		}
+
+		found := false
+		for _, c := range candidates {
+			if r, ok := byName[c.String()]; ok {
+				addRef(r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, errhand.BuildDError("error: '%s' is not a branch or a tag", name).Build()
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil, nil, errhand.BuildDError("error: no refs to bundle").Build()
+	}
+
+	return refs, prerequisites, nil
+}
+
+func unbundle(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgParseResults) errhand.VerboseError {
+	if apr.NArg() != 2 {
+		return errhand.BuildDError("").SetPrintUsage().Build()
+	}
+
+	bundleDB, prerequisites, verr := openBundle(ctx, dEnv, apr.Arg(1))
+	if verr != nil {
+		return verr
+	}
+
+	refs, err := actions.Unbundle(ctx, dEnv.TempTableFilesDir(), bundleDB, dEnv.DoltDB, prerequisites, buildProgStarter(downloadLanguage), stopProgFuncs)
+	if errors.Is(err, actions.ErrMissingBundlePrerequisites) {
+		return errhand.BuildDError("error: %s", err.Error()).AddDetails("fetch the commits the bundle was created against before unbundling it").Build()
+	} else if err != nil {
+		return errhand.BuildDError("error: failed to unbundle").AddCause(err).Build()
+	}
+
+	for _, r := range refs {
+		cli.Printf("%s\t%s\n", r.Addr.String(), r.Ref.String())
+	}
+
+	return nil
+}
+
+func cloneBundle(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgParseResults) errhand.VerboseError {
+	if apr.NArg() < 2 || apr.NArg() > 3 {
+		return errhand.BuildDError("").SetPrintUsage().Build()
+	}
+
+	file := apr.Arg(1)
+	_, prerequisites, verr := openBundle(ctx, dEnv, file)
+	if verr != nil {
+		return verr
+	}
+	if len(prerequisites) > 0 {
+		return errhand.BuildDError("error: '%s' was created with a range of commits and can't be cloned", file).AddDetails("use dolt bundle unbundle in a repository which has the commits it was created against").Build()
+	}
+
+	var dir string
+	if apr.NArg() == 3 {
+		dir = apr.Arg(2)
+	} else {
+		base := filepath.Base(file)
+		dir = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	path, err := dEnv.FS.Abs(file)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	cloneApr, err := CloneCmd{}.ArgParser().Parse([]string{dbfactory.BundleScheme + "://" + filepath.ToSlash(path), dir})
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	return clone(ctx, cloneApr, dEnv)
+}
+
+// openBundle opens the bundle |file| as a read-only database, and returns it along with the commits it was created
+// against.
+func openBundle(ctx context.Context, dEnv *env.DoltEnv, file string) (*doltdb.DoltDB, hash.HashSet, errhand.VerboseError) {
+	path, err := dEnv.FS.Abs(file)
+	if err != nil {
+		return nil, nil, errhand.VerboseErrorFromError(err)
+	}
+
+	prerequisites, err := doltdb.ReadBundlePrerequisites(ctx, path)
+	if errors.Is(err, blobstore.ErrInvalidBundle) {
+		return nil, nil, errhand.BuildDError("error: '%s' is not a bundle", file).Build()
+	} else if err != nil {
+		return nil, nil, errhand.BuildDError("error: failed to read bundle '%s'", file).AddCause(err).Build()
+	}
+
+	bundleDB, err := doltdb.LoadDoltDB(ctx, types.Format_Default, dbfactory.BundleScheme+"://"+filepath.ToSlash(path), dEnv.FS)
+	if err != nil {
+		return nil, nil, errhand.BuildDError("error: failed to open bundle '%s'", file).AddCause(err).Build()
+	}
+
+	return bundleDB, prerequisites, nil
+}
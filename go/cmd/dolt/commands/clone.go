@@ -16,6 +16,7 @@ package commands
 
 import (
 	"context"
+	"fmt"
 	"path"
 	"strings"
 
@@ -40,9 +41,15 @@ var cloneDocs = cli.CommandDocumentationContent{
 After the clone, a plain {{.EmphasisLeft}}dolt fetch{{.EmphasisRight}} without arguments will update all the remote-tracking branches, and a {{.EmphasisLeft}}dolt pull{{.EmphasisRight}} without arguments will in addition merge the remote branch into the current branch.
 
 This default configuration is achieved by creating references to the remote branch heads under {{.LessThan}}refs/remotes/origin{{.GreaterThan}}  and by creating a remote named 'origin'.
+
+With {{.EmphasisLeft}}--depth{{.EmphasisRight}}, only the most recent commits of each branch are cloned. The history of the clone stops at the oldest commits cloned, which are recorded as shallow commits. {{.EmphasisLeft}}dolt fetch --unshallow{{.EmphasisRight}} fetches the rest of the history later.
+
+With {{.EmphasisLeft}}--single-branch{{.EmphasisRight}}, only the branch given by {{.EmphasisLeft}}--branch{{.EmphasisRight}}, or the default branch of the remote, is cloned, and the remote is configured to only fetch that branch.
+
+With {{.EmphasisLeft}}--lazy{{.EmphasisRight}}, no table data is downloaded when cloning. The clone fetches the data it reads from the remote as it is needed, and keeps it. Garbage collection, {{.EmphasisLeft}}dolt fsck{{.EmphasisRight}} and cloning from a lazy clone are not supported, and the remote can't be removed.
 `,
 	Synopsis: []string{
-		"[-remote {{.LessThan}}remote{{.GreaterThan}}] [-branch {{.LessThan}}branch{{.GreaterThan}}]  [--aws-region {{.LessThan}}region{{.GreaterThan}}] [--aws-creds-type {{.LessThan}}creds-type{{.GreaterThan}}] [--aws-creds-file {{.LessThan}}file{{.GreaterThan}}] [--aws-creds-profile {{.LessThan}}profile{{.GreaterThan}}] {{.LessThan}}remote-url{{.GreaterThan}} {{.LessThan}}new-dir{{.GreaterThan}}",
+		"[-remote {{.LessThan}}remote{{.GreaterThan}}] [-branch {{.LessThan}}branch{{.GreaterThan}}] [--depth {{.LessThan}}depth{{.GreaterThan}} | --lazy] [--single-branch]  [--aws-region {{.LessThan}}region{{.GreaterThan}}] [--aws-creds-type {{.LessThan}}creds-type{{.GreaterThan}}] [--aws-creds-file {{.LessThan}}file{{.GreaterThan}}] [--aws-creds-profile {{.LessThan}}profile{{.GreaterThan}}] [--aws-endpoint {{.LessThan}}endpoint{{.GreaterThan}}] {{.LessThan}}remote-url{{.GreaterThan}} {{.LessThan}}new-dir{{.GreaterThan}}",
 	},
 }
 
@@ -70,7 +77,11 @@ func (cmd CloneCmd) Docs() *cli.CommandDocumentation {
 }
 
 func (cmd CloneCmd) ArgParser() *argparser.ArgParser {
-	return cli.CreateCloneArgParser()
+	ap := cli.CreateCloneArgParser()
+	ap.SupportsInt(cli.DepthParam, "", "depth", "Clone only the most recent {{.LessThan}}depth{{.GreaterThan}} commits of each branch.")
+	ap.SupportsFlag(cli.SingleBranchFlag, "", "Clone only the branch given by --branch, or the default branch of the remote.")
+	ap.SupportsFlag(cli.LazyFlag, "", "Fetch the data of the clone from the remote as it is read, instead of downloading all of it.")
+	return ap
 }
 
 // EventType returns the type of the event to log
@@ -95,6 +106,15 @@ func (cmd CloneCmd) Exec(ctx context.Context, commandStr string, args []string,
 func clone(ctx context.Context, apr *argparser.ArgParseResults, dEnv *env.DoltEnv) errhand.VerboseError {
 	remoteName := apr.GetValueOrDefault(cli.RemoteParam, "origin")
 	branch := apr.GetValueOrDefault(cli.BranchParam, "")
+	singleBranch := apr.Contains(cli.SingleBranchFlag)
+	depth, hasDepth := apr.GetInt(cli.DepthParam)
+	if hasDepth && depth < 1 {
+		return errhand.BuildDError("error: depth must be a positive number").Build()
+	}
+	lazy := apr.Contains(cli.LazyFlag)
+	if lazy && hasDepth {
+		return errhand.BuildDError("error: --%s and --%s cannot be used together", cli.LazyFlag, cli.DepthParam).Build()
+	}
 	dir, urlStr, verr := parseArgs(apr)
 	if verr != nil {
 		return verr
@@ -126,6 +146,17 @@ func clone(ctx context.Context, apr *argparser.ArgParseResults, dEnv *env.DoltEn
 		return verr
 	}
 
+	if singleBranch {
+		if branch == "" {
+			branches, err := srcDB.GetBranches(ctx)
+			if err != nil {
+				return errhand.BuildDError("error: failed to list branches of the remote").AddCause(err).Build()
+			}
+			branch = env.GetDefaultBranch(dEnv, branches)
+		}
+		r.FetchSpecs = []string{fmt.Sprintf("refs/heads/%s:refs/remotes/%s/%s", branch, remoteName, branch)}
+	}
+
 	// Create a new Dolt env for the clone
 	clonedEnv, err := actions.EnvForClone(ctx, srcDB.ValueReadWriter().Format(), r, dir, dEnv.FS, dEnv.Version, env.GetCurrentUserHomeDir)
 	if err != nil {
@@ -135,7 +166,13 @@ func clone(ctx context.Context, apr *argparser.ArgParseResults, dEnv *env.DoltEn
 	// Nil out the old Dolt env so we don't accidentally operate on the wrong database
 	dEnv = nil
 
-	err = actions.CloneRemote(ctx, srcDB, remoteName, branch, clonedEnv)
+	if lazy {
+		err = actions.LazyCloneRemote(ctx, srcDB, remoteName, branch, singleBranch, clonedEnv, buildProgStarter(downloadLanguage), stopProgFuncs)
+	} else if singleBranch || hasDepth {
+		err = actions.ShallowCloneRemote(ctx, srcDB, remoteName, branch, singleBranch, depth, clonedEnv, buildProgStarter(downloadLanguage), stopProgFuncs)
+	} else {
+		err = actions.CloneRemote(ctx, srcDB, remoteName, branch, clonedEnv)
+	}
 	if err != nil {
 		// If we're cloning into a directory that already exists do not erase it. Otherwise
 		// make best effort to delete the directory we created.
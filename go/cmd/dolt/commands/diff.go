@@ -33,7 +33,6 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
-	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlutil"
 	"github.com/dolthub/dolt/go/libraries/utils/argparser"
 	"github.com/dolthub/dolt/go/libraries/utils/set"
@@ -412,9 +411,9 @@ func diffUserTable(
 }
 
 func writeSqlSchemaDiff(ctx context.Context, td diff.TableDelta, toSchemas map[string]schema.Schema) errhand.VerboseError {
-	ddlStatements, err := sqlSchemaDiff(ctx, td, toSchemas)
+	ddlStatements, err := sqle.SqlSchemaDiff(ctx, td, toSchemas)
 	if err != nil {
-		return err
+		return errhand.VerboseErrorFromError(err)
 	}
 
 	for _, stmt := range ddlStatements {
@@ -424,99 +423,6 @@ func writeSqlSchemaDiff(ctx context.Context, td diff.TableDelta, toSchemas map[s
 	return nil
 }
 
-// sqlSchemaDiff returns a slice of DDL statements that will transform the schema in the from delta to the schema in
-// the to delta.
-// TODO: this doesn't handle constraints or triggers
-// TODO: this should live in the diff package
-func sqlSchemaDiff(ctx context.Context, td diff.TableDelta, toSchemas map[string]schema.Schema) ([]string, errhand.VerboseError) {
-	fromSch, toSch, err := td.GetSchemas(ctx)
-	if err != nil {
-		return nil, errhand.BuildDError("cannot retrieve schema for table %s", td.ToName).AddCause(err).Build()
-	}
-
-	var ddlStatements []string
-
-	if td.IsDrop() {
-		ddlStatements = append(ddlStatements, sqlfmt.DropTableStmt(td.FromName))
-	} else if td.IsAdd() {
-		sqlDb := sqle.NewSingleTableDatabase(td.ToName, toSch, td.ToFks, td.ToFksParentSch)
-		sqlCtx, engine, _ := sqle.PrepareCreateTableStmt(ctx, sqlDb)
-		stmt, err := sqle.GetCreateTableStmt(sqlCtx, engine, td.ToName)
-		if err != nil {
-			return nil, errhand.VerboseErrorFromError(err)
-		}
-		ddlStatements = append(ddlStatements, stmt)
-	} else {
-		if td.FromName != td.ToName {
-			ddlStatements = append(ddlStatements, sqlfmt.RenameTableStmt(td.FromName, td.ToName))
-		}
-
-		eq := schema.SchemasAreEqual(fromSch, toSch)
-		if eq && !td.HasFKChanges() {
-			return ddlStatements, nil
-		}
-
-		colDiffs, unionTags := diff.DiffSchColumns(fromSch, toSch)
-		for _, tag := range unionTags {
-			cd := colDiffs[tag]
-			switch cd.DiffType {
-			case diff.SchDiffNone:
-			case diff.SchDiffAdded:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddColStmt(td.ToName, sqlfmt.FmtCol(0, 0, 0, *cd.New)))
-			case diff.SchDiffRemoved:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropColStmt(td.ToName, cd.Old.Name))
-			case diff.SchDiffModified:
-				// Ignore any primary key set changes here
-				if cd.Old.IsPartOfPK != cd.New.IsPartOfPK {
-					continue
-				}
-				if cd.Old.Name != cd.New.Name {
-					ddlStatements = append(ddlStatements, sqlfmt.AlterTableRenameColStmt(td.ToName, cd.Old.Name, cd.New.Name))
-				}
-			}
-		}
-
-		// Print changes between a primary key set change. It contains an ALTER TABLE DROP and an ALTER TABLE ADD
-		if !schema.ColCollsAreEqual(fromSch.GetPKCols(), toSch.GetPKCols()) {
-			ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropPks(td.ToName))
-			if toSch.GetPKCols().Size() > 0 {
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddPrimaryKeys(td.ToName, toSch.GetPKCols()))
-			}
-		}
-
-		for _, idxDiff := range diff.DiffSchIndexes(fromSch, toSch) {
-			switch idxDiff.DiffType {
-			case diff.SchDiffNone:
-			case diff.SchDiffAdded:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddIndexStmt(td.ToName, idxDiff.To))
-			case diff.SchDiffRemoved:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropIndexStmt(td.FromName, idxDiff.From))
-			case diff.SchDiffModified:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropIndexStmt(td.FromName, idxDiff.From))
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddIndexStmt(td.ToName, idxDiff.To))
-			}
-		}
-
-		for _, fkDiff := range diff.DiffForeignKeys(td.FromFks, td.ToFks) {
-			switch fkDiff.DiffType {
-			case diff.SchDiffNone:
-			case diff.SchDiffAdded:
-				parentSch := toSchemas[fkDiff.To.ReferencedTableName]
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddForeignKeyStmt(fkDiff.To, toSch, parentSch))
-			case diff.SchDiffRemoved:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropForeignKeyStmt(fkDiff.From))
-			case diff.SchDiffModified:
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropForeignKeyStmt(fkDiff.From))
-
-				parentSch := toSchemas[fkDiff.To.ReferencedTableName]
-				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddForeignKeyStmt(fkDiff.To, toSch, parentSch))
-			}
-		}
-	}
-
-	return ddlStatements, nil
-}
-
 func diffRows(
 	ctx context.Context,
 	se *engine.SqlEngine,
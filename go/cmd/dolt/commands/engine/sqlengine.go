@@ -33,6 +33,8 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
 	dsqle "github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/binlogreplication"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/cluster"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/mysql_file_handler"
 	"github.com/dolthub/dolt/go/libraries/utils/config"
@@ -59,6 +61,14 @@ type SqlEngineConfig struct {
 	Autocommit     bool
 	Bulk           bool
 	JwksConfig     []JwksConfig
+	// ClusterController manages the role of the server in its cluster. It is nil if the server isn't in a cluster.
+	ClusterController *cluster.Controller
+	// BinlogReplicaController manages the replica which applies the binlog of a MySQL source. It is nil for engines
+	// which aren't run by a sql-server.
+	BinlogReplicaController *binlogreplication.Controller
+	// BinlogPrimaryController manages the binlog the server writes for MySQL replicas. It is nil if the server doesn't
+	// write a binlog.
+	BinlogPrimaryController *binlogreplication.PrimaryController
 }
 
 // NewSqlEngine returns a SqlEngine
@@ -86,6 +96,19 @@ func NewSqlEngine(
 		return nil, err
 	}
 
+	err = config.ClusterController.ApplyCommitHooks(ctx, mrEnv, mrEnv.RemoteDialProvider())
+	if err != nil {
+		return nil, err
+	}
+	err = config.ClusterController.ManageSystemVariables(sql.SystemVariables)
+	if err != nil {
+		return nil, err
+	}
+	err = config.BinlogPrimaryController.ApplyCommitHooks(ctx, mrEnv)
+	if err != nil {
+		return nil, err
+	}
+
 	infoDB := information_schema.NewInformationSchemaDatabase()
 	all := append(dsqleDBsAsSqlDBs(dbs), infoDB)
 	locations = append(locations, nil)
@@ -96,6 +119,11 @@ func NewSqlEngine(
 		return nil, err
 	}
 	pro = pro.WithRemoteDialer(mrEnv.RemoteDialProvider())
+	pro = config.ClusterController.ManageDatabaseProvider(pro)
+	pro = config.BinlogPrimaryController.ManageDatabaseProvider(pro)
+	config.ClusterController.RegisterStoredProcedures(pro)
+	config.BinlogReplicaController.RegisterStoredProcedures(pro)
+	config.BinlogPrimaryController.RegisterStoredProcedures(pro)
 
 	// Load in privileges from file, if it exists
 	persister := mysql_file_handler.NewPersister(config.PrivFilePath, config.DoltCfgDirPath)
@@ -107,6 +135,7 @@ func NewSqlEngine(
 	// Set up engine
 	engine := gms.New(analyzer.NewBuilder(pro).WithParallelism(parallelism).Build(), &gms.Config{IsReadOnly: config.IsReadOnly, IsServerLocked: config.IsServerLocked}).WithBackgroundThreads(bThreads)
 	engine.Analyzer.Catalog.MySQLDb.SetPersister(persister)
+	config.ClusterController.ManagePrivileges(engine.Analyzer.Catalog.MySQLDb)
 
 	engine.Analyzer.Catalog.MySQLDb.SetPlugins(map[string]mysql_db.PlaintextAuthPlugin{
 		"authentication_dolt_jwt": NewAuthenticateDoltJWTPlugin(config.JwksConfig),
@@ -34,10 +34,12 @@ var fetchDocs = cli.CommandDocumentationContent{
 By default dolt will attempt to fetch from a remote named {{.EmphasisLeft}}origin{{.EmphasisRight}}.  The {{.LessThan}}remote{{.GreaterThan}} parameter allows you to specify the name of a different remote you wish to pull from by the remote's name.
 
 When no refspec(s) are specified on the command line, the fetch_specs for the default remote are used.
+
+If the repository is a shallow clone, {{.EmphasisLeft}}--unshallow{{.EmphasisRight}} fetches the history below its shallow commits as well, which turns it into a complete clone.
 `,
 
 	Synopsis: []string{
-		"[{{.LessThan}}remote{{.GreaterThan}}] [{{.LessThan}}refspec{{.GreaterThan}} ...]",
+		"[--unshallow] [{{.LessThan}}remote{{.GreaterThan}}] [{{.LessThan}}refspec{{.GreaterThan}} ...]",
 	},
 }
 
@@ -59,17 +61,19 @@ func (cmd FetchCmd) EventType() eventsapi.ClientEventType {
 }
 
 func (cmd FetchCmd) Docs() *cli.CommandDocumentation {
-	ap := cli.CreateFetchArgParser()
+	ap := cmd.ArgParser()
 	return cli.NewCommandDocumentation(fetchDocs, ap)
 }
 
 func (cmd FetchCmd) ArgParser() *argparser.ArgParser {
-	return cli.CreateFetchArgParser()
+	ap := cli.CreateFetchArgParser()
+	ap.SupportsFlag(cli.UnshallowFlag, "", "Fetch the history below the shallow commits of a shallow clone.")
+	return ap
 }
 
 // Exec executes the command
 func (cmd FetchCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
-	ap := cli.CreateFetchArgParser()
+	ap := cmd.ArgParser()
 	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, fetchDocs, ap))
 	apr := cli.ParseArgsOrDie(ap, args, help)
 
@@ -84,10 +88,15 @@ func (cmd FetchCmd) Exec(ctx context.Context, commandStr string, args []string,
 		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(err), usage)
 	}
 
+	unshallow := apr.Contains(cli.UnshallowFlag)
+	if unshallow && !dEnv.DoltDB.IsShallow() {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: --unshallow on a complete repository does not make sense").Build(), usage)
+	}
+
 	err = actions.FetchRefSpecs(ctx, dEnv.DbData(), srcDB, refSpecs, r, updateMode, buildProgStarter(downloadLanguage), stopProgFuncs)
 	switch err {
 	case doltdb.ErrUpToDate:
-		return HandleVErrAndExitCode(nil, usage)
+		err = nil
 	case actions.ErrCantFF:
 		verr := errhand.BuildDError("error: fetch failed, can't fast forward remote tracking ref").AddCause(err).Build()
 		return HandleVErrAndExitCode(verr, usage)
@@ -95,5 +104,13 @@ func (cmd FetchCmd) Exec(ctx context.Context, commandStr string, args []string,
 	if err != nil {
 		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(err), usage)
 	}
+
+	if unshallow {
+		err = actions.FetchShallowHistory(ctx, dEnv.TempTableFilesDir(), srcDB, dEnv.DoltDB, buildProgStarter(downloadLanguage), stopProgFuncs)
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: failed to fetch the history of the shallow clone").AddCause(err).Build(), usage)
+		}
+	}
+
 	return HandleVErrAndExitCode(nil, usage)
 }
@@ -0,0 +1,272 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+const repairFromParam = "repair-from"
+
+var fsckDocs = cli.CommandDocumentationContent{
+	ShortDesc: "Checks the repository for missing or corrupt data.",
+	LongDesc: `Reads every chunk of data reachable from the branches, tags, remote branches and working sets of the repository and checks that it is present and that its contents match its hash. Table files that are missing or can not be opened are reported as well.
+
+For every missing or corrupt chunk, the commits, working sets and tables which depend on it are reported.
+
+If {{.EmphasisLeft}}--repair-from{{.EmphasisRight}} is supplied, the missing and corrupt chunks are fetched from {{.LessThan}}remote{{.GreaterThan}} and written to the repository, and table files which can not be opened are removed from the repository. The repository is checked again afterwards.`,
+	Synopsis: []string{
+		"[--repair-from {{.LessThan}}remote{{.GreaterThan}}]",
+	},
+}
+
+type FsckCmd struct{}
+
+// Name returns the name of the Dolt cli command. This is what is used on the command line to invoke the command
+func (cmd FsckCmd) Name() string {
+	return "fsck"
+}
+
+// Description returns a description of the command
+func (cmd FsckCmd) Description() string {
+	return fsckDocs.ShortDesc
+}
+
+// RequiresRepo should return false if this interface is implemented, and the command does not have the requirement
+// that it be run from within a data repository directory. fsck checks for a repository itself, so that it can run
+// on repositories whose database fails to load.
+func (cmd FsckCmd) RequiresRepo() bool {
+	return false
+}
+
+func (cmd FsckCmd) Docs() *cli.CommandDocumentation {
+	ap := cmd.ArgParser()
+	return cli.NewCommandDocumentation(fsckDocs, ap)
+}
+
+func (cmd FsckCmd) ArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsString(repairFromParam, "", "remote", "fetch missing and corrupt chunks from this remote")
+	return ap
+}
+
+// Exec executes the command
+func (cmd FsckCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cmd.ArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, fsckDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+
+	if apr.NArg() != 0 {
+		return HandleVErrAndExitCode(errhand.BuildDError("%s does not take positional arguments", cmd.Name()).Build(), usage)
+	}
+
+	if !dEnv.HasDoltDir() || !dEnv.HasDoltDataDir() {
+		cli.PrintErrln(color.RedString("The current directory is not a valid dolt repository."))
+		return 1
+	}
+
+	remoteName, repair := apr.GetValue(repairFromParam)
+	var remote env.Remote
+	if repair {
+		if dEnv.IsLocked() {
+			return HandleVErrAndExitCode(errhand.VerboseErrorFromError(env.ErrActiveServerLock.New(dEnv.LockFile())), help)
+		}
+
+		remotes, err := dEnv.GetRemotes()
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: failed to read remotes").AddCause(err).Build(), usage)
+		}
+
+		var ok bool
+		remote, ok = remotes[remoteName]
+		if !ok {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: unknown remote '%s'", remoteName).Build(), usage)
+		}
+	}
+
+	damagedFiles, err := checkTableFiles(ctx, dEnv)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to check table files").AddCause(err).Build(), usage)
+	}
+
+	for _, f := range damagedFiles {
+		cli.Println(color.RedString("damaged table file %s: %s", f.Name, f.Err.Error()))
+	}
+
+	ddb := dEnv.DoltDB
+	if len(damagedFiles) > 0 {
+		// read whatever is left of the database without the damaged table files
+		skip := make([]string, len(damagedFiles))
+		for i, f := range damagedFiles {
+			skip[i] = f.Name
+		}
+
+		params := map[string]interface{}{dbfactory.SkipTableFilesParam: skip}
+		ddb, err = doltdb.LoadDoltDBWithParams(ctx, types.Format_Default, doltdb.LocalDirDoltDB, dEnv.FS, params)
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: failed to load database").AddCause(err).Build(), usage)
+		}
+	} else if dEnv.DBLoadError != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to load database").AddCause(dEnv.DBLoadError).Build(), usage)
+	}
+
+	report, err := checkDatabase(ctx, ddb)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to check database").AddCause(err).Build(), usage)
+	}
+	printFsckReport(report)
+
+	damaged := report.Damaged()
+	if !repair || (len(damaged) == 0 && len(damagedFiles) == 0) {
+		if len(damaged) > 0 || len(damagedFiles) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	srcDB, err := remote.GetRemoteDBWithoutCaching(ctx, ddb.Format(), dEnv)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to load remote '%s'", remoteName).AddCause(err).Build(), usage)
+	}
+
+	// repairing damaged commits makes the chunks below them reachable again, which may turn up more damage. Repair
+	// until no new damage is found. The first round runs even without damaged chunks, it drops the damaged table files.
+	attempted := hash.NewHashSet()
+	repaired := 0
+	for round := 0; ; round++ {
+		toRepair := hash.NewHashSet()
+		for h := range damaged {
+			if !attempted.Has(h) {
+				toRepair.Insert(h)
+			}
+		}
+		if len(toRepair) == 0 && round > 0 {
+			break
+		}
+		attempted.InsertAll(toRepair)
+
+		unrepaired, err := ddb.RepairChunks(ctx, srcDB, toRepair)
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: failed to repair chunks").AddCause(err).Build(), usage)
+		}
+		repaired += len(toRepair) - len(unrepaired)
+		for _, h := range sortedHashes(unrepaired) {
+			cli.Println(color.YellowString("%s was not found on %s", h.String(), remoteName))
+		}
+
+		report, err = checkDatabase(ctx, ddb)
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.BuildDError("error: failed to check database").AddCause(err).Build(), usage)
+		}
+		damaged = report.Damaged()
+	}
+
+	cli.Printf("Repaired %s chunks from %s.\n", humanize.Comma(int64(repaired)), remoteName)
+	printFsckReport(report)
+
+	if len(damaged) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkTableFiles returns the table files of both generations of the local database which are missing or can't be
+// opened.
+func checkTableFiles(ctx context.Context, dEnv *env.DoltEnv) ([]nbs.DamagedTableFile, error) {
+	dir, err := dEnv.FS.Abs(dbfactory.DoltDataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	damaged, err := nbs.CheckLocalTableFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	oldgen := filepath.Join(dir, "oldgen")
+	if exists, isDir := dEnv.FS.Exists(oldgen); !exists || !isDir {
+		return damaged, nil
+	}
+
+	oldgenDamaged, err := nbs.CheckLocalTableFiles(ctx, oldgen)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(damaged, oldgenDamaged...), nil
+}
+
+// checkDatabase checks |ddb|, displaying its progress.
+func checkDatabase(ctx context.Context, ddb *doltdb.DoltDB) (*doltdb.FsckReport, error) {
+	p := cli.NewEphemeralPrinter()
+	report, err := ddb.Fsck(ctx, func(checked int) {
+		p.Printf("Checked %s chunks.", humanize.Comma(int64(checked)))
+		p.Display()
+	})
+	p.Display()
+	return report, err
+}
+
+func printFsckReport(report *doltdb.FsckReport) {
+	cli.Printf("Checked %s chunks: %s missing, %s corrupt.\n",
+		humanize.Comma(int64(report.ChunksChecked)),
+		humanize.Comma(int64(len(report.Missing))),
+		humanize.Comma(int64(len(report.Corrupt))))
+
+	for _, d := range report.Damage {
+		var header string
+		if d.Ref != "" {
+			header = d.Ref
+		} else {
+			header = "commit " + d.Commit.String()
+		}
+		if d.Table != "" {
+			header += ", table " + d.Table
+		}
+		cli.Println(color.YellowString(header))
+
+		for _, h := range sortedHashes(d.Chunks) {
+			if report.Missing.Has(h) {
+				cli.Println(color.RedString("\tmissing chunk %s", h.String()))
+			} else {
+				cli.Println(color.RedString("\tcorrupt chunk %s", h.String()))
+			}
+		}
+	}
+}
+
+func sortedHashes(hs hash.HashSet) hash.HashSlice {
+	hashes := make(hash.HashSlice, 0, len(hs))
+	for h := range hs {
+		hashes = append(hashes, h)
+	}
+	sort.Sort(hashes)
+	return hashes
+}
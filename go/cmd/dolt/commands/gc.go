@@ -17,6 +17,8 @@ package commands
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 
 	"github.com/fatih/color"
 
@@ -33,16 +35,23 @@ import (
 )
 
 const (
-	gcShallowFlag = "shallow"
+	gcZstdFlag               = "zstd"
+	gcRemoveChunkJournalFlag = "remove-chunk-journal"
 )
 
 var gcDocs = cli.CommandDocumentationContent{
 	ShortDesc: "Cleans up unreferenced data from the repository.",
 	LongDesc: `Searches the repository for data that is no longer referenced and no longer needed.
 
-If the {{.EmphasisLeft}}--shallow{{.EmphasisRight}} flag is supplied, a faster but less thorough garbage collection will be performed.`,
+If the {{.EmphasisLeft}}--shallow{{.EmphasisRight}} flag is supplied, a faster but less thorough garbage collection will be performed.
+
+Garbage collection can also be run against a running sql-server with {{.EmphasisLeft}}CALL dolt_gc(){{.EmphasisRight}}. Writes to the database block while the collection finishes. The data held by the sessions of the server is kept, including the uncommitted changes and the snapshots read by their open transactions.
+
+If the {{.EmphasisLeft}}--zstd{{.EmphasisRight}} flag is supplied, table files are then rewritten to be compressed with zstd, using a dictionary trained on the chunks of each table file. This usually makes the repository substantially smaller. Later garbage collections keep the table files of such a repository compressed with zstd.
+
+If the {{.EmphasisLeft}}--remove-chunk-journal{{.EmphasisRight}} flag is supplied, the chunks of the chunk journal of the repository are first written to a table file, and the journal is removed. Repositories with a chunk journal keep using it until it's removed this way, even when {{.EmphasisLeft}}DOLT_ENABLE_CHUNK_JOURNAL{{.EmphasisRight}} is not set.`,
 	Synopsis: []string{
-		"[--shallow]",
+		"[--shallow] [--zstd] [--remove-chunk-journal]",
 	},
 }
 
@@ -75,8 +84,9 @@ func (cmd GarbageCollectionCmd) Docs() *cli.CommandDocumentation {
 }
 
 func (cmd GarbageCollectionCmd) ArgParser() *argparser.ArgParser {
-	ap := argparser.NewArgParser()
-	ap.SupportsFlag(gcShallowFlag, "s", "perform a fast, but incomplete garbage collection pass")
+	ap := cli.CreateGCArgParser()
+	ap.SupportsFlag(gcZstdFlag, "", "rewrite table files to be compressed with zstd dictionaries")
+	ap.SupportsFlag(gcRemoveChunkJournalFlag, "", "write the chunks of the chunk journal to a table file and remove the journal")
 	return ap
 }
 
@@ -99,7 +109,15 @@ func (cmd GarbageCollectionCmd) Exec(ctx context.Context, commandStr string, arg
 	}
 
 	var err error
-	if apr.Contains(gcShallowFlag) {
+	if apr.Contains(gcRemoveChunkJournalFlag) {
+		dEnv, err = removeChunkJournal(ctx, dEnv)
+		if err != nil {
+			verr = errhand.BuildDError("could not remove the chunk journal").AddCause(err).Build()
+			return HandleVErrAndExitCode(verr, usage)
+		}
+	}
+
+	if apr.Contains(cli.ShallowFlag) {
 		err = dEnv.DoltDB.ShallowGC(ctx)
 		if err != nil {
 			if err == chunks.ErrUnsupportedOperation {
@@ -133,9 +151,50 @@ func (cmd GarbageCollectionCmd) Exec(ctx context.Context, commandStr string, arg
 		}
 	}
 
+	if verr == nil && apr.Contains(gcZstdFlag) {
+		err = dEnv.DoltDB.CompressTableFiles(ctx)
+		if err == chunks.ErrUnsupportedOperation {
+			verr = errhand.BuildDError("this database does not support zstd table files").Build()
+		} else if err != nil {
+			verr = errhand.BuildDError("an error occurred while compressing table files").AddCause(err).Build()
+		}
+	}
+
 	return HandleVErrAndExitCode(verr, usage)
 }
 
+// removeChunkJournal turns the database of |dEnv| back into one without a chunk journal, and returns the environment
+// reloaded with the converted database.
+func removeChunkJournal(ctx context.Context, dEnv *env.DoltEnv) (*env.DoltEnv, error) {
+	if os.Getenv(dbfactory.ChunkJournalEnvVar) != "" {
+		return nil, fmt.Errorf("%s must not be set", dbfactory.ChunkJournalEnvVar)
+	}
+	exists, err := nbs.ChunkJournalExists(dbfactory.DoltDataDir)
+	if err != nil || !exists {
+		return dEnv, err
+	}
+
+	// the journal is held by the database until it's closed
+	if err = dEnv.DoltDB.Close(); err != nil {
+		return nil, err
+	}
+	if err = nbs.RemoveChunkJournal(ctx, dbfactory.DoltDataDir, nbs.NewUnlimitedMemQuotaProvider()); err != nil {
+		return nil, err
+	}
+
+	tmp := env.Load(ctx, env.GetCurrentUserHomeDir, filesys.LocalFS, doltdb.LocalDirDoltDB, dEnv.Version)
+	if tmp.CfgLoadErr != nil {
+		return nil, tmp.CfgLoadErr
+	}
+	if tmp.RSLoadErr != nil {
+		return nil, tmp.RSLoadErr
+	}
+	if tmp.DBLoadError != nil {
+		return nil, tmp.DBLoadError
+	}
+	return tmp, nil
+}
+
 func MaybeMigrateEnv(ctx context.Context, dEnv *env.DoltEnv) (*env.DoltEnv, error) {
 	migrated, err := nbs.MaybeMigrateFileManifest(ctx, dbfactory.DoltDataDir)
 	if err != nil {
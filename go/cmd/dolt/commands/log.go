@@ -35,12 +35,9 @@ import (
 )
 
 const (
-	numLinesParam   = "number"
-	mergesParam     = "merges"
-	minParentsParam = "min-parents"
-	parentsParam    = "parents"
-	decorateParam   = "decorate"
-	oneLineParam    = "oneline"
+	parentsParam  = "parents"
+	decorateParam = "decorate"
+	oneLineParam  = "oneline"
 )
 
 type logOpts struct {
@@ -93,9 +90,9 @@ func (cmd LogCmd) Docs() *cli.CommandDocumentation {
 
 func (cmd LogCmd) ArgParser() *argparser.ArgParser {
 	ap := argparser.NewArgParser()
-	ap.SupportsInt(numLinesParam, "n", "num_commits", "Limit the number of commits to output.")
-	ap.SupportsInt(minParentsParam, "", "parent_count", "The minimum number of parents a commit must have to be included in the log.")
-	ap.SupportsFlag(mergesParam, "", "Equivalent to min-parents == 2, this will limit the log to commits with 2 or more parents.")
+	ap.SupportsInt(cli.NumberFlag, "n", "num_commits", "Limit the number of commits to output.")
+	ap.SupportsInt(cli.MinParentsFlag, "", "parent_count", "The minimum number of parents a commit must have to be included in the log.")
+	ap.SupportsFlag(cli.MergesFlag, "", "Equivalent to min-parents == 2, this will limit the log to commits with 2 or more parents.")
 	ap.SupportsFlag(parentsParam, "", "Shows all parents of each commit in the log.")
 	ap.SupportsString(decorateParam, "", "decorate_fmt", "Shows refs next to commits. Valid options are short, full, no, and auto")
 	ap.SupportsFlag(oneLineParam, "", "Shows logs in a compact format.")
@@ -117,8 +114,8 @@ func (cmd LogCmd) logWithLoggerFunc(ctx context.Context, commandStr string, args
 		return 1
 	}
 
-	minParents := apr.GetIntOrDefault(minParentsParam, 0)
-	if apr.Contains(mergesParam) {
+	minParents := apr.GetIntOrDefault(cli.MinParentsFlag, 0)
+	if apr.Contains(cli.MergesFlag) {
 		minParents = 2
 	}
 
@@ -130,7 +127,7 @@ func (cmd LogCmd) logWithLoggerFunc(ctx context.Context, commandStr string, args
 		return 1
 	}
 	opts := logOpts{
-		numLines:    apr.GetIntOrDefault(numLinesParam, -1),
+		numLines:    apr.GetIntOrDefault(cli.NumberFlag, -1),
 		showParents: apr.Contains(parentsParam),
 		minParents:  minParents,
 		oneLine:     apr.Contains(oneLineParam),
@@ -0,0 +1,262 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	goisatty "github.com/mattn/go-isatty"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/rebase"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	texteditor "github.com/dolthub/dolt/go/libraries/utils/editor"
+)
+
+var rebaseDocs = cli.CommandDocumentationContent{
+	ShortDesc: `Reapply commits on top of another base commit.`,
+	LongDesc: `
+Replays the commits on the current branch that are not reachable from {{.LessThan}}upstream{{.GreaterThan}} on top of {{.LessThan}}upstream{{.GreaterThan}}, one at a time, and moves the current branch to the result. Merge commits are not replayed. The working set must be clean to start a rebase.
+
+With {{.EmphasisLeft}}--interactive{{.EmphasisRight}}, an editor is opened with the rebase plan: one line per commit, oldest first, each starting with an action. The lines can be reordered, and the action of each commit can be changed to one of:
+
+	pick   - use the commit
+	reword - use the commit, replacing its message with the rest of the line
+	squash - meld the commit into the previous commit, combining both messages
+	fixup  - meld the commit into the previous commit, keeping the previous commit's message
+	drop   - remove the commit
+
+If the plan is emptied, the rebase is aborted. If the editor can't be opened, the plan is left in the {{.EmphasisLeft}}dolt_rebase{{.EmphasisRight}} system table, where it can be edited with SQL before running {{.EmphasisLeft}}dolt rebase --continue{{.EmphasisRight}}.
+
+If replaying a commit results in conflicts or constraint violations, the rebase stops with the conflicted changes in the working set. Resolve them, then run {{.EmphasisLeft}}dolt rebase --continue{{.EmphasisRight}}, or run {{.EmphasisLeft}}dolt rebase --abort{{.EmphasisRight}} to return the branch to where it was before the rebase started.
+`,
+	Synopsis: []string{
+		`[-i] {{.LessThan}}upstream{{.GreaterThan}}`,
+		`--continue`,
+		`--abort`,
+	},
+}
+
+type RebaseCmd struct{}
+
+// Name returns the name of the Dolt cli command. This is what is used on the command line to invoke the command.
+func (cmd RebaseCmd) Name() string {
+	return "rebase"
+}
+
+// Description returns a description of the command.
+func (cmd RebaseCmd) Description() string {
+	return "Reapply commits on top of another base commit."
+}
+
+func (cmd RebaseCmd) Docs() *cli.CommandDocumentation {
+	ap := cli.CreateRebaseArgParser()
+	return cli.NewCommandDocumentation(rebaseDocs, ap)
+}
+
+func (cmd RebaseCmd) ArgParser() *argparser.ArgParser {
+	return cli.CreateRebaseArgParser()
+}
+
+// Exec executes the command.
+func (cmd RebaseCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cli.CreateRebaseArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, rebaseDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+	if dEnv.IsLocked() {
+		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(env.ErrActiveServerLock.New(dEnv.LockFile())), help)
+	}
+
+	if apr.Contains(cli.AbortParam) {
+		if apr.Contains(cli.ContinueFlag) || apr.NArg() > 0 {
+			usage()
+			return 1
+		}
+		return HandleVErrAndExitCode(abortRebase(ctx, dEnv), usage)
+	}
+
+	// This command creates commits, so we need user identity
+	if !cli.CheckUserNameAndEmail(dEnv) {
+		return 1
+	}
+
+	if apr.Contains(cli.ContinueFlag) {
+		if apr.Contains(cli.InteractiveFlag) || apr.NArg() > 0 {
+			usage()
+			return 1
+		}
+		return HandleVErrAndExitCode(continueRebase(ctx, dEnv), usage)
+	}
+
+	if apr.NArg() != 1 {
+		usage()
+		return 1
+	}
+
+	verr := startRebase(ctx, dEnv, apr.Arg(0), apr.Contains(cli.InteractiveFlag))
+	return HandleVErrAndExitCode(verr, usage)
+}
+
+func startRebase(ctx context.Context, dEnv *env.DoltEnv, upstreamStr string, interactive bool) errhand.VerboseError {
+	cs, err := doltdb.NewCommitSpec(upstreamStr)
+	if err != nil {
+		return errhand.BuildDError("error: invalid upstream '%s'", upstreamStr).AddCause(err).Build()
+	}
+	headRef := dEnv.RepoStateReader().CWBHeadRef()
+	upstream, err := dEnv.DoltDB.Resolve(ctx, cs, headRef)
+	if err != nil {
+		return errhand.BuildDError("error: could not resolve upstream '%s'", upstreamStr).AddCause(err).Build()
+	}
+
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	ws, heads, err := rebase.Start(ctx, dEnv.DoltDB, headRef, ws, upstream)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	err = dEnv.UpdateWorkingSetAndHeads(ctx, ws, heads, nil)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	if interactive {
+		opened, aborted, verr := editRebasePlan(ctx, dEnv)
+		if verr != nil || aborted {
+			return verr
+		}
+		if !opened {
+			cli.Println("The rebase plan is in the dolt_rebase table. Edit it, then run 'dolt rebase --continue'.")
+			return nil
+		}
+	}
+
+	return continueRebase(ctx, dEnv)
+}
+
+// editRebasePlan opens the rebase plan in the user's editor and writes the result back to the dolt_rebase table.
+// Returns whether the editor could be opened, and whether the rebase was aborted because the edited plan was empty.
+func editRebasePlan(ctx context.Context, dEnv *env.DoltEnv) (opened bool, aborted bool, verr errhand.VerboseError) {
+	if cli.ExecuteWithStdioRestored == nil {
+		return false, false, nil
+	}
+	isTerminal := false
+	cli.ExecuteWithStdioRestored(func() {
+		if goisatty.IsTerminal(os.Stdout.Fd()) {
+			isTerminal = true
+		}
+	})
+	if !isTerminal {
+		return false, false, nil
+	}
+
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return false, false, errhand.VerboseErrorFromError(err)
+	}
+	plan, err := rebase.ReadPlan(ctx, ws.WorkingRoot())
+	if err != nil {
+		return false, false, errhand.VerboseErrorFromError(err)
+	}
+
+	backupEd := "vim"
+	if ed, edSet := os.LookupEnv("EDITOR"); edSet {
+		backupEd = ed
+	}
+	editorStr := dEnv.Config.GetStringOrDefault(env.DoltEditor, backupEd)
+
+	var contents string
+	cli.ExecuteWithStdioRestored(func() {
+		contents, err = texteditor.OpenCommitEditor(editorStr, rebase.FormatPlan(plan))
+	})
+	if err != nil {
+		return false, false, errhand.BuildDError("error: failed to open editor").AddCause(err).Build()
+	}
+
+	edited, err := rebase.ParsePlan(contents, plan)
+	if err != nil {
+		return false, false, errhand.VerboseErrorFromError(err)
+	}
+	if len(edited.Steps) == 0 {
+		cli.Println("Empty rebase plan, aborting.")
+		return true, true, abortRebase(ctx, dEnv)
+	}
+
+	root, err := rebase.WritePlan(ctx, ws.WorkingRoot(), edited)
+	if err != nil {
+		return false, false, errhand.VerboseErrorFromError(err)
+	}
+	err = dEnv.UpdateWorkingSet(ctx, ws.WithWorkingRoot(root))
+	if err != nil {
+		return false, false, errhand.VerboseErrorFromError(err)
+	}
+
+	return true, false, nil
+}
+
+func continueRebase(ctx context.Context, dEnv *env.DoltEnv) errhand.VerboseError {
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	headRef := dEnv.RepoStateReader().CWBHeadRef()
+
+	opts := editor.Options{Deaf: dEnv.BulkDbEaFactory(), Tempdir: dEnv.TempTableFilesDir()}
+	ws, heads, stoppedAt, err := rebase.Continue(ctx, dEnv.DoltDB, headRef, ws, opts)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	err = dEnv.UpdateWorkingSetAndHeads(ctx, ws, heads, nil)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	if stoppedAt != nil {
+		msg := stoppedAt.CommitMsg
+		if i := strings.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i]
+		}
+		return errhand.BuildDError("error: could not apply %s... %s\n"+
+			"hint: resolve all conflicts and constraint violations, then run 'dolt rebase --continue'.\n"+
+			"hint: to return the branch to its original state, run 'dolt rebase --abort'.", stoppedAt.CommitHash, msg).Build()
+	}
+
+	cli.Println("Successfully rebased and updated " + headRef.String() + ".")
+	return nil
+}
+
+// abortRebase returns the current branch to where it was before the rebase started.
+func abortRebase(ctx context.Context, dEnv *env.DoltEnv) errhand.VerboseError {
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	ws, heads, err := rebase.Abort(ctx, dEnv.DoltDB, dEnv.RepoStateReader().CWBHeadRef(), ws)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	err = dEnv.UpdateWorkingSetAndHeads(ctx, ws, heads, nil)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	return nil
+}
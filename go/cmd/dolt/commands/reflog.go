@@ -0,0 +1,105 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+)
+
+var reflogDocs = cli.CommandDocumentationContent{
+	ShortDesc: `Show the history of ref updates.`,
+	LongDesc: `Shows the commits that branches, tags and remote tracking branches pointed to over time, most recent first. Every update of a ref, such as a commit, a reset, a merge or a push, is recorded in the reflog of the database.
+
+This can be used to find a commit that is no longer reachable from any branch, e.g. after a {{.EmphasisLeft}}dolt reset --hard{{.EmphasisRight}} or a forced push, and to check it out again with {{.EmphasisLeft}}dolt checkout -b {{.LessThan}}branch{{.GreaterThan}} {{.LessThan}}commit{{.GreaterThan}}{{.EmphasisRight}}. This only works as long as the commit has not been garbage collected.
+
+If {{.LessThan}}ref{{.GreaterThan}} is given, only the updates of that ref are shown. With {{.EmphasisLeft}}--all{{.EmphasisRight}}, updates of working sets and internal refs are shown as well.
+
+The reflog is compacted once it grows larger than 4MB. Compaction keeps updates of branches, tags and remote tracking branches for 90 days, and updates of working sets for 7 days. If the reflog is still too large, the oldest updates are dropped.`,
+	Synopsis: []string{
+		`[--all] [{{.LessThan}}ref{{.GreaterThan}}]`,
+	},
+}
+
+type ReflogCmd struct{}
+
+// Name returns the name of the Dolt cli command. This is what is used on the command line to invoke the command
+func (cmd ReflogCmd) Name() string {
+	return "reflog"
+}
+
+// Description returns a description of the command
+func (cmd ReflogCmd) Description() string {
+	return reflogDocs.ShortDesc
+}
+
+func (cmd ReflogCmd) Docs() *cli.CommandDocumentation {
+	ap := cmd.ArgParser()
+	return cli.NewCommandDocumentation(reflogDocs, ap)
+}
+
+func (cmd ReflogCmd) ArgParser() *argparser.ArgParser {
+	return cli.CreateReflogArgParser()
+}
+
+// Exec executes the command
+func (cmd ReflogCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cmd.ArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, reflogDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+
+	if apr.NArg() > 1 {
+		verr := errhand.BuildDError("%s takes at most 1 arg", cmd.Name()).Build()
+		return HandleVErrAndExitCode(verr, usage)
+	}
+
+	refName := ""
+	if apr.NArg() == 1 {
+		refName = apr.Arg(0)
+	}
+
+	entries, err := dEnv.DoltDB.ReadRefLog(ctx, refName, apr.Contains(cli.AllFlag))
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to read the reflog").AddCause(err).Build(), usage)
+	}
+
+	for _, e := range entries {
+		line := fmt.Sprintf("%s (%s)", color.YellowString(e.Hash.String()), color.CyanString(e.Ref))
+
+		cm, err := dEnv.DoltDB.ResolveRefLogEntry(ctx, e)
+		if err != nil {
+			return HandleVErrAndExitCode(errhand.VerboseErrorFromError(err), usage)
+		}
+		if cm != nil {
+			meta, err := cm.GetCommitMeta(ctx)
+			if err != nil {
+				return HandleVErrAndExitCode(errhand.VerboseErrorFromError(err), usage)
+			}
+			line += " " + strings.SplitN(meta.Description, "\n", 2)[0]
+		}
+
+		cli.Println(line)
+	}
+
+	return 0
+}
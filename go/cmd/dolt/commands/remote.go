@@ -37,7 +37,7 @@ var remoteDocs = cli.CommandDocumentationContent{
 {{.EmphasisLeft}}add{{.EmphasisRight}}
 Adds a remote named {{.LessThan}}name{{.GreaterThan}} for the repository at {{.LessThan}}url{{.GreaterThan}}. The command dolt fetch {{.LessThan}}name{{.GreaterThan}} can then be used to create and update remote-tracking branches {{.EmphasisLeft}}<name>/<branch>{{.EmphasisRight}}.
 
-The {{.LessThan}}url{{.GreaterThan}} parameter supports url schemes of http, https, aws, gs, and file. The url prefix defaults to https. If the {{.LessThan}}url{{.GreaterThan}} parameter is in the format {{.EmphasisLeft}}<organization>/<repository>{{.EmphasisRight}} then dolt will use the {{.EmphasisLeft}}remotes.default_host{{.EmphasisRight}} from your configuration file (Which will be dolthub.com unless changed).
+The {{.LessThan}}url{{.GreaterThan}} parameter supports url schemes of http, https, aws, s3, gs, and file. The url prefix defaults to https. If the {{.LessThan}}url{{.GreaterThan}} parameter is in the format {{.EmphasisLeft}}<organization>/<repository>{{.EmphasisRight}} then dolt will use the {{.EmphasisLeft}}remotes.default_host{{.EmphasisRight}} from your configuration file (Which will be dolthub.com unless changed).
 
 AWS cloud remote urls should be of the form {{.EmphasisLeft}}aws://[dynamo-table:s3-bucket]/database{{.EmphasisRight}}.  You may configure your aws cloud remote using the optional parameters {{.EmphasisLeft}}aws-region{{.EmphasisRight}}, {{.EmphasisLeft}}aws-creds-type{{.EmphasisRight}}, {{.EmphasisLeft}}aws-creds-file{{.EmphasisRight}}.
 
@@ -47,6 +47,8 @@ aws-creds-type specifies the means by which credentials should be retrieved in o
 	env: Looks for environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
 	file: Uses the credentials file specified by the parameter aws-creds-file
 	
+S3 remote urls should be of the form {{.EmphasisLeft}}s3://s3-bucket/database{{.EmphasisRight}}. Unlike aws remotes they keep the manifest of the database in the bucket rather than in a dynamo table, so they also work with S3 compatible services like MinIO, whose url is given by the optional parameter {{.EmphasisLeft}}aws-endpoint{{.EmphasisRight}}. They take the same optional parameters as aws remotes, and the service must support conditional writes.
+
 GCP remote urls should be of the form gs://gcs-bucket/database and will use the credentials setup using the gcloud command line available from Google.
 
 The local filesystem can be used as a remote by providing a repository url in the format file://absolute path. See https://en.wikipedia.org/wiki/File_URI_scheme
@@ -56,7 +58,7 @@ Remove the remote named {{.LessThan}}name{{.GreaterThan}}. All remote-tracking b
 
 	Synopsis: []string{
 		"[-v | --verbose]",
-		"add [--aws-region {{.LessThan}}region{{.GreaterThan}}] [--aws-creds-type {{.LessThan}}creds-type{{.GreaterThan}}] [--aws-creds-file {{.LessThan}}file{{.GreaterThan}}] [--aws-creds-profile {{.LessThan}}profile{{.GreaterThan}}] {{.LessThan}}name{{.GreaterThan}} {{.LessThan}}url{{.GreaterThan}}",
+		"add [--aws-region {{.LessThan}}region{{.GreaterThan}}] [--aws-creds-type {{.LessThan}}creds-type{{.GreaterThan}}] [--aws-creds-file {{.LessThan}}file{{.GreaterThan}}] [--aws-creds-profile {{.LessThan}}profile{{.GreaterThan}}] [--aws-endpoint {{.LessThan}}endpoint{{.GreaterThan}}] {{.LessThan}}name{{.GreaterThan}} {{.LessThan}}url{{.GreaterThan}}",
 		"remove {{.LessThan}}name{{.GreaterThan}}",
 	},
 }
@@ -89,11 +91,13 @@ func (cmd RemoteCmd) ArgParser() *argparser.ArgParser {
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"region", "cloud provider region associated with this remote."})
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"creds-type", "credential type.  Valid options are role, env, and file.  See the help section for additional details."})
 	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"profile", "AWS profile to use."})
+	ap.ArgListHelp = append(ap.ArgListHelp, [2]string{"endpoint", "endpoint of the S3 compatible service of an s3 remote."})
 	ap.SupportsFlag(verboseFlag, "v", "When printing the list of remotes adds additional details.")
 	ap.SupportsString(dbfactory.AWSRegionParam, "", "region", "")
 	ap.SupportsValidatedString(dbfactory.AWSCredsTypeParam, "", "creds-type", "", argparser.ValidatorFromStrList(dbfactory.AWSCredsTypeParam, dbfactory.AWSCredTypes))
 	ap.SupportsString(dbfactory.AWSCredsFileParam, "", "file", "AWS credentials file")
 	ap.SupportsString(dbfactory.AWSCredsProfile, "", "profile", "AWS profile to use")
+	ap.SupportsString(dbfactory.AWSEndpointParam, "", "endpoint", "Endpoint of the S3 compatible service of an s3 remote.")
 	return ap
 }
 
@@ -145,6 +149,8 @@ func removeRemote(ctx context.Context, dEnv *env.DoltEnv, apr *argparser.ArgPars
 		return errhand.BuildDError("error: failed to read from db").AddCause(err).Build()
 	case env.ErrRemoteNotFound:
 		return errhand.BuildDError("error: unknown remote: '%s' ", old).Build()
+	case env.ErrCannotRemoveLazyRemote:
+		return errhand.BuildDError("error: %s '%s'", err.Error(), old).Build()
 	default:
 		return errhand.BuildDError("error: unknown error").AddCause(err).Build()
 	}
@@ -191,7 +197,7 @@ func parseRemoteArgs(apr *argparser.ArgParseResults, scheme, remoteUrl string) (
 	params := map[string]string{}
 
 	var err error
-	if scheme == dbfactory.AWSScheme {
+	if scheme == dbfactory.AWSScheme || scheme == dbfactory.S3Scheme {
 		err = cli.AddAWSParams(remoteUrl, apr, params)
 	} else {
 		err = cli.VerifyNoAwsParams(apr)
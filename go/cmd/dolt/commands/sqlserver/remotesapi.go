@@ -0,0 +1,147 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/commands/engine"
+	"github.com/dolthub/dolt/go/libraries/doltcore/creds"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/cluster"
+)
+
+// startRemotesapiServer starts serving the databases of |sqlEngine| as remotes on |port|, authenticating clients as
+// the SQL users of the engine.
+func startRemotesapiServer(sqlEngine *engine.SqlEngine, port int) (*remotesrv.Server, error) {
+	return startRemoteSrv(remotesrv.ServerArgs{
+		Logger:     logrus.NewEntry(logrus.StandardLogger()).WithField("component", "dolt.remotesapi"),
+		HttpPort:   port,
+		GrpcPort:   port,
+		DBCache:    sqle.NewRemoteSrvDBCache(sqlEngine.NewContext),
+		Authorizer: sqlUserAuthorizer{sqlEngine.GetUnderlyingEngine().Analyzer.Catalog.MySQLDb},
+	})
+}
+
+// startClusterRemotesapiServer starts the remotesapi server which the other servers of the cluster managed by
+// |controller| replicate to.
+func startClusterRemotesapiServer(sqlEngine *engine.SqlEngine, controller *cluster.Controller) (*remotesrv.Server, error) {
+	return startRemoteSrv(controller.RemoteSrvServerArgs(sqlEngine.NewContext))
+}
+
+func startRemoteSrv(args remotesrv.ServerArgs) (*remotesrv.Server, error) {
+	srv, err := remotesrv.NewServer(args)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners, err := srv.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("error starting remotesapi server on port %d: %w", args.HttpPort, err)
+	}
+
+	go srv.Serve(listeners)
+
+	return srv, nil
+}
+
+// sqlUserAuthorizer authorizes remotesapi requests with the privileges of the SQL user whose name and password are
+// sent in a basic authorization header. Reading a database requires SELECT on it, and writing to it requires INSERT,
+// UPDATE and DELETE. A server without any SQL users can't authenticate clients, so its databases can be read by
+// anyone and written by no one.
+type sqlUserAuthorizer struct {
+	mysqlDb *mysql_db.MySQLDb
+}
+
+var _ remotesrv.Authorizer = sqlUserAuthorizer{}
+
+func (a sqlUserAuthorizer) Authorize(ctx context.Context, org, repo string, write bool) error {
+	if !a.mysqlDb.Enabled {
+		if write {
+			return status.Error(codes.PermissionDenied, "pushes are not allowed to a server without SQL users")
+		}
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := md.Get("authorization")
+	if len(authorization) == 0 {
+		return status.Error(codes.Unauthenticated, "a user name and password are required")
+	}
+
+	bc, ok := creds.ParseBasicAuthorization(authorization[0])
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid authorization header, expected basic authorization")
+	}
+
+	host := "localhost"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr.Network() != "unix" {
+		if h, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			host = h
+		}
+	}
+
+	user := a.mysqlDb.GetUser(bc.User, host, false)
+	if user == nil || user.Locked || !isNativePasswordUser(user) || !passwordMatches(user.Password, bc.Password) {
+		return status.Errorf(codes.Unauthenticated, "access denied for user '%s'", bc.User)
+	}
+
+	privs := []sql.PrivilegeType{sql.PrivilegeType_Select}
+	if write {
+		privs = []sql.PrivilegeType{sql.PrivilegeType_Insert, sql.PrivilegeType_Update, sql.PrivilegeType_Delete}
+	}
+
+	sqlCtx := sql.NewContext(ctx, sql.WithSession(sql.NewBaseSessionWithClientServer("", sql.Client{User: bc.User, Address: host}, 0)))
+	if !a.mysqlDb.UserHasPrivileges(sqlCtx, sql.NewPrivilegedOperation(repo, "", "", privs...)) {
+		return status.Errorf(codes.PermissionDenied, "user '%s' does not have the privileges required for this operation on database '%s'", bc.User, repo)
+	}
+
+	return nil
+}
+
+// isNativePasswordUser returns whether |user| authenticates with a password stored in the user table, rather than
+// with an authentication plugin.
+func isNativePasswordUser(user *mysql_db.User) bool {
+	return user.Plugin == "" || user.Plugin == "mysql_native_password"
+}
+
+// passwordMatches returns whether |password| matches |hashed|, a password stored in the user table in the format of
+// mysql_native_password. An empty |hashed| means the user has no password.
+func passwordMatches(hashed, password string) bool {
+	if hashed == "" || password == "" {
+		return hashed == password
+	}
+
+	s1 := sha1.Sum([]byte(password))
+	s2 := sha1.Sum(s1[:])
+	expected := "*" + strings.ToUpper(hex.EncodeToString(s2[:]))
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(hashed)) == 1
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/creds"
+)
+
+func authorizedContext(t *testing.T, user, password string) context.Context {
+	md, err := creds.BasicCreds{User: user, Password: password}.GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+	return metadata.NewIncomingContext(context.Background(), metadata.New(md))
+}
+
+func TestSqlUserAuthorizer(t *testing.T) {
+	// without SQL users, anyone can read and no one can write
+	a := sqlUserAuthorizer{mysql_db.CreateEmptyMySQLDb()}
+	assert.NoError(t, a.Authorize(context.Background(), "", "db", false))
+	err := a.Authorize(context.Background(), "", "db", true)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = a.Authorize(authorizedContext(t, "root", ""), "", "db", true)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	mysqlDb := mysql_db.CreateEmptyMySQLDb()
+	mysqlDb.AddSuperUser("root", "localhost", "pass")
+	a = sqlUserAuthorizer{mysqlDb}
+	err = a.Authorize(context.Background(), "", "db", false)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	err = a.Authorize(authorizedContext(t, "root", "wrong"), "", "db", false)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.NoError(t, a.Authorize(authorizedContext(t, "root", "pass"), "", "db", true))
+}
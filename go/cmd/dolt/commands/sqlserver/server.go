@@ -16,9 +16,11 @@ package sqlserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"time"
@@ -32,8 +34,15 @@ import (
 	"github.com/dolthub/dolt/go/cmd/dolt/cli"
 	"github.com/dolthub/dolt/go/cmd/dolt/commands/engine"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/binlogreplication"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/cluster"
 	_ "github.com/dolthub/dolt/go/libraries/doltcore/sqle/dfunctions"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/nbs"
 )
 
 // Serve starts a MySQL-compatible server. Returns any errors that were encountered.
@@ -120,6 +129,10 @@ func Serve(
 		}
 	}
 
+	if startError = setConjoinPolicy(mrEnv, serverConfig.ConjoinPolicy()); startError != nil {
+		return
+	}
+
 	serverConf, sErr, cErr := getConfigFromServerConfig(serverConfig)
 	if cErr != nil {
 		return nil, cErr
@@ -127,6 +140,20 @@ func Serve(
 		return sErr, nil
 	}
 
+	clusterController, err := newClusterController(lgr, dEnv.FS, serverConfig)
+	if err != nil {
+		return err, nil
+	}
+	binlogReplicaController, err := newBinlogReplicaController(lgr, dEnv.FS, serverConfig)
+	if err != nil {
+		return err, nil
+	}
+	binlogPrimaryController, err := newBinlogPrimaryController(lgr, dEnv.FS, serverConfig)
+	if err != nil {
+		return err, nil
+	}
+	defer binlogPrimaryController.Close()
+
 	// Create SQL Engine with users
 	config := &engine.SqlEngineConfig{
 		InitialDb:      "",
@@ -138,6 +165,10 @@ func Serve(
 		ServerHost:     serverConfig.Host(),
 		Autocommit:     serverConfig.AutoCommit(),
 		JwksConfig:     serverConfig.JwksConfig(),
+
+		ClusterController:       clusterController,
+		BinlogReplicaController: binlogReplicaController,
+		BinlogPrimaryController: binlogPrimaryController,
 	}
 	sqlEngine, err := engine.NewSqlEngine(
 		ctx,
@@ -172,7 +203,7 @@ func Serve(
 	}
 	defer listener.Close()
 
-	mySQLServer, startError = server.NewServer(
+	mySQLServer, startError = binlogPrimaryController.NewServer(
 		serverConf,
 		sqlEngine.GetUnderlyingEngine(),
 		newSessionBuilder(sqlEngine, serverConfig),
@@ -210,10 +241,46 @@ func Serve(
 		return
 	}
 
+	var remoteSrv *remotesrv.Server
+	if port := serverConfig.RemotesapiPort(); port != nil {
+		remoteSrv, startError = startRemotesapiServer(sqlEngine, *port)
+		if startError != nil {
+			cli.PrintErr(startError)
+			if err := mrEnv.Unlock(); err != nil {
+				cli.PrintErr(err)
+			}
+			return
+		}
+	}
+
+	var clusterRemoteSrv *remotesrv.Server
+	if clusterController != nil {
+		clusterRemoteSrv, startError = startClusterRemotesapiServer(sqlEngine, clusterController)
+		if startError != nil {
+			cli.PrintErr(startError)
+			if remoteSrv != nil {
+				remoteSrv.GracefulStop()
+			}
+			if err := mrEnv.Unlock(); err != nil {
+				cli.PrintErr(err)
+			}
+			return
+		}
+	}
+
+	binlogReplicaController.Init(sqlEngine.GetUnderlyingEngine(), newBinlogReplicaSessionFactory(sqlEngine))
+	defer binlogReplicaController.Close()
+
 	serverController.registerCloseFunction(startError, func() error {
 		if metSrv != nil {
 			metSrv.Close()
 		}
+		if remoteSrv != nil {
+			remoteSrv.GracefulStop()
+		}
+		if clusterRemoteSrv != nil {
+			clusterRemoteSrv.GracefulStop()
+		}
 
 		return mySQLServer.Close()
 	})
@@ -229,6 +296,138 @@ func Serve(
 	return
 }
 
+// setConjoinPolicy makes the databases of |mrEnv| conjoin their table files according to |policy|. Databases which
+// don't conjoin table files, like remote databases, are skipped.
+func setConjoinPolicy(mrEnv *env.MultiRepoEnv, policy nbs.ConjoinPolicy) error {
+	return mrEnv.Iter(func(name string, dEnv *env.DoltEnv) (stop bool, err error) {
+		if dEnv.DoltDB == nil {
+			return false, nil
+		}
+		err = dEnv.DoltDB.SetConjoinPolicy(policy)
+		if errors.Is(err, chunks.ErrUnsupportedOperation) {
+			return false, nil
+		}
+		return false, err
+	})
+}
+
+// newClusterController returns the controller of the cluster the server is in, or nil if it isn't in one. The role of
+// the server in the cluster is persisted in its configuration directory.
+func newClusterController(lgr *logrus.Logger, fs filesys.Filesys, serverConfig ServerConfig) (*cluster.Controller, error) {
+	clusterCfg := serverConfig.ClusterConfig()
+	if clusterCfg == nil {
+		return nil, nil
+	}
+
+	pCfg, err := loadPersistentConfig(fs, filepath.Join(serverConfig.CfgDir(), clusterRoleFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster.NewController(lgr, clusterCfg, pCfg)
+}
+
+// newBinlogReplicaController returns the controller of the replica which applies the binlog of a MySQL source to the
+// databases of the server. The source of the replica and its position are persisted in its configuration directory.
+func newBinlogReplicaController(lgr *logrus.Logger, fs filesys.Filesys, serverConfig ServerConfig) (*binlogreplication.Controller, error) {
+	path := filepath.Join(serverConfig.CfgDir(), binlogReplicaFileName)
+	var pCfg config.ReadWriteConfig = &lazyFileConfig{ReadWriteConfig: config.NewMapConfig(map[string]string{}), fs: fs, path: path}
+	if exists, _ := fs.Exists(path); exists {
+		var err error
+		pCfg, err = config.FromFile(path, fs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return binlogreplication.NewController(lgr, pCfg)
+}
+
+// newBinlogPrimaryController returns the controller of the binlog the server writes for MySQL replicas, or nil if it
+// doesn't write one. The binlog and its state are kept in the configuration directory of the server.
+func newBinlogPrimaryController(lgr *logrus.Logger, fs filesys.Filesys, serverConfig ServerConfig) (*binlogreplication.PrimaryController, error) {
+	binlogCfg := serverConfig.BinlogConfig()
+	if binlogCfg == nil {
+		return nil, nil
+	}
+
+	pCfg, err := loadPersistentConfig(fs, filepath.Join(serverConfig.CfgDir(), binlogPrimaryFileName))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := fs.Abs(filepath.Join(serverConfig.CfgDir(), binlogDirName))
+	if err != nil {
+		return nil, err
+	}
+
+	return binlogreplication.NewPrimaryController(lgr, pCfg, dir, binlogCfg.MaxBinlogSize())
+}
+
+// lazyFileConfig is a config persisted in a file which is only created when a value is first set, so that servers
+// which never configure a binlog replica don't create its file. The file holds the password of the replication source,
+// so it is only readable by its owner.
+type lazyFileConfig struct {
+	config.ReadWriteConfig
+	fs   filesys.Filesys
+	path string
+}
+
+func (c *lazyFileConfig) SetStrings(updates map[string]string) error {
+	if _, ok := c.ReadWriteConfig.(*config.FileConfig); ok {
+		return c.ReadWriteConfig.SetStrings(updates)
+	}
+
+	props := make(map[string]string)
+	c.ReadWriteConfig.Iter(func(k, v string) bool {
+		props[k] = v
+		return false
+	})
+	for k, v := range updates {
+		props[k] = v
+	}
+
+	// the file is created before the config writes it, which keeps the permissions of existing files
+	err := c.fs.MkDirs(filepath.Dir(c.path))
+	if err != nil {
+		return err
+	}
+	wr, err := c.fs.OpenForWrite(c.path, 0600)
+	if err != nil {
+		return err
+	}
+	err = wr.Close()
+	if err != nil {
+		return err
+	}
+
+	fileCfg, err := config.NewFileConfig(c.path, c.fs, props)
+	if err != nil {
+		return err
+	}
+	c.ReadWriteConfig = fileCfg
+	return nil
+}
+
+// newBinlogReplicaSessionFactory returns a function which makes the sessions that the binlog replica applies the
+// binlog of its source in.
+func newBinlogReplicaSessionFactory(se *engine.SqlEngine) func(ctx context.Context, client sql.Client) (*sql.Context, error) {
+	return func(ctx context.Context, client sql.Client) (*sql.Context, error) {
+		sess, err := se.NewDoltSession(ctx, sql.NewBaseSessionWithClientServer("", client, 0))
+		if err != nil {
+			return nil, err
+		}
+		return sql.NewContext(ctx, sql.WithSession(sess)), nil
+	}
+}
+
+// loadPersistentConfig returns the config persisted in the file at |path|, which is created if it doesn't exist.
+func loadPersistentConfig(fs filesys.Filesys, path string) (config.ReadWriteConfig, error) {
+	if exists, _ := fs.Exists(path); exists {
+		return config.FromFile(path, fs)
+	}
+	return config.NewFileConfig(path, fs, map[string]string{})
+}
+
 func portInUse(hostPort string) bool {
 	timeout := time.Second
 	conn, _ := net.DialTimeout("tcp", hostPort, timeout)
@@ -15,12 +15,17 @@
 package sqlserver
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
+	gmssql "github.com/dolthub/go-mysql-server/sql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gocraft/dbr/v2"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +37,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
 	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
 )
 
 //TODO: server tests need to expose a higher granularity for server interactions:
@@ -449,3 +455,177 @@ func TestReadReplica(t *testing.T) {
 		assert.ElementsMatch(t, res, []int{0})
 	})
 }
+
+// dolt_gc keeps the roots that the transactions of other sessions read and write, although they are
+// not reachable from any ref of the database.
+func TestServerGCKeepsRootsOfOtherSessions(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	defer os.Chdir(cwd)
+
+	multiSetup := testcommands.NewMultiRepoTestSetup(t.Fatal)
+	defer os.RemoveAll(multiSetup.Root)
+	multiSetup.NewDB("gc_db")
+	dbName := multiSetup.DbNames[0]
+
+	// the database is not a read replica, whatever tests which ran before configured globally
+	require.NoError(t, gmssql.SystemVariables.SetGlobal(dsess.ReadReplicaRemote, ""))
+	require.NoError(t, gmssql.SystemVariables.SetGlobal(dsess.ReplicateHeads, ""))
+
+	sc := NewServerController()
+	serverConfig := DefaultServerConfig().withLogLevel(LogLevel_Fatal).WithPort(15304)
+	os.Chdir(multiSetup.DbPaths[dbName])
+	go func() {
+		_, _ = Serve(context.Background(), "0.0.0", serverConfig, sc, multiSetup.MrEnv.GetEnv(dbName))
+	}()
+	require.NoError(t, sc.WaitForStart())
+	defer sc.StopServer()
+
+	db, err := dbr.Open("mysql", ConnectionString(serverConfig, dbName), nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	reader, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer reader.Close()
+	writer, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer writer.Close()
+	collector, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer collector.Close()
+
+	exec := func(conn *sql.Conn, query string) {
+		_, err := conn.ExecContext(ctx, query)
+		require.NoError(t, err, query)
+	}
+	count := func(conn *sql.Conn) (n int) {
+		require.NoError(t, conn.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&n))
+		return n
+	}
+
+	exec(collector, "CREATE TABLE t (pk INT PRIMARY KEY, v VARCHAR(100))")
+	const rows = 1000
+	for i := 0; i < rows; i += 100 {
+		values := make([]string, 0, 100)
+		for j := i; j < i+100; j++ {
+			values = append(values, fmt.Sprintf("(%d, '%s')", j, strings.Repeat(strconv.Itoa(j), 10)))
+		}
+		exec(collector, "INSERT INTO t VALUES "+strings.Join(values, ", "))
+	}
+
+	// the reader reads the working root of the rows, and the writer writes a new one from it
+	exec(reader, "START TRANSACTION")
+	assert.Equal(t, rows, count(reader))
+	exec(writer, "START TRANSACTION")
+	exec(writer, fmt.Sprintf("INSERT INTO t VALUES (%d, 'new')", rows))
+
+	// the rows are no longer reachable from the working set of the database
+	exec(collector, "DELETE FROM t")
+	exec(collector, "CALL dolt_gc()")
+
+	var sum int
+	require.NoError(t, reader.QueryRowContext(ctx, "SELECT sum(pk) FROM t").Scan(&sum))
+	assert.Equal(t, rows*(rows-1)/2, sum)
+	assert.Equal(t, rows, count(reader))
+	exec(reader, "COMMIT")
+
+	assert.Equal(t, rows+1, count(writer))
+	exec(writer, "COMMIT")
+	assert.Equal(t, 1, count(collector))
+}
+
+// dolt_gc reads the roots of other sessions while they run queries. Run with -race to check that it doesn't race with
+// the sessions changing them.
+func TestServerGCDuringConcurrentWrites(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	defer os.Chdir(cwd)
+
+	multiSetup := testcommands.NewMultiRepoTestSetup(t.Fatal)
+	defer os.RemoveAll(multiSetup.Root)
+	multiSetup.NewDB("gc_db")
+	dbName := multiSetup.DbNames[0]
+
+	require.NoError(t, gmssql.SystemVariables.SetGlobal(dsess.ReadReplicaRemote, ""))
+	require.NoError(t, gmssql.SystemVariables.SetGlobal(dsess.ReplicateHeads, ""))
+
+	sc := NewServerController()
+	serverConfig := DefaultServerConfig().withLogLevel(LogLevel_Fatal).WithPort(15305)
+	os.Chdir(multiSetup.DbPaths[dbName])
+	go func() {
+		_, _ = Serve(context.Background(), "0.0.0", serverConfig, sc, multiSetup.MrEnv.GetEnv(dbName))
+	}()
+	require.NoError(t, sc.WaitForStart())
+	defer sc.StopServer()
+
+	db, err := dbr.Open("mysql", ConnectionString(serverConfig, dbName), nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	writer, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer writer.Close()
+	collector, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer collector.Close()
+
+	_, err = collector.ExecContext(ctx, "CREATE TABLE t (pk INT PRIMARY KEY, v VARCHAR(100))")
+	require.NoError(t, err)
+
+	const rows = 50
+	done := make(chan error)
+	go func() {
+		defer close(done)
+		for i := 0; i < rows; i++ {
+			for _, query := range []string{
+				"START TRANSACTION",
+				fmt.Sprintf("INSERT INTO t VALUES (%d, 'before savepoint')", 2*i),
+				"SAVEPOINT sp",
+				fmt.Sprintf("INSERT INTO t VALUES (%d, 'after savepoint')", 2*i+1),
+				"ROLLBACK TO SAVEPOINT sp",
+				"COMMIT",
+			} {
+				if _, err := writer.ExecContext(ctx, query); err != nil {
+					done <- fmt.Errorf("%s: %w", query, err)
+					return
+				}
+			}
+		}
+	}()
+
+	for collecting := true; collecting; {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+			collecting = false
+		default:
+			_, err = collector.ExecContext(ctx, "CALL dolt_gc()")
+			require.NoError(t, err)
+		}
+	}
+
+	var n int
+	require.NoError(t, collector.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&n))
+	assert.Equal(t, rows, n)
+}
+
+func TestBinlogReplicaConfigIsPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not enforced on windows")
+	}
+	path := filepath.Join(t.TempDir(), ".doltcfg", binlogReplicaFileName)
+	cfg := &lazyFileConfig{ReadWriteConfig: config.NewMapConfig(map[string]string{}), fs: filesys.LocalFS, path: path}
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// the file holds the password of the source
+	require.NoError(t, cfg.SetStrings(map[string]string{"source_password": "secret"}))
+	require.NoError(t, cfg.SetStrings(map[string]string{"position": "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1"}))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	assert.Equal(t, "secret", cfg.GetStringOrDefault("source_password", ""))
+}
@@ -22,6 +22,8 @@ import (
 
 	"github.com/dolthub/dolt/go/cmd/dolt/commands/engine"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/cluster"
+	"github.com/dolthub/dolt/go/store/nbs"
 )
 
 // LogLevel defines the available levels of logging for the server.
@@ -51,6 +53,10 @@ const (
 	defaultDataDir                 = "."
 	defaultCfgDir                  = ".doltcfg"
 	defaultPrivilegeFilePath       = "privileges.db"
+	clusterRoleFileName            = "cluster.json"
+	binlogReplicaFileName          = "binlog_replica.json"
+	binlogPrimaryFileName          = "binlog_primary.json"
+	binlogDirName                  = "binlog"
 	defaultMetricsHost             = ""
 	defaultMetricsPort             = -1
 	defaultAllowCleartextPasswords = false
@@ -114,6 +120,8 @@ type ServerConfig interface {
 	MaxConnections() uint64
 	// QueryParallelism returns the parallelism that should be used by the go-mysql-server analyzer
 	QueryParallelism() int
+	// ConjoinPolicy returns the policy databases use to conjoin their table files
+	ConjoinPolicy() nbs.ConjoinPolicy
 	// TLSKey returns a path to the servers PEM-encoded private TLS key. "" if there is none.
 	TLSKey() string
 	// TLSCert returns a path to the servers PEM-encoded TLS certificate chain. "" if there is none.
@@ -141,6 +149,19 @@ type ServerConfig interface {
 	AllowCleartextPasswords() bool
 	// Socket is a path to the unix socket file
 	Socket() string
+	// RemotesapiPort is the port the remotesapi server listens on, or nil if the databases are not served as remotes.
+	RemotesapiPort() *int
+	// ClusterConfig is the configuration of the cluster the server is in, or nil if it isn't in a cluster.
+	ClusterConfig() cluster.Config
+	// BinlogConfig is the configuration of the binlog the server writes for MySQL replicas, or nil if it doesn't
+	// write one.
+	BinlogConfig() BinlogConfig
+}
+
+// BinlogConfig configures the binlog a server writes for MySQL replicas.
+type BinlogConfig interface {
+	// MaxBinlogSize is the size at which the binlog continues in a new file.
+	MaxBinlogSize() int64
 }
 
 type commandLineServerConfig struct {
@@ -223,6 +244,11 @@ func (cfg *commandLineServerConfig) QueryParallelism() int {
 	return cfg.queryParallelism
 }
 
+// ConjoinPolicy returns the policy databases use to conjoin their table files
+func (cfg *commandLineServerConfig) ConjoinPolicy() nbs.ConjoinPolicy {
+	return nbs.DefaultConjoinPolicy
+}
+
 // PersistenceBehavior returns whether to autoload persisted server configuration
 func (cfg *commandLineServerConfig) PersistenceBehavior() string {
 	return cfg.persistenceBehavior
@@ -304,6 +330,21 @@ func (cfg *commandLineServerConfig) Socket() string {
 	return cfg.socket
 }
 
+// RemotesapiPort is the port the remotesapi server listens on, or nil if the databases are not served as remotes.
+func (cfg *commandLineServerConfig) RemotesapiPort() *int {
+	return nil
+}
+
+// ClusterConfig is the configuration of the cluster the server is in, or nil if it isn't in a cluster.
+func (cfg *commandLineServerConfig) ClusterConfig() cluster.Config {
+	return nil
+}
+
+// BinlogConfig is the configuration of the binlog the server writes for MySQL replicas, or nil if it doesn't write one.
+func (cfg *commandLineServerConfig) BinlogConfig() BinlogConfig {
+	return nil
+}
+
 // WithHost updates the host and returns the called `*commandLineServerConfig`, which is useful for chaining calls.
 func (cfg *commandLineServerConfig) WithHost(host string) *commandLineServerConfig {
 	cfg.host = host
@@ -437,6 +478,29 @@ func ValidateConfig(config ServerConfig) error {
 	if config.RequireSecureTransport() && config.TLSCert() == "" && config.TLSKey() == "" {
 		return fmt.Errorf("require_secure_transport can only be `true` when a tls_key and tls_cert are provided.")
 	}
+	if port := config.RemotesapiPort(); port != nil {
+		if *port < 1024 || *port > 65535 {
+			return fmt.Errorf("remotesapi_port is not in the range between 1024-65535: %v\n", *port)
+		}
+		if *port == config.Port() {
+			return fmt.Errorf("remotesapi_port must be different from the port of the server: %v\n", *port)
+		}
+	}
+	if err := config.ConjoinPolicy().Validate(); err != nil {
+		return fmt.Errorf("performance.conjoin is invalid: %w", err)
+	}
+	if clusterCfg := config.ClusterConfig(); clusterCfg != nil {
+		if err := cluster.ValidateConfig(clusterCfg); err != nil {
+			return err
+		}
+		port := clusterCfg.RemotesAPIConfig().Port()
+		if port == config.Port() || (config.RemotesapiPort() != nil && port == *config.RemotesapiPort()) {
+			return fmt.Errorf("cluster: remotesapi.port must be different from the other ports of the server: %v\n", port)
+		}
+	}
+	if binlogCfg := config.BinlogConfig(); binlogCfg != nil && binlogCfg.MaxBinlogSize() <= 0 {
+		return fmt.Errorf("binlog: max_binlog_size must be positive: %v\n", binlogCfg.MaxBinlogSize())
+	}
 	return nil
 }
 
@@ -92,14 +92,42 @@ SUPPORTED CONFIG FILE FIELDS:
 
 {{.EmphasisLeft}}listener.write_timeout_millis{{.EmphasisRight}}: The number of milliseconds that the server will wait for a write operation
 
+{{.EmphasisLeft}}listener.remotesapi_port{{.EmphasisRight}}: If set, the databases of the server are served as remotes on this port, so that they can be cloned, fetched from and pushed to with urls like {{.EmphasisLeft}}http://host:port/database{{.EmphasisRight}}. Clients authenticate as a SQL user given by the {{.EmphasisLeft}}DOLT_REMOTE_USER{{.EmphasisRight}} and {{.EmphasisLeft}}DOLT_REMOTE_PASSWORD{{.EmphasisRight}} environment variables. Reading a database requires the SELECT privilege on it, and writing to it requires the INSERT, UPDATE and DELETE privileges. A server without any SQL users can't authenticate clients, so its remotes can be read by anyone but not pushed to. The remotes are served without TLS, so passwords and data are sent in plain text
+
 {{.EmphasisLeft}}performance.query_parallelism{{.EmphasisRight}}: Amount of go routines spawned to process each query
 
+{{.EmphasisLeft}}performance.conjoin.strategy{{.EmphasisRight}}: How the table files of each database are conjoined. One of {{.EmphasisLeft}}table_count{{.EmphasisRight}} (the default), {{.EmphasisLeft}}size_tiered{{.EmphasisRight}} or {{.EmphasisLeft}}off{{.EmphasisRight}}
+
+{{.EmphasisLeft}}performance.conjoin.max_tables{{.EmphasisRight}}: The number of table files above which the {{.EmphasisLeft}}table_count{{.EmphasisRight}} strategy conjoins table files
+
+{{.EmphasisLeft}}performance.conjoin.tier_tables{{.EmphasisRight}}: The number of similarly sized table files which the {{.EmphasisLeft}}size_tiered{{.EmphasisRight}} strategy conjoins together
+
+{{.EmphasisLeft}}performance.conjoin.background{{.EmphasisRight}}: If true, table files are conjoined in the background instead of while committing
+
 {{.EmphasisLeft}}databases{{.EmphasisRight}}: a list of dolt data repositories to make available as SQL databases. If databases is missing or empty then the working directory must be a valid dolt data repository which will be made available as a SQL database
 
 {{.EmphasisLeft}}databases[i].path{{.EmphasisRight}}: A path to a dolt data repository
 
 {{.EmphasisLeft}}databases[i].name{{.EmphasisRight}}: The name that the database corresponding to the given path should be referenced via SQL
 
+{{.EmphasisLeft}}cluster{{.EmphasisRight}}: If set, the server is part of a cluster in which one server is the primary and the others are standbys. The primary replicates every write to its databases, including uncommitted changes, to all standbys before the write completes. A write that can't be replicated is still committed on the primary, and fails with an error starting with {{.EmphasisLeft}}the write was committed, but it could not be replicated{{.EmphasisRight}}. Such writes must not be retried, since retrying them would apply them twice. Standbys reject writes. Dropped databases are not replicated. The role of the server is stored in {{.EmphasisLeft}}cluster.json{{.EmphasisRight}} in its configuration directory, so that it keeps its role across restarts. The role can be read from the {{.EmphasisLeft}}@@dolt_cluster_role{{.EmphasisRight}} and {{.EmphasisLeft}}@@dolt_cluster_role_epoch{{.EmphasisRight}} system variables and changed with {{.EmphasisLeft}}CALL dolt_assume_cluster_role('primary'|'standby', epoch){{.EmphasisRight}} by users with the SUPER privilege, where the epoch must be newer than the server's. A primary which steps down to standby this way first brings all standbys up to date. A primary which learns that another server is the primary at a newer epoch steps down to standby
+
+{{.EmphasisLeft}}cluster.standby_remotes{{.EmphasisRight}}: The other servers of the cluster
+
+{{.EmphasisLeft}}cluster.standby_remotes[i].name{{.EmphasisRight}}: The name of the standby
+
+{{.EmphasisLeft}}cluster.standby_remotes[i].remote_url_template{{.EmphasisRight}}: The url of the databases of the standby, in which {{.EmphasisLeft}}{database}{{.EmphasisRight}} is replaced by the name of a database, like {{.EmphasisLeft}}http://standby:50051/{database}{{.EmphasisRight}}
+
+{{.EmphasisLeft}}cluster.bootstrap_role{{.EmphasisRight}}: The role of the server the first time it starts in the cluster, either {{.EmphasisLeft}}primary{{.EmphasisRight}} (the default) or {{.EmphasisLeft}}standby{{.EmphasisRight}}
+
+{{.EmphasisLeft}}cluster.bootstrap_epoch{{.EmphasisRight}}: The epoch of the role of the server the first time it starts in the cluster. Defaults to 1
+
+{{.EmphasisLeft}}cluster.remotesapi.port{{.EmphasisRight}}: The port on which the other servers of the cluster replicate to this one
+
+{{.EmphasisLeft}}cluster.remotesapi.secret{{.EmphasisRight}}: The secret shared by all servers of the cluster. A server sends it with every request it makes to the other servers, which reject the requests that don't carry it. The port is served without TLS, so the secret is sent in the clear, and the port must only be reachable over a trusted network
+
+A server can replicate from a MySQL server by streaming its binlog, which must be row-based with full row images ({{.EmphasisLeft}}binlog_format=ROW{{.EmphasisRight}} and {{.EmphasisLeft}}binlog_row_image=FULL{{.EmphasisRight}}). The source is configured with {{.EmphasisLeft}}CALL dolt_change_replication_source('KEY=value', ...){{.EmphasisRight}}, which takes the {{.EmphasisLeft}}SOURCE_HOST{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_PORT{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_USER{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_PASSWORD{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_CONNECT_RETRY{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_AUTO_POSITION{{.EmphasisRight}}, {{.EmphasisLeft}}SOURCE_LOG_FILE{{.EmphasisRight}} and {{.EmphasisLeft}}SOURCE_LOG_POS{{.EmphasisRight}} options of MySQL's {{.EmphasisLeft}}CHANGE REPLICATION SOURCE TO{{.EmphasisRight}}, and {{.EmphasisLeft}}DOLT_TRANSACTION_COMMIT=1{{.EmphasisRight}} to make a Dolt commit for every replicated transaction. The source user must authenticate with {{.EmphasisLeft}}mysql_native_password{{.EmphasisRight}}. The replica is started with {{.EmphasisLeft}}CALL dolt_start_replica(){{.EmphasisRight}}, applying the binlog with the privileges of the calling user, stopped with {{.EmphasisLeft}}CALL dolt_stop_replica(){{.EmphasisRight}} and inspected with {{.EmphasisLeft}}CALL dolt_show_replica_status(){{.EmphasisRight}}. The replica identifies itself to the source with the {{.EmphasisLeft}}@@server_id{{.EmphasisRight}} system variable. Replicated transactions may only write to a single database. The source, including its password, and the position of the replica are stored in {{.EmphasisLeft}}binlog_replica.json{{.EmphasisRight}} in the configuration directory of the server, which only its owner can read, so a running replica resumes when the server restarts. Every replicated transaction also writes the position of the replica to the {{.EmphasisLeft}}dolt_replica_position{{.EmphasisRight}} table of its database, in the same transaction, so a replica never applies a transaction twice
+
 If a config file is not provided many of these settings may be configured on the command line.`,
 	Synopsis: []string{
 		"--config {{.LessThan}}file{{.GreaterThan}}",
@@ -24,6 +24,9 @@ import (
 
 	"github.com/dolthub/dolt/go/cmd/dolt/commands/engine"
 	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/binlogreplication"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/cluster"
+	"github.com/dolthub/dolt/go/store/nbs"
 )
 
 func strPtr(s string) *string {
@@ -100,11 +103,47 @@ type ListenerYAMLConfig struct {
 	AllowCleartextPasswords *bool `yaml:"allow_cleartext_passwords"`
 	// Socket is unix socket file path
 	Socket *string `yaml:"socket"`
+	// RemotesapiPort is the port the databases of the server are served on as remotes with the remotesapi.
+	RemotesapiPort *int `yaml:"remotesapi_port"`
 }
 
 // PerformanceYAMLConfig contains configuration parameters for performance tweaking
 type PerformanceYAMLConfig struct {
-	QueryParallelism *int `yaml:"query_parallelism"`
+	QueryParallelism *int              `yaml:"query_parallelism"`
+	Conjoin          ConjoinYAMLConfig `yaml:"conjoin"`
+}
+
+// ConjoinYAMLConfig contains configuration parameters for how the table files of databases are conjoined
+type ConjoinYAMLConfig struct {
+	Strategy   *string `yaml:"strategy"`
+	MaxTables  *int    `yaml:"max_tables"`
+	TierTables *int    `yaml:"tier_tables"`
+	Background *bool   `yaml:"background"`
+}
+
+// ClusterYAMLConfig configures the cluster the server replicates its databases in
+type ClusterYAMLConfig struct {
+	StandbyRemotesList []StandbyRemoteYAMLConfig   `yaml:"standby_remotes"`
+	BootstrapRoleStr   *string                     `yaml:"bootstrap_role"`
+	BootstrapEpochNum  *int                        `yaml:"bootstrap_epoch"`
+	RemotesAPI         ClusterRemotesAPIYAMLConfig `yaml:"remotesapi"`
+}
+
+// StandbyRemoteYAMLConfig is a peer of the server in its cluster
+type StandbyRemoteYAMLConfig struct {
+	NameStr              string `yaml:"name"`
+	RemoteURLTemplateStr string `yaml:"remote_url_template"`
+}
+
+// ClusterRemotesAPIYAMLConfig configures the remotesapi server the peers of the cluster replicate to
+type ClusterRemotesAPIYAMLConfig struct {
+	PortNumber int    `yaml:"port"`
+	SecretStr  string `yaml:"secret"`
+}
+
+// BinlogYAMLConfig configures the binlog the server writes for MySQL replicas
+type BinlogYAMLConfig struct {
+	MaxSize *int64 `yaml:"max_binlog_size"`
 }
 
 type MetricsYAMLConfig struct {
@@ -132,6 +171,8 @@ type YAMLConfig struct {
 	PrivilegeFile     *string               `yaml:"privilege_file"`
 	Vars              []UserSessionVars     `yaml:"user_session_vars"`
 	Jwks              []engine.JwksConfig   `yaml:"jwks"`
+	ClusterCfg        *ClusterYAMLConfig    `yaml:"cluster,omitempty"`
+	BinlogCfg         *BinlogYAMLConfig     `yaml:"binlog,omitempty"`
 }
 
 var _ ServerConfig = YAMLConfig{}
@@ -163,6 +204,7 @@ func serverConfigAsYAMLConfig(cfg ServerConfig) YAMLConfig {
 			nillableBoolPtr(cfg.RequireSecureTransport()),
 			nillableBoolPtr(cfg.AllowCleartextPasswords()),
 			nillableStrPtr(cfg.Socket()),
+			cfg.RemotesapiPort(),
 		},
 		DatabaseConfig: nil,
 	}
@@ -376,6 +418,25 @@ func (cfg YAMLConfig) QueryParallelism() int {
 	return *cfg.PerformanceConfig.QueryParallelism
 }
 
+// ConjoinPolicy returns the policy databases use to conjoin their table files
+func (cfg YAMLConfig) ConjoinPolicy() nbs.ConjoinPolicy {
+	policy := nbs.DefaultConjoinPolicy
+	conjoin := cfg.PerformanceConfig.Conjoin
+	if conjoin.Strategy != nil {
+		policy.Strategy = nbs.ConjoinStrategy(*conjoin.Strategy)
+	}
+	if conjoin.MaxTables != nil {
+		policy.MaxTables = *conjoin.MaxTables
+	}
+	if conjoin.TierTables != nil {
+		policy.TierTables = *conjoin.TierTables
+	}
+	if conjoin.Background != nil {
+		policy.Background = *conjoin.Background
+	}
+	return policy
+}
+
 // TLSKey returns a path to the servers PEM-encoded private TLS key. "" if there is none.
 func (cfg YAMLConfig) TLSKey() string {
 	if cfg.ListenerConfig.TLSKey == nil {
@@ -435,3 +496,73 @@ func (cfg YAMLConfig) Socket() string {
 	}
 	return *cfg.ListenerConfig.Socket
 }
+
+// RemotesapiPort is the port the remotesapi server listens on, or nil if the databases are not served as remotes.
+func (cfg YAMLConfig) RemotesapiPort() *int {
+	return cfg.ListenerConfig.RemotesapiPort
+}
+
+// ClusterConfig is the configuration of the cluster the server is in, or nil if it isn't in a cluster.
+func (cfg YAMLConfig) ClusterConfig() cluster.Config {
+	if cfg.ClusterCfg == nil {
+		return nil
+	}
+	return cfg.ClusterCfg
+}
+
+// BinlogConfig is the configuration of the binlog the server writes for MySQL replicas, or nil if it doesn't write one.
+func (cfg YAMLConfig) BinlogConfig() BinlogConfig {
+	if cfg.BinlogCfg == nil {
+		return nil
+	}
+	return cfg.BinlogCfg
+}
+
+func (c *BinlogYAMLConfig) MaxBinlogSize() int64 {
+	if c.MaxSize != nil {
+		return *c.MaxSize
+	}
+	return binlogreplication.DefaultMaxBinlogSize
+}
+
+func (c *ClusterYAMLConfig) StandbyRemotes() []cluster.StandbyRemoteConfig {
+	remotes := make([]cluster.StandbyRemoteConfig, len(c.StandbyRemotesList))
+	for i := range c.StandbyRemotesList {
+		remotes[i] = c.StandbyRemotesList[i]
+	}
+	return remotes
+}
+
+func (c *ClusterYAMLConfig) BootstrapRole() string {
+	if c.BootstrapRoleStr != nil {
+		return *c.BootstrapRoleStr
+	}
+	return string(cluster.RolePrimary)
+}
+
+func (c *ClusterYAMLConfig) BootstrapEpoch() int {
+	if c.BootstrapEpochNum != nil {
+		return *c.BootstrapEpochNum
+	}
+	return 1
+}
+
+func (c *ClusterYAMLConfig) RemotesAPIConfig() cluster.RemotesAPIConfig {
+	return c.RemotesAPI
+}
+
+func (r StandbyRemoteYAMLConfig) Name() string {
+	return r.NameStr
+}
+
+func (r StandbyRemoteYAMLConfig) RemoteURLTemplate() string {
+	return r.RemoteURLTemplateStr
+}
+
+func (r ClusterRemotesAPIYAMLConfig) Port() int {
+	return r.PortNumber
+}
+
+func (r ClusterRemotesAPIYAMLConfig) Secret() string {
+	return r.SecretStr
+}
@@ -22,6 +22,8 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/dolthub/dolt/go/cmd/dolt/commands/engine"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/binlogreplication"
+	"github.com/dolthub/dolt/go/store/nbs"
 )
 
 func TestUnmarshall(t *testing.T) {
@@ -204,6 +206,7 @@ func TestYAMLConfigDefaults(t *testing.T) {
 	assert.Equal(t, defaultMetricsPort, cfg.MetricsPort())
 	assert.Nil(t, cfg.MetricsConfig.Labels)
 	assert.Equal(t, defaultAllowCleartextPasswords, cfg.AllowCleartextPasswords())
+	assert.Equal(t, nbs.DefaultConjoinPolicy, cfg.ConjoinPolicy())
 
 	c, err := LoadTLSConfig(cfg)
 	assert.NoError(t, err)
@@ -275,3 +278,202 @@ listener:
 	err = ValidateConfig(cfg)
 	assert.Error(t, err)
 }
+
+func TestYAMLConfigConjoinPolicy(t *testing.T) {
+	cfg, err := NewYamlConfig([]byte(`
+performance:
+  conjoin:
+    strategy: size_tiered
+    tier_tables: 8
+    background: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, nbs.ConjoinPolicy{
+		Strategy:   nbs.ConjoinSizeTiered,
+		MaxTables:  nbs.DefaultConjoinPolicy.MaxTables,
+		TierTables: 8,
+		Background: true,
+	}, cfg.ConjoinPolicy())
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+performance:
+  conjoin:
+    strategy: sometimes
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+performance:
+  conjoin:
+    max_tables: 1
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestYAMLConfigRemotesapiPort(t *testing.T) {
+	cfg, err := NewYamlConfig([]byte(`
+listener:
+  port: 3306
+  remotesapi_port: 50051
+`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.RemotesapiPort())
+	assert.Equal(t, 50051, *cfg.RemotesapiPort())
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  port: 3306
+  remotesapi_port: 3306
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  remotesapi_port: 80
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  port: 3306
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.RemotesapiPort())
+}
+
+func TestYAMLConfigCluster(t *testing.T) {
+	cfg, err := NewYamlConfig([]byte(`
+listener:
+  port: 3306
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/{database}
+  bootstrap_role: standby
+  bootstrap_epoch: 3
+  remotesapi:
+    port: 50051
+    secret: hunter2
+`))
+	require.NoError(t, err)
+	clusterCfg := cfg.ClusterConfig()
+	require.NotNil(t, clusterCfg)
+	require.Len(t, clusterCfg.StandbyRemotes(), 1)
+	assert.Equal(t, "standby", clusterCfg.StandbyRemotes()[0].Name())
+	assert.Equal(t, "http://standby:50051/{database}", clusterCfg.StandbyRemotes()[0].RemoteURLTemplate())
+	assert.Equal(t, "standby", clusterCfg.BootstrapRole())
+	assert.Equal(t, 3, clusterCfg.BootstrapEpoch())
+	assert.Equal(t, 50051, clusterCfg.RemotesAPIConfig().Port())
+	assert.Equal(t, "hunter2", clusterCfg.RemotesAPIConfig().Secret())
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/{database}
+  remotesapi:
+    port: 50051
+    secret: hunter2
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "primary", cfg.ClusterConfig().BootstrapRole())
+	assert.Equal(t, 1, cfg.ClusterConfig().BootstrapEpoch())
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/db
+  remotesapi:
+    port: 50051
+    secret: hunter2
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/{database}
+  bootstrap_role: leader
+  remotesapi:
+    port: 50051
+    secret: hunter2
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  port: 3306
+  remotesapi_port: 50051
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/{database}
+  remotesapi:
+    port: 50051
+    secret: hunter2
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+cluster:
+  standby_remotes:
+  - name: standby
+    remote_url_template: http://standby:50051/{database}
+  remotesapi:
+    port: 50051
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  port: 3306
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.ClusterConfig())
+}
+
+func TestYAMLConfigBinlog(t *testing.T) {
+	cfg, err := NewYamlConfig([]byte(`
+binlog:
+  max_binlog_size: 1048576
+`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.BinlogConfig())
+	assert.Equal(t, int64(1048576), cfg.BinlogConfig().MaxBinlogSize())
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+binlog: {}
+`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.BinlogConfig())
+	assert.Equal(t, int64(binlogreplication.DefaultMaxBinlogSize), cfg.BinlogConfig().MaxBinlogSize())
+
+	cfg, err = NewYamlConfig([]byte(`
+binlog:
+  max_binlog_size: 0
+`))
+	require.NoError(t, err)
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg, err = NewYamlConfig([]byte(`
+listener:
+  port: 3306
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.BinlogConfig())
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	eventsapi "github.com/dolthub/dolt/go/gen/proto/dolt/services/eventsapi/v1alpha1"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/rebase"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	"github.com/dolthub/dolt/go/store/chunks"
+)
+
+const (
+	olderThanParam  = "older-than"
+	keepRecentParam = "keep-recent"
+	periodParam     = "period"
+	skipGCFlag      = "skip-gc"
+)
+
+var squashHistoryDocs = cli.CommandDocumentationContent{
+	ShortDesc: "Squashes old commits to cap the size of the commit history",
+	LongDesc: `Rewrites the history of the current branch so that the commits older than {{.EmphasisLeft}}--older-than{{.EmphasisRight}}, and not among the {{.EmphasisLeft}}--keep-recent{{.EmphasisRight}} most recent commits of the branch, are squashed into one commit per day or week, then garbage collects the data only the dropped commits referenced.
+
+The commit kept for a day or week is the last one made in it, with its data and message unchanged. Merge commits, the commits they merge, the commits branches fork from, the first commit of the repository and tagged commits are never dropped, and tags are moved to the rewritten commits. Days and weeks are in UTC.
+
+If the {{.EmphasisLeft}}--all{{.EmphasisRight}} flag is supplied, the history of all branches is rewritten.
+
+The data of the dropped commits is only garbage collected if no other ref, like a branch not rewritten or a remote tracking branch, still references it. Use {{.EmphasisLeft}}--skip-gc{{.EmphasisRight}} to garbage collect later with {{.EmphasisLeft}}dolt gc{{.EmphasisRight}}.
+`,
+
+	Synopsis: []string{
+		"[--all] [--older-than {{.LessThan}}age{{.GreaterThan}}] [--keep-recent {{.LessThan}}n{{.GreaterThan}}] [--period day|week] [--skip-gc]",
+	},
+}
+
+type SquashHistoryCmd struct{}
+
+// Name is returns the name of the Dolt cli command. This is what is used on the command line to invoke the command
+func (cmd SquashHistoryCmd) Name() string {
+	return "squash-history"
+}
+
+// Description returns a description of the command
+func (cmd SquashHistoryCmd) Description() string {
+	return fmt.Sprintf("%s.", squashHistoryDocs.ShortDesc)
+}
+
+func (cmd SquashHistoryCmd) Docs() *cli.CommandDocumentation {
+	ap := cmd.ArgParser()
+	return cli.NewCommandDocumentation(squashHistoryDocs, ap)
+}
+
+func (cmd SquashHistoryCmd) ArgParser() *argparser.ArgParser {
+	ap := argparser.NewArgParser()
+	ap.SupportsFlag(allFlag, "a", "squash the history of all branches")
+	ap.SupportsString(olderThanParam, "", "age", "Only squash commits older than {{.LessThan}}age{{.GreaterThan}}, like 30d, 2w or 12h.")
+	ap.SupportsInt(keepRecentParam, "", "n", "Never squash the {{.LessThan}}n{{.GreaterThan}} most recent commits of a branch.")
+	ap.SupportsString(periodParam, "", "period", "Squash the commits of each day or week into one. Defaults to day.")
+	ap.SupportsFlag(skipGCFlag, "", "Do not garbage collect once the history is rewritten.")
+	return ap
+}
+
+// EventType returns the type of the event to log
+func (cmd SquashHistoryCmd) EventType() eventsapi.ClientEventType {
+	return eventsapi.ClientEventType_FILTER_BRANCH
+}
+
+// Exec executes the command
+func (cmd SquashHistoryCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cmd.ArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, squashHistoryDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+
+	if apr.NArg() != 0 {
+		return HandleVErrAndExitCode(errhand.BuildDError("").SetPrintUsage().Build(), usage)
+	}
+
+	if dEnv.IsLocked() {
+		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(env.ErrActiveServerLock.New(dEnv.LockFile())), help)
+	}
+
+	opts, verr := parseSquashOpts(apr)
+	if verr != nil {
+		return HandleVErrAndExitCode(verr, usage)
+	}
+
+	var dropped int
+	var err error
+	if apr.Contains(allFlag) {
+		dropped, err = rebase.SquashAllBranches(ctx, dEnv, opts)
+	} else {
+		dropped, err = rebase.SquashCurrentBranch(ctx, dEnv, opts)
+	}
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("error: failed to squash history").AddCause(err).Build(), usage)
+	}
+
+	if dropped == 0 {
+		cli.Println("No commits to squash.")
+		return 0
+	}
+	cli.Printf("Squashed %d commits.\n", dropped)
+
+	if apr.Contains(skipGCFlag) {
+		return 0
+	}
+
+	keepers, err := env.GetGCKeepers(ctx, dEnv)
+	if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("an error occurred while saving working set").AddCause(err).Build(), usage)
+	}
+
+	err = dEnv.DoltDB.GC(ctx, keepers...)
+	if errors.Is(err, chunks.ErrNothingToCollect) {
+		cli.PrintErrln(color.YellowString("Nothing to collect."))
+	} else if err != nil {
+		return HandleVErrAndExitCode(errhand.BuildDError("an error occurred during garbage collection").AddCause(err).Build(), usage)
+	}
+
+	return 0
+}
+
+func parseSquashOpts(apr *argparser.ArgParseResults) (rebase.SquashOpts, errhand.VerboseError) {
+	var opts rebase.SquashOpts
+
+	olderThan, hasOlderThan := apr.GetValue(olderThanParam)
+	keepRecent, hasKeepRecent := apr.GetInt(keepRecentParam)
+	if !hasOlderThan && !hasKeepRecent {
+		return opts, errhand.BuildDError("error: at least one of --%s and --%s is required", olderThanParam, keepRecentParam).Build()
+	}
+
+	if hasOlderThan {
+		age, err := parseAge(olderThan)
+		if err != nil {
+			return opts, errhand.BuildDError("error: invalid --%s '%s'", olderThanParam, olderThan).AddCause(err).Build()
+		}
+		opts.Before = time.Now().Add(-age)
+	}
+
+	if hasKeepRecent {
+		if keepRecent < 0 {
+			return opts, errhand.BuildDError("error: --%s must not be negative", keepRecentParam).Build()
+		}
+		opts.KeepRecent = keepRecent
+	}
+
+	period, err := rebase.ParseSquashPeriod(apr.GetValueOrDefault(periodParam, "day"))
+	if err != nil {
+		return opts, errhand.VerboseErrorFromError(err)
+	}
+	opts.Period = period
+
+	return opts, nil
+}
+
+// parseAge parses a duration which, in addition to the units time.ParseDuration supports, can be a number of days or
+// weeks like 30d or 2w.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			count, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil {
+				return 0, err
+			} else if count < 0 {
+				return 0, errors.New("age must not be negative")
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	} else if age < 0 {
+		return 0, errors.New("age must not be negative")
+	}
+	return age, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/cmd/dolt/errhand"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/stash"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/libraries/utils/argparser"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const (
+	stashPushId  = "push"
+	stashPopId   = "pop"
+	stashApplyId = "apply"
+	stashListId  = "list"
+	stashDropId  = "drop"
+	stashClearId = "clear"
+)
+
+var stashDocs = cli.CommandDocumentationContent{
+	ShortDesc: `Stash the changes in a dirty working set away.`,
+	LongDesc: `
+Use {{.EmphasisLeft}}dolt stash{{.EmphasisRight}} to record the current state of the working set and go back to a clean working set. Both staged and unstaged changes are saved, including tables that have never been staged. The saved changes can be listed with {{.EmphasisLeft}}dolt stash list{{.EmphasisRight}} and restored, potentially on top of a different commit, with {{.EmphasisLeft}}dolt stash apply{{.EmphasisRight}} or {{.EmphasisLeft}}dolt stash pop{{.EmphasisRight}}.
+
+Stash entries are referred to by their position in the stash list: {{.EmphasisLeft}}stash@{0}{{.EmphasisRight}} is the most recently created entry, {{.EmphasisLeft}}stash@{1}{{.EmphasisRight}} the one before it, and so on.
+
+{{.EmphasisLeft}}push{{.EmphasisRight}}
+Save the local changes to a new stash entry and reset the working set to HEAD. This is the default when no subcommand is given. With {{.EmphasisLeft}}-m{{.EmphasisRight}}, the given message is used as the description of the entry.
+
+{{.EmphasisLeft}}list{{.EmphasisRight}}
+List the stash entries, most recent first.
+
+{{.EmphasisLeft}}apply{{.EmphasisRight}}
+Merge the changes recorded in a stash entry into the working set. Conflicts are recorded in the {{.EmphasisLeft}}dolt_conflicts{{.EmphasisRight}} tables as for any other merge. Staged changes are restored as unstaged changes.
+
+{{.EmphasisLeft}}pop{{.EmphasisRight}}
+Apply a stash entry and remove it from the stash list. If applying the entry results in conflicts, it is kept in the list.
+
+{{.EmphasisLeft}}drop{{.EmphasisRight}}
+Remove a stash entry from the stash list.
+
+{{.EmphasisLeft}}clear{{.EmphasisRight}}
+Remove all stash entries.
+`,
+	Synopsis: []string{
+		`[push] [-m {{.LessThan}}msg{{.GreaterThan}}]`,
+		`list`,
+		`(pop | apply | drop) [{{.LessThan}}stash{{.GreaterThan}}]`,
+		`clear`,
+	},
+}
+
+type StashCmd struct{}
+
+// Name returns the name of the Dolt cli command. This is what is used on the command line to invoke the command.
+func (cmd StashCmd) Name() string {
+	return "stash"
+}
+
+// Description returns a description of the command.
+func (cmd StashCmd) Description() string {
+	return "Stash the changes in a dirty working set away."
+}
+
+func (cmd StashCmd) Docs() *cli.CommandDocumentation {
+	ap := cli.CreateStashArgParser()
+	return cli.NewCommandDocumentation(stashDocs, ap)
+}
+
+func (cmd StashCmd) ArgParser() *argparser.ArgParser {
+	return cli.CreateStashArgParser()
+}
+
+// Exec executes the command.
+func (cmd StashCmd) Exec(ctx context.Context, commandStr string, args []string, dEnv *env.DoltEnv) int {
+	ap := cli.CreateStashArgParser()
+	help, usage := cli.HelpAndUsagePrinters(cli.CommandDocsForCommandString(commandStr, stashDocs, ap))
+	apr := cli.ParseArgsOrDie(ap, args, help)
+	if dEnv.IsLocked() {
+		return HandleVErrAndExitCode(errhand.VerboseErrorFromError(env.ErrActiveServerLock.New(dEnv.LockFile())), help)
+	}
+
+	subcommand := stashPushId
+	if apr.NArg() > 0 {
+		subcommand = apr.Arg(0)
+	}
+	if apr.Contains(cli.MessageArg) && subcommand != stashPushId {
+		usage()
+		return 1
+	}
+
+	var verr errhand.VerboseError
+	switch subcommand {
+	case stashPushId:
+		if apr.NArg() > 1 {
+			usage()
+			return 1
+		}
+		// Stash entries are stored as commits, so we need user identity
+		if !cli.CheckUserNameAndEmail(dEnv) {
+			return 1
+		}
+		msg, _ := apr.GetValue(cli.MessageArg)
+		verr = stashPush(ctx, dEnv, msg)
+	case stashListId, stashClearId:
+		if apr.NArg() > 1 {
+			usage()
+			return 1
+		}
+		if subcommand == stashListId {
+			verr = stashList(ctx, dEnv)
+		} else {
+			verr = errhand.VerboseErrorFromError(stash.Clear(ctx, dEnv.DoltDB))
+		}
+	case stashPopId, stashApplyId, stashDropId:
+		if apr.NArg() > 2 {
+			usage()
+			return 1
+		}
+		name := ""
+		if apr.NArg() == 2 {
+			name = apr.Arg(1)
+		}
+		if subcommand == stashDropId {
+			verr = stashDrop(ctx, dEnv, name)
+		} else {
+			verr = stashApply(ctx, dEnv, name, subcommand == stashPopId)
+		}
+	default:
+		verr = errhand.BuildDError("error: unknown subcommand '%s'", subcommand).SetPrintUsage().Build()
+	}
+
+	return HandleVErrAndExitCode(verr, usage)
+}
+
+func stashPush(ctx context.Context, dEnv *env.DoltEnv, msg string) errhand.VerboseError {
+	name, email, err := env.GetNameAndEmail(dEnv.Config)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	s, err := stash.Push(ctx, dEnv.DoltDB, dEnv.RepoStateReader().CWBHeadRef(), ws, name, email, msg, stashWriter(ctx, dEnv))
+	if err == stash.ErrNoLocalChanges {
+		cli.Println(err.Error())
+		return nil
+	} else if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	cli.Println("Saved working directory and index state " + s.Description)
+	return nil
+}
+
+func stashList(ctx context.Context, dEnv *env.DoltEnv) errhand.VerboseError {
+	stashes, err := stash.List(ctx, dEnv.DoltDB)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	for _, s := range stashes {
+		cli.Println(fmt.Sprintf("%s: %s", s.Name(), s.Description))
+	}
+	return nil
+}
+
+func stashApply(ctx context.Context, dEnv *env.DoltEnv, name string, pop bool) errhand.VerboseError {
+	s, err := stash.Get(ctx, dEnv.DoltDB, name)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	ws, err := dEnv.WorkingSet(ctx)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	opts := editor.Options{Deaf: dEnv.BulkDbEaFactory(), Tempdir: dEnv.TempTableFilesDir()}
+	var hasConflicts bool
+	if pop {
+		hasConflicts, err = stash.Pop(ctx, dEnv.DoltDB, ws, s, opts, stashWriter(ctx, dEnv))
+	} else {
+		ws, hasConflicts, err = stash.Apply(ctx, dEnv.DoltDB, ws, s, opts)
+		if err == nil {
+			err = dEnv.UpdateWorkingSet(ctx, ws)
+		}
+	}
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	if hasConflicts {
+		bdr := errhand.BuildDError("error: applying %s resulted in conflicts or constraint violations.", s.Name())
+		bdr.AddDetails("hint: resolve them in the working set before committing.")
+		if pop {
+			bdr.AddDetails("The stash entry is kept in case you need it again.")
+		}
+		return bdr.Build()
+	}
+
+	if !pop {
+		return nil
+	}
+	return printDroppedStash(s)
+}
+
+func stashDrop(ctx context.Context, dEnv *env.DoltEnv, name string) errhand.VerboseError {
+	s, err := stash.Get(ctx, dEnv.DoltDB, name)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	err = stash.Drop(ctx, dEnv.DoltDB, s)
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+
+	return printDroppedStash(s)
+}
+
+// stashWriter returns the stash.WriteFunc which writes the working set of |dEnv|.
+func stashWriter(ctx context.Context, dEnv *env.DoltEnv) stash.WriteFunc {
+	return func(ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error {
+		return dEnv.UpdateWorkingSetAndHeads(ctx, ws, heads, prevHeads)
+	}
+}
+
+func printDroppedStash(s *stash.Stash) errhand.VerboseError {
+	h, err := s.Commit.HashOf()
+	if err != nil {
+		return errhand.VerboseErrorFromError(err)
+	}
+	cli.Println(fmt.Sprintf("Dropped %s (%s)", s.Name(), h.String()))
+	return nil
+}
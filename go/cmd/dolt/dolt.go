@@ -79,6 +79,9 @@ var doltCommand = cli.NewSubCommandHandler("dolt", "it's git for data", []cli.Co
 	cnfcmds.Commands,
 	commands.CherryPickCmd{},
 	commands.RevertCmd{},
+	commands.RebaseCmd{},
+	commands.StashCmd{},
+	commands.ReflogCmd{},
 	commands.CloneCmd{},
 	commands.FetchCmd{},
 	commands.PullCmd{},
@@ -86,6 +89,7 @@ var doltCommand = cli.NewSubCommandHandler("dolt", "it's git for data", []cli.Co
 	commands.ConfigCmd{},
 	commands.RemoteCmd{},
 	commands.BackupCmd{},
+	commands.BundleCmd{},
 	commands.LoginCmd{},
 	credcmds.Commands,
 	commands.LsCmd{},
@@ -99,7 +103,9 @@ var doltCommand = cli.NewSubCommandHandler("dolt", "it's git for data", []cli.Co
 	indexcmds.Commands,
 	commands.ReadTablesCmd{},
 	commands.GarbageCollectionCmd{},
+	commands.FsckCmd{},
 	commands.FilterBranchCmd{},
+	commands.SquashHistoryCmd{},
 	commands.MergeBaseCmd{},
 	commands.RootsCmd{},
 	commands.VersionCmd{VersionStr: Version},
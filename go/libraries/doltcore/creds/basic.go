@@ -0,0 +1,61 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creds
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+const basicAuthPrefix = "Basic "
+
+// BasicCreds are per rpc credentials which send a user name and password in a basic authorization header. They are
+// used to authenticate with the SQL users of remotes served by dolt sql-server.
+type BasicCreds struct {
+	User     string
+	Password string
+}
+
+func (bc BasicCreds) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	userPass := base64.StdEncoding.EncodeToString([]byte(bc.User + ":" + bc.Password))
+	return map[string]string{
+		"authorization": basicAuthPrefix + userPass,
+	}, nil
+}
+
+func (bc BasicCreds) RequireTransportSecurity() bool {
+	return false
+}
+
+// ParseBasicAuthorization parses the user name and password from the value of a basic authorization header. It
+// returns false if |authorization| is not a valid basic authorization header.
+func ParseBasicAuthorization(authorization string) (BasicCreds, bool) {
+	if len(authorization) < len(basicAuthPrefix) || !strings.EqualFold(authorization[:len(basicAuthPrefix)], basicAuthPrefix) {
+		return BasicCreds{}, false
+	}
+
+	userPass, err := base64.StdEncoding.DecodeString(authorization[len(basicAuthPrefix):])
+	if err != nil {
+		return BasicCreds{}, false
+	}
+
+	idx := strings.IndexByte(string(userPass), ':')
+	if idx == -1 {
+		return BasicCreds{}, false
+	}
+
+	return BasicCreds{User: string(userPass[:idx]), Password: string(userPass[idx+1:])}, true
+}
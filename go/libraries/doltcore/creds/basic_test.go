@@ -0,0 +1,40 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package creds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasicCredsRoundTrip(t *testing.T) {
+	bc := BasicCreds{User: "root", Password: "pass:word"}
+	md, err := bc.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+
+	parsed, ok := ParseBasicAuthorization(md["authorization"])
+	require.True(t, ok)
+	assert.Equal(t, bc, parsed)
+
+	_, ok = ParseBasicAuthorization("Bearer abc")
+	assert.False(t, ok)
+	_, ok = ParseBasicAuthorization("Basic !!!")
+	assert.False(t, ok)
+	_, ok = ParseBasicAuthorization("Basic cm9vdA==")
+	assert.False(t, ok)
+}
@@ -47,6 +47,10 @@ const (
 
 	//AWSCredsProfile is a creation parameter that can be used to specify which AWS profile to use.
 	AWSCredsProfile = "aws-creds-profile"
+
+	// AWSEndpointParam is a creation parameter that can be used to set the endpoint of an S3 compatible service, like
+	// MinIO, for s3 remotes. Buckets are addressed by path when it is set.
+	AWSEndpointParam = "aws-endpoint"
 )
 
 var AWSCredTypes = []string{RoleCS.String(), EnvCS.String(), FileCS.String()}
@@ -175,6 +179,10 @@ func awsConfigFromParams(params map[string]interface{}) (session.Options, error)
 		awsConfig = awsConfig.WithRegion(val.(string))
 	}
 
+	if val, ok := params[AWSEndpointParam]; ok && len(val.(string)) != 0 {
+		awsConfig = awsConfig.WithEndpoint(val.(string)).WithS3ForcePathStyle(true)
+	}
+
 	awsCredsSource := RoleCS
 	if val, ok := params[AWSCredsTypeParam]; ok {
 		awsCredsSource = AWSCredentialSourceFromStr(val.(string))
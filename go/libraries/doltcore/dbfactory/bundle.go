@@ -0,0 +1,54 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbfactory
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/dolthub/dolt/go/store/blobstore"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/prolly/tree"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// BundleFactory is a DBFactory implementation for opening bundle files as read-only databases
+type BundleFactory struct {
+}
+
+// CreateDB opens the bundle file at the bundle:// url given as a read-only database
+func (fact BundleFactory) CreateDB(ctx context.Context, nbf *types.NomsBinFormat, urlObj *url.URL, params map[string]interface{}) (datas.Database, types.ValueReadWriter, tree.NodeStore, error) {
+	path, err := FilePathFromURL(urlObj)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bs, err := blobstore.OpenBundle(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	q := nbs.NewUnlimitedMemQuotaProvider()
+	st, err := nbs.NewBundleStore(ctx, nbf.VersionString(), bs, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	vrw := types.NewValueStore(st)
+	ns := tree.NewNodeStore(st)
+
+	return datas.NewTypesDatabase(vrw, ns), vrw, ns, nil
+}
@@ -30,6 +30,9 @@ const (
 	// AWSScheme
 	AWSScheme = "aws"
 
+	// S3Scheme
+	S3Scheme = "s3"
+
 	// GSScheme
 	GSScheme = "gs"
 
@@ -48,6 +51,9 @@ const (
 	// InMemBlobstore Scheme
 	LocalBSScheme = "localbs"
 
+	// BundleScheme
+	BundleScheme = "bundle"
+
 	defaultScheme       = HTTPSScheme
 	defaultMemTableSize = 256 * 1024 * 1024
 )
@@ -61,10 +67,12 @@ type DBFactory interface {
 // from external packages.
 var DBFactories = map[string]DBFactory{
 	AWSScheme:     AWSFactory{},
+	S3Scheme:      S3Factory{},
 	GSScheme:      GSFactory{},
 	FileScheme:    FileFactory{},
 	MemScheme:     MemFactory{},
 	LocalBSScheme: LocalBSFactory{},
+	BundleScheme:  BundleFactory{},
 	HTTPScheme:    NewDoltRemoteFactory(true),
 	HTTPSScheme:   NewDoltRemoteFactory(false),
 }
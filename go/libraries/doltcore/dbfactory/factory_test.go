@@ -16,10 +16,14 @@ package dbfactory
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
 	"github.com/dolthub/dolt/go/store/types"
 )
 
@@ -61,3 +65,36 @@ func TestCreateMemDB(t *testing.T) {
 	assert.NotNil(t, vrw)
 	assert.NotNil(t, ns)
 }
+
+func TestCreateFileDBKeepsChunkJournal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	urlStr := "file://" + filepath.ToSlash(dir)
+
+	t.Setenv(ChunkJournalEnvVar, "true")
+	db, _, _, err := CreateDB(ctx, types.Format_Default, urlStr, nil)
+	require.NoError(t, err)
+	ds, err := db.GetDataset(ctx, "ds")
+	require.NoError(t, err)
+	ds, err = datas.CommitValue(ctx, db, ds, types.String("a"))
+	require.NoError(t, err)
+	addr, ok := ds.MaybeHeadAddr()
+	require.True(t, ok)
+	require.NoError(t, db.Close())
+
+	// the database keeps its journal, and the commit in it, when it's opened without the journal enabled
+	t.Setenv(ChunkJournalEnvVar, "")
+	db, _, _, err = CreateDB(ctx, types.Format_Default, urlStr, nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	exists, err := nbs.ChunkJournalExists(dir)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	ds, err = db.GetDataset(ctx, "ds")
+	require.NoError(t, err)
+	actual, ok := ds.MaybeHeadAddr()
+	require.True(t, ok)
+	assert.Equal(t, addr, actual)
+}
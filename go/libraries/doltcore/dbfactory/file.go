@@ -34,6 +34,16 @@ const (
 
 	// DataDir is the directory internal to the DoltDir which holds the noms files.
 	DataDir = "noms"
+
+	// ChunkJournalEnvVar is an environment variable which, when set, makes local databases write new chunks and
+	// root hashes to an append-only chunk journal instead of writing a new table file for every commit. Databases
+	// which have a chunk journal already keep using it when the variable isn't set.
+	ChunkJournalEnvVar = "DOLT_ENABLE_CHUNK_JOURNAL"
+
+	// SkipTableFilesParam is a creation parameter holding a []string of table file names. The database is opened as
+	// if those table files were not listed in its manifests, which allows opening a database with damaged table files.
+	// The table files are dropped from the manifests as soon as the database is written to.
+	SkipTableFilesParam = "__DOLT__skip_table_files"
 )
 
 // DoltDataDir is the directory where noms files will be stored
@@ -45,21 +55,28 @@ type FileFactory struct {
 
 // CreateDB creates a local filesys backed database
 func (fact FileFactory) CreateDB(ctx context.Context, nbf *types.NomsBinFormat, urlObj *url.URL, params map[string]interface{}) (datas.Database, types.ValueReadWriter, tree.NodeStore, error) {
-	path, err := url.PathUnescape(urlObj.Path)
+	path, err := FilePathFromURL(urlObj)
 
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	path = filepath.FromSlash(path)
-	path = urlObj.Host + path
-
 	err = validateDir(path)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	var skip []string
+	if val, ok := params[SkipTableFilesParam]; ok {
+		skip = val.([]string)
+	}
+
 	q := nbs.NewUnlimitedMemQuotaProvider()
-	newGenSt, err := nbs.NewLocalStore(ctx, nbf.VersionString(), path, defaultMemTableSize, q)
+	var newGenSt *nbs.NomsBlockStore
+	if skip != nil {
+		newGenSt, err = nbs.NewLocalStoreWithoutTableFiles(ctx, nbf.VersionString(), path, skip, defaultMemTableSize, q)
+	} else {
+		newGenSt, err = newLocalStore(ctx, nbf.VersionString(), path, q)
+	}
 
 	if err != nil {
 		return nil, nil, nil, err
@@ -78,7 +95,12 @@ func (fact FileFactory) CreateDB(ctx context.Context, nbf *types.NomsBinFormat,
 		}
 	}
 
-	oldGenSt, err := nbs.NewLocalStore(ctx, newGenSt.Version(), oldgenPath, defaultMemTableSize, q)
+	var oldGenSt *nbs.NomsBlockStore
+	if skip != nil {
+		oldGenSt, err = nbs.NewLocalStoreWithoutTableFiles(ctx, newGenSt.Version(), oldgenPath, skip, defaultMemTableSize, q)
+	} else {
+		oldGenSt, err = nbs.NewLocalStore(ctx, newGenSt.Version(), oldgenPath, defaultMemTableSize, q)
+	}
 
 	if err != nil {
 		return nil, nil, nil, err
@@ -93,6 +115,37 @@ func (fact FileFactory) CreateDB(ctx context.Context, nbf *types.NomsBinFormat,
 	return datas.NewTypesDatabase(vrw, ns), vrw, ns, nil
 }
 
+// newLocalStore opens the store in |path|, using a chunk journal if it's enabled or if the store already has one. The
+// latest chunks and root hash of a store with a journal are only in its journal, so the journal is kept until it's
+// removed explicitly, with `dolt gc --remove-chunk-journal`.
+func newLocalStore(ctx context.Context, nbfVerStr string, path string, q nbs.MemoryQuotaProvider) (*nbs.NomsBlockStore, error) {
+	useJournal := os.Getenv(ChunkJournalEnvVar) != ""
+	if !useJournal {
+		exists, err := nbs.ChunkJournalExists(path)
+		if err != nil {
+			return nil, err
+		}
+		useJournal = exists
+	}
+
+	if useJournal {
+		return nbs.NewLocalJournalingStore(ctx, nbfVerStr, path, defaultMemTableSize, q)
+	}
+	return nbs.NewLocalStore(ctx, nbfVerStr, path, defaultMemTableSize, q)
+}
+
+// FilePathFromURL returns the local filesystem path of the database at the file:// url given.
+func FilePathFromURL(urlObj *url.URL) (string, error) {
+	path, err := url.PathUnescape(urlObj.Path)
+
+	if err != nil {
+		return "", err
+	}
+
+	path = filepath.FromSlash(path)
+	return urlObj.Host + path, nil
+}
+
 func validateDir(path string) error {
 	info, err := os.Stat(path)
 
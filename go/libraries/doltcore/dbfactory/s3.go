@@ -0,0 +1,85 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbfactory
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/dolthub/dolt/go/store/blobstore"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/prolly/tree"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// defaultS3Region is the region used for S3 compatible services when none is configured. It is the region MinIO
+// uses by default.
+const defaultS3Region = "us-east-1"
+
+// S3Factory is a DBFactory implementation for creating databases stored entirely in an S3 bucket, including the
+// manifest, which is updated with conditional writes. Unlike AWSFactory it does not need DynamoDB, so it works with S3
+// compatible services like MinIO. Urls are of the form s3://bucket/path/to/database.
+type S3Factory struct {
+}
+
+// CreateDB creates an S3 backed database
+func (fact S3Factory) CreateDB(ctx context.Context, nbf *types.NomsBinFormat, urlObj *url.URL, params map[string]interface{}) (datas.Database, types.ValueReadWriter, tree.NodeStore, error) {
+	var db datas.Database
+	if urlObj.Host == "" {
+		return nil, nil, nil, errors.New("s3 url has an invalid format")
+	}
+
+	dbPath, err := validatePath(urlObj.Path)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	opts, err := awsConfigFromParams(params)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sess := session.Must(session.NewSessionWithOptions(opts))
+	if aws.StringValue(sess.Config.Region) == "" {
+		sess.Config.Region = aws.String(defaultS3Region)
+	}
+
+	_, err = sess.Config.Credentials.Get()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bs := blobstore.NewS3Blobstore(s3.New(sess), urlObj.Host, dbPath)
+	q := nbs.NewUnlimitedMemQuotaProvider()
+	s3Store, err := nbs.NewBSStore(ctx, nbf.VersionString(), bs, defaultMemTableSize, q)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	vrw := types.NewValueStore(s3Store)
+	ns := tree.NewNodeStore(s3Store)
+	db = datas.NewTypesDatabase(vrw, ns)
+
+	return db, vrw, ns, nil
+}
@@ -78,6 +78,13 @@ func SummaryForTableDelta(ctx context.Context, ch chan DiffSummaryProgress, td T
 		return errhand.BuildDError("diff summary will not compute due to primary key set change with table %s", td.CurName()).Build()
 	}
 
+	// the row data of an added or dropped table is diffed against an empty index with the same schema
+	if td.IsAdd() {
+		fromSch = toSch
+	} else if td.IsDrop() {
+		toSch = fromSch
+	}
+
 	keyless, err := td.IsKeyless(ctx)
 	if err != nil {
 		return err
@@ -246,28 +253,196 @@ func prollyCountCellDiff(mapping val.OrdinalMapping, fromD, toD val.TupleDesc, f
 	changed := uint64(0)
 	for i, j := range mapping {
 		newCols--
-		if j == -1 {
-			// column was dropped
+		if prollyCellChanged(fromD, toD, from, to, i, j) {
 			changed++
-			continue
 		}
+	}
 
-		if fromD.Types[i].Enc != toD.Types[j].Enc {
-			// column type is different
-			changed++
-			continue
+	// some columns were added
+	changed += newCols
+	return changed
+}
+
+// prollyCellChanged returns whether the column |i| of |from| was dropped, which
+// is a |j| of -1, or is different in the column |j| of |to|.
+func prollyCellChanged(fromD, toD val.TupleDesc, from, to val.Tuple, i, j int) bool {
+	if j == -1 {
+		// column was dropped
+		return true
+	}
+
+	if fromD.Types[i].Enc != toD.Types[j].Enc {
+		// column type is different
+		return true
+	}
+
+	// column was modified
+	return fromD.CompareField(toD.GetField(j, to), i, from) != 0
+}
+
+// ModifiedColumnsForTableDelta returns the tags of the columns in |tags|, which must exist with the same definition on
+// both sides of |td|, that have a different value in any of the rows changed by |td|. The rows are diffed the same way
+// as for the diff summary, and the diff stops as soon as all the columns were found to be modified. The rows of keyless
+// tables are only ever added or removed, so every non-NULL cell of a changed row is a modification.
+func ModifiedColumnsForTableDelta(ctx context.Context, td TableDelta, tags []uint64) (map[uint64]bool, error) {
+	fromSch, toSch, err := td.GetSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keyless, err := td.IsKeyless(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fromRows, toRows, err := td.GetRowData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if types.IsFormat_DOLT(td.Format()) {
+		return modifiedProllyColumns(ctx, keyless, fromRows, toRows, fromSch, toSch, tags)
+	}
+	return modifiedNomsColumns(ctx, keyless, fromRows, toRows, tags)
+}
+
+func modifiedProllyColumns(ctx context.Context, keyless bool, from, to durable.Index, fromSch, toSch schema.Schema, tags []uint64) (map[uint64]bool, error) {
+	_, vMapping, err := schema.MapSchemaBasedOnTagAndName(fromSch, toSch)
+	if err != nil {
+		return nil, err
+	}
+
+	f := durable.ProllyMapFromIndex(from)
+	t := durable.ProllyMapFromIndex(to)
+	fKD, fVD := f.Descriptors()
+	tKD, tVD := t.Descriptors()
+
+	// the values of keyless rows come after their cardinality
+	offset := 0
+	if keyless {
+		offset = 1
+	}
+
+	modified := make(map[uint64]bool)
+	err = prolly.DiffMaps(ctx, f, t, func(ctx context.Context, change tree.Diff) error {
+		for _, tag := range tags {
+			if modified[tag] {
+				continue
+			}
+
+			var changed bool
+			if i, ok := fromSch.GetPKCols().TagToIdx[tag]; ok {
+				// the key of a modified row is unchanged
+				switch change.Type {
+				case tree.AddedDiff:
+					changed = !tKD.IsNull(i, val.Tuple(change.Key))
+				case tree.RemovedDiff:
+					changed = !fKD.IsNull(i, val.Tuple(change.Key))
+				}
+			} else {
+				i := fromSch.GetNonPKCols().TagToIdx[tag]
+				j := vMapping[i]
+				switch {
+				case change.Type == tree.AddedDiff || keyless && change.Type == tree.ModifiedDiff:
+					changed = !tVD.IsNull(j+offset, val.Tuple(change.To))
+				case change.Type == tree.RemovedDiff:
+					changed = !fVD.IsNull(i+offset, val.Tuple(change.From))
+				default:
+					changed = prollyCellChanged(fVD, tVD, val.Tuple(change.From), val.Tuple(change.To), i, j)
+				}
+			}
+			if changed {
+				modified[tag] = true
+			}
 		}
 
-		if fromD.CompareField(toD.GetField(j, to), i, from) != 0 {
-			// column was modified
-			changed++
-			continue
+		if len(modified) == len(tags) {
+			return io.EOF
 		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
 	}
+	return modified, nil
+}
 
-	// some columns were added
-	changed += newCols
-	return changed
+func modifiedNomsColumns(ctx context.Context, keyless bool, from, to durable.Index, tags []uint64) (modified map[uint64]bool, err error) {
+	ad := NewAsyncDiffer(1024)
+	ad.Start(ctx, durable.NomsMapFromIndex(from), durable.NomsMapFromIndex(to))
+	defer func() {
+		if cerr := ad.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	modified = make(map[uint64]bool)
+	for len(modified) < len(tags) {
+		diffs, more, err := ad.GetDiffs(100, time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, df := range diffs {
+			fromVals, toVals, err := nomsTaggedValues(keyless, df)
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range tags {
+				fv, tv := fromVals[tag], toVals[tag]
+				if types.IsNull(fv) && types.IsNull(tv) {
+					continue
+				} else if types.IsNull(fv) || types.IsNull(tv) || !fv.Equals(tv) {
+					modified[tag] = true
+				}
+			}
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return modified, nil
+}
+
+// nomsTaggedValues returns the values of the row changed by |df| before and after the change, keyed by their tags. The
+// values of a row that doesn't exist on a side of the change are empty. A changed keyless row is only returned after
+// the change, or before it if it was removed.
+func nomsTaggedValues(keyless bool, df *diff.Difference) (from, to row.TaggedValues, err error) {
+	parse := func(v types.Value) (row.TaggedValues, error) {
+		if v == nil {
+			return nil, nil
+		}
+		vals, err := row.ParseTaggedValues(v.(types.Tuple))
+		if err != nil {
+			return nil, err
+		}
+		if !keyless {
+			keyVals, err := row.ParseTaggedValues(df.KeyValue.(types.Tuple))
+			if err != nil {
+				return nil, err
+			}
+			for tag, v := range keyVals {
+				vals[tag] = v
+			}
+		}
+		return vals, nil
+	}
+
+	oldValue := df.OldValue
+	if keyless && df.NewValue != nil {
+		oldValue = nil
+	}
+	from, err = parse(oldValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err = parse(df.NewValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
 }
 
 func reportNomsPkChanges(ctx context.Context, change *diff.Difference, ch chan<- DiffSummaryProgress) error {
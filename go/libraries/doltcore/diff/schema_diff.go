@@ -16,6 +16,7 @@ package diff
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
 	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
@@ -204,3 +205,51 @@ func DiffForeignKeys(fromFks, toFKs []doltdb.ForeignKey) (diffs []ForeignKeyDiff
 	}
 	return diffs
 }
+
+type CheckDifference struct {
+	DiffType SchemaChangeType
+	From     schema.Check
+	To       schema.Check
+}
+
+// DiffSchChecks matches two sets of Checks based on their names.
+// It returns matched and unmatched Checks as a slice of CheckDifferences.
+func DiffSchChecks(fromSch, toSch schema.Schema) (diffs []CheckDifference) {
+	toChecks := allChecks(toSch)
+	matched := make(map[string]bool)
+	for _, from := range allChecks(fromSch) {
+		d := CheckDifference{DiffType: SchDiffRemoved, From: from}
+		for _, to := range toChecks {
+			if !strings.EqualFold(from.Name(), to.Name()) {
+				continue
+			}
+			matched[strings.ToLower(to.Name())] = true
+			d.To = to
+			d.DiffType = SchDiffModified
+			if from.Expression() == to.Expression() && from.Enforced() == to.Enforced() {
+				d.DiffType = SchDiffNone
+			}
+			break
+		}
+		diffs = append(diffs, d)
+	}
+
+	for _, to := range toChecks {
+		if matched[strings.ToLower(to.Name())] {
+			continue
+		}
+		diffs = append(diffs, CheckDifference{
+			DiffType: SchDiffAdded,
+			To:       to,
+		})
+	}
+	return diffs
+}
+
+// allChecks returns the checks of the schema given, which has no check collection if it is schema.EmptySchema.
+func allChecks(sch schema.Schema) []schema.Check {
+	if sch.Checks() == nil {
+		return nil
+	}
+	return sch.Checks().AllChecks()
+}
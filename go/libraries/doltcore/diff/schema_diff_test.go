@@ -66,3 +66,48 @@ func TestDiffSchemas(t *testing.T) {
 		t.Error(diffs, "!=", expected)
 	}
 }
+
+func TestDiffSchChecks(t *testing.T) {
+	cols := schema.NewColCollection(schema.NewColumn("pk", 0, types.IntKind, true, schema.NotNullConstraint{}))
+	oldSch, err := schema.SchemaFromCols(cols)
+	require.NoError(t, err)
+	newSch, err := schema.SchemaFromCols(cols)
+	require.NoError(t, err)
+
+	_, err = oldSch.Checks().AddCheck("unchanged", "(pk > 0)", true)
+	require.NoError(t, err)
+	_, err = oldSch.Checks().AddCheck("dropped", "(pk < 100)", true)
+	require.NoError(t, err)
+	_, err = oldSch.Checks().AddCheck("expr_changed", "(pk <> 5)", true)
+	require.NoError(t, err)
+	_, err = oldSch.Checks().AddCheck("unenforced", "(pk <> 6)", true)
+	require.NoError(t, err)
+
+	_, err = newSch.Checks().AddCheck("unchanged", "(pk > 0)", true)
+	require.NoError(t, err)
+	_, err = newSch.Checks().AddCheck("EXPR_CHANGED", "(pk <> 7)", true)
+	require.NoError(t, err)
+	_, err = newSch.Checks().AddCheck("unenforced", "(pk <> 6)", false)
+	require.NoError(t, err)
+	_, err = newSch.Checks().AddCheck("added", "(pk < 1000)", true)
+	require.NoError(t, err)
+
+	diffs := DiffSchChecks(oldSch, newSch)
+	require.Len(t, diffs, 5)
+
+	actual := make(map[string]SchemaChangeType)
+	for _, d := range diffs {
+		if d.From != nil {
+			actual[d.From.Name()] = d.DiffType
+		} else {
+			actual[d.To.Name()] = d.DiffType
+		}
+	}
+	require.Equal(t, map[string]SchemaChangeType{
+		"unchanged":    SchDiffNone,
+		"dropped":      SchDiffRemoved,
+		"expr_changed": SchDiffModified,
+		"unenforced":   SchDiffModified,
+		"added":        SchDiffAdded,
+	}, actual)
+}
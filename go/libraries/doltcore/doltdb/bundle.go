@@ -0,0 +1,237 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/blobstore"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/datas/pull"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// BundlePrerequisitesKey is the key of the blob of a bundle which lists the commits it was created against, one hash
+// per line. A bundle with prerequisites only holds the history since them, and can only be unbundled into a database
+// which has them.
+const BundlePrerequisitesKey = "prerequisites"
+
+// ErrNoBundleRefs is returned when creating a bundle without any refs.
+var ErrNoBundleRefs = errors.New("refusing to create an empty bundle")
+
+// BundleRef is a ref of a bundle and the address it points at.
+type BundleRef struct {
+	Ref  ref.DoltRef
+	Addr hash.Hash
+}
+
+var bundleRefFilter = map[ref.RefType]struct{}{ref.BranchRefType: {}, ref.TagRefType: {}}
+
+// GetBundleRefs returns the branches and tags of this database, which are the refs a bundle holds.
+func (ddb *DoltDB) GetBundleRefs(ctx context.Context) ([]BundleRef, error) {
+	var refs []BundleRef
+	err := ddb.VisitRefsOfType(ctx, bundleRefFilter, func(r ref.DoltRef, addr hash.Hash) error {
+		refs = append(refs, BundleRef{Ref: r, Addr: addr})
+		return nil
+	})
+	return refs, err
+}
+
+// CreateBundle writes |refs| and the chunks they reference to a new bundle file at |path|. The chunks reachable from
+// the commits in |prerequisites| are left out, the bundle can only be unbundled into a database which has them.
+func (ddb *DoltDB) CreateBundle(
+	ctx context.Context,
+	tempDir string,
+	path string,
+	refs []BundleRef,
+	prerequisites hash.HashSet,
+	progChan chan pull.PullProgress,
+	statsCh chan pull.Stats,
+) error {
+	if len(refs) == 0 {
+		return ErrNoBundleRefs
+	}
+
+	excluded, err := ddb.reachableChunks(ctx, prerequisites)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp(tempDir, "bundle")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	q := nbs.NewUnlimitedMemQuotaProvider()
+	st, err := nbs.NewLocalStore(ctx, ddb.Format().VersionString(), dir, 0, q)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	bundleDB := DoltDBFromCS(st)
+	waf := excludingWalkAddrs(types.WalkAddrsForNBF(ddb.Format()), excluded)
+	for _, r := range refs {
+		err = bundleDB.pullChunks(ctx, tempDir, ddb, r.Addr, waf, progChan, statsCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range refs {
+		err = bundleDB.SetHead(ctx, r.Ref, r.Addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the bundle is written next to its destination, so that an existing file is only replaced by a complete bundle
+	f, err := os.CreateTemp(filepath.Dir(path), ".bundle")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	err = f.Chmod(0644)
+	if err == nil {
+		err = writeBundle(ctx, f, st, dir, prerequisites)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func writeBundle(ctx context.Context, f *os.File, st *nbs.NomsBlockStore, dir string, prerequisites hash.HashSet) error {
+	bw, err := blobstore.NewBundleWriter(f)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for h := range prerequisites {
+		sb.WriteString(h.String())
+		sb.WriteString("\n")
+	}
+
+	err = bw.Put(BundlePrerequisitesKey, strings.NewReader(sb.String()))
+	if err != nil {
+		return err
+	}
+
+	err = nbs.WriteBundle(ctx, st, dir, bw)
+	if err != nil {
+		return err
+	}
+
+	return bw.Close()
+}
+
+// ReadBundlePrerequisites returns the commits the bundle at |path| was created against.
+func ReadBundlePrerequisites(ctx context.Context, path string) (hash.HashSet, error) {
+	bs, err := blobstore.OpenBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := blobstore.GetBytes(ctx, bs, BundlePrerequisitesKey, blobstore.AllRange)
+	if blobstore.IsNotFoundError(err) {
+		return hash.NewHashSet(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	prerequisites := hash.NewHashSet()
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		h, ok := hash.MaybeParse(string(line))
+		if !ok {
+			return nil, errors.New("invalid commit hash in bundle prerequisites: " + string(line))
+		}
+		prerequisites.Insert(h)
+	}
+
+	return prerequisites, nil
+}
+
+// reachableChunks returns the addresses of all the chunks reachable from |roots|, including |roots| themselves.
+// Chunks this database doesn't have are left out, along with the chunks only reachable through them.
+func (ddb *DoltDB) reachableChunks(ctx context.Context, roots hash.HashSet) (hash.HashSet, error) {
+	cs := datas.ChunkStoreFromDatabase(ddb.db)
+	walk := types.WalkAddrsForNBF(ddb.Format())
+
+	reached := roots.Copy()
+	next := roots.Copy()
+	for len(next) > 0 {
+		batch := next
+		next = hash.NewHashSet()
+
+		mu := &sync.Mutex{}
+		var walkErr error
+		err := cs.GetMany(ctx, batch, func(ctx context.Context, c *chunks.Chunk) {
+			mu.Lock()
+			defer mu.Unlock()
+			if walkErr != nil {
+				return
+			}
+			walkErr = walk(*c, func(h hash.Hash, _ bool) error {
+				if !reached.Has(h) {
+					reached.Insert(h)
+					next.Insert(h)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		} else if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+
+	return reached, nil
+}
+
+// excludingWalkAddrs returns a chunk walk which leaves out the chunks in |excluded|, and the chunks only reachable
+// through them.
+func excludingWalkAddrs(walk pull.WalkAddrs, excluded hash.HashSet) pull.WalkAddrs {
+	return func(c chunks.Chunk, cb func(h hash.Hash, isleaf bool) error) error {
+		return walk(c, func(h hash.Hash, isleaf bool) error {
+			if excluded.Has(h) {
+				return nil
+			}
+			return cb(h, isleaf)
+		})
+	}
+}
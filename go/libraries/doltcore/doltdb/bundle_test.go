@@ -0,0 +1,110 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	dbDir := filepath.Join(dir, "db")
+	require.NoError(t, os.Mkdir(dbDir, os.ModePerm))
+
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, "file://"+filepath.ToSlash(dbDir), filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	branch := ref.NewBranchRef("main")
+	var hashes []hash.Hash
+	for i := 0; i < 2; i++ {
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		require.NoError(t, err)
+		root, err := head.GetRootValue(ctx)
+		require.NoError(t, err)
+		_, rootHash, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := datas.NewCommitMeta("Bill Billerson", "bigbillieb@fake.horse", fmt.Sprintf("commit %d", i))
+		require.NoError(t, err)
+		cm, err := ddb.Commit(ctx, rootHash, branch, meta)
+		require.NoError(t, err)
+		h, err := cm.HashOf()
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	refs, err := ddb.GetBundleRefs(ctx)
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, branch.String(), refs[0].Ref.String())
+	assert.Equal(t, hashes[1], refs[0].Addr)
+
+	assert.Equal(t, ErrNoBundleRefs, ddb.CreateBundle(ctx, dir, filepath.Join(dir, "empty.bundle"), nil, nil, nil, nil))
+
+	t.Run("full", func(t *testing.T) {
+		path := filepath.Join(dir, "full.bundle")
+		require.NoError(t, ddb.CreateBundle(ctx, dir, path, refs, hash.NewHashSet(), nil, nil))
+
+		prerequisites, err := ReadBundlePrerequisites(ctx, path)
+		require.NoError(t, err)
+		assert.Empty(t, prerequisites)
+
+		bundleDB, err := LoadDoltDB(ctx, types.Format_Default, "bundle://"+filepath.ToSlash(path), filesys.LocalFS)
+		require.NoError(t, err)
+		bundleRefs, err := bundleDB.GetBundleRefs(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, refs, bundleRefs)
+
+		// the whole history is in the bundle
+		cs, err := NewCommitSpec("main~2")
+		require.NoError(t, err)
+		cm, err := bundleDB.Resolve(ctx, cs, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, cm.NumParents())
+	})
+
+	t.Run("range", func(t *testing.T) {
+		path := filepath.Join(dir, "range.bundle")
+		require.NoError(t, ddb.CreateBundle(ctx, dir, path, refs, hash.NewHashSet(hashes[0]), nil, nil))
+
+		prerequisites, err := ReadBundlePrerequisites(ctx, path)
+		require.NoError(t, err)
+		assert.Equal(t, hash.NewHashSet(hashes[0]), prerequisites)
+
+		bundleDB, err := LoadDoltDB(ctx, types.Format_Default, "bundle://"+filepath.ToSlash(path), filesys.LocalFS)
+		require.NoError(t, err)
+		has, err := bundleDB.Has(ctx, hashes[1])
+		require.NoError(t, err)
+		assert.True(t, has)
+		has, err = bundleDB.Has(ctx, hashes[0])
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+}
@@ -41,6 +41,7 @@ type Rootish interface {
 type Commit struct {
 	vrw     types.ValueReadWriter
 	ns      tree.NodeStore
+	shallow *shallowCommits
 	parents []*datas.Commit
 	dCommit *datas.Commit
 }
@@ -48,11 +49,21 @@ type Commit struct {
 var _ Rootish = &Commit{}
 
 func NewCommit(ctx context.Context, vrw types.ValueReadWriter, ns tree.NodeStore, commit *datas.Commit) (*Commit, error) {
-	parents, err := datas.GetCommitParents(ctx, vrw, commit.NomsValue())
-	if err != nil {
-		return nil, err
+	return newCommit(ctx, vrw, ns, nil, commit)
+}
+
+// newCommit returns the Commit for |commit|. Commits in |shallow| are loaded without their parents, which aren't in
+// the database. The commits read from the Commit returned, like its parents, are loaded the same way.
+func newCommit(ctx context.Context, vrw types.ValueReadWriter, ns tree.NodeStore, shallow *shallowCommits, commit *datas.Commit) (*Commit, error) {
+	var parents []*datas.Commit
+	if !shallow.has(commit.Addr()) {
+		var err error
+		parents, err = datas.GetCommitParents(ctx, vrw, commit.NomsValue())
+		if err != nil {
+			return nil, err
+		}
 	}
-	return &Commit{vrw, ns, parents, commit}, nil
+	return &Commit{vrw, ns, shallow, parents, commit}, nil
 }
 
 // HashOf returns the hash of the commit
@@ -101,7 +112,7 @@ func (c *Commit) GetRootValue(ctx context.Context) (*RootValue, error) {
 }
 
 func (c *Commit) GetParent(ctx context.Context, idx int) (*Commit, error) {
-	return NewCommit(ctx, c.vrw, c.ns, c.parents[idx])
+	return newCommit(ctx, c.vrw, c.ns, c.shallow, c.parents[idx])
 }
 
 var ErrNoCommonAncestor = errors.New("no common ancestor")
@@ -117,7 +128,7 @@ func GetCommitAncestor(ctx context.Context, cm1, cm2 *Commit) (*Commit, error) {
 		return nil, err
 	}
 
-	return NewCommit(ctx, cm1.vrw, cm1.ns, targetCommit)
+	return newCommit(ctx, cm1.vrw, cm1.ns, cm1.shallow, targetCommit)
 }
 
 func getCommitAncestorAddr(ctx context.Context, c1, c2 *datas.Commit, vrw1, vrw2 types.ValueReadWriter, ns1, ns2 tree.NodeStore) (hash.Hash, error) {
@@ -30,12 +30,13 @@ import (
 )
 
 type PushOnWriteHook struct {
-	destDB datas.Database
-	tmpDir string
-	out    io.Writer
+	destDB      datas.Database
+	tmpDir      string
+	out         io.Writer
+	workingSets bool
 }
 
-var _ CommitHook = (*PushOnWriteHook)(nil)
+var _ WorkingSetHook = (*PushOnWriteHook)(nil)
 
 // NewPushOnWriteHook creates a ReplicateHook, parameterizaed by the backup database
 // and a local tempfile for pushing
@@ -48,6 +49,16 @@ func (ph *PushOnWriteHook) Execute(ctx context.Context, ds datas.Dataset, db dat
 	return pushDataset(ctx, ph.destDB, db, ph.tmpDir, ds)
 }
 
+// SetReplicateWorkingSets sets whether the hook pushes working sets in addition to refs
+func (ph *PushOnWriteHook) SetReplicateWorkingSets(replicate bool) {
+	ph.workingSets = replicate
+}
+
+// ReplicatesWorkingSets implements WorkingSetHook
+func (ph *PushOnWriteHook) ReplicatesWorkingSets() bool {
+	return ph.workingSets
+}
+
 // HandleError implements CommitHook
 func (ph *PushOnWriteHook) HandleError(ctx context.Context, err error) error {
 	if ph.out != nil {
@@ -70,9 +81,14 @@ func pushDataset(ctx context.Context, destDB, srcDB datas.Database, tempTableDir
 		return err
 	}
 
-	rf, err := ref.Parse(ds.ID())
-	if err != nil {
-		return err
+	// working sets are not refs, their dataset ids are used as they are
+	id := ds.ID()
+	if !ds.IsWorkingSet() {
+		rf, err := ref.Parse(id)
+		if err != nil {
+			return err
+		}
+		id = rf.String()
 	}
 
 	srcCS := datas.ChunkStoreFromDatabase(srcDB)
@@ -93,7 +109,7 @@ func pushDataset(ctx context.Context, destDB, srcDB datas.Database, tempTableDir
 		}
 	}
 
-	ds, err = destDB.GetDataset(ctx, rf.String())
+	ds, err = destDB.GetDataset(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -109,8 +125,9 @@ type PushArg struct {
 }
 
 type AsyncPushOnWriteHook struct {
-	out io.Writer
-	ch  chan PushArg
+	out         io.Writer
+	ch          chan PushArg
+	workingSets bool
 }
 
 const (
@@ -120,7 +137,7 @@ const (
 	asyncPushSyncReplica   = "async_push_sync_replica"
 )
 
-var _ CommitHook = (*AsyncPushOnWriteHook)(nil)
+var _ WorkingSetHook = (*AsyncPushOnWriteHook)(nil)
 
 // NewAsyncPushOnWriteHook creates a AsyncReplicateHook
 func NewAsyncPushOnWriteHook(bThreads *sql.BackgroundThreads, destDB *DoltDB, tmpDir string, logger io.Writer) (*AsyncPushOnWriteHook, error) {
@@ -145,6 +162,16 @@ func (ah *AsyncPushOnWriteHook) Execute(ctx context.Context, ds datas.Dataset, d
 	return nil
 }
 
+// SetReplicateWorkingSets sets whether the hook pushes working sets in addition to refs
+func (ah *AsyncPushOnWriteHook) SetReplicateWorkingSets(replicate bool) {
+	ah.workingSets = replicate
+}
+
+// ReplicatesWorkingSets implements WorkingSetHook
+func (ah *AsyncPushOnWriteHook) ReplicatesWorkingSets() bool {
+	return ah.workingSets
+}
+
 // HandleError implements CommitHook
 func (ah *AsyncPushOnWriteHook) HandleError(ctx context.Context, err error) error {
 	if ah.out != nil {
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"path/filepath"
 	"testing"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/utils/filesys"
 	"github.com/dolthub/dolt/go/libraries/utils/test"
 	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
 	"github.com/dolthub/dolt/go/store/types"
 )
 
@@ -158,6 +160,65 @@ func TestPushOnWriteHook(t *testing.T) {
 	})
 }
 
+func TestPushOnWriteHookWorkingSets(t *testing.T) {
+	ctx := context.Background()
+
+	// the puller requires databases on disk
+	destDB, err := LoadDoltDB(ctx, types.Format_Default, "file://"+t.TempDir(), filesys.LocalFS)
+	require.NoError(t, err)
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, "file://"+t.TempDir(), filesys.LocalFS)
+	require.NoError(t, err)
+	err = ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse")
+	require.NoError(t, err)
+
+	hook := NewPushOnWriteHook(destDB, t.TempDir())
+	ddb.SetCommitHooks(ctx, []CommitHook{hook})
+
+	root, err := mustResolveMain(t, ddb).GetRootValue(ctx)
+	require.NoError(t, err)
+	wsRef, err := ref.WorkingSetRefForHead(ref.NewBranchRef("main"))
+	require.NoError(t, err)
+
+	t.Run("working sets are not pushed by default", func(t *testing.T) {
+		ws := EmptyWorkingSet(wsRef).WithWorkingRoot(root).WithStagedRoot(root)
+		err = ddb.UpdateWorkingSet(ctx, wsRef, ws, hash.Hash{}, TodoWorkingSetMeta())
+		require.NoError(t, err)
+
+		_, err = destDB.ResolveWorkingSet(ctx, wsRef)
+		assert.Equal(t, ErrWorkingSetNotFound, err)
+	})
+
+	t.Run("push working sets", func(t *testing.T) {
+		hook.SetReplicateWorkingSets(true)
+
+		tSchema := createTestSchema(t)
+		rowData, _ := createTestRowData(t, ddb.vrw, tSchema)
+		tbl, err := CreateTestTable(ddb.vrw, ddb.ns, tSchema, rowData)
+		require.NoError(t, err)
+		workingRoot, err := root.PutTable(ctx, "test", tbl)
+		require.NoError(t, err)
+
+		ws, err := ddb.ResolveWorkingSet(ctx, wsRef)
+		require.NoError(t, err)
+		prevHash, err := ws.HashOf()
+		require.NoError(t, err)
+		err = ddb.UpdateWorkingSet(ctx, wsRef, ws.WithWorkingRoot(workingRoot), prevHash, TodoWorkingSetMeta())
+		require.NoError(t, err)
+
+		srcWs, err := ddb.ResolveWorkingSet(ctx, wsRef)
+		require.NoError(t, err)
+		destWs, err := destDB.ResolveWorkingSet(ctx, wsRef)
+		require.NoError(t, err)
+		srcHash, _ := srcWs.HashOf()
+		destHash, _ := destWs.HashOf()
+		assert.Equal(t, srcHash, destHash)
+
+		has, err := destWs.WorkingRoot().HasTable(ctx, "test")
+		require.NoError(t, err)
+		assert.True(t, has)
+	})
+}
+
 func TestLogHook(t *testing.T) {
 	msg := []byte("hello")
 	var err error
@@ -271,3 +332,68 @@ func TestAsyncPushOnWrite(t *testing.T) {
 		}
 	})
 }
+
+type testReplicationHook struct {
+	writeErr   error
+	replicated int
+}
+
+var _ ReplicationHook = (*testReplicationHook)(nil)
+
+func (h *testReplicationHook) Execute(ctx context.Context, ds datas.Dataset, db datas.Database) error {
+	return errors.New("replication hooks are not executed")
+}
+
+func (h *testReplicationHook) HandleError(ctx context.Context, err error) error {
+	return nil
+}
+
+func (h *testReplicationHook) SetLogger(ctx context.Context, wr io.Writer) error {
+	return nil
+}
+
+func (h *testReplicationHook) CheckWrite(ctx context.Context) error {
+	return h.writeErr
+}
+
+func (h *testReplicationHook) Replicate(ctx context.Context, db datas.Database) error {
+	h.replicated++
+	return nil
+}
+
+func TestReplicationHook(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, InMemDoltDB, filesys.LocalFS)
+	require.NoError(t, err)
+
+	hook := &testReplicationHook{}
+	ddb = ddb.AddCommitHooks(ctx, hook)
+
+	err = ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse")
+	require.NoError(t, err)
+	assert.Positive(t, hook.replicated)
+
+	replicated := hook.replicated
+	err = ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("other"), mustResolveMain(t, ddb))
+	require.NoError(t, err)
+	assert.Greater(t, hook.replicated, replicated)
+
+	replicated = hook.replicated
+	hook.writeErr = errors.New("read only")
+	err = ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("another"), mustResolveMain(t, ddb))
+	assert.Equal(t, hook.writeErr, err)
+	assert.Equal(t, replicated, hook.replicated)
+	assert.Equal(t, hook.writeErr, ddb.CheckWrite(ctx))
+
+	has, err := ddb.HasRef(ctx, ref.NewBranchRef("another"))
+	require.NoError(t, err)
+	assert.False(t, has)
+}
+
+func mustResolveMain(t *testing.T, ddb *DoltDB) *Commit {
+	cs, err := NewCommitSpec("main")
+	require.NoError(t, err)
+	commit, err := ddb.Resolve(context.Background(), cs, nil)
+	require.NoError(t, err)
+	return commit
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/dolthub/dolt/go/store/datas"
 	"github.com/dolthub/dolt/go/store/datas/pull"
 	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
 	"github.com/dolthub/dolt/go/store/prolly/tree"
 	"github.com/dolthub/dolt/go/store/types"
 	"github.com/dolthub/dolt/go/store/types/edits"
@@ -65,9 +66,11 @@ var ErrCannotDeleteLastBranch = errors.New("cannot delete the last branch")
 // Additionally the noms codebase uses panics in a way that is non idiomatic and We've opted to recover and return
 // errors in many cases.
 type DoltDB struct {
-	db  hooksDatabase
-	vrw types.ValueReadWriter
-	ns  tree.NodeStore
+	db      hooksDatabase
+	vrw     types.ValueReadWriter
+	ns      tree.NodeStore
+	shallow *shallowCommits
+	lazy    *lazyRemote
 }
 
 // DoltDBFromCS creates a DoltDB from a noms chunks.ChunkStore
@@ -76,7 +79,7 @@ func DoltDBFromCS(cs chunks.ChunkStore) *DoltDB {
 	ns := tree.NewNodeStore(cs)
 	db := datas.NewTypesDatabase(vrw, ns)
 
-	return &DoltDB{hooksDatabase{Database: db}, vrw, ns}
+	return &DoltDB{hooksDatabase{Database: db, vr: vrw, reflog: newMemRefLog()}, vrw, ns, newMemShallowCommits(), newMemLazyRemote()}
 }
 
 // HackDatasDatabaseFromDoltDB unwraps a DoltDB to a datas.Database.
@@ -116,7 +119,22 @@ func LoadDoltDBWithParams(ctx context.Context, nbf *types.NomsBinFormat, urlStr
 		return nil, err
 	}
 
-	return &DoltDB{hooksDatabase{Database: db}, vrw, ns}, nil
+	reflog, err := newRefLogForURL(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	shallow, err := newShallowCommitsForURL(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	lazy, err := newLazyRemoteForURL(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoltDB{hooksDatabase{Database: db, vr: vrw, reflog: reflog}, vrw, ns, shallow, lazy}, nil
 }
 
 // NomsRoot returns the hash of the noms dataset map
@@ -327,7 +345,7 @@ func (ddb *DoltDB) Resolve(ctx context.Context, cs *CommitSpec, cwb ref.DoltRef)
 		return nil, err
 	}
 
-	commit, err := NewCommit(ctx, ddb.vrw, ddb.ns, commitVal)
+	commit, err := newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, commitVal)
 	if err != nil {
 		return nil, err
 	}
@@ -341,7 +359,7 @@ func (ddb *DoltDB) ResolveCommitRef(ctx context.Context, ref ref.DoltRef) (*Comm
 	if err != nil {
 		return nil, err
 	}
-	return NewCommit(ctx, ddb.vrw, ddb.ns, commitVal)
+	return newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, commitVal)
 }
 
 // ResolveTag takes a TagRef and returns the corresponding Tag object.
@@ -359,7 +377,7 @@ func (ddb *DoltDB) ResolveTag(ctx context.Context, tagRef ref.TagRef) (*Tag, err
 		return nil, fmt.Errorf("tagRef head is not a tag")
 	}
 
-	return NewTag(ctx, tagRef.GetPath(), ds, ddb.vrw, ddb.ns)
+	return newTag(ctx, tagRef.GetPath(), ds, ddb.vrw, ddb.ns, ddb.shallow)
 }
 
 // ResolveWorkingSet takes a WorkingSetRef and returns the corresponding WorkingSet object.
@@ -378,7 +396,7 @@ func (ddb *DoltDB) ResolveWorkingSet(ctx context.Context, workingSetRef ref.Work
 		return nil, fmt.Errorf("workingSetRef head is not a workingSetRef")
 	}
 
-	return NewWorkingSet(ctx, workingSetRef.GetPath(), ddb.vrw, ddb.ns, ds)
+	return newWorkingSet(ctx, workingSetRef.GetPath(), ddb.vrw, ddb.ns, ddb.shallow, ds)
 }
 
 // TODO: convenience method to resolve the head commit of a branch.
@@ -425,7 +443,7 @@ func (ddb *DoltDB) ReadCommit(ctx context.Context, h hash.Hash) (*Commit, error)
 	if err != nil {
 		return nil, err
 	}
-	return NewCommit(ctx, ddb.vrw, ddb.ns, c)
+	return newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, c)
 }
 
 // Commit will update a branch's head value to be that of a previously committed root value hash
@@ -491,6 +509,18 @@ func (ddb *DoltDB) SetHead(ctx context.Context, ref ref.DoltRef, addr hash.Hash)
 	return err
 }
 
+// SetWorkingSetHead force sets the working set given to the working set struct at |addr|, which must already be in the
+// database, for example after pulling it from a remote.
+func (ddb *DoltDB) SetWorkingSetHead(ctx context.Context, workingSetRef ref.WorkingSetRef, addr hash.Hash) error {
+	ds, err := ddb.db.GetDataset(ctx, workingSetRef.String())
+	if err != nil {
+		return err
+	}
+
+	_, err = ddb.db.SetHead(ctx, ds, addr)
+	return err
+}
+
 // CommitWithParentSpecs commits the value hash given to the branch given, using the list of parent hashes given. Returns an
 // error if the value or any parents can't be resolved, or if anything goes wrong accessing the underlying storage.
 func (ddb *DoltDB) CommitWithParentSpecs(ctx context.Context, valHash hash.Hash, dref ref.DoltRef, parentCmSpecs []*CommitSpec, cm *datas.CommitMeta) (*Commit, error) {
@@ -569,7 +599,7 @@ func (ddb *DoltDB) CommitValue(ctx context.Context, dref ref.DoltRef, val types.
 		return nil, err
 	}
 
-	return NewCommit(ctx, ddb.vrw, ddb.ns, dc)
+	return newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, dc)
 }
 
 // dangling commits are unreferenced by any branch or ref. They are created in the course of programmatic updates
@@ -610,7 +640,7 @@ func (ddb *DoltDB) CommitDangling(ctx context.Context, val types.Value, opts dat
 		return nil, err
 	}
 
-	return NewCommit(ctx, ddb.vrw, ddb.ns, dcommit)
+	return newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, dcommit)
 }
 
 // ValueReadWriter returns the underlying noms database as a types.ValueReadWriter.
@@ -1092,7 +1122,102 @@ func (ddb *DoltDB) CommitWithWorkingSet(
 		return nil, err
 	}
 
-	return NewCommit(ctx, ddb.vrw, ddb.ns, dc)
+	return newCommit(ctx, ddb.vrw, ddb.ns, ddb.shallow, dc)
+}
+
+// SetHeadsWithWorkingSet combines SetHeadToCommit and ref deletion with UpdateWorkingSet. Each ref in |heads| is
+// set to its commit, or deleted if its commit is nil, and the working set is updated, in the same atomic transaction.
+// Like UpdateWorkingSet, it asserts that the working set hash given is still current. Each ref in |prevHeads| must
+// also still point at the hash given, or not exist if the hash is empty, or datas.ErrUnexpectedHead is returned.
+func (ddb *DoltDB) SetHeadsWithWorkingSet(
+	ctx context.Context,
+	heads map[ref.DoltRef]*Commit,
+	prevHeads map[ref.DoltRef]hash.Hash,
+	workingSetRef ref.WorkingSetRef, workingSet *WorkingSet,
+	prevHash hash.Hash,
+	meta *datas.WorkingSetMeta,
+) error {
+	wsDs, err := ddb.db.GetDataset(ctx, workingSetRef.String())
+	if err != nil {
+		return err
+	}
+
+	addrs := make(map[string]hash.Hash, len(heads))
+	for r, cm := range heads {
+		var addr hash.Hash
+		if cm != nil {
+			addr, err = cm.HashOf()
+			if err != nil {
+				return err
+			}
+		}
+		addrs[r.String()] = addr
+	}
+	prevAddrs := make(map[string]hash.Hash, len(prevHeads))
+	for r, addr := range prevHeads {
+		prevAddrs[r.String()] = addr
+	}
+
+	workingRootRef, stagedRef, mergeState, err := workingSet.writeValues(ctx, ddb)
+	if err != nil {
+		return err
+	}
+
+	_, err = ddb.db.SetHeadsWithWorkingSet(ctx, addrs, prevAddrs, wsDs, datas.WorkingSetSpec{
+		Meta:        meta,
+		WorkingRoot: workingRootRef,
+		StagedRoot:  stagedRef,
+		MergeState:  mergeState,
+	}, prevHash)
+
+	return err
+}
+
+// MoveRefs moves each branch or tag in |refs| to its commit in the same atomic transaction. Tags are replaced with new
+// tags at their commits, keeping their meta. Each ref must still point at its commit in |prevRefs|, or
+// datas.ErrUnexpectedHead is returned and none of them are moved.
+func (ddb *DoltDB) MoveRefs(ctx context.Context, refs map[ref.DoltRef]*Commit, prevRefs map[ref.DoltRef]hash.Hash) error {
+	addrs := make(map[string]hash.Hash, len(refs))
+	prevAddrs := make(map[string]hash.Hash, len(refs))
+	for r, cm := range refs {
+		addr, err := cm.HashOf()
+		if err != nil {
+			return err
+		}
+		prevAddr, ok := prevRefs[r]
+		if !ok {
+			return fmt.Errorf("no previous commit given for ref %s", r.String())
+		}
+
+		if r.GetType() != ref.TagRefType {
+			addrs[r.String()] = addr
+			prevAddrs[r.String()] = prevAddr
+			continue
+		}
+
+		// the previous commit of a tag is checked here, and its dataset is checked by the update
+		ds, err := ddb.db.GetDataset(ctx, r.String())
+		if err != nil {
+			return err
+		}
+		if !ds.HasHead() || !ds.IsTag() {
+			return datas.ErrUnexpectedHead
+		}
+		meta, tagged, err := ds.HeadTag()
+		if err != nil {
+			return err
+		}
+		if tagged != prevAddr {
+			return datas.ErrUnexpectedHead
+		}
+		addrs[r.String()], err = datas.WriteTag(ctx, ddb.vrw, addr, meta)
+		if err != nil {
+			return err
+		}
+		prevAddrs[r.String()], _ = ds.MaybeHeadAddr()
+	}
+
+	return ddb.db.SetHeads(ctx, addrs, prevAddrs)
 }
 
 // DeleteWorkingSet deletes the working set given
@@ -1151,11 +1276,42 @@ func (ddb *DoltDB) Rebase(ctx context.Context) error {
 
 // GC performs garbage collection on this ddb. Values passed in |uncommitedVals| will be temporarily saved during gc.
 func (ddb *DoltDB) GC(ctx context.Context, uncommitedVals ...hash.Hash) error {
+	return ddb.gc(ctx, hash.NewHashSet(uncommitedVals...), nil)
+}
+
+// OnlineGC performs garbage collection on this ddb while it is in use. |inUse| is called once the collection has
+// begun, and returns the root values held by the users of the ddb, which are kept along with everything reachable
+// from its refs.
+func (ddb *DoltDB) OnlineGC(ctx context.Context, inUse func(ctx context.Context) ([]*RootValue, error)) error {
+	return ddb.gc(ctx, make(hash.HashSet), func(ctx context.Context) (hash.HashSet, error) {
+		roots, err := inUse(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// roots which have not been committed yet are only held in memory, so they are written to be walked
+		hashes := make(hash.HashSet, len(roots))
+		for _, root := range roots {
+			_, h, err := ddb.WriteRootValue(ctx, root)
+			if err != nil {
+				return nil, err
+			}
+			hashes.Insert(h)
+		}
+		return hashes, nil
+	})
+}
+
+func (ddb *DoltDB) gc(ctx context.Context, newGen hash.HashSet, inUse types.GCRootsFunc) error {
 	collector, ok := ddb.db.Database.(datas.GarbageCollector)
 	if !ok {
 		return fmt.Errorf("this database does not support garbage collection")
 	}
 
+	if ddb.IsLazy() {
+		return ErrLazyClone
+	}
+
 	err := ddb.pruneUnreferencedDatasets(ctx)
 	if err != nil {
 		return err
@@ -1165,7 +1321,6 @@ func (ddb *DoltDB) GC(ctx context.Context, uncommitedVals ...hash.Hash) error {
 	if err != nil {
 		return err
 	}
-	newGen := hash.NewHashSet(uncommitedVals...)
 	oldGen := make(hash.HashSet)
 	err = datasets.IterAll(ctx, func(keyStr string, h hash.Hash) error {
 		var isOldGen bool
@@ -1193,13 +1348,34 @@ func (ddb *DoltDB) GC(ctx context.Context, uncommitedVals ...hash.Hash) error {
 		return err
 	}
 
-	return collector.GC(ctx, oldGen, newGen)
+	// the history below the shallow commits of a shallow clone is not in the database
+	absent, err := ddb.MissingShallowHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	return collector.GC(ctx, oldGen, newGen, absent, inUse)
 }
 
 func (ddb *DoltDB) ShallowGC(ctx context.Context) error {
 	return datas.PruneTableFiles(ctx, ddb.db)
 }
 
+// CompressTableFiles rewrites the table files of the database into the zstd table file format.
+func (ddb *DoltDB) CompressTableFiles(ctx context.Context) error {
+	return datas.RewriteTableFiles(ctx, ddb.db, nbs.ZstdTableFileFormat)
+}
+
+// Close closes the database. It can't be used afterwards.
+func (ddb *DoltDB) Close() error {
+	return ddb.db.Close()
+}
+
+// SetConjoinPolicy changes the policy the database uses to conjoin its table files.
+func (ddb *DoltDB) SetConjoinPolicy(policy nbs.ConjoinPolicy) error {
+	return datas.SetConjoinPolicy(ddb.db, policy)
+}
+
 func (ddb *DoltDB) pruneUnreferencedDatasets(ctx context.Context) error {
 	dd, err := ddb.db.Datasets(ctx)
 	if err != nil {
@@ -1238,9 +1414,16 @@ func (ddb *DoltDB) pruneUnreferencedDatasets(ctx context.Context) error {
 // given, pulling all chunks reachable from the given targetHash. Pull progress
 // is communicated over the provided channel.
 func (ddb *DoltDB) PullChunks(ctx context.Context, tempDir string, srcDB *DoltDB, targetHash hash.Hash, progChan chan pull.PullProgress, statsCh chan pull.Stats) error {
+	return ddb.pullChunks(ctx, tempDir, srcDB, targetHash, types.WalkAddrsForNBF(srcDB.Format()), progChan, statsCh)
+}
+
+func (ddb *DoltDB) pullChunks(ctx context.Context, tempDir string, srcDB *DoltDB, targetHash hash.Hash, waf pull.WalkAddrs, progChan chan pull.PullProgress, statsCh chan pull.Stats) error {
 	srcCS := datas.ChunkStoreFromDatabase(srcDB.db)
 	destCS := datas.ChunkStoreFromDatabase(ddb.db)
-	waf := types.WalkAddrsForNBF(srcDB.Format())
+
+	if srcDB.IsShallow() {
+		waf = srcDB.pullFromShallowWalkAddrs(waf)
+	}
 
 	if datas.CanUsePuller(srcDB.db) && datas.CanUsePuller(ddb.db) {
 		puller, err := pull.NewPuller(ctx, tempDir, defaultChunksPerTF, srcCS, destCS, waf, targetHash, statsCh)
@@ -1257,6 +1440,10 @@ func (ddb *DoltDB) PullChunks(ctx context.Context, tempDir string, srcDB *DoltDB
 }
 
 func (ddb *DoltDB) Clone(ctx context.Context, destDB *DoltDB, eventCh chan<- pull.TableFileEvent) error {
+	// the table files of a lazy clone don't hold all of its chunks
+	if ddb.IsLazy() {
+		return ErrLazyClone
+	}
 	return pull.Clone(ctx, datas.ChunkStoreFromDatabase(ddb.db), datas.ChunkStoreFromDatabase(destDB.db), eventCh)
 }
 
@@ -1265,6 +1452,24 @@ func (ddb *DoltDB) SetCommitHooks(ctx context.Context, postHooks []CommitHook) *
 	return ddb
 }
 
+// AddCommitHooks appends |postHooks| to the commit hooks of this DoltDB.
+func (ddb *DoltDB) AddCommitHooks(ctx context.Context, postHooks ...CommitHook) *DoltDB {
+	hooks := append(append([]CommitHook{}, ddb.db.PostCommitHooks()...), postHooks...)
+	ddb.db = ddb.db.SetCommitHooks(ctx, hooks)
+	return ddb
+}
+
+// Replicate executes the replication hooks of this DoltDB. It is used after updates which are made to the chunk store
+// of the database directly, such as pushes served by a remote server.
+func (ddb *DoltDB) Replicate(ctx context.Context) error {
+	return ddb.db.replicate(ctx)
+}
+
+// CheckWrite returns an error if a replication hook of this DoltDB rejects updates.
+func (ddb *DoltDB) CheckWrite(ctx context.Context) error {
+	return ddb.db.checkWrite(ctx)
+}
+
 func (ddb *DoltDB) SetCommitHookLogger(ctx context.Context, wr io.Writer) *DoltDB {
 	if ddb.db.Database != nil {
 		ddb.db = ddb.db.SetCommitHookLogger(ctx, wr)
@@ -353,3 +353,60 @@ func TestLDNoms(t *testing.T) {
 		}
 	}
 }
+
+func TestMoveRefs(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, InMemDoltDB, filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	main := mustResolveMain(t, ddb)
+	mainHash, err := main.HashOf()
+	require.NoError(t, err)
+	root, err := main.GetRootValue(ctx)
+	require.NoError(t, err)
+	_, valHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+	meta, err := datas.NewCommitMeta("Bill Billerson", "bigbillieb@fake.horse", "other")
+	require.NoError(t, err)
+	other, err := ddb.CommitDanglingWithParentCommits(ctx, valHash, []*Commit{main}, meta)
+	require.NoError(t, err)
+	otherHash, err := other.HashOf()
+	require.NoError(t, err)
+
+	branch := ref.NewBranchRef("branch")
+	tagRef := ref.NewTagRef("tag")
+	require.NoError(t, ddb.NewBranchAtCommit(ctx, branch, main))
+	require.NoError(t, ddb.NewTagAtCommit(ctx, tagRef, main, datas.NewTagMeta("Bill Billerson", "bigbillieb@fake.horse", "tagged")))
+
+	// a ref which moved fails the update without moving any of them
+	refs := map[ref.DoltRef]*Commit{branch: other, tagRef: other}
+	err = ddb.MoveRefs(ctx, refs, map[ref.DoltRef]hash.Hash{branch: otherHash, tagRef: mainHash})
+	assert.ErrorIs(t, err, datas.ErrUnexpectedHead)
+	err = ddb.MoveRefs(ctx, refs, map[ref.DoltRef]hash.Hash{branch: mainHash, tagRef: otherHash})
+	assert.ErrorIs(t, err, datas.ErrUnexpectedHead)
+	cm, err := ddb.ResolveCommitRef(ctx, branch)
+	require.NoError(t, err)
+	h, err := cm.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, mainHash, h)
+
+	require.NoError(t, ddb.MoveRefs(ctx, refs, map[ref.DoltRef]hash.Hash{branch: mainHash, tagRef: mainHash}))
+	cm, err = ddb.ResolveCommitRef(ctx, branch)
+	require.NoError(t, err)
+	h, err = cm.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, otherHash, h)
+	tag, err := ddb.ResolveTag(ctx, tagRef)
+	require.NoError(t, err)
+	h, err = tag.Commit.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, otherHash, h)
+	assert.Equal(t, "tagged", tag.Meta.Description)
+
+	// the reflog records the commit of the tag
+	entries, err := ddb.ReadRefLog(ctx, "tag", false)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, otherHash, entries[0].Hash)
+}
@@ -35,6 +35,7 @@ var ErrBranchNotFound = errors.New("branch not found")
 var ErrTagNotFound = errors.New("tag not found")
 var ErrWorkingSetNotFound = errors.New("working set not found")
 var ErrWorkspaceNotFound = errors.New("workspace not found")
+var ErrStashNotFound = errors.New("stash not found")
 var ErrTableNotFound = errors.New("table not found")
 var ErrTableExists = errors.New("table already exists")
 var ErrAlreadyOnBranch = errors.New("Already on branch")
@@ -42,6 +43,10 @@ var ErrAlreadyOnWorkspace = errors.New("Already on workspace")
 
 var ErrNomsIO = errors.New("error reading from or writing to noms")
 
+// ErrUnreplicatedWrite is returned, wrapped, by updates of a database that were made durable but could not be
+// replicated by one of its ReplicationHooks. The update is not rolled back, so it must not be retried.
+var ErrUnreplicatedWrite = errors.New("the write was committed, but it could not be replicated")
+
 var ErrUpToDate = errors.New("up to date")
 var ErrIsAhead = errors.New("current fast forward from a to b. a is ahead of b already")
 var ErrIsBehind = errors.New("cannot reverse from b to a. b is a is behind a already")
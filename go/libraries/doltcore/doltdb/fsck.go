@@ -0,0 +1,507 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/pantoerr"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+const fsckBatchSize = 4096
+
+// FsckReport is the result of checking the chunks of a DoltDB.
+type FsckReport struct {
+	// ChunksChecked is the number of reachable chunks that were checked.
+	ChunksChecked int
+	// Missing holds the reachable chunks which are not in the database.
+	Missing hash.HashSet
+	// Corrupt holds the reachable chunks which can't be read, or whose contents don't match their address.
+	Corrupt hash.HashSet
+	// Damage lists the commits, working sets, tags and tables which depend on missing or corrupt chunks.
+	Damage []FsckDamage
+}
+
+// Damaged returns the chunks which are missing or corrupt.
+func (r *FsckReport) Damaged() hash.HashSet {
+	damaged := r.Missing.Copy()
+	damaged.InsertAll(r.Corrupt)
+	return damaged
+}
+
+// FsckDamage is a commit, working set or tag which depends on missing or corrupt chunks.
+type FsckDamage struct {
+	// Commit is the damaged commit, or the empty hash if the damage is in a working set or tag.
+	Commit hash.Hash
+	// Ref is the damaged working set or tag, or "" if the damage is in a commit.
+	Ref string
+	// Table is the damaged table, or "" if the damaged chunks aren't part of a table.
+	Table string
+	// Chunks are the missing or corrupt chunks the damaged data depends on.
+	Chunks hash.HashSet
+}
+
+// Fsck reads every chunk reachable from the refs and working sets of the database and checks that it's present and
+// that its contents match its address. If any chunk is missing or corrupt, the commits, working sets and tables that
+// depend on it are reported as well. |progress| is called with the number of chunks checked so far.
+func (ddb *DoltDB) Fsck(ctx context.Context, progress func(checked int)) (*FsckReport, error) {
+	// checking a lazy clone would fetch every chunk it doesn't have yet
+	if ddb.IsLazy() {
+		return nil, ErrLazyClone
+	}
+
+	walk := types.WalkAddrsForNBF(ddb.Format())
+	if ddb.IsShallow() {
+		// the history below the shallow commits of a shallow clone was never there to begin with
+		missing, err := ddb.MissingShallowHistory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		walk = shallowWalkAddrs(walk, missing)
+	}
+
+	f := &fsck{
+		ddb:     ddb,
+		cs:      datas.ChunkStoreFromDatabase(ddb.db),
+		walk:    walk,
+		missing: hash.NewHashSet(),
+		corrupt: hash.NewHashSet(),
+	}
+
+	checked, err := f.checkChunks(ctx, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FsckReport{
+		ChunksChecked: checked,
+		Missing:       f.missing,
+		Corrupt:       f.corrupt,
+	}
+
+	if len(f.missing) == 0 && len(f.corrupt) == 0 {
+		return report, nil
+	}
+
+	report.Damage, err = f.findDamage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// RepairChunks reads the chunks in |addrs| from |src| and writes them to this database, replacing any damaged copies.
+// It returns the chunks which |src| doesn't have, or has damaged copies of. Table files this database was opened
+// without are dropped, even if there are no chunks to repair.
+func (ddb *DoltDB) RepairChunks(ctx context.Context, src *DoltDB, addrs hash.HashSet) (hash.HashSet, error) {
+	unrepaired := addrs.Copy()
+	var repaired []chunks.Chunk
+	var mu sync.Mutex
+	err := datas.ChunkStoreFromDatabase(src.db).GetMany(ctx, addrs, func(_ context.Context, c *chunks.Chunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		if hash.Of(c.Data()) == c.Hash() {
+			repaired = append(repaired, *c)
+			unrepaired.Remove(c.Hash())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = datas.RepairChunks(ctx, ddb.db, repaired)
+	if err != nil {
+		return nil, err
+	}
+
+	return unrepaired, nil
+}
+
+type fsck struct {
+	ddb  *DoltDB
+	cs   chunks.ChunkStore
+	walk func(chunks.Chunk, func(h hash.Hash, isleaf bool) error) error
+
+	missing hash.HashSet
+	corrupt hash.HashSet
+
+	// commits holds every commit reachable from the refs of the database.
+	commits hash.HashSet
+	// below holds the damaged chunks reachable from each chunk walked by damagedBelow.
+	below map[hash.Hash]hash.HashSet
+}
+
+func (f *fsck) isDamaged(h hash.Hash) bool {
+	return f.missing.Has(h) || f.corrupt.Has(h)
+}
+
+// checkChunks visits every chunk reachable from the root of the chunk store, breadth first, and records the ones
+// which are missing or corrupt.
+func (f *fsck) checkChunks(ctx context.Context, progress func(checked int)) (int, error) {
+	root, err := f.cs.Root(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if root.IsEmpty() {
+		return 0, nil
+	}
+
+	visited := hash.NewHashSet(root)
+	next := hash.HashSlice{root}
+	checked := 0
+	for len(next) > 0 {
+		batch := next
+		if len(batch) > fsckBatchSize {
+			batch = batch[:fsckBatchSize]
+		}
+		next = next[len(batch):]
+
+		found, err := f.getChunks(ctx, hash.NewHashSet(batch...))
+		if err != nil {
+			return checked, err
+		}
+
+		for _, h := range batch {
+			c, ok := found[h]
+			if !ok {
+				if !f.corrupt.Has(h) {
+					f.missing.Insert(h)
+				}
+				continue
+			}
+			if hash.Of(c.Data()) != h {
+				f.corrupt.Insert(h)
+				continue
+			}
+
+			err = f.walk(c, func(child hash.Hash, _ bool) error {
+				if !visited.Has(child) {
+					visited.Insert(child)
+					next = append(next, child)
+				}
+				return nil
+			})
+			if err != nil {
+				// a chunk whose contents match its address, but which can't be decoded
+				f.corrupt.Insert(h)
+			}
+		}
+
+		checked += len(batch)
+		if progress != nil {
+			progress(checked)
+		}
+	}
+
+	return checked, nil
+}
+
+// getChunks reads the chunks in |addrs|. If they can't be read together, they are read one by one, and the ones that
+// fail to read are recorded as corrupt.
+func (f *fsck) getChunks(ctx context.Context, addrs hash.HashSet) (map[hash.Hash]chunks.Chunk, error) {
+	found := make(map[hash.Hash]chunks.Chunk, len(addrs))
+	var mu sync.Mutex
+	err := f.cs.GetMany(ctx, addrs, func(_ context.Context, c *chunks.Chunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		found[c.Hash()] = *c
+	})
+	if err == nil {
+		return found, nil
+	}
+
+	found = make(map[hash.Hash]chunks.Chunk, len(addrs))
+	for h := range addrs {
+		c, err := f.cs.Get(ctx, h)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		} else if err != nil {
+			f.corrupt.Insert(h)
+		} else if !c.IsEmpty() {
+			found[h] = c
+		}
+	}
+
+	return found, nil
+}
+
+// findDamage attributes the missing and corrupt chunks to the commits, working sets, tags and tables that depend on
+// them.
+func (f *fsck) findDamage(ctx context.Context) ([]FsckDamage, error) {
+	datasets, err := f.ddb.db.Datasets(ctx)
+	if err != nil {
+		// the map of refs itself is damaged
+		return nil, nil
+	}
+
+	var heads hash.HashSlice
+	var others []string
+	otherAddrs := make(map[string]hash.Hash)
+	err = datasets.IterAll(ctx, func(id string, addr hash.Hash) error {
+		if ref.IsWorkingSet(id) {
+			others = append(others, id)
+			otherAddrs[id] = addr
+			return nil
+		} else if !ref.IsRef(id) {
+			return nil
+		}
+
+		dref, err := ref.Parse(id)
+		if errors.Is(err, ref.ErrUnknownRefType) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if dref.GetType() == ref.TagRefType {
+			others = append(others, id)
+			otherAddrs[id] = addr
+			if f.isDamaged(addr) {
+				return nil
+			}
+
+			ds, err := f.ddb.db.GetDataset(ctx, id)
+			if err != nil {
+				return err
+			}
+			_, commitAddr, err := ds.HeadTag()
+			if err != nil {
+				return err
+			}
+			heads = append(heads, commitAddr)
+			return nil
+		}
+
+		heads = append(heads, addr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := f.findCommits(ctx, heads)
+	if err != nil {
+		return nil, err
+	}
+
+	f.commits = hash.NewHashSet(commits...)
+	f.below = make(map[hash.Hash]hash.HashSet)
+
+	var damage []FsckDamage
+	for _, h := range commits {
+		if f.isDamaged(h) {
+			damage = append(damage, FsckDamage{Commit: h, Chunks: hash.NewHashSet(h)})
+			continue
+		}
+
+		var roots []*RootValue
+		if root, err := f.commitRoot(ctx, h); err == nil {
+			roots = append(roots, root)
+		}
+
+		d, err := f.damageOf(ctx, h, roots)
+		if err != nil {
+			return nil, err
+		}
+		for i := range d {
+			d[i].Commit = h
+		}
+		damage = append(damage, d...)
+	}
+
+	for _, id := range others {
+		addr := otherAddrs[id]
+		if f.isDamaged(addr) {
+			damage = append(damage, FsckDamage{Ref: id, Chunks: hash.NewHashSet(addr)})
+			continue
+		}
+
+		var roots []*RootValue
+		if ref.IsWorkingSet(id) {
+			ws, err := f.ddb.ResolveWorkingSet(ctx, ref.NewWorkingSetRef(id))
+			if err == nil {
+				roots = append(roots, ws.WorkingRoot(), ws.StagedRoot())
+			}
+		}
+
+		d, err := f.damageOf(ctx, addr, roots)
+		if err != nil {
+			return nil, err
+		}
+		for i := range d {
+			d[i].Ref = id
+		}
+		damage = append(damage, d...)
+	}
+
+	return damage, nil
+}
+
+// findCommits returns every commit reachable from |heads|, including damaged ones.
+func (f *fsck) findCommits(ctx context.Context, heads hash.HashSlice) (hash.HashSlice, error) {
+	var commits hash.HashSlice
+	seen := hash.NewHashSet()
+	for len(heads) > 0 {
+		h := heads[len(heads)-1]
+		heads = heads[:len(heads)-1]
+		if seen.Has(h) {
+			continue
+		}
+		seen.Insert(h)
+		commits = append(commits, h)
+
+		if f.isDamaged(h) || f.ddb.IsShallowCommit(h) {
+			continue
+		}
+
+		// the parents are read from the commit itself, loading them would fail if they are damaged
+		cv, err := f.ddb.vrw.ReadValue(ctx, h)
+		if err != nil {
+			return nil, err
+		}
+		parents, err := datas.GetCommitParentAddrs(ctx, f.ddb.vrw.Format(), cv)
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, parents...)
+	}
+
+	sort.Sort(commits)
+	return commits, nil
+}
+
+// commitRoot returns the root value of commit |h| without loading any other commits.
+func (f *fsck) commitRoot(ctx context.Context, h hash.Hash) (*RootValue, error) {
+	cv, err := f.ddb.vrw.ReadValue(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := datas.GetCommittedValue(ctx, f.ddb.vrw, cv)
+	if err != nil {
+		return nil, err
+	}
+	if rv == nil {
+		return nil, errors.New("root value not found")
+	}
+	return newRootValue(f.ddb.vrw, f.ddb.ns, rv)
+}
+
+// damageOf returns the damage of the value in chunk |h|, split up by the tables of |roots|. Damaged chunks which
+// aren't part of any table are reported without a table name.
+func (f *fsck) damageOf(ctx context.Context, h hash.Hash, roots []*RootValue) ([]FsckDamage, error) {
+	all, err := f.damagedBelow(ctx, h)
+	if err != nil || len(all) == 0 {
+		return nil, err
+	}
+
+	tables := make(map[string]hash.HashSet)
+	var names []string
+	inTables := hash.NewHashSet()
+	for _, root := range roots {
+		if root == nil {
+			continue
+		}
+		// the table map of the root may be damaged itself, reading it panics if chunks are missing
+		var tableHashes map[string]hash.Hash
+		err := pantoerr.PanicToError("failed to read tables", func() (err error) {
+			tableHashes, err = root.MapTableHashes(ctx)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+
+		for name, th := range tableHashes {
+			damaged, err := f.damagedBelow(ctx, th)
+			if err != nil {
+				return nil, err
+			}
+			if len(damaged) == 0 {
+				continue
+			}
+
+			if _, ok := tables[name]; !ok {
+				tables[name] = hash.NewHashSet()
+				names = append(names, name)
+			}
+			tables[name].InsertAll(damaged)
+			inTables.InsertAll(damaged)
+		}
+	}
+
+	var damage []FsckDamage
+	sort.Strings(names)
+	for _, name := range names {
+		damage = append(damage, FsckDamage{Table: name, Chunks: tables[name]})
+	}
+
+	rest := hash.NewHashSet()
+	for d := range all {
+		if !inTables.Has(d) {
+			rest.Insert(d)
+		}
+	}
+	if len(rest) > 0 {
+		damage = append(damage, FsckDamage{Chunks: rest})
+	}
+
+	return damage, nil
+}
+
+// damagedBelow returns the damaged chunks reachable from |h|. Commits other than |h| aren't walked, they are
+// checked on their own.
+func (f *fsck) damagedBelow(ctx context.Context, h hash.Hash) (hash.HashSet, error) {
+	if f.isDamaged(h) {
+		return hash.NewHashSet(h), nil
+	}
+	if below, ok := f.below[h]; ok {
+		return below, nil
+	}
+
+	c, err := f.cs.Get(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	if c.IsEmpty() {
+		return nil, errors.New("chunk disappeared while checking the database: " + h.String())
+	}
+
+	below := hash.NewHashSet()
+	err = f.walk(c, func(child hash.Hash, _ bool) error {
+		if f.commits.Has(child) {
+			return nil
+		}
+		damaged, err := f.damagedBelow(ctx, child)
+		if err != nil {
+			return err
+		}
+		below.InsertAll(damaged)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f.below[h] = below
+	return below, nil
+}
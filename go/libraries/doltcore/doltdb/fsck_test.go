@@ -0,0 +1,54 @@
+// Copyright 2020 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/commands"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+)
+
+func TestFsck(t *testing.T) {
+	ctx := context.Background()
+	dEnv := dtestutils.CreateTestEnv()
+
+	setup := []testCommand{
+		{commands.SqlCmd{}, []string{"-q", "CREATE TABLE test (pk int PRIMARY KEY)"}},
+		{commands.AddCmd{}, []string{"."}},
+		{commands.CommitCmd{}, []string{"-m", "created test table"}},
+		{commands.BranchCmd{}, []string{"other"}},
+		{commands.TagCmd{}, []string{"v1"}},
+		{commands.SqlCmd{}, []string{"-q", "INSERT INTO test VALUES (0),(1),(2);"}},
+	}
+	for _, c := range setup {
+		exitCode := c.cmd.Exec(ctx, c.cmd.Name(), c.args, dEnv)
+		require.Equal(t, 0, exitCode)
+	}
+
+	var progress int
+	report, err := dEnv.DoltDB.Fsck(ctx, func(checked int) {
+		progress = checked
+	})
+	require.NoError(t, err)
+	assert.Greater(t, report.ChunksChecked, 0)
+	assert.Equal(t, report.ChunksChecked, progress)
+	assert.Empty(t, report.Damaged())
+	assert.Empty(t, report.Damage)
+}
@@ -16,7 +16,9 @@ package doltdb
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/dolthub/dolt/go/store/datas"
 	"github.com/dolthub/dolt/go/store/hash"
@@ -25,7 +27,10 @@ import (
 
 type hooksDatabase struct {
 	datas.Database
+	// vr reads the values of the database, like the tags set by SetHeads.
+	vr              types.ValueReader
 	postCommitHooks []CommitHook
+	reflog          refLog
 }
 
 // CommitHook is an abstraction for executing arbitrary commands after atomic database commits
@@ -38,6 +43,26 @@ type CommitHook interface {
 	SetLogger(ctx context.Context, wr io.Writer) error
 }
 
+// ReplicationHook is a CommitHook which replicates a database synchronously. It is executed after every update of the
+// database, including updates of working sets, and its errors are returned by the update instead of being passed to
+// HandleError, so that updates which have not been replicated are not acknowledged. The update is durable by the time
+// it is replicated, so these errors are wrapped in ErrUnreplicatedWrite.
+type ReplicationHook interface {
+	CommitHook
+	// CheckWrite returns an error if the database can not be written to. It is called before every update.
+	CheckWrite(ctx context.Context) error
+	// Replicate replicates |db| after an update.
+	Replicate(ctx context.Context, db datas.Database) error
+}
+
+// WorkingSetHook is a CommitHook which can also be executed after updates of working sets, so that they can be
+// replicated along with refs.
+type WorkingSetHook interface {
+	CommitHook
+	// ReplicatesWorkingSets returns whether the hook is executed after updates of working sets.
+	ReplicatesWorkingSets() bool
+}
+
 func (db hooksDatabase) SetCommitHooks(ctx context.Context, postHooks []CommitHook) hooksDatabase {
 	db.postCommitHooks = postHooks
 	return db
@@ -57,6 +82,9 @@ func (db hooksDatabase) PostCommitHooks() []CommitHook {
 func (db hooksDatabase) ExecuteCommitHooks(ctx context.Context, ds datas.Dataset) {
 	var err error
 	for _, hook := range db.postCommitHooks {
+		if _, ok := hook.(ReplicationHook); ok {
+			continue
+		}
 		err = hook.Execute(ctx, ds, db)
 		if err != nil {
 			hook.HandleError(ctx, err)
@@ -64,12 +92,78 @@ func (db hooksDatabase) ExecuteCommitHooks(ctx context.Context, ds datas.Dataset
 	}
 }
 
+// executeWorkingSetHooks executes the hooks which replicate working sets after |ds|, a working set, was updated.
+func (db hooksDatabase) executeWorkingSetHooks(ctx context.Context, ds datas.Dataset) {
+	var err error
+	for _, hook := range db.postCommitHooks {
+		if wh, ok := hook.(WorkingSetHook); !ok || !wh.ReplicatesWorkingSets() {
+			continue
+		}
+		err = hook.Execute(ctx, ds, db)
+		if err != nil {
+			hook.HandleError(ctx, err)
+		}
+	}
+}
+
+// checkWrite returns an error if any of the replication hooks of the database rejects updates.
+func (db hooksDatabase) checkWrite(ctx context.Context) error {
+	for _, hook := range db.postCommitHooks {
+		if rh, ok := hook.(ReplicationHook); ok {
+			if err := rh.CheckWrite(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replicate executes the replication hooks of the database, returning the first error wrapped in
+// ErrUnreplicatedWrite, since the update being replicated is durable already.
+func (db hooksDatabase) replicate(ctx context.Context) error {
+	for _, hook := range db.postCommitHooks {
+		if rh, ok := hook.(ReplicationHook); ok {
+			if err := rh.Replicate(ctx, db); err != nil {
+				return fmt.Errorf("%w: %s", ErrUnreplicatedWrite, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// recordRefUpdates appends the current heads of the datasets given to the reflog. The datasets have already been
+// updated at this point, so failing to write the reflog doesn't fail the update.
+func (db hooksDatabase) recordRefUpdates(dss ...datas.Dataset) {
+	entries := make([]RefLogEntry, len(dss))
+	for i, ds := range dss {
+		addr, _ := ds.MaybeHeadAddr()
+		entries[i] = RefLogEntry{Ref: ds.ID(), Hash: addr}
+	}
+	db.appendRefLog(entries...)
+}
+
+// appendRefLog appends |entries| to the reflog, timestamped with the current time.
+func (db hooksDatabase) appendRefLog(entries ...RefLogEntry) {
+	if db.reflog == nil {
+		return
+	}
+
+	now := time.Now()
+	for i := range entries {
+		entries[i].Timestamp = now
+	}
+	_ = db.reflog.appendEntries(entries...)
+}
+
 func (db hooksDatabase) CommitWithWorkingSet(
 	ctx context.Context,
 	commitDS, workingSetDS datas.Dataset,
 	val types.Value, workingSetSpec datas.WorkingSetSpec,
 	prevWsHash hash.Hash, opts datas.CommitOptions,
 ) (datas.Dataset, datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, datas.Dataset{}, err
+	}
 	commitDS, workingSetDS, err := db.Database.CommitWithWorkingSet(
 		ctx,
 		commitDS,
@@ -79,39 +173,156 @@ func (db hooksDatabase) CommitWithWorkingSet(
 		prevWsHash,
 		opts)
 	if err == nil {
+		db.recordRefUpdates(commitDS, workingSetDS)
 		db.ExecuteCommitHooks(ctx, commitDS)
+		db.executeWorkingSetHooks(ctx, workingSetDS)
+		err = db.replicate(ctx)
 	}
 	return commitDS, workingSetDS, err
 }
 
+func (db hooksDatabase) SetHeadsWithWorkingSet(
+	ctx context.Context,
+	heads, prevHeads map[string]hash.Hash,
+	workingSetDS datas.Dataset, workingSetSpec datas.WorkingSetSpec,
+	prevWsHash hash.Hash,
+) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
+	workingSetDS, err := db.Database.SetHeadsWithWorkingSet(ctx, heads, prevHeads, workingSetDS, workingSetSpec, prevWsHash)
+	if err != nil {
+		return workingSetDS, err
+	}
+
+	// the heads written are recorded rather than the current ones, which may have been updated again since
+	entries := make([]RefLogEntry, 0, len(heads)+1)
+	for id, addr := range heads {
+		entries = append(entries, RefLogEntry{Ref: id, Hash: addr})
+	}
+	wsAddr, _ := workingSetDS.MaybeHeadAddr()
+	db.appendRefLog(append(entries, RefLogEntry{Ref: workingSetDS.ID(), Hash: wsAddr})...)
+
+	dss := make([]datas.Dataset, 0, len(heads))
+	for id := range heads {
+		ds, err := db.Database.GetDataset(ctx, id)
+		if err != nil {
+			return workingSetDS, err
+		}
+		dss = append(dss, ds)
+	}
+	for _, ds := range dss {
+		db.ExecuteCommitHooks(ctx, ds)
+	}
+	db.executeWorkingSetHooks(ctx, workingSetDS)
+	return workingSetDS, db.replicate(ctx)
+}
+
+func (db hooksDatabase) SetHeads(ctx context.Context, heads, prevHeads map[string]hash.Hash) error {
+	if err := db.checkWrite(ctx); err != nil {
+		return err
+	}
+	if err := db.Database.SetHeads(ctx, heads, prevHeads); err != nil {
+		return err
+	}
+
+	entries := make([]RefLogEntry, 0, len(heads))
+	for id, addr := range heads {
+		// record the tagged commit rather than the tag itself
+		if !addr.IsEmpty() {
+			if cmAddr, err := datas.TagCommitAddr(ctx, db.vr, addr); err == nil {
+				addr = cmAddr
+			}
+		}
+		entries = append(entries, RefLogEntry{Ref: id, Hash: addr})
+	}
+	db.appendRefLog(entries...)
+
+	for id := range heads {
+		ds, err := db.Database.GetDataset(ctx, id)
+		if err != nil {
+			return err
+		}
+		db.ExecuteCommitHooks(ctx, ds)
+	}
+	return db.replicate(ctx)
+}
+
 func (db hooksDatabase) Commit(ctx context.Context, ds datas.Dataset, v types.Value, opts datas.CommitOptions) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
 	ds, err := db.Database.Commit(ctx, ds, v, opts)
 	if err == nil {
+		db.recordRefUpdates(ds)
 		db.ExecuteCommitHooks(ctx, ds)
+		err = db.replicate(ctx)
 	}
 	return ds, err
 }
 
 func (db hooksDatabase) SetHead(ctx context.Context, ds datas.Dataset, newHeadAddr hash.Hash) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
 	ds, err := db.Database.SetHead(ctx, ds, newHeadAddr)
 	if err == nil {
+		db.recordRefUpdates(ds)
 		db.ExecuteCommitHooks(ctx, ds)
+		err = db.replicate(ctx)
 	}
 	return ds, err
 }
 
 func (db hooksDatabase) FastForward(ctx context.Context, ds datas.Dataset, newHeadAddr hash.Hash) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
 	ds, err := db.Database.FastForward(ctx, ds, newHeadAddr)
 	if err == nil {
+		db.recordRefUpdates(ds)
 		db.ExecuteCommitHooks(ctx, ds)
+		err = db.replicate(ctx)
 	}
 	return ds, err
 }
 
 func (db hooksDatabase) Delete(ctx context.Context, ds datas.Dataset) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
 	ds, err := db.Database.Delete(ctx, ds)
 	if err == nil {
-		db.ExecuteCommitHooks(ctx, datas.NewHeadlessDataset(ds.Database(), ds.ID()))
+		headless := datas.NewHeadlessDataset(ds.Database(), ds.ID())
+		db.recordRefUpdates(headless)
+		db.ExecuteCommitHooks(ctx, headless)
+		err = db.replicate(ctx)
+	}
+	return ds, err
+}
+
+func (db hooksDatabase) Tag(ctx context.Context, ds datas.Dataset, commitAddr hash.Hash, opts datas.TagOptions) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
+	ds, err := db.Database.Tag(ctx, ds, commitAddr, opts)
+	if err == nil {
+		// record the tagged commit rather than the tag itself
+		db.appendRefLog(RefLogEntry{Ref: ds.ID(), Hash: commitAddr})
+		err = db.replicate(ctx)
+	}
+	return ds, err
+}
+
+func (db hooksDatabase) UpdateWorkingSet(ctx context.Context, ds datas.Dataset, workingSet datas.WorkingSetSpec, prevHash hash.Hash) (datas.Dataset, error) {
+	if err := db.checkWrite(ctx); err != nil {
+		return datas.Dataset{}, err
+	}
+	ds, err := db.Database.UpdateWorkingSet(ctx, ds, workingSet, prevHash)
+	if err == nil {
+		db.recordRefUpdates(ds)
+		db.executeWorkingSetHooks(ctx, ds)
+		err = db.replicate(ctx)
 	}
 	return ds, err
 }
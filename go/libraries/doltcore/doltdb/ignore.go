@@ -0,0 +1,102 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IgnorePattern is a row of the dolt_ignore table. Patterns are matched against whole table names, case-insensitively.
+// In a pattern, '*' matches any sequence of characters and '?' matches any single character.
+type IgnorePattern struct {
+	Pattern string
+	Ignore  bool
+}
+
+// IgnorePatterns is the contents of the dolt_ignore table.
+type IgnorePatterns []IgnorePattern
+
+// ErrConflictingIgnorePatterns is returned when a table name matches patterns of the dolt_ignore table that disagree
+// on whether it is ignored, and none of them is more specific than the others.
+type ErrConflictingIgnorePatterns struct {
+	TableName string
+	Patterns  []IgnorePattern
+}
+
+func (e ErrConflictingIgnorePatterns) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("the table %s matches conflicting patterns in %s:", e.TableName, IgnoreTableName))
+	for _, p := range e.Patterns {
+		sb.WriteString(fmt.Sprintf("\n%s: %t", p.Pattern, p.Ignore))
+	}
+	return sb.String()
+}
+
+// compilePattern returns the regular expression equivalent to the ignore pattern given.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// IsTableNameIgnored returns whether the table name given is ignored by these patterns. When a table name matches
+// patterns that disagree, the most specific pattern wins. A pattern is more specific than another one if the other
+// pattern matches it, e.g. "tmp_keep" is more specific than "tmp_*". If no pattern is the most specific one, an
+// ErrConflictingIgnorePatterns is returned.
+func (ip IgnorePatterns) IsTableNameIgnored(tableName string) (bool, error) {
+	var matches []IgnorePattern
+	var regexps []*regexp.Regexp
+	for _, p := range ip {
+		re, err := compilePattern(p.Pattern)
+		if err != nil {
+			return false, err
+		}
+		if re.MatchString(tableName) {
+			matches = append(matches, p)
+			regexps = append(regexps, re)
+		}
+	}
+
+	for _, p := range matches {
+		mostSpecific := true
+		for j, q := range matches {
+			if q.Ignore != p.Ignore && !regexps[j].MatchString(p.Pattern) {
+				mostSpecific = false
+				break
+			}
+		}
+		if mostSpecific {
+			return p.Ignore, nil
+		}
+	}
+
+	if len(matches) == 0 {
+		return false, nil
+	}
+	return false, ErrConflictingIgnorePatterns{TableName: tableName, Patterns: matches}
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTableNameIgnored(t *testing.T) {
+	patterns := IgnorePatterns{
+		{Pattern: "tmp_*", Ignore: true},
+		{Pattern: "staging_*", Ignore: true},
+		{Pattern: "tmp_keep", Ignore: false},
+		{Pattern: "ab?", Ignore: true},
+		{Pattern: "*_a", Ignore: false},
+	}
+
+	tests := []struct {
+		name     string
+		ignored  bool
+		conflict bool
+	}{
+		{name: "tmp_foo", ignored: true},
+		{name: "TMP_FOO", ignored: true},
+		{name: "staging_", ignored: true},
+		{name: "tmp_keep", ignored: false},
+		{name: "abc", ignored: true},
+		{name: "abcd", ignored: false},
+		{name: "ab", ignored: false},
+		{name: "users", ignored: false},
+		{name: "tmp", ignored: false},
+		{name: "staging_a", conflict: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ignored, err := patterns.IsTableNameIgnored(test.name)
+			if test.conflict {
+				require.Error(t, err)
+				assert.IsType(t, ErrConflictingIgnorePatterns{}, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.ignored, ignored)
+		})
+	}
+}
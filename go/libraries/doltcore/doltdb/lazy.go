@@ -0,0 +1,151 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
+)
+
+// LazyFileName is the name of the file in the data directory of a lazy clone that names the remote it fetches the
+// chunks it doesn't have from.
+const LazyFileName = "lazy"
+
+// ErrLazyNotSupported is returned when making a database lazy whose storage can't fetch chunks on demand.
+var ErrLazyNotSupported = errors.New("this database does not support fetching chunks on demand")
+
+// ErrLazyClone is returned by operations which would need every chunk of a lazy clone.
+var ErrLazyClone = errors.New("this operation is not supported on a lazy clone, which does not have all of its data locally")
+
+// lazyRemote is the name of the remote a lazy clone fetches the chunks it doesn't have from. Only lazy clones have
+// one.
+type lazyRemote struct {
+	// path is the file the name is persisted to. The remote of a database that is not stored on the local filesystem
+	// only lives as long as the process that set it.
+	path string
+	mu   *sync.Mutex
+	name string
+}
+
+// newLazyRemoteForURL returns the lazy remote of the database at the url given.
+func newLazyRemoteForURL(urlStr string) (*lazyRemote, error) {
+	urlObj, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(urlObj.Scheme) != dbfactory.FileScheme {
+		return newMemLazyRemote(), nil
+	}
+
+	path, err := dbfactory.FilePathFromURL(urlObj)
+	if err != nil {
+		return nil, err
+	}
+
+	path = filepath.Join(path, LazyFileName)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return &lazyRemote{path: path, mu: &sync.Mutex{}, name: strings.TrimSpace(string(data))}, nil
+}
+
+func newMemLazyRemote() *lazyRemote {
+	return &lazyRemote{mu: &sync.Mutex{}}
+}
+
+func (l *lazyRemote) get() string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.name
+}
+
+func (l *lazyRemote) set(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path != "" {
+		var err error
+		if name == "" {
+			err = os.Remove(l.path)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(l.path, []byte(name+"\n"), 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	l.name = name
+	return nil
+}
+
+// IsLazy returns whether this database is a lazy clone, which doesn't have all of its chunks and fetches the ones it
+// reads from its lazy remote.
+func (ddb *DoltDB) IsLazy() bool {
+	return ddb.lazy.get() != ""
+}
+
+// LazyRemote returns the name of the remote this lazy clone fetches the chunks it doesn't have from, or the empty
+// string if it is not a lazy clone.
+func (ddb *DoltDB) LazyRemote() string {
+	return ddb.lazy.get()
+}
+
+// SetLazyRemote records that this database is a lazy clone of the remote named |name|. The chunks it doesn't have
+// can't be read until SetLazySource is called.
+func (ddb *DoltDB) SetLazyRemote(name string) error {
+	if _, ok := datas.ChunkStoreFromDatabase(ddb.db).(nbs.LazyChunkStore); !ok {
+		return ErrLazyNotSupported
+	}
+	return ddb.lazy.set(name)
+}
+
+// SetLazySource makes this database read the chunks it doesn't have from the database |open| returns, and keep the
+// chunks it reads. |open| is only called once a chunk is missing.
+func (ddb *DoltDB) SetLazySource(open func(ctx context.Context) (*DoltDB, error)) error {
+	lcs, ok := datas.ChunkStoreFromDatabase(ddb.db).(nbs.LazyChunkStore)
+	if !ok {
+		return ErrLazyNotSupported
+	}
+
+	lcs.SetLazySource(func(ctx context.Context) (chunks.ChunkStore, error) {
+		src, err := open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return datas.ChunkStoreFromDatabase(src.db), nil
+	})
+
+	return nil
+}
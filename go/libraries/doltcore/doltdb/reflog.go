@@ -0,0 +1,459 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// RefLogFileName is the name of the file in the data directory of a local database that holds its reflog.
+const RefLogFileName = "reflog"
+
+// RefLogEntry records a single update of a ref. A ref that was deleted is recorded with an empty hash.
+type RefLogEntry struct {
+	// Ref is the full path of the ref that was updated, e.g. refs/heads/main or workingSets/heads/main.
+	Ref string
+	// Hash is the address the ref pointed to after the update.
+	Hash hash.Hash
+	// Timestamp is the time of the update.
+	Timestamp time.Time
+}
+
+// IsDelete returns whether this entry records the deletion of its ref.
+func (e RefLogEntry) IsDelete() bool {
+	return e.Hash.IsEmpty()
+}
+
+// refLog is an append-only journal of ref updates.
+type refLog interface {
+	// appendEntries adds the entries given to the end of the journal.
+	appendEntries(entries ...RefLogEntry) error
+	// readEntries returns all the entries in the journal, oldest first.
+	readEntries() ([]RefLogEntry, error)
+}
+
+// newRefLogForURL returns the reflog for the database at the url given. Databases on the local filesystem keep their
+// reflog in a file in their data directory. For all other databases, only the updates made by this process are
+// recorded.
+func newRefLogForURL(urlStr string) (refLog, error) {
+	urlObj, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(urlObj.Scheme) != dbfactory.FileScheme {
+		return newMemRefLog(), nil
+	}
+
+	path, err := dbfactory.FilePathFromURL(urlObj)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileRefLog(filepath.Join(path, RefLogFileName)), nil
+}
+
+const (
+	// refLogMaxSize is the size in bytes above which the entries of a reflog are compacted.
+	refLogMaxSize = 4 * 1024 * 1024
+	// refLogExpiry is how long updates of branches, tags and other refs are kept once a reflog is compacted, like
+	// the default of git's gc.reflogExpire.
+	refLogExpiry = 90 * 24 * time.Hour
+	// refLogWorkingSetExpiry is how long updates of working sets are kept once a reflog is compacted. Every SQL
+	// transaction updates a working set, so these are kept for less time than the updates of other refs.
+	refLogWorkingSetExpiry = 7 * 24 * time.Hour
+)
+
+// formatRefLogEntry returns the line of the reflog file that records |e|.
+func formatRefLogEntry(e RefLogEntry) string {
+	return fmt.Sprintf("%d\t%s\t%s\n", e.Timestamp.UnixNano(), e.Ref, e.Hash.String())
+}
+
+// compactRefLog drops the entries of |entries| that have expired by |now|, and then the oldest of the others, until
+// the rest take up no more than |maxSize| bytes in a reflog file.
+func compactRefLog(entries []RefLogEntry, now time.Time, maxSize int) []RefLogEntry {
+	kept := make([]RefLogEntry, 0, len(entries))
+	size := 0
+	for _, e := range entries {
+		expiry := refLogExpiry
+		if ref.IsWorkingSet(e.Ref) {
+			expiry = refLogWorkingSetExpiry
+		}
+		if now.Sub(e.Timestamp) > expiry {
+			continue
+		}
+		kept = append(kept, e)
+		size += len(formatRefLogEntry(e))
+	}
+
+	for len(kept) > 0 && size > maxSize {
+		size -= len(formatRefLogEntry(kept[0]))
+		kept = kept[1:]
+	}
+
+	return kept
+}
+
+// memRefLog is a refLog that only lives as long as the process that created it. It is used for databases that are not
+// stored on the local filesystem. Its entries are compacted once they take up more than |maxSize| bytes.
+type memRefLog struct {
+	mu      sync.Mutex
+	entries []RefLogEntry
+	size    int
+	maxSize int
+}
+
+var _ refLog = &memRefLog{}
+
+func newMemRefLog() *memRefLog {
+	return &memRefLog{maxSize: refLogMaxSize}
+}
+
+func (l *memRefLog) appendEntries(entries ...RefLogEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entries...)
+	for _, e := range entries {
+		l.size += len(formatRefLogEntry(e))
+	}
+
+	if l.size > l.maxSize {
+		l.entries = compactRefLog(l.entries, time.Now(), l.maxSize/2)
+		l.size = 0
+		for _, e := range l.entries {
+			l.size += len(formatRefLogEntry(e))
+		}
+	}
+
+	return nil
+}
+
+func (l *memRefLog) readEntries() ([]RefLogEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]RefLogEntry(nil), l.entries...), nil
+}
+
+// fileRefLog is a refLog that is persisted to a file. Each entry is written as a single line of the form
+// "<unix nanos>\t<ref>\t<hash>", so that concurrent writers appending to the same file don't interleave their entries.
+//
+// Once the file grows larger than |maxSize| bytes, it is rotated: its entries are moved to an archive file next to it,
+// where they are compacted together with the entries archived before, and the file starts over. The archive never
+// takes up more than |maxSize| bytes.
+type fileRefLog struct {
+	path    string
+	mu      *sync.Mutex
+	maxSize int64
+}
+
+var _ refLog = fileRefLog{}
+
+func newFileRefLog(path string) fileRefLog {
+	return fileRefLog{path: path, mu: &sync.Mutex{}, maxSize: refLogMaxSize}
+}
+
+// archivePath is the path of the file holding the compacted entries of rotated logs.
+func (l fileRefLog) archivePath() string {
+	return l.path + ".archive"
+}
+
+// rotatingPattern matches the files of logs that are being rotated, or whose rotation was interrupted by a crash.
+func (l fileRefLog) rotatingPattern() string {
+	return l.path + ".rotating.*"
+}
+
+func (l fileRefLog) appendEntries(entries ...RefLogEntry) error {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(formatRefLogEntry(e))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(sb.String())
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > l.maxSize {
+		return l.rotate(time.Now())
+	}
+
+	return nil
+}
+
+// rotate moves the entries of the log into the archive, compacting them together with the entries archived before.
+// The log is renamed before it is read, so that entries appended by other processes in the meantime go to a new log
+// rather than being lost. Callers must hold |l.mu|.
+func (l fileRefLog) rotate(now time.Time) error {
+	rotating := fmt.Sprintf("%s.rotating.%d", l.path, now.UnixNano())
+	err := os.Rename(l.path, rotating)
+	if errors.Is(err, os.ErrNotExist) {
+		// another process rotated the log already
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	rotated, err := filepath.Glob(l.rotatingPattern())
+	if err != nil {
+		return err
+	}
+
+	entries, err := readRefLogFiles(append([]string{l.archivePath()}, rotated...)...)
+	if err != nil {
+		return err
+	}
+	entries = compactRefLog(entries, now, int(l.maxSize))
+
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(formatRefLogEntry(e))
+	}
+	tmp := fmt.Sprintf("%s.tmp.%d", l.archivePath(), now.UnixNano())
+	err = os.WriteFile(tmp, []byte(sb.String()), 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, l.archivePath())
+	if err != nil {
+		return err
+	}
+
+	for _, path := range rotated {
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l fileRefLog) readEntries() ([]RefLogEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rotated, err := filepath.Glob(l.rotatingPattern())
+	if err != nil {
+		return nil, err
+	}
+
+	paths := append([]string{l.archivePath()}, rotated...)
+	return readRefLogFiles(append(paths, l.path)...)
+}
+
+// readRefLogFiles returns the entries of the reflog files given, oldest first. Files that don't exist are skipped.
+func readRefLogFiles(paths ...string) ([]RefLogEntry, error) {
+	var entries []RefLogEntry
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		fileEntries, err := parseRefLog(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	// the entries of a file that was being rotated may be older than those of the archive it was rotated into
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	return entries, nil
+}
+
+func parseRefLog(rd io.Reader) ([]RefLogEntry, error) {
+	var entries []RefLogEntry
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			// a partially written entry, left behind by a crash
+			continue
+		}
+
+		nanos, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		h, ok := hash.MaybeParse(fields[2])
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, RefLogEntry{
+			Ref:       fields[1],
+			Hash:      h,
+			Timestamp: time.Unix(0, nanos),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// RecordRefUpdates appends |entries| to the reflog of this database. Updates made through DoltDB are recorded
+// already; this is for updates made to the chunk store of the database directly, like pushes to it as a remote.
+func (ddb *DoltDB) RecordRefUpdates(ctx context.Context, entries ...RefLogEntry) error {
+	if ddb.db.reflog == nil || len(entries) == 0 {
+		return nil
+	}
+	return ddb.db.reflog.appendEntries(entries...)
+}
+
+// ReadRefLog returns the entries of the reflog of this database, most recent first. If |refName| is not empty, only
+// the entries of the matching ref are returned. It can be the full path of a ref, or the name of a branch, tag or
+// remote tracking branch. Updates of working sets and internal refs are only returned when |all| is true, and
+// deletions of refs are never returned.
+func (ddb *DoltDB) ReadRefLog(ctx context.Context, refName string, all bool) ([]RefLogEntry, error) {
+	if ddb.db.reflog == nil {
+		return nil, nil
+	}
+
+	entries, err := ddb.db.reflog.readEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var refPaths map[string]struct{}
+	if refName != "" {
+		refPaths = refLogPathsForName(entries, refName, all)
+	}
+
+	var filtered []RefLogEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.IsDelete() {
+			continue
+		}
+		if !all && (ref.IsWorkingSet(e.Ref) || strings.HasPrefix(e.Ref, ref.PrefixForType(ref.InternalRefType))) {
+			continue
+		}
+		if refPaths != nil {
+			if _, ok := refPaths[e.Ref]; !ok {
+				continue
+			}
+		}
+		filtered = append(filtered, e)
+	}
+
+	return filtered, nil
+}
+
+// ResolveRefLogEntry returns the commit that |e| points to, or nil if it points to something other than a commit, like
+// a working set, or to a commit that no longer exists.
+func (ddb *DoltDB) ResolveRefLogEntry(ctx context.Context, e RefLogEntry) (*Commit, error) {
+	if ref.IsWorkingSet(e.Ref) {
+		return nil, nil
+	}
+
+	cs, err := NewCommitSpec(e.Hash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err := ddb.Resolve(ctx, cs, nil)
+	if IsNotACommit(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return cm, nil
+}
+
+// refLogPathsForName returns the full ref paths of the entries that |refName| refers to. A full ref path is used as
+// is, otherwise the first of the branch, tag and remote tracking branch of that name that appears in |entries| is
+// used. The working set of a branch goes along with the branch when |includeWorkingSets| is true.
+func refLogPathsForName(entries []RefLogEntry, refName string, includeWorkingSets bool) map[string]struct{} {
+	seen := make(map[string]struct{})
+	for _, e := range entries {
+		seen[e.Ref] = struct{}{}
+	}
+
+	var candidates []ref.DoltRef
+	if ref.IsRef(refName) {
+		if r, err := ref.Parse(refName); err == nil {
+			candidates = append(candidates, r)
+		}
+	} else {
+		candidates = append(candidates, ref.NewBranchRef(refName), ref.NewTagRef(refName))
+		if r, err := ref.NewRemoteRefFromPathStr(refName); err == nil {
+			candidates = append(candidates, r)
+		}
+	}
+
+	paths := map[string]struct{}{refName: {}}
+	for _, r := range candidates {
+		if _, ok := seen[r.String()]; !ok {
+			continue
+		}
+		paths = map[string]struct{}{r.String(): {}}
+		if includeWorkingSets && r.GetType() == ref.BranchRefType {
+			if ws, err := ref.WorkingSetRefForHead(r); err == nil {
+				paths[ws.String()] = struct{}{}
+			}
+		}
+		break
+	}
+
+	return paths
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+func TestReadRefLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	urlStr := fmt.Sprintf("file://%s", filepath.ToSlash(dir))
+
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, urlStr, filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	cs, err := NewCommitSpec("main")
+	require.NoError(t, err)
+	main, err := ddb.Resolve(ctx, cs, nil)
+	require.NoError(t, err)
+	mainHash, err := main.HashOf()
+	require.NoError(t, err)
+
+	require.NoError(t, ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("other"), main))
+	require.NoError(t, ddb.DeleteBranch(ctx, ref.NewBranchRef("other")))
+
+	// reload the database to make sure the reflog was persisted
+	ddb, err = LoadDoltDB(ctx, types.Format_Default, urlStr, filesys.LocalFS)
+	require.NoError(t, err)
+
+	entries, err := ddb.ReadRefLog(ctx, "", false)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "refs/heads/other", entries[0].Ref)
+	assert.Equal(t, mainHash, entries[0].Hash)
+	assert.Equal(t, "refs/heads/main", entries[1].Ref)
+	assert.Equal(t, mainHash, entries[1].Hash)
+
+	entries, err = ddb.ReadRefLog(ctx, "other", false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "refs/heads/other", entries[0].Ref)
+
+	entries, err = ddb.ReadRefLog(ctx, "refs/heads/main", true)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.True(t, e.Ref == "refs/heads/main" || e.Ref == "workingSets/heads/main", e.Ref)
+	}
+
+	entries, err = ddb.ReadRefLog(ctx, "unknown", false)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = os.Stat(filepath.Join(dir, RefLogFileName))
+	assert.NoError(t, err)
+}
+
+// racingDatabase moves every head written by SetHeadsWithWorkingSet to |next| right after it's written, like a
+// concurrent writer would.
+type racingDatabase struct {
+	datas.Database
+	next hash.Hash
+}
+
+func (db racingDatabase) SetHeadsWithWorkingSet(ctx context.Context, heads, prevHeads map[string]hash.Hash, workingSetDS datas.Dataset, workingSetSpec datas.WorkingSetSpec, prevWsHash hash.Hash) (datas.Dataset, error) {
+	workingSetDS, err := db.Database.SetHeadsWithWorkingSet(ctx, heads, prevHeads, workingSetDS, workingSetSpec, prevWsHash)
+	if err != nil {
+		return workingSetDS, err
+	}
+	for id := range heads {
+		ds, err := db.Database.GetDataset(ctx, id)
+		if err != nil {
+			return workingSetDS, err
+		}
+		if _, err = db.Database.SetHead(ctx, ds, db.next); err != nil {
+			return workingSetDS, err
+		}
+	}
+	return workingSetDS, nil
+}
+
+func TestRefLogRecordsWrittenHeads(t *testing.T) {
+	ctx := context.Background()
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, InMemDoltDB, filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	main := mustResolveMain(t, ddb)
+	mainHash, err := main.HashOf()
+	require.NoError(t, err)
+	root, err := main.GetRootValue(ctx)
+	require.NoError(t, err)
+	_, valHash, err := ddb.WriteRootValue(ctx, root)
+	require.NoError(t, err)
+	meta, err := datas.NewCommitMeta("Bill Billerson", "bigbillieb@fake.horse", "other")
+	require.NoError(t, err)
+	other, err := ddb.CommitDanglingWithParentCommits(ctx, valHash, []*Commit{main}, meta)
+	require.NoError(t, err)
+	otherHash, err := other.HashOf()
+	require.NoError(t, err)
+
+	ddb.db.Database = racingDatabase{Database: ddb.db.Database, next: mainHash}
+	branch := ref.NewBranchRef("other")
+	wsRef, err := ref.WorkingSetRefForHead(branch)
+	require.NoError(t, err)
+	ws := EmptyWorkingSet(wsRef).WithWorkingRoot(root).WithStagedRoot(root)
+	require.NoError(t, ddb.SetHeadsWithWorkingSet(ctx, map[ref.DoltRef]*Commit{branch: other}, nil, wsRef, ws, hash.Hash{}, TodoWorkingSetMeta()))
+
+	entries, err := ddb.ReadRefLog(ctx, "other", false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, otherHash, entries[0].Hash)
+}
+
+func TestParseRefLog(t *testing.T) {
+	log := strings.Join([]string{
+		"1000\trefs/heads/main\tgk3ifv5mtu7k4rqrbvc6e6vtsbaem2kt",
+		"not an entry",
+		"2000\trefs/heads/main\t00000000000000000000000000000000",
+		"3000\trefs/heads/ma",
+	}, "\n")
+
+	entries, err := parseRefLog(strings.NewReader(log))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "refs/heads/main", entries[0].Ref)
+	assert.Equal(t, int64(1000), entries[0].Timestamp.UnixNano())
+	assert.False(t, entries[0].IsDelete())
+	assert.True(t, entries[1].IsDelete())
+}
+
+func testRefLogEntries(n int, refName string, ts time.Time) []RefLogEntry {
+	entries := make([]RefLogEntry, n)
+	for i := range entries {
+		entries[i] = RefLogEntry{
+			Ref:       refName,
+			Hash:      hash.Of([]byte(fmt.Sprintf("%s-%d", refName, i))),
+			Timestamp: ts.Add(time.Duration(i) * time.Microsecond),
+		}
+	}
+	return entries
+}
+
+func TestCompactRefLog(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	var entries []RefLogEntry
+	entries = append(entries, testRefLogEntries(1, "refs/heads/main", now.Add(-100*day))...)
+	entries = append(entries, testRefLogEntries(1, "workingSets/heads/main", now.Add(-30*day))...)
+	entries = append(entries, testRefLogEntries(1, "refs/heads/main", now.Add(-30*day))...)
+	entries = append(entries, testRefLogEntries(1, "workingSets/heads/main", now.Add(-day))...)
+	entries = append(entries, testRefLogEntries(1, "refs/tags/v1", now)...)
+
+	compacted := compactRefLog(entries, now, refLogMaxSize)
+	assert.Equal(t, entries[2:], compacted)
+
+	// the oldest entries are dropped to fit the size given
+	compacted = compactRefLog(entries, now, len(formatRefLogEntry(entries[4])))
+	assert.Equal(t, entries[4:], compacted)
+}
+
+func TestMemRefLogCompaction(t *testing.T) {
+	l := newMemRefLog()
+	l.maxSize = 64 * 1024
+	entries := testRefLogEntries(10_000, "workingSets/heads/main", time.Now())
+	for i := 0; i < len(entries); i += 10 {
+		require.NoError(t, l.appendEntries(entries[i:i+10]...))
+	}
+
+	read, err := l.readEntries()
+	require.NoError(t, err)
+	assert.NotEmpty(t, read)
+	assert.Less(t, len(read), len(entries))
+	assert.LessOrEqual(t, l.size, l.maxSize)
+	assert.Equal(t, entries[len(entries)-len(read):], read)
+}
+
+func TestFileRefLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	l := newFileRefLog(filepath.Join(dir, RefLogFileName))
+	l.maxSize = 64 * 1024
+
+	entries := testRefLogEntries(10_000, "workingSets/heads/main", time.Now())
+	for i := 0; i < len(entries); i += 10 {
+		require.NoError(t, l.appendEntries(entries[i:i+10]...))
+	}
+
+	archive, err := os.Stat(l.archivePath())
+	require.NoError(t, err)
+	assert.LessOrEqual(t, archive.Size(), l.maxSize)
+	current, err := os.Stat(l.path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, current.Size(), l.maxSize)
+	rotating, err := filepath.Glob(l.rotatingPattern())
+	require.NoError(t, err)
+	assert.Empty(t, rotating)
+
+	read, err := l.readEntries()
+	require.NoError(t, err)
+	assert.Less(t, len(read), len(entries))
+	for i := range read {
+		assert.Equal(t, entries[len(entries)-len(read)+i].Ref, read[i].Ref)
+		assert.Equal(t, entries[len(entries)-len(read)+i].Hash, read[i].Hash)
+	}
+}
+
+func TestReadLargeRefLog(t *testing.T) {
+	dir := t.TempDir()
+	l := newFileRefLog(filepath.Join(dir, RefLogFileName))
+
+	// a log that grew large before it was rotated
+	entries := testRefLogEntries(200_000, "refs/heads/main", time.Now())
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(formatRefLogEntry(e))
+	}
+	require.Greater(t, sb.Len(), refLogMaxSize)
+	require.NoError(t, os.WriteFile(l.path, []byte(sb.String()), 0644))
+
+	read, err := l.readEntries()
+	require.NoError(t, err)
+	require.Len(t, read, len(entries))
+	assert.Equal(t, entries[0].Hash, read[0].Hash)
+	assert.Equal(t, entries[len(entries)-1].Hash, read[len(read)-1].Hash)
+
+	// the next update rotates it
+	last := testRefLogEntries(1, "refs/heads/other", time.Now().Add(time.Second))[0]
+	require.NoError(t, l.appendEntries(last))
+	read, err = l.readEntries()
+	require.NoError(t, err)
+	assert.Less(t, len(read), len(entries))
+	assert.Equal(t, last.Hash, read[len(read)-1].Hash)
+	_, err = os.Stat(l.path)
+	assert.True(t, os.IsNotExist(err))
+}
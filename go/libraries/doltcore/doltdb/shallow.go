@@ -0,0 +1,260 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/datas/pull"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// ShallowFileName is the name of the file in the data directory of a local database that lists its shallow commits.
+const ShallowFileName = "shallow"
+
+// ErrMissingShallowHistory is returned when pulling from a shallow clone needs the history it doesn't have.
+var ErrMissingShallowHistory = errors.New("the history below the shallow commits of a shallow clone is needed; run dolt fetch --unshallow first")
+
+// shallowCommits is the set of commits of a shallow clone whose parents were not cloned. Shallow commits are loaded
+// as if they had no parents, so that walking the history of a shallow clone stops at them.
+type shallowCommits struct {
+	// path is the file the set is persisted to. The set of databases that are not stored on the local filesystem only
+	// lives as long as the process that created it.
+	path    string
+	mu      *sync.Mutex
+	commits hash.HashSet
+}
+
+// newShallowCommitsForURL returns the shallow commits of the database at the url given.
+func newShallowCommitsForURL(urlStr string) (*shallowCommits, error) {
+	urlObj, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(urlObj.Scheme) != dbfactory.FileScheme {
+		return newMemShallowCommits(), nil
+	}
+
+	path, err := dbfactory.FilePathFromURL(urlObj)
+	if err != nil {
+		return nil, err
+	}
+
+	path = filepath.Join(path, ShallowFileName)
+	commits, err := readShallowFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shallowCommits{path: path, mu: &sync.Mutex{}, commits: commits}, nil
+}
+
+func newMemShallowCommits() *shallowCommits {
+	return &shallowCommits{mu: &sync.Mutex{}, commits: hash.NewHashSet()}
+}
+
+// has returns whether |h| is a shallow commit. A nil set has no shallow commits.
+func (s *shallowCommits) has(h hash.Hash) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commits.Has(h)
+}
+
+func (s *shallowCommits) get() hash.HashSet {
+	if s == nil {
+		return hash.NewHashSet()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commits.Copy()
+}
+
+func (s *shallowCommits) set(commits hash.HashSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		if err := writeShallowFile(s.path, commits); err != nil {
+			return err
+		}
+	}
+
+	s.commits = commits.Copy()
+	return nil
+}
+
+// readShallowFile reads the shallow commits listed in the file at |path|, one hash per line.
+func readShallowFile(path string) (hash.HashSet, error) {
+	commits := hash.NewHashSet()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return commits, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		h, ok := hash.MaybeParse(line)
+		if !ok {
+			return nil, errors.New("invalid commit hash in shallow file: " + line)
+		}
+		commits.Insert(h)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// writeShallowFile replaces the file at |path| with one listing |commits|. The file is removed when there are no
+// shallow commits left.
+func writeShallowFile(path string, commits hash.HashSet) error {
+	if len(commits) == 0 {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var sb strings.Builder
+	for h := range commits {
+		sb.WriteString(h.String())
+		sb.WriteString("\n")
+	}
+
+	// write to a temporary file first, so that a crash can't leave a partial list behind
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, []byte(sb.String()), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// IsShallow returns whether this database is a shallow clone, missing the history below some of its commits.
+func (ddb *DoltDB) IsShallow() bool {
+	return len(ddb.shallow.get()) > 0
+}
+
+// IsShallowCommit returns whether the commit with hash |h| is a shallow commit, whose parents are not in this database.
+func (ddb *DoltDB) IsShallowCommit(h hash.Hash) bool {
+	return ddb.shallow.has(h)
+}
+
+// ShallowCommits returns the shallow commits of this database.
+func (ddb *DoltDB) ShallowCommits() hash.HashSet {
+	return ddb.shallow.get()
+}
+
+// SetShallowCommits replaces the shallow commits of this database with |commits|. Commits read after this call are
+// loaded as if commits in |commits| had no parents.
+func (ddb *DoltDB) SetShallowCommits(commits hash.HashSet) error {
+	return ddb.shallow.set(commits)
+}
+
+// PullShallowChunks pulls the chunks reachable from |targetHash| in |srcDB| into this database, except for the commits
+// in |excluded| and the chunks only reachable through them. Excluding the parents of the boundary commits of a shallow
+// clone leaves out the history below them.
+func (ddb *DoltDB) PullShallowChunks(
+	ctx context.Context,
+	tempDir string,
+	srcDB *DoltDB,
+	targetHash hash.Hash,
+	excluded hash.HashSet,
+	progChan chan pull.PullProgress,
+	statsCh chan pull.Stats,
+) error {
+	waf := shallowWalkAddrs(types.WalkAddrsForNBF(srcDB.Format()), excluded)
+	return ddb.pullChunks(ctx, tempDir, srcDB, targetHash, waf, progChan, statsCh)
+}
+
+// shallowWalkAddrs returns a chunk walk of a history that stops at the commits in |excluded|. The ancestors listed in
+// the parent closures of commits are left out, every commit of the history is reached through its children.
+func shallowWalkAddrs(walk pull.WalkAddrs, excluded hash.HashSet) pull.WalkAddrs {
+	walk = excludingWalkAddrs(walk, excluded)
+	return func(c chunks.Chunk, cb func(h hash.Hash, isleaf bool) error) error {
+		if datas.IsCommitClosureLeaf(c) {
+			return nil
+		}
+		return walk(c, cb)
+	}
+}
+
+// pullFromShallowWalkAddrs returns the chunk walk for pulling from this shallow clone. Pulling a shallow commit fails
+// with ErrMissingShallowHistory, the destination doesn't have its history and this database can't provide it.
+func (ddb *DoltDB) pullFromShallowWalkAddrs(walk pull.WalkAddrs) pull.WalkAddrs {
+	walk = shallowWalkAddrs(walk, nil)
+	return func(c chunks.Chunk, cb func(h hash.Hash, isleaf bool) error) error {
+		if ddb.IsShallowCommit(c.Hash()) {
+			return ErrMissingShallowHistory
+		}
+		return walk(c, cb)
+	}
+}
+
+// MissingShallowHistory returns the commits below the shallow commits of this database which it doesn't have. Shallow
+// commits which aren't in the database are left out, fsck reports them.
+func (ddb *DoltDB) MissingShallowHistory(ctx context.Context) (hash.HashSet, error) {
+	ancestors := hash.NewHashSet()
+	for h := range ddb.shallow.get() {
+		dc, err := datas.LoadCommitAddr(ctx, ddb.vrw, h)
+		if errors.Is(err, datas.ErrCommitNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		parents, err := datas.GetCommitParentAddrs(ctx, ddb.Format(), dc.NomsValue())
+		if err != nil {
+			return nil, err
+		}
+		closure, err := datas.GetCommitClosureAddrs(ctx, dc, ddb.vrw, ddb.ns)
+		if err != nil {
+			return nil, err
+		}
+		ancestors.InsertAll(hash.NewHashSet(parents...))
+		ancestors.InsertAll(hash.NewHashSet(closure...))
+	}
+
+	if len(ancestors) == 0 {
+		return ancestors, nil
+	}
+	return datas.ChunkStoreFromDatabase(ddb.db).HasMany(ctx, ancestors)
+}
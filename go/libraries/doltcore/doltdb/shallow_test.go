@@ -0,0 +1,99 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+func TestShallowCommits(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	urlStr := fmt.Sprintf("file://%s", filepath.ToSlash(dir))
+
+	ddb, err := LoadDoltDB(ctx, types.Format_Default, urlStr, filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+	assert.False(t, ddb.IsShallow())
+
+	branch := ref.NewBranchRef("main")
+	var hashes []hash.Hash
+	for i := 0; i < 2; i++ {
+		head, err := ddb.ResolveCommitRef(ctx, branch)
+		require.NoError(t, err)
+		root, err := head.GetRootValue(ctx)
+		require.NoError(t, err)
+		_, rootHash, err := ddb.WriteRootValue(ctx, root)
+		require.NoError(t, err)
+		meta, err := datas.NewCommitMeta("Bill Billerson", "bigbillieb@fake.horse", fmt.Sprintf("commit %d", i))
+		require.NoError(t, err)
+		cm, err := ddb.Commit(ctx, rootHash, branch, meta)
+		require.NoError(t, err)
+		h, err := cm.HashOf()
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	require.NoError(t, ddb.SetShallowCommits(hash.NewHashSet(hashes[0])))
+
+	// reload the database to make sure the shallow commits were persisted
+	ddb, err = LoadDoltDB(ctx, types.Format_Default, urlStr, filesys.LocalFS)
+	require.NoError(t, err)
+	assert.True(t, ddb.IsShallow())
+	assert.True(t, ddb.IsShallowCommit(hashes[0]))
+	assert.False(t, ddb.IsShallowCommit(hashes[1]))
+
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	require.NoError(t, err)
+	require.Equal(t, 1, head.NumParents())
+	parent, err := head.GetParent(ctx, 0)
+	require.NoError(t, err)
+	parentHash, err := parent.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, hashes[0], parentHash)
+	assert.Equal(t, 0, parent.NumParents())
+
+	cs, err := NewCommitSpec("main~2")
+	require.NoError(t, err)
+	_, err = ddb.Resolve(ctx, cs, nil)
+	assert.Equal(t, ErrInvalidAncestorSpec, err)
+
+	// the history below the shallow commit is still there, and shallow commits that aren't in the database are skipped
+	require.NoError(t, ddb.SetShallowCommits(hash.NewHashSet(hashes[0], hash.Of([]byte("not a commit")))))
+	missing, err := ddb.MissingShallowHistory(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	require.NoError(t, ddb.SetShallowCommits(hash.NewHashSet()))
+	assert.False(t, ddb.IsShallow())
+	_, err = os.Stat(filepath.Join(dir, ShallowFileName))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = ddb.Resolve(ctx, cs, nil)
+	assert.NoError(t, err)
+}
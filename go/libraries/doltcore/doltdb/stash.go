@@ -0,0 +1,90 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doltdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// Each stash entry is stored in its own dataset, refs/stashes/<n>, pointing at a commit of the stashed working root.
+// Entries are numbered in the order they were created, so the entry with the highest number is the most recent one.
+
+var stashesRefFilter = map[ref.RefType]struct{}{ref.StashRefType: {}}
+
+// GetStashes returns the refs of all stash entries in the database, most recent first.
+func (ddb *DoltDB) GetStashes(ctx context.Context) ([]ref.StashRef, error) {
+	type numberedRef struct {
+		r ref.StashRef
+		n int
+	}
+
+	var refs []numberedRef
+	err := ddb.VisitRefsOfType(ctx, stashesRefFilter, func(r ref.DoltRef, _ hash.Hash) error {
+		n, err := strconv.Atoi(r.GetPath())
+		if err != nil {
+			return fmt.Errorf("invalid stash ref %s", r.String())
+		}
+		refs = append(refs, numberedRef{r: r.(ref.StashRef), n: n})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].n > refs[j].n
+	})
+
+	stashes := make([]ref.StashRef, len(refs))
+	for i := range refs {
+		stashes[i] = refs[i].r
+	}
+	return stashes, nil
+}
+
+// NewStashRef returns the ref of a new stash entry, which becomes the most recent one once it's set.
+func (ddb *DoltDB) NewStashRef(ctx context.Context) (ref.StashRef, error) {
+	stashes, err := ddb.GetStashes(ctx)
+	if err != nil {
+		return ref.StashRef{}, err
+	}
+
+	next := 1
+	if len(stashes) > 0 {
+		last, err := strconv.Atoi(stashes[0].GetPath())
+		if err != nil {
+			return ref.StashRef{}, err
+		}
+		next = last + 1
+	}
+	return ref.NewStashRef(strconv.Itoa(next)), nil
+}
+
+// DeleteStash deletes the stash entry given. Returns ErrStashNotFound if it doesn't exist.
+func (ddb *DoltDB) DeleteStash(ctx context.Context, stashRef ref.DoltRef) error {
+	err := ddb.deleteRef(ctx, stashRef)
+
+	if err == ErrBranchNotFound {
+		return ErrStashNotFound
+	}
+
+	return err
+}
@@ -123,6 +123,9 @@ var writeableSystemTables = []string{
 	SchemasTableName,
 	ProceduresTableName,
 	DocTableName,
+	RebaseTableName,
+	IgnoreTableName,
+	ReplicaPositionTableName,
 }
 
 var persistedSystemTables = []string{
@@ -130,6 +133,9 @@ var persistedSystemTables = []string{
 	DoltQueryCatalogTableName,
 	SchemasTableName,
 	ProceduresTableName,
+	RebaseTableName,
+	IgnoreTableName,
+	ReplicaPositionTableName,
 }
 
 var generatedSystemTables = []string{
@@ -141,6 +147,7 @@ var generatedSystemTables = []string{
 	CommitAncestorsTableName,
 	StatusTableName,
 	RemotesTableName,
+	MergeStatusTableName,
 }
 
 var generatedSystemViewPrefixes = []string{
@@ -150,6 +157,7 @@ var generatedSystemViewPrefixes = []string{
 var generatedSystemTablePrefixes = []string{
 	DoltDiffTablePrefix,
 	DoltCommitDiffTablePrefix,
+	DoltColumnDiffTablePrefix,
 	DoltHistoryTablePrefix,
 	DoltConfTablePrefix,
 	DoltConstViolTablePrefix,
@@ -184,6 +192,51 @@ const (
 	DocTextColumnName = "doc_text"
 )
 
+var ignoreColumns = schema.NewColCollection(
+	schema.NewColumn(IgnoreTablePatternCol, schema.DoltIgnorePatternTag, types.StringKind, true, schema.NotNullConstraint{}),
+	schema.NewColumn(IgnoreTableIgnoredCol, schema.DoltIgnoreIgnoredTag, types.BoolKind, false, schema.NotNullConstraint{}),
+)
+
+// IgnoreSchema is the schema of the dolt_ignore table.
+var IgnoreSchema = schema.MustSchemaFromCols(ignoreColumns)
+
+const (
+	// IgnoreTableName is the name of the table holding the patterns of table names that are ignored by dolt add and
+	// dolt status, unless they are already staged
+	IgnoreTableName = "dolt_ignore"
+	// IgnoreTablePatternCol is the column containing a table name pattern
+	IgnoreTablePatternCol = "pattern"
+	// IgnoreTableIgnoredCol is the column containing whether tables matching the pattern are ignored
+	IgnoreTableIgnoredCol = "ignored"
+)
+
+var replicaPositionColumns = schema.NewColCollection(
+	schema.NewColumn(ReplicaPositionTableChannelCol, schema.DoltReplicaPositionChannelTag, types.StringKind, true, schema.NotNullConstraint{}),
+	schema.NewColumn(ReplicaPositionTableSourceIDCol, schema.DoltReplicaPositionSourceIDTag, types.StringKind, false, schema.NotNullConstraint{}),
+	schema.NewColumn(ReplicaPositionTableSeqCol, schema.DoltReplicaPositionSeqTag, types.UintKind, false, schema.NotNullConstraint{}),
+	schema.NewColumn(ReplicaPositionTablePositionCol, schema.DoltReplicaPositionPositionTag, types.StringKind, false, schema.NotNullConstraint{}),
+)
+
+// ReplicaPositionSchema is the schema of the dolt_replica_position table.
+var ReplicaPositionSchema = schema.MustSchemaFromCols(replicaPositionColumns)
+
+const (
+	// ReplicaPositionTableName is the name of the table holding the position in the binlog of its MySQL source that a
+	// binlog replica applied up to. The replica updates it in the transaction of every source transaction it applies
+	// to the database.
+	ReplicaPositionTableName = "dolt_replica_position"
+	// ReplicaPositionTableChannelCol is the column containing the replication channel, which is always the default,
+	// empty channel
+	ReplicaPositionTableChannelCol = "channel"
+	// ReplicaPositionTableSourceIDCol is the column containing the id of the source configuration of the replica
+	ReplicaPositionTableSourceIDCol = "source_id"
+	// ReplicaPositionTableSeqCol is the column containing the number of transactions the replica applied with its
+	// source configuration
+	ReplicaPositionTableSeqCol = "seq"
+	// ReplicaPositionTablePositionCol is the column containing the encoded binlog position
+	ReplicaPositionTablePositionCol = "position"
+)
+
 const (
 	// DoltQueryCatalogTableName is the name of the query catalog table
 	DoltQueryCatalogTableName = "dolt_query_catalog"
@@ -231,6 +284,8 @@ const (
 	DoltDiffTablePrefix = "dolt_diff_"
 	// DoltCommitDiffTablePrefix is the prefix assigned to all the generated commit diff tables
 	DoltCommitDiffTablePrefix = "dolt_commit_diff_"
+	// DoltColumnDiffTablePrefix is the prefix assigned to all the generated column diff tables
+	DoltColumnDiffTablePrefix = "dolt_column_diff_"
 	// DoltConfTablePrefix is the prefix assigned to all the generated conflict tables
 	DoltConfTablePrefix = "dolt_conflicts_"
 	// DoltConstViolTablePrefix is the prefix assigned to all the generated constraint violation tables
@@ -244,6 +299,9 @@ const (
 	// DiffTableName is the name of the table with a map of commits to tables changed
 	DiffTableName = "dolt_diff"
 
+	// ColumnDiffTableName is the name of the table with a map of commits to the columns of tables changed
+	ColumnDiffTableName = "dolt_column_diff"
+
 	// TableOfTablesInConflictName is the conflicts system table name
 	TableOfTablesInConflictName = "dolt_conflicts"
 
@@ -267,6 +325,12 @@ const (
 
 	// TagsTableName is the tags table name
 	TagsTableName = "dolt_tags"
+
+	// StashesTableName is the stashes table name
+	StashesTableName = "dolt_stashes"
+
+	// MergeStatusTableName is the name of the table showing the state of a merge in progress
+	MergeStatusTableName = "dolt_merge_status"
 )
 
 const (
@@ -281,3 +345,17 @@ const (
 	// ProceduresTableModifiedAtCol is the time that the stored procedure was last modified, in UTC.
 	ProceduresTableModifiedAtCol = "modified_at"
 )
+
+const (
+	// RebaseTableName is the name of the table holding the plan for an interactive rebase. It only exists in the
+	// working set of a branch while a rebase of that branch is in progress.
+	RebaseTableName = "dolt_rebase"
+	// RebaseTableOrderCol is the column containing the order in which the steps of the rebase plan are applied
+	RebaseTableOrderCol = "rebase_order"
+	// RebaseTableActionCol is the column containing the action (pick, reword, squash, fixup, drop) for a step
+	RebaseTableActionCol = "action"
+	// RebaseTableCommitHashCol is the column containing the hash of the commit being replayed
+	RebaseTableCommitHashCol = "commit_hash"
+	// RebaseTableCommitMessageCol is the column containing the commit message used for a step
+	RebaseTableCommitMessageCol = "commit_message"
+)
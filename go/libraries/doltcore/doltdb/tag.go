@@ -34,6 +34,10 @@ type Tag struct {
 
 // NewTag creates a new Tag object.
 func NewTag(ctx context.Context, name string, ds datas.Dataset, vrw types.ValueReadWriter, ns tree.NodeStore) (*Tag, error) {
+	return newTag(ctx, name, ds, vrw, ns, nil)
+}
+
+func newTag(ctx context.Context, name string, ds datas.Dataset, vrw types.ValueReadWriter, ns tree.NodeStore, shallow *shallowCommits) (*Tag, error) {
 	meta, commitAddr, err := ds.HeadTag()
 	if err != nil {
 		return nil, err
@@ -42,7 +46,7 @@ func NewTag(ctx context.Context, name string, ds datas.Dataset, vrw types.ValueR
 	if err != nil {
 		return nil, err
 	}
-	commit, err := NewCommit(ctx, vrw, ns, dc)
+	commit, err := newCommit(ctx, vrw, ns, shallow, dc)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +28,7 @@ import (
 
 type MergeState struct {
 	commit          *Commit
+	commitSpecStr   string
 	preMergeWorking *RootValue
 }
 
@@ -52,6 +53,12 @@ func (m MergeState) Commit() *Commit {
 	return m.commit
 }
 
+// CommitSpecStr returns the commit spec that was given to the merge, e.g. the name of the branch being merged. It's
+// empty for merges that were started before it was recorded.
+func (m MergeState) CommitSpecStr() string {
+	return m.commitSpecStr
+}
+
 func (m MergeState) PreMergeWorkingRoot() *RootValue {
 	return m.preMergeWorking
 }
@@ -89,9 +96,11 @@ func (ws WorkingSet) WithMergeState(mergeState *MergeState) *WorkingSet {
 	return &ws
 }
 
-func (ws WorkingSet) StartMerge(commit *Commit) *WorkingSet {
+// StartMerge returns a copy of this working set that is merging |commit|, which was specified as |commitSpecStr|.
+func (ws WorkingSet) StartMerge(commit *Commit, commitSpecStr string) *WorkingSet {
 	ws.mergeState = &MergeState{
 		commit:          commit,
+		commitSpecStr:   commitSpecStr,
 		preMergeWorking: ws.workingRoot,
 	}
 
@@ -132,6 +141,10 @@ func (ws WorkingSet) Meta() *datas.WorkingSetMeta {
 
 // NewWorkingSet creates a new WorkingSet object.
 func NewWorkingSet(ctx context.Context, name string, vrw types.ValueReadWriter, ns tree.NodeStore, ds datas.Dataset) (*WorkingSet, error) {
+	return newWorkingSet(ctx, name, vrw, ns, nil, ds)
+}
+
+func newWorkingSet(ctx context.Context, name string, vrw types.ValueReadWriter, ns tree.NodeStore, shallow *shallowCommits, ds datas.Dataset) (*WorkingSet, error) {
 	dsws, err := ds.HeadWorkingSet()
 	if err != nil {
 		return nil, err
@@ -179,8 +192,12 @@ func NewWorkingSet(ctx context.Context, name string, vrw types.ValueReadWriter,
 		if err != nil {
 			return nil, err
 		}
+		commitSpecStr, err := dsws.MergeState.FromCommitSpecStr(ctx, vrw)
+		if err != nil {
+			return nil, err
+		}
 
-		commit, err := NewCommit(ctx, vrw, ns, fromDCommit)
+		commit, err := newCommit(ctx, vrw, ns, shallow, fromDCommit)
 		if err != nil {
 			return nil, err
 		}
@@ -197,6 +214,7 @@ func NewWorkingSet(ctx context.Context, name string, vrw types.ValueReadWriter,
 
 		mergeState = &MergeState{
 			commit:          commit,
+			commitSpecStr:   commitSpecStr,
 			preMergeWorking: preMergeWorkingRoot,
 		}
 	}
@@ -273,7 +291,7 @@ func (ws *WorkingSet) writeValues(ctx context.Context, db *DoltDB) (
 			return types.Ref{}, types.Ref{}, nil, err
 		}
 
-		mergeState, err = datas.NewMergeState(ctx, db.vrw, preMergeWorking, dCommit)
+		mergeState, err = datas.NewMergeState(ctx, db.vrw, preMergeWorking, dCommit, ws.mergeState.commitSpecStr)
 		if err != nil {
 			return types.Ref{}, types.Ref{}, nil, err
 		}
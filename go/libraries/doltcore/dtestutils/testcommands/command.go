@@ -51,7 +51,7 @@ func (a StageAll) Exec(t *testing.T, dEnv *env.DoltEnv) error {
 	roots, err := dEnv.Roots(context.Background())
 	require.NoError(t, err)
 
-	roots, err = actions.StageAllTables(context.Background(), roots)
+	roots, err = actions.StageAllTables(context.Background(), roots, true)
 	require.NoError(t, err)
 
 	return dEnv.UpdateRoots(context.Background(), roots)
@@ -115,7 +115,7 @@ func (c CommitAll) Exec(t *testing.T, dEnv *env.DoltEnv) error {
 	roots, err := dEnv.Roots(context.Background())
 	require.NoError(t, err)
 
-	roots, err = actions.StageAllTables(context.Background(), roots)
+	roots, err = actions.StageAllTables(context.Background(), roots, true)
 	require.NoError(t, err)
 
 	name, email, err := env.GetNameAndEmail(dEnv.Config)
@@ -302,7 +302,7 @@ func (m Merge) Exec(t *testing.T, dEnv *env.DoltEnv) error {
 			require.True(t, stats.Conflicts == 0)
 		}
 
-		err = dEnv.StartMerge(context.Background(), cm2)
+		err = dEnv.StartMerge(context.Background(), cm2, m.BranchName)
 		if err != nil {
 			return err
 		}
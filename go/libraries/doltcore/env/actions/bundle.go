@@ -0,0 +1,84 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/store/datas/pull"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var ErrMissingBundlePrerequisites = errors.New("the database is missing commits the bundle requires")
+
+// CreateBundle writes |refs| of |srcDB| and the history they reference to a bundle file at |path|. The history of the
+// commits in |prerequisites| is left out of the bundle.
+func CreateBundle(ctx context.Context, tempTableDir string, srcDB *doltdb.DoltDB, path string, refs []doltdb.BundleRef, prerequisites hash.HashSet, progStarter ProgStarter, progStopper ProgStopper) error {
+	newCtx, cancelFunc := context.WithCancel(ctx)
+	wg, progChan, statsCh := progStarter(newCtx)
+	err := srcDB.CreateBundle(ctx, tempTableDir, path, refs, prerequisites, progChan, statsCh)
+	progStopper(cancelFunc, wg, progChan, statsCh)
+	if err == nil {
+		cli.Println()
+	}
+
+	return err
+}
+
+// Unbundle fetches the history referenced by the refs of |bundleDB| into |destDB|, and returns those refs. It fails
+// if |destDB| is missing any of the |prerequisites| of the bundle. No refs of |destDB| are updated.
+func Unbundle(ctx context.Context, tempTableDir string, bundleDB, destDB *doltdb.DoltDB, prerequisites hash.HashSet, progStarter ProgStarter, progStopper ProgStopper) ([]doltdb.BundleRef, error) {
+	var missing []string
+	for h := range prerequisites {
+		has, err := destDB.Has(ctx, h)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			missing = append(missing, h.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%w: %s", ErrMissingBundlePrerequisites, strings.Join(missing, ", "))
+	}
+
+	refs, err := bundleDB.GetBundleRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range refs {
+		newCtx, cancelFunc := context.WithCancel(ctx)
+		wg, progChan, statsCh := progStarter(newCtx)
+		err = destDB.PullChunks(ctx, tempTableDir, bundleDB, r.Addr, progChan, statsCh)
+		progStopper(cancelFunc, wg, progChan, statsCh)
+		if err == pull.ErrDBUpToDate {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return refs, nil
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/utils/strhelp"
 	"github.com/dolthub/dolt/go/store/datas"
 	"github.com/dolthub/dolt/go/store/datas/pull"
+	"github.com/dolthub/dolt/go/store/hash"
 	"github.com/dolthub/dolt/go/store/nbs"
 	"github.com/dolthub/dolt/go/store/types"
 )
@@ -179,6 +180,175 @@ func CloneRemote(ctx context.Context, srcDB *doltdb.DoltDB, remoteName, branch s
 		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
 	}
 
+	return initClonedBranches(ctx, remoteName, branch, dEnv)
+}
+
+// ShallowCloneRemote clones |srcDB| into |dEnv| like CloneRemote, but only pulls the history of the branches it clones
+// down to |depth| commits, or their entire history if |depth| is 0. If |singleBranch| is true, only |branch| is cloned,
+// or the default branch of |srcDB| if |branch| is empty. The commits the history of the clone stops at are recorded as
+// its shallow commits.
+func ShallowCloneRemote(
+	ctx context.Context,
+	srcDB *doltdb.DoltDB,
+	remoteName, branch string,
+	singleBranch bool,
+	depth int,
+	dEnv *env.DoltEnv,
+	progStarter ProgStarter,
+	progStopper ProgStopper,
+) error {
+	if depth > 0 && !types.IsFormat_DOLT(srcDB.Format()) {
+		return fmt.Errorf("%w; shallow clones are only supported for the %s storage format", ErrCloneFailed, types.Format_DOLT.VersionString())
+	}
+
+	srcBranches, err := srcDB.GetBranches(ctx)
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrFailedToListBranches, err.Error())
+	}
+	if len(srcBranches) == 0 {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, ErrNoDataAtRemote.Error())
+	}
+
+	if branch == "" {
+		branch = env.GetDefaultBranch(dEnv, srcBranches)
+	}
+	if singleBranch {
+		srcBranches = []ref.DoltRef{ref.NewBranchRef(branch)}
+	}
+
+	heads := make([]hash.Hash, len(srcBranches))
+	for i, brnch := range srcBranches {
+		cm, err := srcDB.ResolveCommitRef(ctx, brnch)
+		if err != nil {
+			return fmt.Errorf("%w: %s; %s", ErrFailedToResolveBranchRef, brnch.String(), err.Error())
+		}
+		heads[i], err = cm.HashOf()
+		if err != nil {
+			return err
+		}
+	}
+
+	shallow, excluded, err := shallowBoundary(ctx, srcDB, heads, depth)
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+	}
+
+	destDB := dEnv.DoltDB
+	for _, h := range heads {
+		newCtx, cancelFunc := context.WithCancel(ctx)
+		wg, progChan, statsCh := progStarter(newCtx)
+		err = destDB.PullShallowChunks(ctx, dEnv.TempTableFilesDir(), srcDB, h, excluded, progChan, statsCh)
+		progStopper(cancelFunc, wg, progChan, statsCh)
+		if err != nil {
+			return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+		}
+	}
+
+	// the shallow commits are recorded before any ref points at them, a clone must never look like it has history it
+	// doesn't have
+	err = destDB.SetShallowCommits(shallow)
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+	}
+
+	for i, brnch := range srcBranches {
+		err = destDB.SetHead(ctx, brnch, heads[i])
+		if err != nil {
+			return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+		}
+	}
+
+	err = FetchFollowTags(ctx, dEnv.TempTableFilesDir(), srcDB, destDB, progStarter, progStopper)
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+	}
+
+	return initClonedBranches(ctx, remoteName, branch, dEnv)
+}
+
+// LazyCloneRemote clones |srcDB| into |dEnv| without downloading its data. The clone is recorded as a lazy clone of
+// |remoteName|, and fetches the chunks it reads from it as they are needed. If |singleBranch| is true, only |branch| is
+// cloned, or the default branch of |srcDB| if |branch| is empty.
+func LazyCloneRemote(
+	ctx context.Context,
+	srcDB *doltdb.DoltDB,
+	remoteName, branch string,
+	singleBranch bool,
+	dEnv *env.DoltEnv,
+	progStarter ProgStarter,
+	progStopper ProgStopper,
+) error {
+	destDB := dEnv.DoltDB
+	err := destDB.SetLazyRemote(remoteName)
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+	}
+
+	err = destDB.SetLazySource(func(ctx context.Context) (*doltdb.DoltDB, error) {
+		return srcDB, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w; %s", ErrCloneFailed, err.Error())
+	}
+
+	// the chunks of the lazy source count as present, pulling the heads of |srcDB| only sets the refs of the clone
+	return ShallowCloneRemote(ctx, srcDB, remoteName, branch, singleBranch, 0, dEnv, progStarter, progStopper)
+}
+
+// shallowBoundary walks the history of |heads| in |srcDB| down to |depth| commits. It returns the commits at the bottom
+// of the walk whose parents it doesn't reach, and those parents. A |depth| of 0 walks the entire history, which has no
+// boundary.
+func shallowBoundary(ctx context.Context, srcDB *doltdb.DoltDB, heads []hash.Hash, depth int) (boundary, excluded hash.HashSet, err error) {
+	boundary, excluded = hash.NewHashSet(), hash.NewHashSet()
+	if depth <= 0 {
+		return boundary, excluded, nil
+	}
+
+	reached := hash.NewHashSet()
+	boundaryParents := make(map[hash.Hash][]hash.Hash)
+	level := heads
+	for d := 1; len(level) > 0; d++ {
+		var next []hash.Hash
+		for _, h := range level {
+			if reached.Has(h) {
+				continue
+			}
+			reached.Insert(h)
+
+			cm, err := srcDB.ReadCommit(ctx, h)
+			if err != nil {
+				return nil, nil, err
+			}
+			parents, err := cm.ParentHashes(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if d < depth {
+				next = append(next, parents...)
+			} else if len(parents) > 0 {
+				boundaryParents[h] = parents
+			}
+		}
+		level = next
+	}
+
+	// a parent of a boundary commit may have been reached through another commit, and is cloned after all
+	for h, parents := range boundaryParents {
+		for _, p := range parents {
+			if !reached.Has(p) {
+				boundary.Insert(h)
+				excluded.Insert(p)
+			}
+		}
+	}
+
+	return boundary, excluded, nil
+}
+
+// initClonedBranches turns the branches of a newly cloned repository into remote branches of |remoteName|, and checks
+// out |branch|, or the default branch of the clone if |branch| is empty.
+func initClonedBranches(ctx context.Context, remoteName, branch string, dEnv *env.DoltEnv) error {
 	branches, err := dEnv.DoltDB.GetBranches(ctx)
 	if err != nil {
 		return fmt.Errorf("%w; %s", ErrFailedToListBranches, err.Error())
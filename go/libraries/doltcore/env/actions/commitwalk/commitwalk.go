@@ -152,14 +152,17 @@ func newQueue() *q {
 // GetDotDotRevisions returns the commits reachable from commit at hash
 // `includedHead` that are not reachable from hash `excludedHead`.
 // `includedHead` and `excludedHead` must be commits in `ddb`. Returns up
-// to `num` commits, in reverse topological order starting at `includedHead`,
+// to `num` commits, or all of them if `num` <= 0, in reverse topological order starting at `includedHead`,
 // with tie breaking based on the height of commit graph between
 // concurrent commits --- higher commits appear first. Remaining
 // ties are broken by timestamp; newer commits appear first.
 //
 // Roughly mimics `git log main..feature`.
 func GetDotDotRevisions(ctx context.Context, includedDB *doltdb.DoltDB, includedHead hash.Hash, excludedDB *doltdb.DoltDB, excludedHead hash.Hash, num int) ([]*doltdb.Commit, error) {
-	commitList := make([]*doltdb.Commit, 0, num)
+	var commitList []*doltdb.Commit
+	if num > 0 {
+		commitList = make([]*doltdb.Commit, 0, num)
+	}
 	q := newQueue()
 	if err := q.SetInvisible(ctx, excludedDB, excludedHead); err != nil {
 		return nil, err
@@ -196,6 +199,26 @@ func GetDotDotRevisions(ctx context.Context, includedDB *doltdb.DoltDB, included
 	return commitList, nil
 }
 
+// GetDotDotRevisionsIterator returns an iterator over the commits reachable from any of the commits in
+// `includedHeads` that are not reachable from any of the commits in `excludedHeads`, with the same ordering as
+// GetDotDotRevisions. All the commits must be in `ddb`.
+//
+// Roughly mimics `git log feature1 feature2 ^main`.
+func GetDotDotRevisionsIterator(ctx context.Context, ddb *doltdb.DoltDB, includedHeads []hash.Hash, excludedHeads []hash.Hash) (doltdb.CommitItr, error) {
+	itr := &dotDotCommiterator{
+		ddb:           ddb,
+		includedHeads: includedHeads,
+		excludedHeads: excludedHeads,
+	}
+
+	err := itr.Reset(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return itr, nil
+}
+
 // GetTopologicalOrderCommits returns the commits reachable from the commit at hash `startCommitHash`
 // in reverse topological order, with tiebreaking done by the height of the commit graph -- higher commits
 // appear first. Remaining ties are broken by timestamp; newer commits appear first.
@@ -295,3 +318,59 @@ func GetTopNTopoOrderedCommitsMatching(ctx context.Context, ddb *doltdb.DoltDB,
 
 	return commitList, nil
 }
+
+type dotDotCommiterator struct {
+	ddb           *doltdb.DoltDB
+	includedHeads []hash.Hash
+	excludedHeads []hash.Hash
+	q             *q
+}
+
+var _ doltdb.CommitItr = (*dotDotCommiterator)(nil)
+
+// Next implements doltdb.CommitItr
+func (i *dotDotCommiterator) Next(ctx context.Context) (hash.Hash, *doltdb.Commit, error) {
+	for i.q.NumVisiblePending() > 0 {
+		nextC := i.q.PopPending()
+		parents, err := nextC.commit.ParentHashes(ctx)
+		if err != nil {
+			return hash.Hash{}, nil, err
+		}
+
+		for _, parentID := range parents {
+			if nextC.invisible {
+				if err := i.q.SetInvisible(ctx, nextC.ddb, parentID); err != nil {
+					return hash.Hash{}, nil, err
+				}
+			}
+			if err := i.q.AddPendingIfUnseen(ctx, nextC.ddb, parentID); err != nil {
+				return hash.Hash{}, nil, err
+			}
+		}
+
+		if !nextC.invisible {
+			return nextC.hash, nextC.commit, nil
+		}
+	}
+
+	return hash.Hash{}, nil, io.EOF
+}
+
+// Reset implements doltdb.CommitItr
+func (i *dotDotCommiterator) Reset(ctx context.Context) error {
+	i.q = newQueue()
+	for _, excludedHead := range i.excludedHeads {
+		if err := i.q.SetInvisible(ctx, i.ddb, excludedHead); err != nil {
+			return err
+		}
+		if err := i.q.AddPendingIfUnseen(ctx, i.ddb, excludedHead); err != nil {
+			return err
+		}
+	}
+	for _, includedHead := range i.includedHeads {
+		if err := i.q.AddPendingIfUnseen(ctx, i.ddb, includedHead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
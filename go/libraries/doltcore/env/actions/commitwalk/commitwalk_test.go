@@ -16,6 +16,7 @@ package commitwalk
 
 import (
 	"context"
+	"io"
 	"testing"
 	"time"
 
@@ -133,6 +134,10 @@ func TestGetDotDotRevisions(t *testing.T) {
 	assertEqualHashes(t, featureCommits[2], res[5])
 	assertEqualHashes(t, featureCommits[1], res[6])
 
+	res, err = GetDotDotRevisions(context.Background(), dEnv.DoltDB, featureHash, dEnv.DoltDB, mainHash, 0)
+	require.NoError(t, err)
+	assert.Len(t, res, 7)
+
 	res, err = GetDotDotRevisions(context.Background(), dEnv.DoltDB, mainHash, dEnv.DoltDB, featureHash, 100)
 	require.NoError(t, err)
 	assert.Len(t, res, 0)
@@ -211,6 +216,98 @@ func TestGetDotDotRevisions(t *testing.T) {
 	assertEqualHashes(t, featureCommits[1], res[2])
 }
 
+func TestGetDotDotRevisionsIterator(t *testing.T) {
+	ctx := context.Background()
+	dEnv := createUninitializedEnv()
+	err := dEnv.InitRepo(ctx, types.Format_Default, "Bill Billerson", "bill@billerson.com", env.DefaultInitBranch)
+	require.NoError(t, err)
+
+	cs, err := doltdb.NewCommitSpec(env.DefaultInitBranch)
+	require.NoError(t, err)
+	commit, err := dEnv.DoltDB.Resolve(ctx, cs, nil)
+	require.NoError(t, err)
+
+	rv, err := commit.GetRootValue(ctx)
+	require.NoError(t, err)
+	_, rvh, err := dEnv.DoltDB.WriteRootValue(ctx, rv)
+	require.NoError(t, err)
+
+	// Create 2 commits on main.
+	mainCommits := []*doltdb.Commit{commit}
+	for i := 1; i < 3; i++ {
+		mainCommits = append(mainCommits, mustCreateCommit(t, dEnv.DoltDB, env.DefaultInitBranch, rvh, mainCommits[i-1]))
+	}
+
+	// Create 2 feature branches off of main, with 2 commits each.
+	branchCommits := make(map[string][]*doltdb.Commit)
+	for _, bn := range []string{"feature1", "feature2"} {
+		err = dEnv.DoltDB.NewBranchAtCommit(ctx, ref.NewBranchRef(bn), mainCommits[2])
+		require.NoError(t, err)
+		cms := []*doltdb.Commit{mainCommits[2]}
+		for i := 1; i < 3; i++ {
+			cms = append(cms, mustCreateCommit(t, dEnv.DoltDB, bn, rvh, cms[i-1]))
+		}
+		branchCommits[bn] = cms
+	}
+
+	// Branches look like this:
+	//
+	//      feature1: *--*
+	//               /
+	// main:  *--*--*
+	//               \
+	//      feature2: *--*
+
+	mainHash := mustGetHash(t, mainCommits[2])
+	feature1Hash := mustGetHash(t, branchCommits["feature1"][2])
+	feature2Hash := mustGetHash(t, branchCommits["feature2"][2])
+
+	collect := func(included, excluded []hash.Hash) []*doltdb.Commit {
+		itr, err := GetDotDotRevisionsIterator(ctx, dEnv.DoltDB, included, excluded)
+		require.NoError(t, err)
+		var res []*doltdb.Commit
+		for {
+			_, cm, err := itr.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			res = append(res, cm)
+		}
+		return res
+	}
+
+	res := collect([]hash.Hash{feature1Hash}, []hash.Hash{mainHash})
+	require.Len(t, res, 2)
+	assertEqualHashes(t, branchCommits["feature1"][2], res[0])
+	assertEqualHashes(t, branchCommits["feature1"][1], res[1])
+
+	res = collect([]hash.Hash{feature1Hash, feature2Hash}, []hash.Hash{mainHash})
+	assert.Len(t, res, 4)
+
+	res = collect([]hash.Hash{feature1Hash, feature2Hash}, []hash.Hash{mainHash, feature2Hash})
+	require.Len(t, res, 2)
+	assertEqualHashes(t, branchCommits["feature1"][2], res[0])
+	assertEqualHashes(t, branchCommits["feature1"][1], res[1])
+
+	res = collect([]hash.Hash{mainHash}, []hash.Hash{feature1Hash})
+	assert.Len(t, res, 0)
+
+	res = collect([]hash.Hash{feature1Hash}, nil)
+	require.Len(t, res, 5)
+	assertEqualHashes(t, branchCommits["feature1"][2], res[0])
+	assertEqualHashes(t, mainCommits[0], res[4])
+
+	itr, err := GetDotDotRevisionsIterator(ctx, dEnv.DoltDB, []hash.Hash{feature1Hash}, []hash.Hash{mainHash})
+	require.NoError(t, err)
+	_, _, err = itr.Next(ctx)
+	require.NoError(t, err)
+	require.NoError(t, itr.Reset(ctx))
+	_, cm, err := itr.Next(ctx)
+	require.NoError(t, err)
+	assertEqualHashes(t, branchCommits["feature1"][2], cm)
+}
+
 func assertEqualHashes(t *testing.T, lc, rc *doltdb.Commit) {
 	assert.Equal(t, mustGetHash(t, lc), mustGetHash(t, rc))
 }
@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
 )
 
 type tblErrorType string
@@ -27,6 +28,7 @@ const (
 	tblErrTypeNotExist   tblErrorType = "do not exist"
 	tblErrTypeInConflict tblErrorType = "are in conflict"
 	tblErrTypeConstViols tblErrorType = "have constraint violations"
+	tblErrTypeIgnored    tblErrorType = "are ignored by " + doltdb.IgnoreTableName
 )
 
 type TblError struct {
@@ -46,6 +48,10 @@ func NewTblHasConstraintViolations(tbls []string) TblError {
 	return TblError{tbls, tblErrTypeConstViols}
 }
 
+func NewTblIgnoredError(tbls []string) TblError {
+	return TblError{tbls, tblErrTypeIgnored}
+}
+
 func (te TblError) Error() string {
 	return "error: the table(s) " + strings.Join(te.tables, ", ") + " " + string(te.tblErrType)
 }
@@ -76,6 +82,10 @@ func IsTblViolatesConstraints(err error) bool {
 	return getTblErrType(err) == tblErrTypeConstViols
 }
 
+func IsTblIgnored(err error) bool {
+	return getTblErrType(err) == tblErrTypeIgnored
+}
+
 func GetTablesForError(err error) []string {
 	te, ok := err.(TblError)
 
@@ -0,0 +1,145 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table"
+)
+
+// GetIgnoredTablePatterns reads the patterns of the dolt_ignore table in the working root of |roots|. If there is no
+// dolt_ignore table, no patterns are returned.
+func GetIgnoredTablePatterns(ctx context.Context, roots doltdb.Roots) (doltdb.IgnorePatterns, error) {
+	tbl, ok, err := roots.Working.GetTable(ctx, doltdb.IgnoreTableName)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	sch, err := tbl.GetSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	patternIdx := sch.GetAllCols().IndexOf(doltdb.IgnoreTablePatternCol)
+	ignoredIdx := sch.GetAllCols().IndexOf(doltdb.IgnoreTableIgnoredCol)
+	if patternIdx < 0 || ignoredIdx < 0 {
+		return nil, fmt.Errorf("the schema of %s has been altered", doltdb.IgnoreTableName)
+	}
+
+	idx, err := tbl.GetRowData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	itr, err := table.NewTableIterator(ctx, sch, idx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close(ctx)
+
+	var patterns doltdb.IgnorePatterns
+	for {
+		r, err := itr.Next(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		pattern, ok := r[patternIdx].(string)
+		if !ok {
+			return nil, fmt.Errorf("the schema of %s has been altered", doltdb.IgnoreTableName)
+		}
+		ignore, err := sql.ConvertToBool(r[ignoredIdx])
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, doltdb.IgnorePattern{Pattern: pattern, Ignore: ignore})
+	}
+
+	return patterns, nil
+}
+
+// IdentifyIgnoredTables splits |tbls| into the tables that are ignored by the dolt_ignore table and the ones that
+// aren't. Only new tables, which don't exist in the staged root, can be ignored.
+func IdentifyIgnoredTables(ctx context.Context, roots doltdb.Roots, tbls []string) (included []string, ignored []string, err error) {
+	patterns, err := GetIgnoredTablePatterns(ctx, roots)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(patterns) == 0 {
+		return tbls, nil, nil
+	}
+
+	for _, tbl := range tbls {
+		isIgnored, err := isNewTableIgnored(ctx, roots, patterns, tbl)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isIgnored {
+			ignored = append(ignored, tbl)
+		} else {
+			included = append(included, tbl)
+		}
+	}
+
+	return included, ignored, nil
+}
+
+// FilterIgnoredTableDeltas removes the deltas for new tables that are ignored by the dolt_ignore table from
+// |unstaged|.
+func FilterIgnoredTableDeltas(ctx context.Context, roots doltdb.Roots, unstaged []diff.TableDelta) ([]diff.TableDelta, error) {
+	patterns, err := GetIgnoredTablePatterns(ctx, roots)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return unstaged, nil
+	}
+
+	filtered := make([]diff.TableDelta, 0, len(unstaged))
+	for _, td := range unstaged {
+		if td.IsAdd() {
+			isIgnored, err := isNewTableIgnored(ctx, roots, patterns, td.ToName)
+			if err != nil {
+				return nil, err
+			}
+			if isIgnored {
+				continue
+			}
+		}
+		filtered = append(filtered, td)
+	}
+
+	return filtered, nil
+}
+
+func isNewTableIgnored(ctx context.Context, roots doltdb.Roots, patterns doltdb.IgnorePatterns, tbl string) (bool, error) {
+	if has, err := roots.Staged.HasTable(ctx, tbl); err != nil {
+		return false, err
+	} else if has {
+		return false, nil
+	}
+	return patterns.IsTableNameIgnored(tbl)
+}
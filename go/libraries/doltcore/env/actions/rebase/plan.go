@@ -0,0 +1,344 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebase
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb/durable"
+	"github.com/dolthub/dolt/go/libraries/doltcore/row"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table"
+	"github.com/dolthub/dolt/go/store/types"
+	"github.com/dolthub/dolt/go/store/val"
+)
+
+const (
+	// ActionPick replays the commit as is.
+	ActionPick = "pick"
+	// ActionReword replays the commit using the message from the plan.
+	ActionReword = "reword"
+	// ActionSquash melds the commit into the previous one, combining both commit messages.
+	ActionSquash = "squash"
+	// ActionFixup melds the commit into the previous one, keeping the previous commit's message.
+	ActionFixup = "fixup"
+	// ActionDrop removes the commit from the history.
+	ActionDrop = "drop"
+)
+
+var validActions = map[string]struct{}{
+	ActionPick:   {},
+	ActionReword: {},
+	ActionSquash: {},
+	ActionFixup:  {},
+	ActionDrop:   {},
+}
+
+// actionAbbreviations are the single letter forms of actions accepted in plan files, as in git.
+var actionAbbreviations = map[string]string{
+	"p": ActionPick,
+	"r": ActionReword,
+	"s": ActionSquash,
+	"f": ActionFixup,
+	"d": ActionDrop,
+}
+
+var rebaseCols = schema.NewColCollection(
+	schema.NewColumn(doltdb.RebaseTableOrderCol, schema.DoltRebaseOrderTag, types.FloatKind, true, schema.NotNullConstraint{}),
+	schema.NewColumn(doltdb.RebaseTableActionCol, schema.DoltRebaseActionTag, types.StringKind, false, schema.NotNullConstraint{}),
+	schema.NewColumn(doltdb.RebaseTableCommitHashCol, schema.DoltRebaseCommitHashTag, types.StringKind, false, schema.NotNullConstraint{}),
+	schema.NewColumn(doltdb.RebaseTableCommitMessageCol, schema.DoltRebaseCommitMessageTag, types.StringKind, false),
+)
+
+// RebasePlanSchema is the schema of the dolt_rebase table.
+var RebasePlanSchema = schema.MustSchemaFromCols(rebaseCols)
+
+// PlanStep is a single step of a rebase plan.
+type PlanStep struct {
+	// RebaseOrder determines the order in which steps are applied, lowest first.
+	RebaseOrder float64
+	// Action is one of pick, reword, squash, fixup or drop.
+	Action string
+	// CommitHash is the hash of the commit to replay.
+	CommitHash string
+	// CommitMsg is the commit message for the step. It's only used by reword, and to build the message for squash.
+	CommitMsg string
+}
+
+// Plan is the ordered list of steps for a rebase.
+type Plan struct {
+	Steps []PlanStep
+}
+
+// Validate returns an error if any step has an unknown action, or if the plan cannot be applied as written.
+func (p *Plan) Validate() error {
+	firstApplied := true
+	for _, step := range p.Steps {
+		if _, ok := validActions[step.Action]; !ok {
+			return fmt.Errorf("invalid rebase action '%s' for commit %s", step.Action, step.CommitHash)
+		}
+		if step.Action == ActionDrop {
+			continue
+		}
+		if firstApplied && (step.Action == ActionSquash || step.Action == ActionFixup) {
+			return fmt.Errorf("cannot %s commit %s without a previous commit", step.Action, step.CommitHash)
+		}
+		firstApplied = false
+	}
+	return nil
+}
+
+func (p *Plan) sort() {
+	sort.SliceStable(p.Steps, func(i, j int) bool {
+		return p.Steps[i].RebaseOrder < p.Steps[j].RebaseOrder
+	})
+}
+
+// ReadPlan reads the rebase plan stored in the dolt_rebase table of |root|.
+func ReadPlan(ctx context.Context, root *doltdb.RootValue) (*Plan, error) {
+	tbl, ok, err := root.GetTable(ctx, doltdb.RebaseTableName)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoRebaseInProgress
+	}
+
+	sch, err := tbl.GetSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !schema.SchemasAreEqual(sch, RebasePlanSchema) {
+		return nil, fmt.Errorf("the schema of %s has been altered", doltdb.RebaseTableName)
+	}
+
+	idx, err := tbl.GetRowData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	itr, err := table.NewTableIterator(ctx, sch, idx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close(ctx)
+
+	plan := &Plan{}
+	for {
+		r, err := itr.Next(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		step := PlanStep{
+			RebaseOrder: r[0].(float64),
+			Action:      strings.ToLower(r[1].(string)),
+			CommitHash:  r[2].(string),
+		}
+		if r[3] != nil {
+			step.CommitMsg = r[3].(string)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	plan.sort()
+	return plan, nil
+}
+
+// WritePlan replaces the contents of the dolt_rebase table in |root| with |plan|, creating the table if necessary.
+func WritePlan(ctx context.Context, root *doltdb.RootValue, plan *Plan) (*doltdb.RootValue, error) {
+	root, err := RemovePlan(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err = root.CreateEmptyTable(ctx, doltdb.RebaseTableName, RebasePlanSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, _, err := root.GetTable(ctx, doltdb.RebaseTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if types.IsFormat_DOLT(tbl.Format()) {
+		tbl, err = writePlanProlly(ctx, tbl, plan)
+	} else {
+		tbl, err = writePlanNoms(ctx, tbl, plan)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return root.PutTable(ctx, doltdb.RebaseTableName, tbl)
+}
+
+// RemovePlan removes the dolt_rebase table from |root| if it exists.
+func RemovePlan(ctx context.Context, root *doltdb.RootValue) (*doltdb.RootValue, error) {
+	ok, err := root.HasTable(ctx, doltdb.RebaseTableName)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return root, nil
+	}
+	return root.RemoveTables(ctx, true, false, doltdb.RebaseTableName)
+}
+
+func writePlanProlly(ctx context.Context, tbl *doltdb.Table, plan *Plan) (*doltdb.Table, error) {
+	idx, err := tbl.GetRowData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := durable.ProllyMapFromIndex(idx)
+
+	kb := val.NewTupleBuilder(RebasePlanSchema.GetKeyDescriptor())
+	vb := val.NewTupleBuilder(RebasePlanSchema.GetValueDescriptor())
+	mut := m.Mutate()
+	for _, step := range plan.Steps {
+		kb.PutFloat64(0, step.RebaseOrder)
+		vb.PutString(0, step.Action)
+		vb.PutString(1, step.CommitHash)
+		vb.PutString(2, step.CommitMsg)
+		err = mut.Put(ctx, kb.Build(m.Pool()), vb.Build(m.Pool()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err = mut.Map(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl.UpdateRows(ctx, durable.IndexFromProllyMap(m))
+}
+
+func writePlanNoms(ctx context.Context, tbl *doltdb.Table, plan *Plan) (*doltdb.Table, error) {
+	data, err := tbl.GetNomsRowData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	me := data.Edit()
+	for _, step := range plan.Steps {
+		taggedVals := row.TaggedValues{
+			schema.DoltRebaseOrderTag:         types.Float(step.RebaseOrder),
+			schema.DoltRebaseActionTag:        types.String(step.Action),
+			schema.DoltRebaseCommitHashTag:    types.String(step.CommitHash),
+			schema.DoltRebaseCommitMessageTag: types.String(step.CommitMsg),
+		}
+		r, err := row.New(tbl.Format(), RebasePlanSchema, taggedVals)
+		if err != nil {
+			return nil, err
+		}
+		me.Set(r.NomsMapKey(RebasePlanSchema), r.NomsMapValue(RebasePlanSchema))
+	}
+
+	updated, err := me.Map(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl.UpdateNomsRows(ctx, updated)
+}
+
+// FormatPlan renders |plan| in the plan file format edited on the command line: one step per line, as
+// "<action> <commit hash> <first line of the commit message>".
+func FormatPlan(plan *Plan) string {
+	sb := strings.Builder{}
+	for _, step := range plan.Steps {
+		msg := step.CommitMsg
+		if i := strings.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i]
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s\n", step.Action, step.CommitHash, msg))
+	}
+	sb.WriteString(planFileHelp)
+	return sb.String()
+}
+
+const planFileHelp = `
+# Commands:
+# p, pick <commit> = use commit
+# r, reword <commit> <message> = use commit, but replace its message with the rest of the line
+# s, squash <commit> = use commit, but meld into previous commit
+# f, fixup <commit> = like "squash", but discard this commit's message
+# d, drop <commit> = remove commit
+#
+# These lines can be re-ordered; they are executed from top to bottom.
+# If you remove a line here THAT COMMIT WILL BE LOST.
+# However, if you remove everything, the rebase will be aborted.
+`
+
+// ParsePlan parses a plan file as written by FormatPlan. Lines are ordered as they appear in the file. Blank lines
+// and lines starting with # are ignored. Messages in the file only replace the original commit message for reword
+// steps, since the file only shows the first line of each message; |original| supplies the full messages of the
+// commits in the plan, keyed by commit hash.
+func ParsePlan(contents string, original *Plan) (*Plan, error) {
+	messages := make(map[string]string, len(original.Steps))
+	for _, step := range original.Steps {
+		messages[step.CommitHash] = step.CommitMsg
+	}
+
+	plan := &Plan{}
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid line in rebase plan: '%s'", line)
+		}
+
+		action := strings.ToLower(fields[0])
+		if full, ok := actionAbbreviations[action]; ok {
+			action = full
+		}
+
+		commitHash := fields[1]
+		msg, ok := messages[commitHash]
+		if !ok {
+			return nil, fmt.Errorf("commit %s is not part of this rebase", commitHash)
+		}
+		if action == ActionReword && len(fields) == 3 {
+			msg = strings.TrimSpace(fields[2])
+		}
+
+		plan.Steps = append(plan.Steps, PlanStep{
+			RebaseOrder: float64(len(plan.Steps) + 1),
+			Action:      action,
+			CommitHash:  commitHash,
+			CommitMsg:   msg,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return plan, plan.Validate()
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPlan = &Plan{Steps: []PlanStep{
+	{RebaseOrder: 1, Action: ActionPick, CommitHash: "aaaa", CommitMsg: "first\n\nwith a body"},
+	{RebaseOrder: 2, Action: ActionPick, CommitHash: "bbbb", CommitMsg: "second"},
+	{RebaseOrder: 3, Action: ActionPick, CommitHash: "cccc", CommitMsg: "third"},
+}}
+
+func TestFormatAndParsePlan(t *testing.T) {
+	formatted := FormatPlan(testPlan)
+	assert.Contains(t, formatted, "pick aaaa first\n")
+	assert.NotContains(t, formatted, "with a body")
+
+	parsed, err := ParsePlan(formatted, testPlan)
+	require.NoError(t, err)
+	assert.Equal(t, testPlan, parsed)
+}
+
+func TestParseEditedPlan(t *testing.T) {
+	edited := `
+r cccc reworded third
+pick aaaa first
+# a comment
+squash bbbb second
+`
+	parsed, err := ParsePlan(edited, testPlan)
+	require.NoError(t, err)
+	assert.Equal(t, []PlanStep{
+		{RebaseOrder: 1, Action: ActionReword, CommitHash: "cccc", CommitMsg: "reworded third"},
+		{RebaseOrder: 2, Action: ActionPick, CommitHash: "aaaa", CommitMsg: "first\n\nwith a body"},
+		{RebaseOrder: 3, Action: ActionSquash, CommitHash: "bbbb", CommitMsg: "second"},
+	}, parsed.Steps)
+
+	parsed, err = ParsePlan("# nothing left\n", testPlan)
+	require.NoError(t, err)
+	assert.Len(t, parsed.Steps, 0)
+}
+
+func TestParseInvalidPlan(t *testing.T) {
+	_, err := ParsePlan("pick dddd unknown", testPlan)
+	assert.Error(t, err)
+
+	_, err = ParsePlan("edit aaaa first", testPlan)
+	assert.Error(t, err)
+
+	_, err = ParsePlan("drop aaaa first\nfixup bbbb second", testPlan)
+	assert.Error(t, err)
+
+	_, err = ParsePlan("aaaa", testPlan)
+	assert.Error(t, err)
+}
@@ -0,0 +1,453 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/commitwalk"
+	"github.com/dolthub/dolt/go/libraries/doltcore/merge"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var ErrRebaseInProgress = errors.New("a rebase is already in progress; use --continue or --abort")
+var ErrNoRebaseInProgress = errors.New("no rebase in progress")
+var ErrMergeInProgress = errors.New("cannot rebase while a merge is in progress")
+var ErrUncommittedChanges = errors.New("cannot rebase: you have uncommitted changes; commit or reset them first")
+var ErrUnresolvedConflicts = errors.New("cannot continue rebase: resolve all conflicts and constraint violations first")
+
+// originalHeadRef returns the internal ref recording the head of |branch| at the start of a rebase. The presence of
+// this ref is what marks a rebase of |branch| as in progress.
+func originalHeadRef(branch ref.DoltRef) ref.DoltRef {
+	return ref.NewInternalRef("rebase/" + branch.GetPath())
+}
+
+// stoppedCommitRef returns the internal ref recording the commit whose replay stopped on conflicts, if any.
+func stoppedCommitRef(branch ref.DoltRef) ref.DoltRef {
+	return ref.NewInternalRef("rebase-stopped/" + branch.GetPath())
+}
+
+// ontoRef returns the internal ref recording the commit |branch| is rebased onto. Commits after it on the branch were
+// created by the rebase.
+func ontoRef(branch ref.DoltRef) ref.DoltRef {
+	return ref.NewInternalRef("rebase-onto/" + branch.GetPath())
+}
+
+// IsActive returns whether a rebase of |branch| is in progress.
+func IsActive(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef) (bool, error) {
+	return ddb.HasRef(ctx, originalHeadRef(branch))
+}
+
+// Heads are the refs a rebase operation moves, mapped to their new commits. A ref mapped to nil is deleted. Callers
+// must persist them in the same atomic write as the working set returned with them, with
+// DoltDB.SetHeadsWithWorkingSet or its session equivalent, so that the branch never gets ahead of its working set.
+type Heads map[ref.DoltRef]*doltdb.Commit
+
+// Start begins a rebase of |branch| onto |upstream|. The commits reachable from the head of |branch| but not from
+// |upstream| are collected, oldest first, into a plan that picks each of them. The branch is moved to |upstream| and
+// the plan is written to the dolt_rebase table of the returned working set, where it can be edited before calling
+// Continue. Merge commits are not replayed. The caller is responsible for persisting the returned working set and
+// heads.
+func Start(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, ws *doltdb.WorkingSet, upstream *doltdb.Commit) (*doltdb.WorkingSet, Heads, error) {
+	if active, err := IsActive(ctx, ddb, branch); err != nil {
+		return nil, nil, err
+	} else if active {
+		return nil, nil, ErrRebaseInProgress
+	}
+	if ws.MergeActive() {
+		return nil, nil, ErrMergeInProgress
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return nil, nil, err
+	}
+	headRoot, err := head.GetRootValue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dirty, err := isDirty(ctx, ws, headRoot); err != nil {
+		return nil, nil, err
+	} else if dirty {
+		return nil, nil, ErrUncommittedChanges
+	}
+
+	headHash, err := head.HashOf()
+	if err != nil {
+		return nil, nil, err
+	}
+	upstreamHash, err := upstream.HashOf()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commits, err := commitwalk.GetDotDotRevisions(ctx, ddb, headHash, ddb, upstreamHash, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &Plan{}
+	for i := len(commits) - 1; i >= 0; i-- {
+		cm := commits[i]
+		if cm.NumParents() > 1 {
+			continue
+		}
+		h, err := cm.HashOf()
+		if err != nil {
+			return nil, nil, err
+		}
+		meta, err := cm.GetCommitMeta(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		plan.Steps = append(plan.Steps, PlanStep{
+			RebaseOrder: float64(len(plan.Steps) + 1),
+			Action:      ActionPick,
+			CommitHash:  h.String(),
+			CommitMsg:   meta.Description,
+		})
+	}
+
+	upstreamRoot, err := upstream.GetRootValue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	workingRoot, err := WritePlan(ctx, upstreamRoot, plan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	heads := Heads{originalHeadRef(branch): head, ontoRef(branch): upstream, branch: upstream}
+	return ws.WithWorkingRoot(workingRoot).WithStagedRoot(upstreamRoot), heads, nil
+}
+
+// Continue applies the rebase plan in the working set of |branch|, committing each step on top of the branch head.
+// If a step was previously stopped on conflicts, its resolved changes in the working root are committed first. If
+// applying a step produces conflicts or constraint violations, the branch is left at the last successfully applied
+// step, the working root holds the conflicted result, and the step is returned. Otherwise the rebase is finished and
+// the returned step is nil. The caller is responsible for persisting the returned working set and heads.
+func Continue(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, ws *doltdb.WorkingSet, opts editor.Options) (*doltdb.WorkingSet, Heads, *PlanStep, error) {
+	if active, err := IsActive(ctx, ddb, branch); err != nil {
+		return nil, nil, nil, err
+	} else if !active {
+		return nil, nil, nil, ErrNoRebaseInProgress
+	}
+
+	workingRoot := ws.WorkingRoot()
+	plan, err := ReadPlan(ctx, workingRoot)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = plan.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if ok, err := workingRoot.HasConflicts(ctx); err != nil {
+		return nil, nil, nil, err
+	} else if ok {
+		return nil, nil, nil, ErrUnresolvedConflicts
+	}
+	if ok, err := workingRoot.HasConstraintViolations(ctx); err != nil {
+		return nil, nil, nil, err
+	} else if ok {
+		return nil, nil, nil, ErrUnresolvedConflicts
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	onto, err := ddb.ResolveCommitRef(ctx, ontoRef(branch))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ontoHash, err := onto.HashOf()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	heads := Heads{}
+
+	stopped, err := ddb.HasRef(ctx, stoppedCommitRef(branch))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if stopped {
+		stoppedCm, err := ddb.ResolveCommitRef(ctx, stoppedCommitRef(branch))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		stoppedHash, err := stoppedCm.HashOf()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		// The stopped step stays at the front of the plan until it's resolved. If it's been removed from the plan or
+		// dropped in the meantime, its changes are discarded.
+		if len(plan.Steps) > 0 && plan.Steps[0].CommitHash == stoppedHash.String() {
+			if plan.Steps[0].Action != ActionDrop {
+				resolved, err := RemovePlan(ctx, workingRoot)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				head, err = commitStep(ctx, ddb, head, ontoHash, stoppedCm, resolved, plan.Steps[0])
+				if err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			plan.Steps = plan.Steps[1:]
+		}
+
+		heads[stoppedCommitRef(branch)] = nil
+	} else if dirty, err := isDirty(ctx, ws, nil); err != nil {
+		return nil, nil, nil, err
+	} else if dirty {
+		return nil, nil, nil, ErrUncommittedChanges
+	}
+
+	for i, step := range plan.Steps {
+		if step.Action == ActionDrop {
+			continue
+		}
+
+		h, ok := hash.MaybeParse(step.CommitHash)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("invalid commit hash '%s' in rebase plan", step.CommitHash)
+		}
+		cs, err := doltdb.NewCommitSpec(h.String())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		cm, err := ddb.Resolve(ctx, cs, nil)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		merged, err := applyCommit(ctx, ddb, head, cm, opts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		hasConflicts, err := merged.HasConflicts(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hasViolations, err := merged.HasConstraintViolations(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if hasConflicts || hasViolations {
+			heads[branch] = head
+			heads[stoppedCommitRef(branch)] = cm
+
+			remaining := &Plan{Steps: plan.Steps[i:]}
+			workingRoot, err = WritePlan(ctx, merged, remaining)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			headRoot, err := head.GetRootValue(ctx)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			return ws.WithWorkingRoot(workingRoot).WithStagedRoot(headRoot), heads, &step, nil
+		}
+
+		head, err = commitStep(ctx, ddb, head, ontoHash, cm, merged, step)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	heads[branch] = head
+	heads[originalHeadRef(branch)] = nil
+	heads[ontoRef(branch)] = nil
+
+	headRoot, err := head.GetRootValue(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return ws.WithWorkingRoot(headRoot).WithStagedRoot(headRoot).ClearMerge(), heads, nil, nil
+}
+
+// Abort cancels the rebase of |branch|, returning it to the commit it pointed to when the rebase started. Any
+// changes in the working set are discarded. The caller is responsible for persisting the returned working set and
+// heads.
+func Abort(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, ws *doltdb.WorkingSet) (*doltdb.WorkingSet, Heads, error) {
+	if active, err := IsActive(ctx, ddb, branch); err != nil {
+		return nil, nil, err
+	} else if !active {
+		return nil, nil, ErrNoRebaseInProgress
+	}
+
+	origHead, err := ddb.ResolveCommitRef(ctx, originalHeadRef(branch))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	heads := Heads{branch: origHead, originalHeadRef(branch): nil, ontoRef(branch): nil}
+	if stopped, err := ddb.HasRef(ctx, stoppedCommitRef(branch)); err != nil {
+		return nil, nil, err
+	} else if stopped {
+		heads[stoppedCommitRef(branch)] = nil
+	}
+
+	root, err := origHead.GetRootValue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ws.WithWorkingRoot(root).WithStagedRoot(root).ClearMerge(), heads, nil
+}
+
+// applyCommit replays the changes introduced by |cm| on top of |head| with a three-way merge, using the parent of
+// |cm| as the ancestor.
+func applyCommit(ctx context.Context, ddb *doltdb.DoltDB, head, cm *doltdb.Commit, opts editor.Options) (*doltdb.RootValue, error) {
+	if cm.NumParents() == 0 {
+		h, err := cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot rebase commit with no parents (%s)", h.String())
+	}
+
+	headRoot, err := head.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmRoot, err := cm.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := ddb.ResolveParent(ctx, cm, 0)
+	if err != nil {
+		return nil, err
+	}
+	parentRoot, err := parent.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, _, err := merge.MergeRoots(ctx, headRoot, cmRoot, parentRoot, cm, parent, opts, merge.MergeOpts{IsCherryPick: false})
+	return merged, err
+}
+
+// commitStep commits |root| as the result of applying |step| for commit |cm| on top of |head|, returning the new head.
+// Steps that result in no changes are skipped, and |head| is returned unchanged. Squash and fixup steps amend |head|,
+// unless it's still the commit |onto| the rebase started from, which the rebase must not rewrite. They're committed
+// like picks in that case.
+func commitStep(ctx context.Context, ddb *doltdb.DoltDB, head *doltdb.Commit, onto hash.Hash, cm *doltdb.Commit, root *doltdb.RootValue, step PlanStep) (*doltdb.Commit, error) {
+	headRoot, err := head.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if equal, err := rootsEqual(ctx, headRoot, root); err != nil {
+		return nil, err
+	} else if equal {
+		return head, nil
+	}
+
+	headHash, err := head.HashOf()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := cm.GetCommitMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := []*doltdb.Commit{head}
+	var newMeta *datas.CommitMeta
+	switch {
+	case step.Action == ActionPick, step.Action == ActionFixup && headHash == onto:
+		newMeta, err = datas.NewCommitMetaWithUserTS(meta.Name, meta.Email, meta.Description, meta.Time())
+	case step.Action == ActionReword, step.Action == ActionSquash && headHash == onto:
+		newMeta, err = datas.NewCommitMetaWithUserTS(meta.Name, meta.Email, step.CommitMsg, meta.Time())
+	case step.Action == ActionSquash, step.Action == ActionFixup:
+		parents, err = ddb.ResolveAllParents(ctx, head)
+		if err != nil {
+			return nil, err
+		}
+		var headMeta *datas.CommitMeta
+		headMeta, err = head.GetCommitMeta(ctx)
+		if err != nil {
+			return nil, err
+		}
+		msg := headMeta.Description
+		if step.Action == ActionSquash {
+			msg = msg + "\n\n" + step.CommitMsg
+		}
+		newMeta, err = datas.NewCommitMetaWithUserTS(headMeta.Name, headMeta.Email, msg, headMeta.Time())
+	default:
+		return nil, fmt.Errorf("invalid rebase action '%s' for commit %s", step.Action, step.CommitHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, valHash, err := ddb.WriteRootValue(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddb.CommitDanglingWithParentCommits(ctx, valHash, parents, newMeta)
+}
+
+// isDirty returns whether the working set has changes other than the rebase plan. If |headRoot| is nil, the staged
+// root is used for comparison.
+func isDirty(ctx context.Context, ws *doltdb.WorkingSet, headRoot *doltdb.RootValue) (bool, error) {
+	if headRoot == nil {
+		headRoot = ws.StagedRoot()
+	} else if equal, err := rootsEqual(ctx, ws.StagedRoot(), headRoot); err != nil {
+		return false, err
+	} else if !equal {
+		return true, nil
+	}
+
+	equal, err := rootsEqual(ctx, ws.WorkingRoot(), headRoot)
+	return !equal, err
+}
+
+// rootsEqual returns whether |left| and |right| have the same tables with the same contents, ignoring the rebase plan.
+func rootsEqual(ctx context.Context, left, right *doltdb.RootValue) (bool, error) {
+	lh, err := left.MapTableHashes(ctx)
+	if err != nil {
+		return false, err
+	}
+	rh, err := right.MapTableHashes(ctx)
+	if err != nil {
+		return false, err
+	}
+	delete(lh, doltdb.RebaseTableName)
+	delete(rh, doltdb.RebaseTableName)
+
+	if len(lh) != len(rh) {
+		return false, nil
+	}
+	for name, h := range lh {
+		if rh[name] != h {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/utils/earl"
 	"github.com/dolthub/dolt/go/store/datas"
 	"github.com/dolthub/dolt/go/store/datas/pull"
+	"github.com/dolthub/dolt/go/store/hash"
 )
 
 var ErrCantFF = errors.New("can't fast forward merge")
@@ -267,7 +268,13 @@ func FetchFollowTags(ctx context.Context, tempTableDir string, srcDB, destDB *do
 			return true, err
 		}
 
-		has, err := destDB.Has(ctx, tagHash)
+		var has bool
+		if destDB.IsLazy() {
+			// every chunk of its remote counts as fetched by a lazy clone, its tags are fetched once they have refs
+			has, err = destDB.HasRef(ctx, tag.GetDoltRef())
+		} else {
+			has, err = destDB.Has(ctx, tagHash)
+		}
 		if err != nil {
 			return true, err
 		}
@@ -316,6 +323,36 @@ func FetchFollowTags(ctx context.Context, tempTableDir string, srcDB, destDB *do
 	return nil
 }
 
+// FetchShallowHistory fetches the history below the shallow commits of |destDB| from |srcDB|, which turns a shallow
+// clone into a complete one. The tags of the history fetched are fetched as well.
+func FetchShallowHistory(ctx context.Context, tempTableDir string, srcDB, destDB *doltdb.DoltDB, progStarter ProgStarter, progStopper ProgStopper) error {
+	if !destDB.IsShallow() {
+		return nil
+	}
+
+	missing, err := destDB.MissingShallowHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	for h := range missing {
+		newCtx, cancelFunc := context.WithCancel(ctx)
+		wg, progChan, statsCh := progStarter(newCtx)
+		err = destDB.PullChunks(ctx, tempTableDir, srcDB, h, progChan, statsCh)
+		progStopper(cancelFunc, wg, progChan, statsCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = destDB.SetShallowCommits(hash.NewHashSet())
+	if err != nil {
+		return err
+	}
+
+	return FetchFollowTags(ctx, tempTableDir, srcDB, destDB, progStarter, progStopper)
+}
+
 // FetchRemoteBranch fetches and returns the |Commit| corresponding to the remote ref given. Returns an error if the
 // remote reference doesn't exist or can't be fetched. Blocks until the fetch is complete.
 func FetchRemoteBranch(
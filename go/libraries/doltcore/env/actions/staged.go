@@ -21,16 +21,37 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
 )
 
-func StageTables(ctx context.Context, roots doltdb.Roots, tbls []string) (doltdb.Roots, error) {
+// StageTables stages the tables named. If |filterIgnoredTables| is true, naming a new table that is ignored by the
+// dolt_ignore table is an error.
+func StageTables(ctx context.Context, roots doltdb.Roots, tbls []string, filterIgnoredTables bool) (doltdb.Roots, error) {
+	if filterIgnoredTables {
+		_, ignored, err := IdentifyIgnoredTables(ctx, roots, tbls)
+		if err != nil {
+			return doltdb.Roots{}, err
+		}
+		if len(ignored) > 0 {
+			return doltdb.Roots{}, NewTblIgnoredError(ignored)
+		}
+	}
+
 	return stageTables(ctx, roots, tbls)
 }
 
-func StageAllTables(ctx context.Context, roots doltdb.Roots) (doltdb.Roots, error) {
+// StageAllTables stages all tables. If |filterIgnoredTables| is true, new tables that are ignored by the dolt_ignore
+// table are skipped.
+func StageAllTables(ctx context.Context, roots doltdb.Roots, filterIgnoredTables bool) (doltdb.Roots, error) {
 	tbls, err := doltdb.UnionTableNames(ctx, roots.Staged, roots.Working)
 	if err != nil {
 		return doltdb.Roots{}, err
 	}
 
+	if filterIgnoredTables {
+		tbls, _, err = IdentifyIgnoredTables(ctx, roots, tbls)
+		if err != nil {
+			return doltdb.Roots{}, err
+		}
+	}
+
 	return stageTables(ctx, roots, tbls)
 }
 
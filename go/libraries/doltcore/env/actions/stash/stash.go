@@ -0,0 +1,350 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/merge"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var ErrNoLocalChanges = errors.New("No local changes to save")
+var ErrNoStashEntries = errors.New("No stash entries found.")
+var ErrMergeInProgress = errors.New("a merge is in progress; commit or abort it first")
+var ErrUnresolvedConflicts = errors.New("resolve all conflicts and constraint violations first")
+
+// maxPushRetries is the number of times Push tries to add its entry when concurrent pushes take its place in the list.
+const maxPushRetries = 5
+
+// WriteFunc persists the working set |ws| and sets |heads|, deleting those with a nil commit, in one write, such as
+// DoltDB.SetHeadsWithWorkingSet or its session equivalent. The heads in |prevHeads| must still be at the hashes given,
+// or not exist if the hash is empty, or it returns datas.ErrUnexpectedHead without writing anything.
+type WriteFunc func(ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error
+
+// Stashes are stored the same way git stores them. Each entry is a commit of the working root whose parents are the
+// HEAD commit the changes were made on top of and a commit of the staged root. The entry's message records the
+// branch it was created on, e.g. "WIP on main: <hash> <subject>", or "On main: <message>" for a user supplied message.
+
+var stashNameRegex = regexp.MustCompile(`^stash@\{(\d+)\}$`)
+var stashBranchRegex = regexp.MustCompile(`^(?:WIP on|On) ([^:]+): `)
+
+// Stash is an entry in the stash list.
+type Stash struct {
+	// Index is the position of the entry in the stash list, 0 being the most recent.
+	Index int
+	// Ref is the ref the entry is stored under.
+	Ref ref.StashRef
+	// Commit is the commit of the stashed working root.
+	Commit *doltdb.Commit
+	// BranchName is the branch that was checked out when the entry was created.
+	BranchName string
+	// HeadHash is the hash of the commit the stashed changes were made on top of.
+	HeadHash hash.Hash
+	// Description is the entry's message.
+	Description string
+}
+
+// Name returns the name of the entry, e.g. stash@{0}.
+func (s *Stash) Name() string {
+	return fmt.Sprintf("stash@{%d}", s.Index)
+}
+
+// List returns the entries of the stash list, most recent first.
+func List(ctx context.Context, ddb *doltdb.DoltDB) ([]*Stash, error) {
+	refs, err := ddb.GetStashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stashes := make([]*Stash, len(refs))
+	for i, r := range refs {
+		stashes[i], err = load(ctx, ddb, i, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stashes, nil
+}
+
+// Get returns the stash entry with the name given. The name may be of the form stash@{n}, or just n. An empty name
+// refers to the most recent entry.
+func Get(ctx context.Context, ddb *doltdb.DoltDB, name string) (*Stash, error) {
+	idx := 0
+	if name != "" {
+		if matches := stashNameRegex.FindStringSubmatch(name); matches != nil {
+			name = matches[1]
+		}
+		var err error
+		idx, err = strconv.Atoi(name)
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("%s is not a valid reference", name)
+		}
+	}
+
+	refs, err := ddb.GetStashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, ErrNoStashEntries
+	}
+	if idx >= len(refs) {
+		return nil, fmt.Errorf("stash@{%d} is not a valid reference", idx)
+	}
+
+	return load(ctx, ddb, idx, refs[idx])
+}
+
+func load(ctx context.Context, ddb *doltdb.DoltDB, idx int, r ref.StashRef) (*Stash, error) {
+	cm, err := ddb.ResolveCommitRef(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := cm.GetCommitMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := cm.ParentHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) != 2 {
+		return nil, fmt.Errorf("invalid stash entry %s", r.String())
+	}
+
+	branchName := ""
+	if matches := stashBranchRegex.FindStringSubmatch(meta.Description); matches != nil {
+		branchName = matches[1]
+	}
+
+	return &Stash{
+		Index:       idx,
+		Ref:         r,
+		Commit:      cm,
+		BranchName:  branchName,
+		HeadHash:    parents[0],
+		Description: meta.Description,
+	}, nil
+}
+
+// Push saves the staged and working changes of |ws| as a new stash entry, and resets both roots of the working set to
+// the HEAD of |branch|. The entry and the working set are written together with |write|. Tables that have never been
+// staged are stashed as well. If |msg| is empty, a description of the HEAD commit is used as the entry's message.
+func Push(ctx context.Context, ddb *doltdb.DoltDB, branch ref.DoltRef, ws *doltdb.WorkingSet, name, email, msg string, write WriteFunc) (*Stash, error) {
+	if ws.MergeActive() {
+		return nil, ErrMergeInProgress
+	}
+	if err := checkConflicts(ctx, ws.WorkingRoot()); err != nil {
+		return nil, err
+	}
+
+	head, err := ddb.ResolveCommitRef(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	headRoot, err := head.GetRootValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	headRootHash, err := headRoot.HashOf()
+	if err != nil {
+		return nil, err
+	}
+	workingHash, err := ws.WorkingRoot().HashOf()
+	if err != nil {
+		return nil, err
+	}
+	stagedHash, err := ws.StagedRoot().HashOf()
+	if err != nil {
+		return nil, err
+	}
+	if workingHash == headRootHash && stagedHash == headRootHash {
+		return nil, ErrNoLocalChanges
+	}
+
+	headHash, err := head.HashOf()
+	if err != nil {
+		return nil, err
+	}
+	headMeta, err := head.GetCommitMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subject := strings.SplitN(headMeta.Description, "\n", 2)[0]
+	headDesc := fmt.Sprintf("%s: %s %s", branch.GetPath(), headHash.String(), subject)
+
+	indexCm, err := commitRoot(ctx, ddb, ws.StagedRoot(), []*doltdb.Commit{head}, name, email, "index on "+headDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := "WIP on " + headDesc
+	if msg != "" {
+		desc = fmt.Sprintf("On %s: %s", branch.GetPath(), msg)
+	}
+	stashCm, err := commitRoot(ctx, ddb, ws.WorkingRoot(), []*doltdb.Commit{head, indexCm}, name, email, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	ws = ws.WithWorkingRoot(headRoot).WithStagedRoot(headRoot)
+	for i := 0; i < maxPushRetries; i++ {
+		// the entry is added if no other entry was added under its ref meanwhile
+		stashRef, err := ddb.NewStashRef(ctx)
+		if err != nil {
+			return nil, err
+		}
+		heads := map[ref.DoltRef]*doltdb.Commit{stashRef: stashCm}
+		err = write(ws, heads, map[ref.DoltRef]hash.Hash{stashRef: {}})
+		if errors.Is(err, datas.ErrUnexpectedHead) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return &Stash{
+			Index:       0,
+			Ref:         stashRef,
+			Commit:      stashCm,
+			BranchName:  branch.GetPath(),
+			HeadHash:    headHash,
+			Description: desc,
+		}, nil
+	}
+	return nil, fmt.Errorf("failed to add the stash entry: %w", datas.ErrUnexpectedHead)
+}
+
+// Apply merges the changes recorded in stash entry |s| into the working root of |ws|, using the commit the changes
+// were stashed on top of as the ancestor. Conflicts and constraint violations are left in the working root, where they
+// can be resolved as for any other merge, and true is returned. The staged root is left unchanged. The caller is
+// responsible for persisting the returned working set.
+func Apply(ctx context.Context, ddb *doltdb.DoltDB, ws *doltdb.WorkingSet, s *Stash, opts editor.Options) (*doltdb.WorkingSet, bool, error) {
+	if ws.MergeActive() {
+		return nil, false, ErrMergeInProgress
+	}
+	workingRoot := ws.WorkingRoot()
+	if err := checkConflicts(ctx, workingRoot); err != nil {
+		return nil, false, err
+	}
+
+	stashRoot, err := s.Commit.GetRootValue(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	base, err := ddb.ResolveParent(ctx, s.Commit, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	baseRoot, err := base.GetRootValue(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged, _, err := merge.MergeRoots(ctx, workingRoot, stashRoot, baseRoot, s.Commit, base, opts, merge.MergeOpts{})
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasConflicts, err := merged.HasConflicts(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	hasViolations, err := merged.HasConstraintViolations(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return ws.WithWorkingRoot(merged), hasConflicts || hasViolations, nil
+}
+
+// Pop applies stash entry |s| to the working root of |ws| like Apply, and writes the working set with |write|. Unless
+// applying the entry results in conflicts, the entry is removed from the stash list in the same write. Returns whether
+// there are conflicts.
+func Pop(ctx context.Context, ddb *doltdb.DoltDB, ws *doltdb.WorkingSet, s *Stash, opts editor.Options, write WriteFunc) (bool, error) {
+	ws, hasConflicts, err := Apply(ctx, ddb, ws, s, opts)
+	if err != nil {
+		return false, err
+	}
+	if hasConflicts {
+		return true, write(ws, nil, nil)
+	}
+
+	addr, err := s.Commit.HashOf()
+	if err != nil {
+		return false, err
+	}
+	err = write(ws, map[ref.DoltRef]*doltdb.Commit{s.Ref: nil}, map[ref.DoltRef]hash.Hash{s.Ref: addr})
+	if errors.Is(err, datas.ErrUnexpectedHead) {
+		return false, fmt.Errorf("%s was dropped while it was applied", s.Name())
+	}
+	return false, err
+}
+
+// Drop removes stash entry |s| from the stash list.
+func Drop(ctx context.Context, ddb *doltdb.DoltDB, s *Stash) error {
+	return ddb.DeleteStash(ctx, s.Ref)
+}
+
+// Clear removes all entries from the stash list.
+func Clear(ctx context.Context, ddb *doltdb.DoltDB) error {
+	refs, err := ddb.GetStashes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		err = ddb.DeleteStash(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func commitRoot(ctx context.Context, ddb *doltdb.DoltDB, root *doltdb.RootValue, parents []*doltdb.Commit, name, email, desc string) (*doltdb.Commit, error) {
+	_, valHash, err := ddb.WriteRootValue(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := datas.NewCommitMeta(name, email, desc)
+	if err != nil {
+		return nil, err
+	}
+	return ddb.CommitDanglingWithParentCommits(ctx, valHash, parents, meta)
+}
+
+func checkConflicts(ctx context.Context, root *doltdb.RootValue) error {
+	if ok, err := root.HasConflicts(ctx); err != nil {
+		return err
+	} else if ok {
+		return ErrUnresolvedConflicts
+	}
+	if ok, err := root.HasConstraintViolations(ctx); err != nil {
+		return err
+	} else if ok {
+		return ErrUnresolvedConflicts
+	}
+	return nil
+}
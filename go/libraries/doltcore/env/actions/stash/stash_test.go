@@ -0,0 +1,197 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stash_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	cmd "github.com/dolthub/dolt/go/cmd/dolt/commands"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/stash"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var branch = ref.NewBranchRef(env.DefaultInitBranch)
+
+func setupStashTests(t *testing.T) *env.DoltEnv {
+	dEnv := dtestutils.CreateTestEnv()
+	sqlQuery(t, dEnv, "CREATE TABLE t (pk INT PRIMARY KEY, c INT); INSERT INTO t VALUES (1, 1), (2, 2);")
+	exec(t, dEnv, cmd.AddCmd{}, ".")
+	exec(t, dEnv, cmd.CommitCmd{}, "-m", "created t")
+	return dEnv
+}
+
+func exec(t *testing.T, dEnv *env.DoltEnv, c cli.Command, args ...string) {
+	exitCode := c.Exec(context.Background(), c.Name(), args, dEnv)
+	require.Equal(t, 0, exitCode)
+}
+
+func sqlQuery(t *testing.T, dEnv *env.DoltEnv, query string) {
+	exec(t, dEnv, cmd.SqlCmd{}, "-q", query)
+}
+
+// workingRows returns the rows of t in the working root.
+func workingRows(t *testing.T, dEnv *env.DoltEnv) string {
+	root, err := dEnv.WorkingRoot(context.Background())
+	require.NoError(t, err)
+	rows, err := sqle.ExecuteSelect(t, dEnv, dEnv.DoltDB, root, "SELECT pk, c FROM t ORDER BY pk")
+	require.NoError(t, err)
+	return fmt.Sprint(rows)
+}
+
+func writer(dEnv *env.DoltEnv) stash.WriteFunc {
+	return func(ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error {
+		return dEnv.UpdateWorkingSetAndHeads(context.Background(), ws, heads, prevHeads)
+	}
+}
+
+func push(t *testing.T, dEnv *env.DoltEnv, msg string, write stash.WriteFunc) *stash.Stash {
+	ctx := context.Background()
+	ws, err := dEnv.WorkingSet(ctx)
+	require.NoError(t, err)
+	s, err := stash.Push(ctx, dEnv.DoltDB, branch, ws, "name", "name@example.com", msg, write)
+	require.NoError(t, err)
+	return s
+}
+
+func pop(t *testing.T, dEnv *env.DoltEnv, name string) (bool, error) {
+	ctx := context.Background()
+	s, err := stash.Get(ctx, dEnv.DoltDB, name)
+	require.NoError(t, err)
+	ws, err := dEnv.WorkingSet(ctx)
+	require.NoError(t, err)
+	return stash.Pop(ctx, dEnv.DoltDB, ws, s, editorOpts(dEnv), writer(dEnv))
+}
+
+func editorOpts(dEnv *env.DoltEnv) editor.Options {
+	return editor.Options{Deaf: dEnv.DbEaFactory(), Tempdir: dEnv.TempTableFilesDir()}
+}
+
+func descriptions(t *testing.T, dEnv *env.DoltEnv) []string {
+	stashes, err := stash.List(context.Background(), dEnv.DoltDB)
+	require.NoError(t, err)
+	var descs []string
+	for _, s := range stashes {
+		descs = append(descs, s.Name()+" "+s.Description)
+	}
+	return descs
+}
+
+func TestStashOrder(t *testing.T) {
+	ctx := context.Background()
+	dEnv := setupStashTests(t)
+	committed := workingRows(t, dEnv)
+
+	sqlQuery(t, dEnv, "UPDATE t SET c = 10 WHERE pk = 1;")
+	push(t, dEnv, "first", writer(dEnv))
+	assert.Equal(t, committed, workingRows(t, dEnv))
+	sqlQuery(t, dEnv, "UPDATE t SET c = 20 WHERE pk = 2;")
+	push(t, dEnv, "second", writer(dEnv))
+	assert.Equal(t, []string{"stash@{0} On main: second", "stash@{1} On main: first"}, descriptions(t, dEnv))
+
+	// pop applies and drops the most recent entry
+	hasConflicts, err := pop(t, dEnv, "")
+	require.NoError(t, err)
+	assert.False(t, hasConflicts)
+	assert.Equal(t, "[[1 1] [2 20]]", workingRows(t, dEnv))
+	assert.Equal(t, []string{"stash@{0} On main: first"}, descriptions(t, dEnv))
+
+	// apply keeps the entry
+	s, err := stash.Get(ctx, dEnv.DoltDB, "stash@{0}")
+	require.NoError(t, err)
+	ws, err := dEnv.WorkingSet(ctx)
+	require.NoError(t, err)
+	ws, hasConflicts, err = stash.Apply(ctx, dEnv.DoltDB, ws, s, editorOpts(dEnv))
+	require.NoError(t, err)
+	assert.False(t, hasConflicts)
+	require.NoError(t, dEnv.UpdateWorkingSet(ctx, ws))
+	assert.Equal(t, "[[1 10] [2 20]]", workingRows(t, dEnv))
+	assert.Equal(t, []string{"stash@{0} On main: first"}, descriptions(t, dEnv))
+
+	require.NoError(t, stash.Drop(ctx, dEnv.DoltDB, s))
+	assert.Empty(t, descriptions(t, dEnv))
+	_, err = stash.Get(ctx, dEnv.DoltDB, "")
+	assert.Equal(t, stash.ErrNoStashEntries, err)
+	assert.Equal(t, "[[1 10] [2 20]]", workingRows(t, dEnv))
+}
+
+func TestStashPopConflicts(t *testing.T) {
+	dEnv := setupStashTests(t)
+	sqlQuery(t, dEnv, "UPDATE t SET c = 10 WHERE pk = 1;")
+	push(t, dEnv, "", writer(dEnv))
+	sqlQuery(t, dEnv, "UPDATE t SET c = 100 WHERE pk = 1;")
+
+	// the entry is kept when it conflicts with the working set
+	hasConflicts, err := pop(t, dEnv, "")
+	require.NoError(t, err)
+	assert.True(t, hasConflicts)
+	assert.Len(t, descriptions(t, dEnv), 1)
+	root, err := dEnv.WorkingRoot(context.Background())
+	require.NoError(t, err)
+	ok, err := root.HasConflicts(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// and no entry can be pushed or popped until the conflicts are resolved
+	ws, err := dEnv.WorkingSet(context.Background())
+	require.NoError(t, err)
+	_, err = stash.Push(context.Background(), dEnv.DoltDB, branch, ws, "name", "name@example.com", "", writer(dEnv))
+	assert.Equal(t, stash.ErrUnresolvedConflicts, err)
+	_, err = pop(t, dEnv, "")
+	assert.Equal(t, stash.ErrUnresolvedConflicts, err)
+}
+
+func TestStashConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	dEnv := setupStashTests(t)
+
+	// another push takes the ref of the entry before it's written, and the entry is added after it
+	sqlQuery(t, dEnv, "UPDATE t SET c = 10 WHERE pk = 1;")
+	raced := false
+	racer := func(ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error {
+		if !raced {
+			raced = true
+			for r, cm := range heads {
+				require.NoError(t, dEnv.DoltDB.SetHeadToCommit(ctx, r, cm))
+			}
+		}
+		return writer(dEnv)(ws, heads, prevHeads)
+	}
+	s := push(t, dEnv, "mine", racer)
+	assert.Equal(t, ref.NewStashRef("2"), s.Ref)
+	assert.Equal(t, []string{"stash@{0} On main: mine", "stash@{1} On main: mine"}, descriptions(t, dEnv))
+
+	// an entry dropped while it's popped isn't applied
+	s, err := stash.Get(ctx, dEnv.DoltDB, "")
+	require.NoError(t, err)
+	ws, err := dEnv.WorkingSet(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stash.Drop(ctx, dEnv.DoltDB, s))
+	_, err = stash.Pop(ctx, dEnv.DoltDB, ws, s, editorOpts(dEnv), writer(dEnv))
+	assert.Error(t, err)
+	assert.Equal(t, "[[1 1] [2 2]]", workingRows(t, dEnv))
+	assert.Len(t, descriptions(t, dEnv), 1)
+}
@@ -74,6 +74,7 @@ var ErrInvalidBackupName = errors.New("backup name invalid")
 var ErrFailedToDeleteBackup = errors.New("failed to delete backup")
 var ErrFailedToReadFromDb = errors.New("failed to read from db")
 var ErrFailedToDeleteRemote = errors.New("failed to delete remote")
+var ErrCannotRemoveLazyRemote = errors.New("cannot remove the remote a lazy clone fetches its data from")
 var ErrFailedToWriteRepoState = errors.New("failed to write repo state")
 var ErrRemoteAddressConflict = errors.New("address conflict with a remote")
 
@@ -164,6 +165,11 @@ func loadWithFormat(ctx context.Context, hdp HomeDirProvider, fs filesys.Filesys
 		}
 	}
 
+	if rsErr == nil && dbLoadErr == nil && ddb.IsLazy() {
+		dbLoadErr = dEnv.setLazySource()
+		dEnv.DBLoadError = dbLoadErr
+	}
+
 	var dbFormatErr error
 	if rsErr == nil && dbLoadErr == nil {
 		if binFormat != nil && dEnv.DoltDB.Format() != binFormat {
@@ -618,6 +624,17 @@ func (dEnv *DoltEnv) UpdateWorkingSet(ctx context.Context, ws *doltdb.WorkingSet
 	return dEnv.DoltDB.UpdateWorkingSet(ctx, ws.Ref(), ws, h, dEnv.workingSetMeta())
 }
 
+// UpdateWorkingSetAndHeads updates the working set and sets the heads given, deleting those with a nil commit, in one
+// atomic write. The heads in |prevHeads| must still be at the hashes given, see DoltDB.SetHeadsWithWorkingSet.
+func (dEnv *DoltEnv) UpdateWorkingSetAndHeads(ctx context.Context, ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error {
+	h, err := ws.HashOf()
+	if err != nil {
+		return err
+	}
+
+	return dEnv.DoltDB.SetHeadsWithWorkingSet(ctx, heads, prevHeads, ws.Ref(), ws, h, dEnv.workingSetMeta())
+}
+
 type repoStateReader struct {
 	*DoltEnv
 }
@@ -769,7 +786,7 @@ func (dEnv *DoltEnv) ClearMerge(ctx context.Context) error {
 	return dEnv.DoltDB.UpdateWorkingSet(ctx, ws.Ref(), ws.ClearMerge(), h, dEnv.workingSetMeta())
 }
 
-func (dEnv *DoltEnv) StartMerge(ctx context.Context, commit *doltdb.Commit) error {
+func (dEnv *DoltEnv) StartMerge(ctx context.Context, commit *doltdb.Commit, commitSpecStr string) error {
 	ws, err := dEnv.WorkingSet(ctx)
 	if err != nil {
 		return err
@@ -780,7 +797,7 @@ func (dEnv *DoltEnv) StartMerge(ctx context.Context, commit *doltdb.Commit) erro
 		return err
 	}
 
-	return dEnv.DoltDB.UpdateWorkingSet(ctx, ws.Ref(), ws.StartMerge(commit), h, dEnv.workingSetMeta())
+	return dEnv.DoltDB.UpdateWorkingSet(ctx, ws.Ref(), ws.StartMerge(commit, commitSpecStr), h, dEnv.workingSetMeta())
 }
 
 func (dEnv *DoltEnv) IsMergeActive(ctx context.Context) (bool, error) {
@@ -829,6 +846,21 @@ func (dEnv *DoltEnv) GetGRPCDialParams(config grpcendpoint.Config) (string, []gr
 	return NewGRPCDialProviderFromDoltEnv(dEnv).GetGRPCDialParams(config)
 }
 
+// setLazySource makes the database of this lazy clone fetch the chunks it doesn't have from its lazy remote. The
+// remote is only opened once a chunk is missing.
+func (dEnv *DoltEnv) setLazySource() error {
+	name := dEnv.DoltDB.LazyRemote()
+	r, ok := dEnv.RepoState.Remotes[name]
+	if !ok {
+		return fmt.Errorf("%w: '%s' is the remote of this lazy clone", ErrRemoteNotFound, name)
+	}
+
+	nbf := dEnv.DoltDB.Format()
+	return dEnv.DoltDB.SetLazySource(func(ctx context.Context) (*doltdb.DoltDB, error) {
+		return r.GetRemoteDB(ctx, nbf, dEnv)
+	})
+}
+
 func (dEnv *DoltEnv) GetRemotes() (map[string]Remote, error) {
 	if dEnv.RSLoadErr != nil {
 		return nil, dEnv.RSLoadErr
@@ -915,6 +947,10 @@ func (dEnv *DoltEnv) RemoveRemote(ctx context.Context, name string) error {
 		return ErrRemoteNotFound
 	}
 
+	if dEnv.DoltDB.LazyRemote() == remote.Name {
+		return ErrCannotRemoveLazyRemote
+	}
+
 	ddb := dEnv.DoltDB
 	refs, err := ddb.GetRemoteRefs(ctx)
 	if err != nil {
@@ -16,6 +16,7 @@ package env
 
 import (
 	"crypto/tls"
+	"os"
 	"runtime"
 	"strings"
 	"unicode"
@@ -23,10 +24,18 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/dolthub/dolt/go/libraries/doltcore/creds"
 	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
 	"github.com/dolthub/dolt/go/libraries/doltcore/grpcendpoint"
 )
 
+const (
+	// RemoteUserEnvVar and RemotePasswordEnvVar hold the SQL user name and password used to authenticate with remotes
+	// served by dolt sql-server. When a user is set they are used instead of the user's dolt credentials.
+	RemoteUserEnvVar     = "DOLT_REMOTE_USER"
+	RemotePasswordEnvVar = "DOLT_REMOTE_PASSWORD"
+)
+
 // GRPCDialProvider implements dbfactory.GRPCDialProvider. By default, it is not able to use custom user credentials, but
 // if it is initialized with a DoltEnv, it will load custom user credentials from it.
 type GRPCDialProvider struct {
@@ -86,9 +95,14 @@ func (p GRPCDialProvider) GetGRPCDialParams(config grpcendpoint.Config) (string,
 	return endpoint, opts, nil
 }
 
-// getRPCCreds returns any RPC credentials available to this dial provider. If a DoltEnv has been configured
-// in this dial provider, it will be used to load custom user credentials, otherwise nil will be returned.
+// getRPCCreds returns any RPC credentials available to this dial provider. If a remote user is set in the
+// environment, basic credentials for it are returned. Otherwise, if a DoltEnv has been configured in this dial
+// provider, it will be used to load custom user credentials, otherwise nil will be returned.
 func (p GRPCDialProvider) getRPCCreds() (credentials.PerRPCCredentials, error) {
+	if user := os.Getenv(RemoteUserEnvVar); user != "" {
+		return creds.BasicCreds{User: user, Password: os.Getenv(RemotePasswordEnvVar)}, nil
+	}
+
 	if p.dEnv == nil {
 		return nil, nil
 	}
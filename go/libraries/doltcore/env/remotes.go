@@ -432,6 +432,14 @@ func GetAbsRemoteUrl(fs filesys2.Filesys, cfg config.ReadableConfig, urlArg stri
 				return "", "", err
 			}
 
+			return u.Scheme, absUrl, err
+		} else if u.Scheme == dbfactory.BundleScheme {
+			absUrl, err := getAbsBundleUrl(u.Host+u.Path, fs)
+
+			if err != nil {
+				return "", "", err
+			}
+
 			return u.Scheme, absUrl, err
 		}
 
@@ -482,6 +490,31 @@ func getAbsFileRemoteUrl(urlStr string, scheme string, fs filesys2.Filesys) (str
 	return scheme + "://" + urlStr, nil
 }
 
+// getAbsBundleUrl returns the bundle:// url of the bundle file at |urlStr|, which must exist.
+func getAbsBundleUrl(urlStr string, fs filesys2.Filesys) (string, error) {
+	var err error
+	urlStr = filepath.Clean(urlStr)
+	urlStr, err = fs.Abs(urlStr)
+
+	if err != nil {
+		return "", err
+	}
+
+	exists, isDir := fs.Exists(urlStr)
+
+	if !exists {
+		return "", fmt.Errorf("bundle file '%s' does not exist", urlStr)
+	} else if isDir {
+		return "", fmt.Errorf("'%s' is a directory, not a bundle file", urlStr)
+	}
+
+	urlStr = strings.ReplaceAll(urlStr, `\`, "/")
+	if !strings.HasPrefix(urlStr, "/") {
+		urlStr = "/" + urlStr
+	}
+	return dbfactory.BundleScheme + "://" + urlStr, nil
+}
+
 // GetDefaultBranch returns the default branch from among the branches given, returning
 // the configs default config branch first, then init branch main, then the old init branch master,
 // and finally the first lexicographical branch if none of the others are found
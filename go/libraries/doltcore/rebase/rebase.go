@@ -61,6 +61,11 @@ func StopAtCommit(stopCommit *doltdb.Commit) NeedsRebaseFn {
 	}
 }
 
+// SquashCommitFn returns whether |cm| is squashed into its children. A squashed commit is dropped from the rebased
+// history, and its children are rebased onto its rebased parent instead. Only commits with a single parent can be
+// squashed.
+type SquashCommitFn func(ctx context.Context, cm *doltdb.Commit) (bool, error)
+
 type ReplayRootFn func(ctx context.Context, root, parentRoot, rebasedParentRoot *doltdb.RootValue) (rebaseRoot *doltdb.RootValue, err error)
 
 type ReplayCommitFn func(ctx context.Context, commit, parent, rebasedParent *doltdb.Commit) (rebaseRoot *doltdb.RootValue, err error)
@@ -135,7 +140,7 @@ func rebaseRefs(ctx context.Context, dbData env.DbData, replay ReplayCommitFn, n
 		}
 	}
 
-	newHeads, err := rebase(ctx, ddb, replay, nerf, heads...)
+	newHeads, err := rebase(ctx, ddb, replay, nerf, nil, heads...)
 	if err != nil {
 		return err
 	}
@@ -177,11 +182,13 @@ func rebaseRefs(ctx context.Context, dbData env.DbData, replay ReplayCommitFn, n
 	return rsw.UpdateWorkingRoot(ctx, r)
 }
 
-func rebase(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommitFn, nerf NeedsRebaseFn, origins ...*doltdb.Commit) ([]*doltdb.Commit, error) {
+// rebase rewrites the history of |origins| using the |replay| function, and returns the rebased commit of each origin.
+// If |squash| is not nil, the commits it selects are dropped from the rebased history.
+func rebase(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommitFn, nerf NeedsRebaseFn, squash SquashCommitFn, origins ...*doltdb.Commit) ([]*doltdb.Commit, error) {
 	var rebasedCommits []*doltdb.Commit
 	vs := make(visitedSet)
 	for _, cm := range origins {
-		rc, err := rebaseRecursive(ctx, ddb, replay, nerf, vs, cm)
+		rc, err := rebaseRecursive(ctx, ddb, replay, nerf, squash, vs, cm)
 
 		if err != nil {
 			return nil, err
@@ -193,7 +200,7 @@ func rebase(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommitFn, nerf
 	return rebasedCommits, nil
 }
 
-func rebaseRecursive(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommitFn, nerf NeedsRebaseFn, vs visitedSet, commit *doltdb.Commit) (*doltdb.Commit, error) {
+func rebaseRecursive(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommitFn, nerf NeedsRebaseFn, squash SquashCommitFn, vs visitedSet, commit *doltdb.Commit) (*doltdb.Commit, error) {
 	commitHash, err := commit.HashOf()
 	if err != nil {
 		return nil, err
@@ -224,7 +231,7 @@ func rebaseRecursive(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommi
 
 	var allRebasedParents []*doltdb.Commit
 	for _, p := range allParents {
-		rp, err := rebaseRecursive(ctx, ddb, replay, nerf, vs, p)
+		rp, err := rebaseRecursive(ctx, ddb, replay, nerf, squash, vs, p)
 
 		if err != nil {
 			return nil, err
@@ -233,6 +240,18 @@ func rebaseRecursive(ctx context.Context, ddb *doltdb.DoltDB, replay ReplayCommi
 		allRebasedParents = append(allRebasedParents, rp)
 	}
 
+	if squash != nil && len(allParents) == 1 {
+		squashed, err := squash(ctx, commit)
+		if err != nil {
+			return nil, err
+		}
+		if squashed {
+			// the squashed commit is replaced by its rebased parent in the history of its children
+			vs[commitHash] = allRebasedParents[0]
+			return allRebasedParents[0], nil
+		}
+	}
+
 	rebasedRoot, err := replay(ctx, commit, allParents[0], allRebasedParents[0])
 	if err != nil {
 		return nil, err
@@ -0,0 +1,291 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// ErrHistoryChanged is returned by SquashHistory when a branch or tag was moved while its history was squashed.
+var ErrHistoryChanged = errors.New("cannot squash history: a branch or tag was updated while it was squashed")
+
+// SquashPeriod is the length of time whose commits are squashed into a single commit.
+type SquashPeriod int
+
+const (
+	SquashByDay SquashPeriod = iota
+	SquashByWeek
+)
+
+// ParseSquashPeriod returns the SquashPeriod named |s|, either "day" or "week".
+func ParseSquashPeriod(s string) (SquashPeriod, error) {
+	switch s {
+	case "day":
+		return SquashByDay, nil
+	case "week":
+		return SquashByWeek, nil
+	default:
+		return 0, fmt.Errorf("invalid squash period '%s', expected 'day' or 'week'", s)
+	}
+}
+
+// of returns the period |t| is in. Periods are in UTC, weeks are ISO weeks.
+func (p SquashPeriod) of(t time.Time) string {
+	t = t.UTC()
+	if p == SquashByWeek {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01-02")
+}
+
+// SquashOpts selects the commits squashed by SquashHistory.
+type SquashOpts struct {
+	// Before is the time before which commits are squashed. Commits made at or after it are left alone. The zero
+	// time squashes commits of any age.
+	Before time.Time
+	// KeepRecent is the number of most recent commits of each branch which are left alone.
+	KeepRecent int
+	// Period is the length of time whose commits are squashed into one.
+	Period SquashPeriod
+}
+
+// SquashCurrentBranch squashes the history of the current branch, see SquashHistory.
+func SquashCurrentBranch(ctx context.Context, dEnv *env.DoltEnv, opts SquashOpts) (int, error) {
+	return SquashHistory(ctx, dEnv.DoltDB, opts, dEnv.RepoStateReader().CWBHeadRef())
+}
+
+// SquashAllBranches squashes the history of all branches, see SquashHistory.
+func SquashAllBranches(ctx context.Context, dEnv *env.DoltEnv, opts SquashOpts) (int, error) {
+	branches, err := dEnv.DoltDB.GetBranches(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return SquashHistory(ctx, dEnv.DoltDB, opts, branches...)
+}
+
+// SquashHistory rewrites the history of |branches| so that the commits selected by |opts| are squashed into the last
+// commit of the period they were made in. The values of the commits kept are unchanged, only their parents are
+// rewritten. Merge commits, the commits they merge, the commits branches fork from, root commits and the commits of
+// tags are kept, and the tags are moved to the rewritten commits. The working sets of the branches are not changed.
+// The branches and tags are moved in one atomic update, which fails with ErrHistoryChanged if any of them was moved
+// after their history was read. Returns the number of commits dropped from the history.
+func SquashHistory(ctx context.Context, ddb *doltdb.DoltDB, opts SquashOpts, branches ...ref.DoltRef) (int, error) {
+	for _, b := range branches {
+		if _, ok := b.(ref.BranchRef); !ok {
+			return 0, fmt.Errorf("cannot squash the history of ref: %s", ref.String(b))
+		}
+	}
+
+	h, err := newSquashHistory(ctx, ddb, branches)
+	if err != nil {
+		return 0, err
+	}
+
+	tags, err := ddb.GetTagsWithHashes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	kept, err := h.keptCommits(ctx, opts, tags)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := len(h.commits) - len(kept)
+	if dropped == 0 {
+		return 0, nil
+	}
+
+	// the tagged commits are rebased along with the branches, so that the tags can be moved to the rebased commits
+	origins := make([]*doltdb.Commit, 0, len(h.heads)+len(tags))
+	for _, addr := range h.heads {
+		origins = append(origins, h.commits[addr])
+	}
+	var movedTags []doltdb.TagWithHash
+	for _, t := range tags {
+		if cm, ok := h.commits[t.Hash]; ok {
+			origins = append(origins, cm)
+			movedTags = append(movedTags, t)
+		}
+	}
+
+	keepRoot := func(ctx context.Context, commit, parent, rebasedParent *doltdb.Commit) (*doltdb.RootValue, error) {
+		return commit.GetRootValue(ctx)
+	}
+	squash := func(ctx context.Context, cm *doltdb.Commit) (bool, error) {
+		addr, err := cm.HashOf()
+		if err != nil {
+			return false, err
+		}
+		return !kept.Has(addr), nil
+	}
+
+	rebased, err := rebase(ctx, ddb, keepRoot, EntireHistory(), squash, origins...)
+	if err != nil {
+		return 0, err
+	}
+
+	// the branches and tags are moved together, as long as none of them moved since their history was read
+	refs := make(map[ref.DoltRef]*doltdb.Commit, len(branches)+len(movedTags))
+	prevRefs := make(map[ref.DoltRef]hash.Hash, len(branches)+len(movedTags))
+	for i, b := range branches {
+		refs[b] = rebased[i]
+		prevRefs[b] = h.heads[i]
+	}
+	for i, t := range movedTags {
+		cm := rebased[len(branches)+i]
+		addr, err := cm.HashOf()
+		if err != nil {
+			return 0, err
+		}
+		if addr == t.Hash {
+			continue
+		}
+		refs[t.Tag.GetDoltRef()] = cm
+		prevRefs[t.Tag.GetDoltRef()] = t.Hash
+	}
+
+	err = ddb.MoveRefs(ctx, refs, prevRefs)
+	if errors.Is(err, datas.ErrUnexpectedHead) {
+		return 0, ErrHistoryChanged
+	} else if err != nil {
+		return 0, err
+	}
+
+	return dropped, nil
+}
+
+// squashHistory is the commit graph of the branches being squashed.
+type squashHistory struct {
+	heads    []hash.Hash
+	commits  map[hash.Hash]*doltdb.Commit
+	parents  map[hash.Hash][]hash.Hash
+	children map[hash.Hash][]hash.Hash
+	// depth is the distance of each commit from the closest head.
+	depth map[hash.Hash]int
+}
+
+func newSquashHistory(ctx context.Context, ddb *doltdb.DoltDB, branches []ref.DoltRef) (*squashHistory, error) {
+	h := &squashHistory{
+		commits:  make(map[hash.Hash]*doltdb.Commit),
+		parents:  make(map[hash.Hash][]hash.Hash),
+		children: make(map[hash.Hash][]hash.Hash),
+		depth:    make(map[hash.Hash]int),
+	}
+
+	var queue []*doltdb.Commit
+	for _, b := range branches {
+		cm, err := ddb.ResolveCommitRef(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+		h.heads = append(h.heads, addr)
+		if _, ok := h.commits[addr]; !ok {
+			h.commits[addr] = cm
+			h.depth[addr] = 0
+			queue = append(queue, cm)
+		}
+	}
+
+	// breadth first, so that the first path reaching a commit is the shortest
+	for len(queue) > 0 {
+		cm := queue[0]
+		queue = queue[1:]
+
+		addr, err := cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+
+		parents, err := ddb.ResolveAllParents(ctx, cm)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range parents {
+			pAddr, err := p.HashOf()
+			if err != nil {
+				return nil, err
+			}
+			h.parents[addr] = append(h.parents[addr], pAddr)
+			h.children[pAddr] = append(h.children[pAddr], addr)
+			if _, ok := h.commits[pAddr]; !ok {
+				h.commits[pAddr] = p
+				h.depth[pAddr] = h.depth[addr] + 1
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// keptCommits returns the commits which aren't squashed into their children.
+func (h *squashHistory) keptCommits(ctx context.Context, opts SquashOpts, tags []doltdb.TagWithHash) (hash.HashSet, error) {
+	kept := hash.NewHashSet(h.heads...)
+	for _, t := range tags {
+		if _, ok := h.commits[t.Hash]; ok {
+			kept.Insert(t.Hash)
+		}
+	}
+
+	periods := make(map[hash.Hash]string, len(h.commits))
+	recent := hash.NewHashSet()
+	for addr, cm := range h.commits {
+		meta, err := cm.GetCommitMeta(ctx)
+		if err != nil {
+			return nil, err
+		}
+		periods[addr] = opts.Period.of(meta.Time())
+
+		if h.depth[addr] < opts.KeepRecent || (!opts.Before.IsZero() && !meta.Time().Before(opts.Before)) {
+			recent.Insert(addr)
+		}
+	}
+
+	for addr := range h.commits {
+		children := h.children[addr]
+		switch {
+		case recent.Has(addr):
+		case len(h.parents[addr]) != 1:
+			// root commits and merge commits
+		case len(children) != 1:
+			// commits branches fork from
+		case len(h.parents[children[0]]) > 1:
+			// commits merged by their child
+		case recent.Has(children[0]) || periods[children[0]] != periods[addr]:
+			// last commit of its period
+		default:
+			continue
+		}
+		kept.Insert(addr)
+	}
+
+	return kept, nil
+}
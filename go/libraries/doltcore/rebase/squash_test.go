@@ -0,0 +1,122 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebase_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmd "github.com/dolthub/dolt/go/cmd/dolt/commands"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/rebase"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+)
+
+func TestSquashHistory(t *testing.T) {
+	ctx := context.Background()
+	dEnv := setupFilterBranchTests(t)
+
+	// three commits on each of three days
+	var setup []testCommand
+	for day := 1; day <= 3; day++ {
+		for hour := 1; hour <= 3; hour++ {
+			setup = append(setup,
+				testCommand{cmd.SqlCmd{}, args{"-q", fmt.Sprintf("INSERT INTO test VALUES (%d%d, 0);", day, hour)}},
+				testCommand{cmd.CommitCmd{}, args{"-am", fmt.Sprintf("day %d hour %d", day, hour), "--date", fmt.Sprintf("2022-01-0%dT0%d:00:00", day, hour)}},
+			)
+		}
+	}
+	setup = append(setup, testCommand{cmd.TagCmd{}, args{"tagged", "HEAD~4"}})
+	for _, c := range setup {
+		exitCode := c.cmd.Exec(ctx, c.cmd.Name(), c.args, dEnv)
+		require.Equal(t, 0, exitCode)
+	}
+
+	rootBefore, err := dEnv.WorkingRoot(ctx)
+	require.NoError(t, err)
+
+	dropped, err := rebase.SquashCurrentBranch(ctx, dEnv, rebase.SquashOpts{
+		Before: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+		Period: rebase.SquashByDay,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, dropped)
+
+	assert.Equal(t, []string{
+		"day 3 hour 3",
+		"day 3 hour 2",
+		"day 3 hour 1",
+		"day 2 hour 3",
+		"day 2 hour 2",
+		"day 1 hour 3",
+		"added test tables",
+		"Initialize data repository",
+	}, commitMessages(t, dEnv, ref.NewBranchRef(env.DefaultInitBranch)))
+
+	// the tagged commit is kept, and the tag follows it
+	tag, err := dEnv.DoltDB.ResolveTag(ctx, ref.NewTagRef("tagged"))
+	require.NoError(t, err)
+	meta, err := tag.Commit.GetCommitMeta(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "day 2 hour 2", meta.Description)
+
+	rootAfter, err := dEnv.WorkingRoot(ctx)
+	require.NoError(t, err)
+	hashBefore, err := rootBefore.HashOf()
+	require.NoError(t, err)
+	hashAfter, err := rootAfter.HashOf()
+	require.NoError(t, err)
+	assert.Equal(t, hashBefore, hashAfter)
+
+	// 2022-01-01 and 2022-01-02 are in the same ISO week, 2022-01-03 starts the next one
+	dropped, err = rebase.SquashCurrentBranch(ctx, dEnv, rebase.SquashOpts{KeepRecent: 2, Period: rebase.SquashByWeek})
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, []string{
+		"day 3 hour 3",
+		"day 3 hour 2",
+		"day 3 hour 1",
+		"day 2 hour 3",
+		"day 2 hour 2",
+		"added test tables",
+		"Initialize data repository",
+	}, commitMessages(t, dEnv, ref.NewBranchRef(env.DefaultInitBranch)))
+}
+
+func commitMessages(t *testing.T, dEnv *env.DoltEnv, branch ref.DoltRef) []string {
+	ctx := context.Background()
+	cm, err := dEnv.DoltDB.ResolveCommitRef(ctx, branch)
+	require.NoError(t, err)
+
+	var messages []string
+	for {
+		meta, err := cm.GetCommitMeta(ctx)
+		require.NoError(t, err)
+		messages = append(messages, meta.Description)
+		if cm.NumParents() == 0 {
+			return messages
+		}
+		var parents []*doltdb.Commit
+		parents, err = dEnv.DoltDB.ResolveAllParents(ctx, cm)
+		require.NoError(t, err)
+		cm = parents[0]
+	}
+}
@@ -51,6 +51,9 @@ const (
 
 	// WorkspaceRefType is a reference to a workspace
 	WorkspaceRefType RefType = "workspaces"
+
+	// StashRefType is a reference to a stash entry. Stash entries point to Commits, but they are not HEADs.
+	StashRefType RefType = "stashes"
 )
 
 // HeadRefTypes are the ref types that point to a HEAD and contain a Commit struct. These are the types that are
@@ -161,5 +164,9 @@ func Parse(str string) (DoltRef, error) {
 		}
 	}
 
+	if prefix := PrefixForType(StashRefType); strings.HasPrefix(str, prefix) {
+		return NewStashRef(str[len(prefix):]), nil
+	}
+
 	return nil, ErrUnknownRefType
 }
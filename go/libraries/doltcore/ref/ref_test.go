@@ -46,6 +46,10 @@ func TestJsonMarshalAndUnmarshal(t *testing.T) {
 			NewWorkspaceRef("newworkspace"),
 			`{"test":"refs/workspaces/newworkspace"}`,
 		},
+		{
+			NewStashRef("1"),
+			`{"test":"refs/stashes/1"}`,
+		},
 	}
 
 	for _, test := range tests {
@@ -160,6 +164,16 @@ func TestEqualsStr(t *testing.T) {
 			"refs/remotes/origin/newworkspace",
 			false,
 		},
+		{
+			NewStashRef("refs/stashes/1"),
+			"refs/stashes/1",
+			true,
+		},
+		{
+			NewStashRef("1"),
+			"refs/stashes/2",
+			false,
+		},
 	}
 
 	for _, test := range tests {
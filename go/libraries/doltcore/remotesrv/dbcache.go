@@ -0,0 +1,54 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"context"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
+)
+
+// RemoteSrvStore is a chunk store which can be served by the remotesapi server. Its table files have to be on local
+// disk so that they can be served over http.
+type RemoteSrvStore interface {
+	chunks.ChunkStore
+	nbs.TableFileStore
+
+	// GetChunkLocations returns the table files and the ranges within them of the chunks of |hashes|, keyed by table
+	// file. Found hashes are removed from |hashes|.
+	GetChunkLocations(hashes hash.HashSet) (map[hash.Hash]map[hash.Hash]nbs.Range, error)
+
+	// TableFilePath returns the path on disk of the table file |fileId|, or false if it has none.
+	TableFilePath(fileId string) (string, bool, error)
+}
+
+var _ RemoteSrvStore = &nbs.NomsBlockStore{}
+var _ RemoteSrvStore = &nbs.GenerationalNBS{}
+
+// DBCache provides the stores served by the remotesapi server.
+type DBCache interface {
+	// Get returns the store of the repository |org|/|repo|. |org| is empty for repositories which are named by their
+	// name alone. |nbfVerStr| is the format of the client, which is used if the store is created.
+	Get(ctx context.Context, org, repo, nbfVerStr string) (RemoteSrvStore, error)
+}
+
+// Authorizer authorizes the requests made to the remotesapi server.
+type Authorizer interface {
+	// Authorize returns nil if the caller of the rpc whose context is |ctx| may read the repository |org|/|repo|, or
+	// write to it if |write| is true. Otherwise it returns a grpc status error.
+	Authorize(ctx context.Context, org, repo string, write bool) error
+}
@@ -0,0 +1,558 @@
+// Copyright 2019 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	remotesapi "github.com/dolthub/dolt/go/gen/proto/dolt/services/remotesapi/v1alpha1"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotestorage"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+type RemoteChunkStore struct {
+	HttpHost      string
+	httpPort      int
+	csCache       DBCache
+	authorizer    Authorizer
+	signer        urlSigner
+	expectedFiles fileDetails
+	listedFiles   *listedTableFiles
+	lgr           *logrus.Entry
+	remotesapi.UnimplementedChunkStoreServiceServer
+}
+
+func newHttpFSBackedChunkStore(lgr *logrus.Entry, httpHost string, httpPort int, csCache DBCache, authorizer Authorizer, signer urlSigner, expectedFiles fileDetails, listedFiles *listedTableFiles) *RemoteChunkStore {
+	return &RemoteChunkStore{
+		HttpHost:      httpHost,
+		httpPort:      httpPort,
+		csCache:       csCache,
+		authorizer:    authorizer,
+		signer:        signer,
+		expectedFiles: expectedFiles,
+		listedFiles:   listedFiles,
+		lgr: lgr.WithFields(logrus.Fields{
+			"service": "dolt.services.remotesapi.v1alpha1.ChunkStoreServiceServer",
+		}),
+	}
+}
+
+func (rs *RemoteChunkStore) HasChunks(ctx context.Context, req *remotesapi.HasChunksRequest) (*remotesapi.HasChunksResponse, error) {
+	logger := getReqLogger(rs.lgr, "HasChunks")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, hashToIndex := remotestorage.ParseByteSlices(req.Hashes)
+
+	absent, err := cs.HasMany(ctx, hashes)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "HasMany failure:"+err.Error())
+	}
+
+	indices := make([]int32, len(absent))
+
+	n := 0
+	for h := range absent {
+		indices[n] = int32(hashToIndex[h])
+		n++
+	}
+
+	resp := &remotesapi.HasChunksResponse{
+		Absent: indices,
+	}
+
+	return resp, nil
+}
+
+func (rs *RemoteChunkStore) GetDownloadLocations(ctx context.Context, req *remotesapi.GetDownloadLocsRequest) (*remotesapi.GetDownloadLocsResponse, error) {
+	logger := getReqLogger(rs.lgr, "GetDownloadLocations")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := rs.getDownloadLocs(ctx, logger, cs, req.RepoId, req.ChunkHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remotesapi.GetDownloadLocsResponse{Locs: locs}, nil
+}
+
+func (rs *RemoteChunkStore) StreamDownloadLocations(stream remotesapi.ChunkStoreService_StreamDownloadLocationsServer) error {
+	logger := getReqLogger(rs.lgr, "StreamDownloadLocations")
+	defer func() { logger.Trace("finished") }()
+
+	var repoID *remotesapi.RepoId
+	var cs RemoteSrvStore
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if !proto.Equal(req.RepoId, repoID) {
+			repoID = req.RepoId
+			cs, err = rs.getStore(stream.Context(), logger, repoID, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		locs, err := rs.getDownloadLocs(stream.Context(), logger, cs, repoID, req.ChunkHashes)
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(&remotesapi.GetDownloadLocsResponse{Locs: locs}); err != nil {
+			return err
+		}
+	}
+}
+
+func (rs *RemoteChunkStore) getDownloadLocs(ctx context.Context, logger *logrus.Entry, cs RemoteSrvStore, repoId *remotesapi.RepoId, chunkHashes [][]byte) ([]*remotesapi.DownloadLoc, error) {
+	hashes, _ := remotestorage.ParseByteSlices(chunkHashes)
+	locations, err := cs.GetChunkLocations(hashes)
+
+	if err != nil {
+		logger.Errorf("error getting chunk locations: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to get chunk locations: %v", err)
+	}
+
+	var locs []*remotesapi.DownloadLoc
+	for loc, hashToRange := range locations {
+		var ranges []*remotesapi.RangeChunk
+		for h, r := range hashToRange {
+			hCpy := h
+			ranges = append(ranges, &remotesapi.RangeChunk{Hash: hCpy[:], Offset: r.Offset, Length: r.Length})
+		}
+
+		url, refreshAfter, err := rs.getDownloadUrl(ctx, repoId, loc.String())
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Tracef("the URL is %s", url)
+
+		getRange := &remotesapi.HttpGetRange{Url: url, Ranges: ranges}
+		locs = append(locs, &remotesapi.DownloadLoc{
+			Location:       &remotesapi.DownloadLoc_HttpGetRange{HttpGetRange: getRange},
+			RefreshAfter:   refreshAfter,
+			RefreshRequest: &remotesapi.RefreshTableFileUrlRequest{RepoId: repoId, FileId: loc.String()},
+		})
+	}
+
+	return locs, nil
+}
+
+// getDownloadUrl returns a signed url for downloading the table file |fileId| of |repoId|, and the time after which
+// clients should refresh it.
+func (rs *RemoteChunkStore) getDownloadUrl(ctx context.Context, repoId *remotesapi.RepoId, fileId string) (string, *timestamppb.Timestamp, error) {
+	url, expires, err := rs.getSignedUrl(ctx, http.MethodGet, repoId, fileId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return url, timestamppb.New(expires.Add(-urlRefreshWindow)), nil
+}
+
+func (rs *RemoteChunkStore) getSignedUrl(ctx context.Context, method string, repoId *remotesapi.RepoId, fileId string) (string, time.Time, error) {
+	host, err := rs.getHttpHost(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	path := tableFileUrlPath(repoId.Org, repoId.RepoName, fileId)
+	params, expires := rs.signer.sign(method, path)
+
+	u := url.URL{
+		Scheme:   "http",
+		Host:     host,
+		Path:     path,
+		RawQuery: params.Encode(),
+	}
+
+	return u.String(), expires, nil
+}
+
+// getHttpHost returns the host of the http file server. If it is not configured, the host the client used to connect
+// to the grpc server is used, with the port of the http server.
+func (rs *RemoteChunkStore) getHttpHost(ctx context.Context) (string, error) {
+	if rs.HttpHost != "" {
+		return rs.HttpHost, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authority := md.Get(":authority")
+	if len(authority) == 0 {
+		return "", status.Error(codes.Internal, "could not determine the http host")
+	}
+
+	host, _, err := net.SplitHostPort(authority[0])
+	if err != nil {
+		host = authority[0]
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(rs.httpPort)), nil
+}
+
+// tableFileUrlPath returns the path of the table file |fileId| of the repository |org|/|repo| on the http server.
+func tableFileUrlPath(org, repo, fileId string) string {
+	if org == "" {
+		return "/" + repo + "/" + fileId
+	}
+
+	return "/" + org + "/" + repo + "/" + fileId
+}
+
+func parseTableFileDetails(req *remotesapi.GetUploadLocsRequest) []*remotesapi.TableFileDetails {
+	tfd := req.GetTableFileDetails()
+
+	if len(tfd) == 0 {
+		_, hashToIdx := remotestorage.ParseByteSlices(req.TableFileHashes)
+
+		tfd = make([]*remotesapi.TableFileDetails, len(hashToIdx))
+		for h, i := range hashToIdx {
+			tfd[i] = &remotesapi.TableFileDetails{
+				Id:            h[:],
+				ContentLength: 0,
+				ContentHash:   nil,
+			}
+		}
+	}
+
+	return tfd
+}
+
+func (rs *RemoteChunkStore) GetUploadLocations(ctx context.Context, req *remotesapi.GetUploadLocsRequest) (*remotesapi.GetUploadLocsResponse, error) {
+	logger := getReqLogger(rs.lgr, "GetUploadLocations")
+	defer func() { logger.Trace("finished") }()
+
+	_, err := rs.getStore(ctx, logger, req.RepoId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tfds := parseTableFileDetails(req)
+
+	var locs []*remotesapi.UploadLoc
+	for _, tfd := range tfds {
+		h := hash.New(tfd.Id)
+		url, err := rs.getUploadUrl(ctx, req.RepoId, tfd)
+
+		if err != nil {
+			return nil, err
+		}
+
+		loc := &remotesapi.UploadLoc_HttpPost{HttpPost: &remotesapi.HttpPostTableFile{Url: url}}
+		locs = append(locs, &remotesapi.UploadLoc{TableFileHash: h[:], Location: loc})
+
+		logger.Tracef("sending upload location for chunk %s: %s", h.String(), url)
+	}
+
+	return &remotesapi.GetUploadLocsResponse{Locs: locs}, nil
+}
+
+func (rs *RemoteChunkStore) getUploadUrl(ctx context.Context, repoId *remotesapi.RepoId, tfd *remotesapi.TableFileDetails) (string, error) {
+	fileID := hash.New(tfd.Id).String()
+	rs.expectedFiles.Put(tableFileUrlPath(repoId.Org, repoId.RepoName, fileID), tfd)
+	url, _, err := rs.getSignedUrl(ctx, http.MethodPut, repoId, fileID)
+	return url, err
+}
+
+func (rs *RemoteChunkStore) Rebase(ctx context.Context, req *remotesapi.RebaseRequest) (*remotesapi.RebaseResponse, error) {
+	logger := getReqLogger(rs.lgr, "Rebase")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cs.Rebase(ctx)
+
+	if err != nil {
+		logger.Errorf("error occurred during processing of Rebase rpc of %s/%s details: %v", req.RepoId.Org, req.RepoId.RepoName, err)
+		return nil, status.Errorf(codes.Internal, "failed to rebase: %v", err)
+	}
+
+	return &remotesapi.RebaseResponse{}, nil
+}
+
+func (rs *RemoteChunkStore) Root(ctx context.Context, req *remotesapi.RootRequest) (*remotesapi.RootResponse, error) {
+	logger := getReqLogger(rs.lgr, "Root")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := cs.Root(ctx)
+
+	if err != nil {
+		logger.Errorf("error occurred during processing of Root rpc of %s/%s details: %v", req.RepoId.Org, req.RepoId.RepoName, err)
+		return nil, status.Error(codes.Internal, "Failed to get root")
+	}
+
+	return &remotesapi.RootResponse{RootHash: h[:]}, nil
+}
+
+func (rs *RemoteChunkStore) Commit(ctx context.Context, req *remotesapi.CommitRequest) (*remotesapi.CommitResponse, error) {
+	logger := getReqLogger(rs.lgr, "Commit")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.addTableFiles(ctx, cs, req.ChunkTableInfo)
+
+	if err != nil {
+		logger.Errorf("error occurred updating the manifest: %s", err.Error())
+		return nil, status.Errorf(codes.Internal, "manifest update error: %v", err)
+	}
+
+	currHash := hash.New(req.Current)
+	lastHash := hash.New(req.Last)
+
+	var ok bool
+	ok, err = cs.Commit(ctx, currHash, lastHash)
+
+	if err != nil {
+		logger.Errorf("error occurred during processing of Commit of %s/%s last %s curr: %s details: %v", req.RepoId.Org, req.RepoId.RepoName, lastHash.String(), currHash.String(), err)
+		return nil, status.Errorf(codes.Internal, "failed to commit: %v", err)
+	}
+
+	logger.Tracef("committed %s/%s moved from %s -> %s", req.RepoId.Org, req.RepoId.RepoName, lastHash.String(), currHash.String())
+	return &remotesapi.CommitResponse{Success: ok}, nil
+}
+
+func (rs *RemoteChunkStore) GetRepoMetadata(ctx context.Context, req *remotesapi.GetRepoMetadataRequest) (*remotesapi.GetRepoMetadataResponse, error) {
+	logger := getReqLogger(rs.lgr, "GetRepoMetadata")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getOrCreateStore(ctx, logger, req.RepoId, false, req.ClientRepoFormat.NbfVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := cs.Size(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get repository size: %v", err)
+	}
+
+	return &remotesapi.GetRepoMetadataResponse{
+		NbfVersion:  cs.Version(),
+		NbsVersion:  req.ClientRepoFormat.NbsVersion,
+		StorageSize: size,
+	}, nil
+}
+
+func (rs *RemoteChunkStore) ListTableFiles(ctx context.Context, req *remotesapi.ListTableFilesRequest) (*remotesapi.ListTableFilesResponse, error) {
+	logger := getReqLogger(rs.lgr, "ListTableFiles")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	root, tables, appendixTables, err := cs.Sources(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get sources")
+	}
+
+	// table files which are not on disk, like the table file of the chunk journal, are only in memory. They are
+	// kept until the next listing of the repository so that they can be downloaded.
+	var inMemory []nbs.TableFile
+	for _, t := range tables {
+		_, onDisk, err := cs.TableFilePath(t.FileID())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get table file path: %v", err)
+		}
+		if !onDisk {
+			inMemory = append(inMemory, t)
+		}
+	}
+	rs.listedFiles.Put(tableFileUrlPath(req.RepoId.Org, req.RepoId.RepoName, ""), inMemory)
+
+	tableFileInfo, err := rs.getTableFileInfo(ctx, tables, req)
+	if err != nil {
+		return nil, err
+	}
+
+	appendixTableFileInfo, err := rs.getTableFileInfo(ctx, appendixTables, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &remotesapi.ListTableFilesResponse{
+		RootHash:              root[:],
+		TableFileInfo:         tableFileInfo,
+		AppendixTableFileInfo: appendixTableFileInfo,
+	}
+
+	return resp, nil
+}
+
+func (rs *RemoteChunkStore) getTableFileInfo(ctx context.Context, tableList []nbs.TableFile, req *remotesapi.ListTableFilesRequest) ([]*remotesapi.TableFileInfo, error) {
+	tableFileInfo := make([]*remotesapi.TableFileInfo, 0)
+	for _, t := range tableList {
+		url, refreshAfter, err := rs.getDownloadUrl(ctx, req.RepoId, t.FileID())
+		if err != nil {
+			return nil, err
+		}
+
+		tableFileInfo = append(tableFileInfo, &remotesapi.TableFileInfo{
+			FileId:         t.FileID(),
+			NumChunks:      uint32(t.NumChunks()),
+			Url:            url,
+			RefreshAfter:   refreshAfter,
+			RefreshRequest: &remotesapi.RefreshTableFileUrlRequest{RepoId: req.RepoId, FileId: t.FileID()},
+		})
+	}
+	return tableFileInfo, nil
+}
+
+// RefreshTableFileUrl returns a new url for a table file whose url is about to expire.
+func (rs *RemoteChunkStore) RefreshTableFileUrl(ctx context.Context, req *remotesapi.RefreshTableFileUrlRequest) (*remotesapi.RefreshTableFileUrlResponse, error) {
+	logger := getReqLogger(rs.lgr, "RefreshTableFileUrl")
+	defer func() { logger.Trace("finished") }()
+
+	_, err := rs.getStore(ctx, logger, req.RepoId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	url, refreshAfter, err := rs.getDownloadUrl(ctx, req.RepoId, req.FileId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remotesapi.RefreshTableFileUrlResponse{Url: url, RefreshAfter: refreshAfter}, nil
+}
+
+// AddTableFiles updates the remote manifest with new table files without modifying the root hash.
+func (rs *RemoteChunkStore) AddTableFiles(ctx context.Context, req *remotesapi.AddTableFilesRequest) (*remotesapi.AddTableFilesResponse, error) {
+	logger := getReqLogger(rs.lgr, "AddTableFiles")
+	defer func() { logger.Trace("finished") }()
+
+	cs, err := rs.getStore(ctx, logger, req.RepoId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.addTableFiles(ctx, cs, req.ChunkTableInfo)
+
+	if err != nil {
+		logger.Errorf("error occurred updating the manifest: %s", err.Error())
+		return nil, status.Error(codes.Internal, "manifest update error")
+	}
+
+	return &remotesapi.AddTableFilesResponse{Success: true}, nil
+}
+
+func (rs *RemoteChunkStore) addTableFiles(ctx context.Context, cs RemoteSrvStore, infos []*remotesapi.ChunkTableInfo) error {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	// should validate
+	updates := make(map[string]int)
+	for _, cti := range infos {
+		updates[hash.New(cti.Hash).String()] = int(cti.ChunkCount)
+	}
+
+	return cs.AddTableFilesToManifest(ctx, updates)
+}
+
+func (rs *RemoteChunkStore) getStore(ctx context.Context, logger *logrus.Entry, repoId *remotesapi.RepoId, write bool) (RemoteSrvStore, error) {
+	return rs.getOrCreateStore(ctx, logger, repoId, write, types.Format_Default.VersionString())
+}
+
+func (rs *RemoteChunkStore) getOrCreateStore(ctx context.Context, logger *logrus.Entry, repoId *remotesapi.RepoId, write bool, nbfVerStr string) (RemoteSrvStore, error) {
+	if repoId == nil {
+		return nil, status.Error(codes.InvalidArgument, "repo_id is required")
+	}
+
+	org := repoId.Org
+	repoName := repoId.RepoName
+
+	if rs.authorizer != nil {
+		err := rs.authorizer.Authorize(ctx, org, repoName, write)
+		if err != nil {
+			logger.Tracef("request for %s/%s was not authorized: %v", org, repoName, err)
+			return nil, err
+		}
+	}
+
+	cs, err := rs.csCache.Get(ctx, org, repoName, nbfVerStr)
+
+	if err != nil {
+		logger.Errorf("Failed to retrieve chunkstore for %s/%s: %v", org, repoName, err)
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get chunkstore: %v", err)
+	}
+
+	logger.Tracef("found repo %s/%s", org, repoName)
+
+	return cs, nil
+}
+
+var requestId int32
+
+func incReqId() int32 {
+	return atomic.AddInt32(&requestId, 1)
+}
+
+func getReqLogger(lgr *logrus.Entry, method string) *logrus.Entry {
+	lgr = lgr.WithFields(logrus.Fields{
+		"method":      method,
+		"request_num": strconv.Itoa(int(incReqId())),
+	})
+	lgr.Trace("starting request")
+	return lgr
+}
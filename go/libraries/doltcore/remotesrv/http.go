@@ -0,0 +1,426 @@
+// Copyright 2019 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	remotesapi "github.com/dolthub/dolt/go/gen/proto/dolt/services/remotesapi/v1alpha1"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+var (
+	ErrReadOutOfBounds = errors.New("cannot read file for given length and " +
+		"offset since the read would exceed the size of the file")
+)
+
+// fileDetails holds the details of the table files which clients were given upload urls for, keyed by url path.
+type fileDetails struct {
+	details *sync.Map
+}
+
+func (fd fileDetails) Put(path string, tfd *remotesapi.TableFileDetails) {
+	fd.details.Store(path, tfd)
+}
+
+func (fd fileDetails) Get(path string) (*remotesapi.TableFileDetails, bool) {
+	v, ok := fd.details.Load(path)
+	if !ok {
+		return nil, false
+	}
+	return v.(*remotesapi.TableFileDetails), true
+}
+
+func newFileDetails() fileDetails {
+	return fileDetails{new(sync.Map)}
+}
+
+// listedTableFiles holds the table files of the last listing of each repository which are not on disk, so that they
+// can be downloaded.
+type listedTableFiles struct {
+	mu    *sync.Mutex
+	files map[string]map[string]nbs.TableFile
+}
+
+func newListedTableFiles() *listedTableFiles {
+	return &listedTableFiles{&sync.Mutex{}, make(map[string]map[string]nbs.TableFile)}
+}
+
+// Put replaces the table files listed for the repository with url path |repoPath|.
+func (ltf *listedTableFiles) Put(repoPath string, tfs []nbs.TableFile) {
+	ltf.mu.Lock()
+	defer ltf.mu.Unlock()
+
+	if len(tfs) == 0 {
+		delete(ltf.files, repoPath)
+		return
+	}
+
+	byId := make(map[string]nbs.TableFile, len(tfs))
+	for _, tf := range tfs {
+		byId[tf.FileID()] = tf
+	}
+	ltf.files[repoPath] = byId
+}
+
+func (ltf *listedTableFiles) Get(repoPath, fileId string) (nbs.TableFile, bool) {
+	ltf.mu.Lock()
+	defer ltf.mu.Unlock()
+
+	tf, ok := ltf.files[repoPath][fileId]
+	return tf, ok
+}
+
+type filehandler struct {
+	dbCache       DBCache
+	expectedFiles fileDetails
+	listedFiles   *listedTableFiles
+	signer        urlSigner
+	lgr           *logrus.Entry
+}
+
+func newFileHandler(lgr *logrus.Entry, dbCache DBCache, signer urlSigner, expectedFiles fileDetails, listedFiles *listedTableFiles) filehandler {
+	return filehandler{
+		dbCache:       dbCache,
+		expectedFiles: expectedFiles,
+		listedFiles:   listedFiles,
+		signer:        signer,
+		lgr: lgr.WithFields(logrus.Fields{
+			"service": "dolt.services.remotesapi.v1alpha1.HttpFileServer",
+		}),
+	}
+}
+
+func (fh filehandler) ServeHTTP(respWr http.ResponseWriter, req *http.Request) {
+	logger := getReqLogger(fh.lgr, "HTTP_"+req.Method)
+	logger = logger.WithField("path", req.URL.Path)
+	defer func() { logger.Trace("finished") }()
+
+	path := strings.TrimLeft(req.URL.Path, "/")
+	tokens := strings.Split(path, "/")
+
+	var org, repo, fileId string
+	switch len(tokens) {
+	case 2:
+		repo, fileId = tokens[0], tokens[1]
+	case 3:
+		org, repo, fileId = tokens[0], tokens[1], tokens[2]
+	default:
+		logger.Tracef("response to: %v method: %v http response code: %v", req.RequestURI, req.Method, http.StatusNotFound)
+		respWr.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	statusCode := http.StatusMethodNotAllowed
+	switch req.Method {
+	case http.MethodGet:
+		if err := fh.signer.verify(http.MethodGet, req.URL); err != nil {
+			logger.Tracef("unauthorized request: %v", err)
+			statusCode = http.StatusForbidden
+			break
+		}
+		statusCode = fh.readTableFile(req.Context(), logger, org, repo, fileId, respWr, req)
+
+	case http.MethodPost, http.MethodPut:
+		if err := fh.signer.verify(http.MethodPut, req.URL); err != nil {
+			logger.Tracef("unauthorized request: %v", err)
+			statusCode = http.StatusForbidden
+			break
+		}
+		statusCode = fh.writeTableFile(req.Context(), logger, org, repo, fileId, req)
+	}
+
+	if statusCode != -1 {
+		respWr.WriteHeader(statusCode)
+	}
+}
+
+// readTableFile writes the requested range of the table file |fileId| to |respWr|. It returns the status code of the
+// response, or -1 if the response has been written.
+func (fh filehandler) readTableFile(ctx context.Context, logger *logrus.Entry, org, repo, fileId string, respWr http.ResponseWriter, req *http.Request) int {
+	offset, length, err := offsetAndLenFromRange(req.Header.Get("Range"))
+	if err != nil {
+		logger.Trace(err.Error())
+		return http.StatusBadRequest
+	}
+
+	cs, err := fh.dbCache.Get(ctx, org, repo, types.Format_Default.VersionString())
+	if err != nil {
+		logger.Tracef("failed to get %s/%s repository: %v", org, repo, err)
+		return http.StatusNotFound
+	}
+
+	var r io.ReadCloser
+	var readSize int64
+	var fileErr error
+
+	path, onDisk, err := cs.TableFilePath(fileId)
+	if err != nil {
+		logger.Errorf("failed to get path of table file %s: %v", fileId, err)
+		return http.StatusInternalServerError
+	} else if onDisk {
+		if length == -1 {
+			logger.Trace("going to read entire file")
+			r, readSize, fileErr = getFileReader(path)
+		} else {
+			logger.Tracef("going to read file at offset %d, length %d", offset, length)
+			readSize = length
+			r, fileErr = getFileReaderAt(path, offset, length)
+		}
+	} else if tf, ok := fh.listedFiles.Get(tableFileUrlPath(org, repo, ""), fileId); ok {
+		r, readSize, fileErr = getTableFileReader(ctx, tf, offset, length)
+	} else {
+		fileErr = os.ErrNotExist
+	}
+
+	if fileErr != nil {
+		logger.Trace(fileErr.Error())
+		if errors.Is(fileErr, os.ErrNotExist) {
+			return http.StatusNotFound
+		} else if errors.Is(fileErr, ErrReadOutOfBounds) {
+			return http.StatusBadRequest
+		}
+		return http.StatusInternalServerError
+	}
+	defer func() {
+		err := r.Close()
+		if err != nil {
+			logger.Errorf("failed to close table file %s: %v", fileId, err)
+		}
+	}()
+
+	logger.Tracef("opened table file %s, going to read %d bytes", fileId, readSize)
+
+	respWr.Header().Set("Content-Length", strconv.FormatInt(readSize, 10))
+	respWr.WriteHeader(http.StatusOK)
+
+	n, err := io.Copy(respWr, r)
+	if err != nil {
+		logger.Errorf("failed to write data to response writer: %v", err)
+	} else if n != readSize {
+		logger.Errorf("wanted to write %d bytes from table file %s but only wrote %d", readSize, fileId, n)
+	} else {
+		logger.Tracef("wrote %d bytes", n)
+	}
+
+	return -1
+}
+
+type uploadreader struct {
+	r            io.ReadCloser
+	totalread    int
+	expectedread uint64
+	expectedsum  []byte
+	checksum     gohash.Hash
+}
+
+func (u *uploadreader) Read(p []byte) (n int, err error) {
+	n, err = u.r.Read(p)
+	if err == nil || err == io.EOF {
+		u.totalread += n
+		u.checksum.Write(p[:n])
+	}
+	return n, err
+}
+
+var errBodyLengthTFDMismatch = errors.New("body upload length did not match table file details")
+var errBodyHashTFDMismatch = errors.New("body upload hash did not match table file details")
+
+func (u *uploadreader) Close() error {
+	cerr := u.r.Close()
+	if cerr != nil {
+		return cerr
+	}
+	if u.expectedread != 0 && u.expectedread != uint64(u.totalread) {
+		return errBodyLengthTFDMismatch
+	}
+	sum := u.checksum.Sum(nil)
+	if !bytes.Equal(u.expectedsum, sum[:]) {
+		return errBodyHashTFDMismatch
+	}
+	return nil
+}
+
+func (fh filehandler) writeTableFile(ctx context.Context, logger *logrus.Entry, org, repo, fileId string, request *http.Request) int {
+	_, ok := hash.MaybeParse(fileId)
+
+	if !ok {
+		logger.Trace(fileId + " is not a valid hash")
+		return http.StatusBadRequest
+	}
+
+	tfd, ok := fh.expectedFiles.Get(tableFileUrlPath(org, repo, fileId))
+	if !ok {
+		logger.Trace("bad request for " + fileId + ": tfd not found")
+		return http.StatusBadRequest
+	}
+
+	logger.Trace(fileId + " is valid")
+
+	cs, err := fh.dbCache.Get(ctx, org, repo, types.Format_Default.VersionString())
+	if err != nil {
+		logger.Errorf("failed to get %s/%s repository: %v", org, repo, err)
+		return http.StatusInternalServerError
+	}
+
+	err = cs.WriteTableFile(ctx, fileId, int(tfd.NumChunks), tfd.ContentHash, func() (io.ReadCloser, uint64, error) {
+		reader := request.Body
+		size := tfd.ContentLength
+		return &uploadreader{
+			reader,
+			0,
+			tfd.ContentLength,
+			tfd.ContentHash,
+			md5.New(),
+		}, size, nil
+	})
+
+	if err != nil {
+		if errors.Is(err, errBodyLengthTFDMismatch) {
+			logger.Trace("bad write file request for " + fileId + ": body length mismatch")
+			return http.StatusBadRequest
+		}
+		if errors.Is(err, errBodyHashTFDMismatch) {
+			logger.Trace("bad write file request for " + fileId + ": body hash mismatch")
+			return http.StatusBadRequest
+		}
+		logger.Errorf("failed to read body: %v", err)
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusOK
+}
+
+// offsetAndLenFromRange parses a range header of the form bytes=#-#. It returns a length of -1 if |rngStr| is empty.
+func offsetAndLenFromRange(rngStr string) (int64, int64, error) {
+	if rngStr == "" {
+		return -1, -1, nil
+	}
+
+	if !strings.HasPrefix(rngStr, "bytes=") {
+		return -1, -1, errors.New("range string does not start with 'bytes=")
+	}
+
+	tokens := strings.Split(rngStr[6:], "-")
+
+	if len(tokens) != 2 {
+		return -1, -1, errors.New("invalid range format. should be bytes=#-#")
+	}
+
+	start, err := strconv.ParseUint(strings.TrimSpace(tokens[0]), 10, 64)
+
+	if err != nil {
+		return -1, -1, errors.New("invalid offset is not a number. should be bytes=#-#")
+	}
+
+	end, err := strconv.ParseUint(strings.TrimSpace(tokens[1]), 10, 64)
+
+	if err != nil {
+		return -1, -1, errors.New("invalid length is not a number. should be bytes=#-#")
+	}
+
+	if end < start {
+		return -1, -1, errors.New("invalid range. the end of the range is before its start")
+	}
+
+	return int64(start), int64(end-start) + 1, nil
+}
+
+// getFileReader opens a file at the given path and returns an io.ReadCloser,
+// the corresponding file's filesize, and a http status.
+func getFileReader(path string) (io.ReadCloser, int64, error) {
+	return openFile(path)
+}
+
+func openFile(path string) (*os.File, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get stats for file at path %s: %w", path, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file at path %s: %w", path, err)
+	}
+
+	return f, info.Size(), nil
+}
+
+type closerReaderWrapper struct {
+	io.Reader
+	io.Closer
+}
+
+func getFileReaderAt(path string, offset int64, length int64) (io.ReadCloser, error) {
+	f, fSize, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fSize < int64(offset+length) {
+		f.Close()
+		return nil, fmt.Errorf("failed to read file %s at offset %d, length %d: %w", path, offset, length, ErrReadOutOfBounds)
+	}
+
+	_, err = f.Seek(int64(offset), 0)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek file at path %s to offset %d: %w", path, offset, err)
+	}
+
+	r := closerReaderWrapper{io.LimitReader(f, length), f}
+	return r, nil
+}
+
+// getTableFileReader opens a table file which is not on disk and returns a reader of |length| bytes of it starting
+// at |offset|, or all of it if |length| is -1, along with the number of bytes to be read.
+func getTableFileReader(ctx context.Context, tf nbs.TableFile, offset int64, length int64) (io.ReadCloser, int64, error) {
+	rd, size, err := tf.Open(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if length == -1 {
+		return rd, int64(size), nil
+	}
+
+	if int64(size) < offset+length {
+		rd.Close()
+		return nil, 0, fmt.Errorf("failed to read table file %s at offset %d, length %d: %w", tf.FileID(), offset, length, ErrReadOutOfBounds)
+	}
+
+	_, err = io.CopyN(io.Discard, rd, offset)
+	if err != nil {
+		rd.Close()
+		return nil, 0, err
+	}
+
+	return closerReaderWrapper{io.LimitReader(rd, length), rd}, length, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+
+	remotesapi "github.com/dolthub/dolt/go/gen/proto/dolt/services/remotesapi/v1alpha1"
+)
+
+// ServerArgs configures a remotesapi Server.
+type ServerArgs struct {
+	Logger *logrus.Entry
+	// HttpHost is the host:port of the http file server put in the urls handed out to clients. If it is empty, the
+	// host clients connected to the grpc server with is used, with HttpPort.
+	HttpHost string
+	HttpPort int
+	// GrpcPort is the port of the grpc server. If it is the same as HttpPort, grpc and http requests are served on
+	// the same port.
+	GrpcPort int
+	DBCache  DBCache
+	// Authorizer authorizes grpc requests. If it is nil, all requests are authorized.
+	Authorizer Authorizer
+	Options    []grpc.ServerOption
+}
+
+// Server serves the remotesapi ChunkStoreService over grpc, along with the http file server which the table files of
+// its repositories are uploaded to and downloaded from.
+type Server struct {
+	lgr      *logrus.Entry
+	wg       sync.WaitGroup
+	grpcPort int
+	grpcSrv  *grpc.Server
+	httpPort int
+	httpSrv  http.Server
+}
+
+// Listeners are the listeners a Server serves on. If the server serves grpc and http requests on the same port, grpc
+// is nil.
+type Listeners struct {
+	http net.Listener
+	grpc net.Listener
+}
+
+func NewServer(args ServerArgs) (*Server, error) {
+	if args.Logger == nil {
+		args.Logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	signer, err := newURLSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	expectedFiles := newFileDetails()
+	listedFiles := newListedTableFiles()
+
+	s := &Server{
+		lgr:      args.Logger,
+		grpcPort: args.GrpcPort,
+		httpPort: args.HttpPort,
+	}
+
+	opts := append([]grpc.ServerOption{grpc.MaxRecvMsgSize(128 * 1024 * 1024)}, args.Options...)
+	s.grpcSrv = grpc.NewServer(opts...)
+	chnkSt := newHttpFSBackedChunkStore(args.Logger, args.HttpHost, args.HttpPort, args.DBCache, args.Authorizer, signer, expectedFiles, listedFiles)
+	remotesapi.RegisterChunkStoreServiceServer(s.grpcSrv, chnkSt)
+
+	var handler http.Handler = newFileHandler(args.Logger, args.DBCache, signer, expectedFiles, listedFiles)
+	if args.HttpPort == args.GrpcPort {
+		handler = grpcMultiplexHandler(s.grpcSrv, handler)
+	}
+
+	s.httpSrv = http.Server{
+		Addr:    fmt.Sprintf(":%d", args.HttpPort),
+		Handler: handler,
+	}
+
+	return s, nil
+}
+
+// grpcMultiplexHandler returns a handler which serves grpc requests with |grpcSrv| and other requests with |handler|.
+// grpc clients connect with http/2 without tls, so the handler accepts h2c connections.
+func grpcMultiplexHandler(grpcSrv *grpc.Server, handler http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcSrv.ServeHTTP(w, r)
+		} else {
+			handler.ServeHTTP(w, r)
+		}
+	}), &http2.Server{})
+}
+
+// Listeners opens the listeners the server serves on.
+func (s *Server) Listeners() (Listeners, error) {
+	httpListener, err := net.Listen("tcp", s.httpSrv.Addr)
+	if err != nil {
+		return Listeners{}, err
+	}
+
+	if s.httpPort == s.grpcPort {
+		return Listeners{http: httpListener}, nil
+	}
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.grpcPort))
+	if err != nil {
+		httpListener.Close()
+		return Listeners{}, err
+	}
+
+	return Listeners{http: httpListener, grpc: grpcListener}, nil
+}
+
+// Serve serves requests on |listeners| until the server is stopped.
+func (s *Server) Serve(listeners Listeners) {
+	if listeners.grpc != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.lgr.Trace("Starting grpc server on port ", s.grpcPort)
+			err := s.grpcSrv.Serve(listeners.grpc)
+			s.lgr.Trace("grpc server exited. error: ", err)
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.lgr.Trace("Starting http server on port ", s.httpPort)
+		err := s.httpSrv.Serve(listeners.http)
+		s.lgr.Trace("http server exited. exit error: ", err)
+	}()
+
+	s.wg.Wait()
+}
+
+// GracefulStop stops the server. It waits for in flight http requests to finish, and for in flight grpc requests to
+// finish if they are served on their own port.
+func (s *Server) GracefulStop() {
+	s.httpSrv.Shutdown(context.Background())
+	if s.httpPort == s.grpcPort {
+		// grpc requests served by the http server can't be drained, and connections taken over for h2c are not
+		// closed by Shutdown.
+		s.grpcSrv.Stop()
+	} else {
+		s.grpcSrv.GracefulStop()
+	}
+	s.wg.Wait()
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	expiresParam   = "expires"
+	signatureParam = "sig"
+
+	// urlLifetime is how long the table file urls handed out by the server are valid for.
+	urlLifetime = 15 * time.Minute
+	// urlRefreshWindow is how long before a download url expires clients are asked to refresh it.
+	urlRefreshWindow = 5 * time.Minute
+)
+
+var ErrURLExpired = errors.New("url has expired")
+var ErrInvalidURLSignature = errors.New("url signature is invalid")
+
+// urlSigner signs the table file urls handed out by the grpc server and verifies them in the http server. Urls are
+// only handed out by rpcs which have been authorized, so a request for a url with a valid signature is authorized as
+// well. The key is generated when the server starts, so urls are not valid across restarts.
+type urlSigner struct {
+	key []byte
+	now func() time.Time
+}
+
+func newURLSigner() (urlSigner, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return urlSigner{}, err
+	}
+
+	return urlSigner{key, time.Now}, nil
+}
+
+// sign returns the query parameters which authorize requests with |method| to |path| until the url lifetime passes,
+// and the time they expire at.
+func (s urlSigner) sign(method, path string) (url.Values, time.Time) {
+	expires := s.now().Add(urlLifetime).Truncate(time.Second)
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+
+	params := url.Values{}
+	params.Set(expiresParam, expiresStr)
+	params.Set(signatureParam, hex.EncodeToString(s.mac(method, path, expiresStr)))
+
+	return params, expires
+}
+
+// verify returns nil if the query parameters of |u| authorize a request with |method| to its path.
+func (s urlSigner) verify(method string, u *url.URL) error {
+	params := u.Query()
+	expiresStr := params.Get(expiresParam)
+
+	sig, err := hex.DecodeString(params.Get(signatureParam))
+	if err != nil || !hmac.Equal(sig, s.mac(method, u.Path, expiresStr)) {
+		return ErrInvalidURLSignature
+	}
+
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return ErrInvalidURLSignature
+	}
+
+	if s.now().After(time.Unix(expires, 0)) {
+		return ErrURLExpired
+	}
+
+	return nil
+}
+
+func (s urlSigner) mac(method, path, expires string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(method + "\n" + path + "\n" + expires))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotesrv
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer, err := newURLSigner()
+	require.NoError(t, err)
+
+	now := time.Now()
+	signer.now = func() time.Time { return now }
+
+	path := "/org/repo/0123456789abcdefghijklmnopqrstuv"
+	params, expires := signer.sign(http.MethodGet, path)
+	assert.True(t, expires.After(now))
+
+	u := &url.URL{Path: path, RawQuery: params.Encode()}
+	assert.NoError(t, signer.verify(http.MethodGet, u))
+	assert.ErrorIs(t, signer.verify(http.MethodPut, u), ErrInvalidURLSignature)
+
+	other := &url.URL{Path: "/org/repo/other", RawQuery: params.Encode()}
+	assert.ErrorIs(t, signer.verify(http.MethodGet, other), ErrInvalidURLSignature)
+
+	assert.ErrorIs(t, signer.verify(http.MethodGet, &url.URL{Path: path}), ErrInvalidURLSignature)
+
+	otherSigner, err := newURLSigner()
+	require.NoError(t, err)
+	assert.ErrorIs(t, otherSigner.verify(http.MethodGet, u), ErrInvalidURLSignature)
+
+	now = now.Add(urlLifetime + time.Second)
+	assert.ErrorIs(t, signer.verify(http.MethodGet, u), ErrURLExpired)
+}
+
+func TestOffsetAndLenFromRange(t *testing.T) {
+	_, length, err := offsetAndLenFromRange("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), length)
+
+	offset, length, err := offsetAndLenFromRange("bytes=10-19")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+	assert.Equal(t, int64(10), length)
+
+	_, _, err = offsetAndLenFromRange("bytes=19-10")
+	assert.Error(t, err)
+
+	_, _, err = offsetAndLenFromRange("lines=1-2")
+	assert.Error(t, err)
+}
@@ -120,14 +120,24 @@ type DoltChunkStore struct {
 
 func NewDoltChunkStoreFromPath(ctx context.Context, nbf *types.NomsBinFormat, path, host string, csClient remotesapi.ChunkStoreServiceClient) (*DoltChunkStore, error) {
 	tokens := strings.Split(strings.Trim(path, "/"), "/")
-	if len(tokens) != 2 {
-		return nil, ErrInvalidDoltSpecPath
-	}
 
 	// todo:
 	// this may just be a dolthub thing.  Need to revisit how we do this.
-	org := tokens[0]
-	repoName := tokens[1]
+	var org, repoName string
+	switch len(tokens) {
+	case 1:
+		// remotes served by dolt sql-server are named by database alone
+		repoName = tokens[0]
+	case 2:
+		org = tokens[0]
+		repoName = tokens[1]
+	default:
+		return nil, ErrInvalidDoltSpecPath
+	}
+
+	if repoName == "" {
+		return nil, ErrInvalidDoltSpecPath
+	}
 
 	return NewDoltChunkStore(ctx, nbf, org, repoName, host, csClient)
 }
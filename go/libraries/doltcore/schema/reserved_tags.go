@@ -108,3 +108,25 @@ const (
 	DoltConflictsOurCardinalityTag
 	DoltConflictsTheirCardinalityTag
 )
+
+// Tags for the dolt_rebase table
+const (
+	DoltRebaseOrderTag = iota + SystemTableReservedMin + uint64(8000)
+	DoltRebaseActionTag
+	DoltRebaseCommitHashTag
+	DoltRebaseCommitMessageTag
+)
+
+// Tags for the dolt_ignore table
+const (
+	DoltIgnorePatternTag = iota + SystemTableReservedMin + uint64(9000)
+	DoltIgnoreIgnoredTag
+)
+
+// Tags for the dolt_replica_position table
+const (
+	DoltReplicaPositionChannelTag = iota + SystemTableReservedMin + uint64(10000)
+	DoltReplicaPositionSourceIDTag
+	DoltReplicaPositionSeqTag
+	DoltReplicaPositionPositionTag
+)
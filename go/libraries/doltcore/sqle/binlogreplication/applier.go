@@ -0,0 +1,454 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	querypb "github.com/dolthub/vitess/go/vt/proto/query"
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+)
+
+// queryEngine executes the statements of the query events of a binlog.
+type queryEngine interface {
+	Query(ctx *sql.Context, query string) (sql.Schema, sql.RowIter, error)
+}
+
+// applier applies the events of a binlog to the databases of its session. Row events are written with the inserters,
+// updaters and deleters of the tables of the session, which are the table writers of the sqle/writer write session of
+// each database, the same as the writes of INSERT, UPDATE and DELETE statements. Statements are executed by the engine.
+// Every transaction of the source is applied in a transaction of the replica, which also writes the position of the
+// replica to the dolt_replica_position table of its database, so the position is committed with the transaction and a
+// replica that stops in between doesn't apply the transaction twice.
+type applier struct {
+	engine queryEngine
+	ctx    *sql.Context
+	// committed is called with the position of the replica, and the number of transactions it committed with its
+	// source configuration, after every transaction it commits.
+	committed func(pos mysql.Position, seq uint64) error
+	// sourceID identifies the source configuration of the replica in the dolt_replica_position tables.
+	sourceID string
+	seq      uint64
+
+	format    mysql.BinlogFormat
+	tableMaps map[uint64]*mysql.TableMap
+
+	// position is the position of the last committed transaction, and pending is the position of the last event
+	// applied.
+	position mysql.Position
+	pending  mysql.Position
+
+	// inTx is true between the BEGIN of a transaction and its commit, and txDb is the database of the open
+	// transaction of the session, if one was started.
+	inTx bool
+	txDb string
+}
+
+func newApplier(ctx *sql.Context, engine queryEngine, sourceID string, pos mysql.Position, seq uint64, committed func(pos mysql.Position, seq uint64) error) *applier {
+	return &applier{
+		engine:    engine,
+		ctx:       ctx,
+		committed: committed,
+		sourceID:  sourceID,
+		seq:       seq,
+		tableMaps: make(map[uint64]*mysql.TableMap),
+		position:  pos,
+		pending:   pos,
+	}
+}
+
+// apply applies |ev| to the databases of the applier.
+func (a *applier) apply(ev mysql.BinlogEvent) error {
+	if !ev.IsValid() {
+		return fmt.Errorf("received an invalid binlog event")
+	}
+
+	if ev.IsFormatDescription() {
+		f, err := ev.Format()
+		if err != nil {
+			return err
+		}
+		a.format = f
+		return nil
+	}
+	// events can't be decoded until the format of the binlog is known
+	if a.format.IsZero() {
+		return nil
+	}
+
+	ev, _, err := ev.StripChecksum(a.format)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case ev.IsGTID():
+		gtid, _, err := ev.GTID(a.format)
+		if err != nil {
+			return err
+		}
+		a.pending = mysql.AppendGTID(a.pending, gtid)
+		return nil
+
+	case ev.IsQuery():
+		q, err := ev.Query(a.format)
+		if err != nil {
+			return err
+		}
+		return a.applyQuery(q)
+
+	case ev.IsXID():
+		return a.commit()
+
+	case ev.IsTableMap():
+		tm, err := ev.TableMap(a.format)
+		if err != nil {
+			return err
+		}
+		a.tableMaps[ev.TableID(a.format)] = tm
+		return nil
+
+	case ev.IsWriteRows(), ev.IsUpdateRows(), ev.IsDeleteRows():
+		return a.applyRows(ev)
+
+	default:
+		return nil
+	}
+}
+
+func (a *applier) applyQuery(q mysql.Query) error {
+	switch strings.ToUpper(strings.TrimSpace(q.SQL)) {
+	case "BEGIN":
+		a.inTx = true
+		return nil
+	case "COMMIT":
+		return a.commit()
+	case "ROLLBACK":
+		return a.rollback()
+	}
+
+	// statements outside of a transaction, like DDL, are applied in a transaction of their own so that the position
+	// is committed with them. Statements which create or drop databases are committed by the engine.
+	standalone := !a.inTx
+	if q.Database != "" && (a.inTx || !isDatabaseDDL(q.SQL)) {
+		if err := a.startTransaction(q.Database); err != nil {
+			return err
+		}
+	} else if q.Database != "" {
+		a.ctx.SetCurrentDatabase(q.Database)
+	}
+
+	if err := a.exec(q.SQL); err != nil {
+		return fmt.Errorf("error applying statement '%s': %w", q.SQL, err)
+	}
+
+	if standalone {
+		return a.commit()
+	}
+	return nil
+}
+
+// isDatabaseDDL returns whether |query| creates or drops a database.
+func isDatabaseDDL(query string) bool {
+	stmt, err := sqlparser.Parse(query)
+	if err != nil {
+		return false
+	}
+	_, ok := stmt.(*sqlparser.DBDDL)
+	return ok
+}
+
+func (a *applier) applyRows(ev mysql.BinlogEvent) error {
+	tableID := ev.TableID(a.format)
+	tm, ok := a.tableMaps[tableID]
+	if !ok {
+		return fmt.Errorf("received a rows event for unknown table id %d", tableID)
+	}
+
+	rows, err := ev.Rows(a.format, tm)
+	if err != nil {
+		return err
+	}
+
+	if err = a.startTransaction(tm.Database); err != nil {
+		return err
+	}
+
+	table, err := a.getTable(tm.Database, tm.Name)
+	if err != nil {
+		return err
+	}
+	sch := table.Schema()
+	if len(sch) != len(tm.Types) {
+		return fmt.Errorf("table %s.%s has %d columns on the replica but %d on the source", tm.Database, tm.Name, len(sch), len(tm.Types))
+	}
+
+	switch {
+	case ev.IsWriteRows():
+		t, ok := table.(sql.InsertableTable)
+		if !ok {
+			return fmt.Errorf("table %s.%s is not writable", tm.Database, tm.Name)
+		}
+		inserter := t.Inserter(a.ctx)
+		return a.edit(inserter, func() error {
+			for _, r := range rows.Rows {
+				row, err := decodeRow(tm, sch, rows.DataColumns, r.NullColumns, r.Data)
+				if err != nil {
+					return err
+				}
+				if err = inserter.Insert(a.ctx, row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case ev.IsUpdateRows():
+		t, ok := table.(sql.UpdatableTable)
+		if !ok {
+			return fmt.Errorf("table %s.%s is not writable", tm.Database, tm.Name)
+		}
+		updater := t.Updater(a.ctx)
+		return a.edit(updater, func() error {
+			for _, r := range rows.Rows {
+				old, err := decodeRow(tm, sch, rows.IdentifyColumns, r.NullIdentifyColumns, r.Identify)
+				if err != nil {
+					return err
+				}
+				row, err := decodeRow(tm, sch, rows.DataColumns, r.NullColumns, r.Data)
+				if err != nil {
+					return err
+				}
+				if err = updater.Update(a.ctx, old, row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	default:
+		t, ok := table.(sql.DeletableTable)
+		if !ok {
+			return fmt.Errorf("table %s.%s is not writable", tm.Database, tm.Name)
+		}
+		deleter := t.Deleter(a.ctx)
+		return a.edit(deleter, func() error {
+			for _, r := range rows.Rows {
+				old, err := decodeRow(tm, sch, rows.IdentifyColumns, r.NullIdentifyColumns, r.Identify)
+				if err != nil {
+					return err
+				}
+				if err = deleter.Delete(a.ctx, old); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+type rowEditor interface {
+	sql.TableEditor
+	sql.Closer
+}
+
+// edit makes the edits of |f| as a single statement of |editor|, which is closed afterwards.
+func (a *applier) edit(editor rowEditor, f func() error) error {
+	editor.StatementBegin(a.ctx)
+	err := f()
+	if err == nil {
+		err = editor.StatementComplete(a.ctx)
+	} else {
+		_ = editor.DiscardChanges(a.ctx, err)
+	}
+	if cerr := editor.Close(a.ctx); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (a *applier) getTable(dbName, tableName string) (sql.Table, error) {
+	db, err := dsess.DSessFromSess(a.ctx.Session).Provider().Database(a.ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	table, ok, err := db.GetTableInsensitive(a.ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, sql.ErrTableNotFound.New(tableName)
+	}
+	return table, nil
+}
+
+// startTransaction starts a transaction of the session on |dbName| if one isn't open yet. A transaction of the source
+// may only write to a single database.
+func (a *applier) startTransaction(dbName string) error {
+	if a.txDb != "" {
+		if !strings.EqualFold(a.txDb, dbName) {
+			return fmt.Errorf("transactions writing to more than one database are not supported: '%s' and '%s'", a.txDb, dbName)
+		}
+		return nil
+	}
+
+	a.ctx.SetCurrentDatabase(dbName)
+	if err := a.exec("START TRANSACTION"); err != nil {
+		return err
+	}
+	a.txDb = dbName
+	return nil
+}
+
+func (a *applier) commit() error {
+	if a.txDb != "" {
+		if err := a.writePosition(a.pending, a.seq+1); err != nil {
+			return err
+		}
+		if err := a.exec("COMMIT"); err != nil {
+			return err
+		}
+		a.txDb = ""
+	}
+	a.inTx = false
+	return a.advance()
+}
+
+func (a *applier) rollback() error {
+	if err := a.abort(); err != nil {
+		return err
+	}
+	return a.advance()
+}
+
+// abort rolls back the open transaction of the session without advancing the position of the replica.
+func (a *applier) abort() error {
+	a.inTx = false
+	a.pending = a.position
+	if a.txDb == "" {
+		return nil
+	}
+	a.txDb = ""
+	return a.exec("ROLLBACK")
+}
+
+func (a *applier) advance() error {
+	a.position = a.pending
+	a.seq++
+	return a.committed(a.position, a.seq)
+}
+
+// writePosition writes |pos| to the dolt_replica_position table of the database of the open transaction, creating
+// the table if it doesn't exist yet.
+func (a *applier) writePosition(pos mysql.Position, seq uint64) error {
+	db, err := dsess.DSessFromSess(a.ctx.Session).Provider().Database(a.ctx, a.txDb)
+	if err != nil {
+		return err
+	}
+	sqlDb, ok := db.(sqle.Database)
+	if !ok {
+		return fmt.Errorf("database %s cannot be written to by a replica", a.txDb)
+	}
+	if _, err = sqle.ReplicaPositionGetOrCreateTable(a.ctx, sqlDb); err != nil {
+		return err
+	}
+
+	return a.exec(fmt.Sprintf("REPLACE INTO %s VALUES ('', %s, %d, %s)", doltdb.ReplicaPositionTableName,
+		sqlString(a.sourceID), seq, sqlString(mysql.EncodePosition(pos))))
+}
+
+func (a *applier) exec(query string) error {
+	_, err := queryRows(a.ctx, a.engine, query)
+	return err
+}
+
+func queryRows(ctx *sql.Context, engine queryEngine, query string) ([]sql.Row, error) {
+	_, iter, err := engine.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return sql.RowIterToRows(ctx, nil, iter)
+}
+
+// sqlString returns |s| as a quoted SQL string literal.
+func sqlString(s string) string {
+	var sb strings.Builder
+	sqltypes.NewVarChar(s).EncodeSQL(&sb)
+	return sb.String()
+}
+
+// decodeRow returns the row of |sch| encoded in |data|. Only full row images, in which every column is |present|, are
+// supported.
+func decodeRow(tm *mysql.TableMap, sch sql.Schema, present, nulls mysql.Bitmap, data []byte) (sql.Row, error) {
+	row := make(sql.Row, len(sch))
+	pos := 0
+	for c := range sch {
+		if c >= present.Count() || !present.Bit(c) {
+			return nil, fmt.Errorf("table %s.%s: only full row images are supported, set binlog_row_image=FULL on the source", tm.Database, tm.Name)
+		}
+		// columns are present in order, so the index of a column in |nulls| is its index in |sch|
+		if nulls.Bit(c) {
+			continue
+		}
+
+		val, l, err := mysql.CellValue(data, pos, tm.Types[c], tm.Metadata[c], sch[c].Type.Type())
+		if err != nil {
+			return nil, err
+		}
+		pos += l
+
+		row[c], err = convertValue(val, sch[c].Type)
+		if err != nil {
+			return nil, fmt.Errorf("table %s.%s: column %s: %w", tm.Database, tm.Name, sch[c].Name, err)
+		}
+	}
+	return row, nil
+}
+
+// convertValue converts a value decoded from a binlog to the value of |typ|. The ENUM and SET values of a binlog are
+// their indexes, which the types convert from.
+func convertValue(val sqltypes.Value, typ sql.Type) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch {
+	case val.IsNull():
+		return nil, nil
+	case val.IsSigned():
+		v, err = sqltypes.ToInt64(val)
+	case val.IsUnsigned():
+		v, err = sqltypes.ToUint64(val)
+	case val.IsFloat():
+		v, err = sqltypes.ToFloat64(val)
+	case val.Type() == querypb.Type_BIT:
+		b := make([]byte, 8)
+		copy(b[8-len(val.Raw()):], val.Raw())
+		v = binary.BigEndian.Uint64(b)
+	default:
+		v = string(val.Raw())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return typ.Convert(v)
+}
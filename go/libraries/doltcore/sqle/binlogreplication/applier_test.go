@@ -0,0 +1,324 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	gms "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+const testDB = "mydb"
+
+const testSourceID = "4b0a3b4e-2a4c-4b8e-9c51-0d9c8b6f4f12"
+
+// testSource makes the events of a recorded binlog.
+type testSource struct {
+	f mysql.BinlogFormat
+	s *mysql.FakeBinlogStream
+}
+
+func newTestSource() testSource {
+	return testSource{f: mysql.NewMySQL56BinlogFormat(), s: mysql.NewFakeBinlogStream()}
+}
+
+func (ts testSource) formatDescription() mysql.BinlogEvent {
+	return mysql.NewFormatDescriptionEvent(ts.f, ts.s)
+}
+
+func (ts testSource) query(db, query string) mysql.BinlogEvent {
+	return mysql.NewQueryEvent(ts.f, ts.s, mysql.Query{Database: db, SQL: query})
+}
+
+func (ts testSource) xid() mysql.BinlogEvent {
+	return mysql.NewXIDEvent(ts.f, ts.s)
+}
+
+func (ts testSource) gtid(sid mysql.SID, gno int64) mysql.BinlogEvent {
+	const gtidEventType = 33
+	data := make([]byte, 1+16+8)
+	copy(data[1:17], sid[:])
+	binary.LittleEndian.PutUint64(data[17:], uint64(gno))
+	return mysql.NewMysql56BinlogEvent(ts.s.Packetize(ts.f, gtidEventType, 0, data))
+}
+
+// tableMap maps table id 1 to the table t of testDB, created by createTestTable.
+func (ts testSource) tableMap() mysql.BinlogEvent {
+	tm := &mysql.TableMap{
+		Database:  testDB,
+		Name:      "t",
+		Types:     []byte{mysql.TypeLong, mysql.TypeVarchar},
+		CanBeNull: mysql.NewServerBitmap(2),
+		Metadata:  []uint16{0, 80},
+	}
+	tm.CanBeNull.Set(1, true)
+	return mysql.NewTableMapEvent(ts.f, ts.s, 1, tm)
+}
+
+// encodeRow encodes a row of the table t. An empty |c| is NULL.
+func encodeRow(pk int32, c string) (mysql.Bitmap, []byte) {
+	nulls := mysql.NewServerBitmap(2)
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(pk))
+	if c == "" {
+		nulls.Set(1, true)
+	} else {
+		data = append(data, byte(len(c)))
+		data = append(data, c...)
+	}
+	return nulls, data
+}
+
+func allColumns() mysql.Bitmap {
+	b := mysql.NewServerBitmap(2)
+	b.Set(0, true)
+	b.Set(1, true)
+	return b
+}
+
+type testRow struct {
+	pk int32
+	c  string
+}
+
+func (ts testSource) writeRows(rows ...testRow) mysql.BinlogEvent {
+	r := mysql.Rows{DataColumns: allColumns()}
+	for _, row := range rows {
+		nulls, data := encodeRow(row.pk, row.c)
+		r.Rows = append(r.Rows, mysql.Row{NullColumns: nulls, Data: data})
+	}
+	return mysql.NewWriteRowsEvent(ts.f, ts.s, 1, r)
+}
+
+func (ts testSource) updateRow(old, new testRow) mysql.BinlogEvent {
+	oldNulls, oldData := encodeRow(old.pk, old.c)
+	newNulls, newData := encodeRow(new.pk, new.c)
+	return mysql.NewUpdateRowsEvent(ts.f, ts.s, 1, mysql.Rows{
+		IdentifyColumns: allColumns(),
+		DataColumns:     allColumns(),
+		Rows: []mysql.Row{{
+			NullIdentifyColumns: oldNulls,
+			Identify:            oldData,
+			NullColumns:         newNulls,
+			Data:                newData,
+		}},
+	})
+}
+
+func (ts testSource) deleteRow(old testRow) mysql.BinlogEvent {
+	nulls, data := encodeRow(old.pk, old.c)
+	return mysql.NewDeleteRowsEvent(ts.f, ts.s, 1, mysql.Rows{
+		IdentifyColumns: allColumns(),
+		Rows:            []mysql.Row{{NullIdentifyColumns: nulls, Identify: data}},
+	})
+}
+
+type testReplica struct {
+	engine    *gms.Engine
+	ctx       *sql.Context
+	applier   *applier
+	positions []mysql.Position
+}
+
+func newTestReplica(t *testing.T) *testReplica {
+	engine, sqlCtx := newTestEngine(t, dtestutils.CreateTestEnv(), "replica")
+	r := &testReplica{engine: engine, ctx: sqlCtx}
+	r.applier = newApplier(sqlCtx, r.engine, testSourceID, mysql.Position{}, 0, func(pos mysql.Position, seq uint64) error {
+		r.positions = append(r.positions, pos)
+		return nil
+	})
+	return r
+}
+
+// newTestEngine returns an engine serving the database testDB of |dEnv|, and an autocommit context of |user| in it.
+func newTestEngine(t *testing.T, dEnv *env.DoltEnv, user string) (*gms.Engine, *sql.Context) {
+	sqle.AddDoltSystemVariables()
+	ctx := context.Background()
+
+	opts := editor.Options{Deaf: dEnv.DbEaFactory(), Tempdir: dEnv.TempTableFilesDir()}
+	db, err := sqle.NewDatabase(ctx, testDB, dEnv.DbData(), opts)
+	require.NoError(t, err)
+	pro, err := sqle.NewDoltDatabaseProviderWithDatabase("main", dEnv.FS, db, dEnv.FS)
+	require.NoError(t, err)
+
+	state, err := sqle.GetInitialDBState(ctx, db)
+	require.NoError(t, err)
+	sess, err := dsess.NewDoltSession(sql.NewEmptyContext(), sql.NewBaseSession(), pro, config.NewMapConfig(map[string]string{
+		env.UserNameKey:  user,
+		env.UserEmailKey: user + "@example.com",
+	}), state)
+	require.NoError(t, err)
+	sqlCtx := sql.NewContext(ctx, sql.WithSession(sess))
+	sqlCtx.SetCurrentDatabase(testDB)
+	require.NoError(t, sqlCtx.SetSessionVariable(sqlCtx, sql.AutoCommitSessionVar, true))
+
+	return gms.NewDefault(pro), sqlCtx
+}
+
+func (r *testReplica) apply(t *testing.T, events ...mysql.BinlogEvent) {
+	for _, ev := range events {
+		require.NoError(t, r.applier.apply(ev))
+	}
+}
+
+func (r *testReplica) query(t *testing.T, query string) []sql.Row {
+	_, iter, err := r.engine.Query(r.ctx, query)
+	require.NoError(t, err)
+	rows, err := sql.RowIterToRows(r.ctx, nil, iter)
+	require.NoError(t, err)
+	return rows
+}
+
+func createTestTable(t *testing.T, r *testReplica, ts testSource) {
+	r.apply(t, ts.formatDescription(), ts.query(testDB, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))"))
+}
+
+func TestApplierRows(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	createTestTable(t, r, ts)
+
+	r.apply(t,
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}, testRow{2, "two"}, testRow{3, ""}),
+		ts.xid(),
+	)
+	assert.Equal(t, []sql.Row{{int32(1), "one"}, {int32(2), "two"}, {int32(3), nil}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+
+	r.apply(t,
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.updateRow(testRow{2, "two"}, testRow{2, "deux"}),
+		ts.deleteRow(testRow{1, "one"}),
+		ts.query(testDB, "COMMIT"),
+	)
+	assert.Equal(t, []sql.Row{{int32(2), "deux"}, {int32(3), nil}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+
+	// the CREATE TABLE and both transactions
+	assert.Len(t, r.positions, 3)
+}
+
+func TestApplierAbort(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	createTestTable(t, r, ts)
+
+	r.apply(t,
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+	)
+	require.NoError(t, r.applier.abort())
+	assert.Empty(t, r.query(t, "SELECT * FROM t"))
+	assert.Len(t, r.positions, 1)
+
+	// an event for a table the replica doesn't have fails
+	r.apply(t, ts.query(testDB, "DROP TABLE t"), ts.query(testDB, "BEGIN"), ts.tableMap())
+	assert.Error(t, r.applier.apply(ts.writeRows(testRow{1, "one"})))
+}
+
+func TestApplierDoltCommit(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	require.NoError(t, r.ctx.SetSessionVariable(r.ctx, dsess.DoltCommitOnTransactionCommit, true))
+	createTestTable(t, r, ts)
+	commits := len(r.query(t, "SELECT * FROM dolt_log"))
+
+	r.apply(t,
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+		ts.xid(),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{2, "two"}),
+		ts.xid(),
+	)
+	assert.Len(t, r.query(t, "SELECT * FROM dolt_log"), commits+2)
+}
+
+func TestApplierGTIDPosition(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	sid, err := mysql.ParseSID("00010203-0405-0607-0809-0a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	r.apply(t,
+		ts.formatDescription(),
+		ts.gtid(sid, 1),
+		ts.query(testDB, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))"),
+		ts.gtid(sid, 2),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+	)
+	require.Len(t, r.positions, 1)
+	assert.Equal(t, "00010203-0405-0607-0809-0a0b0c0d0e0f:1", r.positions[0].String())
+	assert.Equal(t, "00010203-0405-0607-0809-0a0b0c0d0e0f:1-2", r.applier.pending.String())
+
+	r.apply(t, ts.xid())
+	require.Len(t, r.positions, 2)
+	assert.Equal(t, "00010203-0405-0607-0809-0a0b0c0d0e0f:1-2", r.positions[1].String())
+}
+
+func TestApplierPositionTable(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	sid, err := mysql.ParseSID("00010203-0405-0607-0809-0a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	r.apply(t,
+		ts.formatDescription(),
+		ts.gtid(sid, 1),
+		ts.query(testDB, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))"),
+	)
+	assert.Equal(t, []sql.Row{{"", testSourceID, uint64(1), "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1"}},
+		r.query(t, "SELECT * FROM dolt_replica_position"))
+
+	// the position is written in the transaction of the replica, and rolled back with it
+	r.apply(t,
+		ts.gtid(sid, 2),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+	)
+	require.NoError(t, r.applier.abort())
+	assert.Equal(t, []sql.Row{{uint64(1), "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1"}},
+		r.query(t, "SELECT seq, position FROM dolt_replica_position"))
+
+	r.apply(t,
+		ts.gtid(sid, 2),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+		ts.xid(),
+	)
+	assert.Equal(t, []sql.Row{{uint64(2), "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1-2"}},
+		r.query(t, "SELECT seq, position FROM dolt_replica_position"))
+}
@@ -0,0 +1,556 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dolthub/vitess/go/mysql"
+)
+
+const (
+	clientProtocol41 = 0x00000200
+	clientSSL        = 0x00000800
+
+	comRegisterSlave = 0x15
+
+	// dumpNonBlockFlag makes a dump end once it reaches the end of the binlog instead of waiting for new events.
+	dumpNonBlockFlag = 0x01
+
+	maxPacketSize = 1<<24 - 1
+
+	// errSourceFatalReadingBinlog is the MySQL error a dump fails with, ER_SOURCE_FATAL_ERROR_READING_BINLOG.
+	errSourceFatalReadingBinlog = 1236
+
+	dumpHeartbeatPeriod = 10 * time.Second
+	dumpWriteTimeout    = time.Minute
+)
+
+// binlogListener is a net.Listener whose connections serve the binlog of a primary to its replicas. vitess answers
+// the replication commands of MySQL's protocol with errors, so the connections read every packet the client sends
+// before vitess does, and handle the commands which register a replica and dump the binlog themselves. Everything
+// else, including the authentication of the client, is left to vitess, and commands are only handled once the
+// binlogHandler of the connection saw vitess authenticate it.
+type binlogListener struct {
+	net.Listener
+	c *PrimaryController
+}
+
+func (l binlogListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &binlogConn{Conn: conn, c: l.c}, nil
+}
+
+// binlogConn is a connection of a binlogListener.
+type binlogConn struct {
+	net.Conn
+	c *PrimaryController
+
+	// handshook is true once the client sent its handshake response.
+	handshook bool
+	// passthrough is true for connections which are encrypted, whose packets can't be read, and for clients too old
+	// to be replicas.
+	passthrough bool
+	// authenticated is true once vitess authenticated the client as |user|. Until then, every packet is left to
+	// vitess.
+	authenticated bool
+	user          string
+	// continued is true if the last packet was followed by another packet of the same command.
+	continued bool
+	// pending is the rest of the last packet, which hasn't been read by vitess yet.
+	pending []byte
+}
+
+func (bc *binlogConn) Read(p []byte) (int, error) {
+	for len(bc.pending) == 0 {
+		if bc.passthrough {
+			return bc.Conn.Read(p)
+		}
+		packet, err := bc.readPacket()
+		if err != nil {
+			return 0, err
+		}
+		handled, err := bc.handle(packet)
+		if err != nil {
+			return 0, err
+		}
+		if !handled {
+			bc.pending = packet
+		}
+	}
+	n := copy(p, bc.pending)
+	bc.pending = bc.pending[n:]
+	return n, nil
+}
+
+// readPacket reads the next packet the client sent, with its header.
+func (bc *binlogConn) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(bc.Conn, header); err != nil {
+		return nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	packet := make([]byte, 4+length)
+	copy(packet, header)
+	if _, err := io.ReadFull(bc.Conn, packet[4:]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// handle handles |packet| if it is a replication command, and returns whether it did. The connection is closed
+// once a dump ends.
+func (bc *binlogConn) handle(packet []byte) (bool, error) {
+	seq, payload := packet[3], packet[4:]
+	continued := bc.continued
+	bc.continued = len(payload) == maxPacketSize
+
+	if !bc.handshook {
+		bc.handshook = true
+		if len(payload) < 32 {
+			bc.passthrough = true
+			return false, nil
+		}
+		caps := binary.LittleEndian.Uint32(payload[0:4])
+		if caps&clientProtocol41 == 0 || caps&clientSSL != 0 {
+			bc.passthrough = true
+		}
+		return false, nil
+	}
+
+	if !bc.authenticated || seq != 0 || continued || len(payload) == 0 {
+		return false, nil
+	}
+	switch payload[0] {
+	case comRegisterSlave:
+		if !bc.c.canReplicate(bc.user, bc.remoteHost()) {
+			return true, writeErrorPacket(bc.Conn, 1, mysql.ERSpecifiedAccessDenied, "Access denied; you need (at least one of) the REPLICATION SLAVE privilege(s) for this operation")
+		}
+		// an OK packet with the autocommit status
+		return true, writePacket(bc.Conn, 1, []byte{mysql.OKPacket, 0, 0, 2, 0, 0, 0})
+	case mysql.ComBinlogDump, mysql.ComBinlogDumpGTID:
+		if !bc.c.canReplicate(bc.user, bc.remoteHost()) {
+			err := writeErrorPacket(bc.Conn, 1, mysql.ERSpecifiedAccessDenied, "Access denied; you need (at least one of) the REPLICATION SLAVE privilege(s) for this operation")
+			if err != nil {
+				return true, err
+			}
+			return true, io.EOF
+		}
+		bc.c.serveDump(bc.Conn, payload)
+		return true, io.EOF
+	}
+	return false, nil
+}
+
+// binlogHandler is the mysql.Handler of the connections of a binlogListener.
+type binlogHandler struct {
+	mysql.Handler
+}
+
+// ComInitDB implements mysql.Handler. vitess calls it when a client is authenticated, before it answers the
+// handshake with an OK packet, and for each COM_INIT_DB after that.
+func (h binlogHandler) ComInitDB(c *mysql.Conn, schemaName string) error {
+	if err := h.Handler.ComInitDB(c, schemaName); err != nil {
+		return err
+	}
+	if bc, ok := c.Conn.(*binlogConn); ok {
+		bc.authenticated, bc.user = true, c.User
+	}
+	return nil
+}
+
+func (bc *binlogConn) remoteHost() string {
+	host, _, err := net.SplitHostPort(bc.RemoteAddr().String())
+	if err != nil {
+		return "localhost"
+	}
+	return host
+}
+
+// dumpRequest is a COM_BINLOG_DUMP or COM_BINLOG_DUMP_GTID command.
+type dumpRequest struct {
+	file  string
+	pos   uint64
+	flags uint16
+	// gtids are the GTIDs the replica executed, if it positions itself with GTIDs.
+	gtids mysql.Mysql56GTIDSet
+}
+
+func parseDumpRequest(payload []byte) (dumpRequest, error) {
+	var req dumpRequest
+	if payload[0] == mysql.ComBinlogDump {
+		if len(payload) < 11 {
+			return req, errors.New("malformed COM_BINLOG_DUMP")
+		}
+		req.pos = uint64(binary.LittleEndian.Uint32(payload[1:5]))
+		req.flags = binary.LittleEndian.Uint16(payload[5:7])
+		req.file = string(payload[11:])
+		return req, nil
+	}
+
+	if len(payload) < 11 {
+		return req, errors.New("malformed COM_BINLOG_DUMP_GTID")
+	}
+	req.flags = binary.LittleEndian.Uint16(payload[1:3])
+	nameLen := int(binary.LittleEndian.Uint32(payload[7:11]))
+	pos := 11 + nameLen
+	if len(payload) < pos+12 {
+		return req, errors.New("malformed COM_BINLOG_DUMP_GTID")
+	}
+	req.file = string(payload[11:pos])
+	req.pos = binary.LittleEndian.Uint64(payload[pos : pos+8])
+	dataLen := int(binary.LittleEndian.Uint32(payload[pos+8 : pos+12]))
+	if len(payload) < pos+12+dataLen {
+		return req, errors.New("malformed COM_BINLOG_DUMP_GTID")
+	}
+	var err error
+	req.gtids, err = mysql.NewMysql56GTIDSetFromSIDBlock(payload[pos+12 : pos+12+dataLen])
+	return req, err
+}
+
+// binlogDump streams the binlog of a primary to a replica.
+type binlogDump struct {
+	c    *PrimaryController
+	conn net.Conn
+	seq  byte
+	// gone is closed when the replica closes the connection.
+	gone chan struct{}
+}
+
+// serveDump streams the binlog to the replica on |conn| as requested by |payload|, until the replica disconnects or
+// the primary is closed. Errors are sent to the replica.
+func (c *PrimaryController) serveDump(conn net.Conn, payload []byte) {
+	d := &binlogDump{c: c, conn: conn, seq: 1, gone: make(chan struct{})}
+	if !c.addDump(d) {
+		return
+	}
+	defer c.removeDump(d)
+
+	// the connection stays idle while the replica waits for events, and the replica sends nothing more
+	_ = conn.SetDeadline(time.Time{})
+	go func() {
+		defer close(d.gone)
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	req, err := parseDumpRequest(payload)
+	if err == nil {
+		err = d.stream(req)
+	}
+	var sqlErr *mysql.SQLError
+	if errors.As(err, &sqlErr) {
+		_ = writeErrorPacket(conn, d.seq, sqlErr.Number(), sqlErr.Message)
+	} else if err != nil && !errors.Is(err, errDumpEnded) {
+		c.lgr.Warnf("error sending the binlog to replica %s: %v", conn.RemoteAddr(), err)
+		_ = writeErrorPacket(conn, d.seq, errSourceFatalReadingBinlog, err.Error())
+	}
+}
+
+// errDumpEnded is returned when a dump ends because the replica disconnected or the primary was closed.
+var errDumpEnded = errors.New("binlog dump ended")
+
+func binlogError(format string, args ...interface{}) error {
+	return mysql.NewSQLError(errSourceFatalReadingBinlog, mysql.SSUnknownSQLState, format, args...)
+}
+
+func (d *binlogDump) stream(req dumpRequest) error {
+	if err := d.c.failed(); err != nil {
+		return err
+	}
+	files, _, _ := d.c.files.status()
+	file, pos := req.file, req.pos
+	skip := req.gtids
+	if req.gtids != nil {
+		var err error
+		if file, err = d.gtidStartFile(files, req.gtids); err != nil {
+			return err
+		}
+		pos = firstBinlogEventOffset
+	} else if file == "" {
+		file = files[0]
+	}
+	if indexOf(files, file) < 0 {
+		return binlogError("Could not find first log file name in binary log index file")
+	}
+	if pos < firstBinlogEventOffset {
+		pos = firstBinlogEventOffset
+	}
+
+	err := d.sendEvent(d.artificialEvent(rotateEventType, rotateBody(file, pos)))
+	if err != nil {
+		return err
+	}
+	if pos > firstBinlogEventOffset {
+		// replicas need the format of the binlog, which is only at the start of the file
+		fde, err := readFormatDescription(d.c.files.dir, file)
+		if err != nil {
+			return err
+		}
+		fde = append(binlogEvent(nil), fde...)
+		fde.setNextPosition(0)
+		if err = d.sendEvent(fde); err != nil {
+			return err
+		}
+	}
+
+	var tx transactionTracker
+	skipping := false
+	for {
+		next, end, err := d.streamFile(file, int64(pos), func(ev binlogEvent) error {
+			ended := tx.next(ev)
+			if ev.eventType() == gtidEventType && skip != nil {
+				skipping = skip.ContainsGTID(tx.gtid)
+			}
+			if skipping {
+				skipping = !ended
+				return nil
+			}
+			return d.sendEvent(ev)
+		})
+		if err != nil {
+			return err
+		}
+		if next != "" {
+			file, pos = next, firstBinlogEventOffset
+			continue
+		}
+
+		// the dump reached the end of the active file, which doesn't grow once the binlog failed
+		if err = d.c.failed(); err != nil {
+			return err
+		}
+		if req.flags&dumpNonBlockFlag != 0 {
+			return writePacket(d.conn, d.seq, []byte{mysql.EOFPacket, 0, 0, 0, 0})
+		}
+		pos = uint64(end)
+		if err = d.wait(file, end); err != nil {
+			return err
+		}
+	}
+}
+
+// streamFile sends the events of |file| from |pos| on with |send|. It returns the file the binlog continues in if
+// |file| isn't the active file, and otherwise the end of the events sent.
+func (d *binlogDump) streamFile(file string, pos int64, send func(ev binlogEvent) error) (next string, end int64, err error) {
+	files, size, _ := d.c.files.status()
+	i := indexOf(files, file)
+	if i < 0 {
+		return "", 0, binlogError("binary log %s was removed", file)
+	}
+	active := i == len(files)-1
+
+	f, err := os.Open(filepath.Join(d.c.files.dir, file))
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	if _, err = f.Seek(pos, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	var r io.Reader = f
+	if active {
+		r = io.LimitReader(f, size-pos)
+	}
+	br := bufio.NewReader(r)
+
+	for {
+		ev, err := readEvent(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", 0, binlogError("could not read binary log %s at %d: %v", file, pos, err)
+		}
+		pos += int64(len(ev))
+		if err = send(ev); err != nil {
+			return "", 0, err
+		}
+		if ev.eventType() == rotateEventType {
+			return string(ev.body()[8:]), pos, nil
+		}
+	}
+
+	if !active {
+		// the file ended without a rotate event, the binlog continues in the next file
+		next = files[i+1]
+		err = d.sendEvent(d.artificialEvent(rotateEventType, rotateBody(next, firstBinlogEventOffset)))
+		return next, pos, err
+	}
+	return "", pos, nil
+}
+
+// wait returns once the binlog grows past |end| of |file|, or the active file changes, sending heartbeats to the
+// replica meanwhile.
+func (d *binlogDump) wait(file string, end int64) error {
+	heartbeat := time.NewTicker(dumpHeartbeatPeriod)
+	defer heartbeat.Stop()
+	for {
+		files, size, changed := d.c.files.status()
+		if files[len(files)-1] != file || size > end {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-d.gone:
+			return errDumpEnded
+		case <-heartbeat.C:
+			ev := newBinlogEvent(heartbeatEventType, artificialEventFlag, 0, serverID(), heartbeatBody(file))
+			ev.setNextPosition(uint32(end))
+			if err := d.sendEvent(ev); err != nil {
+				return err
+			}
+		}
+		if d.c.isClosed() {
+			return errDumpEnded
+		}
+		if err := d.c.failed(); err != nil {
+			return err
+		}
+	}
+}
+
+// gtidStartFile returns the file a replica which executed |gtids| starts at: the last file whose previous GTIDs it
+// has all executed.
+func (d *binlogDump) gtidStartFile(files []string, gtids mysql.Mysql56GTIDSet) (string, error) {
+	sid := d.c.files.sid
+	own := mysql.Mysql56GTIDSet{}
+	if intervals, ok := gtids[sid]; ok {
+		own[sid] = intervals
+	}
+	if !d.c.files.executedGTIDs().Contains(own) {
+		return "", binlogError("Cannot replicate because the source has fewer transactions than the replica: the replica executed GTIDs %s which the source does not have", own.String())
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		prev, err := readPreviousGTIDs(d.c.files.dir, files[i])
+		if err != nil {
+			return "", err
+		}
+		if gtids.Contains(prev) {
+			return files[i], nil
+		}
+	}
+	return "", binlogError("Cannot replicate because the source purged required binary logs")
+}
+
+func (d *binlogDump) artificialEvent(typ byte, body []byte) binlogEvent {
+	return newBinlogEvent(typ, artificialEventFlag, 0, serverID(), body)
+}
+
+func (d *binlogDump) sendEvent(ev binlogEvent) error {
+	if d.c.isClosed() {
+		return errDumpEnded
+	}
+	payload := make([]byte, 1+len(ev))
+	copy(payload[1:], ev)
+	err := writePacket(d.conn, d.seq, payload)
+	d.seq += byte(len(payload)/maxPacketSize + 1)
+	select {
+	case <-d.gone:
+		return errDumpEnded
+	default:
+	}
+	return err
+}
+
+// readFormatDescription returns the format description event at the start of the binlog file |file|.
+func readFormatDescription(dir, file string) (binlogEvent, error) {
+	events, err := readHeaderEvents(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// readPreviousGTIDs returns the GTIDs of the transactions before the binlog file |file|.
+func readPreviousGTIDs(dir, file string) (mysql.Mysql56GTIDSet, error) {
+	events, err := readHeaderEvents(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.NewMysql56GTIDSetFromSIDBlock(events[1].body())
+}
+
+// readHeaderEvents returns the format description and previous GTIDs events every binlog file starts with.
+func readHeaderEvents(dir, file string) ([]binlogEvent, error) {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if _, err = r.Discard(len(binlogMagic)); err != nil {
+		return nil, err
+	}
+	events := make([]binlogEvent, 2)
+	for i, typ := range []byte{formatDescriptionEventType, previousGTIDsEventType} {
+		if events[i], err = readEvent(r); err != nil {
+			return nil, fmt.Errorf("binary log %s: %w", file, err)
+		}
+		if events[i].eventType() != typ {
+			return nil, fmt.Errorf("binary log %s: unexpected event type %d", file, events[i].eventType())
+		}
+	}
+	return events, nil
+}
+
+// writePacket writes |payload| to |conn| in packets numbered from |seq| on.
+func writePacket(conn net.Conn, seq byte, payload []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(dumpWriteTimeout)); err != nil {
+		return err
+	}
+	for {
+		n := len(payload)
+		if n > maxPacketSize {
+			n = maxPacketSize
+		}
+		header := []byte{byte(n), byte(n >> 8), byte(n >> 16), seq}
+		if _, err := conn.Write(append(header, payload[:n]...)); err != nil {
+			return err
+		}
+		payload = payload[n:]
+		seq++
+		// a packet of the maximum size is followed by another one, even if it is empty
+		if n < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+func writeErrorPacket(conn net.Conn, seq byte, code int, msg string) error {
+	payload := []byte{mysql.ErrPacket, byte(code), byte(code >> 8), '#'}
+	payload = append(payload, mysql.SSUnknownSQLState...)
+	return writePacket(conn, seq, append(payload, msg...))
+}
+
+func indexOf(files []string, file string) int {
+	for i, f := range files {
+		if f == file {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+)
+
+// The types of the binlog events a primary writes, as numbered by MySQL.
+const (
+	queryEventType             = 2
+	rotateEventType            = 4
+	formatDescriptionEventType = 15
+	xidEventType               = 16
+	tableMapEventType          = 19
+	heartbeatEventType         = 27
+	rowsQueryEventType         = 29
+	writeRowsEventType         = 30
+	updateRowsEventType        = 31
+	deleteRowsEventType        = 32
+	gtidEventType              = 33
+	previousGTIDsEventType     = 35
+)
+
+const (
+	eventHeaderLength   = 19
+	eventChecksumLength = 4
+
+	// artificialEventFlag marks events which are sent to replicas but aren't in a binlog file, like the rotate event
+	// a dump starts with. Replicas don't update their position for them.
+	artificialEventFlag = 0x20
+	// ignorableEventFlag marks events which replicas may ignore if they don't know them.
+	ignorableEventFlag = 0x80
+
+	// stmtEndRowsFlag marks the last rows event of a statement.
+	stmtEndRowsFlag = 0x01
+	// noForeignKeyChecksRowsFlag makes replicas apply a rows event without checking foreign keys.
+	noForeignKeyChecksRowsFlag = 0x02
+
+	// noForeignKeyChecksQueryFlag is the flag of the flags2 status variable of query events which disables foreign key
+	// checks.
+	noForeignKeyChecksQueryFlag = 1 << 26
+
+	// binlogServerVersion is the MySQL version a binlog is written by, which replicas check for compatibility.
+	binlogServerVersion = "8.0.31"
+)
+
+// binlogMagic starts every binlog file.
+var binlogMagic = []byte{0xfe, 'b', 'i', 'n'}
+
+// eventPostHeaderLengths are the lengths of the fixed parts of the bodies of the events of a MySQL 8.0 binlog,
+// indexed by event type - 1. Replicas read them from the format description event of a binlog.
+var eventPostHeaderLengths = []byte{
+	56, 13, 0, 8, 0, 18, 0, 4, 4, 4,
+	4, 18, 0, 0, 98, 0, 4, 26, 8, 0,
+	0, 0, 8, 8, 8, 2, 0, 0, 0, 10,
+	10, 10, 42, 42, 0, 18, 52, 0, 10, 0,
+	0,
+}
+
+// binlogEvent is an encoded binlog event: its header, its body and the CRC32 checksum of both.
+type binlogEvent []byte
+
+// newBinlogEvent returns the event of |typ| with |body|, written at |timestamp| by the server |serverID|. Its position
+// is set when it is written to a binlog file.
+func newBinlogEvent(typ byte, flags uint16, timestamp, serverID uint32, body []byte) binlogEvent {
+	ev := make(binlogEvent, eventHeaderLength+len(body)+eventChecksumLength)
+	binary.LittleEndian.PutUint32(ev[0:4], timestamp)
+	ev[4] = typ
+	binary.LittleEndian.PutUint32(ev[5:9], serverID)
+	binary.LittleEndian.PutUint32(ev[9:13], uint32(len(ev)))
+	binary.LittleEndian.PutUint16(ev[17:19], flags)
+	copy(ev[eventHeaderLength:], body)
+	ev.setChecksum()
+	return ev
+}
+
+func (ev binlogEvent) eventType() byte {
+	return ev[4]
+}
+
+func (ev binlogEvent) flags() uint16 {
+	return binary.LittleEndian.Uint16(ev[17:19])
+}
+
+// body returns the body of the event, without its header and checksum.
+func (ev binlogEvent) body() []byte {
+	return ev[eventHeaderLength : len(ev)-eventChecksumLength]
+}
+
+// nextPosition returns the offset in its binlog file of the event after this one.
+func (ev binlogEvent) nextPosition() uint32 {
+	return binary.LittleEndian.Uint32(ev[13:17])
+}
+
+// setNextPosition sets the offset in its binlog file of the event after this one. Events which aren't in a binlog
+// file have a next position of 0.
+func (ev binlogEvent) setNextPosition(pos uint32) {
+	binary.LittleEndian.PutUint32(ev[13:17], pos)
+	ev.setChecksum()
+}
+
+func (ev binlogEvent) setChecksum() {
+	n := len(ev) - eventChecksumLength
+	binary.LittleEndian.PutUint32(ev[n:], crc32.ChecksumIEEE(ev[:n]))
+}
+
+// checksumValid returns whether the checksum of the event matches its header and body.
+func (ev binlogEvent) checksumValid() bool {
+	n := len(ev) - eventChecksumLength
+	return binary.LittleEndian.Uint32(ev[n:]) == crc32.ChecksumIEEE(ev[:n])
+}
+
+// formatDescriptionBody returns the body of the format description event every binlog file starts with.
+func formatDescriptionBody(created uint32) []byte {
+	body := make([]byte, 2+50+4+1, 2+50+4+1+len(eventPostHeaderLengths)+1)
+	binary.LittleEndian.PutUint16(body[0:2], 4)
+	copy(body[2:52], binlogServerVersion)
+	binary.LittleEndian.PutUint32(body[52:56], created)
+	body[56] = eventHeaderLength
+	body = append(body, eventPostHeaderLengths...)
+	return append(body, mysql.BinlogChecksumAlgCRC32)
+}
+
+// previousGTIDsBody returns the body of the event which follows the format description event of a binlog file. It
+// holds the GTIDs of the transactions in the binlog files before it.
+func previousGTIDsBody(gtids mysql.Mysql56GTIDSet) []byte {
+	return gtids.SIDBlock()
+}
+
+// gtidBody returns the body of the event a transaction starts with. |seq| numbers the transactions of a binlog
+// file, each transaction is committed after the one before it.
+func gtidBody(gtid mysql.Mysql56GTID, seq int64, ddl bool) []byte {
+	body := make([]byte, 42)
+	if ddl {
+		// the transaction may have statements which aren't row-based
+		body[0] = 1
+	}
+	copy(body[1:17], gtid.Server[:])
+	binary.LittleEndian.PutUint64(body[17:25], uint64(gtid.Sequence))
+	// logical timestamps
+	body[25] = 2
+	binary.LittleEndian.PutUint64(body[26:34], uint64(seq-1))
+	binary.LittleEndian.PutUint64(body[34:42], uint64(seq))
+	return body
+}
+
+// queryBody returns the body of an event which executes |query| in |db|. The query is executed with the default
+// collation of Dolt and without checking foreign keys, since tables are written in the order of their names rather
+// than in the order of their references.
+func queryBody(db, query string) []byte {
+	const (
+		flags2StatusVar  = 0
+		sqlModeStatusVar = 1
+		charsetStatusVar = 4
+	)
+	var vars []byte
+	vars = append(vars, flags2StatusVar)
+	vars = binary.LittleEndian.AppendUint32(vars, noForeignKeyChecksQueryFlag)
+	vars = append(vars, sqlModeStatusVar)
+	vars = binary.LittleEndian.AppendUint64(vars, 0)
+	collation := uint16(sql.Collation_Default)
+	vars = append(vars, charsetStatusVar)
+	vars = binary.LittleEndian.AppendUint16(vars, collation)
+	vars = binary.LittleEndian.AppendUint16(vars, collation)
+	vars = binary.LittleEndian.AppendUint16(vars, collation)
+
+	body := make([]byte, 4+4+1+2+2, 4+4+1+2+2+len(vars)+len(db)+1+len(query))
+	body[8] = byte(len(db))
+	binary.LittleEndian.PutUint16(body[11:13], uint16(len(vars)))
+	body = append(body, vars...)
+	body = append(body, db...)
+	body = append(body, 0)
+	return append(body, query...)
+}
+
+// xidBody returns the body of the event a transaction with row events commits with.
+func xidBody(xid uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, xid)
+}
+
+// rotateBody returns the body of an event which tells replicas that the binlog continues in |file| at |pos|.
+func rotateBody(file string, pos uint64) []byte {
+	body := binary.LittleEndian.AppendUint64(nil, pos)
+	return append(body, file...)
+}
+
+// rowsQueryBody returns the body of an informational event which replicas ignore.
+func rowsQueryBody(text string) []byte {
+	l := len(text)
+	if l > 255 {
+		l = 255
+	}
+	return append([]byte{byte(l)}, text...)
+}
+
+// heartbeatBody returns the body of the event sent to replicas which are waiting for new events, so that they know
+// the connection is alive.
+func heartbeatBody(file string) []byte {
+	return []byte(file)
+}
+
+// appendLenEncInt appends |i| to |buf| as a length-encoded integer.
+func appendLenEncInt(buf []byte, i uint64) []byte {
+	switch {
+	case i < 251:
+		return append(buf, byte(i))
+	case i < 1<<16:
+		return binary.LittleEndian.AppendUint16(append(buf, 0xfc), uint16(i))
+	case i < 1<<24:
+		return append(buf, 0xfd, byte(i), byte(i>>8), byte(i>>16))
+	default:
+		return binary.LittleEndian.AppendUint64(append(buf, 0xfe), i)
+	}
+}
@@ -0,0 +1,541 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dolthub/vitess/go/mysql"
+
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const (
+	binlogFilePrefix = "binlog."
+	binlogIndexFile  = "binlog.index"
+
+	// DefaultMaxBinlogSize is the size at which a primary continues its binlog in a new file.
+	DefaultMaxBinlogSize = 1 << 30
+
+	// maxEventSize bounds the size of the events read from binlog files, so that a corrupt header isn't mistaken for
+	// a huge event.
+	maxEventSize = 1 << 30
+)
+
+// errCorruptEvent is returned when an event of a binlog file is truncated or doesn't match its checksum.
+var errCorruptEvent = errors.New("binlog event is corrupt")
+
+// binlogTransaction is a transaction to append to a binlog. Its GTID event is added when it is appended, along with
+// its XID event if it commits rows.
+type binlogTransaction struct {
+	// events are the events of the transaction after its GTID event.
+	events []binlogEvent
+	// ddl is true if the transaction is a single statement which commits implicitly.
+	ddl bool
+}
+
+// binlogFiles are the binlog files of a primary and the index which lists them in order. Transactions are appended
+// to the last file, the active one, until it grows past the maximum size and the binlog continues in a new file.
+type binlogFiles struct {
+	dir      string
+	maxSize  int64
+	sid      mysql.SID
+	serverID func() uint32
+
+	mu    sync.Mutex
+	files []string
+	// active is the file transactions are appended to, and size is the size of its complete transactions.
+	active *os.File
+	size   int64
+	// seq numbers the transactions of the active file.
+	seq      int64
+	executed mysql.Mysql56GTIDSet
+	// changed is closed and replaced whenever a transaction is appended or the active file changes.
+	changed chan struct{}
+}
+
+// openBinlogFiles opens the binlog files in |dir|, whose transactions have GTIDs of |sid|. The transactions which
+// weren't completely written when the server stopped are removed from the last file. It returns the roots recorded
+// in the binlog, starting with the roots recorded in |checkpoint| as of the start of the file |checkpointFile|.
+func openBinlogFiles(dir string, maxSize int64, sid mysql.SID, serverID func() uint32, checkpointFile string, checkpoint map[string]hash.Hash) (*binlogFiles, map[string]hash.Hash, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBinlogSize
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := readBinlogIndex(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bf := &binlogFiles{
+		dir:      dir,
+		maxSize:  maxSize,
+		sid:      sid,
+		serverID: serverID,
+		files:    files,
+		executed: mysql.Mysql56GTIDSet{},
+		changed:  make(chan struct{}),
+	}
+
+	// the checkpoint is written before the file it is the start of, so it may be newer than every file
+	first := len(files)
+	if checkpointFile == "" {
+		first = 0
+	}
+	for i, f := range files {
+		if f == checkpointFile {
+			first = i
+		}
+	}
+
+	roots := make(map[string]hash.Hash, len(checkpoint))
+	for db, h := range checkpoint {
+		roots[db] = h
+	}
+	for i, f := range files {
+		fileRoots := roots
+		if i < first {
+			fileRoots = nil
+		}
+		last := i == len(files)-1
+		if i < first && !last {
+			continue
+		}
+		executed, err := bf.recoverFile(f, fileRoots, last)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			bf.executed = executed
+		}
+	}
+
+	return bf, roots, nil
+}
+
+// recoverFile reads the binlog file |name|, applying the root markers of its complete transactions to |roots| if it
+// isn't nil. If the file is the |last| one, the incomplete transaction the server stopped in is removed from it and
+// it is ended with a rotate event. It returns the GTIDs of the transactions of the file and the files before it.
+func (bf *binlogFiles) recoverFile(name string, roots map[string]hash.Hash, last bool) (mysql.Mysql56GTIDSet, error) {
+	f, err := os.OpenFile(filepath.Join(bf.dir, name), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(binlogMagic))
+	if _, err = io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, binlogMagic) {
+		return nil, fmt.Errorf("%s is not a binlog file", name)
+	}
+
+	executed := mysql.Mysql56GTIDSet{}
+	var tx transactionTracker
+	var markers []rootMarker
+	var rotated bool
+	offset, complete := int64(len(binlogMagic)), int64(len(binlogMagic))
+	for {
+		ev, err := readEvent(r)
+		if err == io.EOF || errors.Is(err, errCorruptEvent) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		offset += int64(len(ev))
+
+		switch ev.eventType() {
+		case previousGTIDsEventType:
+			if executed, err = mysql.NewMysql56GTIDSetFromSIDBlock(ev.body()); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		case rotateEventType:
+			rotated = true
+		case rowsQueryEventType:
+			if m, ok := parseRootMarker(string(ev.body()[1:])); ok {
+				markers = append(markers, m)
+			}
+		case queryEventType:
+			if _, q := queryEventText(ev.body()); q != "" {
+				if m, ok := parseRootMarker(q); ok {
+					markers = append(markers, m)
+				}
+			}
+		}
+
+		if tx.next(ev) {
+			executed = executed.AddGTID(tx.gtid).(mysql.Mysql56GTIDSet)
+			if roots != nil {
+				for _, m := range markers {
+					roots[m.db] = m.root
+				}
+			}
+			markers = markers[:0]
+		}
+		if !tx.inTransaction() {
+			complete = offset
+		}
+	}
+
+	if last && !rotated {
+		if err = f.Truncate(complete); err != nil {
+			return nil, err
+		}
+		if _, err = f.Seek(complete, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// the binlog continues in the file the server starts next
+		next := binlogFileName(len(bf.files) + 1)
+		ev := newBinlogEvent(rotateEventType, 0, uint32(time.Now().Unix()), bf.serverID(), rotateBody(next, firstBinlogEventOffset))
+		ev.setNextPosition(uint32(complete) + uint32(len(ev)))
+		if _, err = f.Write(ev); err != nil {
+			return nil, err
+		}
+		if err = f.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	return executed, nil
+}
+
+// startFile continues the binlog in a new file. |checkpoint| is called with the name of the file before it is
+// created, and must persist the roots recorded so far. The caller holds |bf.mu| if the binlog is being written.
+func (bf *binlogFiles) startFile(checkpoint func(file string) error) error {
+	name := binlogFileName(len(bf.files) + 1)
+	if bf.active != nil {
+		ev := newBinlogEvent(rotateEventType, 0, uint32(time.Now().Unix()), bf.serverID(), rotateBody(name, firstBinlogEventOffset))
+		ev.setNextPosition(uint32(bf.size) + uint32(len(ev)))
+		if _, err := bf.active.Write(ev); err != nil {
+			return err
+		}
+		if err := bf.active.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if err := checkpoint(name); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(bf.dir, name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	now := uint32(time.Now().Unix())
+	buf := append([]byte{}, binlogMagic...)
+	fde := newBinlogEvent(formatDescriptionEventType, 0, now, bf.serverID(), formatDescriptionBody(now))
+	fde.setNextPosition(uint32(len(buf) + len(fde)))
+	buf = append(buf, fde...)
+	prev := newBinlogEvent(previousGTIDsEventType, 0, now, bf.serverID(), previousGTIDsBody(bf.executed))
+	prev.setNextPosition(uint32(len(buf) + len(prev)))
+	buf = append(buf, prev...)
+	if _, err = f.Write(buf); err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = writeBinlogIndex(bf.dir, append(bf.files, name))
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if bf.active != nil {
+		bf.active.Close()
+	}
+	bf.files = append(bf.files, name)
+	bf.active, bf.size, bf.seq = f, int64(len(buf)), 0
+	bf.notifyLocked()
+	return nil
+}
+
+// appendTransactions appends |txs| to the active file, assigning them the next GTIDs, and syncs it. It continues the
+// binlog in a new file once the active file grows past the maximum size.
+func (bf *binlogFiles) appendTransactions(txs []binlogTransaction, checkpoint func(file string) error) error {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	if bf.active == nil {
+		return errors.New("binlog is closed")
+	}
+
+	now := uint32(time.Now().Unix())
+	serverID := bf.serverID()
+	executed := bf.executed
+	seq := bf.seq
+	pos := bf.size
+	var buf []byte
+	for _, tx := range txs {
+		gtid := mysql.Mysql56GTID{Server: bf.sid, Sequence: nextSequence(executed, bf.sid)}
+		seq++
+		events := append([]binlogEvent{newBinlogEvent(gtidEventType, 0, now, serverID, gtidBody(gtid, seq, tx.ddl))}, tx.events...)
+		if !tx.ddl {
+			events = append(events, newBinlogEvent(xidEventType, 0, now, serverID, xidBody(uint64(gtid.Sequence))))
+		}
+		for _, ev := range events {
+			pos += int64(len(ev))
+			ev.setNextPosition(uint32(pos))
+			buf = append(buf, ev...)
+		}
+		executed = executed.AddGTID(gtid).(mysql.Mysql56GTIDSet)
+	}
+
+	if _, err := bf.active.Write(buf); err != nil {
+		// drops the partial write, so that the next transactions follow the last complete one
+		_ = bf.active.Truncate(bf.size)
+		_, _ = bf.active.Seek(bf.size, io.SeekStart)
+		return err
+	}
+	if err := bf.active.Sync(); err != nil {
+		return err
+	}
+	bf.executed, bf.seq, bf.size = executed, seq, pos
+	bf.notifyLocked()
+
+	if bf.size >= bf.maxSize {
+		return bf.startFile(checkpoint)
+	}
+	return nil
+}
+
+// nextSequence returns the sequence number of the next GTID of |sid| after those in |set|.
+func nextSequence(set mysql.Mysql56GTIDSet, sid mysql.SID) int64 {
+	// the intervals of a set are only exposed by its encoding: the number of SIDs, then for each SID the SID, the
+	// number of its intervals and their bounds, the end of each interval being exclusive
+	block := set.SIDBlock()
+	next := int64(1)
+	pos := 8
+	for n := binary.LittleEndian.Uint64(block[0:8]); n > 0; n-- {
+		var s mysql.SID
+		copy(s[:], block[pos:pos+16])
+		intervals := int(binary.LittleEndian.Uint64(block[pos+16 : pos+24]))
+		pos += 24 + 16*intervals
+		if s == sid && intervals > 0 {
+			next = int64(binary.LittleEndian.Uint64(block[pos-8 : pos]))
+		}
+	}
+	return next
+}
+
+// wake wakes the dumps which wait for the binlog to change, so that they notice it failed.
+func (bf *binlogFiles) wake() {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	bf.notifyLocked()
+}
+
+func (bf *binlogFiles) notifyLocked() {
+	close(bf.changed)
+	bf.changed = make(chan struct{})
+}
+
+// executedGTIDs returns the GTIDs of the transactions in the binlog.
+func (bf *binlogFiles) executedGTIDs() mysql.Mysql56GTIDSet {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	return bf.executed
+}
+
+// status returns the binlog files, the size of the complete transactions of the active file, and a channel which is
+// closed when either changes.
+func (bf *binlogFiles) status() (files []string, size int64, changed <-chan struct{}) {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	return append([]string(nil), bf.files...), bf.size, bf.changed
+}
+
+func (bf *binlogFiles) close() error {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	if bf.active == nil {
+		return nil
+	}
+	err := bf.active.Close()
+	bf.active = nil
+	bf.notifyLocked()
+	return err
+}
+
+func binlogFileName(n int) string {
+	return fmt.Sprintf("%s%06d", binlogFilePrefix, n)
+}
+
+func readBinlogIndex(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, binlogIndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// writeBinlogIndex replaces the index of the binlog files in |dir| with |files|.
+func writeBinlogIndex(dir string, files []string) error {
+	tmp := filepath.Join(dir, binlogIndexFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strings.Join(files, "\n") + "\n")
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, binlogIndexFile))
+}
+
+// readEvent reads the next event of a binlog file from |r|. It returns io.EOF at the end of the file, and
+// errCorruptEvent if the event is truncated or its checksum doesn't match.
+func readEvent(r io.Reader) (binlogEvent, error) {
+	header := make([]byte, eventHeaderLength)
+	if n, err := io.ReadFull(r, header); err == io.EOF {
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%w: truncated after %d bytes", errCorruptEvent, n)
+	} else if err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[9:13])
+	if size < eventHeaderLength+eventChecksumLength || size > maxEventSize {
+		return nil, fmt.Errorf("%w: invalid size %d", errCorruptEvent, size)
+	}
+	ev := make(binlogEvent, size)
+	copy(ev, header)
+	if _, err := io.ReadFull(r, ev[eventHeaderLength:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%w: truncated", errCorruptEvent)
+	} else if err != nil {
+		return nil, err
+	}
+	if !ev.checksumValid() {
+		return nil, fmt.Errorf("%w: checksum mismatch", errCorruptEvent)
+	}
+	return ev, nil
+}
+
+// transactionTracker follows the transactions of a binlog event by event.
+type transactionTracker struct {
+	gtid  mysql.Mysql56GTID
+	inTx  bool
+	begun bool
+}
+
+// next advances the tracker past |ev| and returns whether it completed a transaction.
+func (t *transactionTracker) next(ev binlogEvent) bool {
+	switch ev.eventType() {
+	case gtidEventType:
+		body := ev.body()
+		copy(t.gtid.Server[:], body[1:17])
+		t.gtid.Sequence = int64(binary.LittleEndian.Uint64(body[17:25]))
+		t.inTx, t.begun = true, false
+	case queryEventType:
+		if !t.inTx {
+			return false
+		}
+		_, q := queryEventText(ev.body())
+		switch {
+		case !t.begun && q == "BEGIN":
+			t.begun = true
+		case !t.begun || q == "COMMIT":
+			t.inTx = false
+			return true
+		}
+	case xidEventType:
+		if t.inTx {
+			t.inTx = false
+			return true
+		}
+	}
+	return false
+}
+
+func (t *transactionTracker) inTransaction() bool {
+	return t.inTx
+}
+
+// queryEventText returns the database and the query of the body of a query event.
+func queryEventText(body []byte) (db, query string) {
+	if len(body) < 13 {
+		return "", ""
+	}
+	dbLen := int(body[8])
+	start := 13 + int(binary.LittleEndian.Uint16(body[11:13]))
+	if start+dbLen+1 > len(body) {
+		return "", ""
+	}
+	return string(body[start : start+dbLen]), string(body[start+dbLen+1:])
+}
+
+// rootMarker records that the binlog holds the changes of the database |db| up to its working root |root|. A zero
+// root is the root of an empty database.
+type rootMarker struct {
+	db   string
+	root hash.Hash
+}
+
+const rootMarkerPrefix = "dolt_root "
+
+// text returns the marker as it is written to the binlog, in a rows query event or in a comment of a query event.
+func (m rootMarker) text() string {
+	return rootMarkerPrefix + m.root.String() + " " + m.db
+}
+
+// parseRootMarker returns the root marker in |s|, a rows query or the query of a query event.
+func parseRootMarker(s string) (rootMarker, bool) {
+	if strings.HasSuffix(s, " */") {
+		i := strings.LastIndex(s, "/* "+rootMarkerPrefix)
+		if i < 0 {
+			return rootMarker{}, false
+		}
+		s = s[i+3 : len(s)-3]
+	}
+	if !strings.HasPrefix(s, rootMarkerPrefix) {
+		return rootMarker{}, false
+	}
+	fields := strings.SplitN(s[len(rootMarkerPrefix):], " ", 2)
+	if len(fields) != 2 {
+		return rootMarker{}, false
+	}
+	h, ok := hash.MaybeParse(fields[0])
+	if !ok {
+		return rootMarker{}, false
+	}
+	return rootMarker{db: fields[1], root: h}, true
+}
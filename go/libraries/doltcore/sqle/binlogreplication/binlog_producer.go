@@ -0,0 +1,467 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb/durable"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dtables"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlutil"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/prolly"
+	"github.com/dolthub/dolt/go/store/prolly/tree"
+	"github.com/dolthub/dolt/go/store/val"
+)
+
+// maxRowsEventSize is the size of the rows at which a rows event is split, MySQL's default binlog_row_event_max_size.
+const maxRowsEventSize = 8192
+
+// binlogHook writes the changes of the working set of the default branch of a database to the binlog of a primary.
+// It is executed after every update of the working set, and logs the difference between the working root and the
+// root it logged last.
+type binlogHook struct {
+	c     *PrimaryController
+	db    string
+	ddb   *doltdb.DoltDB
+	wsRef ref.WorkingSetRef
+	out   io.Writer
+}
+
+var _ doltdb.WorkingSetHook = (*binlogHook)(nil)
+
+// Execute implements doltdb.CommitHook
+func (h *binlogHook) Execute(ctx context.Context, ds datas.Dataset, db datas.Database) error {
+	if ds.ID() != h.wsRef.String() {
+		return nil
+	}
+	return h.c.logDatabase(ctx, h)
+}
+
+// HandleError implements doltdb.CommitHook
+func (h *binlogHook) HandleError(ctx context.Context, err error) error {
+	h.c.lgr.Errorf("error writing the binlog of database %s: %v", h.db, err)
+	if h.out != nil {
+		h.out.Write([]byte(err.Error()))
+	}
+	return nil
+}
+
+// SetLogger implements doltdb.CommitHook
+func (h *binlogHook) SetLogger(ctx context.Context, wr io.Writer) error {
+	h.out = wr
+	return nil
+}
+
+// ReplicatesWorkingSets implements doltdb.WorkingSetHook
+func (h *binlogHook) ReplicatesWorkingSets() bool {
+	return true
+}
+
+// binlogGroup builds the transactions which log the changes of a database from one root to another. Every schema
+// change is a transaction of its own, followed by a transaction with the row changes of all tables.
+type binlogGroup struct {
+	db       string
+	now      uint32
+	serverID uint32
+	tableID  func() uint64
+
+	ddl    []string
+	events []binlogEvent
+	// lastRows is the index in |events| of the last rows event of the current table.
+	lastRows int
+}
+
+func newBinlogGroup(db string, serverID uint32, tableID func() uint64) *binlogGroup {
+	return &binlogGroup{db: db, now: uint32(time.Now().Unix()), serverID: serverID, tableID: tableID, lastRows: -1}
+}
+
+func (g *binlogGroup) newEvent(typ byte, flags uint16, body []byte) binlogEvent {
+	return newBinlogEvent(typ, flags, g.now, g.serverID, body)
+}
+
+// transactions returns the transactions of the group. |marker| is written to the last one, so that the root the
+// changes lead to can be recovered from the binlog. A group without changes is a transaction with only the marker.
+func (g *binlogGroup) transactions(marker rootMarker) []binlogTransaction {
+	var txs []binlogTransaction
+	for i, stmt := range g.ddl {
+		stmt = strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+		if i == len(g.ddl)-1 && len(g.events) == 0 {
+			stmt += " /* " + marker.text() + " */"
+		}
+		txs = append(txs, binlogTransaction{
+			events: []binlogEvent{g.newEvent(queryEventType, 0, queryBody(g.db, stmt))},
+			ddl:    true,
+		})
+	}
+	if len(g.events) > 0 || len(g.ddl) == 0 {
+		events := []binlogEvent{
+			g.newEvent(queryEventType, 0, queryBody(g.db, "BEGIN")),
+			g.newEvent(rowsQueryEventType, ignorableEventFlag, rowsQueryBody(marker.text())),
+		}
+		txs = append(txs, binlogTransaction{events: append(events, g.events...)})
+	}
+	return txs
+}
+
+func (g *binlogGroup) empty() bool {
+	return len(g.ddl) == 0 && len(g.events) == 0
+}
+
+// tableRows accumulates the row changes of a table in rows events, which are added to the group as they fill up.
+// Deleted rows are logged before updated rows, and updated rows before written rows, so that rows which take over
+// the unique values of other rows don't conflict with them on replicas.
+type tableRows struct {
+	g        *binlogGroup
+	tableMap binlogEvent
+	// byType holds the rows events of deleted, updated and written rows
+	byType [3]*rowsEvent
+	mapped bool
+}
+
+func (g *binlogGroup) newTableRows(name string, sch sql.Schema) (*tableRows, error) {
+	cols := make([]binlogColumn, len(sch))
+	for i, col := range sch {
+		c, err := newBinlogColumn(col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s of table %s: %w", col.Name, name, err)
+		}
+		cols[i] = c
+	}
+
+	tableID := g.tableID()
+	t := &tableRows{
+		g:        g,
+		tableMap: g.newEvent(tableMapEventType, 0, tableMapBody(tableID, g.db, name, sch, cols)),
+	}
+	for i, typ := range []byte{deleteRowsEventType, updateRowsEventType, writeRowsEventType} {
+		t.byType[i] = newRowsEvent(typ, tableID, cols)
+	}
+	return t, nil
+}
+
+func (t *tableRows) delete(row sql.Row) error {
+	return t.add(0, row, nil)
+}
+
+func (t *tableRows) update(before, after sql.Row) error {
+	return t.add(1, before, after)
+}
+
+func (t *tableRows) write(row sql.Row) error {
+	return t.add(2, nil, row)
+}
+
+func (t *tableRows) add(i int, before, after sql.Row) error {
+	ev := t.byType[i]
+	if err := ev.add(before, after); err != nil {
+		return err
+	}
+	if ev.size() >= maxRowsEventSize {
+		t.flush(ev)
+	}
+	return nil
+}
+
+func (t *tableRows) flush(ev *rowsEvent) {
+	if !t.mapped {
+		t.g.events = append(t.g.events, t.tableMap)
+		t.mapped = true
+	}
+	t.g.lastRows = len(t.g.events)
+	t.g.events = append(t.g.events, t.g.newEvent(ev.typ, 0, ev.body(noForeignKeyChecksRowsFlag)))
+}
+
+// close adds the remaining rows of the table to the group, marking its last rows event as the end of the statement.
+func (t *tableRows) close() {
+	for _, ev := range t.byType {
+		if ev.size() > 0 {
+			t.flush(ev)
+		}
+	}
+	if !t.mapped {
+		return
+	}
+	last := t.g.events[t.g.lastRows]
+	last[eventHeaderLength+6] |= stmtEndRowsFlag
+	last.setChecksum()
+}
+
+// binlogChanges adds the changes of |db| from the root |from| to the root |to| to |g|. Tables which are created or
+// dropped are logged as CREATE TABLE and DROP TABLE statements followed by their rows. The schemas of Dolt tables
+// change in ways which ALTER TABLE statements can't always reproduce, so tables whose schemas change are dropped and
+// created again. Dolt's system tables aren't logged.
+func binlogChanges(ctx context.Context, g *binlogGroup, from, to *doltdb.RootValue) error {
+	deltas, err := diff.GetTableDeltas(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	toSchemas, err := to.GetAllSchemas(ctx)
+	if err != nil {
+		return err
+	}
+
+	var dropped, created, changed []diff.TableDelta
+	for _, td := range deltas {
+		if doltdb.HasDoltPrefix(td.FromName) || doltdb.HasDoltPrefix(td.ToName) {
+			continue
+		}
+		switch {
+		case td.IsDrop():
+			dropped = append(dropped, td)
+		case td.IsAdd():
+			created = append(created, td)
+		default:
+			schemaChanged, err := td.HasSchemaChanged(ctx)
+			if err != nil {
+				return err
+			}
+			if schemaChanged {
+				dropped = append(dropped, td)
+				add := td
+				add.FromName, add.FromTable, add.FromSch, add.FromFks = "", nil, nil, nil
+				// deltas of tables in both roots have no stores, which the rows of a created table are diffed in
+				add.FromVRW, add.FromNodeStore = to.VRW(), to.NodeStore()
+				created = append(created, add)
+			} else {
+				changed = append(changed, td)
+			}
+		}
+	}
+
+	for _, td := range dropped {
+		g.ddl = append(g.ddl, sqlfmt.DropTableStmt(td.FromName))
+	}
+	for _, td := range changed {
+		if td.FromName != td.ToName {
+			// the foreign keys of a table are renamed along with it, rather than dropped and added again
+			fks := make([]doltdb.ForeignKey, len(td.FromFks))
+			for i, fk := range td.FromFks {
+				fk.TableName = td.ToName
+				if fk.ReferencedTableName == td.FromName {
+					fk.ReferencedTableName = td.ToName
+				}
+				fks[i] = fk
+			}
+			td.FromFks = fks
+		}
+		stmts, err := sqle.SqlSchemaDiff(ctx, td, toSchemas)
+		if err != nil {
+			return err
+		}
+		g.ddl = append(g.ddl, stmts...)
+	}
+	for _, td := range referencesFirst(created) {
+		stmts, err := sqle.SqlSchemaDiff(ctx, td, toSchemas)
+		if err != nil {
+			return err
+		}
+		g.ddl = append(g.ddl, stmts...)
+	}
+
+	for _, td := range changed {
+		if err = binlogRowChanges(ctx, g, td); err != nil {
+			return err
+		}
+	}
+	for _, td := range created {
+		if err = binlogRowChanges(ctx, g, td); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencesFirst orders the tables created by |deltas| so that tables come after the tables their foreign keys
+// reference, as far as the references allow.
+func referencesFirst(deltas []diff.TableDelta) []diff.TableDelta {
+	pending := make(map[string]bool, len(deltas))
+	for _, td := range deltas {
+		pending[td.ToName] = true
+	}
+
+	ordered := make([]diff.TableDelta, 0, len(deltas))
+	for len(ordered) < len(deltas) {
+		progress := false
+		for _, td := range deltas {
+			if !pending[td.ToName] {
+				continue
+			}
+			ready := true
+			for _, fk := range td.ToFks {
+				if fk.ReferencedTableName != td.ToName && pending[fk.ReferencedTableName] {
+					ready = false
+				}
+			}
+			if ready {
+				ordered = append(ordered, td)
+				pending[td.ToName] = false
+				progress = true
+			}
+		}
+		if !progress {
+			// the references are circular, the rest is created in the order of its names
+			for _, td := range deltas {
+				if pending[td.ToName] {
+					ordered = append(ordered, td)
+					pending[td.ToName] = false
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// binlogRowChanges adds the row changes of the table of |td| to |g|. The schema of the table is the same at both
+// ends of the delta unless the table is created.
+func binlogRowChanges(ctx context.Context, g *binlogGroup, td diff.TableDelta) error {
+	changed, err := td.HasHashChanged()
+	if err != nil || !changed {
+		return err
+	}
+
+	fromIdx, toIdx, err := td.GetRowData(ctx)
+	if err != nil {
+		return err
+	}
+	sqlSch, err := sqlutil.FromDoltSchema(td.ToName, td.ToSch)
+	if err != nil {
+		return err
+	}
+	t, err := g.newTableRows(td.ToName, sqlSch.Schema)
+	if err != nil {
+		return err
+	}
+
+	toMap := durable.ProllyMapFromIndex(toIdx)
+	conv, err := dtables.NewProllyRowConverter(td.ToSch, td.ToSch, nil, toMap.NodeStore())
+	if err != nil {
+		return err
+	}
+	toRow := func(key, value val.Tuple) (sql.Row, error) {
+		row := make(sql.Row, len(sqlSch.Schema))
+		err := conv.PutConverted(ctx, key, value, row)
+		return row, err
+	}
+	keyless := schema.IsKeyless(td.ToSch)
+
+	if td.FromTable == nil {
+		iter, err := toMap.IterAll(ctx)
+		if err != nil {
+			return err
+		}
+		for {
+			key, value, err := iter.Next(ctx)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			row, err := toRow(key, value)
+			if err != nil {
+				return err
+			}
+			for n := cardinality(keyless, value); n > 0; n-- {
+				if err = t.write(row); err != nil {
+					return err
+				}
+			}
+		}
+		t.close()
+		return nil
+	}
+
+	fromMap := durable.ProllyMapFromIndex(fromIdx)
+	err = prolly.DiffMaps(ctx, fromMap, toMap, func(ctx context.Context, d tree.Diff) error {
+		key := val.Tuple(d.Key)
+		switch d.Type {
+		case tree.AddedDiff:
+			row, err := toRow(key, val.Tuple(d.To))
+			if err != nil {
+				return err
+			}
+			for n := cardinality(keyless, val.Tuple(d.To)); n > 0; n-- {
+				if err = t.write(row); err != nil {
+					return err
+				}
+			}
+		case tree.RemovedDiff:
+			row, err := toRow(key, val.Tuple(d.From))
+			if err != nil {
+				return err
+			}
+			for n := cardinality(keyless, val.Tuple(d.From)); n > 0; n-- {
+				if err = t.delete(row); err != nil {
+					return err
+				}
+			}
+		case tree.ModifiedDiff:
+			if keyless {
+				// the rows are the same, only the number of them changed
+				row, err := toRow(key, val.Tuple(d.To))
+				if err != nil {
+					return err
+				}
+				fromN, toN := cardinality(true, val.Tuple(d.From)), cardinality(true, val.Tuple(d.To))
+				for ; fromN > toN; fromN-- {
+					if err = t.delete(row); err != nil {
+						return err
+					}
+				}
+				for ; toN > fromN; toN-- {
+					if err = t.write(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			before, err := toRow(key, val.Tuple(d.From))
+			if err != nil {
+				return err
+			}
+			after, err := toRow(key, val.Tuple(d.To))
+			if err != nil {
+				return err
+			}
+			return t.update(before, after)
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return err
+	}
+	t.close()
+	return nil
+}
+
+// cardinality returns the number of copies of the row |value| is the value of.
+func cardinality(keyless bool, value val.Tuple) uint64 {
+	if !keyless {
+		return 1
+	}
+	return val.ReadKeylessCardinality(value)
+}
@@ -0,0 +1,670 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	querypb "github.com/dolthub/vitess/go/vt/proto/query"
+	"github.com/shopspring/decimal"
+)
+
+// binlogColumn is the type of a column in the table map and rows events of a binlog.
+type binlogColumn struct {
+	typ  byte
+	meta uint16
+	// realType is the type of the values of TypeString columns, which are CHAR, BINARY, ENUM and SET columns.
+	realType byte
+	// length is the number of bytes of the values of ENUM, SET and BIT columns, and the number of bytes of the length
+	// prefix of the values of the other variable length columns.
+	length int
+	scale  int32
+}
+
+// newBinlogColumn returns the binlog column of a column of |typ|. DATETIME, TIMESTAMP and TIME columns are written
+// without fractional seconds, since the columns of Dolt don't declare a precision and are created on replicas
+// without one.
+func newBinlogColumn(typ sql.Type) (binlogColumn, error) {
+	switch typ.Type() {
+	case querypb.Type_INT8, querypb.Type_UINT8:
+		return binlogColumn{typ: mysql.TypeTiny}, nil
+	case querypb.Type_INT16, querypb.Type_UINT16:
+		return binlogColumn{typ: mysql.TypeShort}, nil
+	case querypb.Type_INT24, querypb.Type_UINT24:
+		return binlogColumn{typ: mysql.TypeInt24}, nil
+	case querypb.Type_INT32, querypb.Type_UINT32:
+		return binlogColumn{typ: mysql.TypeLong}, nil
+	case querypb.Type_INT64, querypb.Type_UINT64:
+		return binlogColumn{typ: mysql.TypeLongLong}, nil
+	case querypb.Type_FLOAT32:
+		return binlogColumn{typ: mysql.TypeFloat, meta: 4}, nil
+	case querypb.Type_FLOAT64:
+		return binlogColumn{typ: mysql.TypeDouble, meta: 8}, nil
+	case querypb.Type_YEAR:
+		return binlogColumn{typ: mysql.TypeYear}, nil
+	case querypb.Type_DATE:
+		return binlogColumn{typ: mysql.TypeDate}, nil
+	case querypb.Type_DATETIME:
+		return binlogColumn{typ: mysql.TypeDateTime2}, nil
+	case querypb.Type_TIMESTAMP:
+		return binlogColumn{typ: mysql.TypeTimestamp2}, nil
+	case querypb.Type_TIME:
+		return binlogColumn{typ: mysql.TypeTime2}, nil
+	case querypb.Type_JSON:
+		return binlogColumn{typ: mysql.TypeJSON, meta: 4, length: 4}, nil
+	case querypb.Type_GEOMETRY:
+		return binlogColumn{typ: mysql.TypeGeometry, meta: 4, length: 4}, nil
+
+	case querypb.Type_DECIMAL:
+		dt := typ.(sql.DecimalType)
+		return binlogColumn{
+			typ:   mysql.TypeNewDecimal,
+			meta:  uint16(dt.Precision())<<8 | uint16(dt.Scale()),
+			scale: int32(dt.Scale()),
+		}, nil
+
+	case querypb.Type_BIT:
+		bits := uint16(typ.(sql.BitType).NumberOfBits())
+		return binlogColumn{typ: mysql.TypeBit, meta: bits/8<<8 | bits%8, length: int(bits+7) / 8}, nil
+
+	case querypb.Type_VARCHAR, querypb.Type_VARBINARY:
+		l := typ.(sql.StringType).MaxByteLength()
+		return binlogColumn{typ: mysql.TypeVarchar, meta: uint16(l), length: lengthPrefixBytes(l)}, nil
+
+	case querypb.Type_CHAR, querypb.Type_BINARY:
+		l := uint16(typ.(sql.StringType).MaxByteLength())
+		return binlogColumn{
+			typ:      mysql.TypeString,
+			meta:     uint16(mysql.TypeString^byte((l&0x300)>>4))<<8 | l&0xff,
+			realType: mysql.TypeString,
+			length:   lengthPrefixBytes(int64(l)),
+		}, nil
+
+	case querypb.Type_TEXT, querypb.Type_BLOB:
+		l := typ.(sql.StringType).MaxByteLength()
+		var n int
+		switch {
+		case l <= math.MaxUint8:
+			n = 1
+		case l <= math.MaxUint16:
+			n = 2
+		case l <= 1<<24-1:
+			n = 3
+		default:
+			n = 4
+		}
+		return binlogColumn{typ: mysql.TypeBlob, meta: uint16(n), length: n}, nil
+
+	case querypb.Type_ENUM:
+		n := 1
+		if typ.(sql.EnumType).NumberOfElements() > math.MaxUint8 {
+			n = 2
+		}
+		return binlogColumn{typ: mysql.TypeString, meta: uint16(mysql.TypeEnum)<<8 | uint16(n), realType: mysql.TypeEnum, length: n}, nil
+
+	case querypb.Type_SET:
+		n := (int(typ.(sql.SetType).NumberOfElements()) + 7) / 8
+		if n > 4 {
+			n = 8
+		}
+		return binlogColumn{typ: mysql.TypeString, meta: uint16(mysql.TypeSet)<<8 | uint16(n), realType: mysql.TypeSet, length: n}, nil
+
+	default:
+		return binlogColumn{}, fmt.Errorf("columns of type %s cannot be written to a binlog", typ.String())
+	}
+}
+
+// lengthPrefixBytes returns the number of bytes of the length of the values of VARCHAR and CHAR columns of |maxBytes|.
+func lengthPrefixBytes(maxBytes int64) int {
+	if maxBytes > math.MaxUint8 {
+		return 2
+	}
+	return 1
+}
+
+// appendMeta appends the metadata of the column to the metadata of a table map event.
+func (c binlogColumn) appendMeta(buf []byte) []byte {
+	switch c.typ {
+	case mysql.TypeFloat, mysql.TypeDouble, mysql.TypeTimestamp2, mysql.TypeDateTime2, mysql.TypeTime2, mysql.TypeJSON,
+		mysql.TypeBlob, mysql.TypeGeometry:
+		return append(buf, byte(c.meta))
+	case mysql.TypeNewDecimal, mysql.TypeString:
+		return binary.BigEndian.AppendUint16(buf, c.meta)
+	case mysql.TypeVarchar, mysql.TypeBit:
+		return binary.LittleEndian.AppendUint16(buf, c.meta)
+	default:
+		return buf
+	}
+}
+
+// appendValue appends |v|, a non-NULL value of the column as read from a table, to the values of a rows event.
+func (c binlogColumn) appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch c.typ {
+	case mysql.TypeTiny:
+		return appendInt(buf, v, 1)
+	case mysql.TypeShort:
+		return appendInt(buf, v, 2)
+	case mysql.TypeInt24:
+		return appendInt(buf, v, 3)
+	case mysql.TypeLong:
+		return appendInt(buf, v, 4)
+	case mysql.TypeLongLong:
+		return appendInt(buf, v, 8)
+
+	case mysql.TypeFloat:
+		f, ok := v.(float32)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(f)), nil
+
+	case mysql.TypeDouble:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(f)), nil
+
+	case mysql.TypeYear:
+		y, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		if y != 0 {
+			y -= 1900
+		}
+		return append(buf, byte(y)), nil
+
+	case mysql.TypeDate:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		d := uint32(t.Year())<<9 | uint32(t.Month())<<5 | uint32(t.Day())
+		return append(buf, byte(d), byte(d>>8), byte(d>>16)), nil
+
+	case mysql.TypeDateTime2:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		ymd := (uint64(t.Year())*13+uint64(t.Month()))<<5 | uint64(t.Day())
+		hms := uint64(t.Hour())<<12 | uint64(t.Minute())<<6 | uint64(t.Second())
+		packed := (ymd<<17 | hms) + 0x8000000000
+		return append(buf, byte(packed>>32), byte(packed>>24), byte(packed>>16), byte(packed>>8), byte(packed)), nil
+
+	case mysql.TypeTimestamp2:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		secs := t.Unix()
+		if secs < 0 {
+			secs = 0
+		}
+		return binary.BigEndian.AppendUint32(buf, uint32(secs)), nil
+
+	case mysql.TypeTime2:
+		ts, ok := v.(sql.Timespan)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		micros := ts.AsMicroseconds()
+		neg := micros < 0
+		if neg {
+			micros = -micros
+		}
+		secs := micros / 1_000_000
+		hms := (secs/3600)<<12 | (secs/60%60)<<6 | secs%60
+		if neg {
+			hms = -hms
+		}
+		packed := uint32(hms + 0x800000)
+		return append(buf, byte(packed>>16), byte(packed>>8), byte(packed)), nil
+
+	case mysql.TypeNewDecimal:
+		d, ok := v.(decimal.Decimal)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		return appendDecimal(buf, d, int(c.meta>>8), int(c.scale))
+
+	case mysql.TypeBit:
+		b, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		for i := c.length - 1; i >= 0; i-- {
+			buf = append(buf, byte(b>>(8*i)))
+		}
+		return buf, nil
+
+	case mysql.TypeVarchar, mysql.TypeBlob:
+		return appendBytes(buf, v, c.length)
+
+	case mysql.TypeString:
+		switch c.realType {
+		case mysql.TypeEnum, mysql.TypeSet:
+			return appendInt(buf, v, c.length)
+		default:
+			return appendBytes(buf, v, c.length)
+		}
+
+	case mysql.TypeJSON:
+		doc, ok := v.(sql.JSONDocument)
+		if !ok {
+			return nil, unexpectedValue(v)
+		}
+		j, err := encodeJSON(doc.Val)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(buf, j, c.length)
+
+	case mysql.TypeGeometry:
+		var g []byte
+		switch v := v.(type) {
+		case sql.Point:
+			g = sql.SerializePoint(v)
+		case sql.LineString:
+			g = sql.SerializeLineString(v)
+		case sql.Polygon:
+			g = sql.SerializePolygon(v)
+		default:
+			return nil, unexpectedValue(v)
+		}
+		return appendBytes(buf, g, c.length)
+
+	default:
+		return nil, fmt.Errorf("unexpected binlog column type %d", c.typ)
+	}
+}
+
+func unexpectedValue(v interface{}) error {
+	return fmt.Errorf("unexpected value %v of type %T", v, v)
+}
+
+func toUint64(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case int8:
+		return uint64(v), nil
+	case uint8:
+		return uint64(v), nil
+	case int16:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case int32:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case int64:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case int:
+		return uint64(v), nil
+	case uint:
+		return uint64(v), nil
+	default:
+		return 0, unexpectedValue(v)
+	}
+}
+
+// appendInt appends the |n| lowest bytes of the integer |v| in little endian order. Signed integers are written in
+// two's complement.
+func appendInt(buf []byte, v interface{}, n int) ([]byte, error) {
+	i, err := toUint64(v)
+	if err != nil {
+		return nil, err
+	}
+	for b := 0; b < n; b++ {
+		buf = append(buf, byte(i>>(8*b)))
+	}
+	return buf, nil
+}
+
+// appendBytes appends the string or byte slice |v| prefixed with its length in |n| bytes.
+func appendBytes(buf []byte, v interface{}, n int) ([]byte, error) {
+	var b []byte
+	switch v := v.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return nil, unexpectedValue(v)
+	}
+	if n < 8 && uint64(len(b)) >= 1<<(8*n) {
+		return nil, fmt.Errorf("value of %d bytes is too long for its column", len(b))
+	}
+	for i := 0; i < n; i++ {
+		buf = append(buf, byte(len(b)>>(8*i)))
+	}
+	return append(buf, b...), nil
+}
+
+// digitBytes are the numbers of bytes in which MySQL stores the decimal digits that don't fill a group of nine.
+var digitBytes = []int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// appendDecimal appends |d| in the binary format of MySQL for DECIMAL(|precision|, |scale|): the integer digits and
+// the fractional digits are each stored in groups of nine digits in four big endian bytes, with the integer digits
+// that don't fill a group first and the fractional digits that don't fill a group last. Negative numbers have all
+// bits inverted, and the highest bit is flipped so that the bytes sort in the order of the numbers.
+func appendDecimal(buf []byte, d decimal.Decimal, precision, scale int) ([]byte, error) {
+	neg := d.Sign() < 0
+	digits := d.Abs().StringFixed(int32(scale))
+	intDigits, fracDigits := digits, ""
+	if scale > 0 {
+		i := strings.IndexByte(digits, '.')
+		intDigits, fracDigits = digits[:i], digits[i+1:]
+	}
+
+	intg := precision - scale
+	intDigits = strings.TrimLeft(intDigits, "0")
+	if len(intDigits) > intg {
+		return nil, fmt.Errorf("decimal %s is out of range for DECIMAL(%d,%d)", d.String(), precision, scale)
+	}
+	intDigits = strings.Repeat("0", intg-len(intDigits)) + intDigits
+
+	start := len(buf)
+	appendGroup := func(group string) {
+		var v uint32
+		for _, c := range group {
+			v = v*10 + uint32(c-'0')
+		}
+		n := digitBytes[len(group)]
+		for i := n - 1; i >= 0; i-- {
+			buf = append(buf, byte(v>>(8*i)))
+		}
+	}
+
+	lead := intg % 9
+	appendGroup(intDigits[:lead])
+	for i := lead; i < intg; i += 9 {
+		appendGroup(intDigits[i : i+9])
+	}
+	for i := 0; i+9 <= scale; i += 9 {
+		appendGroup(fracDigits[i : i+9])
+	}
+	appendGroup(fracDigits[scale-scale%9:])
+
+	if neg {
+		for i := start; i < len(buf); i++ {
+			buf[i] ^= 0xff
+		}
+	}
+	buf[start] ^= 0x80
+	return buf, nil
+}
+
+// The types of the values of the binary JSON format of MySQL.
+const (
+	jsonLargeObject = 1
+	jsonLargeArray  = 3
+	jsonLiteral     = 4
+	jsonInt64       = 9
+	jsonUint64      = 10
+	jsonDouble      = 11
+	jsonString      = 12
+
+	jsonNull  = 0
+	jsonTrue  = 1
+	jsonFalse = 2
+)
+
+// encodeJSON returns |v| in the binary JSON format of MySQL. Objects and arrays are always written in the large
+// format, with four byte offsets, and numbers which are integers are written as integers, as MySQL does when it
+// parses JSON text.
+func encodeJSON(v interface{}) ([]byte, error) {
+	typ, payload, err := encodeJSONValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{typ}, payload...), nil
+}
+
+func encodeJSONValue(v interface{}) (byte, []byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return jsonLiteral, []byte{jsonNull}, nil
+	case bool:
+		if v {
+			return jsonLiteral, []byte{jsonTrue}, nil
+		}
+		return jsonLiteral, []byte{jsonFalse}, nil
+	case string:
+		return jsonString, append(appendJSONLength(nil, len(v)), v...), nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return jsonInt64, binary.LittleEndian.AppendUint64(nil, uint64(int64(v))), nil
+		}
+		return jsonDouble, binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)), nil
+	case int64:
+		return jsonInt64, binary.LittleEndian.AppendUint64(nil, uint64(v)), nil
+	case uint64:
+		return jsonUint64, binary.LittleEndian.AppendUint64(nil, v), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// MySQL looks keys up with a binary search over keys sorted by length, then by their bytes
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		vals := make([]interface{}, len(keys))
+		for i, k := range keys {
+			vals[i] = v[k]
+		}
+		payload, err := encodeJSONContainer(keys, vals)
+		return jsonLargeObject, payload, err
+	case []interface{}:
+		payload, err := encodeJSONContainer(nil, v)
+		return jsonLargeArray, payload, err
+	default:
+		// other values, like those of documents created in SQL, are encoded as the values they marshal to
+		b, err := json.Marshal(v)
+		if err != nil {
+			return 0, nil, err
+		}
+		var u interface{}
+		if err = json.Unmarshal(b, &u); err != nil {
+			return 0, nil, err
+		}
+		return encodeJSONValue(u)
+	}
+}
+
+// encodeJSONContainer returns the payload of a large object with |keys| and |vals|, or of a large array of |vals| if
+// |keys| is nil. Literals are inlined in the value entries, all other values follow the keys.
+func encodeJSONContainer(keys []string, vals []interface{}) ([]byte, error) {
+	const entrySize = 5
+	headerSize := 8 + len(keys)*6 + len(vals)*entrySize
+	payload := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(payload[0:4], uint32(len(vals)))
+
+	pos := 8
+	for _, k := range keys {
+		if len(k) > math.MaxUint16 {
+			return nil, fmt.Errorf("JSON key of %d bytes is too long for a binlog", len(k))
+		}
+		binary.LittleEndian.PutUint32(payload[pos:], uint32(len(payload)))
+		binary.LittleEndian.PutUint16(payload[pos+4:], uint16(len(k)))
+		payload = append(payload, k...)
+		pos += 6
+	}
+	for _, v := range vals {
+		typ, val, err := encodeJSONValue(v)
+		if err != nil {
+			return nil, err
+		}
+		payload[pos] = typ
+		if typ == jsonLiteral {
+			payload[pos+1] = val[0]
+		} else {
+			binary.LittleEndian.PutUint32(payload[pos+1:], uint32(len(payload)))
+			payload = append(payload, val...)
+		}
+		pos += entrySize
+	}
+	binary.LittleEndian.PutUint32(payload[4:8], uint32(len(payload)))
+	return payload, nil
+}
+
+// appendJSONLength appends the length of a JSON string, seven bits per byte with the highest bit set on all bytes
+// but the last.
+func appendJSONLength(buf []byte, l int) []byte {
+	for l >= 0x80 {
+		buf = append(buf, byte(l&0x7f|0x80))
+		l >>= 7
+	}
+	return append(buf, byte(l))
+}
+
+// tableMapBody returns the body of the event which maps |tableID| to the table |name| of |db|, with |cols| written
+// for the columns of |sch|.
+func tableMapBody(tableID uint64, db, name string, sch sql.Schema, cols []binlogColumn) []byte {
+	body := make([]byte, 8, 8+len(db)+len(name)+4+3*len(cols))
+	putTableID(body, tableID)
+	body = append(body, byte(len(db)))
+	body = append(body, db...)
+	body = append(body, 0, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, 0)
+
+	body = appendLenEncInt(body, uint64(len(cols)))
+	var meta []byte
+	for _, c := range cols {
+		body = append(body, c.typ)
+		meta = c.appendMeta(meta)
+	}
+	body = appendLenEncInt(body, uint64(len(meta)))
+	body = append(body, meta...)
+
+	nullable := newBitmap(len(cols))
+	for i, col := range sch {
+		if col.Nullable {
+			nullable.set(i)
+		}
+	}
+	return append(body, nullable...)
+}
+
+// putTableID writes the six byte table id which starts the body of table map and rows events.
+func putTableID(body []byte, tableID uint64) {
+	for i := 0; i < 6; i++ {
+		body[i] = byte(tableID >> (8 * i))
+	}
+}
+
+// bitmap is a bitmap of the columns of a table, as it is written to a binlog.
+type bitmap []byte
+
+func newBitmap(n int) bitmap {
+	return make(bitmap, (n+7)/8)
+}
+
+func (b bitmap) set(i int) {
+	b[i/8] |= 1 << (i % 8)
+}
+
+// rowsEvent accumulates the rows of a write, update or delete rows event of a table. Every event has full images of
+// the rows, with all columns.
+type rowsEvent struct {
+	typ     byte
+	tableID uint64
+	cols    []binlogColumn
+	rows    []byte
+}
+
+func newRowsEvent(typ byte, tableID uint64, cols []binlogColumn) *rowsEvent {
+	return &rowsEvent{typ: typ, tableID: tableID, cols: cols}
+}
+
+// add adds a row to the event. |before| is the row which is updated or deleted, |after| is the row which is written
+// or the updated row.
+func (e *rowsEvent) add(before, after sql.Row) error {
+	var err error
+	if e.typ != writeRowsEventType {
+		if e.rows, err = e.appendRow(e.rows, before); err != nil {
+			return err
+		}
+	}
+	if e.typ != deleteRowsEventType {
+		if e.rows, err = e.appendRow(e.rows, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *rowsEvent) appendRow(buf []byte, row sql.Row) ([]byte, error) {
+	nulls := newBitmap(len(e.cols))
+	for i, v := range row {
+		if v == nil {
+			nulls.set(i)
+		}
+	}
+	buf = append(buf, nulls...)
+
+	var err error
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		if buf, err = e.cols[i].appendValue(buf, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (e *rowsEvent) size() int {
+	return len(e.rows)
+}
+
+// body returns the body of the event with its rows, and resets the rows of the event.
+func (e *rowsEvent) body(flags uint16) []byte {
+	all := newBitmap(len(e.cols))
+	for i := range e.cols {
+		all.set(i)
+	}
+
+	body := make([]byte, 10, 10+2*len(all)+len(e.rows)+9)
+	putTableID(body, e.tableID)
+	binary.LittleEndian.PutUint16(body[6:8], flags)
+	// the length of the extra data, which includes the length itself
+	binary.LittleEndian.PutUint16(body[8:10], 2)
+	body = appendLenEncInt(body, uint64(len(e.cols)))
+	body = append(body, all...)
+	if e.typ == updateRowsEventType {
+		body = append(body, all...)
+	}
+	body = append(body, e.rows...)
+	e.rows = nil
+	return body
+}
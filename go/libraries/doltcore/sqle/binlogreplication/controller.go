@@ -0,0 +1,520 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+const (
+	ioStateConnecting = "Connecting to source"
+	ioStateWaiting    = "Waiting for source to send event"
+)
+
+// ErrReplicaRunning is returned by operations which require the replica to be stopped.
+var ErrReplicaRunning = errors.New("this operation cannot be performed with a running replica; run dolt_stop_replica() first")
+
+// ErrNoReplicationSource is returned when the replica is started before its source is configured.
+var ErrNoReplicationSource = errors.New("the replication source is not configured; run dolt_change_replication_source() first")
+
+// ErrReplicaNotReady is returned when the replica is started before the server finished starting.
+var ErrReplicaNotReady = errors.New("the replica is not ready yet")
+
+// binlogStream is a stream of the binlog events of a source.
+type binlogStream interface {
+	ReadBinlogEvent() (mysql.BinlogEvent, error)
+	Close()
+}
+
+// sessionFactory returns a context with a new session of |client|.
+type sessionFactory func(ctx context.Context, client sql.Client) (*sql.Context, error)
+
+type procedurestore interface {
+	RegisterProcedure(procedure sql.ExternalStoredProcedureDetails)
+}
+
+// applyError is an error applying an event, as opposed to an error reading it from the source.
+type applyError struct {
+	err error
+}
+
+func (e applyError) Error() string {
+	return e.err.Error()
+}
+
+func (e applyError) Unwrap() error {
+	return e.err
+}
+
+// Controller manages the replica of a sql-server. The replica streams the row-based binlog of a MySQL source and
+// applies it to the databases of the server. Its source, position and whether it is running are persisted, so it
+// resumes replicating from where it left off when the server restarts.
+type Controller struct {
+	persistentCfg config.ReadWriteConfig
+	lgr           *logrus.Entry
+	connect       func(ctx context.Context, src sourceConfig, pos mysql.Position, serverID uint32) (binlogStream, error)
+
+	mu     sync.Mutex
+	source sourceConfig
+	// sourceID identifies the configuration of the source, it changes whenever the position of the replica is changed
+	// by a new source configuration. seq is the number of transactions the replica committed since then.
+	sourceID      string
+	seq           uint64
+	position      mysql.Position
+	readPosition  mysql.Position
+	applierClient sql.Client
+	engine        queryEngine
+	sessions      sessionFactory
+
+	running      bool
+	ioState      string
+	ioRunning    string
+	sqlRunning   bool
+	lastIOError  string
+	lastSQLError string
+	stream       binlogStream
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// NewController returns the Controller of a replica whose configuration is persisted in |pCfg|.
+func NewController(lgr *logrus.Logger, pCfg config.ReadWriteConfig) (*Controller, error) {
+	src, pos, err := loadSourceConfig(pCfg)
+	if err != nil {
+		return nil, err
+	}
+	sourceID, seq, err := loadPositionSeq(pCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Controller{
+		persistentCfg: pCfg,
+		lgr:           lgr.WithField("component", "dolt.binlogreplica"),
+		connect:       connectToSource,
+		source:        src,
+		sourceID:      sourceID,
+		seq:           seq,
+		position:      pos,
+		readPosition:  pos,
+		applierClient: sql.Client{
+			User:    pCfg.GetStringOrDefault(persistentApplierUserKey, "root"),
+			Address: pCfg.GetStringOrDefault(persistentApplierHostKey, "localhost"),
+		},
+		running: pCfg.GetStringOrDefault(persistentRunningKey, persistentFalse) == persistentTrue,
+	}, nil
+}
+
+// RegisterStoredProcedures adds the stored procedures which configure, start, stop and show the status of the
+// replica to |store|.
+func (c *Controller) RegisterStoredProcedures(store procedurestore) {
+	if c == nil {
+		return
+	}
+	store.RegisterProcedure(newChangeReplicationSourceProcedure(c))
+	store.RegisterProcedure(newStartReplicaProcedure(c))
+	store.RegisterProcedure(newStopReplicaProcedure(c))
+	store.RegisterProcedure(newShowReplicaStatusProcedure(c))
+}
+
+// Init sets the engine which applies the binlog of the source, in sessions made by |sessions|, and starts the
+// replica if it was running when the server last stopped.
+func (c *Controller) Init(engine queryEngine, sessions sessionFactory) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.engine = engine
+	c.sessions = sessions
+	if c.running {
+		c.startLocked()
+	}
+}
+
+// Close stops the replica without persisting that it was stopped, so it starts again with the server.
+func (c *Controller) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+}
+
+func (c *Controller) changeSource(options []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sqlRunning {
+		return ErrReplicaRunning
+	}
+
+	src, pos, err := changeSource(c.source, c.position, options)
+	if err != nil {
+		return err
+	}
+	sourceID, seq := c.sourceID, c.seq
+	if sourceID == "" || !pos.Equal(c.position) {
+		sourceID, seq = uuid.New().String(), 0
+	}
+	err = persistSourceConfig(c.persistentCfg, src, pos, sourceID, seq)
+	if err != nil {
+		return err
+	}
+
+	c.source, c.position, c.readPosition = src, pos, pos
+	c.sourceID, c.seq = sourceID, seq
+	c.lastIOError, c.lastSQLError = "", ""
+	return nil
+}
+
+func (c *Controller) startReplica(client sql.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sqlRunning {
+		return nil
+	}
+	if c.source.Host == "" {
+		return ErrNoReplicationSource
+	}
+	if c.engine == nil {
+		return ErrReplicaNotReady
+	}
+
+	err := c.persistentCfg.SetStrings(map[string]string{
+		persistentRunningKey:     persistentTrue,
+		persistentApplierUserKey: client.User,
+		persistentApplierHostKey: client.Address,
+	})
+	if err != nil {
+		return err
+	}
+
+	// reaps the goroutine of a replica which stopped on an error
+	c.stopLocked()
+
+	c.running = true
+	c.applierClient = client
+	c.lastIOError, c.lastSQLError = "", ""
+	c.startLocked()
+	return nil
+}
+
+func (c *Controller) stopReplica() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+	c.running = false
+	return c.persistentCfg.SetStrings(map[string]string{persistentRunningKey: persistentFalse})
+}
+
+func (c *Controller) startLocked() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	c.cancel, c.done = cancel, done
+	c.ioRunning, c.ioState = "Connecting", ioStateConnecting
+	c.sqlRunning = true
+	go c.run(ctx, done, c.source, c.applierClient)
+}
+
+// stopLocked stops the replication goroutine and waits for it to exit. |c.mu| is released while waiting.
+func (c *Controller) stopLocked() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	if c.stream != nil {
+		c.stream.Close()
+	}
+	done := c.done
+	c.cancel, c.done = nil, nil
+
+	c.mu.Unlock()
+	<-done
+	c.mu.Lock()
+}
+
+// run replicates the binlog of |src| until |ctx| is canceled or an event can't be applied. It reconnects to the source
+// whenever the connection is lost.
+func (c *Controller) run(ctx context.Context, done chan struct{}, src sourceConfig, client sql.Client) {
+	defer close(done)
+	defer c.setStopped()
+
+	sqlCtx, err := c.newApplierContext(ctx, src, client)
+	if err == nil {
+		err = c.recoverPosition(sqlCtx)
+	}
+	if err != nil {
+		c.setSQLError(err)
+		return
+	}
+
+	for {
+		c.setIOState("Connecting", ioStateConnecting, "")
+		stream, err := c.connect(ctx, src, c.currentPosition(), serverID())
+		if err == nil {
+			err = c.replicate(ctx, sqlCtx, stream)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		var aErr applyError
+		if errors.As(err, &aErr) {
+			c.setSQLError(err)
+			return
+		}
+
+		c.setIOState("Connecting", ioStateConnecting, err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(src.ConnectRetry) * time.Second):
+		}
+	}
+}
+
+// replicate applies the events of |stream| until it fails.
+func (c *Controller) replicate(ctx context.Context, sqlCtx *sql.Context, stream binlogStream) error {
+	c.mu.Lock()
+	if ctx.Err() != nil {
+		c.mu.Unlock()
+		stream.Close()
+		return ctx.Err()
+	}
+	c.stream = stream
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.stream = nil
+		c.mu.Unlock()
+		stream.Close()
+	}()
+
+	c.setIOState("Yes", ioStateWaiting, "")
+	c.mu.Lock()
+	a := newApplier(sqlCtx, c.engine, c.sourceID, c.position, c.seq, c.setPosition)
+	c.mu.Unlock()
+	for {
+		ev, err := stream.ReadBinlogEvent()
+		if err == nil {
+			err = a.apply(ev)
+			if err != nil {
+				err = applyError{err}
+			}
+		}
+		if err != nil {
+			if aErr := a.abort(); aErr != nil {
+				c.lgr.Warnf("error rolling back replicated transaction: %v", aErr)
+			}
+			return err
+		}
+		c.setReadPosition(a.pending)
+	}
+}
+
+func (c *Controller) newApplierContext(ctx context.Context, src sourceConfig, client sql.Client) (*sql.Context, error) {
+	c.mu.Lock()
+	sessions := c.sessions
+	c.mu.Unlock()
+
+	sqlCtx, err := sessions(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	err = sqlCtx.SetSessionVariable(sqlCtx, sql.AutoCommitSessionVar, true)
+	if err != nil {
+		return nil, err
+	}
+	err = sqlCtx.SetSessionVariable(sqlCtx, dsess.DoltCommitOnTransactionCommit, src.DoltCommit)
+	if err != nil {
+		return nil, err
+	}
+	return sqlCtx, nil
+}
+
+func (c *Controller) currentPosition() mysql.Position {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.position
+}
+
+// recoverPosition moves the position of the replica to the position committed with the last transaction it applied.
+// The position is persisted in the configuration of the replica after the transaction commits, so it is behind if the
+// server stopped in between.
+func (c *Controller) recoverPosition(ctx *sql.Context) error {
+	c.mu.Lock()
+	sourceID, seq := c.sourceID, c.seq
+	c.mu.Unlock()
+
+	var pos mysql.Position
+	recovered := false
+	for _, db := range dsess.DSessFromSess(ctx.Session).Provider().AllDatabases(ctx) {
+		_, ok, err := db.GetTableInsensitive(ctx, doltdb.ReplicaPositionTableName)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		rows, err := queryRows(ctx, c.engine, fmt.Sprintf("SELECT %s, %s FROM `%s`.%s WHERE %s = %s",
+			doltdb.ReplicaPositionTableSeqCol, doltdb.ReplicaPositionTablePositionCol, db.Name(),
+			doltdb.ReplicaPositionTableName, doltdb.ReplicaPositionTableSourceIDCol, sqlString(sourceID)))
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			rowSeq, ok := row[0].(uint64)
+			if !ok || rowSeq <= seq {
+				continue
+			}
+			if pos, err = mysql.DecodePosition(row[1].(string)); err != nil {
+				return fmt.Errorf("replication position of database %s is invalid: %w", db.Name(), err)
+			}
+			seq, recovered = rowSeq, true
+		}
+	}
+
+	if !recovered {
+		return nil
+	}
+	c.lgr.Infof("recovered replication position %s from the last committed transaction", pos)
+	return c.setPosition(pos, seq)
+}
+
+func (c *Controller) setPosition(pos mysql.Position, seq uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.position = pos
+	c.readPosition = pos
+	c.seq = seq
+	return c.persistentCfg.SetStrings(map[string]string{
+		persistentPositionKey: mysql.EncodePosition(pos),
+		persistentSeqKey:      strconv.FormatUint(seq, 10),
+	})
+}
+
+func (c *Controller) setReadPosition(pos mysql.Position) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readPosition = pos
+}
+
+func (c *Controller) setIOState(running, state, ioErr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ioRunning, c.ioState = running, state
+	if ioErr != "" {
+		c.lgr.Warnf("error replicating from %s:%d: %s", c.source.Host, c.source.Port, ioErr)
+		c.lastIOError = ioErr
+	}
+}
+
+func (c *Controller) setSQLError(err error) {
+	c.lgr.Errorf("replica stopped, error applying the binlog of the source: %v", err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastSQLError = err.Error()
+}
+
+func (c *Controller) setStopped() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ioRunning, c.ioState = "No", ""
+	c.sqlRunning = false
+}
+
+// connectToSource connects to |src| and requests its binlog from |pos| on. A replica which doesn't auto position and
+// has no position yet starts from the first binlog of the source.
+func connectToSource(ctx context.Context, src sourceConfig, pos mysql.Position, serverID uint32) (binlogStream, error) {
+	conn, err := mysql.Connect(ctx, &mysql.ConnParams{
+		Host:   src.Host,
+		Port:   src.Port,
+		Uname:  src.User,
+		Pass:   src.Password,
+		Flavor: src.positionFlavor(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = requestBinlog(conn, src, pos, serverID)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func requestBinlog(conn *mysql.Conn, src sourceConfig, pos mysql.Position, serverID uint32) error {
+	// the source only sends checksummed events to replicas which declare they can handle them
+	_, err := conn.ExecuteFetch("SET @master_binlog_checksum = @@global.binlog_checksum", 0, false)
+	if err != nil {
+		return err
+	}
+
+	if pos.IsZero() {
+		if src.AutoPosition {
+			pos, err = mysql.ParsePosition(gtidFlavor, "")
+		} else {
+			pos, err = firstBinlogPosition(conn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return conn.SendBinlogDumpCommand(serverID, pos)
+}
+
+func firstBinlogPosition(conn *mysql.Conn) (mysql.Position, error) {
+	qr, err := conn.ExecuteFetch("SHOW BINARY LOGS", math.MaxInt32, false)
+	if err != nil {
+		return mysql.Position{}, err
+	}
+	if len(qr.Rows) == 0 {
+		return mysql.Position{}, fmt.Errorf("binary logging is not enabled on the source")
+	}
+	return mysql.ParsePosition(filePosFlavor, fmt.Sprintf("%s:%d", qr.Rows[0][0].ToString(), firstBinlogEventOffset))
+}
+
+func serverID() uint32 {
+	_, val, ok := sql.SystemVariables.GetGlobal(dsess.ServerID)
+	if !ok {
+		return 1
+	}
+	id, ok := val.(int64)
+	if !ok {
+		return 1
+	}
+	return uint32(id)
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+func TestChangeSource(t *testing.T) {
+	src, pos, err := changeSource(newSourceConfig(), mysql.Position{}, []string{
+		"SOURCE_HOST=mysql.example.com",
+		"source_user = repl",
+		"SOURCE_PASSWORD=secret",
+		"SOURCE_LOG_FILE=binlog.000002",
+		"SOURCE_LOG_POS=1234",
+		"DOLT_TRANSACTION_COMMIT=1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sourceConfig{
+		Host:         "mysql.example.com",
+		Port:         defaultSourcePort,
+		User:         "repl",
+		Password:     "secret",
+		ConnectRetry: defaultSourceConnectRetry,
+		DoltCommit:   true,
+	}, src)
+	assert.Equal(t, "binlog.000002:1234", pos.String())
+
+	// changing the log position keeps the log file
+	_, pos2, err := changeSource(src, pos, []string{"SOURCE_LOG_POS=4321"})
+	require.NoError(t, err)
+	assert.Equal(t, "binlog.000002:4321", pos2.String())
+
+	// changing anything but the source keeps the position
+	_, pos2, err = changeSource(src, pos, []string{"SOURCE_PASSWORD=changed", "SOURCE_CONNECT_RETRY=5"})
+	require.NoError(t, err)
+	assert.Equal(t, pos, pos2)
+
+	// changing the source resets the position
+	_, pos2, err = changeSource(src, pos, []string{"SOURCE_PORT=3307"})
+	require.NoError(t, err)
+	assert.True(t, pos2.IsZero())
+
+	_, pos2, err = changeSource(src, pos, []string{"SOURCE_AUTO_POSITION=1"})
+	require.NoError(t, err)
+	assert.True(t, pos2.IsZero())
+
+	for _, opts := range [][]string{
+		{"SOURCE_HOST"},
+		{"SOURCE_PORT=port"},
+		{"SOURCE_RETRY_COUNT=3"},
+		{"SOURCE_AUTO_POSITION=maybe"},
+		{"SOURCE_AUTO_POSITION=1", "SOURCE_LOG_FILE=binlog.000001"},
+	} {
+		_, _, err = changeSource(src, pos, opts)
+		assert.Error(t, err, "%v", opts)
+	}
+
+	_, _, err = changeSource(newSourceConfig(), mysql.Position{}, []string{"SOURCE_LOG_POS=4"})
+	assert.Error(t, err)
+}
+
+func TestPersistSourceConfig(t *testing.T) {
+	pCfg := config.NewMapConfig(map[string]string{})
+	src, pos, err := loadSourceConfig(pCfg)
+	require.NoError(t, err)
+	assert.Equal(t, newSourceConfig(), src)
+	assert.True(t, pos.IsZero())
+
+	src, pos, err = changeSource(src, pos, []string{"SOURCE_HOST=localhost", "SOURCE_PORT=3307", "SOURCE_LOG_FILE=binlog.000001"})
+	require.NoError(t, err)
+	require.NoError(t, persistSourceConfig(pCfg, src, pos, testSourceID, 3))
+
+	loadedSrc, loadedPos, err := loadSourceConfig(pCfg)
+	require.NoError(t, err)
+	assert.Equal(t, src, loadedSrc)
+	assert.True(t, pos.Equal(loadedPos))
+	sourceID, seq, err := loadPositionSeq(pCfg)
+	require.NoError(t, err)
+	assert.Equal(t, testSourceID, sourceID)
+	assert.Equal(t, uint64(3), seq)
+}
+
+// testStream is a binlog stream of the events sent on |events|.
+type testStream struct {
+	events chan mysql.BinlogEvent
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newTestStream() *testStream {
+	return &testStream{events: make(chan mysql.BinlogEvent), closed: make(chan struct{})}
+}
+
+func (s *testStream) ReadBinlogEvent() (mysql.BinlogEvent, error) {
+	select {
+	case ev := <-s.events:
+		return ev, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *testStream) Close() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+func (s *testStream) send(t *testing.T, events ...mysql.BinlogEvent) {
+	for _, ev := range events {
+		select {
+		case s.events <- ev:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out sending binlog event")
+		}
+	}
+}
+
+func TestControllerStartStop(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	sid, err := mysql.ParseSID("00010203-0405-0607-0809-0a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	pCfg := config.NewMapConfig(map[string]string{})
+	c, err := NewController(logrus.New(), pCfg)
+	require.NoError(t, err)
+	client := sql.Client{User: "root", Address: "localhost"}
+
+	assert.ErrorIs(t, c.startReplica(client), ErrNoReplicationSource)
+	require.NoError(t, c.changeSource([]string{"SOURCE_HOST=localhost", "SOURCE_AUTO_POSITION=1"}))
+	assert.ErrorIs(t, c.startReplica(client), ErrReplicaNotReady)
+
+	stream := newTestStream()
+	c.connect = func(ctx context.Context, src sourceConfig, pos mysql.Position, serverID uint32) (binlogStream, error) {
+		return stream, nil
+	}
+	c.Init(r.engine, func(ctx context.Context, client sql.Client) (*sql.Context, error) {
+		return r.ctx, nil
+	})
+
+	require.NoError(t, c.startReplica(client))
+	assert.ErrorIs(t, c.changeSource([]string{"SOURCE_PORT=3307"}), ErrReplicaRunning)
+	assert.Equal(t, persistentTrue, pCfg.GetStringOrDefault(persistentRunningKey, ""))
+
+	stream.send(t,
+		ts.formatDescription(),
+		ts.gtid(sid, 1),
+		ts.query(testDB, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))"),
+		ts.gtid(sid, 2),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+		ts.xid(),
+	)
+	require.Eventually(t, func() bool {
+		return c.status()[13] == "00010203-0405-0607-0809-0a0b0c0d0e0f:1-2"
+	}, 10*time.Second, 10*time.Millisecond)
+	status := c.status()
+	assert.Equal(t, "Yes", status[7])
+	assert.Equal(t, "Yes", status[8])
+
+	require.NoError(t, c.stopReplica())
+	assert.Equal(t, []sql.Row{{int32(1), "one"}}, r.query(t, "SELECT * FROM t"))
+	assert.Equal(t, "No", c.status()[8])
+	assert.Equal(t, persistentFalse, pCfg.GetStringOrDefault(persistentRunningKey, ""))
+	assert.Equal(t, "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1-2", pCfg.GetStringOrDefault(persistentPositionKey, ""))
+
+	// an event which can't be applied stops the replica
+	stream = newTestStream()
+	require.NoError(t, c.startReplica(client))
+	stream.send(t, ts.formatDescription(), ts.writeRows(testRow{2, "two"}))
+	require.Eventually(t, func() bool {
+		return c.status()[8] == "No"
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Contains(t, c.status()[11], "unknown table id")
+	c.Close()
+}
+
+func TestControllerRecoversPosition(t *testing.T) {
+	r := newTestReplica(t)
+	ts := newTestSource()
+	sid, err := mysql.ParseSID("00010203-0405-0607-0809-0a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	pCfg := config.NewMapConfig(map[string]string{})
+	c, err := NewController(logrus.New(), pCfg)
+	require.NoError(t, err)
+	client := sql.Client{User: "root", Address: "localhost"}
+	require.NoError(t, c.changeSource([]string{"SOURCE_HOST=localhost", "SOURCE_AUTO_POSITION=1"}))
+
+	stream := newTestStream()
+	positions := make(chan mysql.Position, 2)
+	c.connect = func(ctx context.Context, src sourceConfig, pos mysql.Position, serverID uint32) (binlogStream, error) {
+		positions <- pos
+		return stream, nil
+	}
+	c.Init(r.engine, func(ctx context.Context, client sql.Client) (*sql.Context, error) {
+		return r.ctx, nil
+	})
+
+	require.NoError(t, c.startReplica(client))
+	stream.send(t,
+		ts.formatDescription(),
+		ts.gtid(sid, 1),
+		ts.query(testDB, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))"),
+		ts.gtid(sid, 2),
+		ts.query(testDB, "BEGIN"),
+		ts.tableMap(),
+		ts.writeRows(testRow{1, "one"}),
+		ts.xid(),
+	)
+	require.Eventually(t, func() bool {
+		return c.status()[13] == "00010203-0405-0607-0809-0a0b0c0d0e0f:1-2"
+	}, 10*time.Second, 10*time.Millisecond)
+	require.NoError(t, c.stopReplica())
+	assert.True(t, (<-positions).IsZero())
+
+	// the server stopped after the last transaction committed, but before its position was persisted
+	require.NoError(t, pCfg.SetStrings(map[string]string{
+		persistentPositionKey: "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1",
+		persistentSeqKey:      "1",
+	}))
+	c, err = NewController(logrus.New(), pCfg)
+	require.NoError(t, err)
+	stream = newTestStream()
+	c.connect = func(ctx context.Context, src sourceConfig, pos mysql.Position, serverID uint32) (binlogStream, error) {
+		positions <- pos
+		return stream, nil
+	}
+	c.Init(r.engine, func(ctx context.Context, client sql.Client) (*sql.Context, error) {
+		return r.ctx, nil
+	})
+
+	require.NoError(t, c.startReplica(client))
+	select {
+	case pos := <-positions:
+		assert.Equal(t, "00010203-0405-0607-0809-0a0b0c0d0e0f:1-2", pos.String())
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the replica to connect")
+	}
+	assert.Equal(t, "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1-2", pCfg.GetStringOrDefault(persistentPositionKey, ""))
+	require.NoError(t, c.stopReplica())
+	assert.Equal(t, []sql.Row{{int32(1), "one"}}, r.query(t, "SELECT * FROM t"))
+}
@@ -0,0 +1,453 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	gms "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+const (
+	persistentServerUUIDKey = "server_uuid"
+	// persistentRootsFileKey is the binlog file the roots persisted with it were logged at the start of.
+	persistentRootsFileKey  = "roots_file"
+	persistentRootKeyPrefix = "root."
+)
+
+// PrimaryController manages the binlog of a sql-server which is the source of MySQL replicas. The changes of the
+// working set of the default branch of every database are written to the binlog as row-based transactions with
+// GTIDs, which replicas stream from the server with MySQL's replication protocol.
+//
+// The binlog is written from the differences between the roots of the working sets. The root the binlog of a
+// database was last written at is recorded in the binlog itself, so that the changes made while the server was
+// stopped are logged when it starts. A database which isn't in the binlog yet is logged as a whole, so that replicas
+// can start from an empty binlog position.
+//
+// Replicas connect without TLS, and should position themselves with GTIDs or with explicit binlog file names, since
+// the server doesn't support SHOW BINARY LOGS. Views, triggers and stored procedures are not logged, and temporal
+// values are logged with second precision.
+//
+// If the root a database was last logged at can't be read, its changes since then can't be logged, and the binlog
+// fails: nothing is written to it anymore, and replicas streaming it get an error.
+type PrimaryController struct {
+	lgr           *logrus.Entry
+	persistentCfg config.ReadWriteConfig
+	files         *binlogFiles
+
+	mu      sync.Mutex
+	hooks   map[string]*binlogHook
+	roots   map[string]hash.Hash
+	tableID uint64
+	mysqlDb *mysql_db.MySQLDb
+	dumps   map[*binlogDump]struct{}
+	closed  bool
+	// failure is the error the binlog failed with, if it did.
+	failure error
+}
+
+// NewPrimaryController returns the controller of a primary whose binlog is written to |dir|, in files of up to
+// |maxSize| bytes, and whose state is persisted in |pCfg|.
+func NewPrimaryController(lgr *logrus.Logger, pCfg config.ReadWriteConfig, dir string, maxSize int64) (*PrimaryController, error) {
+	serverUUID := pCfg.GetStringOrDefault(persistentServerUUIDKey, "")
+	if serverUUID == "" {
+		serverUUID = uuid.New().String()
+		if err := pCfg.SetStrings(map[string]string{persistentServerUUIDKey: serverUUID}); err != nil {
+			return nil, err
+		}
+	}
+	sid, err := mysql.ParseSID(serverUUID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", persistentServerUUIDKey, err)
+	}
+
+	checkpoint := make(map[string]hash.Hash)
+	var parseErr error
+	pCfg.Iter(func(k, v string) bool {
+		if !strings.HasPrefix(k, persistentRootKeyPrefix) {
+			return false
+		}
+		h, ok := hash.MaybeParse(v)
+		if !ok {
+			parseErr = fmt.Errorf("invalid root %s for database %s", v, k[len(persistentRootKeyPrefix):])
+			return true
+		}
+		checkpoint[k[len(persistentRootKeyPrefix):]] = h
+		return false
+	})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	files, roots, err := openBinlogFiles(dir, maxSize, sid, serverID, pCfg.GetStringOrDefault(persistentRootsFileKey, ""), checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	c := &PrimaryController{
+		lgr:           lgr.WithField("component", "dolt.binlogprimary"),
+		persistentCfg: pCfg,
+		files:         files,
+		hooks:         make(map[string]*binlogHook),
+		roots:         roots,
+		dumps:         make(map[*binlogDump]struct{}),
+	}
+	if err = files.startFile(c.checkpoint); err != nil {
+		return nil, err
+	}
+
+	err = sql.SystemVariables.AssignValues(map[string]interface{}{
+		dsess.ServerUUID:           serverUUID,
+		dsess.LogBin:               int8(1),
+		"gtid_mode":                "ON",
+		"enforce_gtid_consistency": "ON",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, c.setExecutedGTIDs()
+}
+
+// RegisterStoredProcedures adds the stored procedures which show the status of the binlog to |store|.
+func (c *PrimaryController) RegisterStoredProcedures(store procedurestore) {
+	if c == nil {
+		return
+	}
+	store.RegisterProcedure(newShowBinaryLogStatusProcedure(c))
+}
+
+// ApplyCommitHooks adds the hooks which write the binlog of the databases of |mrEnv|, and writes the changes made to
+// them since the binlog was last written.
+func (c *PrimaryController) ApplyCommitHooks(ctx context.Context, mrEnv *env.MultiRepoEnv) error {
+	if c == nil {
+		return nil
+	}
+	return mrEnv.Iter(func(name string, dEnv *env.DoltEnv) (stop bool, err error) {
+		if dEnv.DoltDB == nil {
+			return false, nil
+		}
+		c.mu.Lock()
+		h, err := c.addHookLocked(ctx, name, dEnv)
+		c.mu.Unlock()
+		if err != nil || h == nil {
+			return err != nil, err
+		}
+		err = c.logDatabase(ctx, h)
+		if errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+			err = nil
+		}
+		return err != nil, err
+	})
+}
+
+// addHookLocked adds the hook which writes the binlog of the database |name| to its DoltDB. Databases whose format
+// has no row-level diffs are not logged.
+func (c *PrimaryController) addHookLocked(ctx context.Context, name string, dEnv *env.DoltEnv) (*binlogHook, error) {
+	if !types.IsFormat_DOLT(dEnv.DoltDB.Format()) {
+		c.lgr.Warnf("database %s is not written to the binlog, its storage format %s is not supported", name, dEnv.DoltDB.Format().VersionString())
+		return nil, nil
+	}
+	wsRef, err := ref.WorkingSetRefForHead(dEnv.RepoStateReader().CWBHeadRef())
+	if err != nil {
+		return nil, err
+	}
+	h := &binlogHook{c: c, db: name, ddb: dEnv.DoltDB, wsRef: wsRef}
+	dEnv.DoltDB.AddCommitHooks(ctx, h)
+	c.hooks[name] = h
+	return h, nil
+}
+
+// ManageDatabaseProvider returns a copy of |pro| which writes the databases it creates and drops to the binlog.
+func (c *PrimaryController) ManageDatabaseProvider(pro sqle.DoltDatabaseProvider) sqle.DoltDatabaseProvider {
+	if c == nil {
+		return pro
+	}
+	pro = pro.WithInitDatabaseHook(func(ctx *sql.Context, pro sqle.DoltDatabaseProvider, name string, dEnv *env.DoltEnv) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, err := c.addHookLocked(ctx, name, dEnv); err != nil {
+			return err
+		}
+		g := newBinlogGroup("", serverID(), c.nextTableIDLocked)
+		g.ddl = append(g.ddl, "CREATE DATABASE "+sqlfmt.QuoteIdentifier(name))
+		return c.appendGroupLocked(g, rootMarker{db: name})
+	})
+	return pro.WithDropDatabaseHook(func(ctx *sql.Context, name string) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.hooks, name)
+		g := newBinlogGroup("", serverID(), c.nextTableIDLocked)
+		g.ddl = append(g.ddl, "DROP DATABASE "+sqlfmt.QuoteIdentifier(name))
+		return c.appendGroupLocked(g, rootMarker{db: name})
+	})
+}
+
+// NewServer returns a sql-server like server.NewServer, whose connections also serve the binlog to replicas.
+func (c *PrimaryController) NewServer(cfg server.Config, e *gms.Engine, sb server.SessionBuilder, listener server.ServerEventListener) (*server.Server, error) {
+	if c == nil {
+		return server.NewServer(cfg, e, sb, listener)
+	}
+
+	c.mu.Lock()
+	c.mysqlDb = e.Analyzer.Catalog.MySQLDb
+	c.mu.Unlock()
+
+	// The server's listener is replaced with one which serves the binlog, its handler shares the sessions of the
+	// server. The listener of the server is made on an unused port.
+	placeholderCfg := cfg
+	placeholderCfg.Protocol, placeholderCfg.Address, placeholderCfg.Socket = "tcp", "127.0.0.1:0", ""
+	s, err := server.NewServer(placeholderCfg, e, sb, listener)
+	if err != nil {
+		return nil, err
+	}
+	s.Listener.Close()
+
+	if cfg.ConnReadTimeout < 0 {
+		cfg.ConnReadTimeout = 0
+	}
+	if cfg.ConnWriteTimeout < 0 {
+		cfg.ConnWriteTimeout = 0
+	}
+	if cfg.MaxConnections < 0 {
+		cfg.MaxConnections = 0
+	}
+	handler := server.NewHandler(e, s.SessionManager(), cfg.ConnReadTimeout, cfg.DisableClientMultiStatements, listener)
+	l, err := server.NewListener(cfg.Protocol, cfg.Address, cfg.Socket)
+	if err != nil {
+		return nil, err
+	}
+	vtListener, err := mysql.NewListenerWithConfig(mysql.ListenerConfig{
+		Listener:                 binlogListener{Listener: l, c: c},
+		AuthServer:               e.Analyzer.Catalog.MySQLDb,
+		Handler:                  binlogHandler{Handler: handler},
+		ConnReadTimeout:          cfg.ConnReadTimeout,
+		ConnWriteTimeout:         cfg.ConnWriteTimeout,
+		MaxConns:                 cfg.MaxConnections,
+		ConnReadBufferSize:       mysql.DefaultConnBufferSize,
+		AllowClearTextWithoutTLS: cfg.AllowClearTextWithoutTLS,
+	})
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	if cfg.Version != "" {
+		vtListener.ServerVersion = cfg.Version
+	}
+	vtListener.TLSConfig = cfg.TLSConfig
+	vtListener.RequireSecureTransport = cfg.RequireSecureTransport
+	s.Listener = vtListener
+	return s, nil
+}
+
+// Close ends the dumps of the binlog to replicas and closes the binlog.
+func (c *PrimaryController) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	c.closed = true
+	for d := range c.dumps {
+		d.conn.Close()
+	}
+	c.mu.Unlock()
+	return c.files.close()
+}
+
+// logDatabase writes the changes of the working set of |h| since the binlog of its database was last written.
+func (c *PrimaryController) logDatabase(ctx context.Context, h *binlogHook) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.failure != nil || c.hooks[h.db] != h {
+		return nil
+	}
+
+	ws, err := h.ddb.ResolveWorkingSet(ctx, h.wsRef)
+	if err != nil {
+		return err
+	}
+	to := ws.WorkingRoot()
+	toHash, err := to.HashOf()
+	if err != nil {
+		return err
+	}
+	fromHash, logged := c.roots[h.db]
+	if logged && fromHash == toHash {
+		return nil
+	}
+
+	g := newBinlogGroup(h.db, serverID(), c.nextTableIDLocked)
+	var from *doltdb.RootValue
+	if logged && !fromHash.IsEmpty() {
+		from, err = h.ddb.ReadRootValue(ctx, fromHash)
+		if err != nil {
+			c.failure = binlogError("the binlog can't be continued, the root database %s was last written at can't be read: %v", h.db, err)
+			c.lgr.Errorf("the binlog failed: %v", c.failure)
+			c.files.wake()
+			return nil
+		}
+	}
+	if from == nil {
+		from, err = doltdb.EmptyRootValue(ctx, h.ddb.ValueReadWriter(), h.ddb.NodeStore())
+		if err != nil {
+			return err
+		}
+	}
+	if !logged {
+		g.ddl = append(g.ddl, "CREATE DATABASE IF NOT EXISTS "+sqlfmt.QuoteIdentifier(h.db))
+	}
+
+	if err = binlogChanges(ctx, g, from, to); err != nil {
+		return err
+	}
+	return c.appendGroupLocked(g, rootMarker{db: h.db, root: toHash})
+}
+
+// appendGroupLocked appends the transactions of |g| to the binlog, recording |marker| with them. Groups without
+// changes are not appended.
+func (c *PrimaryController) appendGroupLocked(g *binlogGroup, marker rootMarker) error {
+	if c.failure != nil {
+		return nil
+	}
+	if !g.empty() {
+		if err := c.files.appendTransactions(g.transactions(marker), c.checkpoint); err != nil {
+			return err
+		}
+		if err := c.setExecutedGTIDs(); err != nil {
+			return err
+		}
+	}
+	c.roots[marker.db] = marker.root
+	return nil
+}
+
+// checkpoint persists the roots the binlog was written at before it continues in |file|, so that they don't have to
+// be recovered from the files before it. It is called with |c.mu| held, or before the controller is shared.
+func (c *PrimaryController) checkpoint(file string) error {
+	updates := map[string]string{persistentRootsFileKey: file}
+	for db, h := range c.roots {
+		updates[persistentRootKeyPrefix+db] = h.String()
+	}
+	var stale []string
+	c.persistentCfg.Iter(func(k, v string) bool {
+		if _, ok := updates[k]; !ok && strings.HasPrefix(k, persistentRootKeyPrefix) {
+			stale = append(stale, k)
+		}
+		return false
+	})
+	if len(stale) > 0 {
+		if err := c.persistentCfg.Unset(stale); err != nil {
+			return err
+		}
+	}
+	return c.persistentCfg.SetStrings(updates)
+}
+
+func (c *PrimaryController) setExecutedGTIDs() error {
+	return sql.SystemVariables.AssignValues(map[string]interface{}{
+		"gtid_executed": c.files.executedGTIDs().String(),
+	})
+}
+
+// nextTableIDLocked returns the id of the next table map event of the binlog.
+func (c *PrimaryController) nextTableIDLocked() uint64 {
+	c.tableID = (c.tableID + 1) % (1 << 48)
+	return c.tableID
+}
+
+// canReplicate returns whether |user| connected from |host| may stream the binlog.
+func (c *PrimaryController) canReplicate(user, host string) bool {
+	c.mu.Lock()
+	mysqlDb := c.mysqlDb
+	c.mu.Unlock()
+	if mysqlDb == nil {
+		return false
+	}
+	if !mysqlDb.Enabled {
+		return true
+	}
+	u := mysqlDb.GetUser(user, host, false)
+	return u != nil && u.PrivilegeSet.Has(sql.PrivilegeType_ReplicationSlave)
+}
+
+func (c *PrimaryController) addDump(d *binlogDump) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.dumps[d] = struct{}{}
+	return true
+}
+
+func (c *PrimaryController) removeDump(d *binlogDump) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.dumps, d)
+}
+
+func (c *PrimaryController) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// failed returns the error the binlog failed with, or nil if it didn't.
+func (c *PrimaryController) failed() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failure
+}
+
+// binlogStatusSchema holds the columns of MySQL's SHOW BINARY LOG STATUS.
+var binlogStatusSchema = sql.Schema{
+	&sql.Column{Name: "File", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Position", Type: sql.Int64, Nullable: false},
+	&sql.Column{Name: "Binlog_Do_DB", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Binlog_Ignore_DB", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Executed_Gtid_Set", Type: sql.LongText, Nullable: false},
+}
+
+// newShowBinaryLogStatusProcedure returns the dolt_show_binary_log_status() stored procedure, the equivalent of
+// MySQL's SHOW BINARY LOG STATUS.
+func newShowBinaryLogStatusProcedure(c *PrimaryController) sql.ExternalStoredProcedureDetails {
+	return sql.ExternalStoredProcedureDetails{
+		Name:   "dolt_show_binary_log_status",
+		Schema: binlogStatusSchema,
+		Function: func(ctx *sql.Context) (sql.RowIter, error) {
+			files, size, _ := c.files.status()
+			executed := c.files.executedGTIDs()
+			return sql.RowsToRowIter(sql.Row{files[len(files)-1], size, "", "", executed.String()}), nil
+		},
+	}
+}
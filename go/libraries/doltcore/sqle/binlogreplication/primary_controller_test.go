@@ -0,0 +1,359 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gms "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dtestutils"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+type testPrimary struct {
+	dEnv   *env.DoltEnv
+	engine *gms.Engine
+	ctx    *sql.Context
+	dir    string
+	cfg    *config.MapConfig
+	c      *PrimaryController
+}
+
+func newTestPrimary(t *testing.T) *testPrimary {
+	if !types.IsFormat_DOLT(types.Format_Default) {
+		// only the DOLT storage format has row-level diffs to log
+		t.Skip()
+	}
+	dEnv := dtestutils.CreateTestEnv()
+	engine, sqlCtx := newTestEngine(t, dEnv, "primary")
+	p := &testPrimary{dEnv: dEnv, engine: engine, ctx: sqlCtx, dir: t.TempDir(), cfg: config.NewMapConfig(map[string]string{})}
+	p.open(t)
+	t.Cleanup(func() {
+		p.c.Close()
+	})
+	return p
+}
+
+// open starts the binlog of the primary, as a sql-server does when it starts.
+func (p *testPrimary) open(t *testing.T) {
+	c, err := NewPrimaryController(logrus.New(), p.cfg, p.dir, 0)
+	require.NoError(t, err)
+	p.c = c
+
+	c.mu.Lock()
+	h, err := c.addHookLocked(p.ctx, testDB, p.dEnv)
+	c.mu.Unlock()
+	require.NoError(t, err)
+	require.NoError(t, c.logDatabase(p.ctx, h))
+}
+
+func (p *testPrimary) exec(t *testing.T, queries ...string) {
+	for _, query := range queries {
+		_, iter, err := p.engine.Query(p.ctx, query)
+		require.NoError(t, err)
+		_, err = sql.RowIterToRows(p.ctx, nil, iter)
+		require.NoError(t, err)
+	}
+}
+
+// events returns the events of the binlog files of the primary.
+func (p *testPrimary) events(t *testing.T) []mysql.BinlogEvent {
+	files, _, _ := p.c.files.status()
+	var events []mysql.BinlogEvent
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(p.dir, file))
+		require.NoError(t, err)
+		_, err = f.Seek(int64(len(binlogMagic)), io.SeekStart)
+		require.NoError(t, err)
+		for {
+			ev, err := readEvent(f)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			events = append(events, mysql.NewMysql56BinlogEvent(ev))
+		}
+		f.Close()
+	}
+	return events
+}
+
+func (p *testPrimary) executed() string {
+	return p.c.files.executedGTIDs().String()
+}
+
+func (p *testPrimary) serverUUID() string {
+	return p.cfg.GetStringOrDefault(persistentServerUUIDKey, "")
+}
+
+func TestPrimaryBinlogRows(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one'), (2, 'two'), (3, NULL)",
+		"UPDATE t SET c = 'deux' WHERE pk = 2",
+		"DELETE FROM t WHERE pk = 1",
+		"CREATE TABLE k (a INT, b TEXT)",
+		"INSERT INTO k VALUES (1, 'x'), (1, 'x'), (2, 'y')",
+		"DELETE FROM k WHERE a = 2",
+	)
+	// the baseline of the database, both CREATE TABLEs and the five transactions with rows
+	assert.Equal(t, p.serverUUID()+":1-8", p.executed())
+
+	r := newTestReplica(t)
+	r.apply(t, p.events(t)...)
+	assert.Equal(t, []sql.Row{{int32(2), "deux"}, {int32(3), nil}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+	assert.Equal(t, []sql.Row{{int32(1), "x"}, {int32(1), "x"}}, r.query(t, "SELECT * FROM k"))
+	require.NotEmpty(t, r.positions)
+	assert.Equal(t, p.executed(), r.positions[len(r.positions)-1].String())
+}
+
+func TestPrimaryBinlogSchemaChanges(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one'), (2, 'two')",
+		"ALTER TABLE t ADD COLUMN d INT",
+		"UPDATE t SET d = pk * 10",
+		"CREATE TABLE u (pk INT PRIMARY KEY, t_pk INT, FOREIGN KEY (t_pk) REFERENCES t (pk))",
+		"INSERT INTO u VALUES (1, 2)",
+		"RENAME TABLE u TO v",
+	)
+
+	r := newTestReplica(t)
+	r.apply(t, p.events(t)...)
+	assert.Equal(t, []sql.Row{{int32(1), "one", int32(10)}, {int32(2), "two", int32(20)}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+	assert.Equal(t, []sql.Row{{int32(1), int32(2)}}, r.query(t, "SELECT * FROM v"))
+	assert.Empty(t, r.query(t, "SHOW TABLES LIKE 'u'"))
+}
+
+func TestPrimaryBinlogRecovery(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one')",
+	)
+	executed := p.executed()
+	require.NoError(t, p.c.Close())
+
+	// nothing is logged again when the server restarts without changes
+	p.open(t)
+	assert.Equal(t, executed, p.executed())
+
+	// the changes made while the server is stopped are logged when it starts
+	require.NoError(t, p.c.Close())
+	p.exec(t, "INSERT INTO t VALUES (2, 'two')")
+	p.open(t)
+	assert.Equal(t, p.serverUUID()+":1-4", p.executed())
+
+	// an incomplete transaction at the end of the binlog is removed
+	require.NoError(t, p.c.Close())
+	files, size, _ := p.c.files.status()
+	f, err := os.OpenFile(filepath.Join(p.dir, files[len(files)-1]), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write(newBinlogEvent(gtidEventType, 0, 0, 1, make([]byte, 42))[:20])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	p.open(t)
+	st, err := os.Stat(filepath.Join(p.dir, files[len(files)-1]))
+	require.NoError(t, err)
+	// the file ends with the rotate event to the next one
+	assert.Equal(t, size+int64(eventHeaderLength+8+len(files[len(files)-1])+eventChecksumLength), st.Size())
+	p.exec(t, "INSERT INTO t VALUES (3, 'three')")
+
+	r := newTestReplica(t)
+	r.apply(t, p.events(t)...)
+	assert.Equal(t, []sql.Row{{int32(1), "one"}, {int32(2), "two"}, {int32(3), "three"}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+	assert.Equal(t, p.serverUUID()+":1-5", p.executed())
+}
+
+func TestPrimaryBinlogRotation(t *testing.T) {
+	p := newTestPrimary(t)
+	p.c.files.maxSize = 1024
+	p.exec(t, "CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(200))")
+	for i := 0; i < 8; i++ {
+		p.exec(t, fmt.Sprintf("INSERT INTO t VALUES (%d, REPEAT('x', 200))", i))
+	}
+	files, _, _ := p.c.files.status()
+	assert.Greater(t, len(files), 2)
+
+	r := newTestReplica(t)
+	r.apply(t, p.events(t)...)
+	assert.Equal(t, []sql.Row{{int64(8)}}, r.query(t, "SELECT COUNT(*) FROM t"))
+}
+
+// serve starts a sql-server for the primary with a root account, and returns its address.
+func (p *testPrimary) serve(t *testing.T) *net.TCPAddr {
+	p.engine.Analyzer.Catalog.MySQLDb.SetPersister(&mysql_db.NoopPersister{})
+	p.engine.Analyzer.Catalog.MySQLDb.AddRootAccount()
+	p.ctx.Session.SetClient(sql.Client{User: "root", Address: "localhost"})
+	s, err := p.c.NewServer(server.Config{Protocol: "tcp", Address: "127.0.0.1:0"}, p.engine, server.DefaultSessionBuilder, nil)
+	require.NoError(t, err)
+	go s.Start()
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s.Listener.Addr().(*net.TCPAddr)
+}
+
+// dumpBinlog returns the events the server at |addr| sends to |user|, for a replica which executed |gtids|.
+func dumpBinlog(t *testing.T, addr *net.TCPAddr, user, gtids string) ([]mysql.BinlogEvent, error) {
+	conn, err := mysql.Connect(context.Background(), &mysql.ConnParams{Host: addr.IP.String(), Port: addr.Port, Uname: user, Flavor: gtidFlavor})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecuteFetch("SET @master_binlog_checksum = @@global.binlog_checksum", 0, false)
+	require.NoError(t, err)
+
+	pos, err := mysql.ParsePosition(gtidFlavor, gtids)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteComBinlogDumpGTID(2, "", 4, dumpNonBlockFlag, pos.GTIDSet.(mysql.Mysql56GTIDSet).SIDBlock()))
+	var events []mysql.BinlogEvent
+	for {
+		ev, err := conn.ReadBinlogEvent()
+		var sqlErr *mysql.SQLError
+		if errors.As(err, &sqlErr) && sqlErr.Number() == mysql.CRServerLost {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+}
+
+func TestPrimaryBinlogDump(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one'), (2, 'two')",
+	)
+	addr := p.serve(t)
+	dump := func(user, gtids string) ([]mysql.BinlogEvent, error) {
+		return dumpBinlog(t, addr, user, gtids)
+	}
+
+	r := newTestReplica(t)
+	events, err := dump("root", "")
+	require.NoError(t, err)
+	r.apply(t, events...)
+	assert.Equal(t, []sql.Row{{int32(1), "one"}, {int32(2), "two"}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+
+	// a replica which executed the first transactions only receives the later ones
+	p.exec(t, "INSERT INTO t VALUES (3, 'three')")
+	events, err = dump("root", p.serverUUID()+":1-3")
+	require.NoError(t, err)
+	r.apply(t, events...)
+	assert.Equal(t, []sql.Row{{int32(1), "one"}, {int32(2), "two"}, {int32(3), "three"}}, r.query(t, "SELECT * FROM t ORDER BY pk"))
+	assert.Equal(t, p.executed(), r.positions[len(r.positions)-1].String())
+
+	// users without the REPLICATION SLAVE privilege can't stream the binlog
+	p.exec(t, "CREATE USER 'reader'@'localhost'", "GRANT SELECT ON *.* TO 'reader'@'localhost'")
+	_, err = dump("reader", "")
+	var sqlErr *mysql.SQLError
+	require.True(t, errors.As(err, &sqlErr), "unexpected error %v", err)
+	assert.Equal(t, mysql.ERSpecifiedAccessDenied, sqlErr.Number())
+}
+
+func TestPrimaryBinlogUnreadableRoot(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one')",
+	)
+	addr := p.serve(t)
+	executed := p.executed()
+
+	// the changes since the root the database was last logged at can't be logged if the root can't be read
+	p.c.mu.Lock()
+	p.c.roots[testDB] = hash.Of([]byte("missing"))
+	p.c.mu.Unlock()
+	p.exec(t, "INSERT INTO t VALUES (2, 'two')")
+	assert.Equal(t, executed, p.executed())
+
+	_, err := dumpBinlog(t, addr, "root", "")
+	var sqlErr *mysql.SQLError
+	require.True(t, errors.As(err, &sqlErr), "unexpected error %v", err)
+	assert.Equal(t, errSourceFatalReadingBinlog, sqlErr.Number())
+}
+
+func TestPrimaryBinlogDumpBeforeAuthentication(t *testing.T) {
+	p := newTestPrimary(t)
+	p.exec(t,
+		"CREATE TABLE t (pk INT PRIMARY KEY, c VARCHAR(20))",
+		"INSERT INTO t VALUES (1, 'one')",
+	)
+	addr := p.serve(t)
+	p.exec(t, "CREATE USER 'repl'@'%' IDENTIFIED BY 'secret'", "GRANT REPLICATION SLAVE ON *.* TO 'repl'@'%'")
+
+	conn, err := net.Dial("tcp", addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	readPacket := func() ([]byte, error) {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+		_, err := io.ReadFull(conn, payload)
+		return payload, err
+	}
+
+	// the handshake response of a client without the password of repl, with an auth method vitess switches from
+	_, err = readPacket()
+	require.NoError(t, err)
+	caps := uint32(mysql.CapabilityClientProtocol41 | mysql.CapabilityClientSecureConnection | mysql.CapabilityClientPluginAuth)
+	response := binary.LittleEndian.AppendUint32(nil, caps)
+	response = binary.LittleEndian.AppendUint32(response, maxPacketSize)
+	response = append(response, make([]byte, 24)...)
+	response = append(response, "repl\x00"...)
+	response = append(response, 0)
+	response = append(response, "caching_sha2_password\x00"...)
+	require.NoError(t, writePacket(conn, 1, response))
+	authSwitch, err := readPacket()
+	require.NoError(t, err)
+	require.Equal(t, byte(mysql.AuthSwitchRequestPacket), authSwitch[0])
+
+	// the client answers the auth switch with a dump, which must not be served
+	dump := []byte{mysql.ComBinlogDumpGTID}
+	dump = binary.LittleEndian.AppendUint16(dump, dumpNonBlockFlag)
+	dump = binary.LittleEndian.AppendUint32(dump, 2)
+	dump = binary.LittleEndian.AppendUint32(dump, 0)
+	dump = binary.LittleEndian.AppendUint64(dump, 4)
+	sids := mysql.Mysql56GTIDSet{}.SIDBlock()
+	dump = binary.LittleEndian.AppendUint32(dump, uint32(len(sids)))
+	require.NoError(t, writePacket(conn, 0, append(dump, sids...)))
+	reply, err := readPacket()
+	if err == nil {
+		assert.Equal(t, byte(mysql.ErrPacket), reply[0], "the binlog was sent to an unauthenticated client")
+	}
+}
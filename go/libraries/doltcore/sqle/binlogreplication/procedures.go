@@ -0,0 +1,157 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"strconv"
+
+	"github.com/dolthub/go-mysql-server/sql"
+)
+
+var statusSchema = sql.Schema{
+	&sql.Column{Name: "status", Type: sql.Int64, Nullable: false},
+}
+
+// replicaStatusSchema holds the columns of MySQL's SHOW REPLICA STATUS which apply to a Dolt replica.
+var replicaStatusSchema = sql.Schema{
+	&sql.Column{Name: "Replica_IO_State", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Source_Host", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Source_User", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Source_Port", Type: sql.Int64, Nullable: false},
+	&sql.Column{Name: "Connect_Retry", Type: sql.Int64, Nullable: false},
+	&sql.Column{Name: "Source_Log_File", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Read_Source_Log_Pos", Type: sql.Int64, Nullable: false},
+	&sql.Column{Name: "Replica_IO_Running", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Replica_SQL_Running", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Exec_Source_Log_Pos", Type: sql.Int64, Nullable: false},
+	&sql.Column{Name: "Last_IO_Error", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Last_SQL_Error", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Retrieved_Gtid_Set", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Executed_Gtid_Set", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "Auto_Position", Type: sql.Int64, Nullable: false},
+}
+
+// newChangeReplicationSourceProcedure returns the dolt_change_replication_source(options...) stored procedure, the
+// equivalent of MySQL's CHANGE REPLICATION SOURCE TO. Every option is a string of the form 'KEY=value'.
+func newChangeReplicationSourceProcedure(controller *Controller) sql.ExternalStoredProcedureDetails {
+	return sql.ExternalStoredProcedureDetails{
+		Name:   "dolt_change_replication_source",
+		Schema: statusSchema,
+		Function: func(ctx *sql.Context, options ...string) (sql.RowIter, error) {
+			err := controller.changeSource(options)
+			if err != nil {
+				return nil, err
+			}
+			return sql.RowsToRowIter(sql.Row{int64(0)}), nil
+		},
+	}
+}
+
+// newStartReplicaProcedure returns the dolt_start_replica() stored procedure, the equivalent of MySQL's START REPLICA.
+// The binlog of the source is applied with the privileges of the user who starts the replica.
+func newStartReplicaProcedure(controller *Controller) sql.ExternalStoredProcedureDetails {
+	return sql.ExternalStoredProcedureDetails{
+		Name:   "dolt_start_replica",
+		Schema: statusSchema,
+		Function: func(ctx *sql.Context) (sql.RowIter, error) {
+			err := controller.startReplica(ctx.Session.Client())
+			if err != nil {
+				return nil, err
+			}
+			return sql.RowsToRowIter(sql.Row{int64(0)}), nil
+		},
+	}
+}
+
+// newStopReplicaProcedure returns the dolt_stop_replica() stored procedure, the equivalent of MySQL's STOP REPLICA.
+func newStopReplicaProcedure(controller *Controller) sql.ExternalStoredProcedureDetails {
+	return sql.ExternalStoredProcedureDetails{
+		Name:   "dolt_stop_replica",
+		Schema: statusSchema,
+		Function: func(ctx *sql.Context) (sql.RowIter, error) {
+			err := controller.stopReplica()
+			if err != nil {
+				return nil, err
+			}
+			return sql.RowsToRowIter(sql.Row{int64(0)}), nil
+		},
+	}
+}
+
+// newShowReplicaStatusProcedure returns the dolt_show_replica_status() stored procedure, the equivalent of MySQL's
+// SHOW REPLICA STATUS.
+func newShowReplicaStatusProcedure(controller *Controller) sql.ExternalStoredProcedureDetails {
+	return sql.ExternalStoredProcedureDetails{
+		Name:   "dolt_show_replica_status",
+		Schema: replicaStatusSchema,
+		Function: func(ctx *sql.Context) (sql.RowIter, error) {
+			return sql.RowsToRowIter(controller.status()), nil
+		},
+	}
+}
+
+func (c *Controller) status() sql.Row {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ioRunning := c.ioRunning
+	if ioRunning == "" {
+		ioRunning = "No"
+	}
+	sqlRunning := "No"
+	if c.sqlRunning {
+		sqlRunning = "Yes"
+	}
+
+	readFile, readPos := splitFilePos(c.readPosition)
+	_, execPos := splitFilePos(c.position)
+	var retrievedGTIDs, executedGTIDs string
+	if positionFlavor(c.position) == gtidFlavor {
+		executedGTIDs = c.position.GTIDSet.String()
+	}
+	if positionFlavor(c.readPosition) == gtidFlavor {
+		retrievedGTIDs = c.readPosition.GTIDSet.String()
+	}
+	autoPosition := int64(0)
+	if c.source.AutoPosition {
+		autoPosition = 1
+	}
+
+	return sql.Row{
+		c.ioState,
+		c.source.Host,
+		c.source.User,
+		int64(c.source.Port),
+		int64(c.source.ConnectRetry),
+		readFile,
+		parsePos(readPos),
+		ioRunning,
+		sqlRunning,
+		parsePos(execPos),
+		c.lastIOError,
+		c.lastSQLError,
+		retrievedGTIDs,
+		executedGTIDs,
+		autoPosition,
+	}
+}
+
+func parsePos(s string) int64 {
+	pos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pos
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binlogreplication
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dolthub/vitess/go/mysql"
+
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+const (
+	// filePosFlavor is the flavor of binlog positions which are a binlog file name and an offset in it.
+	filePosFlavor = "FilePos"
+	// gtidFlavor is the flavor of binlog positions which are the set of MySQL GTIDs applied so far.
+	gtidFlavor = "MySQL56"
+
+	defaultSourcePort         = 3306
+	defaultSourceConnectRetry = 60
+	firstBinlogEventOffset    = 4
+)
+
+const (
+	persistentHostKey         = "source_host"
+	persistentPortKey         = "source_port"
+	persistentUserKey         = "source_user"
+	persistentPasswordKey     = "source_password"
+	persistentConnectRetryKey = "source_connect_retry"
+	persistentAutoPositionKey = "source_auto_position"
+	persistentDoltCommitKey   = "dolt_transaction_commit"
+	persistentPositionKey     = "position"
+	persistentSourceIDKey     = "source_id"
+	persistentSeqKey          = "position_seq"
+	persistentRunningKey      = "running"
+	persistentApplierUserKey  = "applier_user"
+	persistentApplierHostKey  = "applier_host"
+	persistentTrue            = "true"
+	persistentFalse           = "false"
+)
+
+// sourceConfig is the MySQL server a replica streams the binlog of.
+type sourceConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// ConnectRetry is the number of seconds between attempts to connect to the source.
+	ConnectRetry int
+	// AutoPosition is true if the replica tracks its position with GTIDs, and false if it tracks it with binlog file
+	// names and offsets.
+	AutoPosition bool
+	// DoltCommit is true if the replica makes a Dolt commit for every transaction it applies.
+	DoltCommit bool
+}
+
+func newSourceConfig() sourceConfig {
+	return sourceConfig{
+		Port:         defaultSourcePort,
+		ConnectRetry: defaultSourceConnectRetry,
+	}
+}
+
+func (s sourceConfig) positionFlavor() string {
+	if s.AutoPosition {
+		return gtidFlavor
+	}
+	return filePosFlavor
+}
+
+// changeSource applies the options of a dolt_change_replication_source call to |src| and |pos|. Every option is a
+// string of the form 'KEY=value', where KEY is the name of an option of MySQL's CHANGE REPLICATION SOURCE TO. Like
+// MySQL, changing the host or port of the source resets the position of the replica, unless the new position is given.
+func changeSource(src sourceConfig, pos mysql.Position, options []string) (sourceConfig, mysql.Position, error) {
+	resetPos := false
+	var logFile string
+	var logPos int
+	hasLogFile, hasLogPos := false, false
+	for _, opt := range options {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return src, pos, fmt.Errorf("invalid replication source option '%s', expected KEY=value", opt)
+		}
+		key, val := strings.ToUpper(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "SOURCE_HOST":
+			resetPos = resetPos || val != src.Host
+			src.Host = val
+		case "SOURCE_PORT":
+			var port int
+			port, err = parseIntOption(key, val)
+			resetPos = resetPos || port != src.Port
+			src.Port = port
+		case "SOURCE_USER":
+			src.User = val
+		case "SOURCE_PASSWORD":
+			src.Password = val
+		case "SOURCE_CONNECT_RETRY":
+			src.ConnectRetry, err = parseIntOption(key, val)
+		case "SOURCE_AUTO_POSITION":
+			src.AutoPosition, err = parseBoolOption(key, val)
+		case "SOURCE_LOG_FILE":
+			logFile, hasLogFile = val, true
+		case "SOURCE_LOG_POS":
+			logPos, err = parseIntOption(key, val)
+			hasLogPos = true
+		case "DOLT_TRANSACTION_COMMIT":
+			src.DoltCommit, err = parseBoolOption(key, val)
+		default:
+			return src, pos, fmt.Errorf("unknown replication source option '%s'", key)
+		}
+		if err != nil {
+			return src, pos, err
+		}
+	}
+
+	if src.AutoPosition && (hasLogFile || hasLogPos) {
+		return src, pos, fmt.Errorf("SOURCE_LOG_FILE and SOURCE_LOG_POS cannot be set when SOURCE_AUTO_POSITION is active")
+	}
+
+	if hasLogFile || hasLogPos {
+		if !hasLogFile {
+			logFile = currentLogFile(pos)
+			if logFile == "" {
+				return src, pos, fmt.Errorf("SOURCE_LOG_POS requires SOURCE_LOG_FILE")
+			}
+		}
+		if !hasLogPos {
+			logPos = firstBinlogEventOffset
+		}
+		newPos, err := mysql.ParsePosition(filePosFlavor, fmt.Sprintf("%s:%d", logFile, logPos))
+		return src, newPos, err
+	}
+
+	if resetPos || positionFlavor(pos) != src.positionFlavor() {
+		pos = mysql.Position{}
+	}
+	return src, pos, nil
+}
+
+func parseIntOption(key, val string) (int, error) {
+	i, err := strconv.Atoi(val)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid value for %s: '%s'", key, val)
+	}
+	return i, nil
+}
+
+func parseBoolOption(key, val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "1", "true", "on":
+		return true, nil
+	case "0", "false", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid value for %s: '%s'", key, val)
+	}
+}
+
+func positionFlavor(pos mysql.Position) string {
+	if pos.GTIDSet == nil {
+		return ""
+	}
+	return pos.GTIDSet.Flavor()
+}
+
+// currentLogFile returns the binlog file of a file and offset position, or the empty string for any other position.
+func currentLogFile(pos mysql.Position) string {
+	file, _ := splitFilePos(pos)
+	return file
+}
+
+// splitFilePos returns the binlog file name and offset of a file and offset position.
+func splitFilePos(pos mysql.Position) (string, string) {
+	if positionFlavor(pos) != filePosFlavor {
+		return "", ""
+	}
+	s := pos.GTIDSet.String()
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", ""
+	}
+	return s[:i], s[i+1:]
+}
+
+func boolToPersistent(b bool) string {
+	if b {
+		return persistentTrue
+	}
+	return persistentFalse
+}
+
+func loadSourceConfig(pCfg config.ReadableConfig) (sourceConfig, mysql.Position, error) {
+	src := newSourceConfig()
+	src.Host = pCfg.GetStringOrDefault(persistentHostKey, "")
+	src.User = pCfg.GetStringOrDefault(persistentUserKey, "")
+	src.Password = pCfg.GetStringOrDefault(persistentPasswordKey, "")
+	src.AutoPosition = pCfg.GetStringOrDefault(persistentAutoPositionKey, persistentFalse) == persistentTrue
+	src.DoltCommit = pCfg.GetStringOrDefault(persistentDoltCommitKey, persistentFalse) == persistentTrue
+
+	if pCfg.GetStringOrDefault(persistentPortKey, "") != "" {
+		port, err := config.GetInt(pCfg, persistentPortKey)
+		if err != nil {
+			return src, mysql.Position{}, fmt.Errorf("persisted replication source port is invalid: %w", err)
+		}
+		src.Port = int(port)
+	}
+	if pCfg.GetStringOrDefault(persistentConnectRetryKey, "") != "" {
+		retry, err := config.GetInt(pCfg, persistentConnectRetryKey)
+		if err != nil {
+			return src, mysql.Position{}, fmt.Errorf("persisted replication source connect retry is invalid: %w", err)
+		}
+		src.ConnectRetry = int(retry)
+	}
+
+	pos, err := mysql.DecodePosition(pCfg.GetStringOrDefault(persistentPositionKey, ""))
+	if err != nil {
+		return src, mysql.Position{}, fmt.Errorf("persisted replication position is invalid: %w", err)
+	}
+	return src, pos, nil
+}
+
+// loadPositionSeq returns the id of the source configuration of a replica, and the number of transactions it committed
+// with it.
+func loadPositionSeq(pCfg config.ReadableConfig) (string, uint64, error) {
+	sourceID := pCfg.GetStringOrDefault(persistentSourceIDKey, "")
+	seq, err := strconv.ParseUint(pCfg.GetStringOrDefault(persistentSeqKey, "0"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("persisted replication position sequence is invalid: %w", err)
+	}
+	return sourceID, seq, nil
+}
+
+func persistSourceConfig(pCfg config.WritableConfig, src sourceConfig, pos mysql.Position, sourceID string, seq uint64) error {
+	return pCfg.SetStrings(map[string]string{
+		persistentHostKey:         src.Host,
+		persistentPortKey:         strconv.Itoa(src.Port),
+		persistentUserKey:         src.User,
+		persistentPasswordKey:     src.Password,
+		persistentConnectRetryKey: strconv.Itoa(src.ConnectRetry),
+		persistentAutoPositionKey: boolToPersistent(src.AutoPosition),
+		persistentDoltCommitKey:   boolToPersistent(src.DoltCommit),
+		persistentPositionKey:     mysql.EncodePosition(pos),
+		persistentSourceIDKey:     sourceID,
+		persistentSeqKey:          strconv.FormatUint(seq, 10),
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/store/datas"
+)
+
+// commithook replicates a database to one standby. The whole database is replicated, including its working sets, by
+// pulling the chunks of the root of its chunk store into the standby and setting the standby's root to it. Since every
+// root includes the updates of the ones before it, replicating the latest root catches the standby up no matter how
+// many updates it missed.
+type commithook struct {
+	controller *Controller
+	remotename string
+	dbname     string
+	remoteUrl  string
+	srcDB      *doltdb.DoltDB
+	tempDir    string
+	dialer     dbfactory.GRPCDialProvider
+	lgr        *logrus.Entry
+
+	// mu serializes replication, so that an older root never replaces a newer one on the standby
+	mu     sync.Mutex
+	destDB *doltdb.DoltDB
+}
+
+var _ doltdb.ReplicationHook = (*commithook)(nil)
+
+func newCommitHook(controller *Controller, remotename, dbname, remoteUrl string, srcDB *doltdb.DoltDB, tempDir string, dialer dbfactory.GRPCDialProvider) *commithook {
+	return &commithook{
+		controller: controller,
+		remotename: remotename,
+		dbname:     dbname,
+		remoteUrl:  remoteUrl,
+		srcDB:      srcDB,
+		tempDir:    tempDir,
+		dialer:     dialer,
+		lgr:        controller.lgr.WithField("database", dbname).WithField("standby", remotename),
+	}
+}
+
+// Execute implements doltdb.CommitHook. Replication hooks are executed through Replicate instead.
+func (h *commithook) Execute(ctx context.Context, ds datas.Dataset, db datas.Database) error {
+	return nil
+}
+
+// HandleError implements doltdb.CommitHook
+func (h *commithook) HandleError(ctx context.Context, err error) error {
+	return nil
+}
+
+// SetLogger implements doltdb.CommitHook
+func (h *commithook) SetLogger(ctx context.Context, wr io.Writer) error {
+	return nil
+}
+
+// CheckWrite implements doltdb.ReplicationHook
+func (h *commithook) CheckWrite(ctx context.Context) error {
+	return h.controller.checkWrite()
+}
+
+// Replicate implements doltdb.ReplicationHook
+func (h *commithook) Replicate(ctx context.Context, db datas.Database) error {
+	return h.replicate(ctx)
+}
+
+func (h *commithook) replicate(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.replicateRoot(ctx)
+	if err != nil {
+		h.lgr.Warnf("failed to replicate to standby: %v", err)
+		// the remote chunk store retries failed requests already, so the write fails now. The connection may have
+		// been lost when the standby restarted, so the next write connects to it again.
+		h.destDB = nil
+		return fmt.Errorf("failed to replicate database %s to standby %s: %w", h.dbname, h.remotename, err)
+	}
+
+	return nil
+}
+
+func (h *commithook) replicateRoot(ctx context.Context) error {
+	if h.destDB == nil {
+		r := env.NewRemote(h.remotename, h.remoteUrl, nil)
+		destDB, err := r.GetRemoteDB(ctx, h.srcDB.Format(), h.dialer)
+		if err != nil {
+			return err
+		}
+		h.destDB = destDB
+	}
+
+	srcRoot, err := h.srcDB.NomsRoot(ctx)
+	if err != nil {
+		return err
+	}
+
+	destRoot, err := h.destDB.NomsRoot(ctx)
+	if err != nil {
+		return err
+	}
+
+	if srcRoot == destRoot {
+		return nil
+	}
+
+	err = h.destDB.PullChunks(ctx, h.tempDir, h.srcDB, srcRoot, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	ok, err := h.destDB.CommitRoot(ctx, srcRoot, destRoot)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	// the root of the standby changed since it was last read, most likely because it was replicated to by a
+	// different server before this one became the primary.
+	err = h.destDB.Rebase(ctx)
+	if err != nil {
+		return err
+	}
+
+	destRoot, err = h.destDB.NomsRoot(ctx)
+	if err != nil {
+		return err
+	}
+
+	ok, err = h.destDB.CommitRoot(ctx, srcRoot, destRoot)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("the root of the standby changed while it was being replicated to")
+	}
+
+	return nil
+}
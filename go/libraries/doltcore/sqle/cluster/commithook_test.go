@@ -0,0 +1,70 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/utils/filesys"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+// unusedPort returns a local port that nothing listens on.
+func unusedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	return port
+}
+
+func TestReplicateToUnreachableStandby(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	urlStr := fmt.Sprintf("file://%s", filepath.ToSlash(dir))
+	ddb, err := doltdb.LoadDoltDB(ctx, types.Format_Default, urlStr, filesys.LocalFS)
+	require.NoError(t, err)
+	require.NoError(t, ddb.WriteEmptyRepo(ctx, "main", "Bill Billerson", "bigbillieb@fake.horse"))
+
+	c, _ := newTestController(t, "primary", 1)
+	remoteURL := fmt.Sprintf("http://127.0.0.1:%d/db", unusedPort(t))
+	hook := newCommitHook(c, "standby", "db", remoteURL, ddb, t.TempDir(), env.NewGRPCDialProvider())
+	ddb.SetCommitHooks(ctx, []doltdb.CommitHook{hook})
+
+	cs, err := doltdb.NewCommitSpec("main")
+	require.NoError(t, err)
+	main, err := ddb.Resolve(ctx, cs, nil)
+	require.NoError(t, err)
+
+	err = ddb.NewBranchAtCommit(ctx, ref.NewBranchRef("other"), main)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, doltdb.ErrUnreplicatedWrite), err.Error())
+
+	// the write is durable even though it wasn't replicated
+	ok, err := ddb.HasBranch(ctx, "other")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Config is the cluster configuration of a sql-server.
+type Config interface {
+	// StandbyRemotes are the other servers in the cluster. A server replicates its databases to all of them while it
+	// is the primary.
+	StandbyRemotes() []StandbyRemoteConfig
+	// BootstrapRole is the role of the server the first time it starts in the cluster. Afterwards, the role is
+	// persisted with the server's configuration.
+	BootstrapRole() string
+	// BootstrapEpoch is the epoch of the server the first time it starts in the cluster.
+	BootstrapEpoch() int
+	// RemotesAPIConfig configures the remotesapi server which cluster peers replicate to.
+	RemotesAPIConfig() RemotesAPIConfig
+}
+
+// StandbyRemoteConfig is a peer in the cluster.
+type StandbyRemoteConfig interface {
+	Name() string
+	// RemoteURLTemplate is the url of the databases of the peer, in which {database} is replaced by the name of a
+	// database.
+	RemoteURLTemplate() string
+}
+
+// RemotesAPIConfig configures the remotesapi server of the cluster.
+type RemotesAPIConfig interface {
+	Port() int
+	// Secret is shared by all servers of the cluster. Peers send it with every request to the remotesapi server, which
+	// rejects the requests which don't carry it.
+	Secret() string
+}
+
+const databaseTemplateParam = "{database}"
+
+// ValidateConfig returns an error if |cfg| is not a valid cluster configuration.
+func ValidateConfig(cfg Config) error {
+	remotes := cfg.StandbyRemotes()
+	if len(remotes) == 0 {
+		return fmt.Errorf("cluster: standby_remotes must list at least one standby")
+	}
+
+	names := make(map[string]struct{}, len(remotes))
+	for _, r := range remotes {
+		if r.Name() == "" {
+			return fmt.Errorf("cluster: standby_remotes: every standby remote must have a name")
+		}
+		if _, ok := names[r.Name()]; ok {
+			return fmt.Errorf("cluster: standby_remotes: duplicate standby remote name %s", r.Name())
+		}
+		names[r.Name()] = struct{}{}
+
+		if !strings.Contains(r.RemoteURLTemplate(), databaseTemplateParam) {
+			return fmt.Errorf("cluster: standby_remotes: remote_url_template of %s must contain %s", r.Name(), databaseTemplateParam)
+		}
+	}
+
+	if _, err := parseRole(cfg.BootstrapRole()); err != nil {
+		return fmt.Errorf("cluster: bootstrap_role: %w", err)
+	}
+	if cfg.BootstrapEpoch() < 0 {
+		return fmt.Errorf("cluster: bootstrap_epoch must not be negative: %d", cfg.BootstrapEpoch())
+	}
+
+	port := cfg.RemotesAPIConfig().Port()
+	if port < 1024 || port > 65535 {
+		return fmt.Errorf("cluster: remotesapi.port is not in the range between 1024-65535: %d", port)
+	}
+	if cfg.RemotesAPIConfig().Secret() == "" {
+		return fmt.Errorf("cluster: remotesapi.secret must be set")
+	}
+
+	return nil
+}
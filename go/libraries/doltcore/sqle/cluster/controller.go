@@ -0,0 +1,379 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+// Role is the role of a server in its cluster.
+type Role string
+
+const (
+	// RolePrimary is the role of the server which accepts writes and replicates them to the standbys.
+	RolePrimary Role = "primary"
+	// RoleStandby is the role of the servers which are replicated to. Standbys reject writes.
+	RoleStandby Role = "standby"
+)
+
+const (
+	// DoltClusterRoleVariable is the read-only system variable holding the role of the server.
+	DoltClusterRoleVariable = "dolt_cluster_role"
+	// DoltClusterRoleEpochVariable is the read-only system variable holding the epoch of the role of the server.
+	DoltClusterRoleEpochVariable = "dolt_cluster_role_epoch"
+
+	persistentRoleKey  = "cluster_role"
+	persistentEpochKey = "cluster_role_epoch"
+)
+
+// ErrNotPrimary is returned by writes to the databases of a server which is not the primary of its cluster.
+var ErrNotPrimary = errors.New("this server is a standby in a cluster and does not accept writes")
+
+func parseRole(role string) (Role, error) {
+	switch Role(strings.ToLower(role)) {
+	case RolePrimary:
+		return RolePrimary, nil
+	case RoleStandby:
+		return RoleStandby, nil
+	default:
+		return "", fmt.Errorf("invalid role '%s', expected '%s' or '%s'", role, RolePrimary, RoleStandby)
+	}
+}
+
+// Controller manages the role of a sql-server in its cluster. The databases of a primary are replicated to all
+// standbys before writes to them are acknowledged, and the databases of a standby reject writes. Every role is held at
+// an epoch. A server only takes a new role at a newer epoch than the one it holds, and replication between servers at
+// different epochs resolves in favor of the newer epoch, so a former primary which was failed over steps down when it
+// comes back.
+type Controller struct {
+	cfg           Config
+	persistentCfg config.ReadWriteConfig
+	lgr           *logrus.Entry
+
+	mu          sync.Mutex
+	role        Role
+	epoch       int
+	commithooks []*commithook
+	dialer      dbfactory.GRPCDialProvider
+	sysvars     sqlvars
+	mysqlDb     *mysql_db.MySQLDb
+
+	cinterceptor clientinterceptor
+	sinterceptor serverinterceptor
+}
+
+type sqlvars interface {
+	AddSystemVariables(sysVars []sql.SystemVariable)
+	AssignValues(vals map[string]interface{}) error
+}
+
+type procedurestore interface {
+	RegisterProcedure(procedure sql.ExternalStoredProcedureDetails)
+}
+
+// NewController returns a Controller for the cluster configured by |cfg|, or nil if |cfg| is nil. The role and epoch
+// of the server are read from |pCfg|, and the bootstrap role and epoch of |cfg| are used if they were never persisted.
+func NewController(lgr *logrus.Logger, cfg Config, pCfg config.ReadWriteConfig) (*Controller, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	role, epoch, err := loadRoleAndEpoch(cfg, pCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Controller{
+		cfg:           cfg,
+		persistentCfg: pCfg,
+		lgr:           lgr.WithField("component", "dolt.cluster"),
+		role:          role,
+		epoch:         epoch,
+	}
+	c.cinterceptor.controller = c
+	c.sinterceptor.controller = c
+
+	err = c.persistRoleAndEpoch()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func loadRoleAndEpoch(cfg Config, pCfg config.ReadWriteConfig) (Role, int, error) {
+	roleStr := pCfg.GetStringOrDefault(persistentRoleKey, "")
+	if roleStr == "" {
+		role, err := parseRole(cfg.BootstrapRole())
+		return role, cfg.BootstrapEpoch(), err
+	}
+
+	role, err := parseRole(roleStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("persisted cluster role is invalid: %w", err)
+	}
+
+	epoch, err := config.GetInt(pCfg, persistentEpochKey)
+	if err != nil {
+		return "", 0, fmt.Errorf("persisted cluster role epoch is invalid: %w", err)
+	}
+
+	return role, int(epoch), nil
+}
+
+// ManageSystemVariables adds the read-only system variables holding the role and epoch of the server to |variables|.
+func (c *Controller) ManageSystemVariables(variables sqlvars) error {
+	if c == nil {
+		return nil
+	}
+
+	variables.AddSystemVariables([]sql.SystemVariable{
+		{
+			Name:              DoltClusterRoleVariable,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemStringType(DoltClusterRoleVariable),
+			Default:           "",
+		},
+		{
+			Name:              DoltClusterRoleEpochVariable,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemIntType(DoltClusterRoleEpochVariable, 0, 9223372036854775807, false),
+			Default:           int64(0),
+		},
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sysvars = variables
+	return c.assignSystemVariables()
+}
+
+func (c *Controller) assignSystemVariables() error {
+	if c.sysvars == nil {
+		return nil
+	}
+	return c.sysvars.AssignValues(map[string]interface{}{
+		DoltClusterRoleVariable:      string(c.role),
+		DoltClusterRoleEpochVariable: int64(c.epoch),
+	})
+}
+
+// RegisterStoredProcedures adds the stored procedures which change the role of the server to |store|.
+func (c *Controller) RegisterStoredProcedures(store procedurestore) {
+	if c == nil {
+		return
+	}
+	store.RegisterProcedure(newAssumeRoleProcedure(c))
+}
+
+// ManagePrivileges makes the stored procedures of the controller check the privileges of their callers in |mysqlDb|.
+// Until it is called, the stored procedures reject all callers.
+func (c *Controller) ManagePrivileges(mysqlDb *mysql_db.MySQLDb) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mysqlDb = mysqlDb
+}
+
+// checkSuper returns an error unless the user of |ctx| has the SUPER privilege.
+func (c *Controller) checkSuper(ctx *sql.Context) error {
+	c.mu.Lock()
+	mysqlDb := c.mysqlDb
+	c.mu.Unlock()
+
+	if mysqlDb == nil || mysqlDb.Enabled && !mysqlDb.UserHasPrivileges(ctx, sql.NewPrivilegedOperation("", "", "", sql.PrivilegeType_Super)) {
+		return sql.ErrPrivilegeCheckFailed.New(ctx.Session.Client().User)
+	}
+	return nil
+}
+
+// ApplyCommitHooks adds the hooks which replicate the databases of |mrEnv| to the standbys of the cluster, dialing
+// the standbys with |dialer|.
+func (c *Controller) ApplyCommitHooks(ctx context.Context, mrEnv *env.MultiRepoEnv, dialer dbfactory.GRPCDialProvider) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.dialer = c.gRPCDialProvider(dialer)
+	c.mu.Unlock()
+
+	return mrEnv.Iter(func(name string, dEnv *env.DoltEnv) (stop bool, err error) {
+		if dEnv.DoltDB == nil {
+			return false, nil
+		}
+		c.applyCommitHooks(ctx, name, dEnv)
+		return false, nil
+	})
+}
+
+func (c *Controller) applyCommitHooks(ctx context.Context, name string, dEnv *env.DoltEnv) []*commithook {
+	tempDir := dEnv.TempTableFilesDir()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var hooks []*commithook
+	for _, r := range c.cfg.StandbyRemotes() {
+		remoteUrl := strings.ReplaceAll(r.RemoteURLTemplate(), databaseTemplateParam, name)
+		hook := newCommitHook(c, r.Name(), name, remoteUrl, dEnv.DoltDB, tempDir, c.dialer)
+		dEnv.DoltDB.AddCommitHooks(ctx, hook)
+		hooks = append(hooks, hook)
+	}
+	c.commithooks = append(c.commithooks, hooks...)
+
+	return hooks
+}
+
+// ManageDatabaseProvider returns a copy of |pro| which replicates the databases it creates.
+func (c *Controller) ManageDatabaseProvider(pro sqle.DoltDatabaseProvider) sqle.DoltDatabaseProvider {
+	if c == nil {
+		return pro
+	}
+
+	return pro.WithInitDatabaseHook(func(ctx *sql.Context, pro sqle.DoltDatabaseProvider, name string, dEnv *env.DoltEnv) error {
+		// the databases of a standby are only created when the primary replicates them
+		if !isReplicationContext(ctx) {
+			if err := c.checkWrite(); err != nil {
+				return err
+			}
+		}
+
+		hooks := c.applyCommitHooks(ctx, name, dEnv)
+		if c.checkWrite() != nil {
+			return nil
+		}
+
+		for _, hook := range hooks {
+			err := hook.replicate(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoteSrvServerArgs returns the arguments of the remotesapi server which the other servers of the cluster
+// replicate to. The databases it serves are those of the sessions of contexts made by |ctxFactory|.
+func (c *Controller) RemoteSrvServerArgs(ctxFactory func(context.Context) (*sql.Context, error)) remotesrv.ServerArgs {
+	port := c.cfg.RemotesAPIConfig().Port()
+	return remotesrv.ServerArgs{
+		Logger:   c.lgr.WithField("component", "dolt.cluster.remotesapi"),
+		HttpPort: port,
+		GrpcPort: port,
+		DBCache:  standbyDBCache{ctxFactory},
+		Options:  c.sinterceptor.Options(),
+	}
+}
+
+// Role returns the role of the server and the epoch it holds it at.
+func (c *Controller) Role() (Role, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.role, c.epoch
+}
+
+// checkWrite returns ErrNotPrimary if the server is not the primary.
+func (c *Controller) checkWrite() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.role != RolePrimary {
+		return ErrNotPrimary
+	}
+	return nil
+}
+
+// setRoleAndEpoch makes the server assume |role| at |epoch|. If |graceful| is true and the server steps down from
+// primary to standby, its databases are replicated to all standbys before it returns, and the server stays the
+// primary if that fails.
+func (c *Controller) setRoleAndEpoch(ctx context.Context, roleStr string, epoch int, graceful bool) error {
+	role, err := parseRole(roleStr)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if epoch == c.epoch && role == c.role {
+		c.mu.Unlock()
+		return nil
+	}
+	if epoch == c.epoch {
+		defer c.mu.Unlock()
+		return fmt.Errorf("error assuming role '%s' at epoch %d: the server is already %s at epoch %d", role, epoch, c.role, c.epoch)
+	}
+	if epoch < c.epoch {
+		defer c.mu.Unlock()
+		return fmt.Errorf("error assuming role '%s' at epoch %d: the server is already at the newer epoch %d", role, epoch, c.epoch)
+	}
+
+	prevRole, prevEpoch := c.role, c.epoch
+	c.role, c.epoch = role, epoch
+	hooks := append([]*commithook{}, c.commithooks...)
+	c.mu.Unlock()
+
+	if graceful && prevRole == RolePrimary && role == RoleStandby {
+		for _, hook := range hooks {
+			err = hook.replicate(ctx)
+			if err != nil {
+				c.mu.Lock()
+				if c.epoch == epoch {
+					c.role, c.epoch = prevRole, prevEpoch
+				}
+				c.mu.Unlock()
+				return fmt.Errorf("error assuming role '%s' at epoch %d: the standbys could not be brought up to date: %w", role, epoch, err)
+			}
+		}
+	}
+
+	c.lgr.Infof("assumed role %s at epoch %d, was %s at epoch %d", role, epoch, prevRole, prevEpoch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = c.persistRoleAndEpoch()
+	if err != nil {
+		return err
+	}
+	return c.assignSystemVariables()
+}
+
+func (c *Controller) persistRoleAndEpoch() error {
+	return c.persistentCfg.SetStrings(map[string]string{
+		persistentRoleKey:  string(c.role),
+		persistentEpochKey: strconv.Itoa(c.epoch),
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/mysql_db"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/libraries/utils/config"
+)
+
+type testConfig struct {
+	role  string
+	epoch int
+}
+
+func (c testConfig) StandbyRemotes() []StandbyRemoteConfig {
+	return []StandbyRemoteConfig{testRemoteConfig{}}
+}
+
+func (c testConfig) BootstrapRole() string {
+	return c.role
+}
+
+func (c testConfig) BootstrapEpoch() int {
+	return c.epoch
+}
+
+func (c testConfig) RemotesAPIConfig() RemotesAPIConfig {
+	return testRemotesAPIConfig{}
+}
+
+type testRemoteConfig struct{}
+
+func (testRemoteConfig) Name() string {
+	return "standby"
+}
+
+func (testRemoteConfig) RemoteURLTemplate() string {
+	return "http://localhost:50051/{database}"
+}
+
+type testRemotesAPIConfig struct{}
+
+func (testRemotesAPIConfig) Port() int {
+	return 50051
+}
+
+func (testRemotesAPIConfig) Secret() string {
+	return "secret"
+}
+
+func newTestController(t *testing.T, role string, epoch int) (*Controller, config.ReadWriteConfig) {
+	pCfg := config.NewMapConfig(map[string]string{})
+	c, err := NewController(logrus.New(), testConfig{role, epoch}, pCfg)
+	require.NoError(t, err)
+	return c, pCfg
+}
+
+func TestNewController(t *testing.T) {
+	c, err := NewController(logrus.New(), nil, config.NewMapConfig(map[string]string{}))
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	c, pCfg := newTestController(t, "standby", 2)
+	role, epoch := c.Role()
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 2, epoch)
+	assert.Equal(t, ErrNotPrimary, c.checkWrite())
+
+	// the persisted role wins over the bootstrap role
+	require.NoError(t, pCfg.SetStrings(map[string]string{persistentRoleKey: "primary", persistentEpochKey: "5"}))
+	c, err = NewController(logrus.New(), testConfig{"standby", 2}, pCfg)
+	require.NoError(t, err)
+	role, epoch = c.Role()
+	assert.Equal(t, RolePrimary, role)
+	assert.Equal(t, 5, epoch)
+	assert.NoError(t, c.checkWrite())
+}
+
+func TestSetRoleAndEpoch(t *testing.T) {
+	ctx := context.Background()
+	c, pCfg := newTestController(t, "primary", 1)
+
+	assert.NoError(t, c.setRoleAndEpoch(ctx, "primary", 1, true))
+	assert.Error(t, c.setRoleAndEpoch(ctx, "standby", 1, true))
+	assert.Error(t, c.setRoleAndEpoch(ctx, "leader", 2, true))
+
+	require.NoError(t, c.setRoleAndEpoch(ctx, "standby", 2, true))
+	role, epoch := c.Role()
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 2, epoch)
+	assert.Equal(t, "standby", pCfg.GetStringOrDefault(persistentRoleKey, ""))
+	assert.Equal(t, "2", pCfg.GetStringOrDefault(persistentEpochKey, ""))
+
+	assert.Error(t, c.setRoleAndEpoch(ctx, "primary", 1, true))
+
+	require.NoError(t, c.setRoleAndEpoch(ctx, "primary", 4, true))
+	role, epoch = c.Role()
+	assert.Equal(t, RolePrimary, role)
+	assert.Equal(t, 4, epoch)
+}
+
+func TestAssumeRoleProcedurePrivileges(t *testing.T) {
+	c, _ := newTestController(t, "primary", 1)
+	assumeRole := newAssumeRoleProcedure(c).Function.(func(*sql.Context, string, int) (sql.RowIter, error))
+	sqlCtx := func(user string) *sql.Context {
+		client := sql.Client{User: user, Address: "localhost"}
+		return sql.NewContext(context.Background(), sql.WithSession(sql.NewBaseSessionWithClientServer("", client, 1)))
+	}
+
+	// the procedure rejects all callers until the controller manages privileges
+	_, err := assumeRole(sqlCtx("root"), "standby", 2)
+	assert.True(t, sql.ErrPrivilegeCheckFailed.Is(err))
+
+	mysqlDb := mysql_db.CreateEmptyMySQLDb()
+	mysqlDb.AddSuperUser("root", "localhost", "")
+	c.ManagePrivileges(mysqlDb)
+
+	_, err = assumeRole(sqlCtx("guest"), "standby", 2)
+	assert.True(t, sql.ErrPrivilegeCheckFailed.Is(err))
+	role, epoch := c.Role()
+	assert.Equal(t, RolePrimary, role)
+	assert.Equal(t, 1, epoch)
+
+	_, err = assumeRole(sqlCtx("root"), "standby", 2)
+	require.NoError(t, err)
+	role, epoch = c.Role()
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 2, epoch)
+}
+
+func TestServerInterceptorAuthenticate(t *testing.T) {
+	c, _ := newTestController(t, "primary", 2)
+	unary := c.sinterceptor.Unary()
+	call := func(md metadata.MD) error {
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	// requests without the secret can't make the primary step down
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(roleMetadata(RolePrimary, 3))))
+	md := metadata.Join(roleMetadata(RolePrimary, 3), metadata.Pairs(clusterSecretHeader, "guess"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(md)))
+	role, epoch := c.Role()
+	assert.Equal(t, RolePrimary, role)
+	assert.Equal(t, 2, epoch)
+
+	// the secret is sent by the client interceptor of a peer
+	peer, _ := newTestController(t, "primary", 3)
+	assert.NoError(t, call(peer.peerMetadata(peer.Role())))
+	role, epoch = c.Role()
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 3, epoch)
+}
+
+func TestServerInterceptorCheck(t *testing.T) {
+	incoming := func(role Role, epoch int) context.Context {
+		return metadata.NewIncomingContext(context.Background(), roleMetadata(role, epoch))
+	}
+
+	c, _ := newTestController(t, "standby", 2)
+	_, _, err := c.sinterceptor.check(context.Background())
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, _, err = c.sinterceptor.check(incoming(RolePrimary, 1))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// a standby accepts requests without taking the epoch of the primary
+	role, epoch, err := c.sinterceptor.check(incoming(RolePrimary, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 2, epoch)
+
+	c, _ = newTestController(t, "primary", 2)
+	_, _, err = c.sinterceptor.check(incoming(RolePrimary, 2))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	role, _ = c.Role()
+	assert.Equal(t, RolePrimary, role)
+
+	// a primary which hears from a newer epoch steps down
+	role, epoch, err = c.sinterceptor.check(incoming(RolePrimary, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 3, epoch)
+}
+
+func TestClientInterceptorHandlePeerRole(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestController(t, "primary", 2)
+
+	c.cinterceptor.handlePeerRole(ctx, 2, RoleStandby, 5)
+	c.cinterceptor.handlePeerRole(ctx, 2, RolePrimary, 2)
+	role, epoch := c.Role()
+	assert.Equal(t, RolePrimary, role)
+	assert.Equal(t, 2, epoch)
+
+	c.cinterceptor.handlePeerRole(ctx, 2, RolePrimary, 3)
+	role, epoch = c.Role()
+	assert.Equal(t, RoleStandby, role)
+	assert.Equal(t, 3, epoch)
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/store/datas"
+)
+
+type replicationContextKey struct{}
+
+// withReplication returns a context for the updates made to a standby by replication.
+func withReplication(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicationContextKey{}, true)
+}
+
+func isReplicationContext(ctx context.Context) bool {
+	v, _ := ctx.Value(replicationContextKey{}).(bool)
+	return v
+}
+
+// standbyDBCache is the remotesrv.DBCache of the cluster remotesapi server. It serves the chunk stores of the databases
+// of the server as they are, so that the primary can replace their roots, and creates the databases which the primary
+// replicates but the server doesn't have yet.
+type standbyDBCache struct {
+	ctxFactory func(context.Context) (*sql.Context, error)
+}
+
+var _ remotesrv.DBCache = standbyDBCache{}
+
+func (c standbyDBCache) Get(ctx context.Context, org, repo, nbfVerStr string) (remotesrv.RemoteSrvStore, error) {
+	if org != "" {
+		return nil, status.Errorf(codes.NotFound, "database not found: %s/%s", org, repo)
+	}
+
+	sqlCtx, err := c.ctxFactory(withReplication(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	pro := dsess.DSessFromSess(sqlCtx.Session).Provider()
+	if !pro.HasDatabase(sqlCtx, repo) {
+		mpro, ok := pro.(sql.MutableDatabaseProvider)
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "database not found: %s", repo)
+		}
+		err = mpro.CreateDatabase(sqlCtx, repo)
+		if err != nil && !sql.ErrDatabaseExists.Is(err) {
+			return nil, err
+		}
+	}
+
+	db, err := pro.Database(sqlCtx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDb, ok := db.(sqle.SqlDatabase)
+	if !ok || sqlDb.DbData().Ddb == nil {
+		return nil, status.Errorf(codes.NotFound, "database not found: %s", repo)
+	}
+
+	cs := datas.ChunkStoreFromDatabase(doltdb.HackDatasDatabaseFromDoltDB(sqlDb.DbData().Ddb))
+	rss, ok := cs.(remotesrv.RemoteSrvStore)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "database %s can not be replicated to", repo)
+	}
+
+	return rss, nil
+}
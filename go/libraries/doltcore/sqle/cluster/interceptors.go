@@ -0,0 +1,241 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/dbfactory"
+	"github.com/dolthub/dolt/go/libraries/doltcore/grpcendpoint"
+)
+
+const (
+	clusterRoleHeader      = "x-dolt-cluster-role"
+	clusterRoleEpochHeader = "x-dolt-cluster-role-epoch"
+	clusterSecretHeader    = "x-dolt-cluster-secret"
+)
+
+func roleMetadata(role Role, epoch int) metadata.MD {
+	return metadata.Pairs(clusterRoleHeader, string(role), clusterRoleEpochHeader, strconv.Itoa(epoch))
+}
+
+// peerMetadata is the metadata sent with the requests of a server at |role| and |epoch| to its peers.
+func (c *Controller) peerMetadata(role Role, epoch int) metadata.MD {
+	return metadata.Join(roleMetadata(role, epoch), metadata.Pairs(clusterSecretHeader, c.cfg.RemotesAPIConfig().Secret()))
+}
+
+// roleFromMetadata returns the role and epoch of a cluster peer sent in |md|.
+func roleFromMetadata(md metadata.MD) (Role, int, bool) {
+	roles := md.Get(clusterRoleHeader)
+	epochs := md.Get(clusterRoleEpochHeader)
+	if len(roles) != 1 || len(epochs) != 1 {
+		return "", 0, false
+	}
+
+	role, err := parseRole(roles[0])
+	if err != nil {
+		return "", 0, false
+	}
+
+	epoch, err := strconv.Atoi(epochs[0])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return role, epoch, true
+}
+
+// clientinterceptor sends the role and epoch of the server, along with the secret of the cluster, with the requests it
+// makes to its standbys. If a standby
+// responds that it is the primary at a newer epoch, the server was failed over and steps down to standby.
+type clientinterceptor struct {
+	controller *Controller
+}
+
+func (ci *clientinterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		role, epoch := ci.controller.Role()
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Join(outgoingMetadata(ctx), ci.controller.peerMetadata(role, epoch)))
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func (ci *clientinterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		role, epoch := ci.controller.Role()
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Join(outgoingMetadata(ctx), ci.controller.peerMetadata(role, epoch)))
+
+		var header, trailer metadata.MD
+		opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		peerRole, peerEpoch, ok := roleFromMetadata(header)
+		if !ok {
+			peerRole, peerEpoch, ok = roleFromMetadata(trailer)
+		}
+		if ok {
+			ci.handlePeerRole(ctx, epoch, peerRole, peerEpoch)
+		}
+
+		return err
+	}
+}
+
+func (ci *clientinterceptor) handlePeerRole(ctx context.Context, epoch int, peerRole Role, peerEpoch int) {
+	if peerRole != RolePrimary {
+		return
+	}
+
+	if peerEpoch > epoch {
+		ci.controller.lgr.Warnf("a standby is the primary at epoch %d, stepping down to standby", peerEpoch)
+		err := ci.controller.setRoleAndEpoch(ctx, string(RoleStandby), peerEpoch, false)
+		if err != nil {
+			ci.controller.lgr.Errorf("failed to step down to standby: %v", err)
+		}
+	} else if peerEpoch == epoch {
+		ci.controller.lgr.Errorf("a standby is also the primary at epoch %d; the cluster is misconfigured", epoch)
+	}
+}
+
+func (ci *clientinterceptor) Options() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(ci.Unary()),
+		grpc.WithChainStreamInterceptor(ci.Stream()),
+	}
+}
+
+func outgoingMetadata(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md
+}
+
+// serverinterceptor guards the remotesapi server of the cluster. Requests must carry the secret of the cluster, which
+// authenticates them as sent by a cluster peer, and must be sent at an epoch at least as new as the server's. A standby accepts them. A primary which receives a request from a newer epoch was
+// failed over, so it steps down to standby and accepts the request. Otherwise the primary rejects it. The role and
+// epoch of the server are sent back with every response.
+type serverinterceptor struct {
+	controller *Controller
+}
+
+func (si *serverinterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := si.authenticate(ss.Context()); err != nil {
+			return err
+		}
+		role, epoch, err := si.check(ss.Context())
+		md := roleMetadata(role, epoch)
+		_ = ss.SetHeader(md)
+		ss.SetTrailer(md)
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (si *serverinterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := si.authenticate(ctx); err != nil {
+			return nil, err
+		}
+		role, epoch, err := si.check(ctx)
+		md := roleMetadata(role, epoch)
+		_ = grpc.SetHeader(ctx, md)
+		_ = grpc.SetTrailer(ctx, md)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticate returns an error if the request of |ctx| doesn't carry the secret of the cluster. Requests are
+// authenticated before they are checked, so that nobody but the peers of the cluster can change the role of the server
+// or learn it.
+func (si *serverinterceptor) authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	secrets := md.Get(clusterSecretHeader)
+	expected := si.controller.cfg.RemotesAPIConfig().Secret()
+	if len(secrets) != 1 || expected == "" || subtle.ConstantTimeCompare([]byte(secrets[0]), []byte(expected)) != 1 {
+		return status.Error(codes.Unauthenticated, "the cluster remotesapi only serves the peers of the cluster")
+	}
+	return nil
+}
+
+// check returns an error if the authenticated request of |ctx| must be rejected, along with the role and epoch of the
+// server after the request.
+func (si *serverinterceptor) check(ctx context.Context) (Role, int, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	peerRole, peerEpoch, ok := roleFromMetadata(md)
+
+	role, epoch := si.controller.Role()
+	if !ok {
+		return role, epoch, status.Error(codes.PermissionDenied, "requests to the cluster remotesapi must carry the role and epoch of the peer")
+	}
+
+	if peerEpoch < epoch {
+		return role, epoch, status.Errorf(codes.FailedPrecondition, "this server is %s at epoch %d, which is newer than the epoch %d of the request", role, epoch, peerEpoch)
+	}
+
+	if role == RoleStandby {
+		return role, epoch, nil
+	}
+
+	if peerEpoch == epoch {
+		si.controller.lgr.Errorf("received a request from a %s at epoch %d while primary at the same epoch; the cluster is misconfigured", peerRole, epoch)
+		return role, epoch, status.Errorf(codes.FailedPrecondition, "this server is the primary at epoch %d", epoch)
+	}
+
+	si.controller.lgr.Warnf("received a request from a %s at epoch %d, stepping down to standby", peerRole, peerEpoch)
+	err := si.controller.setRoleAndEpoch(ctx, string(RoleStandby), peerEpoch, false)
+	role, epoch = si.controller.Role()
+	if err != nil {
+		return role, epoch, status.Errorf(codes.Internal, "failed to step down to standby: %v", err)
+	}
+
+	return role, epoch, nil
+}
+
+func (si *serverinterceptor) Options() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(si.Unary()),
+		grpc.ChainStreamInterceptor(si.Stream()),
+	}
+}
+
+// gRPCDialProvider returns a dial provider which dials like |dialer|, with the client interceptor of the controller.
+func (c *Controller) gRPCDialProvider(dialer dbfactory.GRPCDialProvider) dbfactory.GRPCDialProvider {
+	return grpcDialProvider{dialer, &c.cinterceptor}
+}
+
+type grpcDialProvider struct {
+	orig dbfactory.GRPCDialProvider
+	ci   *clientinterceptor
+}
+
+func (p grpcDialProvider) GetGRPCDialParams(config grpcendpoint.Config) (string, []grpc.DialOption, error) {
+	endpoint, opts, err := p.orig.GetGRPCDialParams(config)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint, append(opts, p.ci.Options()...), nil
+}
@@ -381,6 +381,22 @@ func (db Database) getTableInsensitive(ctx *sql.Context, head *doltdb.Commit, ds
 		}
 		return dt, true, nil
 
+	case strings.HasPrefix(lwrName, doltdb.DoltColumnDiffTablePrefix):
+		if head == nil {
+			var err error
+			head, err = ds.GetHeadCommit(ctx, db.Name())
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		suffix := tblName[len(doltdb.DoltColumnDiffTablePrefix):]
+		dt, err := dtables.NewColumnDiffTable(ctx, suffix, db.ddb, root, head)
+		if err != nil {
+			return nil, false, err
+		}
+		return dt, true, nil
+
 	case strings.HasPrefix(lwrName, doltdb.DoltHistoryTablePrefix):
 		baseTableName := tblName[len(doltdb.DoltHistoryTablePrefix):]
 		baseTable, ok, err := db.getTable(ctx, root, baseTableName)
@@ -441,6 +457,21 @@ func (db Database) getTableInsensitive(ctx *sql.Context, head *doltdb.Commit, ds
 		}
 
 		dt, found = dtables.NewUnscopedDiffTable(ctx, db.ddb, head), true
+	case doltdb.ColumnDiffTableName:
+		if head == nil {
+			var err error
+			head, err = ds.GetHeadCommit(ctx, db.Name())
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		var err error
+		dt, err = dtables.NewColumnDiffTable(ctx, "", db.ddb, root, head)
+		if err != nil {
+			return nil, false, err
+		}
+		found = true
 	case doltdb.TableOfTablesInConflictName:
 		dt, found = dtables.NewTableOfTablesInConflict(ctx, db.name, db.ddb), true
 	case doltdb.TableOfTablesWithViolationsName:
@@ -463,6 +494,19 @@ func (db Database) getTableInsensitive(ctx *sql.Context, head *doltdb.Commit, ds
 		dt, found = dtables.NewStatusTable(ctx, db.name, db.ddb, adapter), true
 	case doltdb.TagsTableName:
 		dt, found = dtables.NewTagsTable(ctx, db.ddb), true
+	case doltdb.StashesTableName:
+		dt, found = dtables.NewStashesTable(ctx, db.ddb), true
+	case doltdb.MergeStatusTableName:
+		dt, found = dtables.NewMergeStatusTable(ctx, db.name), true
+	case doltdb.IgnoreTableName:
+		// dolt_ignore is a regular table once it exists; until then it reads as empty and is created on first insert
+		has, err := root.HasTable(ctx, doltdb.IgnoreTableName)
+		if err != nil {
+			return nil, false, err
+		}
+		if !has {
+			dt, found = newEmptyIgnoreTable(db), true
+		}
 	}
 	if found {
 		return dt, found, nil
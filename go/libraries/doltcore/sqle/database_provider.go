@@ -42,6 +42,13 @@ const (
 	dbRevisionDelimiter = "/"
 )
 
+// InitDatabaseHook is called when the provider creates a database, after its repository has been initialized and
+// before it is made available to sessions. If it returns an error, the database is not created.
+type InitDatabaseHook func(ctx *sql.Context, pro DoltDatabaseProvider, name string, env *env.DoltEnv) error
+
+// DropDatabaseHook is called when the provider drops a database, after its repository has been deleted.
+type DropDatabaseHook func(ctx *sql.Context, name string) error
+
 type DoltDatabaseProvider struct {
 	// dbLocations maps a database name to its file system root
 	dbLocations        map[string]filesys.Filesys
@@ -54,7 +61,9 @@ type DoltDatabaseProvider struct {
 	fs            filesys.Filesys
 	remoteDialer  dbfactory.GRPCDialProvider
 
-	dbFactoryUrl string
+	dbFactoryUrl     string
+	initDatabaseHook InitDatabaseHook
+	dropDatabaseHook DropDatabaseHook
 }
 
 var _ sql.DatabaseProvider = (*DoltDatabaseProvider)(nil)
@@ -143,6 +152,43 @@ func (p DoltDatabaseProvider) WithRemoteDialer(provider dbfactory.GRPCDialProvid
 	return p
 }
 
+// WithInitDatabaseHook returns a copy of this provider which calls |hook| when it creates a database, after the hooks
+// it calls already
+func (p DoltDatabaseProvider) WithInitDatabaseHook(hook InitDatabaseHook) DoltDatabaseProvider {
+	if prev := p.initDatabaseHook; prev != nil {
+		p.initDatabaseHook = func(ctx *sql.Context, pro DoltDatabaseProvider, name string, env *env.DoltEnv) error {
+			if err := prev(ctx, pro, name, env); err != nil {
+				return err
+			}
+			return hook(ctx, pro, name, env)
+		}
+		return p
+	}
+	p.initDatabaseHook = hook
+	return p
+}
+
+// WithDropDatabaseHook returns a copy of this provider which calls |hook| when it drops a database, after the hooks it
+// calls already
+func (p DoltDatabaseProvider) WithDropDatabaseHook(hook DropDatabaseHook) DoltDatabaseProvider {
+	if prev := p.dropDatabaseHook; prev != nil {
+		p.dropDatabaseHook = func(ctx *sql.Context, name string) error {
+			if err := prev(ctx, name); err != nil {
+				return err
+			}
+			return hook(ctx, name)
+		}
+		return p
+	}
+	p.dropDatabaseHook = hook
+	return p
+}
+
+// RegisterProcedure adds |procedure| to the external stored procedures of this provider
+func (p DoltDatabaseProvider) RegisterProcedure(procedure sql.ExternalStoredProcedureDetails) {
+	p.externalProcedures.Register(procedure)
+}
+
 func (p DoltDatabaseProvider) FileSystem() filesys.Filesys {
 	return p.fs
 }
@@ -262,6 +308,15 @@ func (p DoltDatabaseProvider) CreateDatabase(ctx *sql.Context, name string) erro
 		return err
 	}
 
+	if p.initDatabaseHook != nil {
+		err = p.initDatabaseHook(ctx, p, name, newEnv)
+		if err != nil {
+			// the database was never made available, so nothing else refers to its directory
+			_ = p.fs.Delete(name, true)
+			return err
+		}
+	}
+
 	// if calling process has a lockfile, also create one for new database
 	if env.FsIsLocked(p.fs) {
 		newEnv.Lock()
@@ -353,6 +408,13 @@ func (p DoltDatabaseProvider) cloneDatabaseFromRemote(ctx *sql.Context, dbName,
 		Remote: remoteName,
 	})
 
+	if p.initDatabaseHook != nil {
+		err = p.initDatabaseHook(ctx, p, dbName, dEnv)
+		if err != nil {
+			return err
+		}
+	}
+
 	sess := dsess.DSessFromSess(ctx.Session)
 	fkChecks, err := ctx.GetSessionVariable(ctx, "foreign_key_checks")
 	if err != nil {
@@ -420,6 +482,14 @@ func (p DoltDatabaseProvider) DropDatabase(ctx *sql.Context, name string) error
 	dbKey := formatDbMapKeyName(name)
 	db := p.databases[dbKey]
 
+	// databases which are replicas can't be dropped by their sessions
+	if sqlDb, ok := db.(SqlDatabase); ok && sqlDb.DbData().Ddb != nil {
+		err = sqlDb.DbData().Ddb.CheckWrite(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get the DB's directory
 	exists, isDir := p.fs.Exists(db.Name())
 	if !exists {
@@ -446,6 +516,10 @@ func (p DoltDatabaseProvider) DropDatabase(ctx *sql.Context, name string) error
 	}
 
 	delete(p.databases, dbKey)
+
+	if p.dropDatabaseHook != nil {
+		return p.dropDatabaseHook(ctx, db.Name())
+	}
 	return nil
 }
 
@@ -612,11 +686,19 @@ func (p DoltDatabaseProvider) ExternalStoredProcedures(_ *sql.Context, name stri
 
 // TableFunction implements the sql.TableFunctionProvider interface
 func (p DoltDatabaseProvider) TableFunction(_ *sql.Context, name string) (sql.TableFunction, error) {
-	// currently, only one table function is supported, if we extend this, we should clean this up
-	// and store table functions in a map, similar to regular functions.
-	if strings.ToLower(name) == "dolt_diff" {
-		dtf := &DiffTableFunction{}
-		return dtf, nil
+	switch strings.ToLower(name) {
+	case "dolt_diff":
+		return &DiffTableFunction{}, nil
+	case "dolt_diff_summary":
+		return &DiffSummaryTableFunction{}, nil
+	case "dolt_log":
+		return &LogTableFunction{}, nil
+	case "dolt_patch":
+		return &PatchTableFunction{}, nil
+	case "dolt_reflog":
+		return &ReflogTableFunction{}, nil
+	case "dolt_schema_diff":
+		return &SchemaDiffTableFunction{}, nil
 	}
 
 	return nil, sql.ErrTableFunctionNotFound.New(name)
@@ -53,6 +53,7 @@ func DoDoltAdd(ctx *sql.Context, args []string) (int, error) {
 	}
 
 	allFlag := apr.Contains(cli.AllFlag)
+	filterIgnoredTables := !apr.Contains(cli.ForceFlag)
 
 	dSess := dsess.DSessFromSess(ctx.Session)
 	roots, ok := dSess.GetRoots(ctx, dbName)
@@ -63,7 +64,7 @@ func DoDoltAdd(ctx *sql.Context, args []string) (int, error) {
 			return 1, fmt.Errorf("db session not found")
 		}
 
-		roots, err = actions.StageAllTables(ctx, roots)
+		roots, err = actions.StageAllTables(ctx, roots, filterIgnoredTables)
 		if err != nil {
 			return 1, err
 		}
@@ -73,7 +74,7 @@ func DoDoltAdd(ctx *sql.Context, args []string) (int, error) {
 			return 1, err
 		}
 	} else {
-		roots, err = actions.StageTables(ctx, roots, apr.Args)
+		roots, err = actions.StageTables(ctx, roots, apr.Args, filterIgnoredTables)
 		if err != nil {
 			return 1, err
 		}
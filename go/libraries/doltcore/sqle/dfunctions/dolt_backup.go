@@ -84,7 +84,7 @@ func DoDoltBackup(ctx *sql.Context, args []string) (int, error) {
 		return statusErr, err
 	}
 
-	invalidParams := []string{dbfactory.AWSCredsFileParam, dbfactory.AWSCredsProfile, dbfactory.AWSCredsTypeParam, dbfactory.AWSRegionParam}
+	invalidParams := []string{dbfactory.AWSCredsFileParam, dbfactory.AWSCredsProfile, dbfactory.AWSCredsTypeParam, dbfactory.AWSRegionParam, dbfactory.AWSEndpointParam}
 	for _, param := range invalidParams {
 		if apr.Contains(param) {
 			return statusErr, fmt.Errorf("parameter '%s' is not supported when running this command via SQL", param)
@@ -0,0 +1,344 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dtables"
+)
+
+var _ sql.TableFunction = (*DiffSummaryTableFunction)(nil)
+
+// DiffSummaryTableFunction is the dolt_diff_summary table function. It returns the number of changed rows and cells of
+// each table that changed between two revisions, the same numbers `dolt diff --summary` prints.
+type DiffSummaryTableFunction struct {
+	ctx            *sql.Context
+	fromCommitExpr sql.Expression
+	toCommitExpr   sql.Expression
+	tableNameExpr  sql.Expression
+	database       sql.Database
+}
+
+// The row counts that can't be computed for keyless tables, and all the counts of tables whose primary key set
+// changed, are NULL.
+var diffSummaryTableSchema = sql.Schema{
+	&sql.Column{Name: "table_name", Type: sql.LongText, PrimaryKey: true, Nullable: false},
+	&sql.Column{Name: "rows_unmodified", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "rows_added", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "rows_deleted", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "rows_modified", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "cells_modified", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "old_row_count", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "new_row_count", Type: sql.Uint64, Nullable: true},
+	&sql.Column{Name: "schema_change", Type: sql.Boolean, Nullable: false},
+}
+
+// NewInstance implements the TableFunction interface
+func (ds *DiffSummaryTableFunction) NewInstance(ctx *sql.Context, database sql.Database, expressions []sql.Expression) (sql.Node, error) {
+	newInstance := &DiffSummaryTableFunction{
+		ctx:      ctx,
+		database: database,
+	}
+
+	node, err := newInstance.WithExpressions(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Database implements the sql.Databaser interface
+func (ds *DiffSummaryTableFunction) Database() sql.Database {
+	return ds.database
+}
+
+// WithDatabase implements the sql.Databaser interface
+func (ds *DiffSummaryTableFunction) WithDatabase(database sql.Database) (sql.Node, error) {
+	ds.database = database
+
+	return ds, nil
+}
+
+// Expressions implements the sql.Expressioner interface
+func (ds *DiffSummaryTableFunction) Expressions() []sql.Expression {
+	exprs := []sql.Expression{ds.fromCommitExpr, ds.toCommitExpr}
+	if ds.tableNameExpr != nil {
+		exprs = append(exprs, ds.tableNameExpr)
+	}
+	return exprs
+}
+
+// WithExpressions implements the sql.Expressioner interface
+func (ds *DiffSummaryTableFunction) WithExpressions(expression ...sql.Expression) (sql.Node, error) {
+	if len(expression) < 2 || len(expression) > 3 {
+		return nil, sql.ErrInvalidArgumentNumber.New(ds.FunctionName(), "2 or 3", len(expression))
+	}
+
+	for _, expr := range expression {
+		if !expr.Resolved() {
+			return nil, ErrInvalidNonLiteralArgument.New(ds.FunctionName(), expr.String())
+		}
+	}
+
+	ds.fromCommitExpr = expression[0]
+	ds.toCommitExpr = expression[1]
+	if len(expression) == 3 {
+		ds.tableNameExpr = expression[2]
+	}
+
+	// evaluate the arguments now, so that invalid arguments are reported before execution
+	_, _, _, err := ds.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// Children implements the sql.Node interface
+func (ds *DiffSummaryTableFunction) Children() []sql.Node {
+	return nil
+}
+
+// WithChildren implements the sql.Node interface
+func (ds *DiffSummaryTableFunction) WithChildren(node ...sql.Node) (sql.Node, error) {
+	if len(node) != 0 {
+		panic("unexpected children")
+	}
+	return ds, nil
+}
+
+// CheckPrivileges implements the sql.Node interface
+func (ds *DiffSummaryTableFunction) CheckPrivileges(ctx *sql.Context, opChecker sql.PrivilegedOperationChecker) bool {
+	_, _, tableName, err := ds.evaluateArguments()
+	if err != nil {
+		return false
+	}
+
+	return opChecker.UserHasPrivileges(ctx,
+		sql.NewPrivilegedOperation(ds.database.Name(), tableName, "", sql.PrivilegeType_Select))
+}
+
+// Schema implements the sql.Node interface
+func (ds *DiffSummaryTableFunction) Schema() sql.Schema {
+	return diffSummaryTableSchema
+}
+
+// Resolved implements the sql.Resolvable interface
+func (ds *DiffSummaryTableFunction) Resolved() bool {
+	if ds.tableNameExpr != nil && !ds.tableNameExpr.Resolved() {
+		return false
+	}
+	return ds.fromCommitExpr.Resolved() && ds.toCommitExpr.Resolved()
+}
+
+// String implements the Stringer interface
+func (ds *DiffSummaryTableFunction) String() string {
+	if ds.tableNameExpr != nil {
+		return fmt.Sprintf("DOLT_DIFF_SUMMARY(%s, %s, %s)",
+			ds.fromCommitExpr.String(),
+			ds.toCommitExpr.String(),
+			ds.tableNameExpr.String())
+	}
+	return fmt.Sprintf("DOLT_DIFF_SUMMARY(%s, %s)",
+		ds.fromCommitExpr.String(),
+		ds.toCommitExpr.String())
+}
+
+// FunctionName implements the sql.TableFunction interface
+func (ds *DiffSummaryTableFunction) FunctionName() string {
+	return "dolt_diff_summary"
+}
+
+// evaluateArguments evaluates the argument expressions to turn them into values this DiffSummaryTableFunction can
+// use. The table name is empty if no table was given.
+func (ds *DiffSummaryTableFunction) evaluateArguments() (interface{}, interface{}, string, error) {
+	if !ds.Resolved() {
+		return nil, nil, "", nil
+	}
+
+	if !sql.IsText(ds.fromCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ds.FunctionName(), ds.fromCommitExpr.String())
+	}
+
+	if !sql.IsText(ds.toCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ds.FunctionName(), ds.toCommitExpr.String())
+	}
+
+	fromCommitVal, err := ds.fromCommitExpr.Eval(ds.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	toCommitVal, err := ds.toCommitExpr.Eval(ds.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tableName := ""
+	if ds.tableNameExpr != nil {
+		if !sql.IsText(ds.tableNameExpr.Type()) {
+			return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ds.FunctionName(), ds.tableNameExpr.String())
+		}
+
+		tableNameVal, err := ds.tableNameExpr.Eval(ds.ctx, nil)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var ok bool
+		tableName, ok = tableNameVal.(string)
+		if !ok {
+			return nil, nil, "", ErrInvalidTableName.New(ds.tableNameExpr.String())
+		}
+	}
+
+	return fromCommitVal, toCommitVal, tableName, nil
+}
+
+// RowIter implements the sql.Node interface
+func (ds *DiffSummaryTableFunction) RowIter(ctx *sql.Context, _ sql.Row) (sql.RowIter, error) {
+	fromCommitVal, toCommitVal, tableName, err := ds.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	sqledb, ok := ds.database.(Database)
+	if !ok {
+		panic(fmt.Sprintf("unexpected database type: %T", ds.database))
+	}
+
+	fromRoot, fromHash, _, err := loadDetailsForRef(ctx, fromCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, toHash, _, err := loadDetailsForRef(ctx, toCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas, err := diff.GetTableDeltas(ctx, fromRoot, toRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableName != "" {
+		_, _, fromTableExists, err := fromRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		_, _, toTableExists, err := toRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if !fromTableExists && !toTableExists {
+			return nil, sql.ErrTableNotFound.New(tableName)
+		}
+
+		delta := findMatchingDelta(deltas, tableName)
+		deltas = nil
+		if delta.FromTable != nil || delta.ToTable != nil {
+			deltas = []diff.TableDelta{delta}
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		return strings.Compare(deltas[i].CurName(), deltas[j].CurName()) < 0
+	})
+
+	var rows []sql.Row
+	for _, td := range deltas {
+		hasChanges, err := td.HasChanges()
+		if err != nil {
+			return nil, err
+		}
+		if !hasChanges {
+			continue
+		}
+
+		row, err := getDiffSummaryRow(ctx, td, fromHash, toHash)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// getDiffSummaryRow returns the dolt_diff_summary row for the table delta given.
+func getDiffSummaryRow(ctx *sql.Context, td diff.TableDelta, fromHash, toHash string) (sql.Row, error) {
+	schemaChanged, err := td.HasSchemaChanged(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schemaChanged = schemaChanged || td.IsRename() || td.HasFKChanges()
+
+	if td.HasPrimaryKeySetChanged() {
+		ctx.Warn(dtables.PrimaryKeyChangeWarningCode, fmt.Sprintf(dtables.PrimaryKeyChangeWarning, fromHash, toHash))
+		return sql.NewRow(td.CurName(), nil, nil, nil, nil, nil, nil, nil, true), nil
+	}
+
+	acc, err := getDiffSummary(ctx, td)
+	if err != nil {
+		return nil, err
+	}
+
+	keyless, err := td.IsKeyless(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyless {
+		// keyless rows can only be added or deleted, and the row counts aren't reported
+		return sql.NewRow(td.CurName(), nil, acc.Adds, acc.Removes, uint64(0), uint64(0), nil, nil, schemaChanged), nil
+	}
+
+	rowsUnmodified := acc.OldSize - acc.Changes - acc.Removes
+	return sql.NewRow(td.CurName(), rowsUnmodified, acc.Adds, acc.Removes, acc.Changes, acc.CellChanges, acc.OldSize, acc.NewSize, schemaChanged), nil
+}
+
+// getDiffSummary accumulates the diff summary progress of the table delta given.
+func getDiffSummary(ctx *sql.Context, td diff.TableDelta) (diff.DiffSummaryProgress, error) {
+	ch := make(chan diff.DiffSummaryProgress)
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		defer close(ch)
+		return diff.SummaryForTableDelta(egCtx, ch, td)
+	})
+
+	acc := diff.DiffSummaryProgress{}
+	for p := range ch {
+		acc.Adds += p.Adds
+		acc.Removes += p.Removes
+		acc.Changes += p.Changes
+		acc.CellChanges += p.CellChanges
+		acc.NewSize += p.NewSize
+		acc.OldSize += p.OldSize
+	}
+
+	if err := eg.Wait(); err != nil {
+		return diff.DiffSummaryProgress{}, err
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,445 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/commitwalk"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var _ sql.TableFunction = (*LogTableFunction)(nil)
+
+// LogTableFunction is the dolt_log table function. Unlike the dolt_log system table, which shows the history of the
+// session's current head, it accepts any number of revisions and revision ranges, e.g. dolt_log('main..feature'), and
+// the options of dolt log that limit which commits are shown.
+type LogTableFunction struct {
+	ctx      *sql.Context
+	argExprs []sql.Expression
+	database sql.Database
+}
+
+var logTableSchema = sql.Schema{
+	&sql.Column{Name: "commit_hash", Type: sql.Text, PrimaryKey: true},
+	&sql.Column{Name: "committer", Type: sql.Text},
+	&sql.Column{Name: "email", Type: sql.Text},
+	&sql.Column{Name: "date", Type: sql.Datetime},
+	&sql.Column{Name: "message", Type: sql.Text},
+}
+
+// NewInstance implements the TableFunction interface
+func (ltf *LogTableFunction) NewInstance(ctx *sql.Context, database sql.Database, expressions []sql.Expression) (sql.Node, error) {
+	newInstance := &LogTableFunction{
+		ctx:      ctx,
+		database: database,
+	}
+
+	node, err := newInstance.WithExpressions(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Database implements the sql.Databaser interface
+func (ltf *LogTableFunction) Database() sql.Database {
+	return ltf.database
+}
+
+// WithDatabase implements the sql.Databaser interface
+func (ltf *LogTableFunction) WithDatabase(database sql.Database) (sql.Node, error) {
+	ltf.database = database
+
+	return ltf, nil
+}
+
+// Expressions implements the sql.Expressioner interface
+func (ltf *LogTableFunction) Expressions() []sql.Expression {
+	return ltf.argExprs
+}
+
+// WithExpressions implements the sql.Expressioner interface
+func (ltf *LogTableFunction) WithExpressions(expression ...sql.Expression) (sql.Node, error) {
+	for _, expr := range expression {
+		if !expr.Resolved() {
+			return nil, ErrInvalidNonLiteralArgument.New(ltf.FunctionName(), expr.String())
+		}
+	}
+
+	ltf.argExprs = expression
+
+	// evaluate the arguments now, so that invalid arguments are reported before execution
+	_, err := ltf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return ltf, nil
+}
+
+// Children implements the sql.Node interface
+func (ltf *LogTableFunction) Children() []sql.Node {
+	return nil
+}
+
+// WithChildren implements the sql.Node interface
+func (ltf *LogTableFunction) WithChildren(node ...sql.Node) (sql.Node, error) {
+	if len(node) != 0 {
+		panic("unexpected children")
+	}
+	return ltf, nil
+}
+
+// CheckPrivileges implements the sql.Node interface
+func (ltf *LogTableFunction) CheckPrivileges(ctx *sql.Context, opChecker sql.PrivilegedOperationChecker) bool {
+	return opChecker.UserHasPrivileges(ctx,
+		sql.NewPrivilegedOperation(ltf.database.Name(), "", "", sql.PrivilegeType_Select))
+}
+
+// Schema implements the sql.Node interface
+func (ltf *LogTableFunction) Schema() sql.Schema {
+	return logTableSchema
+}
+
+// Resolved implements the sql.Resolvable interface
+func (ltf *LogTableFunction) Resolved() bool {
+	for _, expr := range ltf.argExprs {
+		if !expr.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+// String implements the Stringer interface
+func (ltf *LogTableFunction) String() string {
+	args := make([]string, len(ltf.argExprs))
+	for i, expr := range ltf.argExprs {
+		args[i] = expr.String()
+	}
+	return fmt.Sprintf("DOLT_LOG(%s)", strings.Join(args, ", "))
+}
+
+// FunctionName implements the sql.TableFunction interface
+func (ltf *LogTableFunction) FunctionName() string {
+	return "dolt_log"
+}
+
+// evaluateArguments evaluates the argument expressions and parses them with the log arg parser.
+func (ltf *LogTableFunction) evaluateArguments() (*logArgs, error) {
+	if !ltf.Resolved() {
+		return nil, nil
+	}
+
+	args := make([]string, len(ltf.argExprs))
+	for i, expr := range ltf.argExprs {
+		if !sql.IsText(expr.Type()) {
+			return nil, sql.ErrInvalidArgumentDetails.New(ltf.FunctionName(), expr.String())
+		}
+
+		val, err := expr.Eval(ltf.ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		str, ok := val.(string)
+		if !ok {
+			return nil, sql.ErrInvalidArgumentDetails.New(ltf.FunctionName(), expr.String())
+		}
+		args[i] = str
+	}
+
+	apr, err := cli.CreateLogArgParser().Parse(args)
+	if err != nil {
+		return nil, sql.ErrInvalidArgumentDetails.New(ltf.FunctionName(), err.Error())
+	}
+
+	la := &logArgs{
+		limit:      apr.GetIntOrDefault(cli.NumberFlag, -1),
+		minParents: apr.GetIntOrDefault(cli.MinParentsFlag, 0),
+	}
+	if apr.Contains(cli.MergesFlag) {
+		la.minParents = 2
+	}
+
+	for _, arg := range apr.Args {
+		if strings.Contains(arg, "...") {
+			revs := strings.SplitN(arg, "...", 2)
+			la.symmetricDiffs = append(la.symmetricDiffs, [2]string{revOrHead(revs[0]), revOrHead(revs[1])})
+		} else if strings.Contains(arg, "..") {
+			revs := strings.SplitN(arg, "..", 2)
+			la.excluded = append(la.excluded, revOrHead(revs[0]))
+			la.included = append(la.included, revOrHead(revs[1]))
+		} else if strings.HasPrefix(arg, "^") {
+			la.excluded = append(la.excluded, arg[1:])
+		} else {
+			la.included = append(la.included, arg)
+		}
+	}
+
+	if notRevs, ok := apr.GetValueList(cli.NotFlag); ok {
+		la.excluded = append(la.excluded, notRevs...)
+	}
+
+	if tables, ok := apr.GetValueList(cli.TablesFlag); ok {
+		for _, table := range tables {
+			for _, name := range strings.Split(table, ",") {
+				name = strings.TrimSpace(name)
+				if name != "" {
+					la.tables = append(la.tables, name)
+				}
+			}
+		}
+		if len(la.tables) == 0 {
+			return nil, sql.ErrInvalidArgumentDetails.New(ltf.FunctionName(), "--"+cli.TablesFlag+" requires at least one table")
+		}
+	}
+
+	for _, rev := range append(la.included, la.excluded...) {
+		if rev == "" {
+			return nil, sql.ErrInvalidArgumentDetails.New(ltf.FunctionName(), "empty revision")
+		}
+	}
+
+	return la, nil
+}
+
+// logArgs are the parsed arguments of the dolt_log table function.
+type logArgs struct {
+	// included are the revisions whose history is shown.
+	included []string
+	// excluded are the revisions whose history is not shown.
+	excluded []string
+	// symmetricDiffs are the pairs of revisions given as a...b, whose histories are shown up to their merge base.
+	symmetricDiffs [][2]string
+	// tables, if not empty, limits the log to the commits that changed any of these tables.
+	tables     []string
+	minParents int
+	limit      int
+}
+
+// revOrHead returns |rev|, or HEAD if it is empty, the way git treats the missing end of a revision range.
+func revOrHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// RowIter implements the sql.Node interface
+func (ltf *LogTableFunction) RowIter(ctx *sql.Context, _ sql.Row) (sql.RowIter, error) {
+	la, err := ltf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	sqledb, ok := ltf.database.(Database)
+	if !ok {
+		panic(fmt.Sprintf("unexpected database type: %T", ltf.database))
+	}
+
+	sess := dsess.DSessFromSess(ctx.Session)
+	headRef, err := sess.CWBHeadRef(ctx, sqledb.Name())
+	if err != nil {
+		return nil, err
+	}
+	ddb := sqledb.GetDoltDB()
+
+	included, err := resolveCommitHashes(ctx, ddb, headRef, la.included)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := resolveCommitHashes(ctx, ddb, headRef, la.excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, revs := range la.symmetricDiffs {
+		commits, err := resolveCommits(ctx, ddb, headRef, revs[:])
+		if err != nil {
+			return nil, err
+		}
+		for _, cm := range commits {
+			h, err := cm.HashOf()
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, h)
+		}
+
+		mergeBase, err := doltdb.GetCommitAncestor(ctx, commits[0], commits[1])
+		if err == doltdb.ErrNoCommonAncestor {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		h, err := mergeBase.HashOf()
+		if err != nil {
+			return nil, err
+		}
+		excluded = append(excluded, h)
+	}
+
+	if len(included) == 0 {
+		included, err = resolveCommitHashes(ctx, ddb, headRef, []string{"HEAD"})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	child, err := commitwalk.GetDotDotRevisionsIterator(ctx, ddb, included, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logTableFunctionRowIter{
+		child: child,
+		args:  la,
+	}, nil
+}
+
+func resolveCommits(ctx *sql.Context, ddb *doltdb.DoltDB, headRef ref.DoltRef, revs []string) ([]*doltdb.Commit, error) {
+	commits := make([]*doltdb.Commit, len(revs))
+	for i, rev := range revs {
+		cs, err := doltdb.NewCommitSpec(rev)
+		if err != nil {
+			return nil, err
+		}
+		commits[i], err = ddb.Resolve(ctx, cs, headRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return commits, nil
+}
+
+func resolveCommitHashes(ctx *sql.Context, ddb *doltdb.DoltDB, headRef ref.DoltRef, revs []string) ([]hash.Hash, error) {
+	commits, err := resolveCommits(ctx, ddb, headRef, revs)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]hash.Hash, len(commits))
+	for i, cm := range commits {
+		hashes[i], err = cm.HashOf()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+//------------------------------------
+// logTableFunctionRowIter
+//------------------------------------
+
+var _ sql.RowIter = (*logTableFunctionRowIter)(nil)
+
+// logTableFunctionRowIter is a sql.RowIter over the commits of a commitwalk iterator that match the log arguments.
+type logTableFunctionRowIter struct {
+	child doltdb.CommitItr
+	args  *logArgs
+	count int
+}
+
+func (itr *logTableFunctionRowIter) Next(ctx *sql.Context) (sql.Row, error) {
+	for {
+		if itr.args.limit >= 0 && itr.count >= itr.args.limit {
+			return nil, io.EOF
+		}
+
+		h, cm, err := itr.child.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := itr.matches(ctx, cm)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		itr.count++
+
+		meta, err := cm.GetCommitMeta(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return sql.NewRow(h.String(), meta.Name, meta.Email, meta.Time(), meta.Description), nil
+	}
+}
+
+// matches returns whether |cm| has enough parents and, if tables were given, changed any of them.
+func (itr *logTableFunctionRowIter) matches(ctx *sql.Context, cm *doltdb.Commit) (bool, error) {
+	if cm.NumParents() < itr.args.minParents {
+		return false, nil
+	}
+	if len(itr.args.tables) == 0 {
+		return true, nil
+	}
+
+	root, err := cm.GetRootValue(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var parentRoot *doltdb.RootValue
+	if cm.NumParents() > 0 {
+		parent, err := cm.GetParent(ctx, 0)
+		if err != nil {
+			return false, err
+		}
+		parentRoot, err = parent.GetRootValue(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, table := range itr.args.tables {
+		h, ok, err := root.GetTableHash(ctx, table)
+		if err != nil {
+			return false, err
+		}
+
+		var parentH hash.Hash
+		parentOk := false
+		if parentRoot != nil {
+			parentH, parentOk, err = parentRoot.GetTableHash(ctx, table)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		if ok != parentOk || h != parentH {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (itr *logTableFunctionRowIter) Close(_ *sql.Context) error {
+	return nil
+}
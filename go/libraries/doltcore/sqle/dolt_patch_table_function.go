@@ -0,0 +1,511 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dtables"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlutil"
+	"github.com/dolthub/dolt/go/libraries/utils/iohelp"
+	"github.com/dolthub/dolt/go/store/types"
+)
+
+const (
+	patchDiffTypeSchema = "schema"
+	patchDiffTypeData   = "data"
+)
+
+var IncompatibleSchemaChangeWarning = "incompatible schema change, skipping data diff for table %s"
+
+const IncompatibleSchemaChangeWarningCode int = 1105 // Since this is our own custom warning we'll use 1105, the code for an unknown error
+
+var _ sql.TableFunction = (*PatchTableFunction)(nil)
+
+// PatchTableFunction is the dolt_patch table function. It returns the SQL statements that transform the schema and
+// data of one revision into another, the same statements `dolt diff -r sql` prints.
+type PatchTableFunction struct {
+	ctx            *sql.Context
+	fromCommitExpr sql.Expression
+	toCommitExpr   sql.Expression
+	tableNameExpr  sql.Expression
+	database       sql.Database
+}
+
+var patchTableSchema = sql.Schema{
+	&sql.Column{Name: "statement_order", Type: sql.Uint64, PrimaryKey: true, Nullable: false},
+	&sql.Column{Name: "table_name", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "diff_type", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "statement", Type: sql.LongText, Nullable: false},
+}
+
+// NewInstance implements the TableFunction interface
+func (ptf *PatchTableFunction) NewInstance(ctx *sql.Context, database sql.Database, expressions []sql.Expression) (sql.Node, error) {
+	newInstance := &PatchTableFunction{
+		ctx:      ctx,
+		database: database,
+	}
+
+	node, err := newInstance.WithExpressions(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Database implements the sql.Databaser interface
+func (ptf *PatchTableFunction) Database() sql.Database {
+	return ptf.database
+}
+
+// WithDatabase implements the sql.Databaser interface
+func (ptf *PatchTableFunction) WithDatabase(database sql.Database) (sql.Node, error) {
+	ptf.database = database
+
+	return ptf, nil
+}
+
+// Expressions implements the sql.Expressioner interface
+func (ptf *PatchTableFunction) Expressions() []sql.Expression {
+	exprs := []sql.Expression{ptf.fromCommitExpr, ptf.toCommitExpr}
+	if ptf.tableNameExpr != nil {
+		exprs = append(exprs, ptf.tableNameExpr)
+	}
+	return exprs
+}
+
+// WithExpressions implements the sql.Expressioner interface
+func (ptf *PatchTableFunction) WithExpressions(expression ...sql.Expression) (sql.Node, error) {
+	if len(expression) < 2 || len(expression) > 3 {
+		return nil, sql.ErrInvalidArgumentNumber.New(ptf.FunctionName(), "2 or 3", len(expression))
+	}
+
+	for _, expr := range expression {
+		if !expr.Resolved() {
+			return nil, ErrInvalidNonLiteralArgument.New(ptf.FunctionName(), expr.String())
+		}
+	}
+
+	ptf.fromCommitExpr = expression[0]
+	ptf.toCommitExpr = expression[1]
+	if len(expression) == 3 {
+		ptf.tableNameExpr = expression[2]
+	}
+
+	// evaluate the arguments now, so that invalid arguments are reported before execution
+	_, _, _, err := ptf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return ptf, nil
+}
+
+// Children implements the sql.Node interface
+func (ptf *PatchTableFunction) Children() []sql.Node {
+	return nil
+}
+
+// WithChildren implements the sql.Node interface
+func (ptf *PatchTableFunction) WithChildren(node ...sql.Node) (sql.Node, error) {
+	if len(node) != 0 {
+		panic("unexpected children")
+	}
+	return ptf, nil
+}
+
+// CheckPrivileges implements the sql.Node interface
+func (ptf *PatchTableFunction) CheckPrivileges(ctx *sql.Context, opChecker sql.PrivilegedOperationChecker) bool {
+	_, _, tableName, err := ptf.evaluateArguments()
+	if err != nil {
+		return false
+	}
+
+	return opChecker.UserHasPrivileges(ctx,
+		sql.NewPrivilegedOperation(ptf.database.Name(), tableName, "", sql.PrivilegeType_Select))
+}
+
+// Schema implements the sql.Node interface
+func (ptf *PatchTableFunction) Schema() sql.Schema {
+	return patchTableSchema
+}
+
+// Resolved implements the sql.Resolvable interface
+func (ptf *PatchTableFunction) Resolved() bool {
+	if ptf.tableNameExpr != nil && !ptf.tableNameExpr.Resolved() {
+		return false
+	}
+	return ptf.fromCommitExpr.Resolved() && ptf.toCommitExpr.Resolved()
+}
+
+// String implements the Stringer interface
+func (ptf *PatchTableFunction) String() string {
+	if ptf.tableNameExpr != nil {
+		return fmt.Sprintf("DOLT_PATCH(%s, %s, %s)",
+			ptf.fromCommitExpr.String(),
+			ptf.toCommitExpr.String(),
+			ptf.tableNameExpr.String())
+	}
+	return fmt.Sprintf("DOLT_PATCH(%s, %s)",
+		ptf.fromCommitExpr.String(),
+		ptf.toCommitExpr.String())
+}
+
+// FunctionName implements the sql.TableFunction interface
+func (ptf *PatchTableFunction) FunctionName() string {
+	return "dolt_patch"
+}
+
+// evaluateArguments evaluates the argument expressions to turn them into values this PatchTableFunction can use. The
+// table name is empty if no table was given.
+func (ptf *PatchTableFunction) evaluateArguments() (interface{}, interface{}, string, error) {
+	if !ptf.Resolved() {
+		return nil, nil, "", nil
+	}
+
+	if !sql.IsText(ptf.fromCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ptf.FunctionName(), ptf.fromCommitExpr.String())
+	}
+
+	if !sql.IsText(ptf.toCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ptf.FunctionName(), ptf.toCommitExpr.String())
+	}
+
+	fromCommitVal, err := ptf.fromCommitExpr.Eval(ptf.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	toCommitVal, err := ptf.toCommitExpr.Eval(ptf.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tableName := ""
+	if ptf.tableNameExpr != nil {
+		if !sql.IsText(ptf.tableNameExpr.Type()) {
+			return nil, nil, "", sql.ErrInvalidArgumentDetails.New(ptf.FunctionName(), ptf.tableNameExpr.String())
+		}
+
+		tableNameVal, err := ptf.tableNameExpr.Eval(ptf.ctx, nil)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var ok bool
+		tableName, ok = tableNameVal.(string)
+		if !ok {
+			return nil, nil, "", ErrInvalidTableName.New(ptf.tableNameExpr.String())
+		}
+	}
+
+	return fromCommitVal, toCommitVal, tableName, nil
+}
+
+// RowIter implements the sql.Node interface
+func (ptf *PatchTableFunction) RowIter(ctx *sql.Context, _ sql.Row) (sql.RowIter, error) {
+	fromCommitVal, toCommitVal, tableName, err := ptf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	sqledb, ok := ptf.database.(Database)
+	if !ok {
+		panic(fmt.Sprintf("unexpected database type: %T", ptf.database))
+	}
+
+	fromRoot, fromHash, fromDate, err := loadDetailsForRef(ctx, fromCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, toHash, toDate, err := loadDetailsForRef(ctx, toCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas, err := diff.GetTableDeltas(ctx, fromRoot, toRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableName != "" {
+		_, _, fromTableExists, err := fromRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		_, _, toTableExists, err := toRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if !fromTableExists && !toTableExists {
+			return nil, sql.ErrTableNotFound.New(tableName)
+		}
+
+		delta := findMatchingDelta(deltas, tableName)
+		deltas = nil
+		if delta.FromTable != nil || delta.ToTable != nil {
+			deltas = []diff.TableDelta{delta}
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		return strings.Compare(deltas[i].ToName, deltas[j].ToName) < 0
+	})
+
+	toSchemas, err := toRoot.GetAllSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchTableFunctionRowIter{
+		ddb:       sqledb.GetDoltDB(),
+		deltas:    deltas,
+		toSchemas: toSchemas,
+		fromHash:  fromHash,
+		toHash:    toHash,
+		fromDate:  fromDate,
+		toDate:    toDate,
+	}, nil
+}
+
+//------------------------------------
+// patchTableFunctionRowIter
+//------------------------------------
+
+var _ sql.RowIter = (*patchTableFunctionRowIter)(nil)
+
+// patchTableFunctionRowIter returns the statements for each table delta in turn: first the schema statements, then a
+// statement for each changed row.
+type patchTableFunctionRowIter struct {
+	ddb              *doltdb.DoltDB
+	deltas           []diff.TableDelta
+	toSchemas        map[string]schema.Schema
+	fromHash, toHash string
+	fromDate, toDate *types.Timestamp
+
+	statementOrder uint64
+	currentDelta   *diff.TableDelta
+	schemaStmts    []string
+	dataIter       *patchDataIter
+}
+
+func (itr *patchTableFunctionRowIter) Next(ctx *sql.Context) (sql.Row, error) {
+	for {
+		if itr.currentDelta == nil {
+			if len(itr.deltas) == 0 {
+				return nil, io.EOF
+			}
+			err := itr.startDelta(ctx, itr.deltas[0])
+			if err != nil {
+				return nil, err
+			}
+			itr.deltas = itr.deltas[1:]
+		}
+
+		tableName := itr.currentDelta.ToName
+		if tableName == "" {
+			tableName = itr.currentDelta.FromName
+		}
+
+		if len(itr.schemaStmts) > 0 {
+			stmt := itr.schemaStmts[0]
+			itr.schemaStmts = itr.schemaStmts[1:]
+			return itr.nextRow(tableName, patchDiffTypeSchema, stmt), nil
+		}
+
+		if itr.dataIter != nil {
+			stmt, err := itr.dataIter.Next(ctx)
+			if err == nil {
+				return itr.nextRow(tableName, patchDiffTypeData, stmt), nil
+			} else if err != io.EOF {
+				return nil, err
+			}
+			err = itr.dataIter.Close(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		itr.currentDelta = nil
+		itr.dataIter = nil
+	}
+}
+
+func (itr *patchTableFunctionRowIter) nextRow(tableName, diffType, stmt string) sql.Row {
+	itr.statementOrder++
+	return sql.NewRow(itr.statementOrder, tableName, diffType, stmt)
+}
+
+// startDelta computes the schema statements for |td| and prepares the iteration of its data statements.
+func (itr *patchTableFunctionRowIter) startDelta(ctx *sql.Context, td diff.TableDelta) error {
+	itr.currentDelta = &td
+
+	stmts, err := SqlSchemaDiff(ctx, td, itr.toSchemas)
+	if err != nil {
+		return err
+	}
+	itr.schemaStmts = stmts
+
+	// no DELETE statements after DROP TABLE
+	if td.IsDrop() {
+		return nil
+	}
+
+	diffable := schema.ArePrimaryKeySetsDiffable(td.Format(), td.FromSch, td.ToSch)
+	canSqlDiff := !(td.ToSch == nil || (td.FromSch != nil && !schema.SchemasAreEqual(td.FromSch, td.ToSch)))
+	if !diffable {
+		ctx.Warn(dtables.PrimaryKeyChangeWarningCode, fmt.Sprintf(dtables.PrimaryKeyChangeWarning, itr.fromHash, itr.toHash))
+		return nil
+	} else if !canSqlDiff {
+		ctx.Warn(IncompatibleSchemaChangeWarningCode, fmt.Sprintf(IncompatibleSchemaChangeWarning, td.ToName))
+		return nil
+	}
+
+	itr.dataIter, err = newPatchDataIter(ctx, itr.ddb, td, itr.fromHash, itr.toHash, itr.fromDate, itr.toDate)
+	return err
+}
+
+func (itr *patchTableFunctionRowIter) Close(ctx *sql.Context) error {
+	if itr.dataIter != nil {
+		return itr.dataIter.Close(ctx)
+	}
+	return nil
+}
+
+// patchDataIter turns the rows of the diff between two versions of a table into INSERT, UPDATE and DELETE statements
+// with the same sqlfmt.SqlDiffWriter `dolt diff -r sql` uses.
+type patchDataIter struct {
+	diffIter sql.RowIter
+	writer   *sqlfmt.SqlDiffWriter
+	buf      *bytes.Buffer
+
+	// indexes of the from_ and to_ columns in diff rows for each column of the table
+	fromIdxs    []int
+	toIdxs      []int
+	diffTypeIdx int
+	colTypes    []sql.Type
+}
+
+func newPatchDataIter(ctx *sql.Context, ddb *doltdb.DoltDB, td diff.TableDelta, fromHash, toHash string, fromDate, toDate *types.Timestamp) (*patchDataIter, error) {
+	diffTableSch, joiner, err := dtables.GetDiffTableSchemaAndJoiner(td.ToTable.Format(), td.FromSch, td.ToSch)
+	if err != nil {
+		return nil, err
+	}
+	diffSqlSch, err := sqlutil.FromDoltSchema("", diffTableSch)
+	if err != nil {
+		return nil, err
+	}
+	targetSqlSch, err := sqlutil.FromDoltSchema(td.ToName, td.ToSch)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	itr := &patchDataIter{
+		writer:      sqlfmt.NewSqlDiffWriter(td.ToName, td.ToSch, iohelp.NopWrCloser(buf)),
+		buf:         buf,
+		fromIdxs:    make([]int, len(targetSqlSch.Schema)),
+		toIdxs:      make([]int, len(targetSqlSch.Schema)),
+		diffTypeIdx: diffSqlSch.Schema.IndexOfColName("diff_type"),
+		colTypes:    make([]sql.Type, len(targetSqlSch.Schema)),
+	}
+	for i, col := range targetSqlSch.Schema {
+		itr.fromIdxs[i] = diffSqlSch.Schema.IndexOfColName("from_" + col.Name)
+		itr.toIdxs[i] = diffSqlSch.Schema.IndexOfColName("to_" + col.Name)
+		itr.colTypes[i] = col.Type
+	}
+
+	dp := dtables.NewDiffPartition(td.ToTable, td.FromTable, toHash, fromHash, toDate, fromDate, td.ToSch, td.FromSch)
+	itr.diffIter, err = dp.GetRowIter(ctx, ddb, joiner, sql.IndexLookup{})
+	if err != nil {
+		return nil, err
+	}
+
+	return itr, nil
+}
+
+// Next returns the statement for the next changed row.
+func (itr *patchDataIter) Next(ctx *sql.Context) (string, error) {
+	r, err := itr.diffIter.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	err = itr.writeRow(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	stmt := strings.TrimSuffix(itr.buf.String(), "\n")
+	itr.buf.Reset()
+	return stmt, nil
+}
+
+// writeRow writes the statement for the diff row |r| to the buffer of this iterator.
+func (itr *patchDataIter) writeRow(ctx *sql.Context, r sql.Row) error {
+	colDiffTypes := make([]diff.ChangeType, len(itr.colTypes))
+	switch diffType := r[itr.diffTypeIdx].(string); diffType {
+	case "added":
+		for i := range colDiffTypes {
+			colDiffTypes[i] = diff.Added
+		}
+		return itr.writer.WriteRow(ctx, itr.project(r, itr.toIdxs), diff.Added, colDiffTypes)
+	case "removed":
+		for i := range colDiffTypes {
+			colDiffTypes[i] = diff.Removed
+		}
+		return itr.writer.WriteRow(ctx, itr.project(r, itr.fromIdxs), diff.Removed, colDiffTypes)
+	case "modified":
+		for i := range colDiffTypes {
+			n, err := itr.colTypes[i].Compare(r[itr.fromIdxs[i]], r[itr.toIdxs[i]])
+			if err != nil {
+				return err
+			}
+			if n != 0 {
+				colDiffTypes[i] = diff.ModifiedNew
+			}
+		}
+		return itr.writer.WriteRow(ctx, itr.project(r, itr.toIdxs), diff.ModifiedNew, colDiffTypes)
+	default:
+		return fmt.Errorf("unexpected diff type: %s", diffType)
+	}
+}
+
+// project returns the values of the diff row |r| at the indexes given.
+func (itr *patchDataIter) project(r sql.Row, idxs []int) sql.Row {
+	row := make(sql.Row, len(idxs))
+	for i, idx := range idxs {
+		row[i] = r[idx]
+	}
+	return row
+}
+
+func (itr *patchDataIter) Close(ctx *sql.Context) error {
+	err := itr.diffIter.Close(ctx)
+	if err != nil {
+		return err
+	}
+	return itr.writer.Close(ctx)
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+)
+
+var _ sql.TableFunction = (*ReflogTableFunction)(nil)
+
+// ReflogTableFunction is the dolt_reflog table function. It shows the updates of the refs of the database, most recent
+// first, optionally limited to a single ref, e.g. dolt_reflog('main').
+type ReflogTableFunction struct {
+	ctx      *sql.Context
+	argExprs []sql.Expression
+	database sql.Database
+}
+
+var reflogTableSchema = sql.Schema{
+	&sql.Column{Name: "ref", Type: sql.Text},
+	&sql.Column{Name: "ref_timestamp", Type: sql.Timestamp},
+	&sql.Column{Name: "commit_hash", Type: sql.Text},
+	&sql.Column{Name: "commit_message", Type: sql.Text, Nullable: true},
+}
+
+// NewInstance implements the TableFunction interface
+func (rtf *ReflogTableFunction) NewInstance(ctx *sql.Context, database sql.Database, expressions []sql.Expression) (sql.Node, error) {
+	newInstance := &ReflogTableFunction{
+		ctx:      ctx,
+		database: database,
+	}
+
+	node, err := newInstance.WithExpressions(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Database implements the sql.Databaser interface
+func (rtf *ReflogTableFunction) Database() sql.Database {
+	return rtf.database
+}
+
+// WithDatabase implements the sql.Databaser interface
+func (rtf *ReflogTableFunction) WithDatabase(database sql.Database) (sql.Node, error) {
+	rtf.database = database
+
+	return rtf, nil
+}
+
+// Expressions implements the sql.Expressioner interface
+func (rtf *ReflogTableFunction) Expressions() []sql.Expression {
+	return rtf.argExprs
+}
+
+// WithExpressions implements the sql.Expressioner interface
+func (rtf *ReflogTableFunction) WithExpressions(expression ...sql.Expression) (sql.Node, error) {
+	for _, expr := range expression {
+		if !expr.Resolved() {
+			return nil, ErrInvalidNonLiteralArgument.New(rtf.FunctionName(), expr.String())
+		}
+	}
+
+	rtf.argExprs = expression
+
+	// evaluate the arguments now, so that invalid arguments are reported before execution
+	_, _, err := rtf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return rtf, nil
+}
+
+// Children implements the sql.Node interface
+func (rtf *ReflogTableFunction) Children() []sql.Node {
+	return nil
+}
+
+// WithChildren implements the sql.Node interface
+func (rtf *ReflogTableFunction) WithChildren(node ...sql.Node) (sql.Node, error) {
+	if len(node) != 0 {
+		panic("unexpected children")
+	}
+	return rtf, nil
+}
+
+// CheckPrivileges implements the sql.Node interface
+func (rtf *ReflogTableFunction) CheckPrivileges(ctx *sql.Context, opChecker sql.PrivilegedOperationChecker) bool {
+	return opChecker.UserHasPrivileges(ctx,
+		sql.NewPrivilegedOperation(rtf.database.Name(), "", "", sql.PrivilegeType_Select))
+}
+
+// Schema implements the sql.Node interface
+func (rtf *ReflogTableFunction) Schema() sql.Schema {
+	return reflogTableSchema
+}
+
+// Resolved implements the sql.Resolvable interface
+func (rtf *ReflogTableFunction) Resolved() bool {
+	for _, expr := range rtf.argExprs {
+		if !expr.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+// String implements the Stringer interface
+func (rtf *ReflogTableFunction) String() string {
+	args := make([]string, len(rtf.argExprs))
+	for i, expr := range rtf.argExprs {
+		args[i] = expr.String()
+	}
+	return fmt.Sprintf("DOLT_REFLOG(%s)", strings.Join(args, ", "))
+}
+
+// FunctionName implements the sql.TableFunction interface
+func (rtf *ReflogTableFunction) FunctionName() string {
+	return "dolt_reflog"
+}
+
+// evaluateArguments evaluates the argument expressions and parses them with the reflog arg parser. It returns the
+// name of the ref to show the updates of, which is empty for all refs, and whether --all was given.
+func (rtf *ReflogTableFunction) evaluateArguments() (string, bool, error) {
+	if !rtf.Resolved() {
+		return "", false, nil
+	}
+
+	args := make([]string, len(rtf.argExprs))
+	for i, expr := range rtf.argExprs {
+		if !sql.IsText(expr.Type()) {
+			return "", false, sql.ErrInvalidArgumentDetails.New(rtf.FunctionName(), expr.String())
+		}
+
+		val, err := expr.Eval(rtf.ctx, nil)
+		if err != nil {
+			return "", false, err
+		}
+		str, ok := val.(string)
+		if !ok {
+			return "", false, sql.ErrInvalidArgumentDetails.New(rtf.FunctionName(), expr.String())
+		}
+		args[i] = str
+	}
+
+	apr, err := cli.CreateReflogArgParser().Parse(args)
+	if err != nil {
+		return "", false, sql.ErrInvalidArgumentDetails.New(rtf.FunctionName(), err.Error())
+	}
+	if apr.NArg() > 1 {
+		return "", false, sql.ErrInvalidArgumentNumber.New(rtf.FunctionName(), "0 or 1", apr.NArg())
+	}
+
+	refName := ""
+	if apr.NArg() == 1 {
+		refName = apr.Arg(0)
+	}
+
+	return refName, apr.Contains(cli.AllFlag), nil
+}
+
+// RowIter implements the sql.Node interface
+func (rtf *ReflogTableFunction) RowIter(ctx *sql.Context, _ sql.Row) (sql.RowIter, error) {
+	refName, all, err := rtf.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	sqledb, ok := rtf.database.(Database)
+	if !ok {
+		panic(fmt.Sprintf("unexpected database type: %T", rtf.database))
+	}
+	ddb := sqledb.GetDoltDB()
+
+	entries, err := ddb.ReadRefLog(ctx, refName, all)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]sql.Row, len(entries))
+	for i, e := range entries {
+		var message interface{}
+		cm, err := ddb.ResolveRefLogEntry(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if cm != nil {
+			meta, err := cm.GetCommitMeta(ctx)
+			if err != nil {
+				return nil, err
+			}
+			message = meta.Description
+		}
+
+		rows[i] = sql.NewRow(e.Ref, e.Timestamp, e.Hash.String(), message)
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
@@ -0,0 +1,423 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
+)
+
+var _ sql.TableFunction = (*SchemaDiffTableFunction)(nil)
+
+// SchemaDiffTableFunction is the dolt_schema_diff table function. It returns the schema changes of each table that
+// changed between two revisions: one row with the table's CREATE TABLE statements at both revisions, followed by one row
+// for each column, index, check and foreign key that was added, dropped or modified.
+type SchemaDiffTableFunction struct {
+	ctx            *sql.Context
+	fromCommitExpr sql.Expression
+	toCommitExpr   sql.Expression
+	tableNameExpr  sql.Expression
+	database       sql.Database
+}
+
+const (
+	schemaDiffElementTable      = "table"
+	schemaDiffElementColumn     = "column"
+	schemaDiffElementIndex      = "index"
+	schemaDiffElementCheck      = "check"
+	schemaDiffElementForeignKey = "foreign_key"
+)
+
+// The from and to columns are NULL when the table or element doesn't exist at that revision.
+var schemaDiffTableSchema = sql.Schema{
+	&sql.Column{Name: "from_table_name", Type: sql.LongText, Nullable: true},
+	&sql.Column{Name: "to_table_name", Type: sql.LongText, Nullable: true},
+	&sql.Column{Name: "element_type", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "element_name", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "diff_type", Type: sql.LongText, Nullable: false},
+	&sql.Column{Name: "from_definition", Type: sql.LongText, Nullable: true},
+	&sql.Column{Name: "to_definition", Type: sql.LongText, Nullable: true},
+}
+
+// NewInstance implements the TableFunction interface
+func (sd *SchemaDiffTableFunction) NewInstance(ctx *sql.Context, database sql.Database, expressions []sql.Expression) (sql.Node, error) {
+	newInstance := &SchemaDiffTableFunction{
+		ctx:      ctx,
+		database: database,
+	}
+
+	node, err := newInstance.WithExpressions(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// Database implements the sql.Databaser interface
+func (sd *SchemaDiffTableFunction) Database() sql.Database {
+	return sd.database
+}
+
+// WithDatabase implements the sql.Databaser interface
+func (sd *SchemaDiffTableFunction) WithDatabase(database sql.Database) (sql.Node, error) {
+	sd.database = database
+
+	return sd, nil
+}
+
+// Expressions implements the sql.Expressioner interface
+func (sd *SchemaDiffTableFunction) Expressions() []sql.Expression {
+	exprs := []sql.Expression{sd.fromCommitExpr, sd.toCommitExpr}
+	if sd.tableNameExpr != nil {
+		exprs = append(exprs, sd.tableNameExpr)
+	}
+	return exprs
+}
+
+// WithExpressions implements the sql.Expressioner interface
+func (sd *SchemaDiffTableFunction) WithExpressions(expression ...sql.Expression) (sql.Node, error) {
+	if len(expression) < 2 || len(expression) > 3 {
+		return nil, sql.ErrInvalidArgumentNumber.New(sd.FunctionName(), "2 or 3", len(expression))
+	}
+
+	for _, expr := range expression {
+		if !expr.Resolved() {
+			return nil, ErrInvalidNonLiteralArgument.New(sd.FunctionName(), expr.String())
+		}
+	}
+
+	sd.fromCommitExpr = expression[0]
+	sd.toCommitExpr = expression[1]
+	if len(expression) == 3 {
+		sd.tableNameExpr = expression[2]
+	}
+
+	// evaluate the arguments now, so that invalid arguments are reported before execution
+	_, _, _, err := sd.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return sd, nil
+}
+
+// Children implements the sql.Node interface
+func (sd *SchemaDiffTableFunction) Children() []sql.Node {
+	return nil
+}
+
+// WithChildren implements the sql.Node interface
+func (sd *SchemaDiffTableFunction) WithChildren(node ...sql.Node) (sql.Node, error) {
+	if len(node) != 0 {
+		panic("unexpected children")
+	}
+	return sd, nil
+}
+
+// CheckPrivileges implements the sql.Node interface
+func (sd *SchemaDiffTableFunction) CheckPrivileges(ctx *sql.Context, opChecker sql.PrivilegedOperationChecker) bool {
+	_, _, tableName, err := sd.evaluateArguments()
+	if err != nil {
+		return false
+	}
+
+	return opChecker.UserHasPrivileges(ctx,
+		sql.NewPrivilegedOperation(sd.database.Name(), tableName, "", sql.PrivilegeType_Select))
+}
+
+// Schema implements the sql.Node interface
+func (sd *SchemaDiffTableFunction) Schema() sql.Schema {
+	return schemaDiffTableSchema
+}
+
+// Resolved implements the sql.Resolvable interface
+func (sd *SchemaDiffTableFunction) Resolved() bool {
+	if sd.tableNameExpr != nil && !sd.tableNameExpr.Resolved() {
+		return false
+	}
+	return sd.fromCommitExpr.Resolved() && sd.toCommitExpr.Resolved()
+}
+
+// String implements the Stringer interface
+func (sd *SchemaDiffTableFunction) String() string {
+	if sd.tableNameExpr != nil {
+		return fmt.Sprintf("DOLT_SCHEMA_DIFF(%s, %s, %s)",
+			sd.fromCommitExpr.String(),
+			sd.toCommitExpr.String(),
+			sd.tableNameExpr.String())
+	}
+	return fmt.Sprintf("DOLT_SCHEMA_DIFF(%s, %s)",
+		sd.fromCommitExpr.String(),
+		sd.toCommitExpr.String())
+}
+
+// FunctionName implements the sql.TableFunction interface
+func (sd *SchemaDiffTableFunction) FunctionName() string {
+	return "dolt_schema_diff"
+}
+
+// evaluateArguments evaluates the argument expressions to turn them into values this SchemaDiffTableFunction can
+// use. The table name is empty if no table was given.
+func (sd *SchemaDiffTableFunction) evaluateArguments() (interface{}, interface{}, string, error) {
+	if !sd.Resolved() {
+		return nil, nil, "", nil
+	}
+
+	if !sql.IsText(sd.fromCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(sd.FunctionName(), sd.fromCommitExpr.String())
+	}
+
+	if !sql.IsText(sd.toCommitExpr.Type()) {
+		return nil, nil, "", sql.ErrInvalidArgumentDetails.New(sd.FunctionName(), sd.toCommitExpr.String())
+	}
+
+	fromCommitVal, err := sd.fromCommitExpr.Eval(sd.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	toCommitVal, err := sd.toCommitExpr.Eval(sd.ctx, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	tableName := ""
+	if sd.tableNameExpr != nil {
+		if !sql.IsText(sd.tableNameExpr.Type()) {
+			return nil, nil, "", sql.ErrInvalidArgumentDetails.New(sd.FunctionName(), sd.tableNameExpr.String())
+		}
+
+		tableNameVal, err := sd.tableNameExpr.Eval(sd.ctx, nil)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var ok bool
+		tableName, ok = tableNameVal.(string)
+		if !ok {
+			return nil, nil, "", ErrInvalidTableName.New(sd.tableNameExpr.String())
+		}
+	}
+
+	return fromCommitVal, toCommitVal, tableName, nil
+}
+
+// RowIter implements the sql.Node interface
+func (sd *SchemaDiffTableFunction) RowIter(ctx *sql.Context, _ sql.Row) (sql.RowIter, error) {
+	fromCommitVal, toCommitVal, tableName, err := sd.evaluateArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	sqledb, ok := sd.database.(Database)
+	if !ok {
+		panic(fmt.Sprintf("unexpected database type: %T", sd.database))
+	}
+
+	fromRoot, _, _, err := loadDetailsForRef(ctx, fromCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+	toRoot, _, _, err := loadDetailsForRef(ctx, toCommitVal, sqledb)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas, err := diff.GetTableDeltas(ctx, fromRoot, toRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableName != "" {
+		_, _, fromTableExists, err := fromRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		_, _, toTableExists, err := toRoot.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if !fromTableExists && !toTableExists {
+			return nil, sql.ErrTableNotFound.New(tableName)
+		}
+
+		delta := findMatchingDelta(deltas, tableName)
+		deltas = nil
+		if delta.FromTable != nil || delta.ToTable != nil {
+			deltas = []diff.TableDelta{delta}
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		return strings.Compare(deltas[i].CurName(), deltas[j].CurName()) < 0
+	})
+
+	var rows []sql.Row
+	for _, td := range deltas {
+		schemaChanged, err := td.HasSchemaChanged(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !schemaChanged && !td.IsRename() && !td.HasFKChanges() {
+			continue
+		}
+
+		tableRows, err := getSchemaDiffRows(ctx, td)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, tableRows...)
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// getSchemaDiffRows returns the dolt_schema_diff rows for the table delta given: the table row, followed by the rows
+// of its changed columns, indexes, checks and foreign keys.
+func getSchemaDiffRows(ctx *sql.Context, td diff.TableDelta) ([]sql.Row, error) {
+	fromSch, toSch, err := td.GetSchemas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve schema for table %s: %w", td.CurName(), err)
+	}
+
+	var fromName, toName, fromCreateStmt, toCreateStmt interface{}
+	if td.FromTable != nil {
+		fromName = td.FromName
+		fromCreateStmt, err = getSchemaDiffCreateStmt(ctx, td.FromName, fromSch, td.FromFks, td.FromFksParentSch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if td.ToTable != nil {
+		toName = td.ToName
+		toCreateStmt, err = getSchemaDiffCreateStmt(ctx, td.ToName, toSch, td.ToFks, td.ToFksParentSch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newRow := func(elementType, elementName string, diffType diff.SchemaChangeType, fromDef, toDef interface{}) sql.Row {
+		return sql.NewRow(fromName, toName, elementType, elementName, schemaDiffTypeName(diffType), fromDef, toDef)
+	}
+
+	tableDiffType := diff.SchDiffModified
+	if td.IsAdd() {
+		tableDiffType = diff.SchDiffAdded
+	} else if td.IsDrop() {
+		tableDiffType = diff.SchDiffRemoved
+	}
+	rows := []sql.Row{newRow(schemaDiffElementTable, td.CurName(), tableDiffType, fromCreateStmt, toCreateStmt)}
+
+	colDiffs, unionTags := diff.DiffSchColumns(fromSch, toSch)
+	for _, tag := range unionTags {
+		cd := colDiffs[tag]
+		switch cd.DiffType {
+		case diff.SchDiffNone:
+		case diff.SchDiffAdded:
+			rows = append(rows, newRow(schemaDiffElementColumn, cd.New.Name, cd.DiffType, nil, sqlfmt.FmtCol(0, 0, 0, *cd.New)))
+		case diff.SchDiffRemoved:
+			rows = append(rows, newRow(schemaDiffElementColumn, cd.Old.Name, cd.DiffType, sqlfmt.FmtCol(0, 0, 0, *cd.Old), nil))
+		case diff.SchDiffModified:
+			rows = append(rows, newRow(schemaDiffElementColumn, cd.New.Name, cd.DiffType, sqlfmt.FmtCol(0, 0, 0, *cd.Old), sqlfmt.FmtCol(0, 0, 0, *cd.New)))
+		}
+	}
+
+	for _, idxDiff := range diff.DiffSchIndexes(fromSch, toSch) {
+		switch idxDiff.DiffType {
+		case diff.SchDiffNone:
+		case diff.SchDiffAdded:
+			rows = append(rows, newRow(schemaDiffElementIndex, idxDiff.To.Name(), idxDiff.DiffType, nil, sqlfmt.FmtIndex(idxDiff.To)))
+		case diff.SchDiffRemoved:
+			rows = append(rows, newRow(schemaDiffElementIndex, idxDiff.From.Name(), idxDiff.DiffType, sqlfmt.FmtIndex(idxDiff.From), nil))
+		case diff.SchDiffModified:
+			rows = append(rows, newRow(schemaDiffElementIndex, idxDiff.To.Name(), idxDiff.DiffType, sqlfmt.FmtIndex(idxDiff.From), sqlfmt.FmtIndex(idxDiff.To)))
+		}
+	}
+
+	for _, chkDiff := range diff.DiffSchChecks(fromSch, toSch) {
+		switch chkDiff.DiffType {
+		case diff.SchDiffNone:
+		case diff.SchDiffAdded:
+			rows = append(rows, newRow(schemaDiffElementCheck, chkDiff.To.Name(), chkDiff.DiffType, nil, fmtCheck(chkDiff.To)))
+		case diff.SchDiffRemoved:
+			rows = append(rows, newRow(schemaDiffElementCheck, chkDiff.From.Name(), chkDiff.DiffType, fmtCheck(chkDiff.From), nil))
+		case diff.SchDiffModified:
+			rows = append(rows, newRow(schemaDiffElementCheck, chkDiff.To.Name(), chkDiff.DiffType, fmtCheck(chkDiff.From), fmtCheck(chkDiff.To)))
+		}
+	}
+
+	fmtFk := func(fk doltdb.ForeignKey, sch schema.Schema, parentSchs map[string]schema.Schema) string {
+		parentSch, ok := parentSchs[fk.ReferencedTableName]
+		if !ok {
+			// the parent table no longer exists, its columns can't be named
+			parentSch = schema.EmptySchema
+		}
+		return sqlfmt.FmtForeignKey(fk, sch, parentSch)
+	}
+	for _, fkDiff := range diff.DiffForeignKeys(td.FromFks, td.ToFks) {
+		switch fkDiff.DiffType {
+		case diff.SchDiffNone:
+		case diff.SchDiffAdded:
+			rows = append(rows, newRow(schemaDiffElementForeignKey, fkDiff.To.Name, fkDiff.DiffType,
+				nil, fmtFk(fkDiff.To, toSch, td.ToFksParentSch)))
+		case diff.SchDiffRemoved:
+			rows = append(rows, newRow(schemaDiffElementForeignKey, fkDiff.From.Name, fkDiff.DiffType,
+				fmtFk(fkDiff.From, fromSch, td.FromFksParentSch), nil))
+		case diff.SchDiffModified:
+			rows = append(rows, newRow(schemaDiffElementForeignKey, fkDiff.To.Name, fkDiff.DiffType,
+				fmtFk(fkDiff.From, fromSch, td.FromFksParentSch), fmtFk(fkDiff.To, toSch, td.ToFksParentSch)))
+		}
+	}
+
+	return rows, nil
+}
+
+// getSchemaDiffCreateStmt returns the CREATE TABLE statement of the table given.
+func getSchemaDiffCreateStmt(ctx *sql.Context, tableName string, sch schema.Schema, fks []doltdb.ForeignKey, parentSchs map[string]schema.Schema) (string, error) {
+	sqlDb := NewSingleTableDatabase(tableName, sch, fks, parentSchs)
+	sqlCtx, engine, _ := PrepareCreateTableStmt(ctx, sqlDb)
+	return GetCreateTableStmt(sqlCtx, engine, tableName)
+}
+
+// schemaDiffTypeName returns the diff_type of a schema change.
+func schemaDiffTypeName(diffType diff.SchemaChangeType) string {
+	switch diffType {
+	case diff.SchDiffAdded:
+		return "added"
+	case diff.SchDiffRemoved:
+		return "dropped"
+	case diff.SchDiffModified:
+		return "modified"
+	default:
+		panic(fmt.Sprintf("unexpected schema change type: %d", diffType))
+	}
+}
+
+// fmtCheck formats a check constraint the way it appears in a CREATE TABLE statement.
+func fmtCheck(chk schema.Check) string {
+	def := fmt.Sprintf("CONSTRAINT %s CHECK (%s)", sqlfmt.QuoteIdentifier(chk.Name()), chk.Expression())
+	if !chk.Enforced() {
+		def += " NOT ENFORCED"
+	}
+	return def
+}
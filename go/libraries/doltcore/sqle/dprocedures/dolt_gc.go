@@ -0,0 +1,109 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dprocedures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
+	"github.com/dolthub/dolt/go/store/chunks"
+)
+
+// doltGC is the stored procedure version of the CLI command `dolt gc`.
+func doltGC(ctx *sql.Context, args ...string) (sql.RowIter, error) {
+	res, err := doDoltGC(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return rowToIter(int64(res)), nil
+}
+
+// doDoltGC collects the garbage of the current database while the server keeps running. The
+// roots held by the sessions of the server are kept, even if they have not been committed yet.
+func doDoltGC(ctx *sql.Context, args []string) (int, error) {
+	dbName := ctx.GetCurrentDatabase()
+	if len(dbName) == 0 {
+		return 1, fmt.Errorf("Empty database name.")
+	}
+
+	apr, err := cli.CreateGCArgParser().Parse(args)
+	if err != nil {
+		return 1, err
+	}
+	if apr.NArg() != 0 {
+		return 1, fmt.Errorf("dolt_gc does not take positional arguments")
+	}
+
+	dSess := dsess.DSessFromSess(ctx.Session)
+	ddb, ok := dSess.GetDoltDB(ctx, dbName)
+	if !ok {
+		return 1, fmt.Errorf("Could not load database %s", dbName)
+	}
+
+	if apr.Contains(cli.ShallowFlag) {
+		err = ddb.ShallowGC(ctx)
+		if errors.Is(err, chunks.ErrUnsupportedOperation) {
+			return 1, fmt.Errorf("this database does not support shallow garbage collection")
+		} else if err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
+	err = ddb.OnlineGC(ctx, func(context.Context) ([]*doltdb.RootValue, error) {
+		return rootsInUse(ctx, ddb)
+	})
+	if err != nil && !errors.Is(err, chunks.ErrNothingToCollect) {
+		return 1, err
+	}
+	return 0, nil
+}
+
+// rootsInUse returns the roots of |ddb| held by the calling session and, if a server is running,
+// by every session of the server.
+func rootsInUse(ctx *sql.Context, ddb *doltdb.DoltDB) ([]*doltdb.RootValue, error) {
+	dSess := dsess.DSessFromSess(ctx.Session)
+	roots := dSess.RootsInUse(ddb)
+
+	if !sqlserver.RunningInServerMode() {
+		return roots, nil
+	}
+	runningServer := sqlserver.GetRunningServer()
+	if runningServer == nil {
+		return roots, nil
+	}
+
+	err := runningServer.SessionManager().Iter(func(session sql.Session) (bool, error) {
+		sess, ok := session.(*dsess.DoltSession)
+		if !ok {
+			return false, fmt.Errorf("unexpected session type: %T", session)
+		}
+		if sess != dSess {
+			roots = append(roots, sess.RootsInUse(ddb)...)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
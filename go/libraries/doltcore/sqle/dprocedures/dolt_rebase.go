@@ -0,0 +1,129 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dprocedures
+
+import (
+	"fmt"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/rebase"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+)
+
+const (
+	rebaseNoConflicts  = 0
+	rebaseHasConflicts = 1
+)
+
+// doltRebase is the stored procedure version of the CLI command `dolt rebase`. With --interactive, the rebase plan is
+// written to the dolt_rebase table and the procedure returns, so that the plan can be edited before calling
+// dolt_rebase('--continue'). Returns 1 if the rebase stopped because of conflicts.
+func doltRebase(ctx *sql.Context, args ...string) (sql.RowIter, error) {
+	res, err := doDoltRebase(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return rowToIter(int64(res)), nil
+}
+
+func doDoltRebase(ctx *sql.Context, args []string) (int, error) {
+	dbName := ctx.GetCurrentDatabase()
+	if len(dbName) == 0 {
+		return rebaseNoConflicts, fmt.Errorf("Empty database name.")
+	}
+
+	apr, err := cli.CreateRebaseArgParser().Parse(args)
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+
+	dSess := dsess.DSessFromSess(ctx.Session)
+	ddb, ok := dSess.GetDoltDB(ctx, dbName)
+	if !ok {
+		return rebaseNoConflicts, sql.ErrDatabaseNotFound.New(dbName)
+	}
+	ws, err := dSess.WorkingSet(ctx, dbName)
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+	headRef, err := dSess.CWBHeadRef(ctx, dbName)
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+
+	if apr.Contains(cli.AbortParam) {
+		if apr.Contains(cli.ContinueFlag) || apr.NArg() > 0 {
+			return rebaseNoConflicts, fmt.Errorf("error: --%s does not take any other arguments", cli.AbortParam)
+		}
+		ws, heads, err := rebase.Abort(ctx, ddb, headRef, ws)
+		if err != nil {
+			return rebaseNoConflicts, err
+		}
+		return rebaseNoConflicts, dSess.SetHeadsWithWorkingSet(ctx, dbName, dSess.GetTransaction(), ws, heads, nil)
+	}
+
+	if !apr.Contains(cli.ContinueFlag) {
+		if apr.NArg() != 1 {
+			return rebaseNoConflicts, fmt.Errorf("error: rebase requires exactly one upstream commit")
+		}
+		cs, err := doltdb.NewCommitSpec(apr.Arg(0))
+		if err != nil {
+			return rebaseNoConflicts, err
+		}
+		upstream, err := ddb.Resolve(ctx, cs, headRef)
+		if err != nil {
+			return rebaseNoConflicts, err
+		}
+		ws, heads, err := rebase.Start(ctx, ddb, headRef, ws, upstream)
+		if err != nil {
+			return rebaseNoConflicts, err
+		}
+		err = dSess.SetHeadsWithWorkingSet(ctx, dbName, dSess.GetTransaction(), ws, heads, nil)
+		if err != nil || apr.Contains(cli.InteractiveFlag) {
+			return rebaseNoConflicts, err
+		}
+	} else if apr.NArg() > 0 || apr.Contains(cli.InteractiveFlag) {
+		return rebaseNoConflicts, fmt.Errorf("error: --%s does not take any other arguments", cli.ContinueFlag)
+	}
+
+	dbState, ok, err := dSess.LookupDbState(ctx, dbName)
+	if err != nil {
+		return rebaseNoConflicts, err
+	} else if !ok {
+		return rebaseNoConflicts, fmt.Errorf("Could not load database %s", dbName)
+	}
+
+	// re-read the working set, which may have just been written by rebase.Start
+	ws, err = dSess.WorkingSet(ctx, dbName)
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+	ws, heads, stoppedAt, err := rebase.Continue(ctx, ddb, headRef, ws, dbState.EditOpts())
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+	err = dSess.SetHeadsWithWorkingSet(ctx, dbName, dSess.GetTransaction(), ws, heads, nil)
+	if err != nil {
+		return rebaseNoConflicts, err
+	}
+
+	if stoppedAt != nil {
+		return rebaseHasConflicts, nil
+	}
+	return rebaseNoConflicts, nil
+}
@@ -120,6 +120,10 @@ func removeRemote(ctx *sql.Context, dbd env.DbData, apr *argparser.ArgParseResul
 	}
 
 	ddb := dbd.Ddb
+	if ddb.LazyRemote() == remote.Name {
+		return env.ErrCannotRemoveLazyRemote
+	}
+
 	refs, err := ddb.GetRemoteRefs(ctx)
 	if err != nil {
 		return fmt.Errorf("error: failed to read from db, cause: %s", env.ErrFailedToReadFromDb.Error())
@@ -144,7 +148,7 @@ func remoteParams(apr *argparser.ArgParseResults, scheme, remoteUrl string) (map
 	params := map[string]string{}
 
 	var err error
-	if scheme == dbfactory.AWSScheme {
+	if scheme == dbfactory.AWSScheme || scheme == dbfactory.S3Scheme {
 		// TODO: get AWS params from session
 		err = cli.AddAWSParams(remoteUrl, apr, params)
 	} else {
@@ -0,0 +1,151 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dprocedures
+
+import (
+	"fmt"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/stash"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const (
+	stashNoConflicts  = 0
+	stashHasConflicts = 1
+)
+
+// doltStash is the stored procedure version of the CLI command `dolt stash`. The stash list can be queried with the
+// dolt_stashes system table. Returns 1 if applying a stash entry resulted in conflicts.
+func doltStash(ctx *sql.Context, args ...string) (sql.RowIter, error) {
+	res, err := doDoltStash(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return rowToIter(int64(res)), nil
+}
+
+func doDoltStash(ctx *sql.Context, args []string) (int, error) {
+	dbName := ctx.GetCurrentDatabase()
+	if len(dbName) == 0 {
+		return stashNoConflicts, fmt.Errorf("Empty database name.")
+	}
+
+	apr, err := cli.CreateStashArgParser().Parse(args)
+	if err != nil {
+		return stashNoConflicts, err
+	}
+
+	subcommand := "push"
+	if apr.NArg() > 0 {
+		subcommand = apr.Arg(0)
+	}
+	if apr.Contains(cli.MessageArg) && subcommand != "push" {
+		return stashNoConflicts, fmt.Errorf("error: --%s is only supported by push", cli.MessageArg)
+	}
+
+	dSess := dsess.DSessFromSess(ctx.Session)
+	ddb, ok := dSess.GetDoltDB(ctx, dbName)
+	if !ok {
+		return stashNoConflicts, sql.ErrDatabaseNotFound.New(dbName)
+	}
+	// the stash list changes are committed along with the working set, which otherwise stays in the transaction
+	write := func(ws *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) error {
+		if len(heads) == 0 {
+			return dSess.SetWorkingSet(ctx, dbName, ws)
+		}
+		return dSess.SetHeadsWithWorkingSet(ctx, dbName, dSess.GetTransaction(), ws, heads, prevHeads)
+	}
+
+	switch subcommand {
+	case "push":
+		if apr.NArg() > 1 {
+			return stashNoConflicts, fmt.Errorf("error: push does not take any other arguments")
+		}
+		ws, err := dSess.WorkingSet(ctx, dbName)
+		if err != nil {
+			return stashNoConflicts, err
+		}
+		headRef, err := dSess.CWBHeadRef(ctx, dbName)
+		if err != nil {
+			return stashNoConflicts, err
+		}
+		msg, _ := apr.GetValue(cli.MessageArg)
+		_, err = stash.Push(ctx, ddb, headRef, ws, dSess.Username(), dSess.Email(), msg, write)
+		return stashNoConflicts, err
+
+	case "pop", "apply", "drop":
+		if apr.NArg() > 2 {
+			return stashNoConflicts, fmt.Errorf("error: %s takes at most one stash entry", subcommand)
+		}
+		name := ""
+		if apr.NArg() == 2 {
+			name = apr.Arg(1)
+		}
+		s, err := stash.Get(ctx, ddb, name)
+		if err != nil {
+			return stashNoConflicts, err
+		}
+		if subcommand == "drop" {
+			return stashNoConflicts, stash.Drop(ctx, ddb, s)
+		}
+
+		ws, err := dSess.WorkingSet(ctx, dbName)
+		if err != nil {
+			return stashNoConflicts, err
+		}
+		dbState, ok, err := dSess.LookupDbState(ctx, dbName)
+		if err != nil {
+			return stashNoConflicts, err
+		} else if !ok {
+			return stashNoConflicts, fmt.Errorf("Could not load database %s", dbName)
+		}
+
+		if subcommand == "pop" {
+			hasConflicts, err := stash.Pop(ctx, ddb, ws, s, dbState.EditOpts(), write)
+			if err != nil || !hasConflicts {
+				return stashNoConflicts, err
+			}
+			return stashHasConflicts, nil
+		}
+
+		ws, hasConflicts, err := stash.Apply(ctx, ddb, ws, s, dbState.EditOpts())
+		if err != nil {
+			return stashNoConflicts, err
+		}
+		err = dSess.SetWorkingSet(ctx, dbName, ws)
+		if err != nil || !hasConflicts {
+			return stashNoConflicts, err
+		}
+		return stashHasConflicts, nil
+
+	case "clear":
+		if apr.NArg() > 1 {
+			return stashNoConflicts, fmt.Errorf("error: clear does not take any other arguments")
+		}
+		return stashNoConflicts, stash.Clear(ctx, ddb)
+
+	case "list":
+		return stashNoConflicts, fmt.Errorf("error: invalid argument, use 'dolt_stashes' system table to list stash entries")
+
+	default:
+		return stashNoConflicts, fmt.Errorf("error: unknown subcommand '%s'", subcommand)
+	}
+}
@@ -25,12 +25,15 @@ var DoltProcedures = []sql.ExternalStoredProcedureDetails{
 	{Name: "dolt_clone", Schema: int64Schema("status"), Function: doltClone},
 	{Name: "dolt_commit", Schema: stringSchema("hash"), Function: doltCommit},
 	{Name: "dolt_fetch", Schema: int64Schema("success"), Function: doltFetch},
+	{Name: "dolt_gc", Schema: int64Schema("status"), Function: doltGC},
 	{Name: "dolt_merge", Schema: int64Schema("fast_forward", "conflicts"), Function: doltMerge},
 	{Name: "dolt_pull", Schema: int64Schema("fast_forward", "conflicts"), Function: doltPull},
 	{Name: "dolt_push", Schema: int64Schema("success"), Function: doltPush},
+	{Name: "dolt_rebase", Schema: int64Schema("conflicts"), Function: doltRebase},
 	{Name: "dolt_remote", Schema: int64Schema("status"), Function: doltRemote},
 	{Name: "dolt_reset", Schema: int64Schema("status"), Function: doltReset},
 	{Name: "dolt_revert", Schema: int64Schema("status"), Function: doltRevert},
+	{Name: "dolt_stash", Schema: int64Schema("conflicts"), Function: doltStash},
 	{Name: "dolt_tag", Schema: int64Schema("status"), Function: doltTag},
 	{Name: "dolt_verify_constraints", Schema: int64Schema("violations"), Function: doltVerifyConstraints},
 
@@ -42,12 +45,15 @@ var DoltProcedures = []sql.ExternalStoredProcedureDetails{
 	{Name: "dclone", Schema: int64Schema("status"), Function: doltClone},
 	{Name: "dcommit", Schema: stringSchema("hash"), Function: doltCommit},
 	{Name: "dfetch", Schema: int64Schema("success"), Function: doltFetch},
+	{Name: "dgc", Schema: int64Schema("status"), Function: doltGC},
 	{Name: "dmerge", Schema: int64Schema("fast_forward", "conflicts"), Function: doltMerge},
 	{Name: "dpull", Schema: int64Schema("fast_forward", "conflicts"), Function: doltPull},
 	{Name: "dpush", Schema: int64Schema("success"), Function: doltPush},
+	{Name: "drebase", Schema: int64Schema("conflicts"), Function: doltRebase},
 	{Name: "dremote", Schema: int64Schema("status"), Function: doltRemote},
 	{Name: "dreset", Schema: int64Schema("status"), Function: doltReset},
 	{Name: "drevert", Schema: int64Schema("status"), Function: doltRevert},
+	{Name: "dstash", Schema: int64Schema("conflicts"), Function: doltStash},
 	{Name: "dtag", Schema: int64Schema("status"), Function: doltTag},
 	{Name: "dverify_constraints", Schema: int64Schema("violations"), Function: doltVerifyConstraints},
 }
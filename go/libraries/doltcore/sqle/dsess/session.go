@@ -34,6 +34,7 @@ import (
 	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/writer"
 	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
 	"github.com/dolthub/dolt/go/libraries/utils/config"
+	"github.com/dolthub/dolt/go/store/hash"
 	"github.com/dolthub/dolt/go/store/types"
 )
 
@@ -348,6 +349,49 @@ func (d *DoltSession) DoltCommit(
 	return d.doCommit(ctx, dbName, tx, commitFunc)
 }
 
+// SetHeadsWithWorkingSet commits |ws| and sets the heads given, deleting those with a nil commit, in one atomic write.
+// The heads in |prevHeads| must still be at the hashes given, see DoltDB.SetHeadsWithWorkingSet. It's used by
+// operations that move refs along with the working set without committing on top of it, such as rebase.
+func (d *DoltSession) SetHeadsWithWorkingSet(
+	ctx *sql.Context,
+	dbName string,
+	tx sql.Transaction,
+	ws *doltdb.WorkingSet,
+	heads map[ref.DoltRef]*doltdb.Commit,
+	prevHeads map[ref.DoltRef]hash.Hash,
+) error {
+	commitFunc := func(ctx *sql.Context, dtx *DoltTransaction, _ *doltdb.WorkingSet) (*doltdb.WorkingSet, *doltdb.Commit, error) {
+		ws, err := dtx.SetHeads(ctx, ws, heads, prevHeads)
+		return ws, nil, err
+	}
+
+	_, err := d.doCommit(ctx, dbName, tx, commitFunc)
+	if err != nil {
+		return err
+	}
+
+	sessionState, _, err := d.LookupDbState(ctx, dbName)
+	if err != nil {
+		return err
+	}
+
+	// the branch head has moved under the current transaction, so it's restarted from the working set just written
+	tCharacteristic := sql.ReadWrite
+	if tx.IsReadOnly() {
+		tCharacteristic = sql.ReadOnly
+	}
+	ctx.SetTransaction(NewDoltTransaction(
+		dbName,
+		sessionState.WorkingSet,
+		sessionState.WorkingSet.Ref(),
+		sessionState.dbData,
+		sessionState.WriteSession.GetOptions(),
+		tCharacteristic,
+	))
+
+	return nil
+}
+
 // doCommitFunc is a function to write to the database, which involves updating the working set and potentially
 // updating HEAD with a new commit
 type doCommitFunc func(ctx *sql.Context, dtx *DoltTransaction, workingSet *doltdb.WorkingSet) (*doltdb.WorkingSet, *doltdb.Commit, error)
@@ -392,7 +436,7 @@ func (d *DoltSession) PendingCommitAllStaged(ctx *sql.Context, dbName string, pr
 	}
 
 	var err error
-	roots, err = actions.StageAllTables(ctx, roots)
+	roots, err = actions.StageAllTables(ctx, roots, true)
 	if err != nil {
 		return nil, err
 	}
@@ -558,6 +602,58 @@ func (d *DoltSession) GetRoots(ctx *sql.Context, dbName string) (doltdb.Roots, b
 	return dbState.GetRoots(), true
 }
 
+// RootsInUse returns the root values the session holds for the databases stored in |ddb|: the roots of their working
+// sets and head commits, and the roots the transaction of the session started from. They are read while the session
+// may be running queries in another goroutine, for garbage collection to keep them. The session changes them under
+// |d.mu|, see setWorkingSetAndHead.
+func (d *DoltSession) RootsInUse(ddb *doltdb.DoltDB) []*doltdb.RootValue {
+	var roots []*doltdb.RootValue
+	d.mu.Lock()
+	for _, state := range d.dbStates {
+		if state.dbData.Ddb != ddb {
+			continue
+		}
+		roots = append(roots, workingSetRoots(state.WorkingSet)...)
+		if state.headRoot != nil {
+			roots = append(roots, state.headRoot)
+		}
+	}
+	d.mu.Unlock()
+
+	if tx, ok := d.GetTransaction().(*DoltTransaction); ok && tx.dbData.Ddb == ddb {
+		roots = append(roots, tx.rootsInUse()...)
+	}
+
+	return roots
+}
+
+// setWorkingSetAndHead sets the working set and head of |state|. They are set under |d.mu|, so that RootsInUse can read
+// them from another goroutine. A nil |headCommit| or |headRoot| leaves the head as it is.
+func (d *DoltSession) setWorkingSetAndHead(state *DatabaseSessionState, ws *doltdb.WorkingSet, headCommit *doltdb.Commit, headRoot *doltdb.RootValue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ws != nil {
+		state.WorkingSet = ws
+	}
+	if headCommit != nil {
+		state.headCommit = headCommit
+	}
+	if headRoot != nil {
+		state.headRoot = headRoot
+	}
+}
+
+func workingSetRoots(ws *doltdb.WorkingSet) []*doltdb.RootValue {
+	if ws == nil {
+		return nil
+	}
+	roots := []*doltdb.RootValue{ws.WorkingRoot(), ws.StagedRoot()}
+	if ws.MergeActive() {
+		roots = append(roots, ws.MergeState().PreMergeWorkingRoot())
+	}
+	return roots
+}
+
 // ResolveRootForRef returns the root value for the ref given, which refers to either a commit spec or is one of the
 // special identifiers |WORKING| or |STAGED|
 // Returns the root value associated with the identifier given and its commit time
@@ -632,9 +728,7 @@ func (d *DoltSession) SetRoot(ctx *sql.Context, dbName string, newRoot *doltdb.R
 		// TODO: Return an error here?
 		return nil
 	}
-	sessionState.WorkingSet = sessionState.WorkingSet.WithWorkingRoot(newRoot)
-
-	return d.SetWorkingSet(ctx, dbName, sessionState.WorkingSet)
+	return d.SetWorkingSet(ctx, dbName, sessionState.WorkingSet.WithWorkingRoot(newRoot))
 }
 
 // SetRoots sets new roots for the session for the database named. Typically clients should only set the working root,
@@ -665,7 +759,6 @@ func (d *DoltSession) SetWorkingSet(ctx *sql.Context, dbName string, ws *doltdb.
 	if ws.Ref() != sessionState.WorkingSet.Ref() {
 		return fmt.Errorf("must switch working sets with SwitchWorkingSet")
 	}
-	sessionState.WorkingSet = ws
 
 	cs, err := doltdb.NewCommitSpec(ws.Ref().GetPath())
 	if err != nil {
@@ -681,13 +774,12 @@ func (d *DoltSession) SetWorkingSet(ctx *sql.Context, dbName string, ws *doltdb.
 	if err != nil {
 		return err
 	}
-	sessionState.headCommit = cm
 
 	headRoot, err := cm.GetRootValue(ctx)
 	if err != nil {
 		return err
 	}
-	sessionState.headRoot = headRoot
+	d.setWorkingSetAndHead(sessionState, ws, cm, headRoot)
 
 	err = d.setSessionVarsForDb(ctx, dbName)
 	if err != nil {
@@ -729,8 +821,6 @@ func (d *DoltSession) SwitchWorkingSet(
 	}
 
 	// TODO: just call SetWorkingSet?
-	sessionState.WorkingSet = ws
-
 	cs, err := doltdb.NewCommitSpec(ws.Ref().GetPath())
 	if err != nil {
 		return err
@@ -746,11 +836,11 @@ func (d *DoltSession) SwitchWorkingSet(
 		return err
 	}
 
-	sessionState.headCommit = cm
-	sessionState.headRoot, err = cm.GetRootValue(ctx)
+	headRoot, err := cm.GetRootValue(ctx)
 	if err != nil {
 		return err
 	}
+	d.setWorkingSetAndHead(sessionState, ws, cm, headRoot)
 
 	err = d.setSessionVarsForDb(ctx, dbName)
 	if err != nil {
@@ -900,7 +990,6 @@ func (d *DoltSession) AddDB(ctx *sql.Context, dbState InitialDbState) error {
 	DefineSystemVariablesForDB(db.Name())
 
 	sessionState := NewEmptyDatabaseSessionState()
-	d.dbStates[db.Name()] = sessionState
 	sessionState.dbName = db.Name()
 	// TODO: get rid of all repo state reader / writer stuff. Until we do, swap out the reader with one of our own, and
 	//  the writer with one that errors out
@@ -924,12 +1013,16 @@ func (d *DoltSession) AddDB(ctx *sql.Context, dbState InitialDbState) error {
 	}
 	sessionState.globalState = stateProvider.GetGlobalState()
 
+	d.mu.Lock()
+	d.dbStates[db.Name()] = sessionState
+	d.mu.Unlock()
+
 	// WorkingSet is nil in the case of a read only, detached head DB
 	if dbState.Err != nil {
 		sessionState.Err = dbState.Err
 
 	} else if dbState.WorkingSet != nil {
-		sessionState.WorkingSet = dbState.WorkingSet
+		d.setWorkingSetAndHead(sessionState, dbState.WorkingSet, nil, nil)
 		tracker, err := sessionState.globalState.GetAutoIncrementTracker(ctx)
 		if err != nil {
 			return err
@@ -944,12 +1037,12 @@ func (d *DoltSession) AddDB(ctx *sql.Context, dbState InitialDbState) error {
 		if err != nil {
 			return err
 		}
-		sessionState.headRoot = headRoot
+		d.setWorkingSetAndHead(sessionState, nil, nil, headRoot)
 	}
 
 	// This has to happen after SetRoot above, since it does a stale check before its work
 	// TODO: this needs to be kept up to date as the working set ref changes
-	sessionState.headCommit = dbState.HeadCommit
+	d.setWorkingSetAndHead(sessionState, nil, dbState.HeadCommit, nil)
 
 	// After setting the initial root we have no state to commit
 	sessionState.dirty = false
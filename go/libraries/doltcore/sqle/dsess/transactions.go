@@ -72,10 +72,12 @@ func (d DisabledTransaction) IsReadOnly() bool {
 }
 
 type DoltTransaction struct {
-	sourceDbName    string
-	startState      *doltdb.WorkingSet
-	workingSetRef   ref.WorkingSetRef
-	dbData          env.DbData
+	sourceDbName  string
+	startState    *doltdb.WorkingSet
+	workingSetRef ref.WorkingSetRef
+	dbData        env.DbData
+	// mu guards |savepoints|, which are read by garbage collection from another goroutine
+	mu              *sync.Mutex
 	savepoints      []savepoint
 	mergeEditOpts   editor.Options
 	tCharacteristic sql.TransactionCharacteristic
@@ -99,6 +101,7 @@ func NewDoltTransaction(
 		startState:      startState,
 		workingSetRef:   workingSet,
 		dbData:          dbData,
+		mu:              &sync.Mutex{},
 		mergeEditOpts:   mergeEditOpts,
 		tCharacteristic: tCharacteristic,
 	}
@@ -163,6 +166,25 @@ func txCommit(ctx *sql.Context,
 	return workingSet, nil, tx.dbData.Ddb.UpdateWorkingSet(ctx, tx.workingSetRef, workingSet, hash, tx.getWorkingSetMeta(ctx))
 }
 
+// setHeads returns a transactionWrite function that updates the working set and sets the heads given atomically
+func setHeads(heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) transactionWrite {
+	return func(ctx *sql.Context,
+		tx *DoltTransaction,
+		_ *doltdb.PendingCommit,
+		workingSet *doltdb.WorkingSet,
+		hash hash.Hash,
+	) (*doltdb.WorkingSet, *doltdb.Commit, error) {
+		return workingSet, nil, tx.dbData.Ddb.SetHeadsWithWorkingSet(ctx, heads, prevHeads, tx.workingSetRef, workingSet, hash, tx.getWorkingSetMeta(ctx))
+	}
+}
+
+// SetHeads commits the working set and sets the heads given, in one atomic write. Heads with a nil commit are deleted.
+// The heads in |prevHeads| must still be at the hashes given, see DoltDB.SetHeadsWithWorkingSet.
+func (tx *DoltTransaction) SetHeads(ctx *sql.Context, workingSet *doltdb.WorkingSet, heads map[ref.DoltRef]*doltdb.Commit, prevHeads map[ref.DoltRef]hash.Hash) (*doltdb.WorkingSet, error) {
+	ws, _, err := tx.doCommit(ctx, workingSet, nil, setHeads(heads, prevHeads))
+	return ws, err
+}
+
 // DoltCommit commits the working set and creates a new DoltCommit as specified, in one atomic write
 func (tx *DoltTransaction) DoltCommit(ctx *sql.Context, workingSet *doltdb.WorkingSet, commit *doltdb.PendingCommit) (*doltdb.WorkingSet, *doltdb.Commit, error) {
 	return tx.doCommit(ctx, workingSet, commit, doltCommit)
@@ -402,6 +424,8 @@ func (tx *DoltTransaction) validateWorkingSetForCommit(ctx *sql.Context, working
 // CreateSavepoint creates a new savepoint with the name and root value given. If a savepoint with the name given
 // already exists, it's overwritten.
 func (tx *DoltTransaction) CreateSavepoint(name string, root *doltdb.RootValue) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 	existing := tx.findSavepoint(name)
 	if existing >= 0 {
 		tx.savepoints = append(tx.savepoints[:existing], tx.savepoints[existing+1:]...)
@@ -409,6 +433,17 @@ func (tx *DoltTransaction) CreateSavepoint(name string, root *doltdb.RootValue)
 	tx.savepoints = append(tx.savepoints, savepoint{name, root})
 }
 
+// rootsInUse returns the roots the transaction started from and the roots of its savepoints.
+func (tx *DoltTransaction) rootsInUse() []*doltdb.RootValue {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	roots := workingSetRoots(tx.startState)
+	for _, sp := range tx.savepoints {
+		roots = append(roots, sp.root)
+	}
+	return roots
+}
+
 // findSavepoint returns the index of the savepoint with the name given, or -1 if it doesn't exist
 func (tx *DoltTransaction) findSavepoint(name string) int {
 	for i, s := range tx.savepoints {
@@ -422,6 +457,8 @@ func (tx *DoltTransaction) findSavepoint(name string) int {
 // RollbackToSavepoint returns the root value associated with the savepoint name given, or nil if no such savepoint can
 // be found. All savepoints created after the one being rolled back to are no longer accessible.
 func (tx *DoltTransaction) RollbackToSavepoint(name string) *doltdb.RootValue {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 	existing := tx.findSavepoint(name)
 	if existing >= 0 {
 		// Clear out any savepoints past this one
@@ -434,6 +471,8 @@ func (tx *DoltTransaction) RollbackToSavepoint(name string) *doltdb.RootValue {
 // ClearSavepoint removes the savepoint with the name given and returns the root value recorded there, or nil if no
 // savepoint exists with that name.
 func (tx *DoltTransaction) ClearSavepoint(name string) *doltdb.RootValue {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 	existing := tx.findSavepoint(name)
 	var existingRoot *doltdb.RootValue
 	if existing >= 0 {
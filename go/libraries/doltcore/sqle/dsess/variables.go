@@ -43,9 +43,15 @@ const (
 	ReplicateHeads                = "dolt_replicate_heads"
 	ReplicateAllHeads             = "dolt_replicate_all_heads"
 	AsyncReplication              = "dolt_async_replication"
+	ReplicateWorkingSets          = "dolt_replicate_working_sets"
 	AwsCredsFile                  = "aws_credentials_file"
 	AwsCredsProfile               = "aws_credentials_profile"
 	AwsCredsRegion                = "aws_credentials_region"
+	ServerID                      = "server_id"
+	ServerUUID                    = "server_uuid"
+	LogBin                        = "log_bin"
+	BinlogFormat                  = "binlog_format"
+	BinlogChecksum                = "binlog_checksum"
 )
 
 // DefineSystemVariablesForDB defines per database dolt-session variables in the engine as necessary
@@ -0,0 +1,364 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dtables
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/commitwalk"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// ColumnDiffTable is a sql.Table implementation of a system table that shows which columns of which tables have
+// changed in each commit. It is the dolt_column_diff table, or the dolt_column_diff_<table> table if it is scoped to a
+// single table.
+type ColumnDiffTable struct {
+	// tableName is the name of the table the results are limited to, or empty for the unscoped table
+	tableName string
+	ddb       *doltdb.DoltDB
+	head      *doltdb.Commit
+}
+
+// columnChange is an internal data structure used to hold a column that was added, removed or modified in a
+// diff.TableDelta.
+type columnChange struct {
+	tableName  string
+	columnName string
+	diffType   string
+}
+
+// NewColumnDiffTable creates a ColumnDiffTable. If |tableName| is not empty, the table only shows the changes of
+// that table, which must exist in |root|.
+func NewColumnDiffTable(ctx *sql.Context, tableName string, ddb *doltdb.DoltDB, root *doltdb.RootValue, head *doltdb.Commit) (sql.Table, error) {
+	if tableName != "" {
+		_, name, ok, err := root.GetTableInsensitive(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, sql.ErrTableNotFound.New(doltdb.DoltColumnDiffTablePrefix + tableName)
+		}
+		tableName = name
+	}
+
+	return &ColumnDiffTable{tableName: tableName, ddb: ddb, head: head}, nil
+}
+
+// Name is a sql.Table interface function which returns the name of the table
+func (dt *ColumnDiffTable) Name() string {
+	if dt.tableName != "" {
+		return doltdb.DoltColumnDiffTablePrefix + dt.tableName
+	}
+	return doltdb.ColumnDiffTableName
+}
+
+// String is a sql.Table interface function which returns the name of the table
+func (dt *ColumnDiffTable) String() string {
+	return dt.Name()
+}
+
+// Schema is a sql.Table interface function that returns the sql.Schema for this system table.
+func (dt *ColumnDiffTable) Schema() sql.Schema {
+	name := dt.Name()
+	return []*sql.Column{
+		{Name: "commit_hash", Type: sql.Text, Source: name, PrimaryKey: true},
+		{Name: "table_name", Type: sql.Text, Source: name, PrimaryKey: true},
+		{Name: "column_name", Type: sql.Text, Source: name, PrimaryKey: true},
+		{Name: "committer", Type: sql.Text, Source: name, PrimaryKey: false},
+		{Name: "email", Type: sql.Text, Source: name, PrimaryKey: false},
+		{Name: "date", Type: sql.Datetime, Source: name, PrimaryKey: false},
+		{Name: "message", Type: sql.Text, Source: name, PrimaryKey: false},
+		{Name: "diff_type", Type: sql.Text, Source: name, PrimaryKey: false},
+	}
+}
+
+// Collation implements the sql.Table interface.
+func (dt *ColumnDiffTable) Collation() sql.CollationID {
+	return sql.Collation_Default
+}
+
+// Partitions is a sql.Table interface function that returns a partition of the data. Returns one
+// partition for working set changes and one partition for all commit history.
+func (dt *ColumnDiffTable) Partitions(ctx *sql.Context) (sql.PartitionIter, error) {
+	return NewSliceOfPartitionsItr([]sql.Partition{
+		newDoltDiffPartition(workingSetPartitionKey),
+		newDoltDiffPartition(commitHistoryPartitionKey),
+	}), nil
+}
+
+// PartitionRows is a sql.Table interface function that gets a row iterator for a partition.
+func (dt *ColumnDiffTable) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.RowIter, error) {
+	if bytes.Equal(partition.Key(), workingSetPartitionKey) {
+		return dt.newWorkingSetRowItr(ctx)
+	} else if bytes.Equal(partition.Key(), commitHistoryPartitionKey) {
+		return dt.newCommitHistoryRowItr(ctx)
+	} else {
+		return nil, fmt.Errorf("unexpected partition: %v", partition)
+	}
+}
+
+// filterDeltas returns the deltas of the table this ColumnDiffTable is scoped to, or all the deltas given for the
+// unscoped table.
+func (dt *ColumnDiffTable) filterDeltas(deltas []diff.TableDelta) []diff.TableDelta {
+	if dt.tableName == "" {
+		return deltas
+	}
+
+	var filtered []diff.TableDelta
+	for _, delta := range deltas {
+		if strings.EqualFold(delta.ToName, dt.tableName) || strings.EqualFold(delta.FromName, dt.tableName) {
+			filtered = append(filtered, delta)
+		}
+	}
+	return filtered
+}
+
+func (dt *ColumnDiffTable) newWorkingSetRowItr(ctx *sql.Context) (sql.RowIter, error) {
+	sess := dsess.DSessFromSess(ctx.Session)
+	roots, ok := sess.GetRoots(ctx, ctx.GetCurrentDatabase())
+	if !ok {
+		return nil, fmt.Errorf("unable to lookup roots for database %s", ctx.GetCurrentDatabase())
+	}
+
+	staged, unstaged, err := diff.GetStagedUnstagedTableDeltas(ctx, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sql.Row
+	for _, changeSet := range []struct {
+		name   string
+		deltas []diff.TableDelta
+	}{
+		{"STAGED", dt.filterDeltas(staged)},
+		{"WORKING", dt.filterDeltas(unstaged)},
+	} {
+		for _, delta := range changeSet.deltas {
+			changes, err := calculateColumnChanges(ctx, delta)
+			if err != nil {
+				return nil, err
+			}
+			for _, change := range changes {
+				rows = append(rows, sql.NewRow(
+					changeSet.name,
+					change.tableName,
+					change.columnName,
+					nil, // committer
+					nil, // email
+					nil, // date
+					nil, // message
+					change.diffType,
+				))
+			}
+		}
+	}
+
+	return sql.RowsToRowIter(rows...), nil
+}
+
+// columnDiffCommitHistoryRowItr is a sql.RowItr implementation which iterates over each commit as if it's a row in
+// the table.
+type columnDiffCommitHistoryRowItr struct {
+	dt         *ColumnDiffTable
+	child      doltdb.CommitItr
+	meta       *datas.CommitMeta
+	hash       hash.Hash
+	changes    []columnChange
+	changesIdx int
+}
+
+// newCommitHistoryRowItr creates a columnDiffCommitHistoryRowItr from the current environment.
+func (dt *ColumnDiffTable) newCommitHistoryRowItr(ctx *sql.Context) (*columnDiffCommitHistoryRowItr, error) {
+	h, err := dt.head.HashOf()
+	if err != nil {
+		return nil, err
+	}
+	child, err := commitwalk.GetTopologicalOrderIterator(ctx, dt.ddb, h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &columnDiffCommitHistoryRowItr{
+		dt:    dt,
+		child: child,
+	}, nil
+}
+
+// Next retrieves the next row. It will return io.EOF if it's the last row.
+func (itr *columnDiffCommitHistoryRowItr) Next(ctx *sql.Context) (sql.Row, error) {
+	for itr.changesIdx >= len(itr.changes) {
+		err := itr.loadColumnChanges(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	change := itr.changes[itr.changesIdx]
+	itr.changesIdx++
+
+	return sql.NewRow(
+		itr.hash.String(),
+		change.tableName,
+		change.columnName,
+		itr.meta.Name,
+		itr.meta.Email,
+		itr.meta.Time(),
+		itr.meta.Description,
+		change.diffType,
+	), nil
+}
+
+// loadColumnChanges loads the next commit's column changes and metadata into the iterator.
+func (itr *columnDiffCommitHistoryRowItr) loadColumnChanges(ctx *sql.Context) error {
+	h, commit, err := itr.child.Next(ctx)
+	if err != nil {
+		return err
+	}
+
+	itr.changes = nil
+	itr.changesIdx = 0
+
+	// the changes of a commit are computed against its first parent, initial commits don't have any
+	if len(commit.DatasParents()) == 0 {
+		return nil
+	}
+
+	toRoot, err := commit.GetRootValue(ctx)
+	if err != nil {
+		return err
+	}
+	parent, err := itr.dt.ddb.ResolveParent(ctx, commit, 0)
+	if err != nil {
+		return err
+	}
+	fromRoot, err := parent.GetRootValue(ctx)
+	if err != nil {
+		return err
+	}
+
+	deltas, err := diff.GetTableDeltas(ctx, fromRoot, toRoot)
+	if err != nil {
+		return err
+	}
+
+	var changes []columnChange
+	for _, delta := range itr.dt.filterDeltas(deltas) {
+		tableChanges, err := calculateColumnChanges(ctx, delta)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, tableChanges...)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	meta, err := commit.GetCommitMeta(ctx)
+	if err != nil {
+		return err
+	}
+	itr.meta = meta
+	itr.hash = h
+	itr.changes = changes
+	return nil
+}
+
+// Close closes the iterator.
+func (itr *columnDiffCommitHistoryRowItr) Close(*sql.Context) error {
+	return nil
+}
+
+// calculateColumnChanges returns the columns that were added, removed or modified in the table delta given. Columns
+// added to or dropped from the schema are reported as added or removed, while columns whose definition changed, or
+// that have a different value in any changed row, are reported as modified.
+func calculateColumnChanges(ctx *sql.Context, delta diff.TableDelta) ([]columnChange, error) {
+	fromSch, toSch, err := delta.GetSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	colDiffs, unionTags := diff.DiffSchColumns(fromSch, toSch)
+
+	var unchangedCols []schema.Column
+	for _, tag := range unionTags {
+		if cd := colDiffs[tag]; cd.DiffType == diff.SchDiffNone {
+			unchangedCols = append(unchangedCols, *cd.New)
+		}
+	}
+
+	modifiedTags, err := getModifiedColumnTags(ctx, delta, unchangedCols)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []columnChange
+	for _, tag := range unionTags {
+		cd := colDiffs[tag]
+		switch cd.DiffType {
+		case diff.SchDiffAdded:
+			changes = append(changes, columnChange{delta.CurName(), cd.New.Name, diffTypeAdded})
+		case diff.SchDiffRemoved:
+			changes = append(changes, columnChange{delta.CurName(), cd.Old.Name, diffTypeRemoved})
+		case diff.SchDiffModified:
+			changes = append(changes, columnChange{delta.CurName(), cd.New.Name, diffTypeModified})
+		case diff.SchDiffNone:
+			if modifiedTags[tag] {
+				changes = append(changes, columnChange{delta.CurName(), cd.New.Name, diffTypeModified})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// getModifiedColumnTags returns the tags of the columns in |cols|, which exist with the same definition on both sides
+// of the table delta, that have a different value in any of the rows changed by the delta.
+func getModifiedColumnTags(ctx *sql.Context, delta diff.TableDelta, cols []schema.Column) (map[uint64]bool, error) {
+	modified := make(map[uint64]bool)
+	if len(cols) == 0 {
+		return modified, nil
+	}
+
+	dataChanged, err := delta.HasHashChanged()
+	if err != nil {
+		return nil, err
+	}
+	if !dataChanged {
+		return modified, nil
+	}
+
+	if delta.HasPrimaryKeySetChanged() {
+		// the rows can't be diffed, every row is considered rewritten
+		for _, col := range cols {
+			modified[col.Tag] = true
+		}
+		return modified, nil
+	}
+
+	tags := make([]uint64, len(cols))
+	for i, col := range cols {
+		tags[i] = col.Tag
+	}
+	return diff.ModifiedColumnsForTableDelta(ctx, delta, tags)
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dtables
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/index"
+)
+
+var _ sql.Table = (*MergeStatusTable)(nil)
+
+// MergeStatusTable is a sql.Table implementation that implements a system table which shows whether the working set of
+// the session is in the middle of a merge, and if so, what is being merged and which tables are still unmerged
+type MergeStatusTable struct {
+	dbName string
+}
+
+// NewMergeStatusTable creates a MergeStatusTable
+func NewMergeStatusTable(_ *sql.Context, dbName string) sql.Table {
+	return &MergeStatusTable{dbName: dbName}
+}
+
+// Name is a sql.Table interface function which returns the name of the table which is defined by the constant
+// MergeStatusTableName
+func (mst *MergeStatusTable) Name() string {
+	return doltdb.MergeStatusTableName
+}
+
+// String is a sql.Table interface function which returns the name of the table which is defined by the constant
+// MergeStatusTableName
+func (mst *MergeStatusTable) String() string {
+	return doltdb.MergeStatusTableName
+}
+
+// Schema is a sql.Table interface function that gets the sql.Schema of the merge status system table.
+func (mst *MergeStatusTable) Schema() sql.Schema {
+	return []*sql.Column{
+		{Name: "is_merging", Type: sql.Boolean, Source: doltdb.MergeStatusTableName, PrimaryKey: false, Nullable: false},
+		{Name: "source", Type: sql.Text, Source: doltdb.MergeStatusTableName, PrimaryKey: false, Nullable: true},
+		{Name: "source_commit", Type: sql.Text, Source: doltdb.MergeStatusTableName, PrimaryKey: false, Nullable: true},
+		{Name: "target", Type: sql.Text, Source: doltdb.MergeStatusTableName, PrimaryKey: false, Nullable: true},
+		{Name: "unmerged_tables", Type: sql.Text, Source: doltdb.MergeStatusTableName, PrimaryKey: false, Nullable: true},
+	}
+}
+
+// Collation implements the sql.Table interface.
+func (mst *MergeStatusTable) Collation() sql.CollationID {
+	return sql.Collation_Default
+}
+
+// Partitions is a sql.Table interface function that returns a partition of the data. Currently, the data is unpartitioned.
+func (mst *MergeStatusTable) Partitions(*sql.Context) (sql.PartitionIter, error) {
+	return index.SinglePartitionIterFromNomsMap(nil), nil
+}
+
+// PartitionRows is a sql.Table interface function that gets a row iterator for a partition
+func (mst *MergeStatusTable) PartitionRows(ctx *sql.Context, _ sql.Partition) (sql.RowIter, error) {
+	sess := dsess.DSessFromSess(ctx.Session)
+	ws, err := sess.WorkingSet(ctx, mst.dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := newMergeStatusRow(ctx, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.RowsToRowIter(row), nil
+}
+
+// newMergeStatusRow returns the single row of the merge status table for the working set given. When no merge is in
+// progress, all columns but is_merging are NULL.
+func newMergeStatusRow(ctx *sql.Context, ws *doltdb.WorkingSet) (sql.Row, error) {
+	if ws == nil || !ws.MergeActive() {
+		return sql.NewRow(false, nil, nil, nil, nil), nil
+	}
+
+	ms := ws.MergeState()
+	h, err := ms.Commit().HashOf()
+	if err != nil {
+		return nil, err
+	}
+	sourceCommit := h.String()
+
+	// merges started before the commit spec was recorded only know the commit being merged
+	source := ms.CommitSpecStr()
+	if source == "" {
+		source = sourceCommit
+	}
+
+	target, err := ws.Ref().ToHeadRef()
+	if err != nil {
+		return nil, err
+	}
+
+	unmerged, err := unmergedTables(ctx, ws.WorkingRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	var unmergedTablesVal interface{}
+	if len(unmerged) > 0 {
+		unmergedTablesVal = strings.Join(unmerged, ", ")
+	}
+
+	return sql.NewRow(true, source, sourceCommit, target.String(), unmergedTablesVal), nil
+}
+
+// unmergedTables returns the sorted names of the tables of |root| with conflicts or constraint violations.
+func unmergedTables(ctx *sql.Context, root *doltdb.RootValue) ([]string, error) {
+	inConflict, err := root.TablesInConflict(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	withViolations, err := root.TablesWithConstraintViolations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	for _, name := range append(inConflict, withViolations...) {
+		names[name] = struct{}{}
+	}
+
+	unmerged := make([]string, 0, len(names))
+	for name := range names {
+		unmerged = append(unmerged, name)
+	}
+	sort.Strings(unmerged)
+
+	return unmerged, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dtables
+
+import (
+	"io"
+
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/env/actions/stash"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/index"
+)
+
+var _ sql.Table = (*StashesTable)(nil)
+
+// StashesTable is a sql.Table implementation that implements a system table which shows the stash list
+type StashesTable struct {
+	ddb *doltdb.DoltDB
+}
+
+// NewStashesTable creates a StashesTable
+func NewStashesTable(_ *sql.Context, ddb *doltdb.DoltDB) sql.Table {
+	return &StashesTable{ddb: ddb}
+}
+
+// Name is a sql.Table interface function which returns the name of the table which is defined by the constant
+// StashesTableName
+func (st *StashesTable) Name() string {
+	return doltdb.StashesTableName
+}
+
+// String is a sql.Table interface function which returns the name of the table which is defined by the constant
+// StashesTableName
+func (st *StashesTable) String() string {
+	return doltdb.StashesTableName
+}
+
+// Schema is a sql.Table interface function that gets the sql.Schema of the stashes system table.
+func (st *StashesTable) Schema() sql.Schema {
+	return []*sql.Column{
+		{Name: "stash_id", Type: sql.Text, Source: doltdb.StashesTableName, PrimaryKey: true},
+		{Name: "branch", Type: sql.Text, Source: doltdb.StashesTableName, PrimaryKey: false},
+		{Name: "commit_hash", Type: sql.Text, Source: doltdb.StashesTableName, PrimaryKey: false},
+		{Name: "message", Type: sql.Text, Source: doltdb.StashesTableName, PrimaryKey: false},
+	}
+}
+
+// Collation implements the sql.Table interface.
+func (st *StashesTable) Collation() sql.CollationID {
+	return sql.Collation_Default
+}
+
+// Partitions is a sql.Table interface function that returns a partition of the data. Currently, the data is unpartitioned.
+func (st *StashesTable) Partitions(*sql.Context) (sql.PartitionIter, error) {
+	return index.SinglePartitionIterFromNomsMap(nil), nil
+}
+
+// PartitionRows is a sql.Table interface function that gets a row iterator for a partition
+func (st *StashesTable) PartitionRows(ctx *sql.Context, _ sql.Partition) (sql.RowIter, error) {
+	return NewStashesItr(ctx, st.ddb)
+}
+
+// StashesItr is a sql.RowItr implementation which iterates over each stash entry as if it's a row in the table.
+type StashesItr struct {
+	stashes []*stash.Stash
+	idx     int
+}
+
+// NewStashesItr creates a StashesItr from the current environment.
+func NewStashesItr(ctx *sql.Context, ddb *doltdb.DoltDB) (*StashesItr, error) {
+	stashes, err := stash.List(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StashesItr{stashes, 0}, nil
+}
+
+// Next retrieves the next row. It will return io.EOF if it's the last row.
+// After retrieving the last row, Close will be automatically closed.
+func (itr *StashesItr) Next(ctx *sql.Context) (sql.Row, error) {
+	if itr.idx >= len(itr.stashes) {
+		return nil, io.EOF
+	}
+
+	defer func() {
+		itr.idx++
+	}()
+
+	s := itr.stashes[itr.idx]
+	return sql.NewRow(s.Name(), s.BranchName, s.HeadHash.String(), s.Description), nil
+}
+
+// Close closes the iterator.
+func (itr *StashesItr) Close(*sql.Context) error {
+	return nil
+}
@@ -950,6 +950,42 @@ func TestDoltReset(t *testing.T) {
 	}
 }
 
+func TestDoltRebase(t *testing.T) {
+	for _, script := range DoltRebaseScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
+func TestDoltStash(t *testing.T) {
+	for _, script := range DoltStashScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
+func TestDoltIgnore(t *testing.T) {
+	for _, script := range DoltIgnoreScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
+func TestDoltReflog(t *testing.T) {
+	for _, script := range DoltReflogScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
+func TestDoltMergeStatus(t *testing.T) {
+	for _, script := range DoltMergeStatusScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
+func TestDoltGC(t *testing.T) {
+	for _, script := range DoltGCScripts {
+		enginetest.TestScript(t, newDoltHarness(t), script)
+	}
+}
+
 func TestDoltBranch(t *testing.T) {
 	for _, script := range DoltBranchScripts {
 		enginetest.TestScript(t, newDoltHarness(t), script)
@@ -1122,6 +1158,22 @@ func TestUnscopedDiffSystemTablePrepared(t *testing.T) {
 	}
 }
 
+func TestColumnDiffSystemTable(t *testing.T) {
+	for _, test := range ColumnDiffSystemTableScriptTests {
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScript(t, newDoltHarness(t), test)
+		})
+	}
+}
+
+func TestColumnDiffSystemTablePrepared(t *testing.T) {
+	for _, test := range ColumnDiffSystemTableScriptTests {
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScriptPrepared(t, newDoltHarness(t), test)
+		})
+	}
+}
+
 func TestDiffTableFunction(t *testing.T) {
 	harness := newDoltHarness(t)
 	harness.Setup(setup.MydbData)
@@ -1145,6 +1197,50 @@ func TestDiffTableFunctionPrepared(t *testing.T) {
 	}
 }
 
+func TestLogTableFunction(t *testing.T) {
+	harness := newDoltHarness(t)
+	harness.Setup(setup.MydbData)
+	for _, test := range LogTableFunctionScriptTests {
+		harness.engine = nil
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScript(t, harness, test)
+		})
+	}
+}
+
+func TestDiffSummaryTableFunction(t *testing.T) {
+	harness := newDoltHarness(t)
+	harness.Setup(setup.MydbData)
+	for _, test := range DiffSummaryTableFunctionScriptTests {
+		harness.engine = nil
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScript(t, harness, test)
+		})
+	}
+}
+
+func TestPatchTableFunction(t *testing.T) {
+	harness := newDoltHarness(t)
+	harness.Setup(setup.MydbData)
+	for _, test := range PatchTableFunctionScriptTests {
+		harness.engine = nil
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScript(t, harness, test)
+		})
+	}
+}
+
+func TestSchemaDiffTableFunction(t *testing.T) {
+	harness := newDoltHarness(t)
+	harness.Setup(setup.MydbData)
+	for _, test := range SchemaDiffTableFunctionScriptTests {
+		harness.engine = nil
+		t.Run(test.Name, func(t *testing.T) {
+			enginetest.TestScript(t, harness, test)
+		})
+	}
+}
+
 func TestCommitDiffSystemTable(t *testing.T) {
 	harness := newDoltHarness(t)
 	harness.Setup(setup.MydbData)
@@ -0,0 +1,142 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlutil"
+)
+
+// DoltIgnoreGetOrCreateTable returns the `dolt_ignore` table from the given db, creating it in the db's current root
+// if it doesn't exist
+func DoltIgnoreGetOrCreateTable(ctx *sql.Context, db Database) (*WritableDoltTable, error) {
+	root, err := db.GetRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := root.HasTable(ctx, doltdb.IgnoreTableName)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		err = db.createDoltTable(ctx, doltdb.IgnoreTableName, root, doltdb.IgnoreSchema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tbl, found, err := db.GetTableInsensitive(ctx, doltdb.IgnoreTableName)
+	if err != nil {
+		return nil, err
+	}
+	// Verify it was created successfully
+	wt, ok := tbl.(*WritableDoltTable)
+	if !found || !ok {
+		return nil, sql.ErrTableNotFound.New(doltdb.IgnoreTableName)
+	}
+	return wt, nil
+}
+
+// emptyIgnoreTable stands in for the `dolt_ignore` table until it exists. It has no rows, and creates the table the
+// first time a row is inserted into it.
+type emptyIgnoreTable struct {
+	db Database
+}
+
+var _ sql.Table = emptyIgnoreTable{}
+var _ sql.InsertableTable = emptyIgnoreTable{}
+
+func newEmptyIgnoreTable(db Database) sql.Table {
+	return emptyIgnoreTable{db: db}
+}
+
+func (t emptyIgnoreTable) Name() string {
+	return doltdb.IgnoreTableName
+}
+
+func (t emptyIgnoreTable) String() string {
+	return doltdb.IgnoreTableName
+}
+
+func (t emptyIgnoreTable) Schema() sql.Schema {
+	sqlSch, err := sqlutil.FromDoltSchema(doltdb.IgnoreTableName, doltdb.IgnoreSchema)
+	if err != nil {
+		panic(err) // should never happen
+	}
+	return sqlSch.Schema
+}
+
+func (t emptyIgnoreTable) Collation() sql.CollationID {
+	return sql.CollationID(doltdb.IgnoreSchema.GetCollation())
+}
+
+func (t emptyIgnoreTable) Partitions(*sql.Context) (sql.PartitionIter, error) {
+	return sql.PartitionsToPartitionIter(), nil
+}
+
+func (t emptyIgnoreTable) PartitionRows(*sql.Context, sql.Partition) (sql.RowIter, error) {
+	return sql.RowsToRowIter(), nil
+}
+
+func (t emptyIgnoreTable) Inserter(*sql.Context) sql.RowInserter {
+	return &ignoreTableInserter{db: t.db}
+}
+
+// ignoreTableInserter creates the `dolt_ignore` table when the first row is inserted, and then hands all edits to the
+// inserter of the new table.
+type ignoreTableInserter struct {
+	db       Database
+	inserter sql.RowInserter
+}
+
+var _ sql.RowInserter = (*ignoreTableInserter)(nil)
+
+func (i *ignoreTableInserter) StatementBegin(*sql.Context) {}
+
+func (i *ignoreTableInserter) DiscardChanges(ctx *sql.Context, errorEncountered error) error {
+	if i.inserter == nil {
+		return nil
+	}
+	return i.inserter.DiscardChanges(ctx, errorEncountered)
+}
+
+func (i *ignoreTableInserter) StatementComplete(ctx *sql.Context) error {
+	if i.inserter == nil {
+		return nil
+	}
+	return i.inserter.StatementComplete(ctx)
+}
+
+func (i *ignoreTableInserter) Insert(ctx *sql.Context, row sql.Row) error {
+	if i.inserter == nil {
+		tbl, err := DoltIgnoreGetOrCreateTable(ctx, i.db)
+		if err != nil {
+			return err
+		}
+		i.inserter = tbl.Inserter(ctx)
+		i.inserter.StatementBegin(ctx)
+	}
+	return i.inserter.Insert(ctx, row)
+}
+
+func (i *ignoreTableInserter) Close(ctx *sql.Context) error {
+	if i.inserter == nil {
+		return nil
+	}
+	return i.inserter.Close(ctx)
+}
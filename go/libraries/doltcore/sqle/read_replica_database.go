@@ -199,6 +199,24 @@ func pullBranches(ctx *sql.Context, rrd ReadReplicaDatabase, branches []string,
 
 		srcDBCommit := srcDBCommitA.(*doltdb.Commit)
 
+		// replicate the working set of the branch if there is one, otherwise reset the current one to the head
+		if ReplicateWorkingSets() {
+			wsRef, err := ref.WorkingSetRefForHead(branch)
+			if err != nil {
+				return err
+			}
+
+			pulledA, err := rrd.limiter.Run(ctx, wsRef.String(), func() (any, error) {
+				return pullWorkingSet(fetchCtx, rrd, wsRef)
+			})
+			if err != nil {
+				return err
+			}
+			if pulledA.(bool) {
+				continue
+			}
+		}
+
 		// if ref is active head, update the current working set
 		{
 			if branch == currentBranchRef {
@@ -240,6 +258,34 @@ func pullBranches(ctx *sql.Context, rrd ReadReplicaDatabase, branches []string,
 	return nil
 }
 
+// pullWorkingSet replaces the working set |wsRef| with the one of the remote, returning false if the remote doesn't
+// have it.
+func pullWorkingSet(ctx context.Context, rrd ReadReplicaDatabase, wsRef ref.WorkingSetRef) (bool, error) {
+	srcWs, err := rrd.srcDB.ResolveWorkingSet(ctx, wsRef)
+	if errors.Is(err, doltdb.ErrWorkingSetNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	srcWsHash, err := srcWs.HashOf()
+	if err != nil {
+		return false, err
+	}
+
+	err = rrd.ddb.PullChunks(ctx, rrd.tmpDir, rrd.srcDB, srcWsHash, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	err = rrd.ddb.SetWorkingSetHead(ctx, wsRef, srcWsHash)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func getReplicationBranches(ctx *sql.Context, rrd ReadReplicaDatabase) (allBranches []string, deletedBranches []ref.DoltRef, err error) {
 	remRefs, err := rrd.srcDB.GetBranches(ctx)
 	if err != nil {
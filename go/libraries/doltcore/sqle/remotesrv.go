@@ -0,0 +1,232 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// remotesrvDBCache is a remotesrv.DBCache which serves the chunk stores of the databases of a sql engine. Remotes are
+// named by database name alone, and databases are not created when a remote that doesn't exist is requested.
+type remotesrvDBCache struct {
+	ctxFactory func(context.Context) (*sql.Context, error)
+}
+
+// NewRemoteSrvDBCache returns a remotesrv.DBCache of the databases which are available to the sessions of contexts
+// made by |ctxFactory|.
+func NewRemoteSrvDBCache(ctxFactory func(context.Context) (*sql.Context, error)) remotesrv.DBCache {
+	return remotesrvDBCache{ctxFactory}
+}
+
+func (c remotesrvDBCache) Get(ctx context.Context, org, repo, nbfVerStr string) (remotesrv.RemoteSrvStore, error) {
+	if org != "" {
+		return nil, status.Errorf(codes.NotFound, "database not found: %s/%s", org, repo)
+	}
+
+	sqlCtx, err := c.ctxFactory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dsess.DSessFromSess(sqlCtx.Session).Provider().Database(sqlCtx, repo)
+	if sql.ErrDatabaseNotFound.Is(err) {
+		return nil, status.Errorf(codes.NotFound, "database not found: %s", repo)
+	} else if err != nil {
+		return nil, err
+	}
+
+	sqlDb, ok := db.(SqlDatabase)
+	if !ok || sqlDb.DbData().Ddb == nil {
+		return nil, status.Errorf(codes.NotFound, "database not found: %s", repo)
+	}
+
+	ddb := sqlDb.DbData().Ddb
+	cs := datas.ChunkStoreFromDatabase(doltdb.HackDatasDatabaseFromDoltDB(ddb))
+	rss, ok := cs.(remotesrv.RemoteSrvStore)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "database %s can not be served as a remote", repo)
+	}
+
+	return remotesrvStore{rss, ddb}, nil
+}
+
+// remotesrvStore is the chunk store of a database served as a remote. SQL sessions read and write the working sets of
+// branches rather than their heads, so when a push moves the head of a branch whose working set is clean, the working
+// set is moved along with it. Working sets with changes are left alone. Pushes are subject to the replication hooks of
+// the database like any other update.
+type remotesrvStore struct {
+	remotesrv.RemoteSrvStore
+	ddb *doltdb.DoltDB
+}
+
+func (s remotesrvStore) Commit(ctx context.Context, current, last hash.Hash) (bool, error) {
+	err := s.ddb.CheckWrite(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	before, err := s.ddb.GetBranchesWithHashes(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := s.RemoteSrvStore.Commit(ctx, current, last)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	after, err := s.ddb.GetBranchesWithHashes(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// the push is durable at this point, so failing to write the reflog doesn't fail it
+	_ = s.ddb.RecordRefUpdates(ctx, branchRefLogEntries(before, after, time.Now())...)
+
+	prevHeads := make(map[string]hash.Hash, len(before))
+	for _, b := range before {
+		prevHeads[b.Ref.String()] = b.Hash
+	}
+
+	for _, b := range after {
+		prev, ok := prevHeads[b.Ref.String()]
+		if !ok || prev == b.Hash {
+			continue
+		}
+
+		err = s.updateCleanWorkingSet(ctx, b.Ref, prev, b.Hash)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = s.ddb.Replicate(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// branchRefLogEntries returns the reflog entries of the branches that were created, moved or deleted between |before|
+// and |after|. Pushes update the chunk store directly, so they aren't recorded in the reflog otherwise.
+func branchRefLogEntries(before, after []doltdb.BranchWithHash, now time.Time) []doltdb.RefLogEntry {
+	prevHeads := make(map[string]hash.Hash, len(before))
+	for _, b := range before {
+		prevHeads[b.Ref.String()] = b.Hash
+	}
+
+	var entries []doltdb.RefLogEntry
+	for _, b := range after {
+		prev, ok := prevHeads[b.Ref.String()]
+		delete(prevHeads, b.Ref.String())
+		if ok && prev == b.Hash {
+			continue
+		}
+		entries = append(entries, doltdb.RefLogEntry{Ref: b.Ref.String(), Hash: b.Hash, Timestamp: now})
+	}
+
+	for _, b := range before {
+		if _, ok := prevHeads[b.Ref.String()]; ok {
+			entries = append(entries, doltdb.RefLogEntry{Ref: b.Ref.String(), Timestamp: now})
+		}
+	}
+
+	return entries
+}
+
+// updateCleanWorkingSet moves the working set of |branch| from the root of the commit |prev| to the root of the
+// commit |head| if it has no changes.
+func (s remotesrvStore) updateCleanWorkingSet(ctx context.Context, branch ref.DoltRef, prev, head hash.Hash) error {
+	wsRef, err := ref.WorkingSetRefForHead(branch)
+	if err != nil {
+		return err
+	}
+
+	ws, err := s.ddb.ResolveWorkingSet(ctx, wsRef)
+	if err == doltdb.ErrWorkingSetNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	prevCommit, err := s.ddb.ReadCommit(ctx, prev)
+	if err != nil {
+		return err
+	}
+	prevRoot, err := prevCommit.GetRootValue(ctx)
+	if err != nil {
+		return err
+	}
+	prevRootHash, err := prevRoot.HashOf()
+	if err != nil {
+		return err
+	}
+
+	workingHash, err := ws.WorkingRoot().HashOf()
+	if err != nil {
+		return err
+	}
+	stagedHash, err := ws.StagedRoot().HashOf()
+	if err != nil {
+		return err
+	}
+
+	if ws.MergeActive() || workingHash != prevRootHash || stagedHash != prevRootHash {
+		return nil
+	}
+
+	headCommit, err := s.ddb.ReadCommit(ctx, head)
+	if err != nil {
+		return err
+	}
+	headRoot, err := headCommit.GetRootValue(ctx)
+	if err != nil {
+		return err
+	}
+
+	wsHash, err := ws.HashOf()
+	if err != nil {
+		return err
+	}
+
+	meta := &datas.WorkingSetMeta{
+		Name:        "remotesapi",
+		Email:       "remotesapi",
+		Timestamp:   uint64(time.Now().Unix()),
+		Description: "update working set to pushed head",
+	}
+
+	err = s.ddb.UpdateWorkingSet(ctx, wsRef, ws.WithWorkingRoot(headRoot).WithStagedRoot(headRoot), wsHash, meta)
+	if errors.Is(err, datas.ErrOptimisticLockFailed) {
+		// the working set was changed concurrently, so it may no longer be clean
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+	"github.com/dolthub/dolt/go/libraries/doltcore/ref"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+func TestBranchRefLogEntries(t *testing.T) {
+	now := time.Now()
+	main, feature, old := ref.NewBranchRef("main"), ref.NewBranchRef("feature"), ref.NewBranchRef("old")
+	h1, h2 := hash.Of([]byte("one")), hash.Of([]byte("two"))
+
+	before := []doltdb.BranchWithHash{{Ref: main, Hash: h1}, {Ref: old, Hash: h1}, {Ref: ref.NewBranchRef("same"), Hash: h2}}
+	after := []doltdb.BranchWithHash{{Ref: main, Hash: h2}, {Ref: feature, Hash: h1}, {Ref: ref.NewBranchRef("same"), Hash: h2}}
+
+	entries := branchRefLogEntries(before, after, now)
+	assert.Equal(t, []doltdb.RefLogEntry{
+		{Ref: "refs/heads/main", Hash: h2, Timestamp: now},
+		{Ref: "refs/heads/feature", Hash: h1, Timestamp: now},
+		{Ref: "refs/heads/old", Timestamp: now},
+	}, entries)
+	assert.True(t, entries[2].IsDelete())
+
+	assert.Empty(t, branchRefLogEntries(before, before, now))
+}
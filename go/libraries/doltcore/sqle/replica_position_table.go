@@ -0,0 +1,51 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"github.com/dolthub/go-mysql-server/sql"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/doltdb"
+)
+
+// ReplicaPositionGetOrCreateTable returns the `dolt_replica_position` table from the given db, creating it in the db's
+// current root if it doesn't exist
+func ReplicaPositionGetOrCreateTable(ctx *sql.Context, db Database) (*WritableDoltTable, error) {
+	root, err := db.GetRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := root.HasTable(ctx, doltdb.ReplicaPositionTableName)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		err = db.createDoltTable(ctx, doltdb.ReplicaPositionTableName, root, doltdb.ReplicaPositionSchema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tbl, found, err := db.GetTableInsensitive(ctx, doltdb.ReplicaPositionTableName)
+	if err != nil {
+		return nil, err
+	}
+	wt, ok := tbl.(*WritableDoltTable)
+	if !found || !ok {
+		return nil, sql.ErrTableNotFound.New(doltdb.ReplicaPositionTableName)
+	}
+	return wt, nil
+}
@@ -59,10 +59,17 @@ func getPushOnWriteHook(ctx context.Context, bThreads *sql.BackgroundThreads, dE
 
 	_, val, ok = sql.SystemVariables.GetGlobal(dsess.AsyncReplication)
 	if _, val, ok = sql.SystemVariables.GetGlobal(dsess.AsyncReplication); ok && val == SysVarTrue {
-		return doltdb.NewAsyncPushOnWriteHook(bThreads, ddb, dEnv.TempTableFilesDir(), logger)
+		hook, err := doltdb.NewAsyncPushOnWriteHook(bThreads, ddb, dEnv.TempTableFilesDir(), logger)
+		if err != nil {
+			return nil, err
+		}
+		hook.SetReplicateWorkingSets(ReplicateWorkingSets())
+		return hook, nil
 	}
 
-	return doltdb.NewPushOnWriteHook(ddb, dEnv.TempTableFilesDir()), nil
+	hook := doltdb.NewPushOnWriteHook(ddb, dEnv.TempTableFilesDir())
+	hook.SetReplicateWorkingSets(ReplicateWorkingSets())
+	return hook, nil
 }
 
 // GetCommitHooks creates a list of hooks to execute on database commit. If doltdb.SkipReplicationErrorsKey is set,
@@ -0,0 +1,116 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
+	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
+)
+
+// SqlSchemaDiff returns a slice of DDL statements that will transform the schema in the from delta to the schema in
+// the to delta.
+// TODO: this doesn't handle constraints or triggers
+func SqlSchemaDiff(ctx context.Context, td diff.TableDelta, toSchemas map[string]schema.Schema) ([]string, error) {
+	fromSch, toSch, err := td.GetSchemas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve schema for table %s: %w", td.ToName, err)
+	}
+
+	var ddlStatements []string
+
+	if td.IsDrop() {
+		ddlStatements = append(ddlStatements, sqlfmt.DropTableStmt(td.FromName))
+	} else if td.IsAdd() {
+		sqlDb := NewSingleTableDatabase(td.ToName, toSch, td.ToFks, td.ToFksParentSch)
+		sqlCtx, engine, _ := PrepareCreateTableStmt(ctx, sqlDb)
+		stmt, err := GetCreateTableStmt(sqlCtx, engine, td.ToName)
+		if err != nil {
+			return nil, err
+		}
+		ddlStatements = append(ddlStatements, stmt)
+	} else {
+		if td.FromName != td.ToName {
+			ddlStatements = append(ddlStatements, sqlfmt.RenameTableStmt(td.FromName, td.ToName))
+		}
+
+		eq := schema.SchemasAreEqual(fromSch, toSch)
+		if eq && !td.HasFKChanges() {
+			return ddlStatements, nil
+		}
+
+		colDiffs, unionTags := diff.DiffSchColumns(fromSch, toSch)
+		for _, tag := range unionTags {
+			cd := colDiffs[tag]
+			switch cd.DiffType {
+			case diff.SchDiffNone:
+			case diff.SchDiffAdded:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddColStmt(td.ToName, sqlfmt.FmtCol(0, 0, 0, *cd.New)))
+			case diff.SchDiffRemoved:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropColStmt(td.ToName, cd.Old.Name))
+			case diff.SchDiffModified:
+				// Ignore any primary key set changes here
+				if cd.Old.IsPartOfPK != cd.New.IsPartOfPK {
+					continue
+				}
+				if cd.Old.Name != cd.New.Name {
+					ddlStatements = append(ddlStatements, sqlfmt.AlterTableRenameColStmt(td.ToName, cd.Old.Name, cd.New.Name))
+				}
+			}
+		}
+
+		// Print changes between a primary key set change. It contains an ALTER TABLE DROP and an ALTER TABLE ADD
+		if !schema.ColCollsAreEqual(fromSch.GetPKCols(), toSch.GetPKCols()) {
+			ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropPks(td.ToName))
+			if toSch.GetPKCols().Size() > 0 {
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddPrimaryKeys(td.ToName, toSch.GetPKCols()))
+			}
+		}
+
+		for _, idxDiff := range diff.DiffSchIndexes(fromSch, toSch) {
+			switch idxDiff.DiffType {
+			case diff.SchDiffNone:
+			case diff.SchDiffAdded:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddIndexStmt(td.ToName, idxDiff.To))
+			case diff.SchDiffRemoved:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropIndexStmt(td.FromName, idxDiff.From))
+			case diff.SchDiffModified:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropIndexStmt(td.FromName, idxDiff.From))
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddIndexStmt(td.ToName, idxDiff.To))
+			}
+		}
+
+		for _, fkDiff := range diff.DiffForeignKeys(td.FromFks, td.ToFks) {
+			switch fkDiff.DiffType {
+			case diff.SchDiffNone:
+			case diff.SchDiffAdded:
+				parentSch := toSchemas[fkDiff.To.ReferencedTableName]
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddForeignKeyStmt(fkDiff.To, toSch, parentSch))
+			case diff.SchDiffRemoved:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropForeignKeyStmt(fkDiff.From))
+			case diff.SchDiffModified:
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableDropForeignKeyStmt(fkDiff.From))
+
+				parentSch := toSchemas[fkDiff.To.ReferencedTableName]
+				ddlStatements = append(ddlStatements, sqlfmt.AlterTableAddForeignKeyStmt(fkDiff.To, toSch, parentSch))
+			}
+		}
+	}
+
+	return ddlStatements, nil
+}
@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package sqlexport
+package sqlfmt
 
 import (
 	"context"
@@ -23,20 +23,16 @@ import (
 
 	"github.com/dolthub/dolt/go/libraries/doltcore/diff"
 	"github.com/dolthub/dolt/go/libraries/doltcore/schema"
-	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/sqlfmt"
-	"github.com/dolthub/dolt/go/libraries/doltcore/table/editor"
 	"github.com/dolthub/dolt/go/libraries/utils/iohelp"
 	"github.com/dolthub/dolt/go/libraries/utils/set"
 )
 
+// SqlDiffWriter writes the rows of a table diff as the INSERT, UPDATE and DELETE statements that apply it.
 type SqlDiffWriter struct {
-	tableName            string
-	sch                  schema.Schema
-	writtenFirstRow      bool
-	writtenAutocommitOff bool
-	writeCloser          io.WriteCloser
-	editOpts             editor.Options
-	autocommitOff        bool
+	tableName       string
+	sch             schema.Schema
+	writtenFirstRow bool
+	writeCloser     io.WriteCloser
 }
 
 func NewSqlDiffWriter(tableName string, schema schema.Schema, wr io.WriteCloser) *SqlDiffWriter {
@@ -60,14 +56,14 @@ func (w SqlDiffWriter) WriteRow(
 
 	switch rowDiffType {
 	case diff.Added:
-		stmt, err := sqlfmt.SqlRowAsInsertStmt(row, w.tableName, w.sch)
+		stmt, err := SqlRowAsInsertStmt(row, w.tableName, w.sch)
 		if err != nil {
 			return err
 		}
 
 		return iohelp.WriteLine(w.writeCloser, stmt)
 	case diff.Removed:
-		stmt, err := sqlfmt.SqlRowAsDeleteStmt(row, w.tableName, w.sch, 0)
+		stmt, err := SqlRowAsDeleteStmt(row, w.tableName, w.sch, 0)
 		if err != nil {
 			return err
 		}
@@ -81,7 +77,7 @@ func (w SqlDiffWriter) WriteRow(
 			}
 		}
 
-		stmt, err := sqlfmt.SqlRowAsUpdateStmt(row, w.tableName, w.sch, updatedCols)
+		stmt, err := SqlRowAsUpdateStmt(row, w.tableName, w.sch, updatedCols)
 		if err != nil {
 			return err
 		}
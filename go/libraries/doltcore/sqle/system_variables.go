@@ -79,6 +79,55 @@ func AddDoltSystemVariables() {
 			Type:              sql.NewSystemBoolType(dsess.AsyncReplication),
 			Default:           int8(0),
 		},
+		{ // If true, working sets are replicated along with branch heads, so that replicas see uncommitted changes.
+			Name:              dsess.ReplicateWorkingSets,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           true,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemBoolType(dsess.ReplicateWorkingSets),
+			Default:           int8(0),
+		},
+		{ // The id a replica identifies itself to its binlog source with, and a primary writes its binlog events with.
+			// It must differ between a replica and its source.
+			Name:              dsess.ServerID,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           true,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemIntType(dsess.ServerID, 0, 4294967295, false),
+			Default:           int64(1),
+		},
+		{ // The uuid of the GTIDs of the transactions in the binlog of the server, set when the binlog is enabled.
+			Name:              dsess.ServerUUID,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemStringType(dsess.ServerUUID),
+			Default:           "",
+		},
+		{ // Whether the server writes a binlog for MySQL replicas.
+			Name:              dsess.LogBin,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemBoolType(dsess.LogBin),
+			Default:           int8(0),
+		},
+		{ // The binlog of the server is always row-based.
+			Name:              dsess.BinlogFormat,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemEnumType(dsess.BinlogFormat, "ROW"),
+			Default:           "ROW",
+		},
+		{ // The events of the binlog of the server always have CRC32 checksums.
+			Name:              dsess.BinlogChecksum,
+			Scope:             sql.SystemVariableScope_Global,
+			Dynamic:           false,
+			SetVarHintApplies: false,
+			Type:              sql.NewSystemEnumType(dsess.BinlogChecksum, "CRC32"),
+			Default:           "CRC32",
+		},
 		{ // If true, causes a Dolt commit to occur when you commit a transaction.
 			Name:              dsess.DoltCommitOnTransactionCommit,
 			Scope:             sql.SystemVariableScope_Both,
@@ -153,3 +202,13 @@ func SkipReplicationWarnings() bool {
 	}
 	return skip == SysVarTrue
 }
+
+// ReplicateWorkingSets returns whether working sets are pushed to and pulled from replication remotes along with
+// branch heads.
+func ReplicateWorkingSets() bool {
+	_, replicate, ok := sql.SystemVariables.GetGlobal(dsess.ReplicateWorkingSets)
+	if !ok {
+		panic("dolt system variables not loaded")
+	}
+	return replicate == SysVarTrue
+}
@@ -194,7 +194,7 @@ func TestParsing(t *testing.T) {
 				parser.SupportOption(opt)
 			}
 
-			exp := &ArgParseResults{test.expectedOpts, test.expectedArgs, parser}
+			exp := &ArgParseResults{test.expectedOpts, test.expectedArgs, parser, nil}
 
 			res, err := parser.Parse(test.args)
 			if test.expectedErr != "" {
@@ -273,3 +273,26 @@ func TestValidation(t *testing.T) {
 		t.Error("Arg list issues")
 	}
 }
+
+func TestStringList(t *testing.T) {
+	ap := NewArgParser()
+	ap.SupportsStringList("list", "l", "values", "A list")
+	ap.SupportsStringList("list2", "", "values", "Another list")
+	ap.SupportsFlag("flag", "f", "A flag")
+
+	apr, err := ap.Parse([]string{"--list", "a", "b", "-f", "c", "--list=d", "e"})
+	require.NoError(t, err)
+	vals, ok := apr.GetValueList("list")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b", "d", "e"}, vals)
+	assert.True(t, apr.ContainsAll("list", "flag"))
+	assert.Equal(t, []string{"c"}, apr.Args)
+	_, ok = apr.GetValueList("list2")
+	assert.False(t, ok)
+	assert.False(t, apr.Contains("list2"))
+
+	_, err = ap.Parse([]string{"a", "--list", "-f"})
+	assert.Error(t, err)
+	_, err = ap.Parse([]string{"a", "--list"})
+	assert.Error(t, err)
+}
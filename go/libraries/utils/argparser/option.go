@@ -25,6 +25,9 @@ const (
 	OptionalFlag OptionType = iota
 	OptionalValue
 	OptionalEmptyValue
+	// OptionalValueList is an option taking every argument after it up to the next option as its values. It can be
+	// given more than once, and the values of every occurrence are collected.
+	OptionalValueList
 )
 
 type ValidationFunc func(string) error
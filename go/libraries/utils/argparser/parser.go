@@ -111,6 +111,15 @@ func (ap *ArgParser) SupportsOptionalString(name, abbrev, valDesc, desc string)
 	return ap
 }
 
+// SupportsStringList adds support for a new string list argument with the description given. See OptionalValueList
+// for how its values are parsed, and ArgParseResults.GetValueList for how they're read.
+func (ap *ArgParser) SupportsStringList(name, abbrev, valDesc, desc string) *ArgParser {
+	opt := &Option{name, abbrev, valDesc, OptionalValueList, desc, nil}
+	ap.SupportOption(opt)
+
+	return ap
+}
+
 // SupportsValidatedString adds support for a new string argument with the description given and defined validation function.
 func (ap *ArgParser) SupportsValidatedString(name, abbrev, valDesc, desc string, validator ValidationFunc) *ArgParser {
 	opt := &Option{name, abbrev, valDesc, OptionalValue, desc, validator}
@@ -193,7 +202,7 @@ func (ap *ArgParser) matchModalOptions(arg string) (matches []*Option, rest stri
 func (ap *ArgParser) sortedValueOptions() []string {
 	vos := make([]string, 0, len(ap.Supported))
 	for s, opt := range ap.NameOrAbbrevToOpt {
-		if (opt.OptType == OptionalValue || opt.OptType == OptionalEmptyValue) && s != "" {
+		if (opt.OptType == OptionalValue || opt.OptType == OptionalEmptyValue || opt.OptType == OptionalValueList) && s != "" {
 			vos = append(vos, s)
 		}
 	}
@@ -224,6 +233,7 @@ func (ap *ArgParser) matchValueOption(arg string) (match *Option, value *string)
 func (ap *ArgParser) Parse(args []string) (*ArgParseResults, error) {
 	list := make([]string, 0, 16)
 	results := make(map[string]string)
+	var lists map[string][]string
 
 	i := 0
 	for ; i < len(args); i++ {
@@ -267,6 +277,27 @@ func (ap *ArgParser) Parse(args []string) (*ArgParseResults, error) {
 			return nil, UnknownArgumentParam{name: arg}
 		}
 
+		if opt.OptType == OptionalValueList {
+			var vals []string
+			if value != nil {
+				vals = append(vals, *value)
+			}
+			for i+1 < len(args) && (len(args[i+1]) == 0 || args[i+1][0] != '-') {
+				i++
+				vals = append(vals, args[i])
+			}
+			if len(vals) == 0 {
+				return nil, errors.New("error: no value for option `" + opt.Name + "'")
+			}
+
+			if lists == nil {
+				lists = make(map[string][]string)
+			}
+			results[opt.Name] = ""
+			lists[opt.Name] = append(lists[opt.Name], vals...)
+			continue
+		}
+
 		if _, exists := results[opt.Name]; exists {
 			//already provided
 			return nil, errors.New("error: multiple values provided for `" + opt.Name + "'")
@@ -301,5 +332,5 @@ func (ap *ArgParser) Parse(args []string) (*ArgParseResults, error) {
 		copy(list, args[i:])
 	}
 
-	return &ArgParseResults{results, list, ap}, nil
+	return &ArgParseResults{results, list, ap, lists}, nil
 }
@@ -25,6 +25,8 @@ type ArgParseResults struct {
 	options map[string]string
 	Args    []string
 	parser  *ArgParser
+	// lists are the values of the string list options given.
+	lists map[string][]string
 }
 
 func (res *ArgParseResults) Equals(other *ArgParseResults) bool {
@@ -44,6 +46,18 @@ func (res *ArgParseResults) Equals(other *ArgParseResults) bool {
 		}
 	}
 
+	for k, vals := range res.lists {
+		otherVals := other.lists[k]
+		if len(vals) != len(otherVals) {
+			return false
+		}
+		for i, v := range vals {
+			if otherVals[i] != v {
+				return false
+			}
+		}
+	}
+
 	return true
 }
 
@@ -96,6 +110,12 @@ func (res *ArgParseResults) GetValue(name string) (string, bool) {
 	return val, ok
 }
 
+// GetValueList returns the values of the string list option |name|, in the order they were given.
+func (res *ArgParseResults) GetValueList(name string) ([]string, bool) {
+	vals, ok := res.lists[name]
+	return vals, ok
+}
+
 func (res *ArgParseResults) GetValues(names ...string) map[string]string {
 	vals := make(map[string]string)
 
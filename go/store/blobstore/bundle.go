@@ -0,0 +1,252 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// A bundle is a single file holding a set of blobs. Its layout is
+//
+//	magic | blob data... | index | index offset | magic
+//
+// where the index lists the key, offset and length of every blob:
+//
+//	count uint32 | (key length uint16 | key | offset uint64 | length uint64)...
+//
+// All integers are big endian.
+const (
+	bundleMagic      = "DOLTBNDL"
+	bundleFooterSize = 8 + len(bundleMagic)
+)
+
+// ErrBundleReadOnly is returned when writing to a bundle opened as a Blobstore.
+var ErrBundleReadOnly = errors.New("bundles are read-only")
+
+// ErrInvalidBundle is returned when opening a file which is not a bundle.
+var ErrInvalidBundle = errors.New("not a valid bundle file")
+
+type bundleEntry struct {
+	offset uint64
+	length uint64
+}
+
+// BundleWriter writes blobs to a bundle.
+type BundleWriter struct {
+	w       io.Writer
+	pos     uint64
+	keys    []string
+	entries map[string]bundleEntry
+}
+
+// NewBundleWriter returns a BundleWriter writing a bundle to |w|. The bundle is complete once Close is called.
+func NewBundleWriter(w io.Writer) (*BundleWriter, error) {
+	n, err := io.WriteString(w, bundleMagic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundleWriter{w: w, pos: uint64(n), entries: make(map[string]bundleEntry)}, nil
+}
+
+// Put writes the blob read from |reader| to the bundle with the key given.
+func (bw *BundleWriter) Put(key string, reader io.Reader) error {
+	if _, ok := bw.entries[key]; ok {
+		return fmt.Errorf("duplicate bundle key: %s", key)
+	} else if len(key) > math.MaxUint16 {
+		return fmt.Errorf("bundle key too long: %s", key)
+	}
+
+	n, err := io.Copy(bw.w, reader)
+	if err != nil {
+		return err
+	}
+
+	bw.keys = append(bw.keys, key)
+	bw.entries[key] = bundleEntry{offset: bw.pos, length: uint64(n)}
+	bw.pos += uint64(n)
+	return nil
+}
+
+// Close writes the index of the bundle. It does not close the underlying writer.
+func (bw *BundleWriter) Close() error {
+	var buf []byte
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(bw.keys)))
+	for _, key := range bw.keys {
+		e := bw.entries[key]
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(key)))
+		buf = append(buf, key...)
+		buf = binary.BigEndian.AppendUint64(buf, e.offset)
+		buf = binary.BigEndian.AppendUint64(buf, e.length)
+	}
+	buf = binary.BigEndian.AppendUint64(buf, bw.pos)
+	buf = append(buf, bundleMagic...)
+
+	_, err := bw.w.Write(buf)
+	return err
+}
+
+// BundleBlobstore is a read-only Blobstore over the blobs of a bundle file.
+type BundleBlobstore struct {
+	path    string
+	entries map[string]bundleEntry
+}
+
+var _ Blobstore = (*BundleBlobstore)(nil)
+
+// OpenBundle reads the index of the bundle at |path| and returns a Blobstore for its blobs.
+func OpenBundle(path string) (*BundleBlobstore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	if size < int64(len(bundleMagic)+bundleFooterSize) {
+		return nil, ErrInvalidBundle
+	}
+
+	footer := make([]byte, bundleFooterSize)
+	_, err = f.ReadAt(footer, size-int64(bundleFooterSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if string(footer[8:]) != bundleMagic {
+		return nil, ErrInvalidBundle
+	}
+
+	indexOffset := binary.BigEndian.Uint64(footer[:8])
+	indexEnd := uint64(size) - uint64(bundleFooterSize)
+	if indexOffset < uint64(len(bundleMagic)) || indexOffset > indexEnd {
+		return nil, ErrInvalidBundle
+	}
+
+	index := make([]byte, indexEnd-indexOffset)
+	_, err = f.ReadAt(index, int64(indexOffset))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := parseBundleIndex(index, indexOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundleBlobstore{path: path, entries: entries}, nil
+}
+
+func parseBundleIndex(index []byte, dataEnd uint64) (map[string]bundleEntry, error) {
+	if len(index) < 4 {
+		return nil, ErrInvalidBundle
+	}
+
+	count := binary.BigEndian.Uint32(index)
+	index = index[4:]
+
+	entries := make(map[string]bundleEntry, count)
+	for i := uint32(0); i < count; i++ {
+		if len(index) < 2 {
+			return nil, ErrInvalidBundle
+		}
+		keyLen := int(binary.BigEndian.Uint16(index))
+		index = index[2:]
+
+		if len(index) < keyLen+16 {
+			return nil, ErrInvalidBundle
+		}
+		key := string(index[:keyLen])
+		e := bundleEntry{
+			offset: binary.BigEndian.Uint64(index[keyLen:]),
+			length: binary.BigEndian.Uint64(index[keyLen+8:]),
+		}
+		index = index[keyLen+16:]
+
+		if e.offset+e.length > dataEnd {
+			return nil, ErrInvalidBundle
+		}
+		entries[key] = e
+	}
+
+	return entries, nil
+}
+
+// Keys returns the keys of the blobs in the bundle.
+func (bs *BundleBlobstore) Keys() []string {
+	keys := make([]string, 0, len(bs.entries))
+	for k := range bs.entries {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Exists returns true if a blob exists for the key given.
+func (bs *BundleBlobstore) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := bs.entries[key]
+	return ok, nil
+}
+
+// Get retrieves an io.reader for the portion of a blob specified by br. The blobs of a bundle never change, they all
+// have the empty version.
+func (bs *BundleBlobstore) Get(ctx context.Context, key string, br BlobRange) (io.ReadCloser, string, error) {
+	e, ok := bs.entries[key]
+	if !ok {
+		return nil, "", NotFound{key}
+	}
+
+	br = br.positiveRange(int64(e.length))
+	if br.offset < 0 || br.offset > int64(e.length) {
+		return nil, "", fmt.Errorf("invalid range for blob %s", key)
+	}
+
+	f, err := os.Open(bs.path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sr := io.NewSectionReader(f, int64(e.offset)+br.offset, br.length)
+	return bundleReadCloser{sr, f}, "", nil
+}
+
+// Put is not supported, bundles are read-only.
+func (bs *BundleBlobstore) Put(ctx context.Context, key string, reader io.Reader) (string, error) {
+	return "", ErrBundleReadOnly
+}
+
+// CheckAndPut is not supported, bundles are read-only.
+func (bs *BundleBlobstore) CheckAndPut(ctx context.Context, expectedVersion, key string, reader io.Reader) (string, error) {
+	return "", ErrBundleReadOnly
+}
+
+type bundleReadCloser struct {
+	*io.SectionReader
+	f *os.File
+}
+
+func (rc bundleReadCloser) Close() error {
+	return rc.f.Close()
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.bundle")
+
+	blobs := map[string][]byte{
+		"a":     []byte("first blob"),
+		"b":     []byte("the second blob"),
+		"empty": {},
+	}
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	bw, err := NewBundleWriter(f)
+	require.NoError(t, err)
+	for _, k := range []string{"a", "b", "empty"} {
+		require.NoError(t, bw.Put(k, bytes.NewReader(blobs[k])))
+	}
+	assert.Error(t, bw.Put("a", bytes.NewReader(nil)))
+	require.NoError(t, bw.Close())
+	require.NoError(t, f.Close())
+
+	bs, err := OpenBundle(path)
+	require.NoError(t, err)
+
+	keys := bs.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b", "empty"}, keys)
+
+	for k, expected := range blobs {
+		exists, err := bs.Exists(ctx, k)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		data, _, err := GetBytes(ctx, bs, k, AllRange)
+		require.NoError(t, err)
+		assert.Equal(t, expected, data)
+	}
+
+	data, _, err := GetBytes(ctx, bs, "b", NewBlobRange(4, 6))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	data, _, err = GetBytes(ctx, bs, "b", NewBlobRange(-4, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("blob"), data)
+
+	exists, err := bs.Exists(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, _, err = bs.Get(ctx, "missing", AllRange)
+	assert.True(t, IsNotFoundError(err))
+
+	_, err = bs.Put(ctx, "c", bytes.NewReader(nil))
+	assert.Equal(t, ErrBundleReadOnly, err)
+	_, err = bs.CheckAndPut(ctx, "", "a", bytes.NewReader(nil))
+	assert.Equal(t, ErrBundleReadOnly, err)
+}
+
+func TestOpenInvalidBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bundle")
+
+	require.NoError(t, os.WriteFile(path, []byte("not a bundle file at all"), 0644))
+	_, err := OpenBundle(path)
+	assert.Equal(t, ErrInvalidBundle, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(bundleMagic), 0644))
+	_, err = OpenBundle(path)
+	assert.Equal(t, ErrInvalidBundle, err)
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3svc interface {
+	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error)
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// S3Blobstore provides an S3 implementation of the Blobstore interface. It works with any S3 compatible service which
+// supports the If-Match and If-None-Match conditions on PutObject, like MinIO. The version of a blob is its ETag.
+type S3Blobstore struct {
+	s3         s3svc
+	bucketName string
+	prefix     string
+}
+
+// NewS3Blobstore creates a new instance of a S3Blobstore
+func NewS3Blobstore(s3 s3svc, bucketName, prefix string) *S3Blobstore {
+	for len(prefix) > 0 && prefix[0] == '/' {
+		prefix = prefix[1:]
+	}
+
+	return &S3Blobstore{s3, bucketName, prefix}
+}
+
+// Exists returns true if a blob exists for the given key, and false if it does not.
+func (bs *S3Blobstore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := bs.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bs.bucketName),
+		Key:    aws.String(path.Join(bs.prefix, key)),
+	})
+
+	if isS3NotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Get retrieves an io.reader for the portion of a blob specified by br along with
+// its version
+func (bs *S3Blobstore) Get(ctx context.Context, key string, br BlobRange) (io.ReadCloser, string, error) {
+	absKey := path.Join(bs.prefix, key)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bs.bucketName),
+		Key:    aws.String(absKey),
+	}
+
+	switch {
+	case br.isAllRange():
+	case br.offset >= 0 && br.length == 0:
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", br.offset))
+	case br.offset >= 0:
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", br.offset, br.offset+br.length-1))
+	case br.length == 0:
+		input.Range = aws.String(fmt.Sprintf("bytes=%d", br.offset))
+	default:
+		// a range relative to the end of the blob which does not run to the end of it needs the size of the blob. The
+		// ETag condition makes sure the range is read from the blob the size is of.
+		head, err := bs.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(bs.bucketName),
+			Key:    aws.String(absKey),
+		})
+
+		if isS3NotFound(err) {
+			return nil, "", NotFound{"s3://" + path.Join(bs.bucketName, absKey)}
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		posBr := br.positiveRange(aws.Int64Value(head.ContentLength))
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", posBr.offset, posBr.offset+posBr.length-1))
+		input.IfMatch = head.ETag
+	}
+
+	result, err := bs.s3.GetObjectWithContext(ctx, input)
+
+	if isS3NotFound(err) {
+		return nil, "", NotFound{"s3://" + path.Join(bs.bucketName, absKey)}
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	return result.Body, aws.StringValue(result.ETag), nil
+}
+
+// Put sets the blob and the version for a key
+func (bs *S3Blobstore) Put(ctx context.Context, key string, reader io.Reader) (string, error) {
+	return bs.put(ctx, key, reader, nil)
+}
+
+// CheckAndPut will check the current version of a blob against an expectedVersion, and if the
+// versions match it will update the data and version associated with the key
+func (bs *S3Blobstore) CheckAndPut(ctx context.Context, expectedVersion, key string, reader io.Reader) (string, error) {
+	var condition map[string]string
+	if expectedVersion != "" {
+		condition = map[string]string{"If-Match": expectedVersion}
+	} else {
+		condition = map[string]string{"If-None-Match": "*"}
+	}
+
+	ver, err := bs.put(ctx, key, reader, condition)
+
+	// If-Match fails with 404 rather than 412 when the blob does not exist
+	if isS3PreconditionFailed(err) || (expectedVersion != "" && isS3NotFound(err)) {
+		return "", CheckAndPutError{key, expectedVersion, "unknown (Not supported in S3 implementation)"}
+	}
+
+	return ver, err
+}
+
+func (bs *S3Blobstore) put(ctx context.Context, key string, reader io.Reader, condition map[string]string) (string, error) {
+	body, ok := reader.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(reader)
+
+		if err != nil {
+			return "", err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	var opts []request.Option
+	if condition != nil {
+		opts = append(opts, request.WithSetRequestHeaders(condition))
+	}
+
+	result, err := bs.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bs.bucketName),
+		Key:    aws.String(path.Join(bs.prefix, key)),
+		Body:   body,
+	}, opts...)
+
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(result.ETag), nil
+}
+
+func isS3NotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusNotFound {
+		return true
+	}
+
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound")
+}
+
+// isS3PreconditionFailed returns true if a conditional write failed because the blob changed. S3 returns 409 if a
+// concurrent conditional write to the same key is in progress.
+func isS3PreconditionFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if aerr, ok := err.(awserr.RequestFailure); ok {
+		return aerr.StatusCode() == http.StatusPreconditionFailed || aerr.StatusCode() == http.StatusConflict
+	}
+
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == "PreconditionFailed" || aerr.Code() == "ConditionalRequestConflict")
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+)
+
+// appendS3Tests adds tests of an S3Blobstore backed by an in memory fake of S3, and if TEST_S3_BUCKET is set, by that
+// bucket. TEST_S3_ENDPOINT can be set to test against an S3 compatible service like MinIO.
+func appendS3Tests(tests []BlobstoreTest) []BlobstoreTest {
+	tests = append(tests, BlobstoreTest{"s3fake", NewS3Blobstore(newFakeS3(), "bucket", uuid.New().String()), 10, 20})
+
+	testS3Bucket := os.Getenv("TEST_S3_BUCKET")
+	if testS3Bucket != "" {
+		config := aws.NewConfig()
+		if endpoint := os.Getenv("TEST_S3_ENDPOINT"); endpoint != "" {
+			config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+		}
+
+		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			Config:            *config,
+			SharedConfigState: session.SharedConfigEnable,
+		}))
+
+		tests = append(tests, BlobstoreTest{"s3", NewS3Blobstore(s3.New(sess), testS3Bucket, uuid.New().String()), 4, 4})
+	}
+
+	return tests
+}
+
+// fakeS3 is an in memory implementation of the S3 operations used by S3Blobstore, including the conditional requests
+type fakeS3 struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{data: make(map[string][]byte)}
+}
+
+func fakeS3Error(code string, status int) error {
+	return awserr.NewRequestFailure(awserr.New(code, code, nil), status, "")
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (m *fakeS3) HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.data[*input.Key]
+	if !ok {
+		return nil, fakeS3Error("NotFound", http.StatusNotFound)
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj))),
+		ETag:          aws.String(etag(obj)),
+	}, nil
+}
+
+func (m *fakeS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.data[*input.Key]
+	if !ok {
+		return nil, fakeS3Error(s3.ErrCodeNoSuchKey, http.StatusNotFound)
+	}
+
+	tag := etag(obj)
+	if input.IfMatch != nil && *input.IfMatch != tag {
+		return nil, fakeS3Error("PreconditionFailed", http.StatusPreconditionFailed)
+	}
+
+	if input.Range != nil {
+		start, end := fakeS3Range(*input.Range, len(obj))
+		obj = obj[start:end]
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj)),
+		ContentLength: aws.Int64(int64(len(obj))),
+		ETag:          aws.String(tag),
+	}, nil
+}
+
+func (m *fakeS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	req.ApplyOptions(opts...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.data[*input.Key]
+	if ifMatch := req.HTTPRequest.Header.Get("If-Match"); ifMatch != "" {
+		if !ok {
+			return nil, fakeS3Error(s3.ErrCodeNoSuchKey, http.StatusNotFound)
+		} else if ifMatch != etag(obj) {
+			return nil, fakeS3Error("PreconditionFailed", http.StatusPreconditionFailed)
+		}
+	}
+	if req.HTTPRequest.Header.Get("If-None-Match") == "*" && ok {
+		return nil, fakeS3Error("PreconditionFailed", http.StatusPreconditionFailed)
+	}
+
+	m.data[*input.Key] = data
+
+	return &s3.PutObjectOutput{ETag: aws.String(etag(data))}, nil
+}
+
+// fakeS3Range returns the bounds of the bytes=start-end, bytes=start- or bytes=-suffix range |hdr| in a blob of
+// |size| bytes, as the indexes of a slice expression.
+func fakeS3Range(hdr string, size int) (start, end int) {
+	spec := strings.TrimPrefix(hdr, "bytes=")
+	ends := strings.SplitN(spec, "-", 2)
+
+	if ends[0] == "" {
+		suffix, _ := strconv.Atoi(ends[1])
+		return size - suffix, size
+	}
+
+	start, _ = strconv.Atoi(ends[0])
+	end = size
+	if ends[1] != "" {
+		last, _ := strconv.Atoi(ends[1])
+		if last+1 < size {
+			end = last + 1
+		}
+	}
+
+	return start, end
+}
@@ -31,6 +31,8 @@ import (
 
 var ErrNothingToCollect = errors.New("no changes since last gc")
 
+var ErrGCInProgress = errors.New("garbage collection is already in progress")
+
 // ChunkStore is the core storage abstraction in noms. We can put data
 // anyplace we have a ChunkStore implementation for.
 type ChunkStore interface {
@@ -109,6 +111,19 @@ type ChunkStoreGarbageCollector interface {
 	// chunks sent on |keepChunks| and will have removed all other content
 	// from the ChunkStore.
 	MarkAndSweepChunks(ctx context.Context, last hash.Hash, keepChunks <-chan []hash.Hash, dest ChunkStore) error
+
+	// BeginGC prepares the chunk store for a garbage collection that runs
+	// while it is being read from and written to. Until EndGC is called,
+	// |keeper| is called with the address of every chunk that is written,
+	// every root that is committed and every chunk that Has or HasMany
+	// finds, as well as with the chunks that were written but not yet
+	// committed when BeginGC was called. If |keeper| returns true, the
+	// operation waits for EndGC before it proceeds.
+	BeginGC(keeper func(hash.Hash) bool) error
+
+	// EndGC ends the garbage collection started by BeginGC and resumes
+	// the operations that are waiting for it.
+	EndGC()
 }
 
 type PrefixChunkStore interface {
@@ -129,6 +129,11 @@ type MemoryStoreView struct {
 	mu       sync.RWMutex
 	version  string
 
+	// keeper is set while a garbage collection is in progress, see BeginGC.
+	// gcCond is signaled when it ends.
+	keeper func(hash.Hash) bool
+	gcCond *sync.Cond
+
 	storage *MemoryStorage
 }
 
@@ -161,12 +166,22 @@ func (ms *MemoryStoreView) GetMany(ctx context.Context, hashes hash.HashSet, fou
 }
 
 func (ms *MemoryStoreView) Has(ctx context.Context, h hash.Hash) (bool, error) {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
-	if _, ok := ms.pending[h]; ok {
-		return true, nil
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for {
+		has := false
+		if _, ok := ms.pending[h]; ok {
+			has = true
+		} else if ok, err := ms.storage.Has(ctx, h); err != nil {
+			return false, err
+		} else {
+			has = ok
+		}
+		if !has || ms.keeper == nil || !ms.keeper(h) {
+			return has, nil
+		}
+		ms.gcCond.Wait()
 	}
-	return ms.storage.Has(ctx, h)
 }
 
 func (ms *MemoryStoreView) HasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
@@ -189,6 +204,7 @@ func (ms *MemoryStoreView) Version() string {
 func (ms *MemoryStoreView) Put(ctx context.Context, c Chunk) error {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
+	ms.waitForGC(c.Hash())
 	if ms.pending == nil {
 		ms.pending = map[hash.Hash]Chunk{}
 	}
@@ -220,6 +236,7 @@ func (ms *MemoryStoreView) Root(ctx context.Context) (hash.Hash, error) {
 func (ms *MemoryStoreView) Commit(ctx context.Context, current, last hash.Hash) (bool, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
+	ms.waitForGC(current)
 	if last != ms.rootHash {
 		return false, nil
 	}
@@ -237,8 +254,16 @@ func (ms *MemoryStoreView) MarkAndSweepChunks(ctx context.Context, last hash.Has
 		panic("unsupported")
 	}
 
-	if last != ms.rootHash {
-		return fmt.Errorf("last does not match ms.Root()")
+	if err := func() error {
+		ms.mu.RLock()
+		defer ms.mu.RUnlock()
+		// the root may move during a garbage collection started with BeginGC
+		if ms.keeper == nil && last != ms.rootHash {
+			return fmt.Errorf("last does not match ms.Root()")
+		}
+		return nil
+	}(); err != nil {
+		return err
 	}
 
 	keepers := make(map[hash.Hash]Chunk, ms.storage.Len())
@@ -262,11 +287,47 @@ LOOP:
 		}
 	}
 
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	ms.storage = &MemoryStorage{rootHash: ms.rootHash, data: keepers}
 	ms.pending = map[hash.Hash]Chunk{}
 	return nil
 }
 
+func (ms *MemoryStoreView) BeginGC(keeper func(hash.Hash) bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.keeper != nil {
+		return ErrGCInProgress
+	}
+	if ms.gcCond == nil {
+		ms.gcCond = sync.NewCond(&ms.mu)
+	}
+	for h := range ms.pending {
+		keeper(h)
+	}
+	ms.keeper = keeper
+	return nil
+}
+
+func (ms *MemoryStoreView) EndGC() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.keeper = nil
+	if ms.gcCond != nil {
+		ms.gcCond.Broadcast()
+	}
+}
+
+// waitForGC reports |h| to the keeper of the garbage collection in progress,
+// if there is one, and waits for the garbage collection to end if the keeper
+// asks it to. callers must hold |ms.mu| for writing.
+func (ms *MemoryStoreView) waitForGC(h hash.Hash) {
+	for ms.keeper != nil && ms.keeper(h) {
+		ms.gcCond.Wait()
+	}
+}
+
 func (ms *MemoryStoreView) Stats() interface{} {
 	return nil
 }
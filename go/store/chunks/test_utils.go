@@ -95,6 +95,22 @@ func (s *TestStoreView) MarkAndSweepChunks(ctx context.Context, last hash.Hash,
 	return collector.MarkAndSweepChunks(ctx, last, keepChunks, collector)
 }
 
+func (s *TestStoreView) BeginGC(keeper func(hash.Hash) bool) error {
+	collector, ok := s.ChunkStore.(ChunkStoreGarbageCollector)
+	if !ok {
+		return ErrUnsupportedOperation
+	}
+	return collector.BeginGC(keeper)
+}
+
+func (s *TestStoreView) EndGC() {
+	collector, ok := s.ChunkStore.(ChunkStoreGarbageCollector)
+	if !ok {
+		panic("EndGC called on a TestStoreView that does not support garbage collection")
+	}
+	collector.EndGC()
+}
+
 func (s *TestStoreView) Reads() int {
 	reads := atomic.LoadInt32(&s.reads)
 	return int(reads)
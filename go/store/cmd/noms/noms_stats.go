@@ -24,11 +24,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
 
 	"github.com/attic-labs/kingpin"
+	"github.com/dustin/go-humanize"
 
 	"github.com/dolthub/dolt/go/store/cmd/noms/util"
 	"github.com/dolthub/dolt/go/store/config"
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
 )
 
 func nomsStats(ctx context.Context, noms *kingpin.Application) (*kingpin.CmdClause, util.KingpinHandler) {
@@ -41,7 +47,31 @@ func nomsStats(ctx context.Context, noms *kingpin.Application) (*kingpin.CmdClau
 		util.CheckError(err)
 		defer store.Close()
 
-		fmt.Println(store.StatsSummary())
+		if summary := store.StatsSummary(); summary != "" {
+			fmt.Println(summary)
+		}
+
+		if r, ok := datas.ChunkStoreFromDatabase(store).(nbs.TableFileFormatReporter); ok {
+			formats, err := r.TableFileFormatStats()
+			util.CheckError(err)
+			printTableFileFormatStats(os.Stdout, formats)
+		}
 		return 0
 	}
 }
+
+// printTableFileFormatStats prints the size of the table files of each format, compressed and
+// uncompressed, and how much compressing them saves.
+func printTableFileFormatStats(w io.Writer, formats map[nbs.TableFileFormat]nbs.TableFileFormatStats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table Format\tTable Files\tPhysical Bytes\tUncompressed Bytes\tSaved")
+	for _, f := range []nbs.TableFileFormat{nbs.SnappyTableFileFormat, nbs.ZstdTableFileFormat} {
+		s, ok := formats[f]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.1f%%\n", f, s.TableFiles,
+			humanize.Bytes(s.PhysicalBytes), humanize.Bytes(s.UncompressedBytes), s.SavedPercent())
+	}
+	tw.Flush()
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dolthub/dolt/go/store/datas"
+	"github.com/dolthub/dolt/go/store/nbs"
+	"github.com/dolthub/dolt/go/store/spec"
+	"github.com/dolthub/dolt/go/store/types"
+	"github.com/dolthub/dolt/go/store/util/clienttest"
+)
+
+func TestNomsStats(t *testing.T) {
+	suite.Run(t, &nomsStatsTestSuite{})
+}
+
+type nomsStatsTestSuite struct {
+	clienttest.ClientTestSuite
+}
+
+func (s *nomsStatsTestSuite) TestTableFileFormats() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "stats"))
+	s.NoError(err)
+	defer sp.Close()
+
+	ds := sp.GetDataset(context.Background())
+	_, err = datas.CommitValue(context.Background(), ds.Database(), ds, types.String("hello!"))
+	s.NoError(err)
+
+	out, _ := s.MustRun(main, []string{"stats", spec.CreateDatabaseSpecString("nbs", s.DBDir)})
+	s.Contains(out, "Table Format")
+	s.Contains(out, "snappy")
+	s.NotContains(out, "zstd")
+}
+
+func TestPrintTableFileFormatStats(t *testing.T) {
+	var buf bytes.Buffer
+	printTableFileFormatStats(&buf, map[nbs.TableFileFormat]nbs.TableFileFormatStats{
+		nbs.ZstdTableFileFormat:   {TableFiles: 2, PhysicalBytes: 250_000, UncompressedBytes: 1_000_000},
+		nbs.SnappyTableFileFormat: {TableFiles: 1, PhysicalBytes: 500_000, UncompressedBytes: 1_000_000},
+	})
+	assert.Equal(t, ""+
+		"Table Format  Table Files  Physical Bytes  Uncompressed Bytes  Saved\n"+
+		"snappy        1            500 kB          1.0 MB              50.0%\n"+
+		"zstd          2            250 kB          1.0 MB              75.0%\n",
+		buf.String())
+}
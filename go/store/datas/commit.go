@@ -301,6 +301,9 @@ func commitPtr(nbf *types.NomsBinFormat, v types.Value, r *types.Ref) (*Commit,
 	}, nil
 }
 
+// ErrCommitNotFound is returned when loading a commit that isn't in the database.
+var ErrCommitNotFound = errors.New("target commit not found")
+
 func commitFromValue(nbf *types.NomsBinFormat, v types.Value) (*Commit, error) {
 	return commitPtr(nbf, v, nil)
 }
@@ -311,7 +314,7 @@ func LoadCommitRef(ctx context.Context, vr types.ValueReader, r types.Ref) (*Com
 		return nil, err
 	}
 	if v == nil {
-		return nil, errors.New("target commit not found")
+		return nil, ErrCommitNotFound
 	}
 	return commitPtr(vr.Format(), v, &r)
 }
@@ -322,7 +325,7 @@ func LoadCommitAddr(ctx context.Context, vr types.ValueReader, addr hash.Hash) (
 		return nil, err
 	}
 	if v == nil {
-		return nil, errors.New("target commit not found")
+		return nil, ErrCommitNotFound
 	}
 	return commitFromValue(vr.Format(), v)
 }
@@ -437,12 +440,11 @@ func FindClosureCommonAncestor(ctx context.Context, cl CommitClosure, cm *Commit
 
 // GetCommitParents returns |Ref|s to the parents of the commit.
 func GetCommitParents(ctx context.Context, vr types.ValueReader, cv types.Value) ([]*Commit, error) {
-	if sm, ok := cv.(types.SerialMessage); ok {
-		data := []byte(sm)
-		if serial.GetFileID(data) != serial.CommitFileID {
-			return nil, errors.New("GetCommitParents: provided value is not a commit.")
+	if _, ok := cv.(types.SerialMessage); ok {
+		addrs, err := GetCommitParentAddrs(ctx, vr.Format(), cv)
+		if err != nil {
+			return nil, err
 		}
-		addrs, err := types.SerialCommitParentAddrs(vr.Format(), sm)
 		vals, err := vr.ReadManyValues(ctx, addrs)
 		if err != nil {
 			return nil, err
@@ -465,6 +467,53 @@ func GetCommitParents(ctx context.Context, vr types.ValueReader, cv types.Value)
 		}
 		return res, nil
 	}
+	refs, err := getCommitParentRefs(ctx, cv)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]hash.Hash, len(refs))
+	for i, r := range refs {
+		hashes[i] = r.TargetHash()
+	}
+	vals, err := vr.ReadManyValues(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Commit, len(refs))
+	for i, val := range vals {
+		if val == nil {
+			return nil, fmt.Errorf("GetCommitParents: Did not find parent Commit in ValueReader: %s", hashes[i].String())
+		}
+		res[i] = &Commit{
+			val:    val,
+			height: refs[i].Height(),
+			addr:   refs[i].TargetHash(),
+		}
+	}
+	return res, nil
+}
+
+// GetCommitParentAddrs returns the addresses of the parents of a commit, without reading the parents themselves.
+func GetCommitParentAddrs(ctx context.Context, nbf *types.NomsBinFormat, cv types.Value) ([]hash.Hash, error) {
+	if sm, ok := cv.(types.SerialMessage); ok {
+		data := []byte(sm)
+		if serial.GetFileID(data) != serial.CommitFileID {
+			return nil, errors.New("GetCommitParents: provided value is not a commit.")
+		}
+		return types.SerialCommitParentAddrs(nbf, sm)
+	}
+	refs, err := getCommitParentRefs(ctx, cv)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]hash.Hash, len(refs))
+	for i, r := range refs {
+		hashes[i] = r.TargetHash()
+	}
+	return hashes, nil
+}
+
+func getCommitParentRefs(ctx context.Context, cv types.Value) ([]types.Ref, error) {
 	c, ok := cv.(types.Struct)
 	if !ok {
 		return nil, errors.New("GetCommitParents: provided value is not a commit.")
@@ -496,26 +545,7 @@ func GetCommitParents(ctx context.Context, vr types.ValueReader, cv types.Value)
 			})
 		}
 	}
-	hashes := make([]hash.Hash, len(refs))
-	for i, r := range refs {
-		hashes[i] = r.TargetHash()
-	}
-	vals, err := vr.ReadManyValues(ctx, hashes)
-	if err != nil {
-		return nil, err
-	}
-	res := make([]*Commit, len(refs))
-	for i, val := range vals {
-		if val == nil {
-			return nil, fmt.Errorf("GetCommitParents: Did not find parent Commit in ValueReader: %s", hashes[i].String())
-		}
-		res[i] = &Commit{
-			val:    val,
-			height: refs[i].Height(),
-			addr:   refs[i].TargetHash(),
-		}
-	}
-	return res, nil
+	return refs, err
 }
 
 // GetCommitMeta extracts the CommitMeta field from a commit. Returns |nil,
@@ -112,6 +112,31 @@ func newParentsClosureIterator(ctx context.Context, c *Commit, vr types.ValueRea
 	return &parentsClosureIterator{mi, nil, initialCurr}, nil
 }
 
+// IsCommitClosureLeaf returns whether |c| is a leaf node of the parent closure of a commit. The addresses in a leaf
+// node are ancestors of the commit.
+func IsCommitClosureLeaf(c chunks.Chunk) bool {
+	data := c.Data()
+	if serial.GetFileID(data) != serial.CommitClosureFileID {
+		return false
+	}
+	return serial.GetRootAsCommitClosure(data, serial.MessagePrefixSz).TreeLevel() == 0
+}
+
+// GetCommitClosureAddrs returns the addresses of the ancestors of |c| listed in its parent closure. Commits without a
+// materialized parent closure have none.
+func GetCommitClosureAddrs(ctx context.Context, c *Commit, vr types.ValueReader, ns tree.NodeStore) ([]hash.Hash, error) {
+	itr, err := newParentsClosureIterator(ctx, c, vr, ns)
+	if err != nil || itr == nil {
+		return nil, err
+	}
+
+	var addrs []hash.Hash
+	for itr.Next(ctx) {
+		addrs = append(addrs, itr.Hash())
+	}
+	return addrs, itr.Err()
+}
+
 func commitToMapKeyTuple(f *types.NomsBinFormat, c *Commit) (types.Tuple, error) {
 	h := c.Addr()
 	ib := make([]byte, len(hash.Hash{}))
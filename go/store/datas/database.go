@@ -100,6 +100,20 @@ type Database interface {
 	// updated in the new root, or neither of them are.
 	CommitWithWorkingSet(ctx context.Context, commitDS, workingSetDS Dataset, val types.Value, workingSetSpec WorkingSetSpec, prevWsHash hash.Hash, opts CommitOptions) (Dataset, Dataset, error)
 
+	// SetHeadsWithWorkingSet combines SetHead, Delete and UpdateWorkingSet. Each dataset named in |heads| is set to
+	// the commit at its address, or removed if the address is empty, and |workingSetDS| is set to a new working set
+	// value, all in the same new root. It uses the pessimistic lock that UpdateWorkingSet does, asserting that the hash
+	// |prevWsHash| given is still the current one before attempting to write a new value. Each dataset named in
+	// |prevHeads| must also still have the head at its address, or not exist if the address is empty, or
+	// ErrUnexpectedHead is returned. The returned Dataset is the working set.
+	SetHeadsWithWorkingSet(ctx context.Context, heads, prevHeads map[string]hash.Hash, workingSetDS Dataset, workingSetSpec WorkingSetSpec, prevWsHash hash.Hash) (Dataset, error)
+
+	// SetHeads combines SetHead and Delete for several datasets. Each dataset named in |heads| is set to the commit or
+	// tag at its address, or removed if the address is empty, all in the same new root. Each dataset named in
+	// |prevHeads| must still have the head at its address, or not exist if the address is empty, or ErrUnexpectedHead
+	// is returned.
+	SetHeads(ctx context.Context, heads, prevHeads map[string]hash.Hash) error
+
 	// Delete removes the Dataset named ds.ID() from the map at the root of
 	// the Database. If the Dataset is already not present in the map,
 	// returns success.
@@ -110,9 +124,9 @@ type Database interface {
 
 	// SetHead ignores any lineage constraints (e.g. the current head being
 	// an ancestor of the new Commit) and force-sets a mapping from
-	// datasetID: addr in this database. addr can point to a Commit or a
-	// Tag, but if Dataset is already present in the Database, it must
-	// point to the type of struct.
+	// datasetID: addr in this database. addr can point to a Commit, a
+	// Tag or a WorkingSet, but if Dataset is already present in the
+	// Database, it must point to the type of struct.
 	//
 	// All values that have been written to this Database are guaranteed to
 	// be persistent after SetHead(). If the update cannot be performed,
@@ -158,8 +172,10 @@ type GarbageCollector interface {
 	types.ValueReadWriter
 
 	// GC traverses the database starting at the Root and removes
-	// all unreferenced data from persistent storage.
-	GC(ctx context.Context, oldGenRefs, newGenRefs hash.HashSet) error
+	// all unreferenced data from persistent storage. The roots
+	// returned by |inUse|, if it is not nil, are kept. References
+	// to the chunks in |absent| are not followed.
+	GC(ctx context.Context, oldGenRefs, newGenRefs, absent hash.HashSet, inUse types.GCRootsFunc) error
 }
 
 // CanUsePuller returns true if a datas.Puller can be used to pull data from one Database into another.  Not all
@@ -43,6 +43,7 @@ var (
 	ErrOptimisticLockFailed = errors.New("optimistic lock failed on database Root update")
 	ErrMergeNeeded          = errors.New("dataset head is not ancestor of commit")
 	ErrAlreadyCommitted     = errors.New("dataset head already pointing at given commit")
+	ErrUnexpectedHead       = errors.New("dataset head is not at the expected address")
 )
 
 // rootTracker is a narrowing of the ChunkStore interface, to keep Database disciplined about working directly with Chunks
@@ -294,6 +295,14 @@ func (db *database) doSetHead(ctx context.Context, ds Dataset, addr hash.Hash) e
 		if !iscommit {
 			return fmt.Errorf("SetHead failed: reffered to value is not a tag:")
 		}
+	case workingSetName:
+		isws, err := IsWorkingSet(newVal)
+		if err != nil {
+			return err
+		}
+		if !isws {
+			return fmt.Errorf("SetHead failed: reffered to value is not a working set:")
+		}
 	default:
 		return fmt.Errorf("Unrecognized dataset value: %s", headType)
 	}
@@ -711,6 +720,174 @@ func (db *database) CommitWithWorkingSet(
 	return commitDS, workingSetDS, nil
 }
 
+// SetHeadsWithWorkingSet updates the heads given and the working set atomically. Uses the same global locking
+// mechanism as UpdateWorkingSet.
+func (db *database) SetHeadsWithWorkingSet(ctx context.Context, heads, prevHeads map[string]hash.Hash, workingSetDS Dataset, workingSetSpec WorkingSetSpec, prevWsHash hash.Hash) (Dataset, error) {
+	wsAddr, wsValRef, err := newWorkingSet(ctx, db, workingSetSpec.Meta, workingSetSpec.WorkingRoot, workingSetSpec.StagedRoot, workingSetSpec.MergeState)
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	headRefs := make(map[string]types.Ref, len(heads))
+	for id, addr := range heads {
+		if addr.IsEmpty() {
+			continue
+		}
+		head, err := db.readHead(ctx, addr)
+		if err != nil {
+			return Dataset{}, err
+		}
+		if head.TypeName() != commitName {
+			return Dataset{}, fmt.Errorf("SetHeadsWithWorkingSet failed: %s is not a commit", addr.String())
+		}
+		vref, err := types.NewRef(head.value(), db.Format())
+		if err != nil {
+			return Dataset{}, err
+		}
+		headRefs[id], err = types.ToRefOfValue(vref, db.Format())
+		if err != nil {
+			return Dataset{}, err
+		}
+	}
+
+	err = db.update(ctx, func(ctx context.Context, datasets types.Map) (types.Map, error) {
+		success, err := assertDatasetHash(ctx, datasets, workingSetDS.ID(), prevWsHash)
+		if err != nil {
+			return types.Map{}, err
+		}
+		if !success {
+			return types.Map{}, ErrOptimisticLockFailed
+		}
+		for id, addr := range prevHeads {
+			success, err = assertDatasetHash(ctx, datasets, id, addr)
+			if err != nil {
+				return types.Map{}, err
+			}
+			if !success {
+				return types.Map{}, ErrUnexpectedHead
+			}
+		}
+
+		edit := datasets.Edit().Set(types.String(workingSetDS.ID()), wsValRef)
+		for id := range heads {
+			if r, ok := headRefs[id]; ok {
+				edit = edit.Set(types.String(id), r)
+			} else {
+				edit = edit.Remove(types.String(id))
+			}
+		}
+		return edit.Map(ctx)
+	}, func(ctx context.Context, am prolly.AddressMap) (prolly.AddressMap, error) {
+		currWS, err := am.Get(ctx, workingSetDS.ID())
+		if err != nil {
+			return prolly.AddressMap{}, err
+		}
+		if currWS != prevWsHash {
+			return prolly.AddressMap{}, ErrOptimisticLockFailed
+		}
+		for id, addr := range prevHeads {
+			curr, err := am.Get(ctx, id)
+			if err != nil {
+				return prolly.AddressMap{}, err
+			}
+			if curr != addr {
+				return prolly.AddressMap{}, ErrUnexpectedHead
+			}
+		}
+		ae := am.Editor()
+		for id, addr := range heads {
+			if addr.IsEmpty() {
+				err = ae.Delete(ctx, id)
+			} else {
+				err = ae.Update(ctx, id, addr)
+			}
+			if err != nil {
+				return prolly.AddressMap{}, err
+			}
+		}
+		err = ae.Update(ctx, workingSetDS.ID(), wsAddr)
+		if err != nil {
+			return prolly.AddressMap{}, err
+		}
+		return ae.Flush(ctx)
+	})
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	return db.GetDataset(ctx, workingSetDS.ID())
+}
+
+// SetHeads updates the heads given atomically. Uses the same global locking mechanism as UpdateWorkingSet.
+func (db *database) SetHeads(ctx context.Context, heads, prevHeads map[string]hash.Hash) error {
+	headRefs := make(map[string]types.Ref, len(heads))
+	for id, addr := range heads {
+		if addr.IsEmpty() {
+			continue
+		}
+		head, err := db.readHead(ctx, addr)
+		if err != nil {
+			return err
+		}
+		if head.TypeName() != commitName && head.TypeName() != tagName {
+			return fmt.Errorf("SetHeads failed: %s is not a commit or a tag", addr.String())
+		}
+		vref, err := types.NewRef(head.value(), db.Format())
+		if err != nil {
+			return err
+		}
+		headRefs[id], err = types.ToRefOfValue(vref, db.Format())
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.update(ctx, func(ctx context.Context, datasets types.Map) (types.Map, error) {
+		for id, addr := range prevHeads {
+			success, err := assertDatasetHash(ctx, datasets, id, addr)
+			if err != nil {
+				return types.Map{}, err
+			}
+			if !success {
+				return types.Map{}, ErrUnexpectedHead
+			}
+		}
+
+		edit := datasets.Edit()
+		for id := range heads {
+			if r, ok := headRefs[id]; ok {
+				edit = edit.Set(types.String(id), r)
+			} else {
+				edit = edit.Remove(types.String(id))
+			}
+		}
+		return edit.Map(ctx)
+	}, func(ctx context.Context, am prolly.AddressMap) (prolly.AddressMap, error) {
+		for id, addr := range prevHeads {
+			curr, err := am.Get(ctx, id)
+			if err != nil {
+				return prolly.AddressMap{}, err
+			}
+			if curr != addr {
+				return prolly.AddressMap{}, ErrUnexpectedHead
+			}
+		}
+		ae := am.Editor()
+		for id, addr := range heads {
+			var err error
+			if addr.IsEmpty() {
+				err = ae.Delete(ctx, id)
+			} else {
+				err = ae.Update(ctx, id, addr)
+			}
+			if err != nil {
+				return prolly.AddressMap{}, err
+			}
+		}
+		return ae.Flush(ctx)
+	})
+}
+
 func (db *database) Delete(ctx context.Context, ds Dataset) (Dataset, error) {
 	return db.doHeadUpdate(ctx, ds, func(ds Dataset) error { return db.doDelete(ctx, ds.ID()) })
 }
@@ -817,8 +994,8 @@ func (db *database) doDelete(ctx context.Context, datasetIDstr string) error {
 }
 
 // GC traverses the database starting at the Root and removes all unreferenced data from persistent storage.
-func (db *database) GC(ctx context.Context, oldGenRefs, newGenRefs hash.HashSet) error {
-	return db.ValueStore.GC(ctx, oldGenRefs, newGenRefs)
+func (db *database) GC(ctx context.Context, oldGenRefs, newGenRefs, absent hash.HashSet, inUse types.GCRootsFunc) error {
+	return db.ValueStore.GC(ctx, oldGenRefs, newGenRefs, absent, inUse)
 }
 
 func (db *database) tryCommitChunks(ctx context.Context, newRootHash hash.Hash, currentRootHash hash.Hash) error {
@@ -536,6 +536,131 @@ func (suite *DatabaseSuite) TestSetHead() {
 	suite.True(mustHeadValue(ds).Equals(b))
 }
 
+func (suite *DatabaseSuite) TestSetHeadsWithWorkingSet() {
+	ctx := context.Background()
+
+	ds1, err := suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	ds1, err = CommitValue(ctx, suite.db, ds1, types.String("a"))
+	suite.NoError(err)
+	aCommitAddr := mustHeadAddr(ds1)
+	ds1, err = CommitValue(ctx, suite.db, ds1, types.String("b"))
+	suite.NoError(err)
+	bCommitAddr := mustHeadAddr(ds1)
+
+	rootRef, err := suite.db.WriteValue(ctx, types.String("root"))
+	suite.NoError(err)
+	spec := WorkingSetSpec{
+		Meta:        &WorkingSetMeta{Name: "name", Email: "email", Description: "ws"},
+		WorkingRoot: rootRef,
+		StagedRoot:  rootRef,
+	}
+
+	wsDs, err := suite.db.GetDataset(ctx, "ws1")
+	suite.NoError(err)
+	heads := map[string]hash.Hash{"ds1": aCommitAddr, "ds2": bCommitAddr}
+	wsDs, err = suite.db.SetHeadsWithWorkingSet(ctx, heads, nil, wsDs, spec, hash.Hash{})
+	suite.NoError(err)
+	suite.True(wsDs.HasHead())
+
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("a")))
+	ds2, err := suite.db.GetDataset(ctx, "ds2")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds2).Equals(types.String("b")))
+
+	// a stale working set hash fails without changing any of the heads
+	heads = map[string]hash.Hash{"ds1": bCommitAddr, "ds2": {}}
+	_, err = suite.db.SetHeadsWithWorkingSet(ctx, heads, nil, wsDs, spec, hash.Hash{})
+	suite.Equal(ErrOptimisticLockFailed, err)
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("a")))
+
+	// a head which moved fails without changing any of the heads
+	_, err = suite.db.SetHeadsWithWorkingSet(ctx, heads, map[string]hash.Hash{"ds1": bCommitAddr}, wsDs, spec, mustHeadAddr(wsDs))
+	suite.Equal(ErrUnexpectedHead, err)
+	_, err = suite.db.SetHeadsWithWorkingSet(ctx, heads, map[string]hash.Hash{"ds2": {}}, wsDs, spec, mustHeadAddr(wsDs))
+	suite.Equal(ErrUnexpectedHead, err)
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("a")))
+
+	prevHeads := map[string]hash.Hash{"ds1": aCommitAddr, "ds2": bCommitAddr, "ds3": {}}
+	wsDs, err = suite.db.SetHeadsWithWorkingSet(ctx, heads, prevHeads, wsDs, spec, mustHeadAddr(wsDs))
+	suite.NoError(err)
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("b")))
+	ds2, err = suite.db.GetDataset(ctx, "ds2")
+	suite.NoError(err)
+	suite.False(ds2.HasHead())
+
+	// only commits can be set as heads
+	_, err = suite.db.SetHeadsWithWorkingSet(ctx, map[string]hash.Hash{"ds1": mustHeadAddr(wsDs)}, nil, wsDs, spec, mustHeadAddr(wsDs))
+	suite.Error(err)
+}
+
+func (suite *DatabaseSuite) TestSetHeads() {
+	ctx := context.Background()
+
+	ds1, err := suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	ds1, err = CommitValue(ctx, suite.db, ds1, types.String("a"))
+	suite.NoError(err)
+	aCommitAddr := mustHeadAddr(ds1)
+	ds1, err = CommitValue(ctx, suite.db, ds1, types.String("b"))
+	suite.NoError(err)
+	bCommitAddr := mustHeadAddr(ds1)
+
+	tag1, err := suite.db.GetDataset(ctx, "tag1")
+	suite.NoError(err)
+	meta := NewTagMeta("name", "email", "tag")
+	tag1, err = suite.db.Tag(ctx, tag1, aCommitAddr, TagOptions{Meta: meta})
+	suite.NoError(err)
+	aTagAddr := mustHeadAddr(tag1)
+	addr, err := TagCommitAddr(ctx, suite.db, aTagAddr)
+	suite.NoError(err)
+	suite.Equal(aCommitAddr, addr)
+	addr, err = TagCommitAddr(ctx, suite.db, bCommitAddr)
+	suite.NoError(err)
+	suite.Equal(bCommitAddr, addr)
+
+	// a head which moved fails without changing any of the heads
+	bTagAddr, err := WriteTag(ctx, suite.db, bCommitAddr, meta)
+	suite.NoError(err)
+	heads := map[string]hash.Hash{"ds1": aCommitAddr, "ds2": bCommitAddr, "tag1": bTagAddr}
+	err = suite.db.SetHeads(ctx, heads, map[string]hash.Hash{"ds1": aCommitAddr, "tag1": aTagAddr})
+	suite.Equal(ErrUnexpectedHead, err)
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("b")))
+	ds2, err := suite.db.GetDataset(ctx, "ds2")
+	suite.NoError(err)
+	suite.False(ds2.HasHead())
+
+	err = suite.db.SetHeads(ctx, heads, map[string]hash.Hash{"ds1": bCommitAddr, "ds2": {}, "tag1": aTagAddr})
+	suite.NoError(err)
+	ds1, err = suite.db.GetDataset(ctx, "ds1")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds1).Equals(types.String("a")))
+	ds2, err = suite.db.GetDataset(ctx, "ds2")
+	suite.NoError(err)
+	suite.True(mustHeadValue(ds2).Equals(types.String("b")))
+	tag1, err = suite.db.GetDataset(ctx, "tag1")
+	suite.NoError(err)
+	_, addr, err = tag1.HeadTag()
+	suite.NoError(err)
+	suite.Equal(bCommitAddr, addr)
+
+	err = suite.db.SetHeads(ctx, map[string]hash.Hash{"ds2": {}}, nil)
+	suite.NoError(err)
+	ds2, err = suite.db.GetDataset(ctx, "ds2")
+	suite.NoError(err)
+	suite.False(ds2.HasHead())
+}
+
 func (suite *DatabaseSuite) TestFastForward() {
 	datasetID := "ds1"
 
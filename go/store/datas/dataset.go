@@ -163,6 +163,7 @@ type WorkingSetHead struct {
 type MergeState struct {
 	preMergeWorkingAddr *hash.Hash
 	fromCommitAddr      *hash.Hash
+	fromCommitSpecStr   string
 
 	nomsMergeStateRef *types.Ref
 	nomsMergeState    *types.Struct
@@ -229,6 +230,34 @@ func (ms *MergeState) FromCommit(ctx context.Context, vr types.ValueReader) (*Co
 	return commitFromValue(vr.Format(), commitV)
 }
 
+// FromCommitSpecStr returns the commit spec that was given to the merge, or an empty string for merges that were
+// started before it was recorded.
+func (ms *MergeState) FromCommitSpecStr(ctx context.Context, vr types.ValueReader) (string, error) {
+	if ms.fromCommitAddr != nil {
+		return ms.fromCommitSpecStr, nil
+	}
+	if ms.nomsMergeState == nil {
+		err := ms.loadIfNeeded(ctx, vr)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	commitSpecV, ok, err := ms.nomsMergeState.MaybeGet(mergeStateCommitSpecField)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", nil
+	}
+	commitSpecStr, ok := commitSpecV.(types.String)
+	if !ok {
+		return "", fmt.Errorf("corrupted MergeState struct")
+	}
+
+	return string(commitSpecStr), nil
+}
+
 type dsHead interface {
 	TypeName() string
 	Addr() hash.Hash
@@ -342,6 +371,7 @@ func (h serialWorkingSetHead) HeadWorkingSet() (*WorkingSetHead, error) {
 		}
 		*ret.MergeState.preMergeWorkingAddr = hash.New(mergeState.PreWorkingRootAddrBytes())
 		*ret.MergeState.fromCommitAddr = hash.New(mergeState.FromCommitAddrBytes())
+		ret.MergeState.fromCommitSpecStr = string(mergeState.FromCommitSpecStr())
 	}
 	return &ret, nil
 }
@@ -30,3 +30,36 @@ func PruneTableFiles(ctx context.Context, db Database) error {
 
 	return tfs.PruneTableFiles(ctx)
 }
+
+// RewriteTableFiles rewrites the table files of |db| into |format|.
+func RewriteTableFiles(ctx context.Context, db Database, format nbs.TableFileFormat) error {
+	rw, ok := db.chunkStore().(nbs.TableFileRewriter)
+
+	if !ok {
+		return chunks.ErrUnsupportedOperation
+	}
+
+	return rw.RewriteTableFiles(ctx, format)
+}
+
+// SetConjoinPolicy changes the policy the chunk store of |db| uses to conjoin its table files.
+func SetConjoinPolicy(db Database, policy nbs.ConjoinPolicy) error {
+	cs, ok := db.chunkStore().(nbs.ConjoinPolicySetter)
+
+	if !ok {
+		return chunks.ErrUnsupportedOperation
+	}
+
+	return cs.SetConjoinPolicy(policy)
+}
+
+// RepairChunks writes |repaired| to the chunk store of |db|, replacing any damaged copies of the same chunks.
+func RepairChunks(ctx context.Context, db Database, repaired []chunks.Chunk) error {
+	cs, ok := db.chunkStore().(nbs.ChunkRepairer)
+
+	if !ok {
+		return chunks.ErrUnsupportedOperation
+	}
+
+	return cs.RepairChunks(ctx, repaired)
+}
@@ -51,7 +51,7 @@ type TagOptions struct {
 // newTag serializes a tag pointing to |commitAddr| with the given |meta|,
 // persists it, and returns its addr. Also returns a types.Ref to the tag, if
 // the format for |db| is noms.
-func newTag(ctx context.Context, db *database, commitAddr hash.Hash, meta *TagMeta) (hash.Hash, types.Ref, error) {
+func newTag(ctx context.Context, db types.ValueReadWriter, commitAddr hash.Hash, meta *TagMeta) (hash.Hash, types.Ref, error) {
 	if !db.Format().UsesFlatbuffers() {
 		commitSt, err := db.ReadValue(ctx, commitAddr)
 		if err != nil {
@@ -111,6 +111,31 @@ func newTag(ctx context.Context, db *database, commitAddr hash.Hash, meta *TagMe
 	}
 }
 
+// WriteTag persists a tag pointing to |commitAddr| with the given |meta| and returns its addr, without setting any
+// dataset to it. See Database.SetHeads.
+func WriteTag(ctx context.Context, vrw types.ValueReadWriter, commitAddr hash.Hash, meta *TagMeta) (hash.Hash, error) {
+	addr, _, err := newTag(ctx, vrw, commitAddr, meta)
+	return addr, err
+}
+
+// TagCommitAddr returns the addr of the commit the tag at |addr| points to. If |addr| is the addr of a commit rather
+// than a tag, it's returned as is.
+func TagCommitAddr(ctx context.Context, vr types.ValueReader, addr hash.Hash) (hash.Hash, error) {
+	v, err := vr.ReadValue(ctx, addr)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	head, err := newHead(v, addr)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	if head == nil || head.TypeName() != tagName {
+		return addr, nil
+	}
+	_, commitAddr, err := head.HeadTag()
+	return commitAddr, err
+}
+
 func tag_flatbuffer(commitAddr hash.Hash, meta *TagMeta) serial.Message {
 	builder := flatbuffers.NewBuilder(1024)
 	addroff := builder.CreateByteVector(commitAddr[:])
@@ -594,6 +594,10 @@ func (fc *fakeConjoiner) Conjoin(ctx context.Context, upstream manifestContents,
 	return upstream, err
 }
 
+func (fc *fakeConjoiner) ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error) {
+	return chooseConjoinees(sources)
+}
+
 func assertInputInStore(input []byte, h hash.Hash, s chunks.ChunkStore, assert *assert.Assertions) {
 	c, err := s.Get(context.Background(), h)
 	assert.NoError(err)
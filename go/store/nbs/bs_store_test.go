@@ -0,0 +1,73 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/store/blobstore"
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/constants"
+)
+
+func TestBSStoreCommit(t *testing.T) {
+	ctx := context.Background()
+	bs := blobstore.NewInMemoryBlobstore()
+
+	store, err := NewBSStore(ctx, constants.FormatDefaultString, bs, testMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer store.Close()
+	interloper, err := NewBSStore(ctx, constants.FormatDefaultString, bs, testMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer interloper.Close()
+
+	input1, input2 := []byte("abc"), []byte("def")
+	c1, c2 := chunks.NewChunk(input1), chunks.NewChunk(input2)
+
+	root, err := store.Root(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, interloper.Put(ctx, c1))
+	success, err := interloper.Commit(ctx, c1.Hash(), root)
+	require.NoError(t, err)
+	assert.True(t, success)
+
+	// the manifest changed since |store| read it, so committing from the stale root fails
+	require.NoError(t, store.Put(ctx, c2))
+	success, err = store.Commit(ctx, c2.Hash(), root)
+	require.NoError(t, err)
+	assert.False(t, success)
+
+	root, err = store.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c1.Hash(), root)
+	success, err = store.Commit(ctx, c2.Hash(), root)
+	require.NoError(t, err)
+	assert.True(t, success)
+
+	reopened, err := NewBSStore(ctx, constants.FormatDefaultString, bs, testMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	root, err = reopened.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, c2.Hash(), root)
+	assertInputInStore(input1, c1.Hash(), reopened, assert.New(t))
+	assertInputInStore(input2, c2.Hash(), reopened, assert.New(t))
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/dolthub/dolt/go/store/blobstore"
+)
+
+// WriteBundle writes the manifest and table files of the local store |st| in
+// |dir| to |bw|. The bundle can be opened as a read-only store with
+// NewBundleStore. Stores with a chunk journal can't be bundled.
+func WriteBundle(ctx context.Context, st *NomsBlockStore, dir string, bw *blobstore.BundleWriter) error {
+	if _, ok := st.p.(*chunkJournal); ok {
+		return errors.New("cannot bundle a store with a chunk journal")
+	}
+
+	_, tableFiles, _, err := st.Sources(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tf := range tableFiles {
+		err = func() error {
+			rd, _, err := tf.Open(ctx)
+			if err != nil {
+				return err
+			}
+			defer rd.Close()
+			return bw.Put(tf.FileID(), rd)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return bw.Put(manifestFile, f)
+}
+
+// NewBundleStore opens the bundle |bs| as a read-only store.
+func NewBundleStore(ctx context.Context, nbfVerStr string, bs *blobstore.BundleBlobstore, q MemoryQuotaProvider) (*NomsBlockStore, error) {
+	exists, err := bs.Exists(ctx, manifestFile)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, blobstore.ErrInvalidBundle
+	}
+
+	return NewBSStore(ctx, nbfVerStr, bs, 0, q)
+}
@@ -48,6 +48,7 @@ type CmpChunkTableWriter struct {
 	blockAddr             *addr
 	chunkHashes           nomshash.HashSet
 	path                  string
+	zstd                  *zstdChunkEncoder
 }
 
 // NewCmpChunkTableWriter creates a new CmpChunkTableWriter instance with a default ByteSink
@@ -57,7 +58,30 @@ func NewCmpChunkTableWriter(tempDir string) (*CmpChunkTableWriter, error) {
 		return nil, err
 	}
 
-	return &CmpChunkTableWriter{NewHashingByteSink(s), 0, 0, nil, nil, nomshash.NewHashSet(), s.path}, nil
+	return &CmpChunkTableWriter{NewHashingByteSink(s), 0, 0, nil, nil, nomshash.NewHashSet(), s.path, nil}, nil
+}
+
+// newZstdChunkTableWriter creates a CmpChunkTableWriter that writes a zstd table file compressed with |dict|.
+func newZstdChunkTableWriter(tempDir string, dict []byte) (*CmpChunkTableWriter, error) {
+	enc, err := newZstdChunkEncoder(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	tw, err := NewCmpChunkTableWriter(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	tw.zstd = enc
+
+	return tw, nil
+}
+
+func (tw *CmpChunkTableWriter) format() TableFileFormat {
+	if tw.zstd != nil {
+		return ZstdTableFileFormat
+	}
+	return SnappyTableFileFormat
 }
 
 func (tw *CmpChunkTableWriter) ChunkCount() int {
@@ -91,14 +115,23 @@ func (tw *CmpChunkTableWriter) AddCmpChunk(c CompressedChunk) error {
 		return err
 	}
 
-	fullLen := len(c.FullCompressedChunk)
-	_, err = tw.sink.Write(c.FullCompressedChunk)
+	rec := c.FullCompressedChunk
+	if tw.zstd != nil {
+		data, err := snappy.Decode(nil, c.CompressedData)
+		if err != nil {
+			return err
+		}
+		rec = tw.zstd.encodeRecord(data, len(tw.prefixes) == 0)
+	}
+
+	fullLen := len(rec)
+	_, err = tw.sink.Write(rec)
 
 	if err != nil {
 		return err
 	}
 
-	tw.totalCompressedData += uint64(len(c.CompressedData))
+	tw.totalCompressedData += uint64(fullLen - checksumSize)
 	tw.totalUncompressedData += uint64(uncmpLen)
 
 	a := addr(c.H)
@@ -218,6 +251,10 @@ func (tw *CmpChunkTableWriter) writeIndex() (hash.Hash, error) {
 	}
 
 	blockHash.Write(buff[suffixesOffset:])
+	if tw.zstd != nil {
+		// a zstd table file must not share its name with a snappy table file of the same chunks
+		blockHash.Write([]byte(zstdMagicNumber))
+	}
 	_, err := tw.sink.Write(buff)
 
 	if err != nil {
@@ -243,7 +280,7 @@ func (tw *CmpChunkTableWriter) writeFooter() error {
 	}
 
 	// magic number
-	_, err = tw.sink.Write([]byte(magicNumber))
+	_, err = tw.sink.Write([]byte(tw.format().magicNumber()))
 
 	if err != nil {
 		return err
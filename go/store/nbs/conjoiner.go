@@ -24,6 +24,8 @@ package nbs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/bits"
 	"sort"
 	"sync"
 	"time"
@@ -45,6 +47,93 @@ type conjoiner interface {
 	// handle this, likely by rebasing against upstream and re-evaluating the
 	// situation.
 	Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error)
+
+	// ChooseConjoinees splits |sources| into the tables to conjoin and the
+	// tables to keep as they are, according to the conjoiner's policy.
+	ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error)
+}
+
+// ConjoinStrategy names a policy for deciding when, and which, table files of
+// a store are conjoined.
+type ConjoinStrategy string
+
+const (
+	// ConjoinByTableCount conjoins the smallest table files of a store once it
+	// has more than ConjoinPolicy.MaxTables of them.
+	ConjoinByTableCount ConjoinStrategy = "table_count"
+
+	// ConjoinSizeTiered conjoins table files of a similar size, once there are
+	// ConjoinPolicy.TierTables of them. Table files are tiered by chunk count,
+	// each tier holding tables four times as large as the one below it.
+	ConjoinSizeTiered ConjoinStrategy = "size_tiered"
+
+	// ConjoinOff never conjoins table files. Table files are still compacted
+	// by garbage collection.
+	ConjoinOff ConjoinStrategy = "off"
+)
+
+const defaultTierTables = 4
+
+// ConjoinPolicy configures how a NomsBlockStore conjoins its table files.
+type ConjoinPolicy struct {
+	Strategy ConjoinStrategy
+	// MaxTables is the number of table files above which ConjoinByTableCount conjoins.
+	MaxTables int
+	// TierTables is the number of table files in a tier at which ConjoinSizeTiered conjoins.
+	TierTables int
+	// Background makes commits start conjoins on a goroutine of their own,
+	// rather than conjoining before they update the manifest.
+	Background bool
+}
+
+// DefaultConjoinPolicy conjoins tables inline once a store has more than 256 table files.
+var DefaultConjoinPolicy = ConjoinPolicy{
+	Strategy:   ConjoinByTableCount,
+	MaxTables:  defaultMaxTables,
+	TierTables: defaultTierTables,
+}
+
+// Validate returns an error if |cp| is not a policy a store can use.
+func (cp ConjoinPolicy) Validate() error {
+	switch cp.Strategy {
+	case ConjoinByTableCount:
+		if cp.MaxTables < 2 {
+			return fmt.Errorf("conjoin max_tables must be at least 2, got %d", cp.MaxTables)
+		}
+	case ConjoinSizeTiered:
+		if cp.TierTables < 2 {
+			return fmt.Errorf("conjoin tier_tables must be at least 2, got %d", cp.TierTables)
+		}
+	case ConjoinOff:
+	default:
+		return fmt.Errorf("unknown conjoin strategy '%s'", cp.Strategy)
+	}
+	return nil
+}
+
+// ConjoinPolicySetter is implemented by stores whose ConjoinPolicy can be changed.
+type ConjoinPolicySetter interface {
+	// SetConjoinPolicy changes the policy the store uses to conjoin its table files.
+	SetConjoinPolicy(policy ConjoinPolicy) error
+}
+
+func (cp ConjoinPolicy) conjoiner() (conjoiner, error) {
+	if err := cp.Validate(); err != nil {
+		return nil, err
+	}
+	var c conjoiner
+	switch cp.Strategy {
+	case ConjoinByTableCount:
+		c = inlineConjoiner{cp.MaxTables}
+	case ConjoinSizeTiered:
+		c = sizeTieredConjoiner{cp.TierTables}
+	default:
+		return noopConjoiner{}, nil
+	}
+	if cp.Background {
+		c = backgroundConjoiner{c}
+	}
+	return c, nil
 }
 
 type inlineConjoiner struct {
@@ -56,7 +145,96 @@ func (c inlineConjoiner) ConjoinRequired(ts tableSet) bool {
 }
 
 func (c inlineConjoiner) Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
-	return conjoin(ctx, upstream, mm, p, stats)
+	return conjoin(ctx, upstream, mm, p, c.ChooseConjoinees, stats)
+}
+
+func (c inlineConjoiner) ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error) {
+	return chooseConjoinees(sources)
+}
+
+// sizeTieredConjoiner conjoins the tables of a tier once it has |tierTables| tables.
+type sizeTieredConjoiner struct {
+	tierTables int
+}
+
+func (c sizeTieredConjoiner) ConjoinRequired(ts tableSet) bool {
+	tiers, err := tierSources(ts.upstream)
+	if err != nil {
+		// let Conjoin surface the error
+		return true
+	}
+	for _, tier := range tiers {
+		if len(tier) >= c.tierTables {
+			return true
+		}
+	}
+	return false
+}
+
+func (c sizeTieredConjoiner) Conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
+	return conjoin(ctx, upstream, mm, p, c.ChooseConjoinees, stats)
+}
+
+// ChooseConjoinees conjoins the tables of the lowest tier that is full. If no
+// tier is full, because the tables changed since ConjoinRequired, it falls
+// back to conjoining the smallest tables.
+func (c sizeTieredConjoiner) ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error) {
+	tiers, err := tierSources(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+	full := -1
+	for t, tier := range tiers {
+		if len(tier) >= c.tierTables && (full < 0 || t < full) {
+			full = t
+		}
+	}
+	if full < 0 {
+		return chooseConjoinees(sources)
+	}
+	for t, tier := range tiers {
+		if t == full {
+			toConjoin = append(toConjoin, tier...)
+		} else {
+			toKeep = append(toKeep, tier...)
+		}
+	}
+	return toConjoin, toKeep, nil
+}
+
+// tierSources groups the snappy tables of |sources| by the base 4 logarithm
+// of their chunk count. The chunk journal and zstd tables are never conjoined.
+func tierSources(sources chunkSources) (map[int]chunkSources, error) {
+	tiers := make(map[int]chunkSources)
+	for _, src := range sources {
+		h, err := src.hash()
+		if err != nil {
+			return nil, err
+		}
+		if h == journalAddr {
+			continue
+		}
+		idx, err := src.index()
+		if err != nil {
+			return nil, err
+		}
+		if idx.TableFileFormat() == ZstdTableFileFormat {
+			continue
+		}
+		cnt, err := src.count()
+		if err != nil {
+			return nil, err
+		}
+		t := bits.Len32(cnt) / 2
+		tiers[t] = append(tiers[t], src)
+	}
+	return tiers, nil
+}
+
+// backgroundConjoiner marks a conjoiner whose conjoins are run on a goroutine
+// of their own by the NomsBlockStore, see NomsBlockStore.conjoinInBackground.
+type backgroundConjoiner struct {
+	conjoiner
 }
 
 type noopConjoiner struct {
@@ -70,7 +248,11 @@ func (c noopConjoiner) Conjoin(ctx context.Context, upstream manifestContents, m
 	return manifestContents{}, errors.New("unsupported conjoin operation on noopConjoiner")
 }
 
-func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, stats *Stats) (manifestContents, error) {
+func (c noopConjoiner) ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error) {
+	return nil, nil, errors.New("unsupported conjoin operation on noopConjoiner")
+}
+
+func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater, p tablePersister, choose conjoineeChooser, stats *Stats) (manifestContents, error) {
 	var conjoined tableSpec
 	var conjoinees, keepers, appendixSpecs []tableSpec
 
@@ -84,7 +266,7 @@ func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater,
 			}
 
 			var err error
-			conjoined, conjoinees, keepers, err = conjoinTables(ctx, p, upstream.specs, stats)
+			conjoined, conjoinees, keepers, err = conjoinTables(ctx, p, upstream.specs, choose, stats)
 
 			if err != nil {
 				return manifestContents{}, err
@@ -161,7 +343,60 @@ func conjoin(ctx context.Context, upstream manifestContents, mm manifestUpdater,
 	}
 }
 
-func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec, stats *Stats) (conjoined tableSpec, conjoinees, keepers []tableSpec, err error) {
+// replaceConjoinees returns |upstream| with |conjoinees| replaced by
+// |conjoined|, or false if any of |conjoinees| is no longer upstream.
+func replaceConjoinees(upstream manifestContents, conjoined tableSpec, conjoinees []tableSpec) (manifestContents, bool) {
+	appendixSet := upstream.getAppendixSet()
+	conjoineeSet := toSpecSet(conjoinees)
+
+	var appendixSpecs, keepers []tableSpec
+	found := 0
+	for _, spec := range upstream.specs {
+		if _, ok := appendixSet[spec.name]; ok {
+			appendixSpecs = append(appendixSpecs, spec)
+		} else if _, ok := conjoineeSet[spec.name]; ok {
+			found++
+		} else {
+			keepers = append(keepers, spec)
+		}
+	}
+	if found != len(conjoineeSet) {
+		return manifestContents{}, false
+	}
+
+	specs := make([]tableSpec, 0, len(appendixSpecs)+1+len(keepers))
+	specs = append(specs, appendixSpecs...)
+	specs = append(specs, conjoined)
+	specs = append(specs, keepers...)
+
+	return manifestContents{
+		nbfVers:  upstream.nbfVers,
+		root:     upstream.root,
+		lock:     generateLockHash(upstream.root, specs, appendixSpecs),
+		gcGen:    upstream.gcGen,
+		specs:    specs,
+		appendix: appendixSpecs,
+	}, true
+}
+
+// conjoineeChooser splits |sources| into the tables to conjoin and the tables to keep.
+type conjoineeChooser func(sources chunkSources) (toConjoin, toKeep chunkSources, err error)
+
+func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec, choose conjoineeChooser, stats *Stats) (conjoined tableSpec, conjoinees, keepers []tableSpec, err error) {
+	// The chunk journal is compacted by the chunkJournal itself, never conjoined
+	var journal []tableSpec
+	if containsJournal(upstream) {
+		tables := make([]tableSpec, 0, len(upstream))
+		for _, spec := range upstream {
+			if spec.name == journalAddr {
+				journal = append(journal, spec)
+			} else {
+				tables = append(tables, spec)
+			}
+		}
+		upstream = tables
+	}
+
 	// Open all the upstream tables concurrently
 	sources := make(chunkSources, len(upstream))
 
@@ -184,9 +419,20 @@ func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec,
 		return tableSpec{}, nil, nil, err
 	}
 
+	// The chunk records of zstd tables are compressed with a dictionary of
+	// their own, so they can't be copied into another table
+	var zstdTables chunkSources
+	sources, zstdTables, err = partitionByFormat(sources)
+	if err != nil {
+		return tableSpec{}, nil, nil, err
+	}
+	if len(sources) < 2 {
+		return tableSpec{}, nil, nil, errors.New("not enough snappy table files to conjoin")
+	}
+
 	t1 := time.Now()
 
-	toConjoin, toKeep, err := chooseConjoinees(sources)
+	toConjoin, toKeep, err := choose(sources)
 
 	if err != nil {
 		return tableSpec{}, nil, nil, err
@@ -221,6 +467,15 @@ func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec,
 		return tableSpec{}, nil, nil, err
 	}
 
+	zstdSpecs, err := toSpecs(zstdTables)
+
+	if err != nil {
+		return tableSpec{}, nil, nil, err
+	}
+
+	keepers = append(keepers, zstdSpecs...)
+	keepers = append(keepers, journal...)
+
 	h, err := conjoinedSrc.hash()
 
 	if err != nil {
@@ -236,6 +491,22 @@ func conjoinTables(ctx context.Context, p tablePersister, upstream []tableSpec,
 	return tableSpec{h, cnt}, conjoinees, keepers, nil
 }
 
+// partitionByFormat splits |sources| into snappy and zstd tables.
+func partitionByFormat(sources chunkSources) (snappy, zstd chunkSources, err error) {
+	for _, src := range sources {
+		idx, err := src.index()
+		if err != nil {
+			return nil, nil, err
+		}
+		if idx.TableFileFormat() == ZstdTableFileFormat {
+			zstd = append(zstd, src)
+		} else {
+			snappy = append(snappy, src)
+		}
+	}
+	return snappy, zstd, nil
+}
+
 // Current approach is to choose the smallest N tables which, when removed and replaced with the conjoinment, will leave the conjoinment as the smallest table.
 func chooseConjoinees(upstream chunkSources) (toConjoin, toKeep chunkSources, err error) {
 	sortedUpstream := make(chunkSources, len(upstream))
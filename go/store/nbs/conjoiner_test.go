@@ -161,7 +161,7 @@ func TestConjoin(t *testing.T) {
 			t.Run(c.name, func(t *testing.T) {
 				fm, p, upstream := setup(startLock, startRoot, c.precompact)
 
-				_, err := conjoin(context.Background(), upstream, fm, p, stats)
+				_, err := conjoin(context.Background(), upstream, fm, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -182,7 +182,7 @@ func TestConjoin(t *testing.T) {
 					specs := append([]tableSpec{}, upstream.specs...)
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, append(specs, newTable), nil)
 				}}
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -202,7 +202,7 @@ func TestConjoin(t *testing.T) {
 				u := updatePreemptManifest{fm, func() {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, upstream.specs[1:], nil)
 				}}
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -244,7 +244,7 @@ func TestConjoin(t *testing.T) {
 			t.Run(c.name, func(t *testing.T) {
 				fm, p, upstream := setupAppendix(startLock, startRoot, c.precompact, c.appendix)
 
-				_, err := conjoin(context.Background(), upstream, fm, p, stats)
+				_, err := conjoin(context.Background(), upstream, fm, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -268,7 +268,7 @@ func TestConjoin(t *testing.T) {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, append(specs, newTable), upstream.appendix)
 				}}
 
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -293,7 +293,7 @@ func TestConjoin(t *testing.T) {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, append(specs, upstream.specs...), append(app, newTable))
 				}}
 
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -317,7 +317,7 @@ func TestConjoin(t *testing.T) {
 				u := updatePreemptManifest{fm, func() {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, upstream.specs[len(c.appendix)+1:], upstream.appendix[:])
 				}}
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -341,7 +341,7 @@ func TestConjoin(t *testing.T) {
 					fm.set(constants.NomsVersion, computeAddr([]byte("lock2")), startRoot, specs, append([]tableSpec{}, newTable))
 				}}
 
-				_, err := conjoin(context.Background(), upstream, u, p, stats)
+				_, err := conjoin(context.Background(), upstream, u, p, chooseConjoinees, stats)
 				require.NoError(t, err)
 				exists, newUpstream, err := fm.ParseIfExists(context.Background(), stats, nil)
 				require.NoError(t, err)
@@ -353,6 +353,75 @@ func TestConjoin(t *testing.T) {
 	})
 }
 
+func TestSizeTieredConjoiner(t *testing.T) {
+	p := newFakeTablePersister(&noopQuotaProvider{})
+	c := sizeTieredConjoiner{tierTables: 3}
+
+	getSizes := func(srcs chunkSources) (sizes []uint32) {
+		for _, src := range srcs {
+			sizes = append(sizes, mustUint32(src.count()))
+		}
+		sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+		return
+	}
+
+	tc := []struct {
+		name      string
+		sizes     []uint32
+		required  bool
+		toConjoin []uint32
+	}{
+		{"empty tiers", []uint32{1, 4, 16, 64}, false, nil},
+		{"one tier short", []uint32{4, 5, 64, 70}, false, nil},
+		{"one full tier", []uint32{4, 5, 7, 64, 70}, true, []uint32{4, 5, 7}},
+		{"lowest full tier", []uint32{1, 2, 2, 3, 16, 17, 20, 25}, true, []uint32{2, 2, 3}},
+		{"two full tiers", []uint32{16, 17, 20, 64, 80, 100}, true, []uint32{16, 17, 20}},
+	}
+	for _, test := range tc {
+		t.Run(test.name, func(t *testing.T) {
+			srcs := makeTestSrcs(t, test.sizes, p)
+			defer func() {
+				for _, src := range srcs {
+					require.NoError(t, src.Close())
+				}
+			}()
+			assert.Equal(t, test.required, c.ConjoinRequired(tableSet{upstream: srcs}))
+			if !test.required {
+				return
+			}
+			toConjoin, toKeep, err := c.ChooseConjoinees(srcs)
+			require.NoError(t, err)
+			assert.Equal(t, test.toConjoin, getSizes(toConjoin))
+			assert.Len(t, toKeep, len(srcs)-len(toConjoin))
+		})
+	}
+}
+
+func TestConjoinPolicy(t *testing.T) {
+	for _, policy := range []ConjoinPolicy{
+		DefaultConjoinPolicy,
+		{Strategy: ConjoinSizeTiered, TierTables: defaultTierTables, Background: true},
+		{Strategy: ConjoinOff},
+	} {
+		assert.NoError(t, policy.Validate())
+	}
+	for _, policy := range []ConjoinPolicy{
+		{},
+		{Strategy: "sometimes"},
+		{Strategy: ConjoinByTableCount, MaxTables: 1},
+		{Strategy: ConjoinSizeTiered},
+	} {
+		assert.Error(t, policy.Validate())
+	}
+
+	c, err := ConjoinPolicy{Strategy: ConjoinSizeTiered, TierTables: 4, Background: true}.conjoiner()
+	require.NoError(t, err)
+	assert.Equal(t, backgroundConjoiner{sizeTieredConjoiner{4}}, c)
+	c, err = ConjoinPolicy{Strategy: ConjoinOff, Background: true}.conjoiner()
+	require.NoError(t, err)
+	assert.Equal(t, noopConjoiner{}, c)
+}
+
 type updatePreemptManifest struct {
 	manifest
 	preUpdate func()
@@ -181,6 +181,15 @@ func (ftp *fsTablePersister) ConjoinAll(ctx context.Context, sources chunkSource
 	return ftp.Open(ctx, name, plan.chunkCount, stats)
 }
 
+// RemoveTableFile implements tableFileRemover.
+func (ftp *fsTablePersister) RemoveTableFile(ctx context.Context, name addr) error {
+	err := file.Remove(filepath.Join(ftp.dir, name.String()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
 func (ftp *fsTablePersister) PruneTableFiles(ctx context.Context, contents manifestContents) error {
 	ss := contents.getSpecSet()
 
@@ -26,10 +26,16 @@ import (
 var _ chunks.ChunkStore = (*GenerationalNBS)(nil)
 var _ chunks.GenerationalCS = (*GenerationalNBS)(nil)
 var _ TableFileStore = (*GenerationalNBS)(nil)
+var _ TableFileRewriter = (*GenerationalNBS)(nil)
+var _ TableFileFormatReporter = (*GenerationalNBS)(nil)
+var _ ConjoinPolicySetter = (*GenerationalNBS)(nil)
+var _ ChunkRepairer = (*GenerationalNBS)(nil)
+var _ LazyChunkStore = (*GenerationalNBS)(nil)
 
 type GenerationalNBS struct {
 	oldGen *NomsBlockStore
 	newGen *NomsBlockStore
+	lazy   *lazySource
 }
 
 func NewGenerationalCS(oldGen, newGen *NomsBlockStore) *GenerationalNBS {
@@ -60,7 +66,15 @@ func (gcs *GenerationalNBS) Get(ctx context.Context, h hash.Hash) (chunks.Chunk,
 	}
 
 	if c.IsEmpty() {
-		return gcs.newGen.Get(ctx, h)
+		c, err = gcs.newGen.Get(ctx, h)
+
+		if err != nil {
+			return chunks.EmptyChunk, err
+		}
+	}
+
+	if c.IsEmpty() && gcs.lazy != nil {
+		return gcs.lazy.get(ctx, h)
 	}
 
 	return c, nil
@@ -89,7 +103,30 @@ func (gcs *GenerationalNBS) GetMany(ctx context.Context, hashes hash.HashSet, fo
 		return nil
 	}
 
-	return gcs.newGen.GetMany(ctx, notInOldGen, found)
+	if gcs.lazy == nil {
+		return gcs.newGen.GetMany(ctx, notInOldGen, found)
+	}
+
+	notInNewGen := notInOldGen.Copy()
+	err = gcs.newGen.GetMany(ctx, notInOldGen, func(ctx context.Context, chunk *chunks.Chunk) {
+		func() {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(notInNewGen, chunk.Hash())
+		}()
+
+		found(ctx, chunk)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(notInNewGen) == 0 {
+		return nil
+	}
+
+	return gcs.lazy.getMany(ctx, notInNewGen, found)
 }
 
 func (gcs *GenerationalNBS) GetManyCompressed(ctx context.Context, hashes hash.HashSet, found func(context.Context, CompressedChunk)) error {
@@ -113,7 +150,32 @@ func (gcs *GenerationalNBS) GetManyCompressed(ctx context.Context, hashes hash.H
 		return nil
 	}
 
-	return gcs.newGen.GetManyCompressed(ctx, notInOldGen, found)
+	if gcs.lazy == nil {
+		return gcs.newGen.GetManyCompressed(ctx, notInOldGen, found)
+	}
+
+	notInNewGen := notInOldGen.Copy()
+	err = gcs.newGen.GetManyCompressed(ctx, notInOldGen, func(ctx context.Context, chunk CompressedChunk) {
+		func() {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(notInNewGen, chunk.Hash())
+		}()
+
+		found(ctx, chunk)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(notInNewGen) == 0 {
+		return nil
+	}
+
+	return gcs.lazy.getMany(ctx, notInNewGen, func(ctx context.Context, chunk *chunks.Chunk) {
+		found(ctx, ChunkToCompressedChunk(*chunk))
+	})
 }
 
 // Returns true iff the value at the address |h| is contained in the
@@ -129,7 +191,13 @@ func (gcs *GenerationalNBS) Has(ctx context.Context, h hash.Hash) (bool, error)
 		return true, nil
 	}
 
-	return gcs.newGen.Has(ctx, h)
+	has, err = gcs.newGen.Has(ctx, h)
+
+	if err != nil || has || gcs.lazy == nil {
+		return has, err
+	}
+
+	return gcs.lazy.has(ctx, h)
 }
 
 // Returns a new HashSet containing any members of |hashes| that are
@@ -145,7 +213,13 @@ func (gcs *GenerationalNBS) HasMany(ctx context.Context, hashes hash.HashSet) (a
 		return notInOldGen, nil
 	}
 
-	return gcs.newGen.HasMany(ctx, notInOldGen)
+	absent, err = gcs.newGen.HasMany(ctx, notInOldGen)
+
+	if err != nil || len(absent) == 0 || gcs.lazy == nil {
+		return absent, err
+	}
+
+	return gcs.lazy.hasMany(ctx, absent)
 }
 
 // Put caches c in the ChunkSource. Upon return, c must be visible to
@@ -299,12 +373,111 @@ func (gcs *GenerationalNBS) PruneTableFiles(ctx context.Context) error {
 	return gcs.newGen.PruneTableFiles(ctx)
 }
 
+// RewriteTableFiles rewrites the table files of the old and new gen chunkstores that aren't in |format| into it.
+func (gcs *GenerationalNBS) RewriteTableFiles(ctx context.Context, format TableFileFormat) error {
+	err := gcs.oldGen.RewriteTableFiles(ctx, format)
+
+	if err != nil {
+		return err
+	}
+
+	return gcs.newGen.RewriteTableFiles(ctx, format)
+}
+
+// TableFileFormatStats returns a summary of the table files of the old and new gen chunkstores for each TableFileFormat in use.
+func (gcs *GenerationalNBS) TableFileFormatStats() (map[TableFileFormat]TableFileFormatStats, error) {
+	stats, err := gcs.oldGen.TableFileFormatStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	newGenStats, err := gcs.newGen.TableFileFormatStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for f, s := range newGenStats {
+		stats[f] = stats[f].add(s)
+	}
+	return stats, nil
+}
+
+// SetConjoinPolicy changes the policy the old and new gen chunkstores use to conjoin their table files.
+func (gcs *GenerationalNBS) SetConjoinPolicy(policy ConjoinPolicy) error {
+	err := gcs.oldGen.SetConjoinPolicy(policy)
+
+	if err != nil {
+		return err
+	}
+
+	return gcs.newGen.SetConjoinPolicy(policy)
+}
+
+// RepairChunks writes |repaired| to the old gen chunkstore, which is read before the new gen chunkstore, and drops
+// the table files either chunkstore was opened without from their manifests.
+func (gcs *GenerationalNBS) RepairChunks(ctx context.Context, repaired []chunks.Chunk) error {
+	err := gcs.oldGen.RepairChunks(ctx, repaired)
+
+	if err != nil {
+		return err
+	}
+
+	return gcs.newGen.RepairChunks(ctx, nil)
+}
+
 // SetRootChunk changes the root chunk hash from the previous value to the new root for the newgen cs
 func (gcs *GenerationalNBS) SetRootChunk(ctx context.Context, root, previous hash.Hash) error {
 	return gcs.newGen.SetRootChunk(ctx, root, previous)
 }
 
+// SetLazySource makes this store read the chunks it has in neither generation from the chunk store |open| returns.
+// Chunks read from it are written to the old gen chunkstore.
+func (gcs *GenerationalNBS) SetLazySource(open LazySourceOpener) {
+	gcs.lazy = newLazySource(open, gcs.oldGen)
+}
+
+// IsLazy returns whether this store reads missing chunks from a lazy source.
+func (gcs *GenerationalNBS) IsLazy() bool {
+	return gcs.lazy != nil
+}
+
 // SupportedOperations returns a description of the support TableFile operations. Some stores only support reading table files, not writing.
 func (gcs *GenerationalNBS) SupportedOperations() TableFileStoreOps {
 	return gcs.newGen.SupportedOperations()
 }
+
+// GetChunkLocations returns the table files and the ranges within them of the chunks of |hashes| found in the new gen
+// or the old gen store, keyed by table file. Found hashes are removed from |hashes|.
+func (gcs *GenerationalNBS) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash]map[hash.Hash]Range, error) {
+	ranges, err := gcs.newGen.GetChunkLocations(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return ranges, nil
+	}
+
+	oldRanges, err := gcs.oldGen.GetChunkLocations(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for fileId, chunkRanges := range oldRanges {
+		ranges[fileId] = chunkRanges
+	}
+
+	return ranges, nil
+}
+
+// TableFilePath returns the path on disk of the table file |fileId| of either the new gen or the old gen store.
+func (gcs *GenerationalNBS) TableFilePath(fileId string) (string, bool, error) {
+	path, ok, err := gcs.newGen.TableFilePath(fileId)
+	if err != nil || ok {
+		return path, ok, err
+	}
+
+	return gcs.oldGen.TableFilePath(fileId)
+}
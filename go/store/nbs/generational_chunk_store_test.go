@@ -171,3 +171,108 @@ func TestGenerationalCS(t *testing.T) {
 	putChunks(t, ctx, chnks, cs, inNew, 15, 16, 17, 18, 19)
 	requireChunks(t, ctx, chnks, cs, inOld, inNew)
 }
+
+func TestGenerationalCSChunkLocations(t *testing.T) {
+	ctx := context.Background()
+	oldGen, _, _ := makeTestLocalStore(t, 64)
+	newGen, _, _ := makeTestLocalStore(t, 64)
+	inOld := make(map[int]bool)
+	inNew := make(map[int]bool)
+	chnks := genChunks(t, 10, 1000)
+
+	putChunks(t, ctx, chnks, oldGen, inOld, 0, 1, 2, 3, 4)
+	commitStore(t, ctx, oldGen)
+
+	cs := NewGenerationalCS(oldGen, newGen)
+	putChunks(t, ctx, chnks, cs, inNew, 5, 6, 7, 8, 9)
+	commitStore(t, ctx, cs)
+
+	all := hashesForChunks(chnks, mergeMaps(inOld, inNew))
+	locs, err := cs.GetChunkLocations(all.Copy())
+	require.NoError(t, err)
+
+	found := hash.NewHashSet()
+	for fileId, ranges := range locs {
+		path, ok, err := cs.TableFilePath(fileId.String())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.FileExists(t, path)
+
+		for h := range ranges {
+			found.Insert(h)
+		}
+	}
+	require.Equal(t, all, found)
+
+	_, ok, err := cs.TableFilePath(hash.Of([]byte("not a table file")).String())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func commitStore(t *testing.T, ctx context.Context, cs chunks.ChunkStore) {
+	root, err := cs.Root(ctx)
+	require.NoError(t, err)
+	ok, err := cs.Commit(ctx, root, root)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestGenerationalCSLazySource(t *testing.T) {
+	ctx := context.Background()
+	oldGen, oldGenDir, q := makeTestLocalStore(t, 64)
+	newGen, _, _ := makeTestLocalStore(t, 64)
+	src, _, _ := makeTestLocalStore(t, 64)
+	chnks := genChunks(t, 20, 1000)
+
+	inSrc := make(map[int]bool)
+	putChunks(t, ctx, chnks, src, inSrc, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	inNew := make(map[int]bool)
+	putChunks(t, ctx, chnks, newGen, inNew, 10)
+
+	opened := 0
+	cs := NewGenerationalCS(oldGen, newGen)
+	require.False(t, cs.IsLazy())
+	cs.SetLazySource(func(ctx context.Context) (chunks.ChunkStore, error) {
+		opened++
+		return src, nil
+	})
+	require.True(t, cs.IsLazy())
+
+	// the source is only opened once a chunk is missing
+	c, err := cs.Get(ctx, chnks[10].Hash())
+	require.NoError(t, err)
+	require.Equal(t, chnks[10], c)
+	require.Equal(t, 0, opened)
+
+	c, err = cs.Get(ctx, chnks[0].Hash())
+	require.NoError(t, err)
+	require.Equal(t, chnks[0], c)
+
+	found := make(foundHashes)
+	err = cs.GetMany(ctx, hashesForChunks(chnks, map[int]bool{1: true, 2: true, 10: true, 15: true}), found.found)
+	require.NoError(t, err)
+	require.Equal(t, hashesForChunks(chnks, map[int]bool{1: true, 2: true, 10: true}), hash.HashSet(found))
+
+	compressed := hash.NewHashSet()
+	err = cs.GetManyCompressed(ctx, hashesForChunks(chnks, map[int]bool{3: true, 16: true}), func(ctx context.Context, chunk CompressedChunk) {
+		compressed.Insert(chunk.Hash())
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashesForChunks(chnks, map[int]bool{3: true}), compressed)
+
+	has, err := cs.Has(ctx, chnks[9].Hash())
+	require.NoError(t, err)
+	require.True(t, has)
+	absent, err := cs.HasMany(ctx, hashesForChunks(chnks, map[int]bool{8: true, 10: true, 17: true}))
+	require.NoError(t, err)
+	require.Equal(t, hashesForChunks(chnks, map[int]bool{17: true}), absent)
+	require.Equal(t, 1, opened)
+
+	// the chunks read from the source were persisted to the old gen, the chunks which were only checked for were not
+	reopened, err := newLocalStore(ctx, oldGen.Version(), oldGenDir, defaultMemTableSize, 64, q)
+	require.NoError(t, err)
+	defer reopened.Close()
+	absent, err = reopened.HasMany(ctx, hashesForChunks(chnks, inSrc))
+	require.NoError(t, err)
+	require.Equal(t, hashesForChunks(chnks, map[int]bool{4: true, 5: true, 6: true, 7: true, 8: true, 9: true}), absent)
+}
@@ -0,0 +1,521 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dolthub/fslock"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const (
+	// chunkJournalName is the file name of the chunk journal. It is a valid
+	// table file name, which lets the journal be listed in the manifest
+	// alongside regular table files.
+	chunkJournalName = "vvvvvvvvvvvvvvvvvvvvvvvvvvvvvvvv"
+
+	// chunkJournalLockName is the file name of the lock the chunk journal
+	// holds for as long as it is open.
+	chunkJournalLockName = "journal.lock"
+
+	// journalCompactionSize is the journal size above which the journal is
+	// compacted into a table file when the store is opened.
+	journalCompactionSize = 256 * 1024 * 1024
+)
+
+var journalAddr = addr(hash.Parse(chunkJournalName))
+
+// ErrJournalLocked is returned when opening a database whose chunk journal is
+// in use by another process.
+var ErrJournalLocked = errors.New("the database is in use by another dolt process")
+
+// openJournals are the chunk journals open in this process, by directory.
+// Stores opened on the same directory share its journal.
+var openJournals = struct {
+	mu       sync.Mutex
+	journals map[string]*chunkJournal
+}{journals: make(map[string]*chunkJournal)}
+
+// chunkJournal is a persistence abstraction for a local NomsBlockStore that
+// implements both tablePersister and manifest. Rather than writing a new table
+// file for every memTable and rewriting the manifest for every commit, it
+// appends chunks and root hashes to a single append-only journal file, so that
+// a small commit costs one sequential write and one fsync.
+//
+// The journal is listed in the manifest as a table file named |journalAddr|.
+// The manifest on disk is only rewritten when the set of table files changes;
+// the latest root hash is recovered from the journal when the store is opened.
+// Journals that grow past journalCompactionSize are compacted into regular
+// table files when the store is opened, and garbage collection copies the live
+// chunks of the journal into table files and truncates it when it prunes the
+// old table files.
+//
+// The chunkJournal keeps the current manifest contents in memory, so it must
+// be the only writer of its database directory. It holds an exclusive lock on
+// the directory from the moment it is opened until it is closed, so opening
+// the journal from another process fails with ErrJournalLocked. Stores of the
+// same process share the journal of a directory, which is closed when the
+// last of them closes it.
+type chunkJournal struct {
+	wr    *journalWriter
+	dir   string
+	path  string
+	flock *fslock.Lock
+	// refs is the number of stores using the journal, guarded by |openJournals.mu|
+	refs int
+
+	// contents are the current manifest contents of the store, including the
+	// latest root hash recorded in the journal. |exists| is false until the
+	// manifest is written for the first time.
+	contents manifestContents
+	exists   bool
+
+	// backing holds the table files of the store and the last root hash
+	// written alongside them. |backingLock| is its current lock hash.
+	backing     manifest
+	backingLock addr
+
+	persister *fsTablePersister
+
+	lock sync.RWMutex
+}
+
+var _ tablePersister = &chunkJournal{}
+var _ manifest = &chunkJournal{}
+var _ manifestGCGenUpdater = &chunkJournal{}
+
+// ChunkJournalExists returns true if the database directory |dir| has a chunk journal.
+func ChunkJournalExists(dir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, chunkJournalName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newChunkJournal opens the chunk journal of |dir|, or returns the journal of
+// |dir| already open in this process.
+func newChunkJournal(ctx context.Context, dir string, m manifest, p *fsTablePersister) (j *chunkJournal, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	openJournals.mu.Lock()
+	defer openJournals.mu.Unlock()
+	if j, ok := openJournals.journals[dir]; ok {
+		j.refs++
+		return j, nil
+	}
+
+	flock, err := lockJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = flock.Unlock()
+		}
+	}()
+
+	path := filepath.Join(dir, chunkJournalName)
+	wr, root, ok, err := openJournalWriter(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, contents, err := m.ParseIfExists(ctx, &Stats{}, nil)
+	if err != nil {
+		_ = wr.Close()
+		return nil, err
+	}
+
+	j = &chunkJournal{
+		wr:          wr,
+		dir:         dir,
+		path:        path,
+		flock:       flock,
+		refs:        1,
+		contents:    contents,
+		exists:      exists,
+		backing:     m,
+		backingLock: contents.lock,
+		persister:   p,
+	}
+
+	if exists && ok {
+		j.contents.root = hash.Hash(root)
+		j.contents.lock = generateLockHash(j.contents.root, j.contents.specs, j.contents.appendix)
+	}
+
+	if wr.size() > journalCompactionSize {
+		if err = j.compact(ctx); err != nil {
+			_ = wr.Close()
+			return nil, err
+		}
+	}
+
+	openJournals.journals[dir] = j
+	return j, nil
+}
+
+// lockJournal takes the exclusive lock of the chunk journal in |dir|.
+func lockJournal(dir string) (*fslock.Lock, error) {
+	flock := fslock.New(filepath.Join(dir, chunkJournalLockName))
+	err := flock.TryLock()
+	if err == fslock.ErrLocked {
+		return nil, ErrJournalLocked
+	} else if err != nil {
+		return nil, err
+	}
+	return flock, nil
+}
+
+// Persist implements tablePersister. It appends the chunks of |mt| that aren't
+// already in |haver| to the journal and returns the journal's chunkSource.
+func (j *chunkJournal) Persist(ctx context.Context, mt *memTable, haver chunkReader, stats *Stats) (chunkSource, error) {
+	if haver != nil {
+		sort.Sort(hasRecordByPrefix(mt.order)) // hasMany() requires addresses to be sorted.
+		if _, err := haver.hasMany(mt.order); err != nil {
+			return nil, err
+		}
+		sort.Sort(hasRecordByOrder(mt.order)) // restore "insertion" order for write
+	}
+
+	var count uint64
+	for _, rec := range mt.order {
+		if rec.has {
+			continue
+		}
+		c := chunks.NewChunkWithHash(hash.Hash(*rec.a), mt.chunks[*rec.a])
+		if err := j.wr.writeCompressedChunk(ChunkToCompressedChunk(c)); err != nil {
+			return nil, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		return emptyChunkSource{}, nil
+	}
+	stats.ChunksPerPersist.Sample(count)
+
+	return journalChunkSource{wr: j.wr}, nil
+}
+
+// ConjoinAll implements tablePersister. The journal is never conjoined, see conjoinTables.
+func (j *chunkJournal) ConjoinAll(ctx context.Context, sources chunkSources, stats *Stats) (chunkSource, error) {
+	return j.persister.ConjoinAll(ctx, sources, stats)
+}
+
+// Open implements tablePersister.
+func (j *chunkJournal) Open(ctx context.Context, name addr, chunkCount uint32, stats *Stats) (chunkSource, error) {
+	if name == journalAddr {
+		return journalChunkSource{wr: j.wr}, nil
+	}
+	return j.persister.Open(ctx, name, chunkCount, stats)
+}
+
+// PruneTableFiles implements tablePersister. The journal file is never pruned,
+// but it is truncated once garbage collection has removed it from the manifest.
+func (j *chunkJournal) PruneTableFiles(ctx context.Context, contents manifestContents) error {
+	if err := j.truncateUnreferenced(); err != nil {
+		return err
+	}
+	specs := append([]tableSpec{{name: journalAddr}}, contents.specs...)
+	contents.specs = specs
+	return j.persister.PruneTableFiles(ctx, contents)
+}
+
+// RemoveTableFile implements tableFileRemover. The journal file itself is
+// never removed.
+func (j *chunkJournal) RemoveTableFile(ctx context.Context, name addr) error {
+	if name == journalAddr {
+		return nil
+	}
+	return j.persister.RemoveTableFile(ctx, name)
+}
+
+// truncateUnreferenced truncates the journal if it holds chunks and is no
+// longer referenced by the manifest.
+func (j *chunkJournal) truncateUnreferenced() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if !j.exists || containsJournal(j.contents.specs) || j.wr.count() == 0 {
+		return nil
+	}
+	if err := j.wr.truncate(); err != nil {
+		return err
+	}
+	return j.wr.writeRootHash(addr(j.contents.root))
+}
+
+// Name implements manifest.
+func (j *chunkJournal) Name() string {
+	return j.backing.Name()
+}
+
+// ParseIfExists implements manifest.
+func (j *chunkJournal) ParseIfExists(ctx context.Context, stats *Stats, readHook func() error) (bool, manifestContents, error) {
+	if readHook != nil {
+		if err := readHook(); err != nil {
+			return false, manifestContents{}, err
+		}
+	}
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return j.exists, j.contents, nil
+}
+
+// Update implements manifest. If the set of table files is unchanged, only
+// the new root hash is appended to the journal. Otherwise the backing manifest
+// is rewritten first.
+func (j *chunkJournal) Update(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.contents.lock != lastLock {
+		return j.contents, nil // optimistic lock failure
+	}
+	if writeHook != nil {
+		if err := writeHook(); err != nil {
+			return manifestContents{}, err
+		}
+	}
+
+	if !j.exists || !sameTableFiles(j.contents, newContents) {
+		if err := j.updateBacking(ctx, newContents, stats, j.backing.Update); err != nil {
+			return manifestContents{}, err
+		}
+	}
+
+	if err := j.wr.writeRootHash(addr(newContents.root)); err != nil {
+		return manifestContents{}, err
+	}
+	j.contents, j.exists = newContents, true
+
+	return newContents, nil
+}
+
+// UpdateGCGen implements manifestGCGenUpdater. Garbage collection copies every
+// live chunk into new table files, so once the backing manifest no longer
+// references the journal, the journal is truncated by PruneTableFiles. It is
+// not truncated here, as reads that started before the update may still be
+// reading chunks from it.
+func (j *chunkJournal) UpdateGCGen(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error) {
+	updater, ok := j.backing.(manifestGCGenUpdater)
+	if !ok {
+		return manifestContents{}, errors.New("manifest does not support updating gc gen")
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.contents.lock != lastLock {
+		return manifestContents{}, errors.New("manifest was modified during garbage collection")
+	}
+	if writeHook != nil {
+		if err := writeHook(); err != nil {
+			return manifestContents{}, err
+		}
+	}
+
+	// the backing manifest must be at the current root before its gc generation can change
+	if j.backingLock != j.contents.lock {
+		if err := j.updateBacking(ctx, j.contents, stats, j.backing.Update); err != nil {
+			return manifestContents{}, err
+		}
+	}
+	if err := j.updateBacking(ctx, newContents, stats, updater.UpdateGCGen); err != nil {
+		return manifestContents{}, err
+	}
+
+	if err := j.wr.writeRootHash(addr(newContents.root)); err != nil {
+		return manifestContents{}, err
+	}
+	j.contents, j.exists = newContents, true
+
+	return newContents, nil
+}
+
+type manifestUpdateFunc func(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error)
+
+// updateBacking writes |newContents| to the backing manifest with |update|.
+// callers must hold |j.lock|.
+func (j *chunkJournal) updateBacking(ctx context.Context, newContents manifestContents, stats *Stats, update manifestUpdateFunc) error {
+	upstream, err := update(ctx, j.backingLock, newContents, stats, nil)
+	if err != nil {
+		return err
+	}
+	if upstream.lock != newContents.lock {
+		return errors.New("chunk journal manifest was modified by another process")
+	}
+	j.backingLock = upstream.lock
+	return nil
+}
+
+// compact writes every chunk in the journal into a new table file, replaces
+// the journal with it in the backing manifest, and truncates the journal.
+func (j *chunkJournal) compact(ctx context.Context) error {
+	if !j.exists || !containsJournal(j.contents.specs) {
+		return nil
+	}
+
+	gcc, err := newGarbageCollectionCopier()
+	if err != nil {
+		return err
+	}
+	err = j.wr.iterate(ctx, func(cc CompressedChunk) error {
+		return gcc.addChunk(ctx, cc)
+	})
+	if err != nil {
+		return err
+	}
+	compacted, err := gcc.copyTablesToDir(ctx, j.persister.dir)
+	if err != nil {
+		return err
+	}
+
+	specs := make([]tableSpec, 0, len(j.contents.specs)+len(compacted))
+	for _, s := range j.contents.specs {
+		if s.name != journalAddr {
+			specs = append(specs, s)
+		}
+	}
+	specs = append(specs, compacted...)
+
+	newContents := j.contents
+	newContents.specs = specs
+	newContents.lock = generateLockHash(newContents.root, specs, newContents.appendix)
+
+	if err = j.updateBacking(ctx, newContents, &Stats{}, j.backing.Update); err != nil {
+		return err
+	}
+	if err = j.wr.truncate(); err != nil {
+		return err
+	}
+	if err = j.wr.writeRootHash(addr(newContents.root)); err != nil {
+		return err
+	}
+	j.contents = newContents
+
+	return nil
+}
+
+// Close flushes and closes the journal file, and releases the lock of the
+// journal, once every store using the journal has closed it.
+func (j *chunkJournal) Close() error {
+	openJournals.mu.Lock()
+	defer openJournals.mu.Unlock()
+	if j.refs == 0 {
+		return nil
+	}
+	j.refs--
+	if j.refs > 0 {
+		return nil
+	}
+	delete(openJournals.journals, j.dir)
+
+	err := j.wr.Close()
+	if uerr := j.flock.Unlock(); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// RemoveChunkJournal turns the database in |dir| back into one without a
+// chunk journal. The chunks in the journal are written to a table file, the
+// latest root hash is written to the manifest, and the journal is deleted.
+func RemoveChunkJournal(ctx context.Context, dir string, q MemoryQuotaProvider) (err error) {
+	cacheOnce.Do(makeGlobalCaches)
+	m, err := getFileManifest(ctx, dir)
+	if err != nil {
+		return err
+	}
+	p := newFSTablePersister(dir, globalFDCache, q)
+	j, err := newChunkJournal(ctx, dir, m, p.(*fsTablePersister))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := j.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	openJournals.mu.Lock()
+	shared := j.refs > 1
+	openJournals.mu.Unlock()
+	if shared {
+		return errors.New("the chunk journal of the database is in use and can't be removed")
+	}
+
+	if err = j.compact(ctx); err != nil {
+		return err
+	}
+	// the manifest may not have the latest root hash if the journal was
+	// already removed from it by garbage collection
+	if j.exists && j.backingLock != j.contents.lock {
+		if err = j.updateBacking(ctx, j.contents, &Stats{}, j.backing.Update); err != nil {
+			return err
+		}
+	}
+
+	// the lock is released by Close, once the journal is gone
+	if err = j.wr.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.path)
+}
+
+// sameTableFiles returns true if |a| and |b| reference the same table files,
+// regardless of order or chunk counts, and agree on everything but the root.
+func sameTableFiles(a, b manifestContents) bool {
+	if a.nbfVers != b.nbfVers || a.gcGen != b.gcGen {
+		return false
+	}
+	return sameSpecSet(a.specs, b.specs) && sameSpecSet(a.appendix, b.appendix)
+}
+
+func sameSpecSet(a, b []tableSpec) bool {
+	as, bs := toSpecSet(a), toSpecSet(b)
+	if len(as) != len(bs) {
+		return false
+	}
+	for name := range as {
+		if _, ok := bs[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsJournal(specs []tableSpec) bool {
+	for _, s := range specs {
+		if s.name == journalAddr {
+			return true
+		}
+	}
+	return false
+}
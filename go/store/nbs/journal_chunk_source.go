@@ -0,0 +1,238 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+var errJournalIndex = errors.New("the chunk journal does not have a table index")
+
+// journalChunkSource is a chunkSource for the chunks in a chunk journal. Every
+// journalChunkSource of a store shares the store's journalWriter, so it always
+// reflects the current contents of the journal.
+type journalChunkSource struct {
+	wr *journalWriter
+}
+
+var _ chunkSource = journalChunkSource{}
+
+func (s journalChunkSource) has(h addr) (bool, error) {
+	return s.wr.hasAddr(h), nil
+}
+
+func (s journalChunkSource) hasMany(addrs []hasRecord) (bool, error) {
+	var remaining bool
+	for i := range addrs {
+		if addrs[i].has {
+			continue
+		}
+		if s.wr.hasAddr(*addrs[i].a) {
+			addrs[i].has = true
+		} else {
+			remaining = true
+		}
+	}
+	return remaining, nil
+}
+
+func (s journalChunkSource) get(ctx context.Context, h addr, stats *Stats) ([]byte, error) {
+	cc, ok, err := s.wr.getCompressedChunk(h)
+	if err != nil || !ok {
+		return nil, err
+	}
+	ch, err := cc.ToChunk()
+	if err != nil {
+		return nil, err
+	}
+	return ch.Data(), nil
+}
+
+func (s journalChunkSource) getMany(ctx context.Context, eg *errgroup.Group, reqs []getRecord, found func(context.Context, *chunks.Chunk), stats *Stats) (bool, error) {
+	var remaining bool
+	for i := range reqs {
+		if reqs[i].found {
+			continue
+		}
+		data, err := s.get(ctx, *reqs[i].a, stats)
+		if err != nil {
+			return false, err
+		}
+		if data == nil {
+			remaining = true
+			continue
+		}
+		reqs[i].found = true
+		ch := chunks.NewChunkWithHash(hash.Hash(*reqs[i].a), data)
+		found(ctx, &ch)
+	}
+	return remaining, nil
+}
+
+func (s journalChunkSource) getManyCompressed(ctx context.Context, eg *errgroup.Group, reqs []getRecord, found func(context.Context, CompressedChunk), stats *Stats) (bool, error) {
+	var remaining bool
+	for i := range reqs {
+		if reqs[i].found {
+			continue
+		}
+		cc, ok, err := s.wr.getCompressedChunk(*reqs[i].a)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			remaining = true
+			continue
+		}
+		reqs[i].found = true
+		found(ctx, cc)
+	}
+	return remaining, nil
+}
+
+// extract sends every chunk in the journal to |chunks|, in the order they were written.
+func (s journalChunkSource) extract(ctx context.Context, chunks chan<- extractRecord) error {
+	var ors offsetRecSlice
+	s.wr.lock.RLock()
+	for a, r := range s.wr.ranges {
+		a := a
+		ors = append(ors, offsetRec{a: &a, offset: r.Offset, length: r.Length})
+	}
+	s.wr.lock.RUnlock()
+	sort.Sort(ors)
+
+	for _, or := range ors {
+		cc, ok, err := s.wr.getCompressedChunk(*or.a)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue // the journal was truncated
+		}
+		ch, err := cc.ToChunk()
+		if err != nil {
+			return err
+		}
+		chunks <- extractRecord{a: *or.a, data: ch.Data()}
+	}
+	return nil
+}
+
+func (s journalChunkSource) count() (uint32, error) {
+	return s.wr.count(), nil
+}
+
+func (s journalChunkSource) uncompressedLen() (uint64, error) {
+	return s.wr.uncompressedSize(), nil
+}
+
+func (s journalChunkSource) hash() (addr, error) {
+	return journalAddr, nil
+}
+
+// calcReads returns the number of chunks of |reqs| in the journal, as each one is read separately.
+func (s journalChunkSource) calcReads(reqs []getRecord, blockSize uint64) (reads int, remaining bool, err error) {
+	for _, req := range reqs {
+		if req.found {
+			continue
+		}
+		if s.wr.hasAddr(*req.a) {
+			reads++
+		} else {
+			remaining = true
+		}
+	}
+	return
+}
+
+// reader returns a reader over the raw journal file. The journal is not in the
+// table file format; use newJournalTableFile to export its chunks as a table file.
+func (s journalChunkSource) reader(context.Context) (io.Reader, error) {
+	return s.wr.reader(), nil
+}
+
+func (s journalChunkSource) size() (uint64, error) {
+	return uint64(s.wr.size()), nil
+}
+
+func (s journalChunkSource) index() (tableIndex, error) {
+	return nil, errJournalIndex
+}
+
+// Clone returns |s|. The journalWriter is owned by the chunkJournal, not by its chunk sources.
+func (s journalChunkSource) Clone() (chunkSource, error) {
+	return s, nil
+}
+
+func (s journalChunkSource) Close() error {
+	return nil
+}
+
+// asJournalChunkSource returns the journalChunkSource backing |cs|, if there is one. Sources
+// persisted during the lifetime of a store are wrapped in a persistingChunkSource.
+func asJournalChunkSource(cs chunkSource) (journalChunkSource, bool) {
+	if pcs, ok := cs.(*persistingChunkSource); ok {
+		if err := pcs.wait(); err != nil {
+			return journalChunkSource{}, false
+		}
+		cs = pcs.cs
+	}
+	jcs, ok := cs.(journalChunkSource)
+	return jcs, ok
+}
+
+// newJournalTableFile writes the chunks of the journal into a new table file
+// in the temp directory. Table files are how chunks are shipped between stores,
+// e.g. when pushing to a remote, and a raw journal is only readable by a store
+// that is configured to use it.
+func newJournalTableFile(ctx context.Context, s journalChunkSource) (tableFile, error) {
+	tw, err := NewCmpChunkTableWriter("")
+	if err != nil {
+		return tableFile{}, err
+	}
+
+	err = s.wr.iterate(ctx, func(cc CompressedChunk) error {
+		return tw.AddCmpChunk(cc)
+	})
+	if err != nil {
+		return tableFile{}, err
+	}
+
+	name, err := tw.Finish()
+	if err != nil {
+		return tableFile{}, err
+	}
+	a, err := parseAddr(name)
+	if err != nil {
+		return tableFile{}, err
+	}
+
+	return tableFile{
+		info: tableSpec{name: a, chunkCount: uint32(tw.ChunkCount())},
+		open: func(ctx context.Context) (io.ReadCloser, uint64, error) {
+			rd, err := tw.Reader()
+			if err != nil {
+				return nil, 0, err
+			}
+			return rd, tw.ContentLength(), nil
+		},
+	}, nil
+}
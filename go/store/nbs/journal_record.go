@@ -0,0 +1,208 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// journalRec is a record in a chunk journal. It's serialized as:
+//
+//	|-- uint32 --|- uint8 -|-- 20 bytes --|-- variable --|-- uint32 --|
+//	|   length   |  kind   |   address    |   payload    |  checksum  |
+//
+// |length| is the length of the entire record, including itself and the checksum.
+// |checksum| is a crc32c of every byte of the record that precedes it.
+// For chunk records, |address| is the chunk address and |payload| is the
+// compressed chunk, stored exactly as it is in a table file (snappy data
+// followed by its own crc). For root hash records, |address| is the new root
+// hash of the store and |payload| is empty.
+type journalRec struct {
+	length   uint32
+	kind     journalRecKind
+	address  addr
+	payload  []byte
+	checksum uint32
+}
+
+type journalRecKind uint8
+
+const (
+	unknownJournalRecKind  journalRecKind = 0
+	rootHashJournalRecKind journalRecKind = 1
+	chunkJournalRecKind    journalRecKind = 2
+)
+
+const (
+	journalRecLenSz      = uint32Size
+	journalRecKindSz     = 1
+	journalRecAddrSz     = addrSize
+	journalRecChecksumSz = checksumSize
+
+	// journalRecHeaderSz is the size of the fields preceding the payload of a record
+	journalRecHeaderSz = journalRecLenSz + journalRecKindSz + journalRecAddrSz
+
+	// rootHashRecordSize is the size of a root hash record, which has no payload
+	rootHashRecordSize = journalRecHeaderSz + journalRecChecksumSz
+
+	// journalRecMaxSz bounds the length of a single record. A length larger
+	// than this marks the end of the valid portion of a journal.
+	journalRecMaxSz = 128 * 1024 * 1024
+)
+
+func (k journalRecKind) String() string {
+	switch k {
+	case rootHashJournalRecKind:
+		return "root"
+	case chunkJournalRecKind:
+		return "chunk"
+	default:
+		return "unknown"
+	}
+}
+
+// chunkRecordSize returns the size of the journal record that |c| is written as.
+func chunkRecordSize(c CompressedChunk) uint32 {
+	return uint32(journalRecHeaderSz + len(c.FullCompressedChunk) + journalRecChecksumSz)
+}
+
+// writeChunkRecord writes a chunk record for |c| into |buf|, which must be at
+// least chunkRecordSize(c) bytes long, and returns the number of bytes written.
+func writeChunkRecord(buf []byte, c CompressedChunk) (n uint32) {
+	l := chunkRecordSize(c)
+	n = writeRecordHeader(buf, l, chunkJournalRecKind, addr(c.H))
+	n += uint32(copy(buf[n:], c.FullCompressedChunk))
+	writeRecordChecksum(buf[:l])
+	return l
+}
+
+// writeRootHashRecord writes a root hash record for |root| into |buf|, which must
+// be at least rootHashRecordSize bytes long, and returns the number of bytes written.
+func writeRootHashRecord(buf []byte, root addr) (n uint32) {
+	writeRecordHeader(buf, rootHashRecordSize, rootHashJournalRecKind, root)
+	writeRecordChecksum(buf[:rootHashRecordSize])
+	return rootHashRecordSize
+}
+
+func writeRecordHeader(buf []byte, length uint32, kind journalRecKind, a addr) uint32 {
+	binary.BigEndian.PutUint32(buf, length)
+	buf[journalRecLenSz] = byte(kind)
+	copy(buf[journalRecLenSz+journalRecKindSz:], a[:])
+	return journalRecHeaderSz
+}
+
+func writeRecordChecksum(rec []byte) {
+	o := len(rec) - journalRecChecksumSz
+	binary.BigEndian.PutUint32(rec[o:], crc(rec[:o]))
+}
+
+// readJournalRecord parses the record in |buf|. It does not validate the checksum.
+func readJournalRecord(buf []byte) (rec journalRec, err error) {
+	if len(buf) < rootHashRecordSize {
+		return rec, fmt.Errorf("journal record of %d bytes is too short", len(buf))
+	}
+	rec.length = binary.BigEndian.Uint32(buf)
+	if int(rec.length) != len(buf) {
+		return rec, fmt.Errorf("journal record length %d does not match buffer of %d bytes", rec.length, len(buf))
+	}
+	rec.kind = journalRecKind(buf[journalRecLenSz])
+	copy(rec.address[:], buf[journalRecLenSz+journalRecKindSz:journalRecHeaderSz])
+	rec.payload = buf[journalRecHeaderSz : len(buf)-journalRecChecksumSz]
+	rec.checksum = binary.BigEndian.Uint32(buf[len(buf)-journalRecChecksumSz:])
+
+	switch rec.kind {
+	case rootHashJournalRecKind:
+		if len(rec.payload) != 0 {
+			return rec, errors.New("root hash journal record has a payload")
+		}
+	case chunkJournalRecKind:
+		if len(rec.payload) <= checksumSize {
+			return rec, errors.New("chunk journal record is missing its chunk")
+		}
+	default:
+		return rec, fmt.Errorf("unknown journal record kind %d", rec.kind)
+	}
+	return rec, nil
+}
+
+// validateJournalRecord returns true if the checksum of the record in |buf| matches its contents.
+func validateJournalRecord(buf []byte) bool {
+	if len(buf) < rootHashRecordSize {
+		return false
+	}
+	o := len(buf) - journalRecChecksumSz
+	return crc(buf[:o]) == binary.BigEndian.Uint32(buf[o:])
+}
+
+// chunk returns the compressed chunk held by a chunk record.
+func (rec journalRec) chunk() (CompressedChunk, error) {
+	return NewCompressedChunk(hash.Hash(rec.address), rec.payload)
+}
+
+// processJournalRecords reads every valid record from |r| in order, calling |cb| with each
+// record and the offset at which it starts. Reading stops at the end of the stream, or at
+// the first record that is incomplete or fails validation, which is what a write torn by
+// a crash leaves behind. It returns the offset of the end of the last valid record.
+func processJournalRecords(ctx context.Context, r io.Reader, cb func(o int64, rec journalRec) error) (int64, error) {
+	var off int64
+	rdr := bufio.NewReaderSize(r, 1024*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		lenBuf, err := rdr.Peek(journalRecLenSz)
+		if len(lenBuf) < journalRecLenSz {
+			if err == io.EOF {
+				break // end of journal, or torn write
+			}
+			return 0, err
+		}
+
+		l := binary.BigEndian.Uint32(lenBuf)
+		if l < rootHashRecordSize || l > journalRecMaxSz {
+			break // torn write or garbage
+		}
+
+		buf := make([]byte, l)
+		if _, err = io.ReadFull(rdr, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break // torn write
+		} else if err != nil {
+			return 0, err
+		}
+
+		if !validateJournalRecord(buf) {
+			break // torn write or garbage
+		}
+
+		rec, err := readJournalRecord(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		if err = cb(off, rec); err != nil {
+			return 0, err
+		}
+		off += int64(l)
+	}
+	return off, nil
+}
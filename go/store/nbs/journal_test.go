@@ -0,0 +1,395 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/types"
+	"github.com/dolthub/dolt/go/store/util/tempfiles"
+)
+
+func makeTestJournalingStore(t *testing.T) (st *NomsBlockStore, nomsDir string) {
+	nomsDir = filepath.Join(tempfiles.MovableTempFileProvider.GetTempDir(), "noms_"+uuid.New().String()[:8])
+	err := os.MkdirAll(nomsDir, os.ModePerm)
+	require.NoError(t, err)
+	return openTestJournalingStore(t, nomsDir), nomsDir
+}
+
+func openTestJournalingStore(t *testing.T, nomsDir string) *NomsBlockStore {
+	st, err := NewLocalJournalingStore(context.Background(), types.Format_Default.VersionString(), nomsDir, defaultMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	return st
+}
+
+func putAndCommit(t *testing.T, st *NomsBlockStore, chks map[hash.Hash]chunks.Chunk) hash.Hash {
+	ctx := context.Background()
+	var root hash.Hash
+	for h, c := range chks {
+		require.NoError(t, st.Put(ctx, c))
+		root = h
+	}
+	last, err := st.Root(ctx)
+	require.NoError(t, err)
+	ok, err := st.Commit(ctx, root, last)
+	require.NoError(t, err)
+	require.True(t, ok)
+	return root
+}
+
+func tableFilesInDir(t *testing.T, dir string) (names []string) {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		if len(e.Name()) == 32 && e.Name() != chunkJournalName {
+			names = append(names, e.Name())
+		}
+	}
+	return
+}
+
+func TestJournalRecords(t *testing.T) {
+	ctx := context.Background()
+	chks := makeChunkSet(8, 64)
+
+	var buf []byte
+	var expected []CompressedChunk
+	for _, c := range chks {
+		cc := ChunkToCompressedChunk(c)
+		rec := make([]byte, chunkRecordSize(cc))
+		writeChunkRecord(rec, cc)
+		buf = append(buf, rec...)
+		expected = append(expected, cc)
+	}
+	root := computeAddr([]byte("root"))
+	rec := make([]byte, rootHashRecordSize)
+	writeRootHashRecord(rec, root)
+	buf = append(buf, rec...)
+
+	validLen := int64(len(buf))
+	// a torn write at the end of the journal
+	buf = append(buf, rec[:rootHashRecordSize/2]...)
+
+	var actual []CompressedChunk
+	var roots []addr
+	end, err := processJournalRecords(ctx, bytes.NewReader(buf), func(o int64, rec journalRec) error {
+		switch rec.kind {
+		case chunkJournalRecKind:
+			cc, err := rec.chunk()
+			require.NoError(t, err)
+			actual = append(actual, cc)
+		case rootHashJournalRecKind:
+			roots = append(roots, rec.address)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, validLen, end)
+	assert.Equal(t, []addr{root}, roots)
+	require.Equal(t, len(expected), len(actual))
+	for i := range expected {
+		assert.Equal(t, expected[i].H, actual[i].H)
+		assert.Equal(t, expected[i].FullCompressedChunk, actual[i].FullCompressedChunk)
+	}
+
+	// a corrupt record ends the journal
+	buf[validLen-1] ^= 0xff
+	end, err = processJournalRecords(ctx, bytes.NewReader(buf), func(o int64, rec journalRec) error {
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, validLen-rootHashRecordSize, end)
+}
+
+func TestChunkJournalCommitAndReopen(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+
+	first := makeChunkSet(16, 64)
+	putAndCommit(t, st, first)
+	manifest, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	require.NoError(t, err)
+
+	second := makeChunkSet(16, 64)
+	root := putAndCommit(t, st, second)
+
+	// new chunks and roots go to the journal, not to new table files or the manifest
+	assert.Empty(t, tableFilesInDir(t, dir))
+	after, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	require.NoError(t, err)
+	assert.Equal(t, manifest, after)
+	require.NoError(t, st.Close())
+
+	st = openTestJournalingStore(t, dir)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for _, chks := range []map[hash.Hash]chunks.Chunk{first, second} {
+		for h, c := range chks {
+			out, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, c, out)
+		}
+	}
+	cnt, err := st.Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(32), cnt)
+}
+
+func TestChunkJournalTornWrite(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+	chks := makeChunkSet(16, 64)
+	root := putAndCommit(t, st, chks)
+	require.NoError(t, st.Close())
+
+	path := filepath.Join(dir, chunkJournalName)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, byte(chunkJournalRecKind), 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	st = openTestJournalingStore(t, dir)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for h, c := range chks {
+		out, err := st.Get(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, c, out)
+	}
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), after.Size())
+}
+
+func TestChunkJournalCompaction(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+	chks := makeChunkSet(16, 64)
+	root := putAndCommit(t, st, chks)
+	require.NoError(t, st.Close())
+
+	m, err := getFileManifest(ctx, dir)
+	require.NoError(t, err)
+	p := newFSTablePersister(dir, globalFDCache, NewUnlimitedMemQuotaProvider())
+	j, err := newChunkJournal(ctx, dir, m, p.(*fsTablePersister))
+	require.NoError(t, err)
+	require.NoError(t, j.compact(ctx))
+	require.NoError(t, j.Close())
+
+	assert.Len(t, tableFilesInDir(t, dir), 1)
+	_, contents, err := m.ParseIfExists(ctx, &Stats{}, nil)
+	require.NoError(t, err)
+	assert.False(t, containsJournal(contents.specs))
+	assert.Equal(t, root, contents.root)
+
+	st = openTestJournalingStore(t, dir)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for h, c := range chks {
+		out, err := st.Get(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, c, out)
+	}
+}
+
+func TestChunkJournalSingleWriter(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	putAndCommit(t, st, makeChunkSet(16, 64))
+
+	// another process can't open the journal while it's in use
+	_, err := lockJournal(dir)
+	assert.ErrorIs(t, err, ErrJournalLocked)
+
+	// another store of this process shares it, and sees the roots the first one writes
+	other := openTestJournalingStore(t, dir)
+	stale, err := other.Root(ctx)
+	require.NoError(t, err)
+	root := putAndCommit(t, st, makeChunkSet(16, 64))
+
+	chks := makeChunkSet(16, 64)
+	var h hash.Hash
+	for h = range chks {
+		require.NoError(t, other.Put(ctx, chks[h]))
+	}
+	ok, err := other.Commit(ctx, h, stale)
+	require.NoError(t, err)
+	assert.False(t, ok, "commit from a stale root must fail")
+	actual, err := other.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	ok, err = other.Commit(ctx, h, root)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// the journal is held until both stores are closed
+	require.Error(t, RemoveChunkJournal(ctx, dir, NewUnlimitedMemQuotaProvider()))
+	require.NoError(t, other.Close())
+	_, err = lockJournal(dir)
+	assert.ErrorIs(t, err, ErrJournalLocked)
+
+	require.NoError(t, st.Rebase(ctx))
+	actual, err = st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, h, actual)
+}
+
+func TestRemoveChunkJournal(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+	first := makeChunkSet(16, 64)
+	firstRoot := putAndCommit(t, st, first)
+
+	// garbage collection removes the journal from the manifest, the roots written after it are only in the journal
+	keepChan := make(chan []hash.Hash, len(first))
+	for h := range first {
+		keepChan <- []hash.Hash{h}
+	}
+	close(keepChan)
+	require.NoError(t, st.MarkAndSweepChunks(ctx, firstRoot, keepChan, nil))
+	second := makeChunkSet(16, 64)
+	root := putAndCommit(t, st, second)
+	require.NoError(t, st.Close())
+
+	require.NoError(t, RemoveChunkJournal(ctx, dir, NewUnlimitedMemQuotaProvider()))
+	exists, err := ChunkJournalExists(dir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), dir, defaultMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for _, chks := range []map[hash.Hash]chunks.Chunk{first, second} {
+		for h, c := range chks {
+			out, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, c, out)
+		}
+	}
+}
+
+func TestChunkJournalSources(t *testing.T) {
+	ctx := context.Background()
+	st, _ := makeTestJournalingStore(t)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	chks := makeChunkSet(16, 64)
+	putAndCommit(t, st, chks)
+
+	_, sources, _, err := st.Sources(ctx)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.NotEqual(t, chunkJournalName, sources[0].FileID())
+	assert.Equal(t, len(chks), sources[0].NumChunks())
+
+	rd, _, err := sources[0].Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	require.NoError(t, rd.Close())
+
+	idx, err := parseTableIndexByCopy(data, &noopQuotaProvider{})
+	require.NoError(t, err)
+	tr, err := newTableReader(idx, tableReaderAtFromBytes(data), fileBlockSize)
+	require.NoError(t, err)
+	defer tr.Close()
+	for h, c := range chks {
+		out, err := tr.get(ctx, addr(h), &Stats{})
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), out)
+	}
+}
+
+func TestChunkJournalGC(t *testing.T) {
+	ctx := context.Background()
+	st, dir := makeTestJournalingStore(t)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+
+	keepers := makeChunkSet(64, 64)
+	tossers := makeChunkSet(64, 64)
+	putAndCommit(t, st, tossers)
+	root := putAndCommit(t, st, keepers)
+
+	keepChan := make(chan []hash.Hash, 16)
+	var msErr error
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		msErr = st.MarkAndSweepChunks(ctx, root, keepChan, nil)
+		wg.Done()
+	}()
+	for h := range keepers {
+		keepChan <- []hash.Hash{h}
+	}
+	close(keepChan)
+	wg.Wait()
+	require.NoError(t, msErr)
+
+	for h, c := range keepers {
+		out, err := st.Get(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, c, out)
+	}
+	for h := range tossers {
+		out, err := st.Get(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, chunks.EmptyChunk, out)
+	}
+
+	// the journal only holds the current root
+	info, err := os.Stat(filepath.Join(dir, chunkJournalName))
+	require.NoError(t, err)
+	assert.Equal(t, int64(rootHashRecordSize), info.Size())
+	assert.Len(t, tableFilesInDir(t, dir), 1)
+}
@@ -0,0 +1,349 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/golang/snappy"
+
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const journalWriterBuffSize = 1024 * 1024
+
+// journalWriter is an append-only writer for a chunk journal file. Records are
+// buffered in memory and written to the file when the buffer fills up or when
+// a root hash record is written, at which point the file is also synced. It
+// keeps an in-memory index of the location of every chunk in the journal.
+type journalWriter struct {
+	buf []byte
+	// off is the file offset of the beginning of |buf|, i.e. the length of
+	// the journal that has been written to |file|.
+	off  int64
+	file *os.File
+	path string
+
+	// ranges maps chunk addresses to the location of the compressed
+	// chunk within the journal.
+	ranges  map[addr]Range
+	uncmpSz uint64
+
+	// uncommitted holds the addresses of the chunks written since the
+	// last root hash record.
+	uncommitted []addr
+
+	lock sync.RWMutex
+}
+
+// openJournalWriter opens the journal file at |path|, creating it if it
+// doesn't exist, and indexes its contents. It returns the last root hash
+// recorded in the journal, if any. Any incomplete record at the end of the
+// journal is discarded.
+func openJournalWriter(ctx context.Context, path string) (wr *journalWriter, root addr, ok bool, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, addr{}, false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+		}
+	}()
+
+	wr = &journalWriter{
+		buf:    make([]byte, 0, journalWriterBuffSize),
+		file:   f,
+		path:   path,
+		ranges: make(map[addr]Range),
+	}
+
+	end, err := processJournalRecords(ctx, f, func(o int64, rec journalRec) error {
+		switch rec.kind {
+		case chunkJournalRecKind:
+			wr.ranges[rec.address] = Range{
+				Offset: uint64(o) + journalRecHeaderSz,
+				Length: uint32(len(rec.payload)),
+			}
+			wr.uncmpSz += uncompressedChunkSize(rec.payload)
+		case rootHashJournalRecKind:
+			root, ok = rec.address, true
+		default:
+			return fmt.Errorf("unknown journal record kind (%d)", rec.kind)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, addr{}, false, err
+	}
+
+	// drop anything past the last valid record so that new records are
+	// appended to a well-formed journal
+	if err = f.Truncate(end); err != nil {
+		return nil, addr{}, false, err
+	}
+	if _, err = f.Seek(end, io.SeekStart); err != nil {
+		return nil, addr{}, false, err
+	}
+	wr.off = end
+
+	return wr, root, ok, nil
+}
+
+// uncompressedChunkSize returns the size of the chunk held in the table file
+// formatted compressed chunk |cmp|.
+func uncompressedChunkSize(cmp []byte) uint64 {
+	n, err := snappy.DecodedLen(cmp[:len(cmp)-checksumSize])
+	if err != nil {
+		return 0
+	}
+	return uint64(n)
+}
+
+// writeCompressedChunk appends a chunk record for |cc| to the journal. The
+// record is not durable until the next call to writeRootHash.
+func (wr *journalWriter) writeCompressedChunk(cc CompressedChunk) error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	a := addr(cc.H)
+	if _, ok := wr.ranges[a]; ok {
+		return nil
+	}
+
+	l := int(chunkRecordSize(cc))
+	if err := wr.reserve(l); err != nil {
+		return err
+	}
+
+	o := len(wr.buf)
+	wr.buf = wr.buf[:o+l]
+	writeChunkRecord(wr.buf[o:], cc)
+
+	wr.ranges[a] = Range{
+		Offset: uint64(wr.off) + uint64(o) + journalRecHeaderSz,
+		Length: uint32(len(cc.FullCompressedChunk)),
+	}
+	wr.uncmpSz += uncompressedChunkSize(cc.FullCompressedChunk)
+	wr.uncommitted = append(wr.uncommitted, a)
+	return nil
+}
+
+// writeRootHash appends a root hash record for |root| to the journal and
+// syncs the journal to disk, making it and every chunk record written before
+// it durable.
+func (wr *journalWriter) writeRootHash(root addr) error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	if err := wr.reserve(rootHashRecordSize); err != nil {
+		return err
+	}
+
+	o := len(wr.buf)
+	wr.buf = wr.buf[:o+rootHashRecordSize]
+	writeRootHashRecord(wr.buf[o:], root)
+
+	if err := wr.flush(); err != nil {
+		return err
+	}
+	wr.uncommitted = wr.uncommitted[:0]
+	return wr.file.Sync()
+}
+
+// reserve makes room for |n| more bytes in |wr.buf|, flushing it if needed.
+// callers must hold |wr.lock|.
+func (wr *journalWriter) reserve(n int) error {
+	if len(wr.buf)+n <= cap(wr.buf) {
+		return nil
+	}
+	if err := wr.flush(); err != nil {
+		return err
+	}
+	if n > cap(wr.buf) {
+		wr.buf = make([]byte, 0, n)
+	}
+	return nil
+}
+
+// flush writes the contents of |wr.buf| to the journal file.
+// callers must hold |wr.lock|.
+func (wr *journalWriter) flush() error {
+	if len(wr.buf) == 0 {
+		return nil
+	}
+	n, err := wr.file.Write(wr.buf)
+	if err != nil {
+		return err
+	}
+	wr.off += int64(n)
+	wr.buf = wr.buf[:0]
+	return nil
+}
+
+// readAt reads len(p) bytes of the journal starting at |off|, reading
+// from the write buffer if the range has not been flushed yet.
+// callers must hold |wr.lock|.
+func (wr *journalWriter) readAt(p []byte, off int64) error {
+	if off >= wr.off {
+		o := off - wr.off
+		if o+int64(len(p)) > int64(len(wr.buf)) {
+			return errors.New("journal read out of bounds")
+		}
+		copy(p, wr.buf[o:])
+		return nil
+	}
+	_, err := wr.file.ReadAt(p, off)
+	return err
+}
+
+// getCompressedChunk returns the chunk with address |a|, if it's in the journal.
+func (wr *journalWriter) getCompressedChunk(a addr) (CompressedChunk, bool, error) {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return wr.getCompressedChunkLocked(a)
+}
+
+// callers must hold |wr.lock|.
+func (wr *journalWriter) getCompressedChunkLocked(a addr) (CompressedChunk, bool, error) {
+	r, ok := wr.ranges[a]
+	if !ok {
+		return CompressedChunk{}, false, nil
+	}
+	buf := make([]byte, r.Length)
+	if err := wr.readAt(buf, int64(r.Offset)); err != nil {
+		return CompressedChunk{}, false, err
+	}
+	cc, err := NewCompressedChunk(hash.Hash(a), buf)
+	if err != nil {
+		return CompressedChunk{}, false, err
+	}
+	return cc, true, nil
+}
+
+func (wr *journalWriter) hasAddr(a addr) bool {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	_, ok := wr.ranges[a]
+	return ok
+}
+
+// iterate calls |cb| with every chunk in the journal, in no particular order.
+func (wr *journalWriter) iterate(ctx context.Context, cb func(cc CompressedChunk) error) error {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	for a := range wr.ranges {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cc, _, err := wr.getCompressedChunkLocked(a)
+		if err != nil {
+			return err
+		}
+		if err = cb(cc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkRanges returns the location of the chunks of |addrs| that are in the journal.
+func (wr *journalWriter) chunkRanges(addrs []addr) map[addr]Range {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	ranges := make(map[addr]Range)
+	for _, a := range addrs {
+		if r, ok := wr.ranges[a]; ok {
+			ranges[a] = r
+		}
+	}
+	return ranges
+}
+
+// uncommittedAddrs returns the addresses of the chunks written since the last root hash record.
+func (wr *journalWriter) uncommittedAddrs() []addr {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return append([]addr(nil), wr.uncommitted...)
+}
+
+func (wr *journalWriter) count() uint32 {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return uint32(len(wr.ranges))
+}
+
+func (wr *journalWriter) uncompressedSize() uint64 {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return wr.uncmpSz
+}
+
+// size returns the length of the journal, including buffered records.
+func (wr *journalWriter) size() int64 {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return wr.off + int64(len(wr.buf))
+}
+
+// reader returns a reader over the flushed contents of the journal file.
+func (wr *journalWriter) reader() io.Reader {
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+	return io.NewSectionReader(wr.file, 0, wr.off)
+}
+
+// truncate discards every record in the journal.
+func (wr *journalWriter) truncate() error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	if err := wr.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := wr.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := wr.file.Sync(); err != nil {
+		return err
+	}
+	wr.buf = wr.buf[:0]
+	wr.off = 0
+	wr.ranges = make(map[addr]Range)
+	wr.uncmpSz = 0
+	wr.uncommitted = nil
+	return nil
+}
+
+// Close flushes any buffered records and closes the journal file.
+func (wr *journalWriter) Close() error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	if wr.file == nil {
+		return nil
+	}
+	err := wr.flush()
+	if cerr := wr.file.Close(); err == nil {
+		err = cerr
+	}
+	wr.file = nil
+	return err
+}
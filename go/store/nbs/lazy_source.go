@@ -0,0 +1,159 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// LazySourceOpener opens the chunk store a lazy chunk store reads the chunks
+// it doesn't have from.
+type LazySourceOpener func(ctx context.Context) (chunks.ChunkStore, error)
+
+// LazyChunkStore is implemented by chunk stores which can fetch the chunks
+// they don't have from another chunk store on demand.
+type LazyChunkStore interface {
+	// SetLazySource makes the store read the chunks it doesn't have from the
+	// chunk store |open| returns. The source is only opened once a chunk is
+	// missing. Chunks read from the source are persisted to the store, and
+	// are never fetched again.
+	SetLazySource(open LazySourceOpener)
+
+	// IsLazy returns whether the store has a lazy source.
+	IsLazy() bool
+}
+
+// lazySource fetches chunks from a source chunk store and persists them to a
+// local store. The chunks of the source are the history of the local store,
+// they are written to the local store without changing its root.
+type lazySource struct {
+	open LazySourceOpener
+	dest *NomsBlockStore
+
+	mu  sync.Mutex
+	src chunks.ChunkStore
+}
+
+func newLazySource(open LazySourceOpener, dest *NomsBlockStore) *lazySource {
+	return &lazySource{open: open, dest: dest}
+}
+
+// source returns the source chunk store, opening it if it hasn't been opened
+// yet. A failure to open it is not remembered, the next read tries again.
+func (ls *lazySource) source(ctx context.Context) (chunks.ChunkStore, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.src == nil {
+		src, err := ls.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ls.src = src
+	}
+
+	return ls.src, nil
+}
+
+func (ls *lazySource) get(ctx context.Context, h hash.Hash) (chunks.Chunk, error) {
+	c := chunks.EmptyChunk
+	err := ls.getMany(ctx, hash.NewHashSet(h), func(_ context.Context, chunk *chunks.Chunk) {
+		c = *chunk
+	})
+
+	if err != nil {
+		return chunks.EmptyChunk, err
+	}
+
+	return c, nil
+}
+
+// getMany fetches the chunks with |hashes| from the source and persists the
+// ones it finds before handing them to |found|.
+func (ls *lazySource) getMany(ctx context.Context, hashes hash.HashSet, found func(context.Context, *chunks.Chunk)) error {
+	src, err := ls.source(ctx)
+	if err != nil {
+		return err
+	}
+
+	mu := &sync.Mutex{}
+	var fetched []chunks.Chunk
+	err = src.GetMany(ctx, hashes, func(_ context.Context, chunk *chunks.Chunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetched = append(fetched, *chunk)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(fetched) == 0 {
+		return nil
+	}
+
+	for _, c := range fetched {
+		err = ls.dest.Put(ctx, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ls.persist(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range fetched {
+		found(ctx, &fetched[i])
+	}
+
+	return nil
+}
+
+// persist flushes the chunks fetched into the local store to disk.
+func (ls *lazySource) persist(ctx context.Context) error {
+	root, err := ls.dest.Root(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the root of the store fetched chunks are written to is never set, a
+	// failed commit is a concurrent write the fetched chunks go out with.
+	_, err = ls.dest.Commit(ctx, root, root)
+	return err
+}
+
+func (ls *lazySource) has(ctx context.Context, h hash.Hash) (bool, error) {
+	src, err := ls.source(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return src.Has(ctx, h)
+}
+
+func (ls *lazySource) hasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
+	src, err := ls.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return src.HasMany(ctx, hashes)
+}
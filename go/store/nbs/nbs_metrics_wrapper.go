@@ -39,6 +39,10 @@ func NewNBSMetricWrapper(nbs *NomsBlockStore) *NBSMetricWrapper {
 }
 
 var _ TableFileStore = &NBSMetricWrapper{}
+var _ TableFileRewriter = &NBSMetricWrapper{}
+var _ TableFileFormatReporter = &NBSMetricWrapper{}
+var _ ConjoinPolicySetter = &NBSMetricWrapper{}
+var _ ChunkRepairer = &NBSMetricWrapper{}
 var _ chunks.ChunkStoreGarbageCollector = &NBSMetricWrapper{}
 
 // Sources retrieves the current root hash, a list of all the table files,
@@ -75,11 +79,39 @@ func (nbsMW *NBSMetricWrapper) MarkAndSweepChunks(ctx context.Context, last hash
 	return nbsMW.nbs.MarkAndSweepChunks(ctx, last, keepChunks, dest)
 }
 
+func (nbsMW *NBSMetricWrapper) BeginGC(keeper func(hash.Hash) bool) error {
+	return nbsMW.nbs.BeginGC(keeper)
+}
+
+func (nbsMW *NBSMetricWrapper) EndGC() {
+	nbsMW.nbs.EndGC()
+}
+
 // PruneTableFiles deletes old table files that are no longer referenced in the manifest.
 func (nbsMW *NBSMetricWrapper) PruneTableFiles(ctx context.Context) error {
 	return nbsMW.nbs.PruneTableFiles(ctx)
 }
 
+// RewriteTableFiles rewrites the table files that aren't in |format| into it.
+func (nbsMW *NBSMetricWrapper) RewriteTableFiles(ctx context.Context, format TableFileFormat) error {
+	return nbsMW.nbs.RewriteTableFiles(ctx, format)
+}
+
+// TableFileFormatStats returns a summary of the table files of the store for each TableFileFormat in use.
+func (nbsMW *NBSMetricWrapper) TableFileFormatStats() (map[TableFileFormat]TableFileFormatStats, error) {
+	return nbsMW.nbs.TableFileFormatStats()
+}
+
+// SetConjoinPolicy changes the policy the store uses to conjoin its table files.
+func (nbsMW *NBSMetricWrapper) SetConjoinPolicy(policy ConjoinPolicy) error {
+	return nbsMW.nbs.SetConjoinPolicy(policy)
+}
+
+// RepairChunks writes |repaired| to the store in a way that makes reads return them instead of damaged copies.
+func (nbsMW *NBSMetricWrapper) RepairChunks(ctx context.Context, repaired []chunks.Chunk) error {
+	return nbsMW.nbs.RepairChunks(ctx, repaired)
+}
+
 // GetManyCompressed gets the compressed Chunks with |hashes| from the store. On return,
 // |found| will have been fully sent all chunks which have been
 // found. Any non-present chunks will silently be ignored.
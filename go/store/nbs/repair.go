@@ -0,0 +1,315 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+// DamagedTableFile is a table file listed in the manifest of a local store
+// which is missing or can not be opened.
+type DamagedTableFile struct {
+	Name string
+	Err  error
+}
+
+// ChunkRepairer is implemented by chunk stores which can replace missing or
+// corrupt chunks.
+type ChunkRepairer interface {
+	// RepairChunks writes |repaired| to the store in a way that makes reads
+	// return them instead of any damaged copies the store already has. Table
+	// files the store was opened without are dropped from its manifest.
+	RepairChunks(ctx context.Context, repaired []chunks.Chunk) error
+}
+
+// CheckLocalTableFiles opens every table file in the manifest of the local
+// store in |dir| and returns the ones which are missing or can't be read. The
+// chunk journal is not checked, it is validated when the store is opened.
+func CheckLocalTableFiles(ctx context.Context, dir string) ([]DamagedTableFile, error) {
+	cacheOnce.Do(makeGlobalCaches)
+	err := checkDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exists, contents, err := parseIfExists(ctx, dir, nil)
+
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	// table files are opened with a cache of their own, a cached file
+	// descriptor could hide a table file which has been removed.
+	fc := newFDCache(defaultMaxTables)
+	defer fc.Drop()
+
+	q := NewUnlimitedMemQuotaProvider()
+	var damaged []DamagedTableFile
+	for _, spec := range contents.specs {
+		if spec.name == journalAddr {
+			continue
+		}
+
+		err = q.AcquireQuota(ctx, indexMemSize(spec.chunkCount))
+		if err != nil {
+			return nil, err
+		}
+
+		cs, openErr := newFileTableReader(dir, spec.name, spec.chunkCount, q, fc)
+		if openErr != nil {
+			err = q.ReleaseQuota(indexMemSize(spec.chunkCount))
+			if err != nil {
+				return nil, err
+			}
+			damaged = append(damaged, DamagedTableFile{Name: spec.name.String(), Err: openErr})
+			continue
+		}
+
+		err = cs.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return damaged, nil
+}
+
+// NewLocalStoreWithoutTableFiles opens the local store in |dir| as if the
+// table files named in |skip| were not listed in its manifest. The manifest
+// itself only changes when the store is written to, which drops them for good.
+func NewLocalStoreWithoutTableFiles(ctx context.Context, nbfVerStr string, dir string, skip []string, memTableSize uint64, q MemoryQuotaProvider) (*NomsBlockStore, error) {
+	cacheOnce.Do(makeGlobalCaches)
+	err := checkDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[addr]struct{}, len(skip))
+	for _, name := range skip {
+		h, ok := hash.MaybeParse(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid table file name: %s", name)
+		}
+		skipped[addr(h)] = struct{}{}
+	}
+
+	m, err := getFileManifest(ctx, dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := newFSTablePersister(dir, globalFDCache, q)
+
+	useJournal, err := ChunkJournalExists(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !useJournal {
+		mm := makeManifestManager(skippingManifest{m, skipped})
+		return newNomsBlockStore(ctx, nbfVerStr, mm, p, q, inlineConjoiner{defaultMaxTables}, memTableSize)
+	}
+
+	j, err := newChunkJournal(ctx, dir, m, p.(*fsTablePersister))
+
+	if err != nil {
+		return nil, err
+	}
+
+	mm := makeManifestManager(skippingManifest{j, skipped})
+	nbs, err := newNomsBlockStore(ctx, nbfVerStr, mm, j, q, inlineConjoiner{defaultMaxTables}, memTableSize)
+
+	if err != nil {
+		_ = j.Close()
+		return nil, err
+	}
+
+	return nbs, nil
+}
+
+// skippingManifest is a manifest which leaves the table files in |skip| out
+// of the contents it returns.
+type skippingManifest struct {
+	manifest
+	skip map[addr]struct{}
+}
+
+func (sm skippingManifest) ParseIfExists(ctx context.Context, stats *Stats, readHook func() error) (bool, manifestContents, error) {
+	exists, contents, err := sm.manifest.ParseIfExists(ctx, stats, readHook)
+	if err != nil || !exists {
+		return exists, contents, err
+	}
+	return true, sm.withoutSkipped(contents), nil
+}
+
+func (sm skippingManifest) Update(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error) {
+	contents, err := sm.manifest.Update(ctx, lastLock, newContents, stats, writeHook)
+	if err != nil {
+		return contents, err
+	}
+	return sm.withoutSkipped(contents), nil
+}
+
+func (sm skippingManifest) UpdateGCGen(ctx context.Context, lastLock addr, newContents manifestContents, stats *Stats, writeHook func() error) (manifestContents, error) {
+	updater, ok := sm.manifest.(manifestGCGenUpdater)
+	if !ok {
+		return manifestContents{}, errors.New("manifest does not support updating gc gen")
+	}
+
+	contents, err := updater.UpdateGCGen(ctx, lastLock, newContents, stats, writeHook)
+	if err != nil {
+		return contents, err
+	}
+	return sm.withoutSkipped(contents), nil
+}
+
+// withoutSkipped returns |contents| without the skipped table files. The lock
+// is left as it is, so that the next update of the manifest succeeds.
+func (sm skippingManifest) withoutSkipped(contents manifestContents) manifestContents {
+	filter := func(specs []tableSpec) (filtered []tableSpec) {
+		for _, spec := range specs {
+			if _, ok := sm.skip[spec.name]; !ok {
+				filtered = append(filtered, spec)
+			}
+		}
+		return filtered
+	}
+
+	contents.specs = filter(contents.specs)
+	contents.appendix = filter(contents.appendix)
+	return contents
+}
+
+// RepairChunks writes |repaired| to a new table file which is listed ahead of
+// every other table file in the manifest, so that reads find them before any
+// damaged copies of the same chunks. The manifest is written even if there
+// are no chunks to write, which drops the table files the store was opened
+// without.
+func (nbs *NomsBlockStore) RepairChunks(ctx context.Context, repaired []chunks.Chunk) (err error) {
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return err
+	}
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+	defer nbs.mu.Unlock()
+
+	for {
+		// flush all tables and update manifest
+		err = nbs.updateManifest(ctx, nbs.upstream.root, nbs.upstream.root)
+
+		if err == nil {
+			break
+		} else if err == errOptimisticLockFailedTables {
+			continue
+		} else {
+			return err
+		}
+	}
+
+	specs := nbs.upstream.specs
+	if len(repaired) > 0 {
+		var size uint64
+		for _, c := range repaired {
+			size += uint64(len(c.Data()))
+		}
+
+		mt := newMemTable(size)
+		for _, c := range repaired {
+			mt.addChunk(addr(c.Hash()), c.Data())
+		}
+
+		// repaired chunks always go to a table file of their own
+		p := nbs.p
+		if j, ok := p.(*chunkJournal); ok {
+			p = j.persister
+		}
+
+		count, err := mt.count()
+		if err != nil {
+			return err
+		}
+
+		// the persisted table is only opened to be closed again, the quota
+		// of its index is released when it is.
+		err = nbs.tables.q.AcquireQuota(ctx, indexMemSize(count))
+		if err != nil {
+			return err
+		}
+
+		// the repaired chunks are written even though the store already has
+		// them, the copies it has may be corrupt.
+		cs, err := p.Persist(ctx, mt, nil, nbs.stats)
+		if err != nil {
+			_ = nbs.tables.q.ReleaseQuota(indexMemSize(count))
+			return err
+		}
+
+		name, err := cs.hash()
+		if err != nil {
+			_ = cs.Close()
+			return err
+		}
+
+		err = cs.Close()
+		if err != nil {
+			return err
+		}
+
+		// a table file holding exactly the chunks of a skipped table file
+		// has its name, it must not be skipped any longer.
+		if sm, ok := nbs.mm.m.(skippingManifest); ok {
+			delete(sm.skip, name)
+		}
+
+		specs = append([]tableSpec{{name, count}}, specs...)
+	}
+
+	newContents := nbs.upstream
+	newContents.specs = specs
+	newContents.lock = generateLockHash(newContents.root, specs, newContents.appendix)
+
+	upstream, err := nbs.mm.Update(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
+	if err != nil {
+		return err
+	}
+
+	if upstream.lock != newContents.lock {
+		return errors.New("concurrent manifest edit while repairing chunks")
+	}
+
+	newTables, err := nbs.tables.Rebase(ctx, upstream.specs, nbs.stats)
+	if err != nil {
+		return err
+	}
+
+	nbs.upstream = upstream
+	oldTables := nbs.tables
+	nbs.tables = newTables
+	return oldTables.Close()
+}
@@ -68,6 +68,16 @@ type Stats struct {
 
 	ReadManifestLatency  metrics.Histogram
 	WriteManifestLatency metrics.Histogram
+
+	// CommitConjoinLatency is the time commits spent conjoining tables inline.
+	CommitConjoinLatency metrics.Histogram
+	// BackgroundConjoinLatency is the time taken by conjoins run in the background.
+	BackgroundConjoinLatency metrics.Histogram
+	// AbandonedConjoins has a sample for every background conjoin that failed,
+	// or was abandoned because the tables changed in the meantime. Samples are
+	// the number of tables the conjoin would have replaced, or for conjoins
+	// that failed, the number of tables it started from.
+	AbandonedConjoins metrics.Histogram
 }
 
 func NewStats() *Stats {
@@ -95,6 +105,8 @@ func NewStats() *Stats {
 		BytesPerConjoin:                  metrics.NewByteHistogram(),
 		ReadManifestLatency:              metrics.NewTimeHistogram(),
 		WriteManifestLatency:             metrics.NewTimeHistogram(),
+		CommitConjoinLatency:             metrics.NewTimeHistogram(),
+		BackgroundConjoinLatency:         metrics.NewTimeHistogram(),
 	}
 }
 
@@ -128,6 +140,9 @@ func (s *Stats) Clone() Stats {
 		*s.TablesPerConjoin.Clone(),
 		*s.ReadManifestLatency.Clone(),
 		*s.WriteManifestLatency.Clone(),
+		*s.CommitConjoinLatency.Clone(),
+		*s.BackgroundConjoinLatency.Clone(),
+		*s.AbandonedConjoins.Clone(),
 	}
 }
 
@@ -161,6 +176,9 @@ ChunksPerConjoin:                 %s
 TablesPerConjoin:                 %s
 ReadManifestLatency:              %s
 WriteManifestLatency:             %s
+CommitConjoinLatency:             %s
+BackgroundConjoinLatency:         %s
+AbandonedConjoins:                %s
 `,
 		s.OpenLatency,
 		s.CommitLatency,
@@ -200,5 +218,8 @@ WriteManifestLatency:             %s
 		s.ChunksPerConjoin,
 		s.TablesPerConjoin,
 		s.ReadManifestLatency,
-		s.WriteManifestLatency)
+		s.WriteManifestLatency,
+		s.CommitConjoinLatency,
+		s.BackgroundConjoinLatency,
+		s.AbandonedConjoins)
 }
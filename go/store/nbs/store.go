@@ -95,9 +95,9 @@ type NBSCompressedChunkStore interface {
 type NomsBlockStore struct {
 	mm manifestManager
 	p  tablePersister
-	c  conjoiner
 
 	mu       sync.RWMutex // protects the following state
+	c        conjoiner
 	mt       *memTable
 	tables   tableSet
 	upstream manifestContents
@@ -105,10 +105,37 @@ type NomsBlockStore struct {
 	mtSize   uint64
 	putCount uint64
 
+	// keeper is set while an online garbage collection is in progress,
+	// see BeginGC. gcCond is signaled when it ends.
+	keeper func(hash.Hash) bool
+	gcCond *sync.Cond
+
+	// tableReaders tracks the reads of |tables| that are in progress. A
+	// garbage collection waits for the reads of the tables it swaps out
+	// before it deletes their files.
+	tableReaders *sync.WaitGroup
+
+	// conjoining is set while a conjoin runs in the background, see
+	// conjoinInBackground. Close cancels |conjoinCtx| and waits for it.
+	conjoining     bool
+	conjoins       sync.WaitGroup
+	conjoinCtx     context.Context
+	cancelConjoins context.CancelFunc
+
+	// snappyCopies are the snappy copies of zstd table files that chunk
+	// locations were asked for, by the name of the zstd table file. See
+	// snappyCopy.
+	snappyMu     sync.Mutex
+	snappyCopies map[addr]tableSpec
+
 	stats *Stats
 }
 
 var _ TableFileStore = &NomsBlockStore{}
+var _ TableFileRewriter = &NomsBlockStore{}
+var _ TableFileFormatReporter = &NomsBlockStore{}
+var _ ConjoinPolicySetter = &NomsBlockStore{}
+var _ ChunkRepairer = &NomsBlockStore{}
 var _ chunks.ChunkStoreGarbageCollector = &NomsBlockStore{}
 
 type Range struct {
@@ -116,14 +143,48 @@ type Range struct {
 	Length uint32
 }
 
+// errZstdChunkLocations is returned by GetChunkLocations for chunks in zstd table files of stores
+// that don't keep their table files on disk. The chunk records of zstd table files can't be decoded
+// without the dictionary at the beginning of the file, and their snappy copies are written to disk.
+var errZstdChunkLocations = errors.New("cannot get chunk locations in zstd table files")
+
+// GetChunkLocations returns the table files and the ranges within them of the chunks of |hashes|,
+// keyed by table file. The locations of chunks in zstd table files are given in snappy copies of
+// those table files, see snappyCopy.
 func (nbs *NomsBlockStore) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash]map[hash.Hash]Range, error) {
 	gr := toGetRecords(hashes)
 
 	ranges := make(map[hash.Hash]map[hash.Hash]Range)
 	f := func(css chunkSources) error {
 		for _, cs := range css {
+			// sources persisted during the lifetime of the store are wrapped in a persistingChunkSource
+			if pcs, ok := cs.(*persistingChunkSource); ok {
+				if err := pcs.wait(); err != nil {
+					return err
+				}
+				cs = pcs.cs
+			}
+
+			if _, ok := cs.(journalChunkSource); !ok {
+				index, err := cs.index()
+				if err != nil {
+					return err
+				}
+				if index.TableFileFormat() == ZstdTableFileFormat {
+					cp, err := nbs.snappyCopy(context.Background(), cs)
+					if err != nil {
+						return err
+					}
+					defer cp.Close()
+					cs = cp
+				}
+			}
+
 			switch tr := cs.(type) {
 			case *fileTableReader:
+				if tr.zstd != nil {
+					return errZstdChunkLocations
+				}
 				offsetRecSlice, _, err := tr.findOffsets(gr)
 				if err != nil {
 					return err
@@ -149,6 +210,9 @@ func (nbs *NomsBlockStore) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash
 					ranges[hash.Hash(tr.h)] = y
 				}
 			case *chunkSourceAdapter:
+				if tr.zstd != nil {
+					return errZstdChunkLocations
+				}
 				y, ok := ranges[hash.Hash(tr.h)]
 
 				if !ok {
@@ -180,6 +244,31 @@ func (nbs *NomsBlockStore) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash
 					delete(hashes, h)
 				}
 
+			case journalChunkSource:
+				var addrs []addr
+				for h := range hashes {
+					addrs = append(addrs, addr(h))
+				}
+
+				found := tr.wr.chunkRanges(addrs)
+				if len(found) == 0 {
+					continue
+				}
+
+				y, ok := ranges[hash.Hash(journalAddr)]
+
+				if !ok {
+					y = make(map[hash.Hash]Range)
+				}
+
+				for a, r := range found {
+					y[hash.Hash(a)] = r
+					delete(hashes, hash.Hash(a))
+				}
+
+				ranges[hash.Hash(journalAddr)] = y
+				gr = toGetRecords(hashes)
+
 			default:
 				panic(reflect.TypeOf(cs))
 			}
@@ -210,8 +299,77 @@ func (nbs *NomsBlockStore) GetChunkLocations(hashes hash.HashSet) (map[hash.Hash
 	return ranges, nil
 }
 
+// snappyCopy returns a snappy copy of the zstd table file |cs|, which is
+// written to the directory of the store the first time it is asked for.
+// Clients of the remotesapi download the chunk records of table files and
+// decode them on their own, which they can't do with the chunk records of
+// zstd table files. The copies are not listed in the manifest, so they are
+// deleted whenever the store prunes its table files, and written again the
+// next time they are needed.
+func (nbs *NomsBlockStore) snappyCopy(ctx context.Context, cs chunkSource) (chunkSource, error) {
+	fsp, ok := fsPersister(nbs.p)
+	if !ok {
+		return nil, errZstdChunkLocations
+	}
+	h, err := cs.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	nbs.snappyMu.Lock()
+	defer nbs.snappyMu.Unlock()
+
+	spec, ok := nbs.snappyCopies[h]
+	if ok {
+		_, err = os.Stat(filepath.Join(fsp.dir, spec.name.String()))
+		if errors.Is(err, os.ErrNotExist) {
+			ok = false
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		if spec, ok, err = rewriteTableFile(ctx, cs, fsp.dir, SnappyTableFileFormat); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("zstd table file %s has no chunks", h.String())
+		}
+		if nbs.snappyCopies == nil {
+			nbs.snappyCopies = make(map[addr]tableSpec)
+		}
+		nbs.snappyCopies[h] = spec
+	}
+
+	// the copy is only opened to be closed again, the quota of its index is
+	// released when it is.
+	if err = fsp.q.AcquireQuota(ctx, indexMemSize(spec.chunkCount)); err != nil {
+		return nil, err
+	}
+	cp, err := fsp.Open(ctx, spec.name, spec.chunkCount, nbs.stats)
+	if err != nil {
+		_ = fsp.q.ReleaseQuota(indexMemSize(spec.chunkCount))
+		return nil, err
+	}
+	return cp, nil
+}
+
+// isSnappyCopy returns whether the table file |a| is a snappy copy of a zstd
+// table file of the store.
+func (nbs *NomsBlockStore) isSnappyCopy(a addr) bool {
+	nbs.snappyMu.Lock()
+	defer nbs.snappyMu.Unlock()
+	for _, spec := range nbs.snappyCopies {
+		if spec.name == a {
+			return true
+		}
+	}
+	return false
+}
+
 func (nbs *NomsBlockStore) UpdateManifest(ctx context.Context, updates map[hash.Hash]uint32) (mi ManifestInfo, err error) {
-	nbs.mm.LockForUpdate()
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return manifestContents{}, err
+	}
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
 
@@ -219,8 +377,6 @@ func (nbs *NomsBlockStore) UpdateManifest(ctx context.Context, updates map[hash.
 			err = unlockErr
 		}
 	}()
-
-	nbs.mu.Lock()
 	defer nbs.mu.Unlock()
 
 	var updatedContents manifestContents
@@ -287,7 +443,9 @@ func (nbs *NomsBlockStore) UpdateManifest(ctx context.Context, updates map[hash.
 }
 
 func (nbs *NomsBlockStore) UpdateManifestWithAppendix(ctx context.Context, updates map[hash.Hash]uint32, option ManifestAppendixOption) (mi ManifestInfo, err error) {
-	nbs.mm.LockForUpdate()
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return manifestContents{}, err
+	}
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
 
@@ -295,8 +453,6 @@ func (nbs *NomsBlockStore) UpdateManifestWithAppendix(ctx context.Context, updat
 			err = unlockErr
 		}
 	}()
-
-	nbs.mu.Lock()
 	defer nbs.mu.Unlock()
 
 	var updatedContents manifestContents
@@ -527,6 +683,40 @@ func newLocalStore(ctx context.Context, nbfVerStr string, dir string, memTableSi
 	return nbs, nil
 }
 
+// NewLocalJournalingStore returns a NomsBlockStore for the database in |dir| that appends chunks and root hashes to a
+// chunk journal, instead of writing a new table file and manifest for every commit.
+func NewLocalJournalingStore(ctx context.Context, nbfVerStr string, dir string, memTableSize uint64, q MemoryQuotaProvider) (*NomsBlockStore, error) {
+	cacheOnce.Do(makeGlobalCaches)
+	err := checkDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := getFileManifest(ctx, dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := newFSTablePersister(dir, globalFDCache, q)
+	j, err := newChunkJournal(ctx, dir, m, p.(*fsTablePersister))
+
+	if err != nil {
+		return nil, err
+	}
+
+	mm := makeManifestManager(j)
+	nbs, err := newNomsBlockStore(ctx, nbfVerStr, mm, j, q, inlineConjoiner{defaultMaxTables}, memTableSize)
+
+	if err != nil {
+		_ = j.Close()
+		return nil, err
+	}
+
+	return nbs, nil
+}
+
 func checkDir(dir string) error {
 	stat, err := os.Stat(dir)
 	if err != nil {
@@ -544,14 +734,17 @@ func newNomsBlockStore(ctx context.Context, nbfVerStr string, mm manifestManager
 	}
 
 	nbs := &NomsBlockStore{
-		mm:       mm,
-		p:        p,
-		c:        c,
-		tables:   newTableSet(p, q),
-		upstream: manifestContents{nbfVers: nbfVerStr},
-		mtSize:   memTableSize,
-		stats:    NewStats(),
-	}
+		mm:           mm,
+		p:            p,
+		c:            c,
+		tables:       newTableSet(p, q),
+		upstream:     manifestContents{nbfVers: nbfVerStr},
+		mtSize:       memTableSize,
+		tableReaders: &sync.WaitGroup{},
+		stats:        NewStats(),
+	}
+	nbs.gcCond = sync.NewCond(&nbs.mu)
+	nbs.conjoinCtx, nbs.cancelConjoins = context.WithCancel(context.Background())
 
 	t1 := time.Now()
 	defer nbs.stats.OpenLatency.SampleTimeSince(t1)
@@ -586,18 +779,22 @@ func newNomsBlockStore(ctx context.Context, nbfVerStr string, mm manifestManager
 // contexts when things like table file maintenance is done out-of-process. Not
 // safe for use outside of NomsBlockStore construction.
 func (nbs *NomsBlockStore) WithoutConjoiner() *NomsBlockStore {
-	return &NomsBlockStore{
-		mm:       nbs.mm,
-		p:        nbs.p,
-		c:        noopConjoiner{},
-		mu:       sync.RWMutex{},
-		mt:       nbs.mt,
-		tables:   nbs.tables,
-		upstream: nbs.upstream,
-		mtSize:   nbs.mtSize,
-		putCount: nbs.putCount,
-		stats:    nbs.stats,
-	}
+	ret := &NomsBlockStore{
+		mm:           nbs.mm,
+		p:            nbs.p,
+		c:            noopConjoiner{},
+		mu:           sync.RWMutex{},
+		mt:           nbs.mt,
+		tables:       nbs.tables,
+		upstream:     nbs.upstream,
+		mtSize:       nbs.mtSize,
+		putCount:     nbs.putCount,
+		tableReaders: &sync.WaitGroup{},
+		stats:        nbs.stats,
+	}
+	ret.gcCond = sync.NewCond(&ret.mu)
+	ret.conjoinCtx, ret.cancelConjoins = context.WithCancel(context.Background())
+	return ret
 }
 
 func (nbs *NomsBlockStore) Put(ctx context.Context, c chunks.Chunk) error {
@@ -619,6 +816,7 @@ func (nbs *NomsBlockStore) Put(ctx context.Context, c chunks.Chunk) error {
 func (nbs *NomsBlockStore) addChunk(ctx context.Context, h addr, data []byte) bool {
 	nbs.mu.Lock()
 	defer nbs.mu.Unlock()
+	nbs.waitForGC(hash.Hash(h))
 	if nbs.mt == nil {
 		nbs.mt = newMemTable(nbs.mtSize)
 	}
@@ -641,10 +839,12 @@ func (nbs *NomsBlockStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk,
 	}()
 
 	a := addr(h)
+	var endRead func()
 	data, tables, err := func() ([]byte, chunkReader, error) {
 		var data []byte
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+		endRead = nbs.beginRead()
 		if nbs.mt != nil {
 			var err error
 			data, err = nbs.mt.get(ctx, a, nbs.stats)
@@ -655,6 +855,7 @@ func (nbs *NomsBlockStore) Get(ctx context.Context, h hash.Hash) (chunks.Chunk,
 		}
 		return data, nbs.tables, nil
 	}()
+	defer endRead()
 
 	if err != nil {
 		return chunks.EmptyChunk, err
@@ -710,9 +911,11 @@ func (nbs *NomsBlockStore) getManyWithFunc(
 
 	eg, ctx := errgroup.WithContext(ctx)
 
+	var endRead func()
 	tables, remaining, err := func() (tables chunkReader, remaining bool, err error) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+		endRead = nbs.beginRead()
 		tables = nbs.tables
 		remaining = true
 		if nbs.mt != nil {
@@ -720,6 +923,7 @@ func (nbs *NomsBlockStore) getManyWithFunc(
 		}
 		return
 	}()
+	defer endRead()
 	if err != nil {
 		return err
 	}
@@ -809,85 +1013,127 @@ func (nbs *NomsBlockStore) Has(ctx context.Context, h hash.Hash) (bool, error) {
 		nbs.stats.AddressesPerHas.Sample(1)
 	}()
 
-	a := addr(h)
-	has, tables, err := func() (bool, chunkReader, error) {
+	for {
+		has, gcActive, err := nbs.has(addr(h))
+		if err != nil {
+			return false, err
+		}
+		if !has || !gcActive || nbs.keepFound([]hash.Hash{h}) {
+			return has, nil
+		}
+	}
+}
+
+func (nbs *NomsBlockStore) has(a addr) (has, gcActive bool, err error) {
+	var endRead func()
+	has, tables, gcActive, err := func() (bool, chunkReader, bool, error) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+		endRead = nbs.beginRead()
+		gcActive := nbs.keeper != nil
 
 		if nbs.mt != nil {
 			has, err := nbs.mt.has(a)
 
 			if err != nil {
-				return false, nil, err
+				return false, nil, false, err
 			}
 
-			return has, nbs.tables, nil
+			return has, nbs.tables, gcActive, nil
 		}
 
-		return false, nbs.tables, nil
+		return false, nbs.tables, gcActive, nil
 	}()
+	defer endRead()
 
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	if !has {
 		has, err = tables.has(a)
 
 		if err != nil {
-			return false, err
+			return false, false, err
 		}
 	}
 
-	return has, nil
+	return has, gcActive, nil
 }
 
 func (nbs *NomsBlockStore) HasMany(ctx context.Context, hashes hash.HashSet) (hash.HashSet, error) {
 	t1 := time.Now()
 
-	reqs := toHasRecords(hashes)
+	for {
+		reqs, gcActive, err := nbs.hasMany(hashes)
+		if err != nil {
+			return nil, err
+		}
+
+		if gcActive {
+			found := make([]hash.Hash, 0, len(reqs))
+			for _, r := range reqs {
+				if r.has {
+					found = append(found, hash.Hash(*r.a))
+				}
+			}
+			if !nbs.keepFound(found) {
+				continue
+			}
+		}
 
-	tables, remaining, err := func() (tables chunkReader, remaining bool, err error) {
+		if len(hashes) > 0 {
+			nbs.stats.HasLatency.SampleTimeSince(t1)
+			nbs.stats.AddressesPerHas.SampleLen(len(reqs))
+		}
+
+		absent := hash.HashSet{}
+		for _, r := range reqs {
+			if !r.has {
+				absent.Insert(hash.New(r.a[:]))
+			}
+		}
+		return absent, nil
+	}
+}
+
+func (nbs *NomsBlockStore) hasMany(hashes hash.HashSet) (reqs []hasRecord, gcActive bool, err error) {
+	reqs = toHasRecords(hashes)
+
+	var endRead func()
+	tables, remaining, gcActive, err := func() (tables chunkReader, remaining, gcActive bool, err error) {
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
+		endRead = nbs.beginRead()
 		tables = nbs.tables
+		gcActive = nbs.keeper != nil
 
 		remaining = true
 		if nbs.mt != nil {
 			remaining, err = nbs.mt.hasMany(reqs)
 
 			if err != nil {
-				return nil, false, err
+				return nil, false, false, err
 			}
 		}
 
-		return tables, remaining, nil
+		return tables, remaining, gcActive, nil
 	}()
+	defer endRead()
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if remaining {
 		_, err := tables.hasMany(reqs)
 
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	}
 
-	if len(hashes) > 0 {
-		nbs.stats.HasLatency.SampleTimeSince(t1)
-		nbs.stats.AddressesPerHas.SampleLen(len(reqs))
-	}
-
-	absent := hash.HashSet{}
-	for _, r := range reqs {
-		if !r.has {
-			absent.Insert(hash.New(r.a[:]))
-		}
-	}
-	return absent, nil
+	return reqs, gcActive, nil
 }
 
 func toHasRecords(hashes hash.HashSet) []hasRecord {
@@ -995,7 +1241,13 @@ func (nbs *NomsBlockStore) Commit(ctx context.Context, current, last hash.Hash)
 		return false, err
 	}
 
-	nbs.mm.LockForUpdate()
+	// a root committed during an online garbage collection must be kept by it
+	err = nbs.lockForUpdate(func() bool {
+		return nbs.keeper != nil && nbs.keeper(current)
+	})
+	if err != nil {
+		return false, err
+	}
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
 
@@ -1003,8 +1255,6 @@ func (nbs *NomsBlockStore) Commit(ctx context.Context, current, last hash.Hash)
 			err = unlockErr
 		}
 	}()
-
-	nbs.mu.Lock()
 	defer nbs.mu.Unlock()
 	for {
 		if err := nbs.updateManifest(ctx, current, last); err == nil {
@@ -1072,29 +1322,34 @@ func (nbs *NomsBlockStore) updateManifest(ctx context.Context, current, last has
 	}
 
 	if nbs.c.ConjoinRequired(nbs.tables) {
-		var err error
+		if _, ok := nbs.c.(backgroundConjoiner); ok {
+			nbs.conjoinInBackground()
+		} else {
+			t1 := time.Now()
 
-		newUpstream, err := nbs.c.Conjoin(ctx, nbs.upstream, nbs.mm, nbs.p, nbs.stats)
+			newUpstream, err := nbs.c.Conjoin(ctx, nbs.upstream, nbs.mm, nbs.p, nbs.stats)
 
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
 
-		newTables, err := nbs.tables.Rebase(ctx, newUpstream.specs, nbs.stats)
+			newTables, err := nbs.tables.Rebase(ctx, newUpstream.specs, nbs.stats)
 
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
 
-		nbs.upstream = newUpstream
-		oldTables := nbs.tables
-		nbs.tables = newTables
-		err = oldTables.Close()
-		if err != nil {
-			return err
-		}
+			nbs.upstream = newUpstream
+			oldTables := nbs.tables
+			nbs.tables = newTables
+			err = oldTables.Close()
+			if err != nil {
+				return err
+			}
 
-		return errOptimisticLockFailedTables
+			nbs.stats.CommitConjoinLatency.SampleTimeSince(t1)
+			return errOptimisticLockFailedTables
+		}
 	}
 
 	specs, err := nbs.tables.ToSpecs()
@@ -1150,6 +1405,123 @@ func (nbs *NomsBlockStore) updateManifest(ctx context.Context, current, last has
 	return nil
 }
 
+// SetConjoinPolicy changes the policy the store uses to conjoin its table files.
+func (nbs *NomsBlockStore) SetConjoinPolicy(policy ConjoinPolicy) error {
+	c, err := policy.conjoiner()
+	if err != nil {
+		return err
+	}
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.c = c
+	return nil
+}
+
+// conjoinInBackground starts a conjoin of the upstream tables of the store on
+// a goroutine of its own, unless one is running already. Conjoins that fail
+// are dropped; the next commit that finds a conjoin is required starts another
+// one. callers must hold |nbs.mu|.
+func (nbs *NomsBlockStore) conjoinInBackground() {
+	if nbs.conjoining {
+		return
+	}
+	nbs.conjoining = true
+	nbs.conjoins.Add(1)
+
+	upstream, c := nbs.upstream, nbs.c
+	go func() {
+		defer nbs.conjoins.Done()
+		oldTables, readers, err := nbs.landBackgroundConjoin(nbs.conjoinCtx, upstream, c)
+		if err != nil {
+			// the conjoinees may not have been chosen yet, so the sample is
+			// every table the conjoin could have replaced
+			nbs.stats.AbandonedConjoins.SampleLen(len(upstream.specs))
+		}
+
+		nbs.mu.Lock()
+		nbs.conjoining = false
+		nbs.mu.Unlock()
+
+		if readers != nil {
+			readers.Wait()
+			_ = oldTables.Close()
+		}
+	}()
+}
+
+// landBackgroundConjoin conjoins tables of |upstream| without holding any of
+// the locks of the store, so that commits can proceed in the meantime. The
+// conjoined table replaces its conjoinees in the manifest only if all of them
+// are still upstream once it has been written. It returns the tables that were
+// swapped out and the reads of them in progress, which must finish before the
+// tables are closed.
+func (nbs *NomsBlockStore) landBackgroundConjoin(ctx context.Context, upstream manifestContents, c conjoiner) (oldTables tableSet, readers *sync.WaitGroup, err error) {
+	t1 := time.Now()
+
+	// Appendix table files are never conjoined
+	upstream, _ = upstream.removeAppendixSpecs()
+	conjoined, conjoinees, _, err := conjoinTables(ctx, nbs.p, upstream.specs, c.ChooseConjoinees, nbs.stats)
+	if err != nil {
+		return tableSet{}, nil, err
+	}
+
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return tableSet{}, nil, err
+	}
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+	defer nbs.mu.Unlock()
+
+	newContents, ok := replaceConjoinees(nbs.upstream, conjoined, conjoinees)
+	if !ok {
+		nbs.stats.AbandonedConjoins.SampleLen(len(conjoinees))
+		return tableSet{}, nil, nbs.removeAbandonedConjoin(ctx, conjoined, nbs.upstream)
+	}
+
+	updated, err := nbs.mm.Update(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
+	if err != nil {
+		return tableSet{}, nil, err
+	}
+	if updated.lock != newContents.lock {
+		// The manifest was changed out of process. The next commit rebases onto it.
+		nbs.stats.AbandonedConjoins.SampleLen(len(conjoinees))
+		return tableSet{}, nil, nbs.removeAbandonedConjoin(ctx, conjoined, updated)
+	}
+
+	newTables, err := nbs.tables.Rebase(ctx, updated.specs, nbs.stats)
+	if err != nil {
+		return tableSet{}, nil, err
+	}
+	nbs.upstream = updated
+	oldTables = nbs.tables
+	nbs.tables = newTables
+
+	readers = nbs.tableReaders
+	nbs.tableReaders = &sync.WaitGroup{}
+
+	nbs.stats.BackgroundConjoinLatency.SampleTimeSince(t1)
+	return oldTables, readers, nil
+}
+
+// removeAbandonedConjoin deletes the table file of a background conjoin that
+// didn't land. Conjoined tables are named after their conjoinees, so the file
+// is kept if another writer landed the same conjoin in |upstream|.
+func (nbs *NomsBlockStore) removeAbandonedConjoin(ctx context.Context, conjoined tableSpec, upstream manifestContents) error {
+	remover, ok := nbs.p.(tableFileRemover)
+	if !ok {
+		return nil
+	}
+	if _, ok := upstream.getSpecSet()[conjoined.name]; ok {
+		return nil
+	}
+	return remover.RemoveTableFile(ctx, conjoined.name)
+}
+
 func (nbs *NomsBlockStore) Version() string {
 	nbs.mu.RLock()
 	defer nbs.mu.RUnlock()
@@ -1157,7 +1529,16 @@ func (nbs *NomsBlockStore) Version() string {
 }
 
 func (nbs *NomsBlockStore) Close() error {
-	return nbs.tables.Close()
+	nbs.cancelConjoins()
+	nbs.conjoins.Wait()
+
+	err := nbs.tables.Close()
+	if c, ok := nbs.p.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
 func (nbs *NomsBlockStore) Stats() interface{} {
@@ -1169,7 +1550,25 @@ func (nbs *NomsBlockStore) StatsSummary() string {
 	defer nbs.mu.Unlock()
 	cnt, _ := nbs.tables.count()
 	physLen, _ := nbs.tables.physicalLen()
-	return fmt.Sprintf("Root: %s; Chunk Count %d; Physical Bytes %s", nbs.upstream.root, cnt, humanize.Bytes(physLen))
+	summary := fmt.Sprintf("Root: %s; Chunk Count %d; Physical Bytes %s", nbs.upstream.root, cnt, humanize.Bytes(physLen))
+
+	formats, _ := nbs.tables.formatStats()
+	for _, f := range []TableFileFormat{SnappyTableFileFormat, ZstdTableFileFormat} {
+		s, ok := formats[f]
+		if !ok {
+			continue
+		}
+		summary += fmt.Sprintf("; %s Table Files %d, Physical Bytes %s, Uncompressed Bytes %s (%.1f%% saved)",
+			f, s.TableFiles, humanize.Bytes(s.PhysicalBytes), humanize.Bytes(s.UncompressedBytes), s.SavedPercent())
+	}
+	return summary
+}
+
+// TableFileFormatStats returns a summary of the table files of the store for each TableFileFormat in use.
+func (nbs *NomsBlockStore) TableFileFormatStats() (map[TableFileFormat]TableFileFormatStats, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	return nbs.tables.formatStats()
 }
 
 // tableFile is our implementation of TableFile.
@@ -1214,14 +1613,14 @@ func (nbs *NomsBlockStore) Sources(ctx context.Context) (hash.Hash, []TableFile,
 		return hash.Hash{}, nil, nil, err
 	}
 
-	appendixTableFiles, err := getTableFiles(css, contents, contents.NumAppendixSpecs(), func(mc manifestContents, idx int) tableSpec {
+	appendixTableFiles, err := getTableFiles(ctx, css, contents, contents.NumAppendixSpecs(), func(mc manifestContents, idx int) tableSpec {
 		return mc.getAppendixSpec(idx)
 	})
 	if err != nil {
 		return hash.Hash{}, nil, nil, err
 	}
 
-	allTableFiles, err := getTableFiles(css, contents, contents.NumTableSpecs(), func(mc manifestContents, idx int) tableSpec {
+	allTableFiles, err := getTableFiles(ctx, css, contents, contents.NumTableSpecs(), func(mc manifestContents, idx int) tableSpec {
 		return mc.getSpec(idx)
 	})
 	if err != nil {
@@ -1231,7 +1630,7 @@ func (nbs *NomsBlockStore) Sources(ctx context.Context) (hash.Hash, []TableFile,
 	return contents.GetRoot(), allTableFiles, appendixTableFiles, nil
 }
 
-func getTableFiles(css map[addr]chunkSource, contents manifestContents, numSpecs int, specFunc func(mc manifestContents, idx int) tableSpec) ([]TableFile, error) {
+func getTableFiles(ctx context.Context, css map[addr]chunkSource, contents manifestContents, numSpecs int, specFunc func(mc manifestContents, idx int) tableSpec) ([]TableFile, error) {
 	tableFiles := make([]TableFile, 0)
 	if numSpecs == 0 {
 		return tableFiles, nil
@@ -1242,6 +1641,14 @@ func getTableFiles(css map[addr]chunkSource, contents manifestContents, numSpecs
 		if !ok {
 			return nil, ErrSpecWithoutChunkSource
 		}
+		if jcs, ok := asJournalChunkSource(cs); ok {
+			tf, err := newJournalTableFile(ctx, jcs)
+			if err != nil {
+				return nil, err
+			}
+			tableFiles = append(tableFiles, tf)
+			continue
+		}
 		tableFiles = append(tableFiles, newTableFile(cs, info))
 	}
 	return tableFiles, nil
@@ -1293,6 +1700,14 @@ func (nbs *NomsBlockStore) Size(ctx context.Context) (uint64, error) {
 		if !ok {
 			return uint64(0), errors.New("manifest referenced table file for which there is no chunkSource.")
 		}
+		if jcs, ok := asJournalChunkSource(cs); ok {
+			sz, err := jcs.size()
+			if err != nil {
+				return uint64(0), err
+			}
+			size += sz
+			continue
+		}
 		ti, err := cs.index()
 		if err != nil {
 			return uint64(0), fmt.Errorf("error getting table file index for chunkSource. %w", err)
@@ -1322,8 +1737,53 @@ func (nbs *NomsBlockStore) chunkSourcesByAddr() (map[addr]chunkSource, error) {
 
 }
 
+// fsPersister returns the *fsTablePersister that |p| writes table files with, if there is one.
+func fsPersister(p tablePersister) (*fsTablePersister, bool) {
+	switch p := p.(type) {
+	case *fsTablePersister:
+		return p, true
+	case *chunkJournal:
+		return p.persister, true
+	default:
+		return nil, false
+	}
+}
+
+// TableFilePath returns the path on disk of the table file |fileId| of this store. It returns false if the store does
+// not keep its table files on disk, or if |fileId| is not one of its table files. The chunk journal is a table file of
+// the store named by its chunk journal name.
+func (nbs *NomsBlockStore) TableFilePath(fileId string) (string, bool, error) {
+	a, err := parseAddr(fileId)
+	if err != nil {
+		return "", false, nil
+	}
+
+	fsp, ok := fsPersister(nbs.p)
+	if !ok {
+		return "", false, nil
+	}
+
+	if nbs.isSnappyCopy(a) {
+		return filepath.Join(fsp.dir, fileId), true, nil
+	}
+
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+
+	css, err := nbs.chunkSourcesByAddr()
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, ok := css[a]; !ok {
+		return "", false, nil
+	}
+
+	return filepath.Join(fsp.dir, fileId), true, nil
+}
+
 func (nbs *NomsBlockStore) SupportedOperations() TableFileStoreOps {
-	_, ok := nbs.p.(*fsTablePersister)
+	_, ok := fsPersister(nbs.p)
 	return TableFileStoreOps{
 		CanRead:  true,
 		CanWrite: ok,
@@ -1334,7 +1794,7 @@ func (nbs *NomsBlockStore) SupportedOperations() TableFileStoreOps {
 
 // WriteTableFile will read a table file from the provided reader and write it to the TableFileStore
 func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, numChunks int, contentHash []byte, getRd func() (io.ReadCloser, uint64, error)) error {
-	fsPersister, ok := nbs.p.(*fsTablePersister)
+	fsp, ok := fsPersister(nbs.p)
 	if !ok {
 		return errors.New("Not implemented")
 	}
@@ -1353,7 +1813,7 @@ func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, nu
 		}()
 
 		var temp *os.File
-		temp, err = tempfiles.MovableTempFileProvider.NewFile(fsPersister.dir, tempTablePrefix)
+		temp, err = tempfiles.MovableTempFileProvider.NewFile(fsp.dir, tempTablePrefix)
 		if err != nil {
 			return "", err
 		}
@@ -1376,7 +1836,7 @@ func (nbs *NomsBlockStore) WriteTableFile(ctx context.Context, fileId string, nu
 		return err
 	}
 
-	path := filepath.Join(fsPersister.dir, fileId)
+	path := filepath.Join(fsp.dir, fileId)
 	return file.Rename(tn, path)
 }
 
@@ -1405,10 +1865,9 @@ func (nbs *NomsBlockStore) AddTableFilesToManifest(ctx context.Context, fileIdTo
 
 // PruneTableFiles deletes old table files that are no longer referenced in the manifest.
 func (nbs *NomsBlockStore) PruneTableFiles(ctx context.Context) (err error) {
-	nbs.mu.Lock()
-	defer nbs.mu.Unlock()
-
-	nbs.mm.LockForUpdate()
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return err
+	}
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
 
@@ -1416,6 +1875,7 @@ func (nbs *NomsBlockStore) PruneTableFiles(ctx context.Context) (err error) {
 			err = unlockErr
 		}
 	}()
+	defer nbs.mu.Unlock()
 
 	for {
 		// flush all tables and update manifest
@@ -1444,6 +1904,115 @@ func (nbs *NomsBlockStore) PruneTableFiles(ctx context.Context) (err error) {
 	return nbs.p.PruneTableFiles(ctx, contents)
 }
 
+// RewriteTableFiles rewrites every table file of the store that isn't in |format| into a new
+// table file of |format|, replaces them in the manifest, and deletes them. Table files that
+// would not get smaller as zstd tables, appendix table files and the chunk journal are left
+// as they are.
+func (nbs *NomsBlockStore) RewriteTableFiles(ctx context.Context, format TableFileFormat) (err error) {
+	var dir string
+	switch p := nbs.p.(type) {
+	case *fsTablePersister:
+		dir = p.dir
+	case *chunkJournal:
+		dir = p.persister.dir
+	default:
+		return chunks.ErrUnsupportedOperation
+	}
+
+	if err = nbs.lockForUpdate(nbs.gcInProgress); err != nil {
+		return err
+	}
+	defer func() {
+		unlockErr := nbs.mm.UnlockForUpdate()
+
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+	defer nbs.mu.Unlock()
+
+	for {
+		// flush all tables and update manifest
+		err = nbs.updateManifest(ctx, nbs.upstream.root, nbs.upstream.root)
+
+		if err == nil {
+			break
+		} else if err == errOptimisticLockFailedTables {
+			continue
+		} else {
+			return err
+		}
+	}
+
+	appendix := nbs.upstream.getAppendixSet()
+	rewritten := make(map[addr]tableSpec)
+	for _, cs := range nbs.tables.upstream {
+		h, err := cs.hash()
+		if err != nil {
+			return err
+		}
+		if _, ok := appendix[h]; ok || h == journalAddr {
+			continue
+		}
+
+		index, err := cs.index()
+		if err != nil {
+			return err
+		}
+		if index.TableFileFormat() == format {
+			continue
+		}
+
+		spec, ok, err := rewriteTableFile(ctx, cs, dir, format)
+		if err != nil {
+			return err
+		}
+		if ok {
+			rewritten[h] = spec
+		}
+	}
+
+	if len(rewritten) == 0 {
+		return nil
+	}
+
+	specs := make([]tableSpec, len(nbs.upstream.specs))
+	for i, spec := range nbs.upstream.specs {
+		if rw, ok := rewritten[spec.name]; ok {
+			spec = rw
+		}
+		specs[i] = spec
+	}
+
+	newContents := nbs.upstream
+	newContents.specs = specs
+	newContents.lock = generateLockHash(newContents.root, specs, newContents.appendix)
+
+	upstream, err := nbs.mm.Update(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
+	if err != nil {
+		return err
+	}
+
+	if upstream.lock != newContents.lock {
+		return errors.New("concurrent manifest edit while rewriting table files")
+	}
+
+	newTables, err := nbs.tables.Rebase(ctx, upstream.specs, nbs.stats)
+	if err != nil {
+		return err
+	}
+
+	nbs.upstream = upstream
+	oldTables := nbs.tables
+	nbs.tables = newTables
+	err = oldTables.Close()
+	if err != nil {
+		return err
+	}
+
+	return nbs.p.PruneTableFiles(ctx, upstream)
+}
+
 func (nbs *NomsBlockStore) MarkAndSweepChunks(ctx context.Context, last hash.Hash, keepChunks <-chan []hash.Hash, dest chunks.ChunkStore) error {
 	ops := nbs.SupportedOperations()
 	if !ops.CanGC || !ops.CanPrune {
@@ -1454,12 +2023,14 @@ func (nbs *NomsBlockStore) MarkAndSweepChunks(ctx context.Context, last hash.Has
 		nbs.mu.RLock()
 		defer nbs.mu.RUnlock()
 
-		if nbs.upstream.root != last {
+		// the root may move during an online garbage collection; the keeper
+		// hands the new roots to the collector
+		if nbs.keeper == nil && nbs.upstream.root != last {
 			return errLastRootMismatch
 		}
 
 		// check to see if the specs have changed since last gc.  If they haven't bail early.
-		gcGenCheck := generateLockHash(last, nbs.upstream.specs, nbs.upstream.appendix)
+		gcGenCheck := generateLockHash(nbs.upstream.root, nbs.upstream.specs, nbs.upstream.appendix)
 		if nbs.upstream.gcGen == gcGenCheck {
 			return chunks.ErrNothingToCollect
 		}
@@ -1490,11 +2061,20 @@ func (nbs *NomsBlockStore) MarkAndSweepChunks(ctx context.Context, last hash.Has
 	}
 
 	if destNBS == nbs {
-		err = nbs.swapTables(ctx, specs)
+		oldTables, readers, err := nbs.swapTables(ctx, specs)
 		if err != nil {
 			return err
 		}
 
+		// readers that started before the swap may still be reading
+		// the old table files
+		if readers != nil {
+			readers.Wait()
+			if err = oldTables.Close(); err != nil {
+				return err
+			}
+		}
+
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
@@ -1552,9 +2132,110 @@ LOOP:
 		}
 	}
 
-	nomsDir := dest.p.(*fsTablePersister).dir
+	fsp, ok := fsPersister(dest.p)
+	if !ok {
+		return nil, chunks.ErrUnsupportedOperation
+	}
+	nomsDir := fsp.dir
+
+	specs, err := gcc.copyTablesToDir(ctx, nomsDir)
+	if err != nil {
+		return nil, err
+	}
 
-	return gcc.copyTablesToDir(ctx, nomsDir)
+	zstd, err := nbs.hasZstdTables()
+	if err == nil && !zstd && dest != nbs {
+		zstd, err = dest.hasZstdTables()
+	}
+	if err != nil || !zstd {
+		return specs, err
+	}
+	return dest.compressCopiedTables(ctx, specs)
+}
+
+// hasZstdTables returns whether any of the upstream table files of the store
+// are zstd table files.
+func (nbs *NomsBlockStore) hasZstdTables() (bool, error) {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	for _, cs := range nbs.tables.upstream {
+		h, err := cs.hash()
+		if err != nil {
+			return false, err
+		}
+		if h == journalAddr {
+			continue
+		}
+		index, err := cs.index()
+		if err != nil {
+			return false, err
+		}
+		if index.TableFileFormat() == ZstdTableFileFormat {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compressCopiedTables rewrites the table files of |specs|, which were
+// written by a garbage collection into the directory of the store, into zstd
+// table files, so that the garbage collection of a store with zstd table files
+// keeps them compressed. The snappy table files that were rewritten are
+// removed unless the store lists them in its manifest.
+func (nbs *NomsBlockStore) compressCopiedTables(ctx context.Context, specs []tableSpec) ([]tableSpec, error) {
+	fsp, ok := fsPersister(nbs.p)
+	if !ok {
+		return nil, chunks.ErrUnsupportedOperation
+	}
+
+	compressed := make([]tableSpec, len(specs))
+	for i, spec := range specs {
+		if err := fsp.q.AcquireQuota(ctx, indexMemSize(spec.chunkCount)); err != nil {
+			return nil, err
+		}
+		cs, err := fsp.Open(ctx, spec.name, spec.chunkCount, nbs.stats)
+		if err != nil {
+			_ = fsp.q.ReleaseQuota(indexMemSize(spec.chunkCount))
+			return nil, err
+		}
+		rw, ok, err := rewriteTableFile(ctx, cs, fsp.dir, ZstdTableFileFormat)
+		cerr := cs.Close()
+		if err != nil {
+			return nil, err
+		} else if cerr != nil {
+			return nil, cerr
+		}
+
+		if !ok {
+			compressed[i] = spec
+			continue
+		}
+		compressed[i] = rw
+		if !nbs.inManifest(spec.name) {
+			if err = fsp.RemoveTableFile(ctx, spec.name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return compressed, nil
+}
+
+// inManifest returns whether the table file |name| is in the manifest of the
+// store.
+func (nbs *NomsBlockStore) inManifest(name addr) bool {
+	nbs.mu.RLock()
+	defer nbs.mu.RUnlock()
+	for _, spec := range nbs.upstream.specs {
+		if spec.name == name {
+			return true
+		}
+	}
+	for _, spec := range nbs.upstream.appendix {
+		if spec.name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // todo: what's the optimal table size to copy to?
@@ -1574,7 +2255,10 @@ func (nbs *NomsBlockStore) gcTableSize() (uint64, error) {
 	return nbs.mtSize, nil
 }
 
-func (nbs *NomsBlockStore) swapTables(ctx context.Context, specs []tableSpec) (err error) {
+// swapTables replaces the tables of the store with the tables of |specs|. It
+// returns the tables it replaced, and the readers of those tables that are
+// still in progress, or a nil WaitGroup if the tables were not replaced.
+func (nbs *NomsBlockStore) swapTables(ctx context.Context, specs []tableSpec) (oldTables tableSet, readers *sync.WaitGroup, err error) {
 	nbs.mm.LockForUpdate()
 	defer func() {
 		unlockErr := nbs.mm.UnlockForUpdate()
@@ -1597,37 +2281,176 @@ func (nbs *NomsBlockStore) swapTables(ctx context.Context, specs []tableSpec) (e
 
 	// nothing has changed.  Bail early
 	if newContents.gcGen == nbs.upstream.gcGen {
-		return nil
+		return tableSet{}, nil, nil
 	}
 
 	upstream, uerr := nbs.mm.UpdateGCGen(ctx, nbs.upstream.lock, newContents, nbs.stats, nil)
 	if uerr != nil {
-		return uerr
+		return tableSet{}, nil, uerr
 	}
 
 	if upstream.lock != newContents.lock {
-		return errors.New("concurrent manifest edit during GC, before swapTables. GC failed.")
+		return tableSet{}, nil, errors.New("concurrent manifest edit during GC, before swapTables. GC failed.")
 	}
 
-	// clear memTable
+	// clear memTable. During an online garbage collection, its chunks were
+	// handed to the keeper and copied into the new tables if they are live.
 	nbs.mt = newMemTable(nbs.mtSize)
 
 	// clear nbs.tables.novel
-	nbs.tables, err = nbs.tables.Flatten(ctx)
+	oldTables = nbs.tables
+	flattened, err := nbs.tables.Flatten(ctx)
 	if err != nil {
-		return err
+		return tableSet{}, nil, err
 	}
 
 	// replace nbs.tables.upstream with gc compacted tables
 	nbs.upstream = upstream
-	nbs.tables, err = nbs.tables.Rebase(ctx, upstream.specs, nbs.stats)
+	nbs.tables, err = flattened.Rebase(ctx, upstream.specs, nbs.stats)
+	if err != nil {
+		return tableSet{}, nil, err
+	}
+
+	readers = nbs.tableReaders
+	nbs.tableReaders = &sync.WaitGroup{}
+
+	return oldTables, readers, nil
+}
+
+// BeginGC implements chunks.ChunkStoreGarbageCollector. The chunks in the
+// memTable and the novel tables of the store are handed to |keeper| as the
+// chunks that have not been committed yet. Reads with Get and GetMany are not
+// reported to |keeper|; a client that reads a chunk that is not reachable
+// from a root that is kept may find it missing after the collection.
+func (nbs *NomsBlockStore) BeginGC(keeper func(hash.Hash) bool) error {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	if nbs.keeper != nil {
+		return chunks.ErrGCInProgress
+	}
+
+	pending, err := nbs.pendingChunks()
 	if err != nil {
 		return err
 	}
+	for _, a := range pending {
+		keeper(hash.Hash(a))
+	}
 
+	nbs.keeper = keeper
 	return nil
 }
 
+// EndGC implements chunks.ChunkStoreGarbageCollector.
+func (nbs *NomsBlockStore) EndGC() {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	nbs.keeper = nil
+	nbs.gcCond.Broadcast()
+}
+
+// pendingChunks returns the addresses of the chunks in the memTable and the
+// novel tables of the store. callers must hold |nbs.mu|.
+func (nbs *NomsBlockStore) pendingChunks() ([]addr, error) {
+	var pending []addr
+	if nbs.mt != nil {
+		for _, r := range nbs.mt.order {
+			pending = append(pending, *r.a)
+		}
+	}
+
+	for _, cs := range nbs.tables.novel {
+		if jcs, ok := asJournalChunkSource(cs); ok {
+			pending = append(pending, jcs.wr.uncommittedAddrs()...)
+			continue
+		}
+
+		cnt, err := cs.count()
+		if err != nil {
+			return nil, err
+		} else if cnt == 0 {
+			continue
+		}
+		idx, err := cs.index()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < idx.ChunkCount(); i++ {
+			var a addr
+			if _, err = idx.IndexEntry(i, &a); err != nil {
+				return nil, err
+			}
+			pending = append(pending, a)
+		}
+	}
+	return pending, nil
+}
+
+// gcInProgress returns true if an online garbage collection is in progress.
+// callers must hold |nbs.mu|.
+func (nbs *NomsBlockStore) gcInProgress() bool {
+	return nbs.keeper != nil
+}
+
+// waitForGC hands |h| to the keeper of the online garbage collection in
+// progress, if there is one, and waits for the garbage collection to end if
+// the keeper asks it to. callers must hold |nbs.mu| for writing.
+func (nbs *NomsBlockStore) waitForGC(h hash.Hash) {
+	for nbs.keeper != nil && nbs.keeper(h) {
+		nbs.gcCond.Wait()
+	}
+}
+
+// keepFound hands the chunks of |found| that were found by a lookup to the
+// keeper of the online garbage collection in progress, if there is one. If
+// the keeper asks to wait for the garbage collection to end, keepFound waits
+// and returns false, and the lookup must be done again against the tables
+// the garbage collection left behind.
+func (nbs *NomsBlockStore) keepFound(found []hash.Hash) bool {
+	nbs.mu.Lock()
+	defer nbs.mu.Unlock()
+	for _, h := range found {
+		if nbs.keeper != nil && nbs.keeper(h) {
+			nbs.waitForGC(h)
+			return false
+		}
+	}
+	return true
+}
+
+// lockForUpdate acquires the update lock of |nbs.mm| and |nbs.mu|. If
+// |mustWait| returns true once both are held, it releases them and waits for
+// the online garbage collection in progress to end before it tries again, as
+// the garbage collection needs the update lock to swap in its tables.
+func (nbs *NomsBlockStore) lockForUpdate(mustWait func() bool) error {
+	for {
+		nbs.mm.LockForUpdate()
+		nbs.mu.Lock()
+		if !mustWait() {
+			return nil
+		}
+		nbs.mu.Unlock()
+		if err := nbs.mm.UnlockForUpdate(); err != nil {
+			return err
+		}
+
+		nbs.mu.Lock()
+		for mustWait() {
+			nbs.gcCond.Wait()
+		}
+		nbs.mu.Unlock()
+	}
+}
+
+// beginRead registers a read of |nbs.tables| that takes place outside of
+// |nbs.mu|. The returned function must be called once the read is done.
+// callers must hold |nbs.mu|.
+func (nbs *NomsBlockStore) beginRead() (endRead func()) {
+	wg := nbs.tableReaders
+	wg.Add(1)
+	return wg.Done
+}
+
 // SetRootChunk changes the root chunk hash from the previous value to the new root.
 func (nbs *NomsBlockStore) SetRootChunk(ctx context.Context, root, previous hash.Hash) error {
 	nbs.mu.Lock()
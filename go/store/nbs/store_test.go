@@ -153,6 +153,138 @@ func TestConcurrentPuts(t *testing.T) {
 	}
 }
 
+func TestNBSBackgroundConjoin(t *testing.T) {
+	ctx := context.Background()
+	st, dir, _ := makeTestLocalStore(t, defaultMaxTables)
+	err := st.SetConjoinPolicy(ConjoinPolicy{Strategy: ConjoinByTableCount, MaxTables: 4, Background: true})
+	require.NoError(t, err)
+
+	var all []map[hash.Hash]chunks.Chunk
+	var root hash.Hash
+	for i := 0; i < 16; i++ {
+		chks := makeChunkSet(8, 64)
+		all = append(all, chks)
+		root = putAndCommit(t, st, chks)
+	}
+	st.conjoins.Wait()
+
+	stats := st.Stats().(Stats)
+	assert.Greater(t, stats.BackgroundConjoinLatency.Samples(), uint64(0))
+	assert.Equal(t, uint64(0), stats.CommitConjoinLatency.Samples())
+	assert.Less(t, len(st.upstream.specs), 16)
+
+	for _, chks := range all {
+		for h, c := range chks {
+			out, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, c, out)
+		}
+	}
+	require.NoError(t, st.Close())
+
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), dir, defaultMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for _, chks := range all {
+		for h, c := range chks {
+			out, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, c, out)
+		}
+	}
+}
+
+// blockingConjoinPersister is a tablePersister whose conjoins block until
+// they are canceled.
+type blockingConjoinPersister struct {
+	tablePersister
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *blockingConjoinPersister) ConjoinAll(ctx context.Context, sources chunkSources, stats *Stats) (chunkSource, error) {
+	p.once.Do(func() { close(p.started) })
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestNBSBackgroundConjoinCanceledByClose(t *testing.T) {
+	st, _, _ := makeTestLocalStore(t, defaultMaxTables)
+	err := st.SetConjoinPolicy(ConjoinPolicy{Strategy: ConjoinByTableCount, MaxTables: 4, Background: true})
+	require.NoError(t, err)
+	p := &blockingConjoinPersister{tablePersister: st.p, started: make(chan struct{})}
+	st.mu.Lock()
+	st.p = p
+	st.mu.Unlock()
+
+	for i := 0; i < 8; i++ {
+		putAndCommit(t, st, makeChunkSet(8, 64))
+	}
+	select {
+	case <-p.started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("background conjoin did not start")
+	}
+
+	// Close cancels the conjoin, which fails
+	require.NoError(t, st.Close())
+	stats := st.Stats().(Stats)
+	assert.Equal(t, uint64(1), stats.AbandonedConjoins.Samples())
+	assert.Equal(t, uint64(0), stats.BackgroundConjoinLatency.Samples())
+}
+
+// firstNConjoiner is a conjoiner that conjoins the first |n| tables it is
+// given.
+type firstNConjoiner struct {
+	conjoiner
+	n int
+}
+
+func (c firstNConjoiner) ChooseConjoinees(sources chunkSources) (toConjoin, toKeep chunkSources, err error) {
+	return sources[:c.n], sources[c.n:], nil
+}
+
+func TestNBSAbandonedBackgroundConjoinIsRemoved(t *testing.T) {
+	ctx := context.Background()
+	st, dir, _ := makeTestLocalStore(t, defaultMaxTables)
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+
+	for i := 0; i < 4; i++ {
+		putAndCommit(t, st, makeChunkSet(8, 64))
+	}
+	st.mu.RLock()
+	stale := st.upstream
+	st.mu.RUnlock()
+
+	// conjoin every table, which lands
+	_, readers, err := st.landBackgroundConjoin(ctx, stale, firstNConjoiner{st.c, 4})
+	require.NoError(t, err)
+	require.NotNil(t, readers)
+	assert.Len(t, st.upstream.specs, 1)
+	before := tableFilesInDir(t, dir)
+
+	// a conjoin of the tables that were replaced is abandoned, and its table file is removed
+	_, readers, err = st.landBackgroundConjoin(ctx, stale, firstNConjoiner{st.c, 2})
+	require.NoError(t, err)
+	assert.Nil(t, readers)
+	assert.Equal(t, uint64(1), st.Stats().(Stats).AbandonedConjoins.Samples())
+	assert.ElementsMatch(t, before, tableFilesInDir(t, dir))
+
+	// the same conjoin as the one that landed is abandoned too, but its table file is kept
+	_, readers, err = st.landBackgroundConjoin(ctx, stale, firstNConjoiner{st.c, 4})
+	require.NoError(t, err)
+	assert.Nil(t, readers)
+	assert.ElementsMatch(t, before, tableFilesInDir(t, dir))
+	assert.Contains(t, before, st.upstream.specs[0].name.String())
+}
+
 func makeChunk(i uint32) chunks.Chunk {
 	b := make([]byte, 4)
 	binary.BigEndian.PutUint32(b, i)
@@ -333,6 +465,195 @@ func TestNBSCopyGC(t *testing.T) {
 	}
 }
 
+func TestNBSOnlineGC(t *testing.T) {
+	t.Run("table files", func(t *testing.T) {
+		st, _, _ := makeTestLocalStore(t, 8)
+		testOnlineGC(t, st)
+	})
+	t.Run("chunk journal", func(t *testing.T) {
+		st, _ := makeTestJournalingStore(t)
+		testOnlineGC(t, st)
+	})
+}
+
+func testOnlineGC(t *testing.T, st *NomsBlockStore) {
+	ctx := context.Background()
+	defer func() {
+		require.NoError(t, st.Close())
+	}()
+
+	keepers := makeChunkSet(64, 64)
+	tossers := makeChunkSet(64, 64)
+	putAndCommit(t, st, tossers)
+	root := putAndCommit(t, st, keepers)
+
+	// chunks that are written but not committed when the gc begins
+	pending := makeChunkSet(16, 64)
+	for _, c := range pending {
+		require.NoError(t, st.Put(ctx, c))
+	}
+
+	var mu sync.Mutex
+	finalizing := false
+	recorded := make(hash.HashSet)
+	keeper := func(h hash.Hash) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if finalizing {
+			return true
+		}
+		recorded.Insert(h)
+		return false
+	}
+	require.NoError(t, st.BeginGC(keeper))
+	assert.Equal(t, chunks.ErrGCInProgress, st.BeginGC(keeper))
+	for h := range pending {
+		assert.True(t, recorded.Has(h))
+	}
+
+	// readers of live chunks never see them missing
+	done := make(chan struct{})
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		for {
+			for h := range keepers {
+				c, err := st.Get(egCtx, h)
+				if err != nil {
+					return err
+				}
+				if c.IsEmpty() {
+					return fmt.Errorf("chunk %s is missing", h.String())
+				}
+			}
+			select {
+			case <-done:
+				return nil
+			default:
+			}
+		}
+	})
+
+	keepChan := make(chan []hash.Hash, 16)
+	eg.Go(func() error {
+		return st.MarkAndSweepChunks(egCtx, root, keepChan, nil)
+	})
+
+	// chunks written and roots committed while the gc walks the references are recorded
+	written := makeChunkSet(16, 64)
+	newRoot := putAndCommit(t, st, written)
+	for h := range written {
+		assert.True(t, recorded.Has(h))
+	}
+
+	for h := range keepers {
+		keepChan <- []hash.Hash{h}
+	}
+
+	mu.Lock()
+	finalizing = true
+	mu.Unlock()
+	for h := range recorded {
+		keepChan <- []hash.Hash{h}
+	}
+
+	// writes wait for the gc to end once it's finalizing
+	late := chunks.NewChunk([]byte("late"))
+	putDone := make(chan error)
+	go func() {
+		putDone <- st.Put(ctx, late)
+	}()
+
+	close(keepChan)
+	close(done)
+	require.NoError(t, eg.Wait())
+	select {
+	case <-putDone:
+		t.Fatal("Put did not wait for the gc to end")
+	case <-time.After(50 * time.Millisecond):
+	}
+	st.EndGC()
+	require.NoError(t, <-putDone)
+
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, newRoot, actual)
+	for _, chks := range []map[hash.Hash]chunks.Chunk{keepers, pending, written, {late.Hash(): late}} {
+		for h, c := range chks {
+			out, err := st.Get(ctx, h)
+			require.NoError(t, err)
+			assert.Equal(t, c, out)
+		}
+	}
+	for h := range tossers {
+		out, err := st.Get(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, chunks.EmptyChunk, out)
+	}
+}
+
+func TestNBSRepairChunks(t *testing.T) {
+	ctx := context.Background()
+
+	numTableFiles := 4
+	st, nomsDir, q := makeTestLocalStore(t, defaultMaxTables)
+	fileToData := populateLocalStore(t, st, numTableFiles)
+	require.NoError(t, st.Close())
+
+	damaged, err := CheckLocalTableFiles(ctx, nomsDir)
+	require.NoError(t, err)
+	assert.Empty(t, damaged)
+
+	// remove the last table file written by populateLocalStore
+	var lost []chunks.Chunk
+	for j := 0; j < numTableFiles; j++ {
+		lost = append(lost, chunks.NewChunk([]byte(fmt.Sprintf("%d:%d", numTableFiles-1, j))))
+	}
+	var chunkData [][]byte
+	for _, c := range lost {
+		chunkData = append(chunkData, c.Data())
+	}
+	_, lostAddr, err := buildTable(chunkData)
+	require.NoError(t, err)
+	require.Contains(t, fileToData, lostAddr.String())
+	require.NoError(t, os.Remove(filepath.Join(nomsDir, lostAddr.String())))
+
+	damaged, err = CheckLocalTableFiles(ctx, nomsDir)
+	require.NoError(t, err)
+	require.Len(t, damaged, 1)
+	assert.Equal(t, lostAddr.String(), damaged[0].Name)
+
+	st, err = NewLocalStoreWithoutTableFiles(ctx, types.Format_Default.VersionString(), nomsDir, []string{damaged[0].Name}, defaultMemTableSize, q)
+	require.NoError(t, err)
+	for _, c := range lost {
+		ok, err := st.Has(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	require.NoError(t, st.RepairChunks(ctx, lost))
+	for _, c := range lost {
+		ok, err := st.Has(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	require.NoError(t, st.Close())
+
+	// the missing table file is gone from the manifest for good
+	damaged, err = CheckLocalTableFiles(ctx, nomsDir)
+	require.NoError(t, err)
+	assert.Empty(t, damaged)
+
+	st, err = NewLocalStore(ctx, types.Format_Default.VersionString(), nomsDir, defaultMemTableSize, q)
+	require.NoError(t, err)
+	for _, c := range lost {
+		actual, err := st.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), actual.Data())
+	}
+	require.NoError(t, st.Close())
+	require.Equal(t, uint64(0), q.Usage())
+}
+
 func persistTableFileSources(t *testing.T, p tablePersister, numTableFiles int) (map[hash.Hash]uint32, []hash.Hash) {
 	tableFileMap := make(map[hash.Hash]uint32, numTableFiles)
 	mapIds := make([]hash.Hash, numTableFiles)
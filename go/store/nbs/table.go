@@ -120,6 +120,20 @@ import (
   - Calculate the Offset of your desired Chunk Record: Sum(Lengths[0]...Lengths[Ordinal-1])
   - Load Lengths[Ordinal] bytes from Table[Offset]
   - Check the first 4 bytes of the loaded data against the last 4 bytes of your desired Hash. They should match, and the rest of the data is your Chunk data.
+
+
+  Zstd Tables
+  A zstd table has the same layout as the table described above, but its Footer ends with a different Magic Number
+  (the first 8 bytes of the SHA256 hash of "https://github.com/dolthub/dolt/nbs/zstd") and its Chunk Data is compressed
+  with zstd using a dictionary trained on the chunks of the table, rather than with snappy. The dictionary is stored at
+  the beginning of the first Chunk Record, so that the Index and the offsets of every record are unchanged:
+
+   First Chunk Record:
+   +-------------------------------+------------+------------+----------------+
+   | (Uint32) Dictionary Length    | Dictionary | Chunk Data | (Uint32) CRC32 |
+   +-------------------------------+------------+------------+----------------+
+
+     -The CRC32 of the first record covers the dictionary as well as the Chunk Data.
 */
 
 const (
@@ -132,6 +146,7 @@ const (
 	lengthSize      = uint32Size
 	offsetSize      = uint64Size
 	magicNumber     = "\xff\xb5\xd8\xc2\x24\x63\xee\x50"
+	zstdMagicNumber = "\x39\xeb\x4e\x6e\x41\x1f\xd8\x24"
 	magicNumberSize = 8 //len(magicNumber)
 	footerSize      = uint32Size + uint64Size + magicNumberSize
 	prefixTupleSize = addrPrefixSize + ordinalSize
@@ -139,6 +154,37 @@ const (
 	maxChunkSize    = 0xffffffff // Snappy won't compress slices bigger than this
 )
 
+// TableFileFormat is the encoding of the chunk records in a table file.
+type TableFileFormat uint8
+
+const (
+	// SnappyTableFileFormat is the original table file format, in which
+	// every chunk is compressed independently with snappy.
+	SnappyTableFileFormat TableFileFormat = iota
+
+	// ZstdTableFileFormat compresses every chunk with zstd, using a
+	// dictionary trained on the chunks of the table file.
+	ZstdTableFileFormat
+)
+
+func (f TableFileFormat) String() string {
+	switch f {
+	case SnappyTableFileFormat:
+		return "snappy"
+	case ZstdTableFileFormat:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
+func (f TableFileFormat) magicNumber() string {
+	if f == ZstdTableFileFormat {
+		return zstdMagicNumber
+	}
+	return magicNumber
+}
+
 var crcTable = crc32.MakeTable(crc32.Castagnoli)
 
 func crc(b []byte) uint32 {
@@ -326,3 +372,15 @@ type TableFileStore interface {
 	// SupportedOperations returns a description of the support TableFile operations. Some stores only support reading table files, not writing.
 	SupportedOperations() TableFileStoreOps
 }
+
+// TableFileRewriter is implemented by stores that can rewrite their table files into another TableFileFormat.
+type TableFileRewriter interface {
+	// RewriteTableFiles rewrites the table files of the store that aren't in |format| into it.
+	RewriteTableFiles(ctx context.Context, format TableFileFormat) error
+}
+
+// TableFileFormatReporter is implemented by stores that can summarize their table files by TableFileFormat.
+type TableFileFormatReporter interface {
+	// TableFileFormatStats returns a summary of the table files of the store for each TableFileFormat in use.
+	TableFileFormatStats() (map[TableFileFormat]TableFileFormatStats, error)
+}
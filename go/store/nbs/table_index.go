@@ -63,6 +63,9 @@ type tableIndex interface {
 	// TotalUncompressedData returns the total uncompressed data size of
 	// the table file. Used for informational statistics only.
 	TotalUncompressedData() uint64
+	// TableFileFormat returns the encoding of the chunk records in the
+	// indexed table file.
+	TableFileFormat() TableFileFormat
 
 	// Close releases any resources used by this tableIndex.
 	Close() error
@@ -73,21 +76,31 @@ type tableIndex interface {
 }
 
 func ReadTableFooter(rd io.ReadSeeker) (chunkCount uint32, totalUncompressedData uint64, err error) {
+	chunkCount, totalUncompressedData, _, err = readTableFooter(rd)
+	return
+}
+
+func readTableFooter(rd io.ReadSeeker) (chunkCount uint32, totalUncompressedData uint64, format TableFileFormat, err error) {
 	footerSize := int64(magicNumberSize + uint64Size + uint32Size)
 	_, err = rd.Seek(-footerSize, io.SeekEnd)
 
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
 	footer, err := iohelp.ReadNBytes(rd, int(footerSize))
 
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
-	if string(footer[uint32Size+uint64Size:]) != magicNumber {
-		return 0, 0, ErrInvalidTableFile
+	switch string(footer[uint32Size+uint64Size:]) {
+	case magicNumber:
+		format = SnappyTableFileFormat
+	case zstdMagicNumber:
+		format = ZstdTableFileFormat
+	default:
+		return 0, 0, 0, ErrInvalidTableFile
 	}
 
 	chunkCount = binary.BigEndian.Uint32(footer)
@@ -108,7 +121,7 @@ func indexMemSize(chunkCount uint32) uint64 {
 // and footer and its length must match the expected indexSize for the chunkCount specified in the footer.
 // Retains the buffer and does not allocate new memory except for offsets, computes on buff in place.
 func parseTableIndex(buff []byte, q MemoryQuotaProvider) (onHeapTableIndex, error) {
-	chunkCount, totalUncompressedData, err := ReadTableFooter(bytes.NewReader(buff))
+	chunkCount, totalUncompressedData, format, err := readTableFooter(bytes.NewReader(buff))
 	if err != nil {
 		return onHeapTableIndex{}, err
 	}
@@ -122,13 +135,13 @@ func parseTableIndex(buff []byte, q MemoryQuotaProvider) (onHeapTableIndex, erro
 	chunks1 := chunkCount - chunks2
 	offsetsBuff1 := make([]byte, chunks1*offsetSize)
 
-	return newOnHeapTableIndex(buff, offsetsBuff1, chunkCount, totalUncompressedData, q)
+	return newOnHeapTableIndex(buff, offsetsBuff1, chunkCount, totalUncompressedData, format, q)
 }
 
 // similar to parseTableIndex except that it uses the given |offsetsBuff1|
 // instead of allocating the additional space.
 func parseTableIndexWithOffsetBuff(buff []byte, offsetsBuff1 []byte, q MemoryQuotaProvider) (onHeapTableIndex, error) {
-	chunkCount, totalUncompressedData, err := ReadTableFooter(bytes.NewReader(buff))
+	chunkCount, totalUncompressedData, format, err := readTableFooter(bytes.NewReader(buff))
 	if err != nil {
 		return onHeapTableIndex{}, err
 	}
@@ -138,7 +151,7 @@ func parseTableIndexWithOffsetBuff(buff []byte, offsetsBuff1 []byte, q MemoryQuo
 		return onHeapTableIndex{}, err
 	}
 
-	return newOnHeapTableIndex(buff, offsetsBuff1, chunkCount, totalUncompressedData, q)
+	return newOnHeapTableIndex(buff, offsetsBuff1, chunkCount, totalUncompressedData, format, q)
 }
 
 func removeFooter(p []byte, chunkCount uint32) (out []byte, err error) {
@@ -160,7 +173,7 @@ func parseTableIndexByCopy(buff []byte, q MemoryQuotaProvider) (onHeapTableIndex
 // ReadTableIndexByCopy loads an index into memory from an io.ReadSeeker
 // Caution: Allocates new memory for entire index
 func ReadTableIndexByCopy(rd io.ReadSeeker, q MemoryQuotaProvider) (onHeapTableIndex, error) {
-	chunkCount, totalUncompressedData, err := ReadTableFooter(rd)
+	chunkCount, totalUncompressedData, format, err := readTableFooter(rd)
 	if err != nil {
 		return onHeapTableIndex{}, err
 	}
@@ -179,7 +192,7 @@ func ReadTableIndexByCopy(rd io.ReadSeeker, q MemoryQuotaProvider) (onHeapTableI
 	chunks1 := chunkCount - chunks2
 	offsets1Buff := make([]byte, chunks1*offsetSize)
 
-	return newOnHeapTableIndex(buff, offsets1Buff, chunkCount, totalUncompressedData, q)
+	return newOnHeapTableIndex(buff, offsets1Buff, chunkCount, totalUncompressedData, format, q)
 }
 
 type onHeapTableIndex struct {
@@ -195,6 +208,7 @@ type onHeapTableIndex struct {
 	suffixB               []byte
 	chunkCount            uint32
 	totalUncompressedData uint64
+	format                TableFileFormat
 }
 
 var _ tableIndex = &onHeapTableIndex{}
@@ -206,7 +220,7 @@ var _ tableIndex = &onHeapTableIndex{}
 // additional space) and the rest into the region of |indexBuff| previously
 // occupied by lengths. |onHeapTableIndex| computes directly on the given
 // |indexBuff| and |offsetsBuff1| buffers.
-func newOnHeapTableIndex(indexBuff []byte, offsetsBuff1 []byte, chunkCount uint32, totalUncompressedData uint64, q MemoryQuotaProvider) (onHeapTableIndex, error) {
+func newOnHeapTableIndex(indexBuff []byte, offsetsBuff1 []byte, chunkCount uint32, totalUncompressedData uint64, format TableFileFormat, q MemoryQuotaProvider) (onHeapTableIndex, error) {
 	tuples := indexBuff[:prefixTupleSize*chunkCount]
 	lengths := indexBuff[prefixTupleSize*chunkCount : prefixTupleSize*chunkCount+lengthSize*chunkCount]
 	suffixes := indexBuff[prefixTupleSize*chunkCount+lengthSize*chunkCount:]
@@ -241,6 +255,7 @@ func newOnHeapTableIndex(indexBuff []byte, offsetsBuff1 []byte, chunkCount uint3
 		suffixB:               suffixes,
 		chunkCount:            chunkCount,
 		totalUncompressedData: totalUncompressedData,
+		format:                format,
 	}, nil
 }
 
@@ -531,6 +546,10 @@ func (ti onHeapTableIndex) TotalUncompressedData() uint64 {
 	return ti.totalUncompressedData
 }
 
+func (ti onHeapTableIndex) TableFileFormat() TableFileFormat {
+	return ti.format
+}
+
 func (ti onHeapTableIndex) Close() error {
 	cnt := atomic.AddInt32(ti.refCnt, -1)
 	if cnt == 0 {
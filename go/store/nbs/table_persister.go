@@ -53,6 +53,13 @@ type tablePersister interface {
 	PruneTableFiles(ctx context.Context, contents manifestContents) error
 }
 
+// tableFileRemover is implemented by tablePersisters that can delete a single
+// table file which was written, but never added to the manifest.
+type tableFileRemover interface {
+	// RemoveTableFile deletes the table file named |name|.
+	RemoveTableFile(ctx context.Context, name addr) error
+}
+
 type chunkSourcesByAscendingCount struct {
 	sources chunkSources
 	err     error
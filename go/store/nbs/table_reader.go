@@ -137,6 +137,8 @@ type tableReader struct {
 	totalUncompressedData uint64
 	r                     tableReaderAt
 	blockSize             uint64
+	// zstd decodes the chunk records of zstd table files, it's nil for snappy table files.
+	zstd *zstdChunkDecoder
 }
 
 // newTableReader parses a valid nbs table byte stream and returns a reader. buff must end with an NBS index
@@ -147,6 +149,10 @@ func newTableReader(index tableIndex, r tableReaderAt, blockSize uint64) (tableR
 	if err != nil {
 		return tableReader{}, err
 	}
+	var zd *zstdChunkDecoder
+	if index.TableFileFormat() == ZstdTableFileFormat {
+		zd = newZstdChunkDecoder(r)
+	}
 	return tableReader{
 		index,
 		p,
@@ -154,6 +160,7 @@ func newTableReader(index tableIndex, r tableReaderAt, blockSize uint64) (tableR
 		index.TotalUncompressedData(),
 		r,
 		blockSize,
+		zd,
 	}, nil
 }
 
@@ -256,7 +263,7 @@ func (tr tableReader) get(ctx context.Context, h addr, stats *Stats) ([]byte, er
 		return nil, errors.New("failed to get data")
 	}
 
-	chnk, err := cmp.ToChunk()
+	chnk, err := tr.toChunk(ctx, offset, cmp, stats)
 
 	if err != nil {
 		return nil, err
@@ -265,6 +272,32 @@ func (tr tableReader) get(ctx context.Context, h addr, stats *Stats) ([]byte, er
 	return chnk.Data(), nil
 }
 
+// toChunk decodes the chunk record |cmp|, read from |offset|.
+func (tr tableReader) toChunk(ctx context.Context, offset uint64, cmp CompressedChunk, stats *Stats) (chunks.Chunk, error) {
+	if tr.zstd == nil {
+		return cmp.ToChunk()
+	}
+	data, err := tr.zstd.decode(ctx, offset, cmp.CompressedData, stats)
+	if err != nil {
+		return chunks.Chunk{}, err
+	}
+	return chunks.NewChunkWithHash(cmp.H, data), nil
+}
+
+// toCompressedChunk returns the chunk record |cmp|, read from |offset|, as a snappy
+// CompressedChunk. CompressedChunks are always snappy encoded, whatever the format
+// of the table file they were read from.
+func (tr tableReader) toCompressedChunk(ctx context.Context, offset uint64, cmp CompressedChunk, stats *Stats) (CompressedChunk, error) {
+	if tr.zstd == nil {
+		return cmp, nil
+	}
+	chk, err := tr.toChunk(ctx, offset, cmp, stats)
+	if err != nil {
+		return CompressedChunk{}, err
+	}
+	return ChunkToCompressedChunk(chk), nil
+}
+
 type offsetRec struct {
 	a      *addr
 	offset uint64
@@ -286,7 +319,13 @@ func (tr tableReader) readCompressedAtOffsets(
 	found func(context.Context, CompressedChunk),
 	stats *Stats,
 ) error {
-	return tr.readAtOffsetsWithCB(ctx, rb, stats, func(ctx context.Context, cmp CompressedChunk) error {
+	return tr.readAtOffsetsWithCB(ctx, rb, stats, func(ctx context.Context, offset uint64, cmp CompressedChunk) error {
+		cmp, err := tr.toCompressedChunk(ctx, offset, cmp, stats)
+
+		if err != nil {
+			return err
+		}
+
 		found(ctx, cmp)
 		return nil
 	})
@@ -298,8 +337,8 @@ func (tr tableReader) readAtOffsets(
 	found func(context.Context, *chunks.Chunk),
 	stats *Stats,
 ) error {
-	return tr.readAtOffsetsWithCB(ctx, rb, stats, func(ctx context.Context, cmp CompressedChunk) error {
-		chk, err := cmp.ToChunk()
+	return tr.readAtOffsetsWithCB(ctx, rb, stats, func(ctx context.Context, offset uint64, cmp CompressedChunk) error {
+		chk, err := tr.toChunk(ctx, offset, cmp, stats)
 
 		if err != nil {
 			return err
@@ -314,7 +353,7 @@ func (tr tableReader) readAtOffsetsWithCB(
 	ctx context.Context,
 	rb readBatch,
 	stats *Stats,
-	cb func(ctx context.Context, cmp CompressedChunk) error,
+	cb func(ctx context.Context, offset uint64, cmp CompressedChunk) error,
 ) error {
 	readLength := rb.End() - rb.Start()
 	buff := make([]byte, readLength)
@@ -334,7 +373,7 @@ func (tr tableReader) readAtOffsetsWithCB(
 			return err
 		}
 
-		err = cb(ctx, cmp)
+		err = cb(ctx, rb[i].offset, cmp)
 		if err != nil {
 			return err
 		}
@@ -612,7 +651,7 @@ func (tr tableReader) extract(ctx context.Context, chunks chan<- extractRecord)
 			return err
 		}
 
-		chnk, err := cmp.ToChunk()
+		chnk, err := tr.toChunk(ctx, or.offset, cmp, &Stats{})
 
 		if err != nil {
 			return err
@@ -664,7 +703,7 @@ func (tr tableReader) Clone() (tableReader, error) {
 	if err != nil {
 		return tableReader{}, err
 	}
-	return tableReader{ti, tr.prefixes, tr.chunkCount, tr.totalUncompressedData, tr.r, tr.blockSize}, nil
+	return tableReader{ti, tr.prefixes, tr.chunkCount, tr.totalUncompressedData, tr.r, tr.blockSize, tr.zstd}, nil
 }
 
 type readerAdapter struct {
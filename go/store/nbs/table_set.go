@@ -300,11 +300,11 @@ func (ts tableSet) uncompressedLen() (uint64, error) {
 func (ts tableSet) physicalLen() (uint64, error) {
 	f := func(css chunkSources) (data uint64, err error) {
 		for _, haver := range css {
-			index, err := haver.index()
+			sz, err := haver.size()
 			if err != nil {
 				return 0, err
 			}
-			data += index.TableFileSize()
+			data += sz
 		}
 		return
 	}
@@ -322,6 +322,66 @@ func (ts tableSet) physicalLen() (uint64, error) {
 	return lenNovel + lenUp, nil
 }
 
+// tableFormatStats summarizes the table files of a tableSet that have the same TableFileFormat.
+// TableFileFormatStats summarizes the table files of a store in one TableFileFormat.
+type TableFileFormatStats struct {
+	TableFiles int
+	// PhysicalBytes is the size of the table files.
+	PhysicalBytes uint64
+	// UncompressedBytes is the size of the chunks in the table files, uncompressed.
+	UncompressedBytes uint64
+}
+
+func (s TableFileFormatStats) add(other TableFileFormatStats) TableFileFormatStats {
+	s.TableFiles += other.TableFiles
+	s.PhysicalBytes += other.PhysicalBytes
+	s.UncompressedBytes += other.UncompressedBytes
+	return s
+}
+
+// SavedPercent is the percentage of the uncompressed size of the chunks saved by compressing them.
+func (s TableFileFormatStats) SavedPercent() float64 {
+	if s.UncompressedBytes == 0 {
+		return 0
+	}
+	return 100 * (1 - float64(s.PhysicalBytes)/float64(s.UncompressedBytes))
+}
+
+// formatStats returns a summary of the table files in |ts| for each TableFileFormat.
+// The chunk journal is counted as a snappy table file.
+func (ts tableSet) formatStats() (map[TableFileFormat]TableFileFormatStats, error) {
+	stats := make(map[TableFileFormat]TableFileFormatStats)
+	for _, css := range []chunkSources{ts.novel, ts.upstream} {
+		for _, cs := range css {
+			if cnt, err := cs.count(); err != nil {
+				return nil, err
+			} else if cnt == 0 {
+				continue
+			}
+
+			format := SnappyTableFileFormat
+			index, err := cs.index()
+			if err == nil {
+				format = index.TableFileFormat()
+			} else if !errors.Is(err, errJournalIndex) {
+				return nil, err
+			}
+
+			sz, err := cs.size()
+			if err != nil {
+				return nil, err
+			}
+			ul, err := cs.uncompressedLen()
+			if err != nil {
+				return nil, err
+			}
+
+			stats[format] = stats[format].add(TableFileFormatStats{TableFiles: 1, PhysicalBytes: sz, UncompressedBytes: ul})
+		}
+	}
+	return stats, nil
+}
+
 func (ts tableSet) Close() error {
 	var firstErr error
 	setErr := func(err error) {
@@ -403,6 +463,9 @@ func (ts tableSet) Flatten(ctx context.Context) (tableSet, error) {
 		rl:       ts.rl,
 	}
 
+	// every memTable persisted to a chunk journal yields a source for the whole
+	// journal, so only the first of them is kept.
+	var hasJournal bool
 	for _, src := range ts.novel {
 		cnt, err := src.count()
 
@@ -411,11 +474,38 @@ func (ts tableSet) Flatten(ctx context.Context) (tableSet, error) {
 		}
 
 		if cnt > 0 {
+			h, err := src.hash()
+
+			if err != nil {
+				return tableSet{}, err
+			}
+
+			if h == journalAddr {
+				if hasJournal {
+					continue
+				}
+				hasJournal = true
+			}
+
 			flattened.upstream = append(flattened.upstream, src)
 		}
 	}
 
-	flattened.upstream = append(flattened.upstream, ts.upstream...)
+	for _, src := range ts.upstream {
+		if hasJournal {
+			h, err := src.hash()
+
+			if err != nil {
+				return tableSet{}, err
+			}
+
+			if h == journalAddr {
+				continue
+			}
+		}
+
+		flattened.upstream = append(flattened.upstream, src)
+	}
 	return flattened, nil
 }
 
@@ -538,6 +628,7 @@ OUTER:
 
 func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 	tableSpecs := make([]tableSpec, 0, ts.Size())
+	var hasJournal bool
 	for _, src := range ts.novel {
 		cnt, err := src.count()
 
@@ -552,6 +643,13 @@ func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 				return nil, err
 			}
 
+			if h == journalAddr {
+				if hasJournal {
+					continue
+				}
+				hasJournal = true
+			}
+
 			tableSpecs = append(tableSpecs, tableSpec{h, cnt})
 		}
 	}
@@ -572,6 +670,13 @@ func (ts tableSet) ToSpecs() ([]tableSpec, error) {
 			return nil, err
 		}
 
+		if h == journalAddr {
+			if hasJournal {
+				continue
+			}
+			hasJournal = true
+		}
+
 		tableSpecs = append(tableSpecs, tableSpec{h, cnt})
 	}
 	return tableSpecs, nil
@@ -15,6 +15,7 @@
 package nbs
 
 import (
+	"context"
 	"io"
 	"math"
 
@@ -32,6 +33,11 @@ func IterChunks(rd io.ReadSeeker, cb func(chunk chunks.Chunk) (stop bool, err er
 
 	defer idx.Close()
 
+	var zd *zstdChunkDecoder
+	if idx.TableFileFormat() == ZstdTableFileFormat {
+		zd = newZstdChunkDecoder(readSeekerAt{rd})
+	}
+
 	seen := make(map[addr]bool)
 	for i := uint32(0); i < idx.ChunkCount(); i++ {
 		var a addr
@@ -51,7 +57,14 @@ func IterChunks(rd io.ReadSeeker, cb func(chunk chunks.Chunk) (stop bool, err er
 				return err
 			}
 
-			chunk, err := cmpChnk.ToChunk()
+			var chunk chunks.Chunk
+			if zd != nil {
+				var data []byte
+				data, err = zd.decode(context.Background(), ie.Offset(), cmpChnk.CompressedData, &Stats{})
+				chunk = chunks.NewChunkWithHash(cmpChnk.H, data)
+			} else {
+				chunk, err = cmpChnk.ToChunk()
+			}
 			if err != nil {
 				return err
 			}
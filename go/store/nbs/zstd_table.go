@@ -0,0 +1,283 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/hash"
+)
+
+const (
+	// zstdMaxDictSamples is the maximum number of chunks sampled from a
+	// table file to train its dictionary.
+	zstdMaxDictSamples = 4096
+
+	// zstdDictHistorySize is the size of the history section of a trained
+	// dictionary, which holds raw chunk content that chunks can refer to.
+	zstdDictHistorySize = 64 * 1024
+
+	zstdDictID = 1
+
+	zstdEncoderLevel = zstd.SpeedBetterCompression
+)
+
+// zstdChunkEncoder compresses chunks with zstd for a zstd table file.
+type zstdChunkEncoder struct {
+	dict []byte
+	enc  *zstd.Encoder
+}
+
+func newZstdChunkEncoder(dict []byte) (*zstdChunkEncoder, error) {
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderDict(dict),
+		zstd.WithEncoderLevel(zstdEncoderLevel),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdChunkEncoder{dict: dict, enc: enc}, nil
+}
+
+// encodeRecord returns the chunk record for |data|. The first record of a
+// table file is prefixed with the dictionary of the table file.
+func (e *zstdChunkEncoder) encodeRecord(data []byte, first bool) []byte {
+	var rec []byte
+	if first {
+		rec = make([]byte, uint32Size, uint32Size+len(e.dict)+len(data))
+		binary.BigEndian.PutUint32(rec, uint32(len(e.dict)))
+		rec = append(rec, e.dict...)
+	}
+	rec = e.enc.EncodeAll(data, rec)
+	l := len(rec)
+	rec = append(rec, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(rec[l:], crc(rec[:l]))
+	return rec
+}
+
+// zstdChunkDecoder decompresses the chunk records of a zstd table file. The
+// dictionary of the table file is read the first time a chunk is decoded.
+type zstdChunkDecoder struct {
+	r tableReaderAt
+
+	mu  sync.Mutex
+	dec *zstd.Decoder
+}
+
+func newZstdChunkDecoder(r tableReaderAt) *zstdChunkDecoder {
+	return &zstdChunkDecoder{r: r}
+}
+
+// decode returns the chunk held by |payload|, the chunk record at |offset|
+// without its checksum.
+func (d *zstdChunkDecoder) decode(ctx context.Context, offset uint64, payload []byte, stats *Stats) ([]byte, error) {
+	if offset == 0 {
+		var err error
+		if _, payload, err = splitZstdDictionary(payload); err != nil {
+			return nil, err
+		}
+	}
+	dec, err := d.decoder(ctx, stats)
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(payload, nil)
+}
+
+func (d *zstdChunkDecoder) decoder(ctx context.Context, stats *Stats) (*zstd.Decoder, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dec != nil {
+		return d.dec, nil
+	}
+
+	var l [uint32Size]byte
+	if _, err := d.r.ReadAtWithStats(ctx, l[:], 0, stats); err != nil {
+		return nil, err
+	}
+	dict := make([]byte, binary.BigEndian.Uint32(l[:]))
+	if _, err := d.r.ReadAtWithStats(ctx, dict, uint32Size, stats); err != nil {
+		return nil, err
+	}
+
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict), zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		return nil, err
+	}
+	d.dec = dec
+	return dec, nil
+}
+
+// splitZstdDictionary splits the payload of the first chunk record of a zstd
+// table file into the dictionary and the compressed chunk.
+func splitZstdDictionary(payload []byte) (dict, frame []byte, err error) {
+	if len(payload) < uint32Size {
+		return nil, nil, ErrInvalidTableFile
+	}
+	l := uint64(binary.BigEndian.Uint32(payload))
+	if uint64(len(payload)) < uint32Size+l {
+		return nil, nil, ErrInvalidTableFile
+	}
+	return payload[uint32Size : uint32Size+l], payload[uint32Size+l:], nil
+}
+
+// buildZstdDictionary trains a dictionary on |samples|.
+func buildZstdDictionary(samples [][]byte) (dict []byte, err error) {
+	var history []byte
+	for i := len(samples) - 1; i >= 0 && len(history) < zstdDictHistorySize; i-- {
+		history = append(history, samples[i]...)
+	}
+	if len(samples) < 2 || len(history) < zstdDictHistorySize {
+		return nil, errors.New("not enough chunk data to train a dictionary")
+	}
+	history = history[:zstdDictHistorySize]
+
+	defer func() {
+		// BuildDict panics on some inputs that have too little in common to train on
+		if r := recover(); r != nil {
+			dict, err = nil, fmt.Errorf("failed to train a dictionary: %v", r)
+		}
+	}()
+	return zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       zstdDictID,
+		Contents: samples,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+		Level:    zstdEncoderLevel,
+	})
+}
+
+// rewriteTableFile writes the chunks of |src| into a new table file of
+// |format| in |dir|. It returns false if a zstd table file wouldn't be
+// smaller than |src|, in which case nothing is written.
+func rewriteTableFile(ctx context.Context, src chunkSource, dir string, format TableFileFormat) (tableSpec, bool, error) {
+	var tw *CmpChunkTableWriter
+	var err error
+	switch format {
+	case SnappyTableFileFormat:
+		tw, err = NewCmpChunkTableWriter("")
+	case ZstdTableFileFormat:
+		var samples [][]byte
+		if samples, err = sampleChunks(ctx, src); err != nil {
+			return tableSpec{}, false, err
+		}
+		dict, berr := buildZstdDictionary(samples)
+		if berr != nil {
+			// too few or too small chunks to train a dictionary
+			return tableSpec{}, false, nil
+		}
+		tw, err = newZstdChunkTableWriter("", dict)
+	default:
+		return tableSpec{}, false, fmt.Errorf("unknown table file format %d", format)
+	}
+	if err != nil {
+		return tableSpec{}, false, err
+	}
+	defer func() {
+		_ = tw.Remove()
+	}()
+
+	err = extractChunks(ctx, src, func(a addr, data []byte) error {
+		err := tw.AddCmpChunk(ChunkToCompressedChunk(chunks.NewChunkWithHash(hash.Hash(a), data)))
+		if errors.Is(err, ErrChunkAlreadyWritten) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return tableSpec{}, false, err
+	}
+
+	sz, err := src.size()
+	if err != nil {
+		return tableSpec{}, false, err
+	}
+	n := uint32(tw.ChunkCount())
+	if n == 0 || (format == ZstdTableFileFormat && tw.ContentLength()+indexSize(n)+footerSize >= sz) {
+		return tableSpec{}, false, nil
+	}
+
+	specs, err := (&gcCopier{writer: tw}).copyTablesToDir(ctx, dir)
+	if err != nil {
+		return tableSpec{}, false, err
+	}
+	return specs[0], true, nil
+}
+
+// sampleChunks returns up to zstdMaxDictSamples chunks evenly spaced across |src|.
+func sampleChunks(ctx context.Context, src chunkSource) ([][]byte, error) {
+	cnt, err := src.count()
+	if err != nil {
+		return nil, err
+	}
+	every := cnt/zstdMaxDictSamples + 1
+
+	var samples [][]byte
+	var i uint32
+	err = extractChunks(ctx, src, func(a addr, data []byte) error {
+		if i%every == 0 && len(data) > 0 {
+			samples = append(samples, data)
+		}
+		i++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// extractChunks calls |cb| with every chunk in |cs|.
+func extractChunks(ctx context.Context, cs chunkSource, cb func(a addr, data []byte) error) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	ch := make(chan extractRecord, 64)
+	eg.Go(func() error {
+		defer close(ch)
+		return cs.extract(ctx, ch)
+	})
+	eg.Go(func() error {
+		var err error
+		// keep draining |ch| after an error so that extract can finish
+		for rec := range ch {
+			if err == nil {
+				err = cb(rec.a, rec.data)
+			}
+		}
+		return err
+	})
+	return eg.Wait()
+}
+
+// readSeekerAt adapts an io.ReadSeeker to a tableReaderAt.
+type readSeekerAt struct {
+	rd io.ReadSeeker
+}
+
+func (r readSeekerAt) ReadAtWithStats(ctx context.Context, p []byte, off int64, stats *Stats) (int, error) {
+	if _, err := r.rd.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rd, p)
+}
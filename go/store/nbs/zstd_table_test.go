@@ -0,0 +1,241 @@
+// Copyright 2022 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nbs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dolthub/dolt/go/store/chunks"
+	"github.com/dolthub/dolt/go/store/constants"
+	"github.com/dolthub/dolt/go/store/hash"
+	"github.com/dolthub/dolt/go/store/util/tempfiles"
+)
+
+// makeCompressibleChunks returns |n| chunks with a lot of structure in common, like prolly tree nodes.
+func makeCompressibleChunks(n int) []chunks.Chunk {
+	chks := make([]chunks.Chunk, n)
+	for i := range chks {
+		var sb strings.Builder
+		for j := 0; j < 32; j++ {
+			fmt.Fprintf(&sb, "{\"id\": %d, \"name\": \"customer_%d\", \"email\": \"customer_%d@example.com\", \"status\": \"active\"}\n", i*32+j, (i*7+j)%1000, i*32+j)
+		}
+		chks[i] = chunks.NewChunk([]byte(sb.String()))
+	}
+	return chks
+}
+
+func writeZstdTable(t *testing.T, chks []chunks.Chunk) []byte {
+	samples := make([][]byte, len(chks))
+	for i, c := range chks {
+		samples[i] = c.Data()
+	}
+	dict, err := buildZstdDictionary(samples)
+	require.NoError(t, err)
+
+	tw, err := newZstdChunkTableWriter("", dict)
+	require.NoError(t, err)
+	defer tw.Remove()
+	for _, c := range chks {
+		require.NoError(t, tw.AddCmpChunk(ChunkToCompressedChunk(c)))
+	}
+	_, err = tw.Finish()
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, tw.Flush(buf))
+	return buf.Bytes()
+}
+
+func TestZstdTableReader(t *testing.T) {
+	ctx := context.Background()
+	chks := makeCompressibleChunks(256)
+	buff := writeZstdTable(t, chks)
+
+	var snappySize int
+	for _, c := range chks {
+		snappySize += len(ChunkToCompressedChunk(c).FullCompressedChunk)
+	}
+	assert.Less(t, len(buff), snappySize)
+
+	ti, err := parseTableIndexByCopy(buff, &noopQuotaProvider{})
+	require.NoError(t, err)
+	assert.Equal(t, ZstdTableFileFormat, ti.TableFileFormat())
+	tr, err := newTableReader(ti, tableReaderAtFromBytes(buff), fileBlockSize)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	hashes := make(hash.HashSet)
+	for _, c := range chks {
+		hashes.Insert(c.Hash())
+		data, err := tr.get(ctx, addr(c.Hash()), &Stats{})
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), data)
+	}
+
+	actual, err := readAllChunks(ctx, hashes, tr)
+	require.NoError(t, err)
+	assert.Len(t, actual, len(chks))
+	for _, c := range chks {
+		assert.Equal(t, c.Data(), actual[c.Hash()])
+	}
+
+	// compressed chunks read from zstd tables are snappy encoded
+	var mu sync.Mutex
+	found := make(map[hash.Hash]CompressedChunk)
+	eg, egCtx := errgroup.WithContext(ctx)
+	_, err = tr.getManyCompressed(egCtx, eg, toGetRecords(hashes), func(ctx context.Context, c CompressedChunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		found[c.H] = c
+	}, &Stats{})
+	require.NoError(t, err)
+	require.NoError(t, eg.Wait())
+	require.Len(t, found, len(chks))
+	for _, c := range chks {
+		out, err := found[c.Hash()].ToChunk()
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), out.Data())
+	}
+
+	extracted := 0
+	err = extractChunks(ctx, chunkSourceAdapter{tr, addr{}}, func(a addr, data []byte) error {
+		assert.True(t, hashes.Has(hash.Hash(a)))
+		assert.Equal(t, hash.Of(data), hash.Hash(a))
+		extracted++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(chks), extracted)
+
+	iterated := 0
+	err = IterChunks(bytes.NewReader(buff), func(c chunks.Chunk) (bool, error) {
+		assert.True(t, hashes.Has(c.Hash()))
+		assert.Equal(t, hash.Of(c.Data()), c.Hash())
+		iterated++
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(chks), iterated)
+}
+
+func TestRewriteTableFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(tempfiles.MovableTempFileProvider.GetTempDir(), "noms_"+uuid.New().String()[:8])
+	require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	defer os.RemoveAll(dir)
+
+	st, err := NewLocalStore(ctx, constants.FormatDefaultString, dir, defaultMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+
+	chks := makeCompressibleChunks(512)
+	for _, c := range chks {
+		require.NoError(t, st.Put(ctx, c))
+	}
+	root := chks[0].Hash()
+	ok, err := st.Commit(ctx, root, hash.Hash{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	before, err := st.Size(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, st.RewriteTableFiles(ctx, ZstdTableFileFormat))
+	after, err := st.Size(ctx)
+	require.NoError(t, err)
+	assert.Less(t, after, before)
+	assert.Contains(t, st.StatsSummary(), "zstd Table Files 1")
+	assert.NotContains(t, st.StatsSummary(), "snappy Table Files")
+
+	// zstd tables are left as they are
+	require.NoError(t, st.RewriteTableFiles(ctx, ZstdTableFileFormat))
+	assert.Len(t, tableFilesInDir(t, dir), 1)
+	require.NoError(t, st.Close())
+
+	st, err = NewLocalStore(ctx, constants.FormatDefaultString, dir, defaultMemTableSize, NewUnlimitedMemQuotaProvider())
+	require.NoError(t, err)
+	defer st.Close()
+	actual, err := st.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, root, actual)
+	for _, c := range chks {
+		out, err := st.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), out.Data())
+	}
+
+	// chunk locations are given in a snappy copy of the zstd table
+	hashes := hash.NewHashSet()
+	for _, c := range chks {
+		hashes.Insert(c.Hash())
+	}
+	locs, err := st.GetChunkLocations(hashes.Copy())
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	for name, ranges := range locs {
+		path, ok, err := st.TableFilePath(name.String())
+		require.NoError(t, err)
+		require.True(t, ok)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, ranges, len(hashes))
+		for h, r := range ranges {
+			cc, err := NewCompressedChunk(h, data[r.Offset:r.Offset+uint64(r.Length)])
+			require.NoError(t, err)
+			c, err := cc.ToChunk()
+			require.NoError(t, err)
+			assert.Equal(t, h, c.Hash())
+		}
+	}
+
+	// garbage collection keeps the tables compressed, and prunes the snappy copy
+	keepChan := make(chan []hash.Hash, len(chks))
+	for _, c := range chks {
+		keepChan <- []hash.Hash{c.Hash()}
+	}
+	close(keepChan)
+	require.NoError(t, st.MarkAndSweepChunks(ctx, root, keepChan, nil))
+	assert.Len(t, tableFilesInDir(t, dir), 1)
+	assert.Contains(t, st.StatsSummary(), "zstd Table Files 1")
+	assert.NotContains(t, st.StatsSummary(), "snappy Table Files")
+	for _, c := range chks {
+		out, err := st.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), out.Data())
+	}
+	locs, err = st.GetChunkLocations(hashes.Copy())
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+
+	// and can be rewritten back into snappy tables
+	require.NoError(t, st.RewriteTableFiles(ctx, SnappyTableFileFormat))
+	assert.Len(t, tableFilesInDir(t, dir), 1)
+	assert.Contains(t, st.StatsSummary(), "snappy Table Files 1")
+	for _, c := range chks {
+		out, err := st.Get(ctx, c.Hash())
+		require.NoError(t, err)
+		assert.Equal(t, c.Data(), out.Data())
+	}
+}
@@ -42,6 +42,22 @@ func unfilteredHashFunc(_ context.Context, hs hash.HashSet) (hash.HashSet, error
 	return hs, nil
 }
 
+// excludingHashFunc returns a HashFilterFunc that removes |excluded| before filtering with |filter|.
+func excludingHashFunc(filter HashFilterFunc, excluded hash.HashSet) HashFilterFunc {
+	if len(excluded) == 0 {
+		return filter
+	}
+	return func(ctx context.Context, hs hash.HashSet) (hash.HashSet, error) {
+		remaining := make(hash.HashSet, len(hs))
+		for h := range hs {
+			if !excluded.Has(h) {
+				remaining.Insert(h)
+			}
+		}
+		return filter(ctx, remaining)
+	}
+}
+
 // ValueReader is an interface that knows how to read Noms Values, e.g.
 // datas/Database. Required to avoid import cycle between this package and the
 // package that implements Value reading.
@@ -85,9 +101,28 @@ type ValueStore struct {
 	decodedChunks        *sizecache.SizeCache
 	nbf                  *NomsBinFormat
 
+	gcMu       sync.Mutex
+	gcState    gcState
+	gcNewAddrs hash.HashSet
+
 	versOnce sync.Once
 }
 
+// gcState is the phase of a garbage collection of a ValueStore.
+type gcState int
+
+const (
+	gcState_NoGC gcState = iota
+	// gcState_Collecting is the phase in which the references of the roots
+	// are walked. Chunks written and roots committed are recorded, so that
+	// they can be walked once the roots have been.
+	gcState_Collecting
+	// gcState_Finalizing is the phase in which the recorded chunks are
+	// walked and the chunk store is swept. Writes to the chunk store wait
+	// for it to end.
+	gcState_Finalizing
+)
+
 func PanicIfDangling(ctx context.Context, unresolved hash.HashSet, cs chunks.ChunkStore) {
 	absent, err := cs.HasMany(ctx, unresolved)
 
@@ -662,31 +697,44 @@ func makeBatches(hss []hash.HashSet, count int) [][]hash.Hash {
 	return res
 }
 
-func (lvs *ValueStore) numBuffChunks() int {
-	lvs.bufferMu.RLock()
-	defer lvs.bufferMu.RUnlock()
-	return len(lvs.bufferedChunks)
-}
+// GCRootsFunc returns the roots that clients of a ValueStore hold, which a garbage collection must
+// keep although they may not be reachable from the root of the ChunkStore.
+type GCRootsFunc func(ctx context.Context) (hash.HashSet, error)
+
+// GC traverses the ValueStore from the root and removes unreferenced chunks from the ChunkStore.
+// Clients may keep reading from and writing to the ValueStore while it runs. Chunks that are
+// written and roots that are committed while references are walked are kept, and writes that
+// arrive after that wait for the collection to end. Values that are only reachable from roots
+// that are neither committed nor in |oldGenRefs| or |newGenRefs| nor returned by |inUse| may be
+// collected. |inUse| may be nil. Otherwise it is called once the collection has begun, so the
+// roots clients make after it returns are kept as their chunks are written. References to the
+// chunks in |absent| are not followed, they are known to be missing from the ChunkStore.
+func (lvs *ValueStore) GC(ctx context.Context, oldGenRefs, newGenRefs, absent hash.HashSet, inUse GCRootsFunc) error {
+	lvs.versOnce.Do(lvs.expectVersion)
 
-// GC traverses the ValueStore from the root and removes unreferenced chunks from the ChunkStore
-func (lvs *ValueStore) GC(ctx context.Context, oldGenRefs, newGenRefs hash.HashSet) error {
-	if lvs.numBuffChunks() > 0 {
-		return errors.New("invalid GC state; bufferedChunks must be empty.")
+	gcs, isGenerational := lvs.cs.(chunks.GenerationalCS)
+	var collector chunks.ChunkStoreGarbageCollector
+	if isGenerational {
+		collector = gcs.NewGen()
+	} else if c, ok := lvs.cs.(chunks.ChunkStoreGarbageCollector); ok {
+		collector = c
+	} else {
+		return chunks.ErrUnsupportedOperation
 	}
 
-	err := func() error {
-		lvs.bufferMu.RLock()
-		defer lvs.bufferMu.RUnlock()
-		if len(lvs.bufferedChunks) > 0 {
-			return errors.New("invalid GC state; bufferedChunks must be empty.")
-		}
-		return nil
-	}()
+	err := lvs.beginGC(collector)
 	if err != nil {
 		return err
 	}
+	defer lvs.endGC(collector)
 
-	lvs.versOnce.Do(lvs.expectVersion)
+	if inUse != nil {
+		roots, err := inUse(ctx)
+		if err != nil {
+			return err
+		}
+		newGenRefs.InsertAll(roots)
+	}
 
 	root, err := lvs.Root(ctx)
 
@@ -705,27 +753,154 @@ func (lvs *ValueStore) GC(ctx context.Context, oldGenRefs, newGenRefs hash.HashS
 	}
 
 	newGenRefs.Insert(root)
-	if gcs, ok := lvs.cs.(chunks.GenerationalCS); ok {
+	if isGenerational {
 		oldGen := gcs.OldGen()
-		newGen := gcs.NewGen()
-		err = lvs.gc(ctx, root, oldGenRefs, oldGen.HasMany, newGen, oldGen)
+		err = lvs.gc(ctx, root, oldGenRefs, excludingHashFunc(oldGen.HasMany, absent), collector, oldGen, nil)
 		if err != nil {
 			return err
 		}
 
-		return lvs.gc(ctx, root, newGenRefs, oldGen.HasMany, newGen, newGen)
-	} else if collector, ok := lvs.cs.(chunks.ChunkStoreGarbageCollector); ok {
+		err = lvs.gc(ctx, root, newGenRefs, excludingHashFunc(oldGen.HasMany, absent), collector, collector, lvs.finalizeGC)
+	} else {
 		if len(oldGenRefs) > 0 {
 			newGenRefs.InsertAll(oldGenRefs)
 		}
 
-		return lvs.gc(ctx, root, newGenRefs, unfilteredHashFunc, collector, collector)
-	} else {
-		return chunks.ErrUnsupportedOperation
+		err = lvs.gc(ctx, root, newGenRefs, excludingHashFunc(unfilteredHashFunc, absent), collector, collector, lvs.finalizeGC)
+	}
+	if err != nil {
+		return err
+	}
+
+	// purge the cache
+	lvs.decodedChunks.Purge()
+
+	return nil
+}
+
+func (lvs *ValueStore) beginGC(collector chunks.ChunkStoreGarbageCollector) error {
+	err := func() error {
+		lvs.gcMu.Lock()
+		defer lvs.gcMu.Unlock()
+		if lvs.gcState != gcState_NoGC {
+			return chunks.ErrGCInProgress
+		}
+		lvs.gcState = gcState_Collecting
+		lvs.gcNewAddrs = make(hash.HashSet)
+		return nil
+	}()
+	if err != nil {
+		return err
 	}
+
+	err = collector.BeginGC(lvs.gcAddChunk)
+	if err != nil {
+		lvs.gcMu.Lock()
+		defer lvs.gcMu.Unlock()
+		lvs.gcState = gcState_NoGC
+		lvs.gcNewAddrs = nil
+	}
+	return err
+}
+
+func (lvs *ValueStore) endGC(collector chunks.ChunkStoreGarbageCollector) {
+	collector.EndGC()
+
+	lvs.gcMu.Lock()
+	defer lvs.gcMu.Unlock()
+	lvs.gcState = gcState_NoGC
+	lvs.gcNewAddrs = nil
 }
 
-func (lvs *ValueStore) gc(ctx context.Context, root hash.Hash, toVisit hash.HashSet, hashFilter HashFilterFunc, src, dest chunks.ChunkStoreGarbageCollector) error {
+// gcAddChunk is the keeper of the ChunkStore during a garbage collection. It
+// records |h| while references are being walked, and returns true to make the
+// ChunkStore wait for the collection to end once they have been.
+func (lvs *ValueStore) gcAddChunk(h hash.Hash) bool {
+	lvs.gcMu.Lock()
+	defer lvs.gcMu.Unlock()
+	switch lvs.gcState {
+	case gcState_Collecting:
+		lvs.gcNewAddrs.Insert(h)
+		return false
+	case gcState_Finalizing:
+		return true
+	default:
+		return false
+	}
+}
+
+// finalizeGC ends the collecting phase of a garbage collection. It returns the
+// chunks recorded during that phase and the buffered chunks of the ValueStore,
+// which must be walked before the ChunkStore is swept, and a function to read
+// them with. Writers that wait for the collection to end may be holding
+// |lvs.bufferMu|, so the values are read without it.
+func (lvs *ValueStore) finalizeGC() (hash.HashSet, readValuesFunc) {
+	lvs.bufferMu.Lock()
+	defer lvs.bufferMu.Unlock()
+
+	lvs.gcMu.Lock()
+	defer lvs.gcMu.Unlock()
+	lvs.gcState = gcState_Finalizing
+	toVisit := lvs.gcNewAddrs
+	lvs.gcNewAddrs = make(hash.HashSet)
+
+	buffered := make(map[hash.Hash]chunks.Chunk, len(lvs.bufferedChunks))
+	for h, c := range lvs.bufferedChunks {
+		buffered[h] = c
+		toVisit.Insert(h)
+	}
+
+	read := func(ctx context.Context, hashes hash.HashSlice) (ValueSlice, error) {
+		return lvs.readValuesForGC(ctx, hashes, buffered)
+	}
+	return toVisit, read
+}
+
+// readValuesForGC reads the values of |hashes| from |buffered| and the ChunkStore
+// and returns the ones it found.
+func (lvs *ValueStore) readValuesForGC(ctx context.Context, hashes hash.HashSlice, buffered map[hash.Hash]chunks.Chunk) (ValueSlice, error) {
+	var vals ValueSlice
+	remaining := hash.HashSet{}
+	for _, h := range hashes {
+		if c, ok := buffered[h]; ok {
+			v, err := DecodeValue(c, lvs)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		} else {
+			remaining.Insert(h)
+		}
+	}
+
+	mu := new(sync.Mutex)
+	var decodeErr error
+	err := lvs.cs.GetMany(ctx, remaining, func(ctx context.Context, c *chunks.Chunk) {
+		mu.Lock()
+		defer mu.Unlock()
+		if decodeErr != nil {
+			return
+		}
+		var v Value
+		v, decodeErr = DecodeValue(*c, lvs)
+		if decodeErr == nil {
+			vals = append(vals, v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return vals, nil
+}
+
+type readValuesFunc func(ctx context.Context, hashes hash.HashSlice) (ValueSlice, error)
+
+// gc walks the references of |toVisit| and sweeps |src| into |dest|. If |finalize| is not
+// nil, the chunks it returns are walked after those of |toVisit|, before |src| is swept.
+func (lvs *ValueStore) gc(ctx context.Context, root hash.Hash, toVisit hash.HashSet, hashFilter HashFilterFunc, src, dest chunks.ChunkStoreGarbageCollector, finalize func() (hash.HashSet, readValuesFunc)) error {
 	keepChunks := make(chan []hash.Hash, gcBuffSize)
 
 	eg, ctx := errgroup.WithContext(ctx)
@@ -752,11 +927,30 @@ func (lvs *ValueStore) gc(ctx context.Context, root hash.Hash, toVisit hash.Hash
 		defer walker.Close()
 
 		visited := toVisit.Copy()
-		err := lvs.gcProcessRefs(ctx, visited, []hash.HashSet{toVisit}, keepHashes, walker, hashFilter)
+		err := lvs.gcProcessRefs(ctx, visited, []hash.HashSet{toVisit}, keepHashes, walker, hashFilter, lvs.ReadManyValues)
 		if err != nil {
 			return err
 		}
 
+		if finalize != nil {
+			final, read := finalize()
+			for h := range final {
+				if visited.Has(h) {
+					final.Remove(h)
+				}
+			}
+			final, err = hashFilter(ctx, final)
+			if err != nil {
+				return err
+			}
+			visited.InsertAll(final)
+
+			err = lvs.gcProcessRefs(ctx, visited, []hash.HashSet{final}, keepHashes, walker, hashFilter, read)
+			if err != nil {
+				return err
+			}
+		}
+
 		// NOTE: We do not defer this close here. When keepChunks
 		// closes, it signals to NBSStore.MarkAndSweepChunks that we
 		// are done walking the references. If gcProcessRefs returns an
@@ -777,7 +971,7 @@ func (lvs *ValueStore) gc(ctx context.Context, root hash.Hash, toVisit hash.Hash
 	return eg.Wait()
 }
 
-func (lvs *ValueStore) gcProcessRefs(ctx context.Context, visited hash.HashSet, toVisit []hash.HashSet, keepHashes func(hs []hash.Hash) error, walker *parallelRefWalker, hashFilter HashFilterFunc) error {
+func (lvs *ValueStore) gcProcessRefs(ctx context.Context, visited hash.HashSet, toVisit []hash.HashSet, keepHashes func(hs []hash.Hash) error, walker *parallelRefWalker, hashFilter HashFilterFunc, read readValuesFunc) error {
 	if len(toVisit) != 1 {
 		panic("Must be one initial hashset to visit")
 	}
@@ -792,7 +986,7 @@ func (lvs *ValueStore) gcProcessRefs(ctx context.Context, visited hash.HashSet,
 				return err
 			}
 
-			vals, err := lvs.ReadManyValues(ctx, batch)
+			vals, err := read(ctx, batch)
 			if err != nil {
 				return err
 			}
@@ -816,19 +1010,6 @@ func (lvs *ValueStore) gcProcessRefs(ctx context.Context, visited hash.HashSet,
 		}
 	}
 
-	lvs.bufferMu.Lock()
-	defer lvs.bufferMu.Unlock()
-
-	if len(lvs.bufferedChunks) > 0 {
-		return errors.New("invalid GC state; bufferedChunks started empty and was not empty at end of run.")
-	}
-
-	// purge the cache
-	lvs.decodedChunks = sizecache.New(lvs.decodedChunks.Size())
-	lvs.bufferedChunks = make(map[hash.Hash]chunks.Chunk, lvs.bufferedChunkSize)
-	lvs.bufferedChunkSize = 0
-	lvs.withBufferedChildren = map[hash.Hash]uint64{}
-
 	return nil
 }
 
@@ -23,10 +23,12 @@ package types
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/dolthub/dolt/go/store/chunks"
 	"github.com/dolthub/dolt/go/store/hash"
@@ -384,7 +386,7 @@ func TestGC(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(v2)
 
-	err = vs.GC(ctx, hash.HashSet{}, hash.HashSet{})
+	err = vs.GC(ctx, hash.HashSet{}, hash.HashSet{}, hash.HashSet{}, nil)
 	require.NoError(t, err)
 
 	v1, err = vs.ReadValue(ctx, h1) // non-nil
@@ -395,6 +397,82 @@ func TestGC(t *testing.T) {
 	assert.Nil(v2)
 }
 
+func TestGCWithConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	vs := newTestValueStore()
+
+	commit := func(v Value) (hash.Hash, error) {
+		h, err := vs.WriteValue(ctx, v)
+		if err != nil {
+			return hash.Hash{}, err
+		}
+		rt, err := vs.Root(ctx)
+		if err != nil {
+			return hash.Hash{}, err
+		}
+		ok, err := vs.Commit(ctx, h.TargetHash(), rt)
+		if err != nil {
+			return hash.Hash{}, err
+		} else if !ok {
+			return hash.Hash{}, fmt.Errorf("failed to commit %s", h.TargetHash().String())
+		}
+		return h.TargetHash(), nil
+	}
+
+	_, err := commit(mustList(NewList(ctx, vs, mustRef(vs.WriteValue(ctx, String("initial"))))))
+	require.NoError(t, err)
+
+	// every root committed while the GC runs is kept, along with the values it references
+	stop := make(chan struct{})
+	var roots []hash.Hash
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return nil
+			case <-egCtx.Done():
+				return egCtx.Err()
+			default:
+			}
+			r, err := vs.WriteValue(ctx, String(fmt.Sprintf("value %d", i)))
+			if err != nil {
+				return err
+			}
+			l, err := NewList(ctx, vs, r)
+			if err != nil {
+				return err
+			}
+			h, err := commit(l)
+			if err != nil {
+				return err
+			}
+			roots = append(roots, h)
+		}
+	})
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, vs.GC(ctx, hash.HashSet{}, hash.HashSet{}, hash.HashSet{}, nil))
+	}
+	close(stop)
+	require.NoError(t, eg.Wait())
+
+	require.NotEmpty(t, roots)
+	last := roots[len(roots)-1]
+	rt, err := vs.Root(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, last, rt)
+
+	v, err := vs.ReadValue(ctx, last)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	el, err := v.(List).Get(ctx, 0)
+	require.NoError(t, err)
+	s, err := el.(Ref).TargetValue(ctx, vs)
+	require.NoError(t, err)
+	assert.Equal(t, String(fmt.Sprintf("value %d", len(roots)-1)), s)
+}
+
 type badVersionStore struct {
 	chunks.ChunkStore
 }
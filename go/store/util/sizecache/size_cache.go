@@ -140,6 +140,16 @@ func (c *SizeCache) Drop(key interface{}) {
 	}
 }
 
+// Purge removes every element from the cache.
+func (c *SizeCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Init()
+	c.cache = map[interface{}]sizeCacheEntry{}
+	c.totalSize = 0
+}
+
 func (c *SizeCache) Size() uint64 {
 	return c.maxSize
 }
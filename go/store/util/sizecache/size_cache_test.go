@@ -93,6 +93,13 @@ func TestSizeCache(t *testing.T) {
 	assert.Equal(uint64(800), c.totalSize)
 	assert.Equal(4, c.lru.Len())
 	assert.Equal(4, len(c.cache))
+
+	c.Purge()
+	assert.Equal(uint64(0), c.totalSize)
+	assert.Equal(0, c.lru.Len())
+	assert.Equal(0, len(c.cache))
+	_, ok = c.Get(hashFromString("data-6"))
+	assert.False(ok)
 }
 
 func TestSizeCacheWithExpiry(t *testing.T) {
@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/dolthub/dolt/go/libraries/doltcore/remotesrv"
 	"github.com/dolthub/dolt/go/libraries/utils/filesys"
 	"github.com/dolthub/dolt/go/store/nbs"
 )
@@ -27,6 +28,8 @@ const (
 	defaultMemTableSize = 128 * 1024 * 1024
 )
 
+// DBCache is a remotesrv.DBCache of local stores in a directory of the filesystem. Stores are created the first time
+// they are requested.
 type DBCache struct {
 	mu  *sync.Mutex
 	dbs map[string]*nbs.NomsBlockStore
@@ -42,7 +45,9 @@ func NewLocalCSCache(filesys filesys.Filesys) *DBCache {
 	}
 }
 
-func (cache *DBCache) Get(org, repo, nbfVerStr string) (*nbs.NomsBlockStore, error) {
+var _ remotesrv.DBCache = &DBCache{}
+
+func (cache *DBCache) Get(ctx context.Context, org, repo, nbfVerStr string) (remotesrv.RemoteSrvStore, error) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
 
@@ -60,7 +65,7 @@ func (cache *DBCache) Get(org, repo, nbfVerStr string) (*nbs.NomsBlockStore, err
 			return nil, err
 		}
 
-		newCS, err = nbs.NewLocalStore(context.TODO(), nbfVerStr, id, defaultMemTableSize, nbs.NewUnlimitedMemQuotaProvider())
+		newCS, err = nbs.NewLocalStore(ctx, nbfVerStr, id, defaultMemTableSize, nbs.NewUnlimitedMemQuotaProvider())
 
 		if err != nil {
 			return nil, err
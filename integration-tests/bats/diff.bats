@@ -542,6 +542,72 @@ SQL
     [[ "$output" =~ "where pk=4" ]] || false
 }
 
+@test "diff: dolt_diff_summary table function matches summary" {
+    dolt sql -q "insert into test values (0, 0, 0, 0, 0, 0)"
+    dolt sql -q "insert into test values (1, 1, 1, 1, 1, 1)"
+    dolt add test
+    dolt commit -m "table created"
+    dolt sql -q "insert into test values (2, 11, 0, 0, 0, 0)"
+    dolt sql -q "replace into test values (0, 11, 0, 0, 0, 6)"
+    dolt sql -q "create table other (pk int primary key)"
+    dolt sql -q "insert into other values (1)"
+
+    run dolt diff --summary test
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "1 Row Unmodified (50.00%)" ]] || false
+    [[ "$output" =~ "1 Row Added (50.00%)" ]] || false
+    [[ "$output" =~ "1 Row Modified (50.00%)" ]] || false
+    [[ "$output" =~ "2 Cells Modified (16.67%)" ]] || false
+    [[ "$output" =~ "(2 Entries vs 3 Entries)" ]] || false
+
+    run dolt sql -r csv -q "select * from dolt_diff_summary('HEAD', 'WORKING')"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 3 ]
+    [ "${lines[0]}" = "table_name,rows_unmodified,rows_added,rows_deleted,rows_modified,cells_modified,old_row_count,new_row_count,schema_change" ]
+    [ "${lines[1]}" = "other,0,1,0,0,0,0,1,true" ]
+    [ "${lines[2]}" = "test,1,1,0,1,2,2,3,false" ]
+
+    run dolt sql -r csv -q "select rows_added from dolt_diff_summary('HEAD', 'WORKING', 'test')"
+    [ "$status" -eq 0 ]
+    [ "${lines[1]}" = "1" ]
+
+    run dolt sql -q "select * from dolt_diff_summary('HEAD', 'WORKING', 'doesnotexist')"
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "table not found: doesnotexist" ]] || false
+}
+
+@test "diff: dolt_schema_diff table function matches schema diff" {
+    dolt add test
+    dolt commit -m "table created"
+    dolt sql -q "alter table test drop column c5"
+    dolt sql -q "alter table test add column c6 varchar(20)"
+    dolt sql -q "alter table test add index c6_idx (c6)"
+    dolt sql -q "create table other (pk int primary key)"
+
+    run dolt diff --schema test
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ '-  `c5` bigint' ]] || false
+    [[ "$output" =~ '+  `c6` varchar(20)' ]] || false
+
+    run dolt sql -r csv -q "select to_table_name, element_type, element_name, diff_type, to_definition from dolt_schema_diff('HEAD', 'WORKING') where element_type <> 'table'"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 5 ]
+    [ "${lines[0]}" = "to_table_name,element_type,element_name,diff_type,to_definition" ]
+    [ "${lines[1]}" = 'other,column,pk,added,`pk` int NOT NULL' ]
+    [ "${lines[2]}" = 'test,column,c5,dropped,' ]
+    [ "${lines[3]}" = 'test,column,c6,added,`c6` varchar(20)' ]
+    [ "${lines[4]}" = 'test,index,c6_idx,added,INDEX `c6_idx` (`c6`)' ]
+
+    run dolt sql -r csv -q "select from_definition from dolt_schema_diff('HEAD', 'WORKING', 'test') where element_type = 'table'"
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ 'CREATE TABLE `test`' ]] || false
+    [[ "$output" =~ '`c5` bigint' ]] || false
+
+    run dolt sql -q "select * from dolt_schema_diff('HEAD', 'WORKING', 'doesnotexist')"
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "table not found: doesnotexist" ]] || false
+}
+
 @test "diff: diff summary incorrect primary key set change regression test" {
     dolt sql -q "create table testdrop (col1 varchar(20), id int primary key, col2 varchar(20))"
     dolt add .
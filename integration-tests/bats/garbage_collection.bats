@@ -81,6 +81,32 @@ SQL
     [[ $output =~ "two" ]] || false
 }
 
+@test "garbage_collection: gc --zstd rewrites table files" {
+    dolt sql <<SQL
+CREATE TABLE test (pk int PRIMARY KEY, c0 varchar(64));
+INSERT INTO test VALUES
+    (1,'customer_1@example.com'),(2,'customer_2@example.com'),(3,'customer_3@example.com'),
+    (4,'customer_4@example.com'),(5,'customer_5@example.com'),(6,'customer_6@example.com');
+SQL
+    dolt add -A && dolt commit -m "added a table"
+
+    run dolt gc --zstd
+    [ "$status" -eq "0" ]
+
+    run dolt sql -q 'select count(*) from test' -r csv
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "6" ]] || false
+    run dolt status
+    [ "$status" -eq "0" ]
+
+    # zstd table files are kept as they are
+    run dolt gc --zstd
+    [ "$status" -eq "0" ]
+    run dolt sql -q 'select c0 from test where pk = 4' -r csv
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "customer_4@example.com" ]] || false
+}
+
 @test "garbage_collection: clone a remote" {
     dolt sql <<SQL
 CREATE TABLE test (pk int PRIMARY KEY);
@@ -0,0 +1,113 @@
+#!/usr/bin/env bats
+load $BATS_TEST_DIRNAME/helper/common.bash
+
+setup() {
+    setup_common
+
+    dolt sql <<SQL
+INSERT INTO dolt_ignore VALUES ('tmp_*', true), ('staging_*', true), ('tmp_keep', false);
+CREATE TABLE users (pk int primary key);
+CREATE TABLE tmp_a (pk int primary key);
+CREATE TABLE staging_b (pk int primary key);
+CREATE TABLE tmp_keep (pk int primary key);
+SQL
+}
+
+teardown() {
+    assert_feature_version
+    teardown_common
+}
+
+@test "ignore: status does not show ignored tables" {
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "new table:      dolt_ignore" ]] || false
+    [[ "$output" =~ "new table:      users" ]] || false
+    [[ "$output" =~ "new table:      tmp_keep" ]] || false
+    [[ ! "$output" =~ "tmp_a" ]] || false
+    [[ ! "$output" =~ "staging_b" ]] || false
+
+    run dolt sql -q "SELECT table_name FROM dolt_status ORDER BY table_name" -r csv
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 4 ]
+    [[ ! "$output" =~ "tmp_a" ]] || false
+    [[ ! "$output" =~ "staging_b" ]] || false
+}
+
+@test "ignore: add . skips ignored tables" {
+    dolt add .
+    dolt commit -m "add tables"
+
+    run dolt ls
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "users" ]] || false
+    [[ "$output" =~ "tmp_keep" ]] || false
+    [[ "$output" =~ "tmp_a" ]] || false
+
+    run dolt ls HEAD
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "users" ]] || false
+    [[ "$output" =~ "tmp_keep" ]] || false
+    [[ ! "$output" =~ "tmp_a" ]] || false
+    [[ ! "$output" =~ "staging_b" ]] || false
+
+    run dolt ls --system HEAD
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "dolt_ignore" ]] || false
+}
+
+@test "ignore: dolt_add('.') skips ignored tables" {
+    dolt sql -q "CALL DOLT_ADD('.')"
+
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "Changes to be committed" ]] || false
+    [[ ! "$output" =~ "Untracked files" ]] || false
+    [[ ! "$output" =~ "tmp_a" ]] || false
+}
+
+@test "ignore: adding an ignored table requires --force" {
+    run dolt add tmp_a
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "ignored by dolt_ignore" ]] || false
+    [[ "$output" =~ "tmp_a" ]] || false
+
+    dolt add -f tmp_a
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "new table:      tmp_a" ]] || false
+
+    run dolt sql -q "CALL DOLT_ADD('staging_b')"
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "ignored by dolt_ignore" ]] || false
+
+    dolt sql -q "CALL DOLT_ADD('--force', 'staging_b')"
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "new table:      staging_b" ]] || false
+}
+
+@test "ignore: tracked tables are not ignored" {
+    dolt add -f tmp_a
+    dolt commit -m "add tmp_a"
+    dolt sql -q "INSERT INTO tmp_a VALUES (1)"
+
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "modified:       tmp_a" ]] || false
+
+    dolt add .
+    run dolt status
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "Changes to be committed" ]] || false
+    [[ "$output" =~ "modified:       tmp_a" ]] || false
+}
+
+@test "ignore: conflicting patterns are an error" {
+    dolt sql -q "INSERT INTO dolt_ignore VALUES ('*_b', false)"
+
+    run dolt add .
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "conflicting patterns" ]] || false
+    [[ "$output" =~ "staging_b" ]] || false
+}
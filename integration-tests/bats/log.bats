@@ -381,6 +381,39 @@ teardown() {
     [[ "$output" =~ "fatal: invalid --decorate option" ]] || false
 }
 
+@test "log: dolt_log table function shows revision ranges" {
+    dolt sql -q "create table test (pk int primary key)"
+    dolt add test
+    dolt commit -m "created table"
+    dolt branch feature
+    dolt commit --allow-empty -m "commit 1 MAIN"
+    dolt checkout feature
+    dolt commit --allow-empty -m "commit 1 FEATURE"
+    dolt sql -q "insert into test values (1)"
+    dolt commit -am "commit 2 FEATURE"
+    dolt checkout main
+
+    run dolt sql -r csv -q "select message from dolt_log('main..feature')"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 3 ]
+    [ "${lines[1]}" = "commit 2 FEATURE" ]
+    [ "${lines[2]}" = "commit 1 FEATURE" ]
+
+    run dolt sql -r csv -q "select message from dolt_log('feature', '--not', 'main', '--tables', 'test')"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 2 ]
+    [ "${lines[1]}" = "commit 2 FEATURE" ]
+
+    run dolt sql -r csv -q "select message from dolt_log('main...feature', '-n', '1')"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 2 ]
+    [ "${lines[1]}" = "commit 2 FEATURE" ]
+
+    run dolt sql -q "select * from dolt_log('main..doesnotexist')"
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "branch not found: doesnotexist" ]] || false
+}
+
 @test "log: check pager" {
     skiponwindows "Need to install expect and make this script work on windows."
     dolt commit --allow-empty -m "commit 1"
@@ -0,0 +1,124 @@
+#!/usr/bin/env bats
+load $BATS_TEST_DIRNAME/helper/common.bash
+
+setup() {
+    setup_common
+
+    dolt sql -q "CREATE TABLE test(pk BIGINT PRIMARY KEY, v1 BIGINT)"
+    dolt sql -q "INSERT INTO test VALUES (1, 1)"
+    dolt add -A
+    dolt commit -m "Created table"
+    dolt checkout -b feature
+    dolt sql -q "INSERT INTO test VALUES (2, 2)"
+    dolt commit -am "Inserted 2"
+    dolt sql -q "INSERT INTO test VALUES (3, 3)"
+    dolt commit -am "Inserted 3"
+    dolt checkout main
+    dolt sql -q "INSERT INTO test VALUES (10, 10)"
+    dolt commit -am "Inserted 10"
+    dolt checkout feature
+}
+
+teardown() {
+    assert_feature_version
+    teardown_common
+}
+
+@test "rebase: replays commits onto upstream" {
+    run dolt rebase main
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "Successfully rebased and updated refs/heads/feature" ]] || false
+
+    run dolt sql -q "SELECT * FROM test" -r=csv
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "2,2" ]] || false
+    [[ "$output" =~ "3,3" ]] || false
+    [[ "$output" =~ "10,10" ]] || false
+    [[ "${#lines[@]}" = "5" ]] || false
+
+    run dolt log --oneline
+    [ "$status" -eq "0" ]
+    [[ "${lines[0]}" =~ "Inserted 3" ]] || false
+    [[ "${lines[1]}" =~ "Inserted 2" ]] || false
+    [[ "${lines[2]}" =~ "Inserted 10" ]] || false
+
+    run dolt status
+    [[ "$output" =~ "nothing to commit, working tree clean" ]] || false
+}
+
+@test "rebase: interactive without an editor leaves the plan in dolt_rebase" {
+    run dolt rebase -i main
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "dolt_rebase" ]] || false
+
+    run dolt sql -q "SELECT action, commit_message FROM dolt_rebase" -r=csv
+    [ "$status" -eq "0" ]
+    [[ "${lines[1]}" = "pick,Inserted 2" ]] || false
+    [[ "${lines[2]}" = "pick,Inserted 3" ]] || false
+
+    dolt sql -q "UPDATE dolt_rebase SET action = 'squash' WHERE rebase_order = 2"
+    run dolt rebase --continue
+    [ "$status" -eq "0" ]
+
+    run dolt log --oneline
+    [ "$status" -eq "0" ]
+    [[ "${lines[0]}" =~ "Inserted 2" ]] || false
+    [[ "${lines[1]}" =~ "Inserted 10" ]] || false
+
+    run dolt sql -q "SELECT COUNT(*) FROM test" -r=csv
+    [[ "$output" =~ "4" ]] || false
+}
+
+@test "rebase: abort returns the branch to its original commit" {
+    dolt rebase -i main
+    run dolt rebase --abort
+    [ "$status" -eq "0" ]
+
+    run dolt log --oneline
+    [[ "${lines[0]}" =~ "Inserted 3" ]] || false
+    [[ "${lines[1]}" =~ "Inserted 2" ]] || false
+    [[ "${lines[2]}" =~ "Created table" ]] || false
+
+    run dolt ls
+    [[ ! "$output" =~ "dolt_rebase" ]] || false
+
+    run dolt rebase --abort
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "no rebase in progress" ]] || false
+}
+
+@test "rebase: conflicts stop the rebase until resolved" {
+    dolt sql -q "UPDATE test SET v1 = 100 WHERE pk = 1"
+    dolt commit -am "Updated 1 on feature"
+    dolt checkout main
+    dolt sql -q "UPDATE test SET v1 = 200 WHERE pk = 1"
+    dolt commit -am "Updated 1 on main"
+    dolt checkout feature
+
+    run dolt rebase main
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "could not apply" ]] || false
+    [[ "$output" =~ "Updated 1 on feature" ]] || false
+
+    run dolt rebase --continue
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "resolve all conflicts" ]] || false
+
+    dolt conflicts resolve --theirs test
+    run dolt rebase --continue
+    [ "$status" -eq "0" ]
+
+    run dolt sql -q "SELECT v1 FROM test WHERE pk = 1" -r=csv
+    [[ "$output" =~ "100" ]] || false
+
+    run dolt log --oneline
+    [[ "${lines[0]}" =~ "Updated 1 on feature" ]] || false
+    [[ "${lines[3]}" =~ "Updated 1 on main" ]] || false
+}
+
+@test "rebase: uncommitted changes" {
+    dolt sql -q "INSERT INTO test VALUES (4, 4)"
+    run dolt rebase main
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "uncommitted changes" ]] || false
+}
@@ -0,0 +1,90 @@
+#!/usr/bin/env bats
+load $BATS_TEST_DIRNAME/helper/common.bash
+
+setup() {
+    setup_common
+
+    dolt sql -q "CREATE TABLE t (pk int primary key)"
+    dolt add .
+    dolt commit -m "first"
+    dolt sql -q "INSERT INTO t VALUES (1)"
+    dolt commit -am "second"
+}
+
+teardown() {
+    assert_feature_version
+    teardown_common
+}
+
+get_head_commit() {
+    dolt log -n 1 | grep -m 1 commit | cut -c 13-44
+}
+
+@test "reflog: find and check out a commit lost by a hard reset" {
+    lost=$(get_head_commit)
+    dolt reset --hard HEAD~1
+
+    run dolt reflog main
+    [ "$status" -eq 0 ]
+    [[ "${lines[0]}" =~ "(refs/heads/main) first" ]] || false
+    [[ "${lines[1]}" =~ "$lost (refs/heads/main) second" ]] || false
+    [[ "$output" =~ "Initialize data repository" ]] || false
+
+    dolt checkout -b recovered "$lost"
+    run dolt sql -q "SELECT * FROM t" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "1" ]] || false
+}
+
+@test "reflog: shows all refs by default" {
+    dolt branch other
+    dolt tag v1
+
+    run dolt reflog
+    [ "$status" -eq 0 ]
+    [[ "${lines[0]}" =~ "(refs/tags/v1) second" ]] || false
+    [[ "${lines[1]}" =~ "(refs/heads/other) second" ]] || false
+    [[ ! "$output" =~ "workingSets" ]] || false
+
+    run dolt reflog v1
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 1 ]
+
+    run dolt reflog --all main
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "(workingSets/heads/main)" ]] || false
+    [[ ! "$output" =~ "refs/heads/other" ]] || false
+}
+
+@test "reflog: is persisted across processes and shared with sql" {
+    dolt sql -q "CALL DOLT_RESET('--hard', 'HEAD~1')"
+
+    run dolt reflog main
+    [ "$status" -eq 0 ]
+    [[ "${lines[1]}" =~ "(refs/heads/main) second" ]] || false
+
+    run dolt sql -q "SELECT ref, commit_message FROM dolt_reflog('main') LIMIT 2" -r csv
+    [ "$status" -eq 0 ]
+    [[ "${lines[1]}" = "refs/heads/main,first" ]] || false
+    [[ "${lines[2]}" = "refs/heads/main,second" ]] || false
+}
+
+@test "reflog: records deleted branches" {
+    dolt branch other
+    dolt checkout other
+    dolt sql -q "INSERT INTO t VALUES (2)"
+    dolt commit -am "third"
+    lost=$(get_head_commit)
+    dolt checkout main
+    dolt branch -D other
+
+    run dolt reflog other
+    [ "$status" -eq 0 ]
+    [[ "${lines[0]}" =~ "$lost (refs/heads/other) third" ]] || false
+}
+
+@test "reflog: too many arguments" {
+    run dolt reflog main other
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "at most 1 arg" ]] || false
+}
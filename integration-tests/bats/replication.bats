@@ -160,6 +160,60 @@ teardown() {
     [[ "$output" =~ "t1" ]] || false
 }
 
+@test "replication: pull working set on read" {
+    dolt clone file://./rem1 repo2
+    cd repo2
+    dolt config --local --add sqlserver.global.dolt_read_replica_remote origin
+    dolt config --local --add sqlserver.global.dolt_replicate_heads main
+    dolt config --local --add sqlserver.global.dolt_replicate_working_sets 1
+
+    cd ../repo1
+    dolt config --local --add sqlserver.global.dolt_replicate_to_remote remote1
+    dolt config --local --add sqlserver.global.dolt_replicate_working_sets 1
+    dolt sql -q "create table t1 (a int primary key)"
+    dolt sql -q "insert into t1 values (1), (2)"
+
+    cd ../repo2
+    run dolt sql -q "select count(*) from t1" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "2" ]] || false
+
+    run dolt sql -q "select * from dolt_status" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "t1,false,new table" ]] || false
+
+    cd ../repo1
+    dolt sql -q "call dolt_add('.')"
+    dolt sql -q "call dolt_commit('-m', 'cm')"
+    dolt sql -q "insert into t1 values (3)"
+
+    cd ../repo2
+    run dolt sql -q "select message from dolt_log limit 1" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "cm" ]] || false
+
+    run dolt sql -q "select count(*) from t1" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "3" ]] || false
+}
+
+@test "replication: working set is not pulled by default" {
+    dolt clone file://./rem1 repo2
+    cd repo2
+    dolt config --local --add sqlserver.global.dolt_read_replica_remote origin
+    dolt config --local --add sqlserver.global.dolt_replicate_heads main
+
+    cd ../repo1
+    dolt config --local --add sqlserver.global.dolt_replicate_to_remote remote1
+    dolt config --local --add sqlserver.global.dolt_replicate_working_sets 1
+    dolt sql -q "create table t1 (a int primary key)"
+
+    cd ../repo2
+    run dolt sql -q "show tables" -r csv
+    [ "$status" -eq 0 ]
+    [[ ! "$output" =~ "t1" ]] || false
+}
+
 @test "replication: push on call dolt_branch(..." {
     cd repo1
     dolt config --local --add sqlserver.global.dolt_replicate_to_remote backup1
@@ -713,3 +713,50 @@ SQL
     [ "$status" -eq 0 ]
     [[ "$output" =~ 'INSERT INTO `test` (`pk`,`c1`) VALUES (0,NULL)' ]] || false
 }
+
+@test "sql-diff: dolt_patch table function reconciles branches" {
+    dolt checkout -b firstbranch
+    dolt sql <<SQL
+CREATE TABLE test (
+  pk BIGINT NOT NULL,
+  c1 BIGINT,
+  c2 BIGINT,
+  PRIMARY KEY (pk)
+);
+INSERT INTO test VALUES (0, 0, 0), (1, 1, 1), (2, 2, 2);
+SQL
+    dolt add test
+    dolt commit -m "Added initial rows"
+
+    dolt checkout -b newbranch
+    dolt sql <<SQL
+INSERT INTO test VALUES (3, 3, 3);
+UPDATE test SET c1 = 10 WHERE pk = 1;
+DELETE FROM test WHERE pk = 2;
+CREATE TABLE other (pk INT PRIMARY KEY);
+INSERT INTO other VALUES (1);
+SQL
+    dolt add .
+    dolt commit -m "Changed rows and added a table"
+
+    run dolt sql -r csv -q "SELECT table_name, diff_type FROM dolt_patch('firstbranch', 'newbranch') ORDER BY statement_order"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 6 ]
+    [ "${lines[1]}" = "other,schema" ]
+    [ "${lines[2]}" = "other,data" ]
+    [ "${lines[3]}" = "test,data" ]
+
+    # the patch reconciles the branches, just like the CLI output
+    dolt sql -r csv -q "SELECT statement FROM dolt_patch('firstbranch', 'newbranch', 'test') ORDER BY statement_order" | tail -n +2 | sed -e 's/^"\(.*\)"$/\1/' > query
+    run cat query
+    [[ "$output" =~ 'UPDATE `test` SET `c1`=10 WHERE `pk`=1;' ]] || false
+    [[ "$output" =~ 'DELETE FROM `test` WHERE `pk`=2;' ]] || false
+    [[ "$output" =~ 'INSERT INTO `test` (`pk`,`c1`,`c2`) VALUES (3,3,3);' ]] || false
+    [[ ! "$output" =~ 'other' ]] || false
+
+    dolt checkout firstbranch
+    dolt sql < query
+    run dolt sql -r csv -q "SELECT count(*) FROM dolt_patch('WORKING', 'newbranch', 'test')"
+    [ "$status" -eq 0 ]
+    [ "${lines[1]}" = "0" ]
+}
@@ -1694,3 +1694,26 @@ s.close()
     [[ "$output" =~ "database locked by another sql-server; either clone the database to run a second server" ]] || false
     [ "$status" -eq 1 ]
 }
+
+@test "sql-server: dolt_gc collects garbage while the server is running" {
+    cd repo1
+    start_sql_server repo1
+
+    server_query repo1 1 dolt "" "CREATE TABLE t (pk int primary key, c0 int)" ""
+    server_query repo1 1 dolt "" "INSERT INTO t VALUES (1, 1), (2, 2)" ""
+    server_query repo1 1 dolt "" "call dolt_commit('-am', 'add table t')" ""
+    server_query repo1 1 dolt "" "INSERT INTO t VALUES (3, 3)" ""
+    server_query repo1 1 dolt "" "UPDATE t SET c0 = 10 WHERE pk = 3" ""
+
+    server_query repo1 1 dolt "" "call dolt_gc()" "status\n0"
+    server_query repo1 1 dolt "" "SELECT * FROM t ORDER BY pk" "pk,c0\n1,1\n2,2\n3,10"
+    server_query repo1 1 dolt "" "INSERT INTO t VALUES (4, 4)" ""
+    server_query repo1 1 dolt "" "call dolt_commit('-am', 'add another row')" ""
+    server_query repo1 1 dolt "" "call dolt_gc('--shallow')" "status\n0"
+    server_query repo1 1 dolt "" "SELECT COUNT(*) FROM t" "COUNT(*)\n4"
+
+    stop_sql_server
+    run dolt sql -q "SELECT COUNT(*) FROM t" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "4" ]] || false
+}
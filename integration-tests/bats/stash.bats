@@ -0,0 +1,124 @@
+#!/usr/bin/env bats
+load $BATS_TEST_DIRNAME/helper/common.bash
+
+setup() {
+    setup_common
+
+    dolt sql -q "CREATE TABLE test(pk BIGINT PRIMARY KEY, v1 BIGINT)"
+    dolt sql -q "INSERT INTO test VALUES (1, 1)"
+    dolt add -A
+    dolt commit -m "Created table"
+}
+
+teardown() {
+    assert_feature_version
+    teardown_common
+}
+
+@test "stash: push and pop" {
+    dolt sql -q "INSERT INTO test VALUES (2, 2)"
+    dolt add test
+    dolt sql -q "UPDATE test SET v1 = 10 WHERE pk = 1"
+    dolt sql -q "CREATE TABLE other(pk BIGINT PRIMARY KEY)"
+
+    run dolt stash
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "Saved working directory and index state WIP on main:" ]] || false
+
+    run dolt status
+    [[ "$output" =~ "nothing to commit, working tree clean" ]] || false
+
+    run dolt stash list
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "stash@{0}: WIP on main:" ]] || false
+    [[ "$output" =~ "Created table" ]] || false
+
+    run dolt stash pop
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "Dropped stash@{0}" ]] || false
+
+    run dolt sql -q "SELECT * FROM test" -r=csv
+    [[ "$output" =~ "1,10" ]] || false
+    [[ "$output" =~ "2,2" ]] || false
+
+    run dolt ls
+    [[ "$output" =~ "other" ]] || false
+
+    run dolt stash list
+    [ "$status" -eq "0" ]
+    [ "$output" = "" ]
+}
+
+@test "stash: nothing to stash" {
+    run dolt stash
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "No local changes to save" ]] || false
+
+    run dolt stash list
+    [ "$output" = "" ]
+}
+
+@test "stash: apply and drop specific entries" {
+    dolt sql -q "INSERT INTO test VALUES (2, 2)"
+    dolt stash push -m "first"
+    dolt sql -q "INSERT INTO test VALUES (3, 3)"
+    dolt stash -m "second"
+
+    run dolt stash list
+    [[ "${lines[0]}" = "stash@{0}: On main: second" ]] || false
+    [[ "${lines[1]}" = "stash@{1}: On main: first" ]] || false
+
+    run dolt stash apply stash@{1}
+    [ "$status" -eq "0" ]
+    run dolt sql -q "SELECT COUNT(*) FROM test WHERE pk = 2" -r=csv
+    [[ "$output" =~ "1" ]] || false
+
+    run dolt stash drop stash@{1}
+    [ "$status" -eq "0" ]
+    [[ "$output" =~ "Dropped stash@{1}" ]] || false
+
+    run dolt sql -q "SELECT stash_id, message FROM dolt_stashes" -r=csv
+    [[ "$output" =~ "stash@{0},On main: second" ]] || false
+    [[ ! "$output" =~ "first" ]] || false
+
+    dolt stash clear
+    run dolt stash drop
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "No stash entries found." ]] || false
+}
+
+@test "stash: pop with conflicts keeps the entry" {
+    dolt sql -q "UPDATE test SET v1 = 2 WHERE pk = 1"
+    dolt stash
+    dolt sql -q "UPDATE test SET v1 = 10 WHERE pk = 1"
+    dolt commit -am "Updated 1"
+
+    run dolt stash pop
+    [ "$status" -eq "1" ]
+    [[ "$output" =~ "conflicts" ]] || false
+    [[ "$output" =~ "The stash entry is kept" ]] || false
+
+    run dolt conflicts cat test
+    [[ "$output" =~ "ours" ]] || false
+    [[ "$output" =~ "theirs" ]] || false
+
+    run dolt stash list
+    [[ "$output" =~ "stash@{0}" ]] || false
+
+    dolt conflicts resolve --theirs test
+    run dolt sql -q "SELECT v1 FROM test WHERE pk = 1" -r=csv
+    [[ "$output" =~ "2" ]] || false
+}
+
+@test "stash: dolt_stash procedure" {
+    dolt sql -q "INSERT INTO test VALUES (2, 2)"
+    run dolt sql -q "CALL dolt_stash('push', '-m', 'from sql')"
+    [ "$status" -eq "0" ]
+
+    run dolt stash list
+    [[ "$output" =~ "stash@{0}: On main: from sql" ]] || false
+
+    dolt sql -q "CALL dolt_stash('pop')"
+    run dolt sql -q "SELECT COUNT(*) FROM test" -r=csv
+    [[ "$output" =~ "2" ]] || false
+}
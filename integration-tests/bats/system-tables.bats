@@ -270,6 +270,36 @@ teardown() {
     [[ "$output" =~ "$EXPECTED" ]] || false
 }
 
+@test "system-tables: query dolt_column_diff and dolt_column_diff_ system tables" {
+    dolt sql -q "CREATE TABLE items (pk INT PRIMARY KEY, price INT, name VARCHAR(20))"
+    dolt sql -q "INSERT INTO items VALUES (1, 10, 'apple'), (2, 20, 'pear')"
+    dolt add items
+    dolt commit -m "Added items table"
+    dolt sql -q "UPDATE items SET price = 11 WHERE pk = 1"
+    dolt commit -am "Changed a price"
+    dolt sql -q "UPDATE items SET name = 'banana' WHERE pk = 2"
+
+    run dolt sql -r csv -q "select table_name, column_name, message, diff_type from dolt_column_diff where commit_hash = hashof('HEAD')"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 2 ]
+    [ "${lines[1]}" = "items,price,Changed a price,modified" ]
+
+    run dolt sql -r csv -q "select commit_hash, column_name, diff_type from dolt_column_diff where commit_hash = 'WORKING'"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 2 ]
+    [ "${lines[1]}" = "WORKING,name,modified" ]
+
+    run dolt sql -r csv -q "select message, diff_type from dolt_column_diff_items where column_name = 'price'"
+    [ "$status" -eq 0 ]
+    [ "${#lines[@]}" -eq 3 ]
+    [ "${lines[1]}" = "Changed a price,modified" ]
+    [ "${lines[2]}" = "Added items table,added" ]
+
+    run dolt sql -q "select * from dolt_column_diff_doesnotexist"
+    [ "$status" -eq 1 ]
+    [[ "$output" =~ "table not found: dolt_column_diff_doesnotexist" ]] || false
+}
+
 @test "system-tables: query dolt_diff_ and dolt_commit_diff_ highlighting differences" {
     dolt branch before_creation main
     dolt sql -q "CREATE TABLE test (pk INT, c1 INT, PRIMARY KEY(pk))"
@@ -576,3 +606,37 @@ SQL
     [[ "$output" =~ "tag v2 from branch1" ]] || false
     [[ "$output" =~ "tag v3 from branch1" ]] || false
 }
+
+@test "system-tables: query dolt_merge_status" {
+    dolt sql -q "CREATE TABLE test(pk int primary key, val int)"
+    dolt add .
+    dolt sql -q "INSERT INTO test VALUES (1,1)"
+    dolt commit -am "cm1"
+
+    run dolt sql -q "SELECT * FROM dolt_merge_status" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "false,,,," ]] || false
+
+    dolt checkout -b other
+    dolt sql -q "UPDATE test SET val = 2 WHERE pk = 1"
+    dolt commit -am "cm2"
+    dolt checkout main
+    dolt sql -q "UPDATE test SET val = 3 WHERE pk = 1"
+    dolt commit -am "cm3"
+
+    run dolt merge other
+    [[ "$output" =~ "CONFLICT" ]] || false
+
+    run dolt sql -q "SELECT is_merging, source, target, unmerged_tables FROM dolt_merge_status" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "true,other,refs/heads/main,test" ]] || false
+
+    run dolt sql -q "SELECT source_commit = hashof('other') FROM dolt_merge_status" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "true" ]] || false
+
+    dolt merge --abort
+    run dolt sql -q "SELECT is_merging FROM dolt_merge_status" -r csv
+    [ "$status" -eq 0 ]
+    [[ "$output" =~ "false" ]] || false
+}